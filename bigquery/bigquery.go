@@ -16,16 +16,21 @@ package bigquery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	bqstorage "cloud.google.com/go/bigquery/storage/apiv1"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
 	"cloud.google.com/go/internal"
 	"cloud.google.com/go/internal/detect"
 	"cloud.google.com/go/internal/version"
+	"cloud.google.com/go/storage"
 	gax "github.com/googleapis/gax-go/v2"
 	bq "google.golang.org/api/bigquery/v2"
 	"google.golang.org/api/googleapi"
@@ -53,8 +58,40 @@ type Client struct {
 	// those operations will override this value.
 	Location string
 
+	// StorageRead, if not nil, makes Table.Read, Job.Read and Query.Read
+	// download large results with the BigQuery Storage Read API, reading
+	// several streams in parallel. See StorageReadConfig.
+	StorageRead *StorageReadConfig
+
+	// DefaultJobLabels are added to the labels of every job that the client
+	// runs, including queries, loads, copies and extracts, which lets you
+	// attribute the cost of all of them. A label of a job's config takes
+	// precedence over a default label with the same key.
+	DefaultJobLabels map[string]string
+
+	// DefaultQueryPriority is the priority of queries whose
+	// QueryConfig.Priority is empty. If it is empty too, such queries are
+	// interactive. Load, copy and extract jobs have no priority.
+	DefaultQueryPriority QueryPriority
+
+	// DefaultJobCreationMode is the JobCreationMode of queries whose
+	// QueryConfig.JobCreationMode is empty.
+	DefaultJobCreationMode JobCreationMode
+
 	projectID string
 	bqs       *bq.Service
+	opts      []option.ClientOption // for creating the Storage API clients
+	sc        *storageClients
+	emulator  bool // whether requests are sent to an emulator
+}
+
+// storageClients holds the BigQuery Storage API clients of a Client, which are
+// created on first use by storageWriteClient and storageReadClient.
+type storageClients struct {
+	mu sync.Mutex
+	wc *managedwriter.Client
+	rc *bqstorage.BigQueryReadClient
+	gc *storage.Client // Cloud Storage, for the files of extract jobs
 }
 
 // DetectProjectID is a sentinel value that instructs NewClient to detect the
@@ -72,11 +109,23 @@ const DetectProjectID = "*detect-project-id*"
 // If the project ID is set to DetectProjectID, NewClient will attempt to detect
 // the project ID from credentials.
 func NewClient(ctx context.Context, projectID string, opts ...option.ClientOption) (*Client, error) {
+	config, opts := newClientConfig(opts)
 	o := []option.ClientOption{
 		option.WithScopes(Scope),
 		option.WithUserAgent(fmt.Sprintf("%s/%s", userAgentPrefix, version.Repo)),
 	}
 	o = append(o, opts...)
+	var emulator bool
+	if host := emulatorHost(config); host != "" {
+		u, err := parseEmulatorHost(host)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery: invalid emulator address %q: %v", host, err)
+		}
+		// The emulator options come last to override an endpoint or
+		// credentials in opts.
+		o = append(o, emulatorOptions(u)...)
+		emulator = true
+	}
 	bqs, err := bq.NewService(ctx, o...)
 	if err != nil {
 		return nil, fmt.Errorf("bigquery: constructing client: %v", err)
@@ -91,6 +140,9 @@ func NewClient(ctx context.Context, projectID string, opts ...option.ClientOptio
 	c := &Client{
 		projectID: projectID,
 		bqs:       bqs,
+		opts:      opts,
+		sc:        &storageClients{},
+		emulator:  emulator,
 	}
 	return c, nil
 }
@@ -105,15 +157,103 @@ func (c *Client) Project() string {
 // Close should be called when the client is no longer needed.
 // It need not be called at program exit.
 func (c *Client) Close() error {
-	return nil
+	if c.sc == nil {
+		return nil
+	}
+	c.sc.mu.Lock()
+	defer c.sc.mu.Unlock()
+	var err error
+	if c.sc.wc != nil {
+		err = c.sc.wc.Close()
+		c.sc.wc = nil
+	}
+	if c.sc.rc != nil {
+		if rerr := c.sc.rc.Close(); err == nil {
+			err = rerr
+		}
+		c.sc.rc = nil
+	}
+	if c.sc.gc != nil {
+		if gerr := c.sc.gc.Close(); err == nil {
+			err = gerr
+		}
+		c.sc.gc = nil
+	}
+	return err
+}
+
+// storageWriteClient returns the client's Storage Write API client, creating
+// it with the options given to NewClient if needed.
+func (c *Client) storageWriteClient(ctx context.Context) (*managedwriter.Client, error) {
+	if c.sc == nil {
+		return nil, errors.New("bigquery: Client was not created with NewClient")
+	}
+	if c.emulator {
+		return nil, errStorageAPIEmulator
+	}
+	c.sc.mu.Lock()
+	defer c.sc.mu.Unlock()
+	if c.sc.wc == nil {
+		wc, err := managedwriter.NewClient(ctx, c.projectID, c.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery: constructing storage write client: %v", err)
+		}
+		c.sc.wc = wc
+	}
+	return c.sc.wc, nil
+}
+
+// storageReadClient returns the client's Storage Read API client, creating it
+// with the options given to NewClient if needed.
+func (c *Client) storageReadClient(ctx context.Context) (*bqstorage.BigQueryReadClient, error) {
+	if c.sc == nil {
+		return nil, errors.New("bigquery: Client was not created with NewClient")
+	}
+	if c.emulator {
+		return nil, errStorageAPIEmulator
+	}
+	c.sc.mu.Lock()
+	defer c.sc.mu.Unlock()
+	if c.sc.rc == nil {
+		rc, err := bqstorage.NewBigQueryReadClient(ctx, c.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery: constructing storage read client: %v", err)
+		}
+		c.sc.rc = rc
+	}
+	return c.sc.rc, nil
+}
+
+// gcsClient returns the client's Cloud Storage client, creating it with the
+// options given to NewClient if needed.
+func (c *Client) gcsClient(ctx context.Context) (*storage.Client, error) {
+	if c.sc == nil {
+		return nil, errors.New("bigquery: Client was not created with NewClient")
+	}
+	c.sc.mu.Lock()
+	defer c.sc.mu.Unlock()
+	if c.sc.gc == nil {
+		gc, err := storage.NewClient(ctx, c.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery: constructing Cloud Storage client: %v", err)
+		}
+		c.sc.gc = gc
+	}
+	return c.sc.gc, nil
 }
 
 // Calls the Jobs.Insert RPC and returns a Job.
-func (c *Client) insertJob(ctx context.Context, job *bq.Job, media io.Reader) (*Job, error) {
+func (c *Client) insertJob(ctx context.Context, job *bq.Job, media *mediaUpload, retry *RetryConfig) (*Job, error) {
+	if job.Configuration != nil {
+		job.Configuration.Labels = c.jobLabels(job.Configuration.Labels)
+	}
 	call := c.bqs.Jobs.Insert(c.projectID, job).Context(ctx)
 	setClientHeader(call.Header())
 	if media != nil {
-		call.Media(media)
+		call.Media(media.r, media.opts...)
+		if media.progress != nil {
+			call.ProgressUpdater(media.progress)
+		}
 	}
 	var res *bq.Job
 	var err error
@@ -125,23 +265,29 @@ func (c *Client) insertJob(ctx context.Context, job *bq.Job, media io.Reader) (*
 	// ID makes the insert operation idempotent.
 	// We don't retry if there is media, because it is an io.Reader. We'd
 	// have to read the contents and keep it in memory, and that could be expensive.
-	// TODO(jba): Look into retrying if media != nil.
+	// Instead, the chunks of a resumable upload are retried as they are sent.
 	if job.JobReference != nil && media == nil {
 		// We deviate from default retries due to BigQuery wanting to retry structured internal job errors.
-		err = runWithRetryExplicit(ctx, invoke, jobRetryReasons)
+		err = runWithRetryConfig(ctx, invoke, retry, jobRetryReasons)
 	} else {
 		err = invoke()
 	}
 	if err != nil {
 		return nil, err
 	}
-	return bqToJob(res, c)
+	j, err := bqToJob(res, c)
+	if err != nil {
+		return nil, err
+	}
+	j.retry = retry
+	return j, nil
 }
 
 // runQuery invokes the optimized query path.
 // Due to differences in options it supports, it cannot be used for all existing
 // jobs.insert requests that are query jobs.
-func (c *Client) runQuery(ctx context.Context, queryRequest *bq.QueryRequest) (*bq.QueryResponse, error) {
+func (c *Client) runQuery(ctx context.Context, queryRequest *bq.QueryRequest, retry *RetryConfig) (*bq.QueryResponse, error) {
+	queryRequest.Labels = c.jobLabels(queryRequest.Labels)
 	call := c.bqs.Jobs.Query(c.projectID, queryRequest)
 	setClientHeader(call.Header())
 
@@ -153,13 +299,29 @@ func (c *Client) runQuery(ctx context.Context, queryRequest *bq.QueryRequest) (*
 	}
 
 	// We control request ID, so we can always runWithRetry.
-	err = runWithRetryExplicit(ctx, invoke, jobRetryReasons)
+	err = runWithRetryConfig(ctx, invoke, retry, jobRetryReasons)
 	if err != nil {
 		return nil, err
 	}
 	return res, nil
 }
 
+// jobLabels returns the labels of a job, merged with the client's
+// DefaultJobLabels. The labels are not modified.
+func (c *Client) jobLabels(labels map[string]string) map[string]string {
+	if len(c.DefaultJobLabels) == 0 {
+		return labels
+	}
+	merged := make(map[string]string, len(c.DefaultJobLabels)+len(labels))
+	for k, v := range c.DefaultJobLabels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return merged
+}
+
 // Convert a number of milliseconds since the Unix epoch to a time.Time.
 // Treat an input of zero specially: convert it to the zero time,
 // rather than the start of the epoch.
@@ -179,13 +341,7 @@ func runWithRetry(ctx context.Context, call func() error) error {
 }
 
 func runWithRetryExplicit(ctx context.Context, call func() error, allowedReasons []string) error {
-	// These parameters match the suggestions in https://cloud.google.com/bigquery/sla.
-	backoff := gax.Backoff{
-		Initial:    1 * time.Second,
-		Max:        32 * time.Second,
-		Multiplier: 2,
-	}
-	return internal.Retry(ctx, backoff, func() (stop bool, err error) {
+	return internal.Retry(ctx, defaultBackoff(), func() (stop bool, err error) {
 		err = call()
 		if err == nil {
 			return true, nil
@@ -194,6 +350,16 @@ func runWithRetryExplicit(ctx context.Context, call func() error, allowedReasons
 	})
 }
 
+// defaultBackoff returns the backoff of retried requests. These parameters
+// match the suggestions in https://cloud.google.com/bigquery/sla.
+func defaultBackoff() gax.Backoff {
+	return gax.Backoff{
+		Initial:    1 * time.Second,
+		Max:        32 * time.Second,
+		Multiplier: 2,
+	}
+}
+
 var (
 	defaultRetryReasons = []string{"backendError", "rateLimitExceeded"}
 	jobRetryReasons     = []string{"backendError", "rateLimitExceeded", "internalError"}
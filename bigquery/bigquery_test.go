@@ -21,6 +21,7 @@ import (
 	"net/url"
 	"testing"
 
+	"cloud.google.com/go/internal/testutil"
 	"golang.org/x/xerrors"
 	"google.golang.org/api/googleapi"
 )
@@ -125,3 +126,24 @@ func TestRetryableErrors(t *testing.T) {
 		}
 	}
 }
+
+func TestClientJobLabels(t *testing.T) {
+	labels := map[string]string{"team": "ads", "env": "prod"}
+	c := &Client{}
+	if got := c.jobLabels(labels); !testutil.Equal(got, labels) {
+		t.Errorf("no defaults: got %v, want %v", got, labels)
+	}
+
+	c.DefaultJobLabels = map[string]string{"team": "default", "cost-center": "42"}
+	got := c.jobLabels(labels)
+	want := map[string]string{"team": "ads", "env": "prod", "cost-center": "42"}
+	if !testutil.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok := labels["cost-center"]; ok {
+		t.Error("jobLabels modified the job's labels")
+	}
+	if got := c.jobLabels(nil); !testutil.Equal(got, c.DefaultJobLabels) {
+		t.Errorf("no job labels: got %v, want %v", got, c.DefaultJobLabels)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	gax "github.com/googleapis/gax-go/v2"
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/support/bundler"
+)
+
+// ErrOversizedRow indicates that a row is larger than
+// BufferedInserterSettings.ByteLimit, so it cannot be inserted.
+var ErrOversizedRow = bundler.ErrOversizedItem
+
+var errBufferedInserterStopped = errors.New("bigquery: BufferedInserter has been stopped")
+
+// BufferedInserterSettings control the batching and flow control of a
+// BufferedInserter.
+type BufferedInserterSettings struct {
+	// Send a non-empty batch after this delay has passed.
+	DelayThreshold time.Duration
+
+	// Send a batch when it has this many rows. This is also the maximum number
+	// of rows in a request.
+	CountThreshold int
+
+	// Send a batch when its size in bytes reaches this value.
+	ByteThreshold int
+
+	// The maximum size of a request, in bytes. Rows larger than this are
+	// rejected with ErrOversizedRow.
+	ByteLimit int
+
+	// The maximum number of insert requests that can be in flight at once.
+	NumGoroutines int
+
+	// The maximum number of bytes of rows that have been added to the inserter
+	// and whose insertion has not finished. Insert blocks while the limit is
+	// reached.
+	MaxOutstandingBytes int
+
+	// The maximum number of times a row that failed with a retryable error is
+	// retried. Rows are retried in the same batch, which is split from the rows
+	// that were inserted or failed permanently. A negative value disables
+	// retries.
+	MaxRowRetries int
+
+	// The maximum time spent inserting a batch, including retries.
+	Timeout time.Duration
+}
+
+// DefaultBufferedInserterSettings holds the default values for the
+// BufferedInserterSettings of an inserter. Fields of an inserter's settings
+// that are zero are also replaced by these values.
+var DefaultBufferedInserterSettings = BufferedInserterSettings{
+	DelayThreshold: 10 * time.Millisecond,
+	// BigQuery recommends at most 500 rows per request.
+	CountThreshold: 500,
+	ByteThreshold:  1e6,
+	// Requests are limited to 10 MB, including the request overhead.
+	ByteLimit:           9e6,
+	NumGoroutines:       10,
+	MaxOutstandingBytes: 100e6,
+	MaxRowRetries:       3,
+	Timeout:             60 * time.Second,
+}
+
+// retryableInsertReasons are the reasons of per-row insertion errors that are
+// retried by a BufferedInserter. Rows fail with reason "stopped" if another row
+// of the same request was invalid.
+var retryableInsertReasons = []string{"backendError", "internalError", "timeout", "stopped"}
+
+// A BufferedInserter inserts rows into a table in batches, like an Inserter.
+// It buffers rows, sends them in requests of bounded size from several
+// goroutines, and retries rows that fail with a retryable error. The result of
+// each row is reported by its InsertResult.
+//
+// Rows are retried with the same insert ID, so BigQuery can deduplicate them on
+// a best-effort basis, unless they opt out with NoDedupeID.
+//
+// It is safe for concurrent use. Call Stop to send buffered rows before the
+// program exits.
+type BufferedInserter struct {
+	// Settings are the batching and flow control settings of the inserter.
+	// They must not be changed after the first call to Insert.
+	Settings BufferedInserterSettings
+
+	u *Inserter
+
+	mu          sync.RWMutex
+	stopped     bool
+	bundlerOnce sync.Once
+	bundler     *bundler.Bundler
+}
+
+// bufferedRow is a row waiting to be inserted by a BufferedInserter.
+type bufferedRow struct {
+	row *bq.TableDataInsertAllRequestRows
+	res *InsertResult
+}
+
+// Buffered returns a BufferedInserter that inserts rows into the table of u,
+// with the options of u. The options of u must not be changed after the first
+// call to Insert.
+func (u *Inserter) Buffered() *BufferedInserter {
+	return &BufferedInserter{
+		Settings: DefaultBufferedInserterSettings,
+		u:        u,
+	}
+}
+
+// An InsertResult holds the result of inserting a row with a BufferedInserter.
+type InsertResult struct {
+	ready chan struct{}
+	err   error
+}
+
+func newInsertResult() *InsertResult {
+	return &InsertResult{ready: make(chan struct{})}
+}
+
+func (r *InsertResult) set(err error) {
+	r.err = err
+	close(r.ready)
+}
+
+// Ready returns a channel that is closed when the result is available.
+func (r *InsertResult) Ready() <-chan struct{} { return r.ready }
+
+// Get waits until the row has been inserted, or its insertion has failed, and
+// returns the error, if any. If the row was rejected by BigQuery, the error is
+// a *RowInsertionError. Get returns the context's error if ctx is done first.
+func (r *InsertResult) Get(ctx context.Context) error {
+	select {
+	case <-r.ready:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Insert adds a row to the inserter, and returns its result. The row is a
+// ValueSaver, a struct or a struct pointer, as for Inserter.Put.
+//
+// Insert blocks while the inserter has Settings.MaxOutstandingBytes of rows
+// waiting to be inserted, until space is available or ctx is done; ctx does not
+// affect the insertion of the row once Insert returns.
+func (b *BufferedInserter) Insert(ctx context.Context, row interface{}) *InsertResult {
+	r := newInsertResult()
+	saver, ok, err := toValueSaver(row)
+	if err == nil && !ok {
+		err = fmt.Errorf("bigquery: %T is not a ValueSaver, struct or struct pointer", row)
+	}
+	if err != nil {
+		r.set(err)
+		return r
+	}
+	bqRow, err := newInsertRow(saver)
+	if err != nil {
+		r.set(err)
+		return r
+	}
+	size, err := insertRowSize(bqRow)
+	if err != nil {
+		r.set(err)
+		return r
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.stopped {
+		r.set(errBufferedInserterStopped)
+		return r
+	}
+	if err := b.getBundler().AddWait(ctx, &bufferedRow{row: bqRow, res: r}, size); err != nil {
+		r.set(err)
+	}
+	return r
+}
+
+// insertRowSize returns the approximate size of a row in an insert request.
+func insertRowSize(row *bq.TableDataInsertAllRequestRows) (int, error) {
+	b, err := json.Marshal(row.Json)
+	if err != nil {
+		return 0, err
+	}
+	return len(b) + len(row.InsertId), nil
+}
+
+// Flush sends all buffered rows and waits until their insertion has finished.
+func (b *BufferedInserter) Flush() {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.getBundler().Flush()
+}
+
+// Stop sends all buffered rows and waits until their insertion has finished.
+// Rows inserted after Stop fail.
+func (b *BufferedInserter) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return
+	}
+	b.stopped = true
+	b.getBundler().Flush()
+}
+
+// getBundler returns the bundler of b, creating it on first use.
+func (b *BufferedInserter) getBundler() *bundler.Bundler {
+	b.bundlerOnce.Do(func() {
+		s := b.settings()
+		bu := bundler.NewBundler(&bufferedRow{}, func(rows interface{}) {
+			b.insertBatch(rows.([]*bufferedRow))
+		})
+		bu.DelayThreshold = s.DelayThreshold
+		bu.BundleCountThreshold = s.CountThreshold
+		bu.BundleByteThreshold = s.ByteThreshold
+		bu.BundleByteLimit = s.ByteLimit
+		bu.HandlerLimit = s.NumGoroutines
+		bu.BufferedByteLimit = s.MaxOutstandingBytes
+		b.bundler = bu
+	})
+	return b.bundler
+}
+
+// settings returns the settings of b, with zero fields replaced by defaults.
+func (b *BufferedInserter) settings() BufferedInserterSettings {
+	s := b.Settings
+	d := DefaultBufferedInserterSettings
+	if s.DelayThreshold == 0 {
+		s.DelayThreshold = d.DelayThreshold
+	}
+	if s.CountThreshold == 0 {
+		s.CountThreshold = d.CountThreshold
+	}
+	if s.ByteThreshold == 0 {
+		s.ByteThreshold = d.ByteThreshold
+	}
+	if s.ByteLimit == 0 {
+		s.ByteLimit = d.ByteLimit
+	}
+	if s.NumGoroutines == 0 {
+		s.NumGoroutines = d.NumGoroutines
+	}
+	if s.MaxOutstandingBytes == 0 {
+		s.MaxOutstandingBytes = d.MaxOutstandingBytes
+	}
+	if s.MaxRowRetries == 0 {
+		s.MaxRowRetries = d.MaxRowRetries
+	}
+	if s.Timeout == 0 {
+		s.Timeout = d.Timeout
+	}
+	return s
+}
+
+// insertBatch inserts a batch of rows, retrying the rows that fail with a
+// retryable error, and sets their results.
+func (b *BufferedInserter) insertBatch(rows []*bufferedRow) {
+	s := b.settings()
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+	var err error
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.BufferedInserter.insertBatch")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	backoff := newInsertRetryBackoff()
+	for attempt := 0; ; attempt++ {
+		req := b.u.newEmptyInsertRequest()
+		for _, r := range rows {
+			req.Rows = append(req.Rows, r.row)
+		}
+		var res *bq.TableDataInsertAllResponse
+		res, err = insertAll(ctx, b.u, req)
+		if err != nil {
+			for _, r := range rows {
+				r.res.set(err)
+			}
+			return
+		}
+		rowErrs := map[int]*RowInsertionError{}
+		if err := handleInsertErrors(res.InsertErrors, req.Rows); err != nil {
+			pme, ok := err.(PutMultiError)
+			if !ok {
+				for _, r := range rows {
+					r.res.set(err)
+				}
+				return
+			}
+			for i := range pme {
+				rowErrs[pme[i].RowIndex] = &pme[i]
+			}
+		}
+		var retry []*bufferedRow
+		for i, r := range rows {
+			rie, ok := rowErrs[i]
+			switch {
+			case !ok:
+				r.res.set(nil)
+			case attempt < s.MaxRowRetries && retryableRowInsertionError(rie):
+				retry = append(retry, r)
+			default:
+				r.res.set(rie)
+			}
+		}
+		if len(retry) == 0 {
+			return
+		}
+		if err = gax.Sleep(ctx, backoff.Pause()); err != nil {
+			for _, r := range retry {
+				r.res.set(err)
+			}
+			return
+		}
+		rows = retry
+	}
+}
+
+// newInsertRetryBackoff exists to aid testing.
+var newInsertRetryBackoff = func() gax.Backoff {
+	return gax.Backoff{
+		Initial:    1 * time.Second,
+		Max:        32 * time.Second,
+		Multiplier: 2,
+	}
+}
+
+// retryableRowInsertionError reports whether all errors of a row have a
+// retryable reason.
+func retryableRowInsertionError(rie *RowInsertionError) bool {
+	if len(rie.Errors) == 0 {
+		return false
+	}
+	for _, err := range rie.Errors {
+		e, ok := err.(*Error)
+		if !ok || !containsString(retryableInsertReasons, e.Reason) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	bq "google.golang.org/api/bigquery/v2"
+)
+
+// insertAllStub records insert requests, and answers them with its respond
+// function.
+type insertAllStub struct {
+	mu       sync.Mutex
+	requests [][]string // insert IDs of each request
+	respond  func(req *bq.TableDataInsertAllRequest) (*bq.TableDataInsertAllResponse, error)
+}
+
+func (s *insertAllStub) insertAll(_ context.Context, _ *Inserter, req *bq.TableDataInsertAllRequest) (*bq.TableDataInsertAllResponse, error) {
+	var ids []string
+	for _, r := range req.Rows {
+		ids = append(ids, r.InsertId)
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, ids)
+	s.mu.Unlock()
+	if s.respond == nil {
+		return &bq.TableDataInsertAllResponse{}, nil
+	}
+	return s.respond(req)
+}
+
+// withInsertAllStub replaces insertAll with s, and retries without waiting, for
+// the duration of a test.
+func withInsertAllStub(t *testing.T, s *insertAllStub) {
+	oldInsertAll, oldBackoff := insertAll, newInsertRetryBackoff
+	insertAll = s.insertAll // cannot use t.Parallel with this test
+	newInsertRetryBackoff = func() gax.Backoff { return gax.Backoff{Initial: time.Nanosecond, Max: time.Nanosecond} }
+	t.Cleanup(func() { insertAll, newInsertRetryBackoff = oldInsertAll, oldBackoff })
+}
+
+func newTestBufferedInserter() *BufferedInserter {
+	c := &Client{projectID: "p"}
+	b := c.Dataset("d").Table("t").Inserter().Buffered()
+	b.Settings.CountThreshold = 2
+	b.Settings.DelayThreshold = time.Hour
+	return b
+}
+
+func testRow(id string) ValueSaver {
+	return testSaver{row: map[string]Value{"name": id}, insertID: id}
+}
+
+func TestBufferedInserterBatches(t *testing.T) {
+	stub := &insertAllStub{}
+	withInsertAllStub(t, stub)
+
+	b := newTestBufferedInserter()
+	b.Settings.NumGoroutines = 1
+	var results []*InsertResult
+	for i := 0; i < 5; i++ {
+		results = append(results, b.Insert(context.Background(), testRow(strconv.Itoa(i))))
+	}
+	b.Stop()
+	for i, r := range results {
+		if err := r.Get(context.Background()); err != nil {
+			t.Errorf("row %d: %v", i, err)
+		}
+	}
+	got := stub.requests
+	want := [][]string{{"0", "1"}, {"2", "3"}, {"4"}}
+	if len(got) != len(want) {
+		t.Fatalf("got requests %v, want %v", got, want)
+	}
+	for i := range want {
+		if strings.Join(got[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("request %d: got rows %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if err := b.Insert(context.Background(), testRow("late")).Get(context.Background()); err != errBufferedInserterStopped {
+		t.Errorf("after Stop: got %v, want %v", err, errBufferedInserterStopped)
+	}
+}
+
+func TestBufferedInserterRetries(t *testing.T) {
+	// Row "bad" is invalid, which stops the other rows of its request, and
+	// row "flaky" always fails with a backend error.
+	stub := &insertAllStub{
+		respond: func(req *bq.TableDataInsertAllRequest) (*bq.TableDataInsertAllResponse, error) {
+			res := &bq.TableDataInsertAllResponse{}
+			var invalid bool
+			for _, r := range req.Rows {
+				invalid = invalid || r.InsertId == "bad"
+			}
+			for i, r := range req.Rows {
+				var reason string
+				switch {
+				case r.InsertId == "bad":
+					reason = "invalid"
+				case r.InsertId == "flaky":
+					reason = "backendError"
+				case invalid:
+					reason = "stopped"
+				default:
+					continue
+				}
+				res.InsertErrors = append(res.InsertErrors, &bq.TableDataInsertAllResponseInsertErrors{
+					Index:  int64(i),
+					Errors: []*bq.ErrorProto{{Reason: reason}},
+				})
+			}
+			return res, nil
+		},
+	}
+	withInsertAllStub(t, stub)
+
+	b := newTestBufferedInserter()
+	b.Settings.CountThreshold = 3
+	b.Settings.MaxRowRetries = 2
+	ctx := context.Background()
+	good, bad, flaky := b.Insert(ctx, testRow("good")), b.Insert(ctx, testRow("bad")), b.Insert(ctx, testRow("flaky"))
+	b.Flush()
+
+	if err := good.Get(ctx); err != nil {
+		t.Errorf("good row: %v", err)
+	}
+	var rie *RowInsertionError
+	if err := bad.Get(ctx); !errors.As(err, &rie) || rie.InsertID != "bad" {
+		t.Errorf("bad row: got %v, want RowInsertionError", err)
+	}
+	if err := flaky.Get(ctx); !errors.As(err, &rie) || rie.InsertID != "flaky" {
+		t.Errorf("flaky row: got %v, want RowInsertionError", err)
+	}
+	// The first request has all rows. "good" is retried once, and "flaky"
+	// MaxRowRetries times.
+	want := []string{"good,bad,flaky", "good,flaky", "flaky"}
+	if len(stub.requests) != len(want) {
+		t.Fatalf("got requests %v, want %v", stub.requests, want)
+	}
+	for i := range want {
+		if got := strings.Join(stub.requests[i], ","); got != want[i] {
+			t.Errorf("request %d: got rows %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestBufferedInserterErrors(t *testing.T) {
+	reqErr := errors.New("request failed")
+	stub := &insertAllStub{
+		respond: func(*bq.TableDataInsertAllRequest) (*bq.TableDataInsertAllResponse, error) {
+			return nil, reqErr
+		},
+	}
+	withInsertAllStub(t, stub)
+
+	b := newTestBufferedInserter()
+	b.Settings.ByteLimit = 100
+	ctx := context.Background()
+	r1, r2 := b.Insert(ctx, testRow("1")), b.Insert(ctx, testRow("2"))
+	for _, r := range []*InsertResult{r1, r2} {
+		if err := r.Get(ctx); err != reqErr {
+			t.Errorf("got %v, want %v", err, reqErr)
+		}
+	}
+
+	big := testSaver{row: map[string]Value{"name": strings.Repeat("x", 100)}, insertID: "big"}
+	if err := b.Insert(ctx, big).Get(ctx); err != ErrOversizedRow {
+		t.Errorf("oversized row: got %v, want %v", err, ErrOversizedRow)
+	}
+	if err := b.Insert(ctx, 7).Get(ctx); err == nil {
+		t.Error("bad row type: got nil, want error")
+	}
+	saveErr := errors.New("save failed")
+	if err := b.Insert(ctx, testSaver{err: saveErr}).Get(ctx); err != saveErr {
+		t.Errorf("Save error: got %v, want %v", err, saveErr)
+	}
+	b.Stop()
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+)
+
+// A RowIteratorCheckpoint records the source of a RowIterator and the position
+// of the next row that it returns, so that another RowIterator, possibly in
+// another process, can continue reading from there with
+// Client.ResumeRowIterator. It can be encoded with encoding/json.
+//
+// The rows of a query are read from the query's job, so a checkpoint of them
+// can be resumed until BigQuery deletes the job's results, about a day after
+// the query ran. To split the rows among several workers, give each a copy of
+// a checkpoint with a different StartIndex and no PageToken, and have it read
+// the number of rows it was assigned.
+type RowIteratorCheckpoint struct {
+	// ProjectID is the project of the job or table.
+	ProjectID string `json:"projectId"`
+
+	// JobID and Location identify the job whose results are read.
+	JobID    string `json:"jobId,omitempty"`
+	Location string `json:"location,omitempty"`
+
+	// DatasetID and TableID identify the table that is read, if JobID is
+	// empty.
+	DatasetID string `json:"datasetId,omitempty"`
+	TableID   string `json:"tableId,omitempty"`
+
+	// StartIndex is the index of the next row in the results.
+	StartIndex uint64 `json:"startIndex,string"`
+
+	// PageToken, if not empty, is the token of the page that starts at
+	// StartIndex. Reading from a page token is faster than reading from an
+	// index.
+	PageToken string `json:"pageToken,omitempty"`
+}
+
+// Checkpoint returns a checkpoint of the iterator, which records where the
+// next call to Next continues reading.
+//
+// An iterator that reads with the Storage Read API (see Client.StorageRead) has
+// no checkpoints, as it reads rows out of order. Neither has one whose
+// PageInfo().Token was set to a token that did not come from a checkpoint.
+func (it *RowIterator) Checkpoint() (*RowIteratorCheckpoint, error) {
+	if it.unordered {
+		return nil, errors.New("bigquery: cannot checkpoint a RowIterator that reads with the Storage Read API")
+	}
+	cp := &RowIteratorCheckpoint{}
+	switch {
+	case it.src != nil && it.src.j != nil:
+		cp.ProjectID, cp.JobID, cp.Location = it.src.j.projectID, it.src.j.jobID, it.src.j.location
+	case it.src != nil && it.src.t != nil:
+		cp.ProjectID, cp.DatasetID, cp.TableID = it.src.t.ProjectID, it.src.t.DatasetID, it.src.t.TableID
+	default:
+		return nil, errors.New("bigquery: RowIterator has no job or table to checkpoint")
+	}
+	index, known := it.index, it.indexKnown
+	if !it.fetched && it.pageInfo.Token == "" {
+		// Iteration will start at StartIndex.
+		index, known = it.StartIndex, true
+	}
+	if !known {
+		return nil, errors.New("bigquery: cannot checkpoint a RowIterator that starts from a page token")
+	}
+	cp.StartIndex = index
+	if len(it.rows) == 0 {
+		// The next row is the first of the next page.
+		cp.PageToken = it.pageInfo.Token
+	}
+	return cp, nil
+}
+
+// ResumeRowIterator returns a RowIterator that continues reading at the
+// position recorded by a checkpoint. Rows are not read with the Storage Read
+// API, so that the iterator has checkpoints too.
+func (c *Client) ResumeRowIterator(ctx context.Context, cp *RowIteratorCheckpoint) (*RowIterator, error) {
+	return c.resumeRowIterator(ctx, cp, fetchPage)
+}
+
+func (c *Client) resumeRowIterator(ctx context.Context, cp *RowIteratorCheckpoint, pf pageFetcher) (*RowIterator, error) {
+	if cp == nil || cp.ProjectID == "" {
+		return nil, errors.New("bigquery: checkpoint has no project")
+	}
+	src := &rowSource{}
+	switch {
+	case cp.JobID != "":
+		src.j = &Job{c: c, projectID: cp.ProjectID, jobID: cp.JobID, location: cp.Location}
+	case cp.DatasetID != "" && cp.TableID != "":
+		src.t = &Table{ProjectID: cp.ProjectID, DatasetID: cp.DatasetID, TableID: cp.TableID, c: c}
+	default:
+		return nil, errors.New("bigquery: checkpoint has no job or table")
+	}
+	it := newRowIterator(ctx, src, pf)
+	it.StartIndex = cp.StartIndex
+	it.pageInfo.Token = cp.PageToken
+	it.index, it.indexKnown = cp.StartIndex, true
+	return it, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+	"google.golang.org/api/iterator"
+)
+
+// indexedPageFetcher serves pages of three of the numbers 0 to 9, starting at
+// the index or page token requested. Page tokens are "p" and the index of the
+// page's first row.
+func indexedPageFetcher(ctx context.Context, _ *rowSource, _ Schema, startIndex uint64, _ int64, pageToken string) (*fetchPageResult, error) {
+	const total, pageSize = 10, 3
+	start := int(startIndex)
+	if pageToken != "" {
+		if _, err := fmt.Sscanf(pageToken, "p%d", &start); err != nil {
+			return nil, err
+		}
+	}
+	res := &fetchPageResult{totalRows: total, schema: Schema{{Name: "n", Type: IntegerFieldType}}}
+	for i := start; i < start+pageSize && i < total; i++ {
+		res.rows = append(res.rows, []Value{int64(i)})
+	}
+	if start+pageSize < total {
+		res.pageToken = fmt.Sprintf("p%d", start+pageSize)
+	}
+	return res, nil
+}
+
+// readNumbers reads up to n rows of indexedPageFetcher from the iterator; all
+// of them if n is negative.
+func readNumbers(t *testing.T, it *RowIterator, n int) []int64 {
+	var got []int64
+	for i := 0; n < 0 || i < n; i++ {
+		var row []Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row[0].(int64))
+	}
+	return got
+}
+
+func TestRowIteratorCheckpoint(t *testing.T) {
+	c := &Client{projectID: "p"}
+	job := &Job{c: c, projectID: "p", jobID: "j", location: "EU"}
+	for _, test := range []struct {
+		read       int
+		wantCP     RowIteratorCheckpoint
+		wantResume []int64
+	}{
+		{0, RowIteratorCheckpoint{StartIndex: 0}, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{2, RowIteratorCheckpoint{StartIndex: 2}, []int64{2, 3, 4, 5, 6, 7, 8, 9}},
+		{3, RowIteratorCheckpoint{StartIndex: 3, PageToken: "p3"}, []int64{3, 4, 5, 6, 7, 8, 9}},
+		{7, RowIteratorCheckpoint{StartIndex: 7}, []int64{7, 8, 9}},
+		{-1, RowIteratorCheckpoint{StartIndex: 10}, nil},
+	} {
+		it := newRowIterator(context.Background(), &rowSource{j: job}, indexedPageFetcher)
+		readNumbers(t, it, test.read)
+		cp, err := it.Checkpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := test.wantCP
+		want.ProjectID, want.JobID, want.Location = "p", "j", "EU"
+		if diff := testutil.Diff(*cp, want); diff != "" {
+			t.Errorf("after %d rows: got=-, want=+:\n%s", test.read, diff)
+		}
+
+		// Resume in "another process".
+		b, err := json.Marshal(cp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cp2 RowIteratorCheckpoint
+		if err := json.Unmarshal(b, &cp2); err != nil {
+			t.Fatal(err)
+		}
+		it2, err := c.resumeRowIterator(context.Background(), &cp2, indexedPageFetcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if it2.src.j == nil || it2.src.j.jobID != "j" || it2.src.j.location != "EU" {
+			t.Errorf("after %d rows: resumed source %+v", test.read, it2.src.j)
+		}
+		if diff := testutil.Diff(readNumbers(t, it2, -1), test.wantResume); diff != "" {
+			t.Errorf("after %d rows: resumed rows: got=-, want=+:\n%s", test.read, diff)
+		}
+	}
+}
+
+func TestRowIteratorCheckpointOfResumed(t *testing.T) {
+	c := &Client{projectID: "p"}
+	cp := &RowIteratorCheckpoint{ProjectID: "p", JobID: "j", StartIndex: 3, PageToken: "p3"}
+	it, err := c.resumeRowIterator(context.Background(), cp, indexedPageFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp2, err := it.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cp2 != *cp {
+		t.Errorf("before reading: got %+v, want %+v", cp2, cp)
+	}
+	readNumbers(t, it, 4)
+	cp2, err = it.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (RowIteratorCheckpoint{ProjectID: "p", JobID: "j", StartIndex: 7}); *cp2 != want {
+		t.Errorf("after reading: got %+v, want %+v", cp2, want)
+	}
+}
+
+func TestRowIteratorCheckpointSplit(t *testing.T) {
+	c := &Client{projectID: "p"}
+	table := c.Dataset("d").Table("t")
+	it := table.read(context.Background(), indexedPageFetcher)
+	cp, err := it.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (RowIteratorCheckpoint{ProjectID: "p", DatasetID: "d", TableID: "t"}); *cp != want {
+		t.Fatalf("got %+v, want %+v", cp, want)
+	}
+	var got []int64
+	for start := 0; start < 10; start += 4 {
+		part := *cp
+		part.StartIndex = uint64(start)
+		it, err := c.resumeRowIterator(context.Background(), &part, indexedPageFetcher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if it.src.t == nil || it.src.t.TableID != "t" {
+			t.Fatalf("resumed source %+v", it.src)
+		}
+		got = append(got, readNumbers(t, it, 4)...)
+	}
+	if diff := testutil.Diff(got, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
+
+func TestRowIteratorCheckpointErrors(t *testing.T) {
+	c := &Client{projectID: "p"}
+	job := &Job{c: c, projectID: "p", jobID: "j"}
+
+	it := newRowIterator(context.Background(), &rowSource{j: job}, indexedPageFetcher)
+	it.PageInfo().Token = "p3"
+	readNumbers(t, it, 1)
+	if _, err := it.Checkpoint(); err == nil {
+		t.Error("page token: got nil, want error")
+	}
+
+	it = newRowIterator(context.Background(), &rowSource{j: job}, func(ctx context.Context, src *rowSource, schema Schema, startIndex uint64, pageSize int64, pageToken string) (*fetchPageResult, error) {
+		return &fetchPageResult{rows: [][]Value{{int64(1)}}, pageToken: storageReadPageToken}, nil
+	})
+	readNumbers(t, it, 1)
+	if _, err := it.Checkpoint(); err == nil {
+		t.Error("Storage Read API: got nil, want error")
+	}
+
+	if _, err := newRowIterator(context.Background(), nil, indexedPageFetcher).Checkpoint(); err == nil {
+		t.Error("no source: got nil, want error")
+	}
+
+	for _, cp := range []*RowIteratorCheckpoint{nil, {}, {ProjectID: "p"}, {ProjectID: "p", DatasetID: "d"}} {
+		if _, err := c.ResumeRowIterator(context.Background(), cp); err == nil {
+			t.Errorf("%+v: got nil, want error", cp)
+		}
+	}
+}
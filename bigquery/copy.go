@@ -16,7 +16,9 @@ package bigquery
 
 import (
 	"context"
+	"time"
 
+	"cloud.google.com/go/internal/trace"
 	bq "google.golang.org/api/bigquery/v2"
 )
 
@@ -31,6 +33,9 @@ var (
 	SnapshotOperation TableCopyOperationType = "SNAPSHOT"
 	// RestoreOperation indicates creating/restoring a table from a snapshot.
 	RestoreOperation TableCopyOperationType = "RESTORE"
+	// CloneOperation indicates creating a table clone, a writable copy that
+	// is billed only for the data that differs from its base table.
+	CloneOperation TableCopyOperationType = "CLONE"
 )
 
 // CopyConfig holds the configuration for a copy job.
@@ -49,15 +54,21 @@ type CopyConfig struct {
 	// The default is WriteEmpty.
 	WriteDisposition TableWriteDisposition
 
-	// The labels associated with this job.
+	// The labels associated with this job. The client's DefaultJobLabels are
+	// added to them.
 	Labels map[string]string
 
 	// Custom encryption configuration (e.g., Cloud KMS keys).
 	DestinationEncryptionConfig *EncryptionConfig
 
 	// One of the supported operation types when executing a Table Copy jobs.  By default this
-	// copies tables, but can also be set to perform snapshot or restore operations.
+	// copies tables, but can also be set to perform snapshot, restore or clone operations.
 	OperationType TableCopyOperationType
+
+	// DestinationExpirationTime is the time when the destination table
+	// expires. It is typically set for snapshots. The default is the zero
+	// time, which sets no expiration.
+	DestinationExpirationTime time.Time
 }
 
 func (c *CopyConfig) toBQ() *bq.JobConfiguration {
@@ -65,7 +76,7 @@ func (c *CopyConfig) toBQ() *bq.JobConfiguration {
 	for _, t := range c.Srcs {
 		ts = append(ts, t.toBQ())
 	}
-	return &bq.JobConfiguration{
+	conf := &bq.JobConfiguration{
 		Labels: c.Labels,
 		Copy: &bq.JobConfigurationTableCopy{
 			CreateDisposition:                  string(c.CreateDisposition),
@@ -76,6 +87,10 @@ func (c *CopyConfig) toBQ() *bq.JobConfiguration {
 			OperationType:                      string(c.OperationType),
 		},
 	}
+	if !c.DestinationExpirationTime.IsZero() {
+		conf.Copy.DestinationExpirationTime = c.DestinationExpirationTime.UTC().Format(time.RFC3339Nano)
+	}
+	return conf
 }
 
 func bqToCopyConfig(q *bq.JobConfiguration, c *Client) *CopyConfig {
@@ -90,6 +105,12 @@ func bqToCopyConfig(q *bq.JobConfiguration, c *Client) *CopyConfig {
 	for _, t := range q.Copy.SourceTables {
 		cc.Srcs = append(cc.Srcs, bqToTable(t, c))
 	}
+	if s, ok := q.Copy.DestinationExpirationTime.(string); ok {
+		// A time that fails to parse is left unset.
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			cc.DestinationExpirationTime = t
+		}
+	}
 	return cc
 }
 
@@ -115,7 +136,7 @@ func (t *Table) CopierFrom(srcs ...*Table) *Copier {
 
 // Run initiates a copy job.
 func (c *Copier) Run(ctx context.Context) (*Job, error) {
-	return c.c.insertJob(ctx, c.newJob(), nil)
+	return c.c.insertJob(ctx, c.newJob(), nil, nil)
 }
 
 func (c *Copier) newJob() *bq.Job {
@@ -124,3 +145,44 @@ func (c *Copier) newJob() *bq.Job {
 		Configuration: c.CopyConfig.toBQ(),
 	}
 }
+
+// CreateSnapshot starts a copy job that creates target as a snapshot of t, a
+// read-only copy of its data that is billed only for the data that differs
+// from t. The snapshot expires at expiration; if expiration is the zero
+// time, it never expires. To snapshot t as of an earlier time, use a table
+// whose TableID has a snapshot decorator, like "mytable@1640995200000".
+//
+// To set other options, use t.CopierFrom with the SnapshotOperation.
+func (t *Table) CreateSnapshot(ctx context.Context, target *Table, expiration time.Time) (j *Job, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.CreateSnapshot")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	c := target.CopierFrom(t)
+	c.OperationType = SnapshotOperation
+	c.DestinationExpirationTime = expiration
+	return c.Run(ctx)
+}
+
+// RestoreSnapshot starts a copy job that restores the snapshot t into target,
+// which becomes a regular, writable table. By default, target must not exist;
+// to overwrite it, use target.CopierFrom with the RestoreOperation and
+// WriteTruncate.
+func (t *Table) RestoreSnapshot(ctx context.Context, target *Table) (j *Job, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.RestoreSnapshot")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	c := target.CopierFrom(t)
+	c.OperationType = RestoreOperation
+	return c.Run(ctx)
+}
+
+// Clone starts a copy job that creates target as a clone of t, a writable
+// copy that is billed only for the data that differs from t.
+func (t *Table) Clone(ctx context.Context, target *Table) (j *Job, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.Clone")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	c := target.CopierFrom(t)
+	c.OperationType = CloneOperation
+	return c.Run(ctx)
+}
@@ -32,6 +32,13 @@ To start working with this package, create a client:
         // TODO: Handle error.
     }
 
+To run tests without BigQuery, start an emulator such as bigquery-emulator, and
+set the BIGQUERY_EMULATOR_HOST environment variable to its address, or pass it
+to NewClient with WithEmulator. The client then sends all of its requests to the
+emulator, without authentication:
+
+    client, err = bigquery.NewClient(ctx, "test-project", bigquery.WithEmulator("localhost:9050"))
+
 Querying
 
 To query existing tables, create a Query and call its Read method:
@@ -82,6 +89,46 @@ You can also use a struct whose exported fields match the query:
         fmt.Println(c)
     }
 
+With Go 1.21 or later, Iterate wraps the iterator in a type-safe Rows iterator
+that returns each row as a struct:
+
+    rows := bigquery.Iterate[Count](q.Read(ctx))
+    for {
+        c, err := rows.Next()
+        if err == iterator.Done {
+            break
+        }
+        if err != nil {
+            // TODO: Handle error.
+        }
+        fmt.Println(c)
+    }
+
+To check a query and estimate its cost without running it, call Query.Estimate,
+which performs a dry run:
+
+    e, err := q.Estimate(ctx)
+    if err != nil {
+        // TODO: Handle error.
+    }
+    fmt.Println(e.TotalBytesProcessed, e.OnDemandCost(bigquery.DefaultOnDemandPricePerTiB))
+
+After reading a query's results, RowIterator.QueryStatistics reports whether
+they came from the query cache, the slot time the query used and whether BI
+Engine accelerated it. Set RequireCacheHit to make a query fail, without
+charge, unless its results are cached:
+
+    q.RequireCacheHit = true
+    it, err = q.Read(ctx)
+    if err != nil {
+        // TODO: Handle error.
+    }
+    qs, err := it.QueryStatistics(ctx)
+    if err != nil {
+        // TODO: Handle error.
+    }
+    fmt.Println(qs.CacheHit, qs.SlotMillis)
+
 You can also start the query running and get the results later.
 Create the query as above, but call Run instead of Read. This returns a Job,
 which represents an asynchronous operation.
@@ -113,6 +160,68 @@ Query.Read is just a convenience method that combines Query.Run and Job.Read.
     }
     // Proceed with iteration as above.
 
+To download large results faster, set the client's StorageRead field. Job.Read,
+Query.Read and Table.Read then read results with many rows from several streams
+of the BigQuery Storage Read API, and other results as usual:
+
+    client.StorageRead = &bigquery.StorageReadConfig{MaxStreams: 8}
+
+To stop reading results and continue later, perhaps in another process, call
+RowIterator.Checkpoint. The checkpoint records the job or table and the position of
+the next row, and can be encoded as JSON. Client.ResumeRowIterator returns an
+iterator that continues from a checkpoint. Workers can also split the rows of a
+result by resuming from copies of a checkpoint with different StartIndexes:
+
+    cp, err := it.Checkpoint()
+    if err != nil {
+        // TODO: Handle error.
+    }
+    // Later, or in another process:
+    it, err = client.ResumeRowIterator(ctx, cp)
+    if err != nil {
+        // TODO: Handle error.
+    }
+
+Instead of calling Next, you can call RowIterator.ArrowRecords to read a result as
+Apache Arrow records, and write them to an io.Writer in the Arrow IPC streaming
+format or as a Parquet file for dataframe libraries.
+
+To attribute the cost of all the jobs a client runs, set its DefaultJobLabels,
+which are added to the labels of every query, load, copy and extract job. The
+client's DefaultQueryPriority and DefaultJobCreationMode apply to queries that
+do not set their own Priority and JobCreationMode:
+
+    client.DefaultJobLabels = map[string]string{"team": "analytics"}
+    client.DefaultQueryPriority = bigquery.BatchPriority
+
+Priority applies only to queries: the BigQuery API schedules load, copy and
+extract jobs without one. Jobs cannot select a reservation either. They run in
+the reservation assigned to their project, folder or organization for their
+job type, which you can manage with the Assignment methods of
+cloud.google.com/go/bigquery/reservation/apiv1.
+
+To run several queries in a session, which share temporary tables and
+variables, create a Session and create the queries with its Query method.
+Session.RunInTransaction runs queries of the session in a multi-statement
+transaction, which is committed only if all of them succeed:
+
+    session, err := client.CreateSession(ctx)
+    if err != nil {
+        // TODO: Handle error.
+    }
+    defer session.Abort(ctx)
+    err = session.RunInTransaction(ctx, func(ctx context.Context) error {
+        job, err := session.Query("DELETE mydataset.t1 WHERE num < 0").Run(ctx)
+        if err != nil {
+            return err
+        }
+        status, err := job.Wait(ctx)
+        if err != nil {
+            return err
+        }
+        return status.Err()
+    })
+
 Datasets and Tables
 
 You can refer to datasets in the client's project with the Dataset method, and
@@ -143,6 +252,33 @@ For instance, you could create a temporary table with:
         // TODO: Handle error.
     }
 
+To restrict the rows of a table that some principals can read, create row access
+policies on it. Grantees of a policy can read only the rows that match its filter:
+
+    err = table.RowAccessPolicy("us_only").Create(ctx, &bigquery.RowAccessPolicyMetadata{
+        FilterPredicate: `region = "US"`,
+        Grantees:        []string{"group:sales-us@example.com"},
+    })
+    if err != nil {
+        // TODO: Handle error.
+    }
+
+A materialized view is a table whose TableMetadata has a MaterializedView definition.
+Table.RefreshMaterializedView refreshes a materialized view immediately.
+
+Table.CreateSearchIndex and Table.CreateVectorIndex create indexes for the SEARCH and
+VECTOR_SEARCH functions, and Table.SearchIndexes and Table.VectorIndexes report how
+much of the table they cover.
+
+To add or remove labels on many datasets and tables at once, call Client.UpdateLabels.
+It updates the resources concurrently and reports the ones that failed.
+
+To manage the partitions of a partitioned table, call Table.Partitions to list them
+with their row counts and last-modified times, and Table.DeletePartitions to delete
+some of them. Table.Partition returns a single partition, which can be the
+destination of a load, copy or query. Client.UpdatePartitionExpiration sets the
+partition expiration of many tables at once.
+
 We'll see how to create a table with a schema in the next section.
 
 Schemas
@@ -174,7 +310,14 @@ change names, ignore fields, or mark a field as nullable (non-required). Fields
 declared as one of the Null types (NullInt64, NullFloat64, NullString, NullBool,
 NullTimestamp, NullDate, NullTime, NullDateTime, and NullGeography) are
 automatically inferred as nullable, so the "nullable" tag is only needed for []byte,
-*big.Rat and pointer-to-struct fields.
+*big.Rat, json.RawMessage, *IntervalValue, *Geography and pointer-to-struct fields.
+The "json" option marks a string field as holding JSON data.
+
+GEOGRAPHY values are represented by the Geography type, which holds their
+Well-Known Text and can be converted to and from GeoJSON with its GeoJSON method
+and GeographyFromGeoJSON. Use it for GEOGRAPHY fields of structs and for
+GEOGRAPHY query parameters. Rows read into a []Value hold GEOGRAPHY values as
+strings.
 
     type student2 struct {
         Name     string `bigquery:"full_name"`
@@ -213,6 +356,15 @@ You can chain the call to Run if you don't want to set options:
         // TODO: Handle error.
     }
 
+To back up a table, create a snapshot of it with Table.CreateSnapshot, and restore it
+later with Table.RestoreSnapshot. Table.Clone creates a writable copy of a table. Each
+of these starts a copy job:
+
+    job, err = myDataset.Table("src").CreateSnapshot(ctx, myDataset.Table("backup"), time.Time{})
+    if err != nil {
+        // TODO: Handle error.
+    }
+
 You can wait for your job to complete:
 
     status, err := job.Wait(ctx)
@@ -252,6 +404,19 @@ it as well, and call its Run method.
     job, err = loader.Run(ctx)
     // Poll the job for completion if desired, as above.
 
+To load data from your program, such as a local file, use a ReaderSource instead of a
+GCSReference. The data is streamed to BigQuery in chunks, which are retried if they
+fail, so large files are not held in memory:
+
+    f, err := os.Open("data.csv")
+    if err != nil {
+        // TODO: Handle error.
+    }
+    rs := bigquery.NewReaderSource(f)
+    rs.ChunkSize = 64 << 20
+    rs.ProgressFunc = func(n int64) { fmt.Printf("uploaded %d bytes\n", n) }
+    job, err = myDataset.Table("dest").LoaderFrom(rs).Run(ctx)
+
 To upload, first define a type that implements the ValueSaver interface, which has a single method named Save.
 Then create an Inserter, and call its Put method with a slice of values.
 
@@ -288,6 +453,33 @@ directly and the schema will be inferred:
 BigQuery allows for higher throughput when omitting insertion IDs.  To enable this,
 specify the sentinel `NoDedupeID` value for the insertion ID when implementing a ValueSaver.
 
+To insert rows one at a time from many goroutines, use a BufferedInserter, which
+batches rows into requests, limits the memory and concurrency it uses, and retries
+rows that fail with a temporary error. Each call to Insert returns an InsertResult:
+
+    bi := table.Inserter().Buffered()
+    res := bi.Insert(ctx, &Item{Name: "n4", Size: 12, Count: 3})
+    if err := res.Get(ctx); err != nil {
+        // TODO: Handle error.
+    }
+    bi.Stop()
+
+For higher throughput and exactly-once writes, use a StorageInserter, which writes the same
+values with the BigQuery Storage Write API. In PendingStreamMode, rows become visible
+atomically when Commit is called:
+
+    si, err := table.StorageInserter(ctx, bigquery.PendingStreamMode)
+    if err != nil {
+        // TODO: Handle error.
+    }
+    defer si.Close()
+    if err := si.Put(ctx, items2); err != nil {
+        // TODO: Handle error.
+    }
+    if _, err := si.Commit(ctx); err != nil {
+        // TODO: Handle error.
+    }
+
 Extracting
 
 If you've been following so far, extracting data from a BigQuery table
@@ -299,6 +491,19 @@ Extractor, then optionally configure it, and lastly call its Run method.
     job, err = extractor.Run(ctx)
     // Poll the job for completion if desired, as above.
 
+To shard a large extract across several objects, use a destination URI with a
+'*' wildcard. Extractor.Extract runs the extract, waits for it, and returns the
+objects it wrote and their sizes, so you need not list the bucket to find them:
+
+    gcsRef = bigquery.NewGCSReference("gs://my-bucket/export/part-*.csv")
+    files, err := table.ExtractorTo(gcsRef).Extract(ctx)
+    if err != nil {
+        // TODO: Handle error.
+    }
+    for _, f := range files {
+        fmt.Println(f.URI, f.Size)
+    }
+
 Errors
 
 Errors returned by this client are often of the type googleapi.Error: https://godoc.org/google.golang.org/api/googleapi#Error
@@ -0,0 +1,84 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"google.golang.org/api/option"
+)
+
+// emulatorHostEnv is the environment variable with the address of an
+// emulator, used if WithEmulator is not given.
+const emulatorHostEnv = "BIGQUERY_EMULATOR_HOST"
+
+var errStorageAPIEmulator = errors.New("bigquery: the BigQuery Storage APIs are not supported with an emulator")
+
+// emulatorHost returns the address of the emulator given by config or the
+// environment, or "" if there is none.
+func emulatorHost(config *clientConfig) string {
+	if config.emulatorHost != "" {
+		return config.emulatorHost
+	}
+	return os.Getenv(emulatorHostEnv)
+}
+
+// parseEmulatorHost parses the address of an emulator, as given to
+// WithEmulator or in BIGQUERY_EMULATOR_HOST, and returns its scheme and host.
+// The scheme defaults to http.
+func parseEmulatorHost(host string) (*url.URL, error) {
+	if !strings.Contains(host, "://") {
+		return &url.URL{Scheme: "http", Host: host}, nil
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+// emulatorOptions returns the ClientOptions that send the requests of a
+// client to the emulator, without authentication.
+func emulatorOptions(emulator *url.URL) []option.ClientOption {
+	// The generated client resolves the paths of API requests relative to
+	// the endpoint, and those of uploads relative to its host, so the
+	// endpoint needs the API path.
+	endpoint := &url.URL{Scheme: emulator.Scheme, Host: emulator.Host, Path: "/bigquery/v2/"}
+	hc := &http.Client{Transport: &emulatorTransport{base: http.DefaultTransport, emulator: emulator}}
+	return []option.ClientOption{option.WithEndpoint(endpoint.String()), option.WithHTTPClient(hc)}
+}
+
+// emulatorTransport redirects resumable upload requests to an emulator.
+// Emulators may return upload session URIs with the address they were
+// started with, such as one inside a container, rather than the one the
+// client uses.
+type emulatorTransport struct {
+	base     http.RoundTripper
+	emulator *url.URL
+}
+
+func (t *emulatorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.URL.Path, "/upload/bigquery/v2/") && req.URL.Host != t.emulator.Host {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = t.emulator.Scheme
+		req.URL.Host = t.emulator.Host
+		req.Host = ""
+	}
+	return t.base.RoundTrip(req)
+}
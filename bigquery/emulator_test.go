@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// fakeEmulator serves the requests of a client that uses an emulator, and
+// returns upload session URIs for another address, like an emulator in a
+// container.
+type fakeEmulator struct {
+	mu       sync.Mutex
+	requests []string
+	uploaded int
+	authed   bool
+}
+
+func (e *fakeEmulator) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	e.mu.Lock()
+	e.requests = append(e.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "" {
+		e.authed = true
+	}
+	e.mu.Unlock()
+	const job = `{"jobReference": {"projectId": "p", "jobId": "j"}, "configuration": {"load": {}}, "status": {"state": "DONE"}}`
+	switch {
+	case r.URL.Query().Get("upload_id") == "u":
+		e.mu.Lock()
+		e.uploaded += len(body)
+		e.mu.Unlock()
+		if rng := r.Header.Get("Content-Range"); strings.HasSuffix(rng, "/*") {
+			end := rng[strings.Index(rng, "-")+1 : strings.Index(rng, "/")]
+			w.Header().Set("Range", "bytes=0-"+end)
+			w.Header().Set("X-Http-Status-Code-Override", "308")
+			return
+		}
+		fmt.Fprint(w, job)
+	case r.URL.Query().Get("uploadType") == "resumable":
+		w.Header().Set("Location", "http://0.0.0.0:9050/upload/bigquery/v2/projects/p/jobs?uploadType=resumable&upload_id=u")
+	case strings.HasSuffix(r.URL.Path, "/queries"):
+		fmt.Fprint(w, `{"jobReference": {"projectId": "p", "jobId": "q"}, "jobComplete": true,
+			"schema": {"fields": [{"name": "n", "type": "INTEGER"}]}, "rows": [{"f": [{"v": "1"}]}], "totalRows": "1"}`)
+	case strings.HasSuffix(r.URL.Path, "/data"):
+		fmt.Fprint(w, `{"rows": [{"f": [{"v": "a"}]}], "totalRows": "1"}`)
+	case strings.HasSuffix(r.URL.Path, "/tables/t"):
+		fmt.Fprint(w, `{"schema": {"fields": [{"name": "s", "type": "STRING"}]}}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestWithEmulator(t *testing.T) {
+	e := &fakeEmulator{}
+	ts := httptest.NewServer(http.HandlerFunc(e.handle))
+	defer ts.Close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, "p", WithEmulator(strings.TrimPrefix(ts.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var row []Value
+	it, err := c.Query("SELECT 1 AS n").Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Next(&row); err != nil {
+		t.Fatal(err)
+	}
+	it = c.Dataset("d").Table("t").Read(ctx)
+	if err := it.Next(&row); err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Next(&row); err != iterator.Done {
+		t.Fatalf("got %v, want iterator.Done", err)
+	}
+
+	data := bytes.Repeat([]byte("a\n"), googleapi.MinUploadChunkSize)
+	rs := NewReaderSource(bytes.NewReader(data))
+	rs.ChunkSize = googleapi.MinUploadChunkSize
+	if _, err := c.Dataset("d").Table("t").LoaderFrom(rs).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.storageReadClient(ctx); err != errStorageAPIEmulator {
+		t.Errorf("storage read client: got %v, want %v", err, errStorageAPIEmulator)
+	}
+
+	want := []string{
+		"POST /bigquery/v2/projects/p/queries",
+		"GET /bigquery/v2/projects/p/datasets/d/tables/t/data",
+		"GET /bigquery/v2/projects/p/datasets/d/tables/t",
+		// The upload session and its chunks.
+		"POST /upload/bigquery/v2/projects/p/jobs",
+		"POST /upload/bigquery/v2/projects/p/jobs",
+		"POST /upload/bigquery/v2/projects/p/jobs",
+		"POST /upload/bigquery/v2/projects/p/jobs",
+	}
+	if diff := cmp.Diff(e.requests, want); diff != "" {
+		t.Errorf("requests: got=-, want=+:\n%s", diff)
+	}
+	if e.uploaded != len(data) {
+		t.Errorf("uploaded %d bytes, want %d", e.uploaded, len(data))
+	}
+	if e.authed {
+		t.Error("requests to the emulator were authenticated")
+	}
+}
+
+func TestEmulatorHostEnv(t *testing.T) {
+	old, ok := os.LookupEnv(emulatorHostEnv)
+	os.Setenv(emulatorHostEnv, "localhost:9050")
+	defer func() {
+		if ok {
+			os.Setenv(emulatorHostEnv, old)
+		} else {
+			os.Unsetenv(emulatorHostEnv)
+		}
+	}()
+	if got, want := emulatorHost(&clientConfig{}), "localhost:9050"; got != want {
+		t.Errorf("from the environment: got %q, want %q", got, want)
+	}
+	if got, want := emulatorHost(&clientConfig{emulatorHost: "other:1"}), "other:1"; got != want {
+		t.Errorf("from WithEmulator: got %q, want %q", got, want)
+	}
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"localhost:9050", "http://localhost:9050"},
+		{"https://emulator:443/ignored", "https://emulator:443"},
+	} {
+		u, err := parseEmulatorHost(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := u.String(); got != test.want {
+			t.Errorf("%q: got %s, want %s", test.in, got, test.want)
+		}
+	}
+}
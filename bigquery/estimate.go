@@ -0,0 +1,126 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+// DefaultOnDemandPricePerTiB is the price in US dollars of processing a TiB of
+// data with on-demand pricing in the US multi-region. Prices differ between
+// locations; see https://cloud.google.com/bigquery/pricing#on_demand_pricing.
+const DefaultOnDemandPricePerTiB = 5.0
+
+const (
+	mib = 1 << 20
+	tib = 1 << 40
+
+	// minBilledBytesPerTable is the minimum number of bytes billed for each
+	// table referenced by a query.
+	minBilledBytesPerTable = 10 * mib
+)
+
+// A QueryEstimate holds the results of a dry run of a query, which validates
+// the query and estimates its cost without running it.
+//
+// A dry run does not report the slots a query will use, which are known only
+// after it has run; see QueryStatistics.SlotMillis.
+type QueryEstimate struct {
+	// TotalBytesProcessed is the number of bytes the query will process.
+	TotalBytesProcessed int64
+
+	// TotalBytesProcessedAccuracy indicates how accurate TotalBytesProcessed
+	// is. It is one of "PRECISE", "LOWER_BOUND", "UPPER_BOUND" or "UNKNOWN".
+	TotalBytesProcessedAccuracy string
+
+	// ReferencedTables are the tables that the query reads. Queries that
+	// reference more than 50 tables will not have a complete list.
+	ReferencedTables []*Table
+
+	// StatementType is the type of the query's statement, such as "SELECT".
+	StatementType string
+
+	// Schema is the schema of the query's results. It is set only for Standard
+	// SQL queries.
+	Schema Schema
+
+	// UndeclaredQueryParameterNames are the names of the query parameters
+	// that the query uses but that are not set in its Parameters.
+	UndeclaredQueryParameterNames []string
+}
+
+// Estimate runs the query as a dry run, and returns the estimate of its cost.
+// The query itself is not modified, and no data is processed.
+func (q *Query) Estimate(ctx context.Context) (e *QueryEstimate, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Query.Estimate")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	dq := *q
+	dq.DryRun = true
+	job, err := dq.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return queryEstimateFromStatus(job.LastStatus())
+}
+
+func queryEstimateFromStatus(s *JobStatus) (*QueryEstimate, error) {
+	if s == nil || s.Statistics == nil {
+		return nil, errors.New("bigquery: dry run returned no statistics")
+	}
+	qs, ok := s.Statistics.Details.(*QueryStatistics)
+	if !ok {
+		return nil, errors.New("bigquery: dry run returned no query statistics")
+	}
+	return &QueryEstimate{
+		TotalBytesProcessed:           qs.TotalBytesProcessed,
+		TotalBytesProcessedAccuracy:   qs.TotalBytesProcessedAccuracy,
+		ReferencedTables:              qs.ReferencedTables,
+		StatementType:                 qs.StatementType,
+		Schema:                        qs.Schema,
+		UndeclaredQueryParameterNames: qs.UndeclaredQueryParameterNames,
+	}, nil
+}
+
+// BilledBytes returns the number of bytes that the query would be billed for
+// with on-demand pricing. Processed bytes are rounded up to the nearest MiB,
+// with a minimum of 10 MiB for each referenced table.
+//
+// The result does not take the query cache into account: a query answered from
+// the cache is not billed.
+func (e *QueryEstimate) BilledBytes() int64 {
+	if e.TotalBytesProcessed <= 0 {
+		return 0
+	}
+	billed := (e.TotalBytesProcessed + mib - 1) / mib * mib
+	tables := int64(len(e.ReferencedTables))
+	if tables == 0 {
+		tables = 1
+	}
+	if min := tables * minBilledBytesPerTable; billed < min {
+		billed = min
+	}
+	return billed
+}
+
+// OnDemandCost returns the approximate cost of the query in US dollars with
+// on-demand pricing, given the price of processing a TiB of data, such as
+// DefaultOnDemandPricePerTiB. It does not take free tiers into account.
+func (e *QueryEstimate) OnDemandCost(pricePerTiB float64) float64 {
+	return float64(e.BilledBytes()) / tib * pricePerTiB
+}
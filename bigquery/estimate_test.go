@@ -0,0 +1,89 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"math"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQueryEstimateFromStatus(t *testing.T) {
+	tables := []*Table{{ProjectID: "p", DatasetID: "d", TableID: "t"}}
+	s := &JobStatus{
+		Statistics: &JobStatistics{
+			TotalBytesProcessed: 12,
+			Details: &QueryStatistics{
+				TotalBytesProcessed:           12,
+				TotalBytesProcessedAccuracy:   "PRECISE",
+				ReferencedTables:              tables,
+				StatementType:                 "SELECT",
+				Schema:                        Schema{{Name: "n", Type: IntegerFieldType}},
+				UndeclaredQueryParameterNames: []string{"x"},
+			},
+		},
+	}
+	got, err := queryEstimateFromStatus(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &QueryEstimate{
+		TotalBytesProcessed:           12,
+		TotalBytesProcessedAccuracy:   "PRECISE",
+		ReferencedTables:              tables,
+		StatementType:                 "SELECT",
+		Schema:                        Schema{{Name: "n", Type: IntegerFieldType}},
+		UndeclaredQueryParameterNames: []string{"x"},
+	}
+	if diff := testutil.Diff(got, want, cmp.AllowUnexported(Table{})); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+
+	for _, bad := range []*JobStatus{nil, {}, {Statistics: &JobStatistics{Details: &LoadStatistics{}}}} {
+		if _, err := queryEstimateFromStatus(bad); err == nil {
+			t.Errorf("%+v: got nil error, want non-nil", bad)
+		}
+	}
+}
+
+func TestQueryEstimateCost(t *testing.T) {
+	table := &Table{}
+	for _, test := range []struct {
+		bytes  int64
+		tables int
+		want   int64
+	}{
+		{0, 1, 0},
+		{1, 0, 10 * mib},
+		{1, 2, 20 * mib},
+		{11*mib + 1, 1, 12 * mib},
+		{tib, 3, tib},
+	} {
+		e := &QueryEstimate{TotalBytesProcessed: test.bytes}
+		for i := 0; i < test.tables; i++ {
+			e.ReferencedTables = append(e.ReferencedTables, table)
+		}
+		if got := e.BilledBytes(); got != test.want {
+			t.Errorf("%d bytes, %d tables: got %d billed bytes, want %d", test.bytes, test.tables, got, test.want)
+		}
+	}
+
+	e := &QueryEstimate{TotalBytesProcessed: tib / 2}
+	if got, want := e.OnDemandCost(DefaultOnDemandPricePerTiB), DefaultOnDemandPricePerTiB/2; math.Abs(got-want) > 1e-9 {
+		t.Errorf("got cost %v, want %v", got, want)
+	}
+}
@@ -16,11 +16,14 @@ package bigquery_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/bigquery"
+	gax "github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/iterator"
 )
 
@@ -148,6 +151,43 @@ func ExampleClient_Query_encryptionKey() {
 	// TODO: Call Query.Run or Query.Read.
 }
 
+func ExampleSession_RunInTransaction() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	session, err := client.CreateSession(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	defer session.Abort(ctx)
+	run := func(ctx context.Context, sql string) error {
+		job, err := session.Query(sql).Run(ctx)
+		if err != nil {
+			return err
+		}
+		status, err := job.Wait(ctx)
+		if err != nil {
+			return err
+		}
+		return status.Err()
+	}
+	// Temporary tables live as long as the session.
+	if err := run(ctx, "CREATE TEMP TABLE staged AS SELECT name, num FROM mydataset.incoming"); err != nil {
+		// TODO: Handle error.
+	}
+	err = session.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := run(ctx, "DELETE mydataset.t1 WHERE name IN (SELECT name FROM staged)"); err != nil {
+			return err
+		}
+		return run(ctx, "INSERT mydataset.t1 (name, num) SELECT name, num FROM staged")
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+}
+
 func ExampleQuery_Read() {
 	ctx := context.Background()
 	client, err := bigquery.NewClient(ctx, "project-id")
@@ -162,6 +202,45 @@ func ExampleQuery_Read() {
 	_ = it // TODO: iterate using Next or iterator.Pager.
 }
 
+func ExampleQuery_Estimate() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	q := client.Query("select name, num from t1")
+	e, err := q.Estimate(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// Refuse to run queries that would cost more than a dollar.
+	if e.OnDemandCost(bigquery.DefaultOnDemandPricePerTiB) > 1 {
+		// TODO: Handle expensive query.
+	}
+	fmt.Printf("%d bytes from %d tables\n", e.TotalBytesProcessed, len(e.ReferencedTables))
+}
+
+func ExampleQuery_Retry() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	q := client.Query("select name, num from t1")
+	// Retry rate limit errors patiently, and run the query again up to
+	// twice if it fails with a backend error.
+	q.Retry = &bigquery.RetryConfig{
+		Backoff:    gax.Backoff{Initial: 5 * time.Second, Max: 5 * time.Minute, Multiplier: 2},
+		Reasons:    []string{"rateLimitExceeded", "backendError"},
+		JobRetries: 2,
+	}
+	it, err := q.Read(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = it // TODO: iterate using Next or iterator.Pager.
+}
+
 func ExampleRowIterator_Next() {
 	ctx := context.Background()
 	client, err := bigquery.NewClient(ctx, "project-id")
@@ -216,6 +295,60 @@ func ExampleRowIterator_Next_struct() {
 	}
 }
 
+func ExampleRowIterator_ArrowRecords() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	q := client.Query("select name, num from t1")
+	it, err := q.Read(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	ai, err := it.ArrowRecords()
+	if err != nil {
+		// TODO: Handle error.
+	}
+	for {
+		rec, err := ai.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		fmt.Println(rec.NumRows())
+		rec.Release()
+	}
+}
+
+func ExampleArrowIterator_WriteParquet() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	it, err := client.Query("select name, num from t1").Read(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	ai, err := it.ArrowRecords()
+	if err != nil {
+		// TODO: Handle error.
+	}
+	f, err := os.Create("results.parquet")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	if err := ai.WriteParquet(f, nil); err != nil {
+		// TODO: Handle error.
+	}
+	if err := f.Close(); err != nil {
+		// TODO: Handle error.
+	}
+}
+
 func ExampleJob_Read() {
 	ctx := context.Background()
 	client, err := bigquery.NewClient(ctx, "project-id")
@@ -275,6 +408,32 @@ func ExampleJob_Config() {
 	fmt.Println(copyConfig.Dst, copyConfig.CreateDisposition)
 }
 
+func ExampleJob_ScriptStatements() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	job, err := client.Query("DECLARE n INT64 DEFAULT 3; SELECT n;").Run(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	if _, err := job.Wait(ctx); err != nil {
+		// TODO: Handle error.
+	}
+	it := job.ScriptStatements(ctx)
+	for {
+		s, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		fmt.Println(s.SQL, s.Err)
+	}
+}
+
 func ExampleDataset_Create() {
 	ctx := context.Background()
 	client, err := bigquery.NewClient(ctx, "project-id")
@@ -472,6 +631,44 @@ func ExampleTable_Create_initialize() {
 
 // This example demonstrates how to create a table with
 // a customer-managed encryption key.
+func ExampleTable_Create_bigLake() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// TODO: Replace this with a connection you have created, whose service
+	// account can read the bucket.
+	connection := "projects/P/locations/us/connections/C"
+
+	// A BigLake table over Parquet files, whose metadata is cached for up
+	// to an hour.
+	t := client.Dataset("my_dataset").Table("sales")
+	if err := t.Create(ctx, &bigquery.TableMetadata{
+		MaxStaleness: &bigquery.IntervalValue{Hours: 1},
+		ExternalDataConfig: &bigquery.ExternalDataConfig{
+			SourceFormat:      bigquery.Parquet,
+			SourceURIs:        []string{"gs://my-bucket/sales/*.parquet"},
+			ConnectionID:      connection,
+			MetadataCacheMode: bigquery.AutomaticMetadataCacheMode,
+		},
+	}); err != nil {
+		// TODO: Handle error.
+	}
+
+	// An object table, whose rows describe the images in a bucket.
+	t = client.Dataset("my_dataset").Table("images")
+	if err := t.Create(ctx, &bigquery.TableMetadata{
+		ExternalDataConfig: &bigquery.ExternalDataConfig{
+			SourceURIs:     []string{"gs://my-bucket/images/*"},
+			ConnectionID:   connection,
+			ObjectMetadata: "SIMPLE",
+		},
+	}); err != nil {
+		// TODO: Handle error.
+	}
+}
+
 func ExampleTable_Create_encryptionKey() {
 	ctx := context.Background()
 	// Infer table schema from a Go type.
@@ -566,6 +763,84 @@ func ExampleTable_CopierFrom() {
 	}
 }
 
+func ExampleTable_CreateSnapshot() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	ds := client.Dataset("my_dataset")
+	// Keep a backup of t1 for a week.
+	job, err := ds.Table("t1").CreateSnapshot(ctx, ds.Table("t1_backup"), time.Now().Add(7*24*time.Hour))
+	if err != nil {
+		// TODO: Handle error.
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	if status.Err() != nil {
+		// TODO: Handle error.
+	}
+	// Later, restore the backup into a new table.
+	job, err = ds.Table("t1_backup").RestoreSnapshot(ctx, ds.Table("t1_restored"))
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = job // TODO: Wait for the job to finish.
+}
+
+func ExampleClient_UpdateLabels() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	ds := client.Dataset("my_dataset")
+	err = client.UpdateLabels(ctx, &bigquery.BulkLabelUpdate{
+		Datasets:     []*bigquery.Dataset{ds},
+		Tables:       []*bigquery.Table{ds.Table("t1"), ds.Table("t2")},
+		SetLabels:    map[string]string{"cost-center": "analytics"},
+		DeleteLabels: []string{"temporary"},
+	})
+	if me, ok := err.(bigquery.MultiError); ok {
+		for _, e := range me {
+			// TODO: Handle the error of one resource.
+			_ = e.(*bigquery.LabelUpdateError).Resource
+		}
+	} else if err != nil {
+		// TODO: Handle error.
+	}
+}
+
+func ExampleTable_RowAccessPolicies() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	table := client.Dataset("my_dataset").Table("sales")
+	// Let the US team read only the US rows of the table.
+	err = table.RowAccessPolicy("us_only").Create(ctx, &bigquery.RowAccessPolicyMetadata{
+		FilterPredicate: `region = "US"`,
+		Grantees:        []string{"group:sales-us@example.com"},
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	it := table.RowAccessPolicies(ctx)
+	for {
+		md, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		fmt.Println(md.PolicyID, md.FilterPredicate)
+	}
+}
+
 func ExampleTable_ExtractorTo() {
 	ctx := context.Background()
 	client, err := bigquery.NewClient(ctx, "project-id")
@@ -592,6 +867,138 @@ func ExampleTable_ExtractorTo() {
 	}
 }
 
+func ExampleRowIterator_Checkpoint() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	it, err := client.Query("SELECT * FROM my_dataset.my_table").Read(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// Read some rows, then record where to continue.
+	for i := 0; i < 1000; i++ {
+		var row []bigquery.Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		fmt.Println(row)
+	}
+	cp, err := it.Checkpoint()
+	if err != nil {
+		// TODO: Handle error.
+	}
+	b, err := json.Marshal(cp)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// Send b to another process, which continues reading the rows.
+	var cp2 bigquery.RowIteratorCheckpoint
+	if err := json.Unmarshal(b, &cp2); err != nil {
+		// TODO: Handle error.
+	}
+	it, err = client.ResumeRowIterator(ctx, &cp2)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = it // Proceed with iteration as above.
+}
+
+func ExampleTable_DeletePartitions() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	t := client.Dataset("my_dataset").Table("my_table")
+	partitions, err := t.Partitions(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// Delete the daily partitions that are more than 90 days old.
+	cutoff := bigquery.TimePartitionID(time.Now().AddDate(0, 0, -90), bigquery.DayPartitioningType)
+	var old []string
+	for _, p := range partitions {
+		if p.ID < cutoff && !strings.HasPrefix(p.ID, "__") {
+			old = append(old, p.ID)
+		}
+	}
+	if err := t.DeletePartitions(ctx, old...); err != nil {
+		// TODO: Handle error.
+	}
+}
+
+func ExampleClient_UpdatePartitionExpiration() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	ds := client.Dataset("my_dataset")
+	tables := []*bigquery.Table{ds.Table("events"), ds.Table("logs")}
+	// Keep 30 days of partitions in each table.
+	if err := client.UpdatePartitionExpiration(ctx, tables, 30*24*time.Hour); err != nil {
+		// TODO: Handle error.
+	}
+}
+
+func ExampleGeography_GeoJSON() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	q := client.Query("SELECT name, location FROM my_dataset.places WHERE ST_DWITHIN(location, @home, 1000)")
+	q.Parameters = []bigquery.QueryParameter{
+		{Name: "home", Value: bigquery.Geography{WKT: "POINT(-122.35 47.62)"}},
+	}
+	it, err := q.Read(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	for {
+		var row struct {
+			Name     string
+			Location bigquery.Geography
+		}
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		geoJSON, err := row.Location.GeoJSON()
+		if err != nil {
+			// TODO: Handle error.
+		}
+		fmt.Println(row.Name, string(geoJSON))
+	}
+}
+
+func ExampleExtractor_Extract() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// BigQuery replaces the wildcard with the number of each file it writes.
+	gcsRef := bigquery.NewGCSReference("gs://my-bucket/export/part-*.csv")
+	extractor := client.Dataset("my_dataset").Table("my_table").ExtractorTo(gcsRef)
+	files, err := extractor.Extract(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	for _, f := range files {
+		fmt.Println(f.URI, f.Size)
+	}
+}
+
 func ExampleTable_LoaderFrom() {
 	ctx := context.Background()
 	client, err := bigquery.NewClient(ctx, "project-id")
@@ -634,6 +1041,9 @@ func ExampleTable_LoaderFrom_reader() {
 	rs.AllowJaggedRows = true
 	rs.MaxBadRecords = 5
 	rs.Schema = schema
+	// Upload the file in 32 MiB chunks, and report the progress.
+	rs.ChunkSize = 32 << 20
+	rs.ProgressFunc = func(n int64) { fmt.Printf("uploaded %d bytes\n", n) }
 	// TODO: set other options on the GCSReference.
 	ds := client.Dataset("my_dataset")
 	loader := ds.Table("my_table").LoaderFrom(rs)
@@ -703,6 +1113,53 @@ func ExampleTable_Update_blindWrite() {
 	fmt.Println(tm)
 }
 
+func ExampleTable_UpdatePolicyTags() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	t := client.Dataset("my_dataset").Table("my_table")
+	// Attach a policy tag to the ssn column, and detach those of address.zip.
+	md, err := t.UpdatePolicyTags(ctx, map[string]*bigquery.PolicyTagList{
+		"ssn":         {Names: []string{"projects/project-id/locations/us/taxonomies/1/policyTags/2"}},
+		"address.zip": nil,
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	fmt.Println(md.Schema.PolicyTags())
+}
+
+func ExampleSchema_PreservePolicyTags() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	type Item struct {
+		Name  string
+		Size  float64
+		Count int
+	}
+	schema, err := bigquery.InferSchema(Item{})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	t := client.Dataset("my_dataset").Table("my_table")
+	md, err := t.Metadata(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// Keep the policy tags of the table's columns, which the inferred schema
+	// does not have.
+	if _, err := t.Update(ctx, bigquery.TableMetadataToUpdate{
+		Schema: schema.PreservePolicyTags(md.Schema),
+	}, md.ETag); err != nil {
+		// TODO: Handle error.
+	}
+}
+
 func ExampleTableIterator_Next() {
 	ctx := context.Background()
 	client, err := bigquery.NewClient(ctx, "project-id")
@@ -755,6 +1212,22 @@ func ExampleInserter_Put() {
 	}
 }
 
+func ExampleBufferedInserter_Insert() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	bi := client.Dataset("my_dataset").Table("my_table").Inserter().Buffered()
+	defer bi.Stop()
+	// Item implements the ValueSaver interface.
+	res := bi.Insert(ctx, &Item{Name: "n1", Size: 32.6, Count: 7})
+	// Wait for the row to be inserted.
+	if err := res.Get(ctx); err != nil {
+		// TODO: Handle error.
+	}
+}
+
 var schema bigquery.Schema
 
 func ExampleInserter_Put_structSaver() {
@@ -827,3 +1300,37 @@ func ExampleInserter_Put_valuesSaver() {
 		// TODO: Handle error.
 	}
 }
+
+func ExampleTable_StorageInserter() {
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	defer client.Close()
+
+	si, err := client.Dataset("my_dataset").Table("my_table").StorageInserter(ctx, bigquery.PendingStreamMode)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	defer si.Close()
+
+	type score struct {
+		Name string
+		Num  int
+	}
+	scores := []score{
+		{Name: "n1", Num: 12},
+		{Name: "n2", Num: 31},
+		{Name: "n3", Num: 7},
+	}
+	if err := si.Put(ctx, scores); err != nil {
+		// TODO: Handle error.
+	}
+	// The rows become visible when the stream is committed.
+	rows, err := si.Commit(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	fmt.Println(rows)
+}
@@ -104,8 +104,41 @@ type ExternalDataConfig struct {
 	//
 	// StringTargetType supports all precision and scale values.
 	DecimalTargetTypes []DecimalTargetType
+
+	// ConnectionID is the connection that BigQuery uses to read the data,
+	// which makes the table a BigLake table. It has the form
+	// "projects/{project}/locations/{location}/connections/{connection}" or
+	// "{project}.{location}.{connection}".
+	ConnectionID string
+
+	// MetadataCacheMode sets whether the metadata of the files of a BigLake
+	// table is cached, and how the cache is refreshed. Caching requires
+	// TableMetadata.MaxStaleness. It can only be set when a table is created,
+	// and is not reported by Table.Metadata.
+	MetadataCacheMode MetadataCacheMode
+
+	// ObjectMetadata makes the table an object table, whose rows describe the
+	// objects of SourceURIs rather than their contents. The only value is
+	// "SIMPLE". An object table needs a ConnectionID, and cannot have a
+	// SourceFormat, Schema or Options. It can only be set when a table is
+	// created, and is not reported by Table.Metadata.
+	ObjectMetadata string
 }
 
+// MetadataCacheMode describes how the metadata cache of a BigLake table is
+// refreshed.
+type MetadataCacheMode string
+
+const (
+	// AutomaticMetadataCacheMode refreshes the cache at an interval chosen by
+	// BigQuery.
+	AutomaticMetadataCacheMode MetadataCacheMode = "AUTOMATIC"
+
+	// ManualMetadataCacheMode refreshes the cache only when
+	// Table.RefreshExternalMetadataCache is called.
+	ManualMetadataCacheMode MetadataCacheMode = "MANUAL"
+)
+
 func (e *ExternalDataConfig) toBQ() bq.ExternalDataConfiguration {
 	q := bq.ExternalDataConfiguration{
 		SourceFormat:            string(e.SourceFormat),
@@ -115,6 +148,7 @@ func (e *ExternalDataConfig) toBQ() bq.ExternalDataConfiguration {
 		IgnoreUnknownValues:     e.IgnoreUnknownValues,
 		MaxBadRecords:           e.MaxBadRecords,
 		HivePartitioningOptions: e.HivePartitioningOptions.toBQ(),
+		ConnectionId:            e.ConnectionID,
 	}
 	if e.Schema != nil {
 		q.Schema = e.Schema.toBQ()
@@ -138,6 +172,7 @@ func bqToExternalDataConfig(q *bq.ExternalDataConfiguration) (*ExternalDataConfi
 		MaxBadRecords:           q.MaxBadRecords,
 		Schema:                  bqToSchema(q.Schema),
 		HivePartitioningOptions: bqToHivePartitioningOptions(q.HivePartitioningOptions),
+		ConnectionID:            q.ConnectionId,
 	}
 	for _, v := range q.DecimalTargetTypes {
 		e.DecimalTargetTypes = append(e.DecimalTargetTypes, DecimalTargetType(v))
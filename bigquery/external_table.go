@@ -0,0 +1,138 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+// isObjectTable reports whether tm describes an object table, which can only
+// be created with DDL.
+func (tm *TableMetadata) isObjectTable() bool {
+	return tm != nil && tm.ExternalDataConfig != nil && tm.ExternalDataConfig.ObjectMetadata != ""
+}
+
+// isView reports whether tm describes a logical or materialized view.
+func (tm *TableMetadata) isView() bool {
+	return tm != nil && (tm.ViewQuery != "" || tm.MaterializedView != nil)
+}
+
+// needsCacheOptions reports whether tm has options that can only be set with
+// DDL after the table is created. Views have none: their staleness is part of
+// their MaterializedViewDefinition.
+func (tm *TableMetadata) needsCacheOptions() bool {
+	if tm == nil || tm.isView() {
+		return false
+	}
+	return tm.MaxStaleness != nil || (tm.ExternalDataConfig != nil && tm.ExternalDataConfig.MetadataCacheMode != "")
+}
+
+// cacheOptions returns the DDL options for the MaxStaleness and
+// MetadataCacheMode of tm.
+func (tm *TableMetadata) cacheOptions() []string {
+	var opts []string
+	if tm.MaxStaleness != nil {
+		opts = append(opts, "max_staleness = "+intervalLiteral(tm.MaxStaleness))
+	}
+	if tm.ExternalDataConfig != nil && tm.ExternalDataConfig.MetadataCacheMode != "" {
+		opts = append(opts, "metadata_cache_mode = "+quoteString(string(tm.ExternalDataConfig.MetadataCacheMode)))
+	}
+	return opts
+}
+
+// setCacheOptions sets the cache options of a table that was just created. If
+// they cannot be set, the table is kept, as it may already be in use, and the
+// error says so.
+func (t *Table) setCacheOptions(ctx context.Context, tm *TableMetadata) error {
+	stmt := fmt.Sprintf("ALTER TABLE %s SET OPTIONS(%s)", t.sqlIdentifier(), strings.Join(tm.cacheOptions(), ", "))
+	if err := t.c.Query(stmt).exec(ctx); err != nil {
+		return fmt.Errorf("bigquery: table %s was created, but setting its cache options failed: %v", t.FullyQualifiedName(), err)
+	}
+	return nil
+}
+
+// createObjectTable creates the object table of tm with a CREATE EXTERNAL
+// TABLE statement.
+func (t *Table) createObjectTable(ctx context.Context, tm *TableMetadata) error {
+	stmt, err := t.createObjectTableStatement(tm)
+	if err != nil {
+		return err
+	}
+	return t.c.Query(stmt).exec(ctx)
+}
+
+func (t *Table) createObjectTableStatement(tm *TableMetadata) (string, error) {
+	edc := tm.ExternalDataConfig
+	switch {
+	case edc.ConnectionID == "":
+		return "", errors.New("bigquery: an object table needs a ConnectionID")
+	case len(edc.SourceURIs) == 0:
+		return "", errors.New("bigquery: an object table needs SourceURIs")
+	case edc.SourceFormat != "", edc.Schema != nil, edc.Options != nil, edc.AutoDetect, edc.Compression != "",
+		edc.IgnoreUnknownValues, edc.MaxBadRecords != 0, edc.HivePartitioningOptions != nil, edc.DecimalTargetTypes != nil:
+		return "", errors.New("bigquery: an object table supports only SourceURIs, ConnectionID, ObjectMetadata and MetadataCacheMode of ExternalDataConfig")
+	case tm.Schema != nil, tm.ViewQuery != "", tm.MaterializedView != nil, tm.TimePartitioning != nil, tm.RangePartitioning != nil,
+		tm.Clustering != nil, tm.RequirePartitionFilter, tm.EncryptionConfig != nil, tm.SnapshotDefinition != nil:
+		return "", errors.New("bigquery: an object table supports only Name, Description, Labels, ExpirationTime, MaxStaleness and ExternalDataConfig")
+	}
+	conn, err := connectionIdentifier(edc.ConnectionID)
+	if err != nil {
+		return "", err
+	}
+
+	uris := make([]string, len(edc.SourceURIs))
+	for i, u := range edc.SourceURIs {
+		uris[i] = quoteString(u)
+	}
+	opts := []string{
+		"object_metadata = " + quoteString(edc.ObjectMetadata),
+		"uris = [" + strings.Join(uris, ", ") + "]",
+	}
+	opts = append(opts, tm.cacheOptions()...)
+	opts = append(opts, tm.ddlOptions()...)
+	return fmt.Sprintf("CREATE EXTERNAL TABLE %s WITH CONNECTION %s OPTIONS(%s)",
+		t.sqlIdentifier(), conn, strings.Join(opts, ", ")), nil
+}
+
+// connectionIdentifier returns the quoted DDL identifier of a connection,
+// given either its resource name or its "{project}.{location}.{connection}"
+// ID.
+func connectionIdentifier(id string) (string, error) {
+	if strings.HasPrefix(id, "projects/") {
+		parts := strings.Split(id, "/")
+		if len(parts) != 6 || parts[2] != "locations" || parts[4] != "connections" {
+			return "", fmt.Errorf("bigquery: invalid connection name %q", id)
+		}
+		id = strings.Join([]string{parts[1], parts[3], parts[5]}, ".")
+	}
+	return quoteIdentifier(id), nil
+}
+
+// RefreshExternalMetadataCache refreshes the cached metadata of a BigLake or
+// object table, and waits for the refresh to finish. It is needed only for
+// tables whose ExternalDataConfig.MetadataCacheMode is
+// ManualMetadataCacheMode.
+func (t *Table) RefreshExternalMetadataCache(ctx context.Context) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.RefreshExternalMetadataCache")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	id, _ := t.Identifier(StandardSQLID)
+	return t.c.Query(fmt.Sprintf("CALL BQ.REFRESH_EXTERNAL_METADATA_CACHE(%s)", quoteString(id))).exec(ctx)
+}
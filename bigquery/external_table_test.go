@@ -0,0 +1,133 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"testing"
+)
+
+func TestCreateObjectTableStatement(t *testing.T) {
+	c := &Client{projectID: "p"}
+	table := c.Dataset("d").Table("objects")
+	tm := &TableMetadata{
+		Description:  "Images",
+		MaxStaleness: &IntervalValue{Hours: 4},
+		ExternalDataConfig: &ExternalDataConfig{
+			SourceURIs:        []string{"gs://bucket/images/*", "gs://bucket/more/*"},
+			ConnectionID:      "projects/p/locations/us/connections/c",
+			ObjectMetadata:    "SIMPLE",
+			MetadataCacheMode: AutomaticMetadataCacheMode,
+		},
+	}
+	if !tm.isObjectTable() {
+		t.Fatal("isObjectTable is false")
+	}
+	got, err := table.createObjectTableStatement(tm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE EXTERNAL TABLE `p.d.objects` WITH CONNECTION `p.us.c` OPTIONS(" +
+		`object_metadata = "SIMPLE", uris = ["gs://bucket/images/*", "gs://bucket/more/*"], ` +
+		`max_staleness = INTERVAL "0-0 0 4:0:0" YEAR TO SECOND, metadata_cache_mode = "AUTOMATIC", ` +
+		`description = "Images")`
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	for _, tm := range []*TableMetadata{
+		{ExternalDataConfig: &ExternalDataConfig{ObjectMetadata: "SIMPLE", SourceURIs: []string{"gs://b/*"}}},
+		{ExternalDataConfig: &ExternalDataConfig{ObjectMetadata: "SIMPLE", ConnectionID: "p.us.c"}},
+		{ExternalDataConfig: &ExternalDataConfig{ObjectMetadata: "SIMPLE", ConnectionID: "p.us.c", SourceURIs: []string{"gs://b/*"}, SourceFormat: CSV}},
+		{
+			Schema:             Schema{{Name: "a", Type: StringFieldType}},
+			ExternalDataConfig: &ExternalDataConfig{ObjectMetadata: "SIMPLE", ConnectionID: "p.us.c", SourceURIs: []string{"gs://b/*"}},
+		},
+		{ExternalDataConfig: &ExternalDataConfig{ObjectMetadata: "SIMPLE", ConnectionID: "projects/p/c", SourceURIs: []string{"gs://b/*"}}},
+	} {
+		if _, err := table.createObjectTableStatement(tm); err == nil {
+			t.Errorf("%+v: got nil, want error", tm.ExternalDataConfig)
+		}
+	}
+}
+
+func TestCacheOptions(t *testing.T) {
+	for _, test := range []struct {
+		tm   *TableMetadata
+		want []string
+	}{
+		{&TableMetadata{}, nil},
+		{&TableMetadata{ExternalDataConfig: &ExternalDataConfig{SourceFormat: Parquet}}, nil},
+		{
+			&TableMetadata{MaxStaleness: &IntervalValue{Minutes: 30}},
+			[]string{`max_staleness = INTERVAL "0-0 0 0:30:0" YEAR TO SECOND`},
+		},
+		{
+			&TableMetadata{
+				MaxStaleness:       &IntervalValue{Days: 1},
+				ExternalDataConfig: &ExternalDataConfig{MetadataCacheMode: ManualMetadataCacheMode},
+			},
+			[]string{`max_staleness = INTERVAL "0-0 1 0:0:0" YEAR TO SECOND`, `metadata_cache_mode = "MANUAL"`},
+		},
+	} {
+		if got, want := test.tm.needsCacheOptions(), test.want != nil; got != want {
+			t.Errorf("%+v: needsCacheOptions is %t, want %t", test.tm, got, want)
+		}
+		got := test.tm.cacheOptions()
+		if len(got) != len(test.want) {
+			t.Errorf("%+v: got %q, want %q", test.tm, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%+v: got %q, want %q", test.tm, got, test.want)
+			}
+		}
+	}
+}
+
+func TestMaxStalenessOfViews(t *testing.T) {
+	staleness := &IntervalValue{Hours: 1}
+	for _, tm := range []*TableMetadata{
+		{ViewQuery: "SELECT 1", MaxStaleness: staleness},
+		{MaterializedView: &MaterializedViewDefinition{Query: "SELECT 1"}, MaxStaleness: staleness},
+	} {
+		if tm.needsCacheOptions() {
+			t.Errorf("%+v: needsCacheOptions is true for a view", tm)
+		}
+		if _, err := tm.toBQ(); err == nil {
+			t.Errorf("%+v: got nil, want error", tm)
+		}
+	}
+}
+
+func TestConnectionIdentifier(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{"p.us.c", "`p.us.c`"},
+		{"projects/p/locations/eu/connections/c", "`p.eu.c`"},
+	} {
+		got, err := connectionIdentifier(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %s, want %s", test.in, got, test.want)
+		}
+	}
+	if _, err := connectionIdentifier("projects/p/connections/c"); err == nil {
+		t.Error("got nil, want error")
+	}
+}
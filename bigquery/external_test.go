@@ -98,6 +98,11 @@ func TestExternalDataConfig(t *testing.T) {
 				UseAvroLogicalTypes: true,
 			},
 		},
+		{
+			SourceFormat: Parquet,
+			SourceURIs:   []string{"gs://bucket/data/*.parquet"},
+			ConnectionID: "projects/p/locations/us/connections/c",
+		},
 	} {
 		q := want.toBQ()
 		got, err := bqToExternalDataConfig(&q)
@@ -16,11 +16,19 @@ package bigquery
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
 	"cloud.google.com/go/internal/trace"
 	bq "google.golang.org/api/bigquery/v2"
 )
 
+// extractedFileConcurrency is the number of extracted files whose sizes are
+// read at once.
+const extractedFileConcurrency = 10
+
 // ExtractConfig holds the configuration for an extract job.
 type ExtractConfig struct {
 	// Src is the table from which data will be extracted.
@@ -37,7 +45,8 @@ type ExtractConfig struct {
 	// DisableHeader disables the printing of a header row in exported data.
 	DisableHeader bool
 
-	// The labels associated with this job.
+	// The labels associated with this job. The client's DefaultJobLabels are
+	// added to them.
 	Labels map[string]string
 
 	// For Avro-based extracts, controls whether logical type annotations are generated.
@@ -134,7 +143,7 @@ func (e *Extractor) Run(ctx context.Context) (j *Job, err error) {
 	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Extractor.Run")
 	defer func() { trace.EndSpan(ctx, err) }()
 
-	return e.c.insertJob(ctx, e.newJob(), nil)
+	return e.c.insertJob(ctx, e.newJob(), nil, nil)
 }
 
 func (e *Extractor) newJob() *bq.Job {
@@ -143,3 +152,192 @@ func (e *Extractor) newJob() *bq.Job {
 		Configuration: e.ExtractConfig.toBQ(),
 	}
 }
+
+// An ExtractedFile is a Cloud Storage object written by an extract job.
+type ExtractedFile struct {
+	// URI is the URI of the object, in the form gs://bucket/object.
+	URI string
+
+	// Bucket and Object are the names of the object's bucket and the object.
+	Bucket, Object string
+
+	// Size is the size of the object in bytes.
+	Size int64
+}
+
+// Extract validates the destination URIs of the extractor, runs the extract
+// job, waits for it to finish, and returns the files it wrote, as reported by
+// Job.ExtractedFiles.
+//
+// A destination URI must have the form gs://bucket/object, and may contain a
+// single '*' wildcard in the object name, which BigQuery replaces with the
+// number of each file it writes. Extracts of more than 1 GB of data need a
+// wildcard URI, to shard the output across several files.
+func (e *Extractor) Extract(ctx context.Context) (files []*ExtractedFile, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Extractor.Extract")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if e.Dst == nil {
+		return nil, errors.New("bigquery: extract needs a Dst")
+	}
+	if err := validateExtractURIs(e.Dst.URIs); err != nil {
+		return nil, err
+	}
+	job, err := e.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	return job.ExtractedFiles(ctx)
+}
+
+// validateExtractURIs checks that uris are Cloud Storage URIs with at most one
+// wildcard, in the object name.
+func validateExtractURIs(uris []string) error {
+	if len(uris) == 0 {
+		return errors.New("bigquery: extract needs at least one destination URI")
+	}
+	seen := map[string]bool{}
+	for _, uri := range uris {
+		bucket, object, err := parseGCSURI(uri)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.Contains(bucket, "*"):
+			return fmt.Errorf("bigquery: destination URI %q has a wildcard in its bucket name", uri)
+		case strings.Count(object, "*") > 1:
+			return fmt.Errorf("bigquery: destination URI %q has more than one wildcard", uri)
+		case seen[uri]:
+			return fmt.Errorf("bigquery: duplicate destination URI %q", uri)
+		}
+		seen[uri] = true
+	}
+	return nil
+}
+
+// parseGCSURI returns the bucket and object names of a gs://bucket/object URI.
+func parseGCSURI(uri string) (bucket, object string, err error) {
+	rest := strings.TrimPrefix(uri, "gs://")
+	if rest == uri {
+		return "", "", fmt.Errorf("bigquery: destination URI %q does not start with gs://", uri)
+	}
+	i := strings.Index(rest, "/")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", fmt.Errorf("bigquery: destination URI %q is not of the form gs://bucket/object", uri)
+	}
+	return rest[:i], rest[i+1:], nil
+}
+
+// extractedFileNames returns the URIs of the files that an extract job wrote
+// to its destination URIs, given the number of files for each URI. BigQuery
+// replaces the wildcard of a URI with a 12-digit file number, starting at 0.
+func extractedFileNames(uris []string, counts []int64) ([]string, error) {
+	if len(counts) != len(uris) {
+		return nil, fmt.Errorf("bigquery: extract job reported file counts for %d of %d destination URIs", len(counts), len(uris))
+	}
+	var names []string
+	for i, uri := range uris {
+		if !strings.Contains(uri, "*") {
+			if counts[i] > 0 {
+				names = append(names, uri)
+			}
+			continue
+		}
+		for n := int64(0); n < counts[i]; n++ {
+			names = append(names, strings.Replace(uri, "*", fmt.Sprintf("%012d", n), 1))
+		}
+	}
+	return names, nil
+}
+
+// ExtractedFiles returns the files that a successful extract job wrote to
+// Cloud Storage, in the order of its destination URIs and then of the file
+// numbers that replace their wildcards. The files are found from the job's
+// ExtractStatistics, and their sizes are read from Cloud Storage, so the
+// bucket does not have to be listed.
+func (j *Job) ExtractedFiles(ctx context.Context) (files []*ExtractedFile, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Job.ExtractedFiles")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	config, err := j.Config()
+	if err != nil {
+		return nil, err
+	}
+	ec, ok := config.(*ExtractConfig)
+	if !ok {
+		return nil, errors.New("bigquery: not an extract job")
+	}
+	status := j.LastStatus()
+	if status == nil || !status.Done() || status.Statistics == nil {
+		if status, err = j.Status(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if !status.Done() {
+		return nil, errors.New("bigquery: extract job is not done")
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	var counts []int64
+	if status.Statistics != nil {
+		if es, ok := status.Statistics.Details.(*ExtractStatistics); ok {
+			counts = es.DestinationURIFileCounts
+		}
+	}
+	names, err := extractedFileNames(ec.Dst.URIs, counts)
+	if err != nil {
+		return nil, err
+	}
+
+	files = make([]*ExtractedFile, len(names))
+	var (
+		mu   sync.Mutex
+		errs MultiError
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, extractedFileConcurrency)
+	for i, name := range names {
+		i, name := i, name
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			bucket, object, err := parseGCSURI(name)
+			if err == nil {
+				files[i] = &ExtractedFile{URI: name, Bucket: bucket, Object: object}
+				files[i].Size, err = objectSize(ctx, j.c, bucket, object)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("bigquery: reading size of %s: %v", name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return files, nil
+}
+
+// objectSize exists to aid testing.
+var objectSize = func(ctx context.Context, c *Client, bucket, object string) (int64, error) {
+	gc, err := c.gcsClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	attrs, err := gc.Bucket(bucket).Object(object).Attrs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return attrs.Size, nil
+}
@@ -15,6 +15,9 @@
 package bigquery
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
 
 	"cloud.google.com/go/internal/testutil"
@@ -200,3 +203,100 @@ func TestExtractModel(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateExtractURIs(t *testing.T) {
+	for _, uris := range [][]string{
+		{"gs://bucket/file.csv"},
+		{"gs://bucket/dir/file-*.csv"},
+		{"gs://bucket/a-*.json", "gs://bucket/b-*.json"},
+	} {
+		if err := validateExtractURIs(uris); err != nil {
+			t.Errorf("%q: %v", uris, err)
+		}
+	}
+	for _, uris := range [][]string{
+		nil,
+		{"bucket/file.csv"},
+		{"gs://bucket"},
+		{"gs://bucket/"},
+		{"gs:///file.csv"},
+		{"gs://bucket-*/file.csv"},
+		{"gs://bucket/*-*.csv"},
+		{"gs://bucket/f-*.csv", "gs://bucket/f-*.csv"},
+	} {
+		if err := validateExtractURIs(uris); err == nil {
+			t.Errorf("%q: got nil, want error", uris)
+		}
+	}
+}
+
+func TestExtractedFileNames(t *testing.T) {
+	got, err := extractedFileNames(
+		[]string{"gs://b/single.csv", "gs://b/shard-*.csv", "gs://b/empty-*.csv"},
+		[]int64{1, 3, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"gs://b/single.csv",
+		"gs://b/shard-000000000000.csv",
+		"gs://b/shard-000000000001.csv",
+		"gs://b/shard-000000000002.csv",
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+	if _, err := extractedFileNames([]string{"gs://b/a", "gs://b/b"}, []int64{1}); err == nil {
+		t.Error("mismatched counts: got nil, want error")
+	}
+}
+
+func TestJobExtractedFiles(t *testing.T) {
+	defer func(old func(context.Context, *Client, string, string) (int64, error)) { objectSize = old }(objectSize)
+	var mu sync.Mutex
+	var read []string
+	objectSize = func(_ context.Context, _ *Client, bucket, object string) (int64, error) {
+		mu.Lock()
+		read = append(read, bucket+"/"+object)
+		mu.Unlock()
+		if object == "missing" {
+			return 0, errors.New("not found")
+		}
+		return int64(len(object)), nil
+	}
+
+	c := &Client{projectID: "client-project-id"}
+	newJob := func(uris []string, counts []int64) *Job {
+		bqj := defaultExtractJob()
+		bqj.Configuration.Extract.DestinationUris = uris
+		bqj.Status = &bq.JobStatus{State: "DONE"}
+		bqj.Statistics = &bq.JobStatistics{Extract: &bq.JobStatistics4{DestinationUriFileCounts: counts}}
+		j, err := bqToJob(bqj, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return j
+	}
+
+	j := newJob([]string{"gs://b/out-*.csv", "gs://c/one.csv"}, []int64{2, 1})
+	got, err := j.ExtractedFiles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*ExtractedFile{
+		{URI: "gs://b/out-000000000000.csv", Bucket: "b", Object: "out-000000000000.csv", Size: 20},
+		{URI: "gs://b/out-000000000001.csv", Bucket: "b", Object: "out-000000000001.csv", Size: 20},
+		{URI: "gs://c/one.csv", Bucket: "c", Object: "one.csv", Size: 7},
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+	if len(read) != 3 {
+		t.Errorf("read sizes of %v, want 3 objects", read)
+	}
+
+	j = newJob([]string{"gs://b/missing"}, []int64{1})
+	if _, err := j.ExtractedFiles(context.Background()); err == nil {
+		t.Error("missing object: got nil, want error")
+	}
+}
@@ -16,31 +16,79 @@ package bigquery
 
 import (
 	"io"
+	"time"
 
 	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
 )
 
 // A ReaderSource is a source for a load operation that gets
 // data from an io.Reader.
 //
+// The data is sent with a resumable upload, in chunks of ChunkSize bytes, so
+// only one chunk is held in memory at a time, and a chunk that fails to upload
+// is sent again without starting the upload over.
+//
 // When a ReaderSource is part of a LoadConfig obtained via Job.Config,
 // its internal io.Reader will be nil, so it cannot be used for a
 // subsequent load operation.
 type ReaderSource struct {
 	r io.Reader
 	FileConfig
+
+	// ChunkSize is the number of bytes of data that are buffered and sent
+	// in each request of the upload. It is rounded up to a multiple of
+	// googleapi.MinUploadChunkSize (256 KiB). Data smaller than ChunkSize is
+	// sent in a single request.
+	//
+	// NewReaderSource sets ChunkSize to googleapi.DefaultUploadChunkSize
+	// (16 MiB). Larger chunks need fewer requests, which speeds up large
+	// uploads, at the cost of more memory. If ChunkSize is zero, the data is
+	// sent in a single request, which is not retried if it fails.
+	ChunkSize int
+
+	// ChunkRetryDeadline is how long a chunk that fails to upload is retried.
+	// If zero, it is retried for 32 seconds. To limit the time of the whole
+	// upload, use the deadline of the context passed to Loader.Run.
+	ChunkRetryDeadline time.Duration
+
+	// ProgressFunc, if not nil, is called with the total number of bytes
+	// uploaded so far after each chunk is sent. It should return quickly, as
+	// the upload waits for it.
+	ProgressFunc func(int64)
 }
 
 // NewReaderSource creates a ReaderSource from an io.Reader. You may
 // optionally configure properties on the ReaderSource that describe the
 // data being read, before passing it to Table.LoaderFrom.
 func NewReaderSource(r io.Reader) *ReaderSource {
-	return &ReaderSource{r: r}
+	return &ReaderSource{r: r, ChunkSize: googleapi.DefaultUploadChunkSize}
 }
 
-func (r *ReaderSource) populateLoadConfig(lc *bq.JobConfigurationLoad) io.Reader {
+func (r *ReaderSource) populateLoadConfig(lc *bq.JobConfigurationLoad) *mediaUpload {
 	r.FileConfig.populateLoadConfig(lc)
-	return r.r
+	if r.r == nil {
+		return nil
+	}
+	m := &mediaUpload{
+		r:    r.r,
+		opts: []googleapi.MediaOption{googleapi.ChunkSize(r.ChunkSize)},
+	}
+	if r.ChunkRetryDeadline != 0 {
+		m.opts = append(m.opts, googleapi.ChunkRetryDeadline(r.ChunkRetryDeadline))
+	}
+	if r.ProgressFunc != nil {
+		m.progress = func(current, _ int64) { r.ProgressFunc(current) }
+	}
+	return m
+}
+
+// A mediaUpload is data that is uploaded with a job, and the options of its
+// upload.
+type mediaUpload struct {
+	r        io.Reader
+	opts     []googleapi.MediaOption
+	progress googleapi.ProgressUpdater
 }
 
 // FileConfig contains configuration options that pertain to files, typically
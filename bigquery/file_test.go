@@ -15,10 +15,13 @@
 package bigquery
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/internal/testutil"
 	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
 )
 
 var (
@@ -187,3 +190,35 @@ func TestFileConfigPopulateExternalDataConfig(t *testing.T) {
 	}
 
 }
+
+func TestReaderSourceMedia(t *testing.T) {
+	lc := &bq.JobConfigurationLoad{}
+	if m := NewReaderSource(nil).populateLoadConfig(lc); m != nil {
+		t.Errorf("nil reader: got media %+v, want nil", m)
+	}
+
+	rs := NewReaderSource(strings.NewReader("a,b\n"))
+	m := rs.populateLoadConfig(lc)
+	want := &googleapi.MediaOptions{ChunkSize: googleapi.DefaultUploadChunkSize}
+	if got := googleapi.ProcessMediaOptions(m.opts); !testutil.Equal(got, want) {
+		t.Errorf("default options: got %+v, want %+v", got, want)
+	}
+	if m.progress != nil {
+		t.Error("default options: got a progress updater, want nil")
+	}
+
+	var progress []int64
+	rs.ChunkSize = 1
+	rs.ChunkRetryDeadline = time.Minute
+	rs.ProgressFunc = func(n int64) { progress = append(progress, n) }
+	m = rs.populateLoadConfig(lc)
+	want = &googleapi.MediaOptions{ChunkSize: googleapi.MinUploadChunkSize, ChunkRetryDeadline: time.Minute}
+	if got := googleapi.ProcessMediaOptions(m.opts); !testutil.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	m.progress(10, 0)
+	m.progress(20, 0)
+	if !testutil.Equal(progress, []int64{10, 20}) {
+		t.Errorf("got progress %v, want [10 20]", progress)
+	}
+}
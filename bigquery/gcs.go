@@ -15,8 +15,6 @@
 package bigquery
 
 import (
-	"io"
-
 	bq "google.golang.org/api/bigquery/v2"
 )
 
@@ -65,7 +63,7 @@ const (
 	Snappy Compression = "SNAPPY"
 )
 
-func (gcs *GCSReference) populateLoadConfig(lc *bq.JobConfigurationLoad) io.Reader {
+func (gcs *GCSReference) populateLoadConfig(lc *bq.JobConfigurationLoad) *mediaUpload {
 	lc.SourceUris = gcs.URIs
 	gcs.FileConfig.populateLoadConfig(lc)
 	return nil
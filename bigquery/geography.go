@@ -0,0 +1,360 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Geography is a Go type for representing BigQuery GEOGRAPHY values, which are
+// sets of points, lines and polygons on the surface of the Earth. The value is
+// held in Well-Known Text (WKT) format, which is how BigQuery returns it, for
+// example "POINT(-122.35 47.62)".
+//
+// Use Geography, or *Geography for values that may be NULL, for GEOGRAPHY
+// query parameters and for the fields of structs that GEOGRAPHY columns are
+// read into, written from or inferred from.
+//
+// See https://cloud.google.com/bigquery/docs/reference/standard-sql/data-types#geography_type
+// for more information.
+type Geography struct {
+	// WKT is the value in Well-Known Text format.
+	WKT string
+}
+
+// String returns the WKT of the geography.
+func (g Geography) String() string { return g.WKT }
+
+// GeographyFromGeoJSON returns the Geography of a GeoJSON geometry object, as
+// described in RFC 7946. Positions with an altitude are not supported, as
+// GEOGRAPHY values have none.
+func GeographyFromGeoJSON(b []byte) (Geography, error) {
+	var g geoJSONGeometry
+	if err := json.Unmarshal(b, &g); err != nil {
+		return Geography{}, fmt.Errorf("bigquery: invalid GeoJSON: %v", err)
+	}
+	var sb strings.Builder
+	if err := g.writeWKT(&sb); err != nil {
+		return Geography{}, err
+	}
+	return Geography{WKT: sb.String()}, nil
+}
+
+// GeoJSON returns the geography as a GeoJSON geometry object, as described in
+// RFC 7946. It supports the POINT, LINESTRING, POLYGON, MULTIPOINT,
+// MULTILINESTRING, MULTIPOLYGON and GEOMETRYCOLLECTION types of WKT, which are
+// those that BigQuery returns.
+func (g Geography) GeoJSON() ([]byte, error) {
+	p := &wktParser{s: g.WKT}
+	geom, err := p.geometry()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, p.errorf("unexpected text")
+	}
+	return json.Marshal(geom)
+}
+
+// geoJSONTypes maps WKT geometry types to GeoJSON types.
+var geoJSONTypes = map[string]string{
+	"POINT":              "Point",
+	"LINESTRING":         "LineString",
+	"POLYGON":            "Polygon",
+	"MULTIPOINT":         "MultiPoint",
+	"MULTILINESTRING":    "MultiLineString",
+	"MULTIPOLYGON":       "MultiPolygon",
+	"GEOMETRYCOLLECTION": "GeometryCollection",
+}
+
+// geoJSONDepths are the nesting depths of the coordinates of GeoJSON types; a
+// position has depth 1.
+var geoJSONDepths = map[string]int{
+	"Point":           1,
+	"LineString":      2,
+	"Polygon":         3,
+	"MultiPoint":      2,
+	"MultiLineString": 3,
+	"MultiPolygon":    4,
+}
+
+// geoJSONGeometry is a GeoJSON geometry object.
+type geoJSONGeometry struct {
+	Type        string             `json:"type"`
+	Coordinates interface{}        `json:"coordinates,omitempty"`
+	Geometries  []*geoJSONGeometry `json:"geometries,omitempty"`
+}
+
+// MarshalJSON always includes the coordinates or geometries member, even if
+// the geometry is empty.
+func (g *geoJSONGeometry) MarshalJSON() ([]byte, error) {
+	if g.Type == "GeometryCollection" {
+		geoms := g.Geometries
+		if geoms == nil {
+			geoms = []*geoJSONGeometry{}
+		}
+		return json.Marshal(struct {
+			Type       string             `json:"type"`
+			Geometries []*geoJSONGeometry `json:"geometries"`
+		}{g.Type, geoms})
+	}
+	return json.Marshal(struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{g.Type, g.Coordinates})
+}
+
+func (g *geoJSONGeometry) writeWKT(sb *strings.Builder) error {
+	var wktType string
+	for w, t := range geoJSONTypes {
+		if t == g.Type {
+			wktType = w
+		}
+	}
+	if wktType == "" {
+		return fmt.Errorf("bigquery: unsupported GeoJSON type %q", g.Type)
+	}
+	sb.WriteString(wktType)
+	if g.Type == "GeometryCollection" {
+		if len(g.Geometries) == 0 {
+			sb.WriteString(" EMPTY")
+			return nil
+		}
+		sb.WriteByte('(')
+		for i, geom := range g.Geometries {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if geom == nil {
+				return errors.New("bigquery: invalid GeoJSON: null geometry")
+			}
+			if err := geom.writeWKT(sb); err != nil {
+				return err
+			}
+		}
+		sb.WriteByte(')')
+		return nil
+	}
+	depth := geoJSONDepths[g.Type]
+	if cs, ok := g.Coordinates.([]interface{}); ok && len(cs) == 0 {
+		sb.WriteString(" EMPTY")
+		return nil
+	}
+	if depth == 1 {
+		// A point's position is enclosed in parentheses, unlike the positions
+		// of other geometries.
+		sb.WriteByte('(')
+		defer sb.WriteByte(')')
+	}
+	return writeWKTCoordinates(sb, g.Coordinates, depth)
+}
+
+// writeWKTCoordinates writes the coordinates of the given depth in WKT.
+func writeWKTCoordinates(sb *strings.Builder, c interface{}, depth int) error {
+	cs, ok := c.([]interface{})
+	if !ok {
+		return fmt.Errorf("bigquery: invalid GeoJSON coordinates %v", c)
+	}
+	if depth == 1 {
+		if len(cs) != 2 {
+			return fmt.Errorf("bigquery: GeoJSON position %v must have two coordinates", c)
+		}
+		for i, x := range cs {
+			f, ok := x.(float64)
+			if !ok {
+				return fmt.Errorf("bigquery: invalid GeoJSON position %v", c)
+			}
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+		return nil
+	}
+	sb.WriteByte('(')
+	for i, x := range cs {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if err := writeWKTCoordinates(sb, x, depth-1); err != nil {
+			return err
+		}
+	}
+	sb.WriteByte(')')
+	return nil
+}
+
+// wktParser parses WKT into GeoJSON geometries.
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("bigquery: invalid WKT %q at offset %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *wktParser) done() bool {
+	p.skipSpace()
+	return p.pos == len(p.s)
+}
+
+// peek reports whether the next token is the punctuation c.
+func (p *wktParser) peek(c byte) bool {
+	p.skipSpace()
+	return p.pos < len(p.s) && p.s[p.pos] == c
+}
+
+func (p *wktParser) expect(c byte) error {
+	if !p.peek(c) {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// word returns the next word, in upper case.
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos] | 0x20
+		if c < 'a' || c > 'z' {
+			break
+		}
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+func (p *wktParser) number() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("0123456789+-.eE", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return 0, p.errorf("expected a number")
+	}
+	return f, nil
+}
+
+func (p *wktParser) geometry() (*geoJSONGeometry, error) {
+	w := p.word()
+	typ, ok := geoJSONTypes[w]
+	if !ok {
+		return nil, p.errorf("unsupported geometry type %q", w)
+	}
+	g := &geoJSONGeometry{Type: typ}
+	empty := false
+	if !p.peek('(') {
+		if w := p.word(); w != "EMPTY" {
+			return nil, p.errorf("unexpected %q", w)
+		}
+		empty = true
+	}
+	if typ == "GeometryCollection" {
+		g.Geometries = []*geoJSONGeometry{}
+		if empty {
+			return g, nil
+		}
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		for {
+			geom, err := p.geometry()
+			if err != nil {
+				return nil, err
+			}
+			g.Geometries = append(g.Geometries, geom)
+			if !p.peek(',') {
+				break
+			}
+			p.pos++
+		}
+		return g, p.expect(')')
+	}
+	if empty {
+		g.Coordinates = []interface{}{}
+		return g, nil
+	}
+	depth := geoJSONDepths[typ]
+	if depth == 1 {
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		pos, err := p.coordinates(1, false)
+		if err != nil {
+			return nil, err
+		}
+		g.Coordinates = pos
+		return g, p.expect(')')
+	}
+	var err error
+	g.Coordinates, err = p.coordinates(depth, typ == "MultiPoint")
+	return g, err
+}
+
+// coordinates parses coordinates of the given depth. A position has depth 1,
+// and is optionally enclosed in parentheses if it is a point of a multipoint.
+func (p *wktParser) coordinates(depth int, multiPoint bool) ([]interface{}, error) {
+	if depth == 1 {
+		if multiPoint && p.peek('(') {
+			p.pos++
+			pos, err := p.coordinates(1, false)
+			if err != nil {
+				return nil, err
+			}
+			return pos, p.expect(')')
+		}
+		x, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		y, err := p.number()
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{x, y}, nil
+	}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var cs []interface{}
+	for {
+		c, err := p.coordinates(depth-1, multiPoint)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+		if !p.peek(',') {
+			break
+		}
+		p.pos++
+	}
+	return cs, p.expect(')')
+}
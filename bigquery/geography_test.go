@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"testing"
+)
+
+func TestGeographyGeoJSON(t *testing.T) {
+	for _, test := range []struct {
+		wkt     string
+		geoJSON string
+	}{
+		{"POINT(-122.35 47.62)", `{"type":"Point","coordinates":[-122.35,47.62]}`},
+		{"POINT EMPTY", `{"type":"Point","coordinates":[]}`},
+		{"LINESTRING(1 2, 3 4)", `{"type":"LineString","coordinates":[[1,2],[3,4]]}`},
+		{"POLYGON((0 0, 2 0, 2 2, 0 0), (0.5 0.5, 1 0.5, 1 1, 0.5 0.5))",
+			`{"type":"Polygon","coordinates":[[[0,0],[2,0],[2,2],[0,0]],[[0.5,0.5],[1,0.5],[1,1],[0.5,0.5]]]}`},
+		{"MULTIPOINT(1 2, 3 4)", `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`},
+		{"MULTILINESTRING((1 2, 3 4), (5 6, 7 8))", `{"type":"MultiLineString","coordinates":[[[1,2],[3,4]],[[5,6],[7,8]]]}`},
+		{"MULTIPOLYGON(((0 0, 1 0, 1 1, 0 0)), ((2 2, 3 2, 3 3, 2 2)))",
+			`{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3],[2,2]]]]}`},
+		{"GEOMETRYCOLLECTION(POINT(1 2), LINESTRING(1 2, 3 4))",
+			`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1,2]},{"type":"LineString","coordinates":[[1,2],[3,4]]}]}`},
+		{"GEOMETRYCOLLECTION EMPTY", `{"type":"GeometryCollection","geometries":[]}`},
+	} {
+		got, err := Geography{WKT: test.wkt}.GeoJSON()
+		if err != nil {
+			t.Errorf("%s: %v", test.wkt, err)
+		} else if string(got) != test.geoJSON {
+			t.Errorf("%s: got %s, want %s", test.wkt, got, test.geoJSON)
+		}
+		g, err := GeographyFromGeoJSON([]byte(test.geoJSON))
+		if err != nil {
+			t.Errorf("%s: %v", test.geoJSON, err)
+		} else if g.WKT != test.wkt {
+			t.Errorf("%s: got %q, want %q", test.geoJSON, g.WKT, test.wkt)
+		}
+	}
+}
+
+func TestGeographyGeoJSONParsing(t *testing.T) {
+	// Variations of WKT that BigQuery accepts.
+	for _, test := range []struct {
+		wkt     string
+		geoJSON string
+	}{
+		{" point ( 1e2  -2.5 ) ", `{"type":"Point","coordinates":[100,-2.5]}`},
+		{"MULTIPOINT((1 2), (3 4))", `{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`},
+		{"LineString(1 2,3 4)", `{"type":"LineString","coordinates":[[1,2],[3,4]]}`},
+	} {
+		got, err := Geography{WKT: test.wkt}.GeoJSON()
+		if err != nil {
+			t.Errorf("%s: %v", test.wkt, err)
+		} else if string(got) != test.geoJSON {
+			t.Errorf("%s: got %s, want %s", test.wkt, got, test.geoJSON)
+		}
+	}
+}
+
+func TestGeographyGeoJSONErrors(t *testing.T) {
+	for _, wkt := range []string{
+		"",
+		"CIRCLE(1 2)",
+		"POINT(1)",
+		"POINT(1 2",
+		"POINT(1 2) x",
+		"POINT Z(1 2 3)",
+		"LINESTRING((1 2))",
+		"GEOMETRYCOLLECTION(POINT(1 2),)",
+	} {
+		if _, err := (Geography{WKT: wkt}).GeoJSON(); err == nil {
+			t.Errorf("%q: got nil, want error", wkt)
+		}
+	}
+	for _, geoJSON := range []string{
+		`[]`,
+		`{"type":"Feature"}`,
+		`{"type":"Point","coordinates":[1,2,3]}`,
+		`{"type":"Point","coordinates":[[1,2]]}`,
+		`{"type":"LineString","coordinates":[1,2]}`,
+		`{"type":"GeometryCollection","geometries":[null]}`,
+	} {
+		if _, err := GeographyFromGeoJSON([]byte(geoJSON)); err == nil {
+			t.Errorf("%s: got nil, want error", geoJSON)
+		}
+	}
+}
@@ -7,12 +7,13 @@ require (
 	cloud.google.com/go/datacatalog v1.1.0
 	cloud.google.com/go/iam v0.1.1
 	cloud.google.com/go/storage v1.18.2
+	github.com/apache/arrow/go/v9 v9.0.0
 	github.com/golang/protobuf v1.5.2
 	github.com/google/go-cmp v0.5.7
 	github.com/googleapis/gax-go/v2 v2.1.1
 	go.opencensus.io v0.23.0
-	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
-	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
+	golang.org/x/sync v0.0.0-20220513210516-0976fa681c29
+	golang.org/x/xerrors v0.0.0-20220411194840-2f41105eb62f
 	google.golang.org/api v0.67.0
 	google.golang.org/genproto v0.0.0-20220207164111-0872dc986b00
 	google.golang.org/grpc v1.44.0
@@ -15,8 +15,10 @@
 package bigquery
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -607,6 +609,129 @@ func TestIntegration_SnapshotAndRestore(t *testing.T) {
 
 }
 
+func TestIntegration_SnapshotRestoreClone(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+
+	base := dataset.Table(tableIDs.New())
+	sql := fmt.Sprintf("CREATE TABLE `%s`.%s.%s AS SELECT n FROM UNNEST(GENERATE_ARRAY(1, 100)) AS n",
+		testutil.ProjID(), dataset.DatasetID, base.TableID)
+	if _, _, err := runQuerySQL(ctx, sql); err != nil {
+		t.Fatalf("couldn't instantiate base table: %v", err)
+	}
+	defer base.Delete(ctx)
+
+	run := func(desc string, job *Job, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", desc, err)
+		}
+		if err := wait(ctx, job); err != nil {
+			t.Fatalf("%s: %v", desc, err)
+		}
+	}
+	check := func(table *Table, wantType TableType) *TableMetadata {
+		t.Helper()
+		meta, err := table.Metadata(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.Type != wantType || meta.NumRows != 100 {
+			t.Errorf("%s: got type %s with %d rows, want %s with 100 rows", table.TableID, meta.Type, meta.NumRows, wantType)
+		}
+		return meta
+	}
+
+	snapshot := dataset.Table(tableIDs.New())
+	expiration := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	job, err := base.CreateSnapshot(ctx, snapshot, expiration)
+	run("snapshot", job, err)
+	defer snapshot.Delete(ctx)
+	meta := check(snapshot, Snapshot)
+	if !meta.ExpirationTime.Equal(expiration) {
+		t.Errorf("snapshot expiration: got %v, want %v", meta.ExpirationTime, expiration)
+	}
+
+	restored := dataset.Table(tableIDs.New())
+	job, err = snapshot.RestoreSnapshot(ctx, restored)
+	run("restore", job, err)
+	defer restored.Delete(ctx)
+	check(restored, RegularTable)
+
+	clone := dataset.Table(tableIDs.New())
+	job, err = base.Clone(ctx, clone)
+	run("clone", job, err)
+	defer clone.Delete(ctx)
+	check(clone, RegularTable)
+}
+
+func TestIntegration_RowAccessPolicies(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+
+	table := dataset.Table(tableIDs.New())
+	sql := fmt.Sprintf("CREATE TABLE `%s`.%s.%s AS SELECT n FROM UNNEST(GENERATE_ARRAY(1, 10)) AS n",
+		testutil.ProjID(), dataset.DatasetID, table.TableID)
+	if _, _, err := runQuerySQL(ctx, sql); err != nil {
+		t.Fatalf("couldn't instantiate table: %v", err)
+	}
+	defer table.Delete(ctx)
+
+	list := func() map[string]string {
+		t.Helper()
+		got := map[string]string{}
+		it := table.RowAccessPolicies(ctx)
+		for {
+			md, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if md.CreationTime.IsZero() {
+				t.Errorf("%s: missing creation time", md.PolicyID)
+			}
+			got[md.PolicyID] = md.FilterPredicate
+		}
+		return got
+	}
+
+	small, large := table.RowAccessPolicy("small"), table.RowAccessPolicy("large")
+	if err := small.Create(ctx, &RowAccessPolicyMetadata{FilterPredicate: "n <= 5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := large.Create(ctx, &RowAccessPolicyMetadata{FilterPredicate: "n > 5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := small.Create(ctx, &RowAccessPolicyMetadata{FilterPredicate: "n <= 5"}); err == nil {
+		t.Error("creating an existing policy: got nil error, want non-nil")
+	}
+	if err := small.Replace(ctx, &RowAccessPolicyMetadata{FilterPredicate: "n < 3"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list(), map[string]string{"small": "n < 3", "large": "n > 5"}; !testutil.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := large.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list(), map[string]string{"small": "n < 3"}; !testutil.Equal(got, want) {
+		t.Errorf("after delete: got %v, want %v", got, want)
+	}
+	if err := table.DeleteRowAccessPolicies(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := list(); len(got) != 0 {
+		t.Errorf("after deleting all: got %v, want no policies", got)
+	}
+}
+
 func TestIntegration_HourTimePartitioning(t *testing.T) {
 	if client == nil {
 		t.Skip("Integration tests skipped")
@@ -1171,6 +1296,38 @@ func TestIntegration_ColumnACLs(t *testing.T) {
 		t.Errorf("update with policyTag failed: %v", err)
 	}
 
+	// Test: Updating the schema from a source without policy tags keeps them.
+	md, err := table.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err = table.Update(ctx, TableMetadataToUpdate{
+		Schema: Schema{
+			{Name: "name", Type: StringFieldType},
+			{Name: "ssn", Type: StringFieldType},
+			{Name: "acct_balance", Type: NumericFieldType},
+			{Name: "opened", Type: DateFieldType},
+		}.PreservePolicyTags(md.Schema),
+	}, md.ETag)
+	if err != nil {
+		t.Fatalf("update preserving policy tags failed: %v", err)
+	}
+	if diff := testutil.Diff(md.Schema.PolicyTags(), map[string]*PolicyTagList{"ssn": {Names: []string{tagID}}}); diff != "" {
+		t.Errorf("policy tags after schema update: got=-, want=+:\n%s", diff)
+	}
+
+	// Test: Move the policy tag to another column.
+	md, err = table.UpdatePolicyTags(ctx, map[string]*PolicyTagList{
+		"ssn":          nil,
+		"acct_balance": {Names: []string{tagID}},
+	})
+	if err != nil {
+		t.Fatalf("UpdatePolicyTags: %v", err)
+	}
+	if diff := testutil.Diff(md.Schema.PolicyTags(), map[string]*PolicyTagList{"acct_balance": {Names: []string{tagID}}}); diff != "" {
+		t.Errorf("policy tags after UpdatePolicyTags: got=-, want=+:\n%s", diff)
+	}
+
 	// Test: Create a new table with a policy tag defined.
 	newTable := dataset.Table(tableIDs.New())
 	if err = newTable.Create(ctx, &TableMetadata{
@@ -1467,6 +1624,41 @@ func TestIntegration_InsertErrors(t *testing.T) {
 	}
 }
 
+func TestIntegration_BufferedInserter(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	table := newTable(t, schema)
+	defer table.Delete(ctx)
+
+	bi := table.Inserter().Buffered()
+	bi.Settings.CountThreshold = 2
+	var (
+		wantRows [][]Value
+		results  []*InsertResult
+	)
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		row := []Value{name, []Value{int64(i)}, []Value{true}}
+		wantRows = append(wantRows, row)
+		results = append(results, bi.Insert(ctx, &ValuesSaver{
+			Schema:   schema,
+			InsertID: name,
+			Row:      row,
+		}))
+	}
+	bi.Stop()
+	for i, r := range results {
+		if err := r.Get(ctx); err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+	}
+	if err := waitForRow(ctx, table); err != nil {
+		t.Fatal(err)
+	}
+	checkRead(t, "buffered insert", table.Read(ctx), wantRows)
+}
+
 func TestIntegration_InsertAndRead(t *testing.T) {
 	if client == nil {
 		t.Skip("Integration tests skipped")
@@ -1752,6 +1944,149 @@ func (b byName) Len() int           { return len(b) }
 func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
 func (b byName) Less(i, j int) bool { return b[i].Name < b[j].Name }
 
+func TestIntegration_StorageInserter(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	schema := Schema{
+		{Name: "name", Type: StringFieldType, Required: true},
+		{Name: "num", Type: IntegerFieldType},
+		{Name: "tags", Type: StringFieldType, Repeated: true},
+	}
+	for _, mode := range []StorageWriteMode{DefaultStreamMode, CommittedStreamMode, PendingStreamMode, BufferedStreamMode} {
+		table := newTable(t, schema)
+		defer table.Delete(ctx)
+
+		si, err := table.StorageInserter(ctx, mode)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		rows := []*ValuesSaver{
+			{Schema: schema, Row: []Value{"a", 1, []Value{"x"}}},
+			{Schema: schema, Row: []Value{"b", nil, []Value{}}},
+		}
+		if err := si.Put(ctx, rows); err != nil {
+			t.Fatalf("%s: Put: %v", mode, err)
+		}
+		if err := si.Put(ctx, &ValuesSaver{Schema: schema, Row: []Value{"c", 3, []Value{"y", "z"}}}); err != nil {
+			t.Fatalf("%s: Put: %v", mode, err)
+		}
+		switch mode {
+		case PendingStreamMode:
+			n, err := si.Commit(ctx)
+			if err != nil {
+				t.Fatalf("%s: Commit: %v", mode, err)
+			}
+			if n != 3 {
+				t.Errorf("%s: committed %d rows, want 3", mode, n)
+			}
+		case BufferedStreamMode:
+			if err := si.Flush(ctx); err != nil {
+				t.Fatalf("%s: Flush: %v", mode, err)
+			}
+		}
+		if err := si.Close(); err != nil {
+			t.Errorf("%s: Close: %v", mode, err)
+		}
+
+		q := client.Query(fmt.Sprintf("SELECT name, num, tags FROM %s.%s", table.DatasetID, table.TableID))
+		it, err := q.Read(ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		checkRead(t, string(mode), it, [][]Value{
+			{"a", int64(1), []Value{"x"}},
+			{"b", nil, []Value(nil)},
+			{"c", int64(3), []Value{"y", "z"}},
+		})
+	}
+}
+
+func TestIntegration_StorageRead(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	// A copy of the client shares its services and Storage API clients.
+	sc := *client
+	sc.StorageRead = &StorageReadConfig{MaxStreams: 4, MinRows: 1}
+
+	countRows := func(it *RowIterator) (int, []Value) {
+		var n int
+		var last []Value
+		for {
+			var row []Value
+			err := it.Next(&row)
+			if err == iterator.Done {
+				return n, last
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			n++
+			last = row
+		}
+	}
+
+	it := sc.DatasetInProject("bigquery-public-data", "samples").Table("shakespeare").Read(ctx)
+	n, _ := countRows(it)
+	if n == 0 || uint64(n) != it.TotalRows {
+		t.Errorf("Table.Read: got %d rows, want TotalRows = %d", n, it.TotalRows)
+	}
+
+	q := sc.Query(fmt.Sprintf("SELECT word, word_count FROM %s ORDER BY word_count, word", stdName))
+	it, err := q.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, last := countRows(it)
+	if n2 != n {
+		t.Errorf("Query.Read: got %d rows, want %d", n2, n)
+	}
+	// The rows of an ordered query are read from a single stream, in order.
+	q = sc.Query(fmt.Sprintf("SELECT word, word_count FROM %s ORDER BY word_count DESC, word DESC LIMIT 1", stdName))
+	it, err = q.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRead(t, "ORDER BY", it, [][]Value{last})
+}
+
+func TestIntegration_ArrowRecords(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it, err := client.Query(fmt.Sprintf("SELECT word, word_count FROM %s", stdName)).Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai, err := it.ArrowRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(ai.Schema().Fields()); got != 2 {
+		t.Errorf("got %d fields in Arrow schema, want 2", got)
+	}
+	var rows int64
+	for {
+		rec, err := ai.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	if rows == 0 {
+		t.Error("got no rows")
+	}
+}
+
 func TestIntegration_InsertAndReadNullable(t *testing.T) {
 	if client == nil {
 		t.Skip("Integration tests skipped")
@@ -2024,6 +2359,88 @@ func TestIntegration_QueryStatistics(t *testing.T) {
 	}
 }
 
+func TestIntegration_ClientJobDefaults(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	c := *client
+	c.DefaultJobLabels = map[string]string{"team": "default", "cost-center": "42"}
+	c.DefaultQueryPriority = BatchPriority
+	c.DefaultJobCreationMode = JobCreationRequired
+
+	q := c.Query("SELECT 1")
+	q.Labels = map[string]string{"team": "bq-go"}
+	job, err := q.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := job.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	qc := config.(*QueryConfig)
+	if want := map[string]string{"team": "bq-go", "cost-center": "42"}; !testutil.Equal(qc.Labels, want) {
+		t.Errorf("query labels: got %v, want %v", qc.Labels, want)
+	}
+	if qc.Priority != BatchPriority {
+		t.Errorf("query priority: got %q, want %q", qc.Priority, BatchPriority)
+	}
+	if err := wait(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	table := newTable(t, schema)
+	defer table.Delete(ctx)
+	dst := dataset.Table(tableIDs.New())
+	job, err = dst.CopierFrom(table).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Delete(ctx)
+	if err := wait(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	config, err = job.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.(*CopyConfig).Labels; !testutil.Equal(got, c.DefaultJobLabels) {
+		t.Errorf("copy labels: got %v, want %v", got, c.DefaultJobLabels)
+	}
+}
+
+func TestIntegration_QueryRequireCacheHit(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	// The unique alias keeps the query out of the cache of earlier runs.
+	sql := fmt.Sprintf("SELECT SUM(word_count) AS %s FROM %s", tableIDs.New(), stdName)
+
+	// The first run populates the cache, so it cannot require a cache hit.
+	q := client.Query(sql)
+	q.RequireCacheHit = true
+	if _, err := q.Read(ctx); err == nil {
+		t.Fatal("uncached query with RequireCacheHit: got nil, want error")
+	}
+	if _, err := client.Query(sql).Read(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	it, err := q.Read(ctx)
+	if err != nil {
+		t.Fatalf("cached query with RequireCacheHit: %v", err)
+	}
+	qStats, err := it.QueryStatistics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !qStats.CacheHit {
+		t.Error("got no cache hit, want one")
+	}
+}
+
 func TestIntegration_Load(t *testing.T) {
 	if client == nil {
 		t.Skip("Integration tests skipped")
@@ -2077,6 +2494,47 @@ func TestIntegration_Load(t *testing.T) {
 
 }
 
+func TestIntegration_LoadChunked(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	table := newTable(t, Schema{
+		{Name: "name", Type: StringFieldType},
+		{Name: "num", Type: IntegerFieldType},
+	})
+	defer table.Delete(ctx)
+
+	// Upload the data in several chunks.
+	const numRows = 100000
+	var buf bytes.Buffer
+	for i := 0; i < numRows; i++ {
+		fmt.Fprintf(&buf, "row%d,%d\n", i, i)
+	}
+	size := int64(buf.Len())
+	rs := NewReaderSource(&buf)
+	rs.ChunkSize = googleapi.MinUploadChunkSize
+	var progress []int64
+	rs.ProgressFunc = func(n int64) { progress = append(progress, n) }
+	job, err := table.LoaderFrom(rs).Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if len(progress) < 2 || progress[len(progress)-1] != size {
+		t.Errorf("got progress %v, want several chunks ending at %d", progress, size)
+	}
+	md, err := table.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.NumRows != numRows {
+		t.Errorf("got %d rows, want %d", md.NumRows, numRows)
+	}
+}
+
 func TestIntegration_DML(t *testing.T) {
 	if client == nil {
 		t.Skip("Integration tests skipped")
@@ -2402,22 +2860,77 @@ func TestIntegration_QuerySessionSupport(t *testing.T) {
 
 }
 
-func TestIntegration_QueryParameters(t *testing.T) {
+func TestIntegration_SessionTransaction(t *testing.T) {
 	if client == nil {
 		t.Skip("Integration tests skipped")
 	}
 	ctx := context.Background()
+	table := newTable(t, Schema{{Name: "n", Type: IntegerFieldType}})
+	defer table.Delete(ctx)
 
-	d := civil.Date{Year: 2016, Month: 3, Day: 20}
-	tm := civil.Time{Hour: 15, Minute: 04, Second: 05, Nanosecond: 3008}
-	rtm := tm
-	rtm.Nanosecond = 3000 // round to microseconds
-	dtm := civil.DateTime{Date: d, Time: tm}
-	ts := time.Date(2016, 3, 20, 15, 04, 05, 0, time.UTC)
-	rat := big.NewRat(13, 10)
-
-	type ss struct {
-		String string
+	s, err := client.CreateSession(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Abort(ctx)
+	if s.ID() == "" {
+		t.Fatal("got empty session ID")
+	}
+	run := func(ctx context.Context, sql string) error {
+		job, err := s.Query(sql).Run(ctx)
+		if err != nil {
+			return err
+		}
+		status, err := job.Wait(ctx)
+		if err != nil {
+			return err
+		}
+		return status.Err()
+	}
+
+	if err := run(ctx, "CREATE TEMP TABLE nums AS SELECT n FROM UNNEST([1, 2]) AS n"); err != nil {
+		t.Fatal(err)
+	}
+	insert := fmt.Sprintf("INSERT %s.%s (n) SELECT n FROM _SESSION.nums", table.DatasetID, table.TableID)
+	err = s.RunInTransaction(ctx, func(ctx context.Context) error {
+		return run(ctx, insert)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantErr := errors.New("abandoned")
+	err = s.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := run(ctx, insert); err != nil {
+			return err
+		}
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("got %v, want %v", err, wantErr)
+	}
+	it, err := s.Query(fmt.Sprintf("SELECT n FROM %s.%s ORDER BY n", table.DatasetID, table.TableID)).Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRead(t, "committed rows", it, [][]Value{{int64(1)}, {int64(2)}})
+}
+
+func TestIntegration_QueryParameters(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+
+	d := civil.Date{Year: 2016, Month: 3, Day: 20}
+	tm := civil.Time{Hour: 15, Minute: 04, Second: 05, Nanosecond: 3008}
+	rtm := tm
+	rtm.Nanosecond = 3000 // round to microseconds
+	dtm := civil.DateTime{Date: d, Time: tm}
+	ts := time.Date(2016, 3, 20, 15, 04, 05, 0, time.UTC)
+	rat := big.NewRat(13, 10)
+
+	type ss struct {
+		String string
 	}
 
 	type s struct {
@@ -2579,6 +3092,33 @@ func TestIntegration_QueryDryRun(t *testing.T) {
 	}
 }
 
+func TestIntegration_QueryEstimate(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	q := client.Query("SELECT word from " + stdName + " LIMIT 10")
+	e, err := q.Estimate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.DryRun {
+		t.Error("Estimate modified the query")
+	}
+	if e.TotalBytesProcessed == 0 || e.TotalBytesProcessedAccuracy == "" {
+		t.Errorf("got estimate %+v, want bytes processed and accuracy", e)
+	}
+	if len(e.ReferencedTables) != 1 || e.ReferencedTables[0].TableID != "shakespeare" {
+		t.Errorf("got referenced tables %v, want the shakespeare table", e.ReferencedTables)
+	}
+	if e.StatementType != "SELECT" || e.Schema == nil {
+		t.Errorf("got statement type %q and schema %v", e.StatementType, e.Schema)
+	}
+	if e.OnDemandCost(DefaultOnDemandPricePerTiB) <= 0 {
+		t.Error("got zero cost, want positive")
+	}
+}
+
 func TestIntegration_Scripting(t *testing.T) {
 	if client == nil {
 		t.Skip("Integration tests skipped")
@@ -2670,6 +3210,282 @@ func TestIntegration_Scripting(t *testing.T) {
 
 }
 
+func TestIntegration_ScriptStatements(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	job, err := client.Query("SELECT 17 AS n;\nSELECT ERROR('statement failed');").Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Err() == nil {
+		t.Fatal("script succeeded, want error")
+	}
+
+	var stmts []*ScriptStatement
+	it := job.ScriptStatements(ctx)
+	for {
+		s, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmts = append(stmts, s)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, want 2", len(stmts))
+	}
+	// The statements are listed in reverse order of execution.
+	failed, first := stmts[0], stmts[1]
+	if failed.Err == nil || !strings.Contains(failed.SQL, "ERROR") {
+		t.Errorf("got statement %q with error %v, want the failed statement", failed.SQL, failed.Err)
+	}
+	if len(failed.StackFrames) == 0 || failed.StackFrames[0].StartLine != 2 {
+		t.Errorf("got stack frames %+v, want a frame at line 2", failed.StackFrames)
+	}
+	if first.Err != nil || first.EvaluationKind != "STATEMENT" {
+		t.Errorf("got first statement %q with kind %q and error %v, want a successful statement", first.SQL, first.EvaluationKind, first.Err)
+	}
+	rit, err := first.Job.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row []Value
+	if err := rit.Next(&row); err != nil {
+		t.Fatal(err)
+	}
+	if !testutil.Equal(row, []Value{int64(17)}) {
+		t.Errorf("got row %v, want [17]", row)
+	}
+}
+
+func TestIntegration_RowIteratorCheckpoint(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	it, err := client.Query("SELECT n FROM UNNEST(GENERATE_ARRAY(0, 99)) AS n ORDER BY n").Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it.PageInfo().MaxSize = 30
+	read := func(it *RowIterator, n int) []int64 {
+		var got []int64
+		for i := 0; n < 0 || i < n; i++ {
+			var row []Value
+			err := it.Next(&row)
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, row[0].(int64))
+		}
+		return got
+	}
+	got := read(it, 45)
+	cp, err := it.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp2 RowIteratorCheckpoint
+	if err := json.Unmarshal(b, &cp2); err != nil {
+		t.Fatal(err)
+	}
+	it2, err := client.ResumeRowIterator(ctx, &cp2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, read(it2, -1)...)
+	if len(got) != 100 {
+		t.Fatalf("got %d rows, want 100", len(got))
+	}
+	for i, n := range got {
+		if n != int64(i) {
+			t.Fatalf("row %d: got %d", i, n)
+		}
+	}
+}
+
+func TestIntegration_Partitions(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	table := dataset.Table(tableIDs.New())
+	err := table.Create(ctx, &TableMetadata{
+		Schema: Schema{
+			{Name: "day", Type: DateFieldType},
+			{Name: "num", Type: IntegerFieldType},
+		},
+		TimePartitioning: &TimePartitioning{Field: "day"},
+		ExpirationTime:   testTableExpiration,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Delete(ctx)
+	sql := fmt.Sprintf(`INSERT %s.%s (day, num)
+		                VALUES ('2022-03-01', 1), ('2022-03-01', 2), ('2022-03-02', 3)`,
+		table.DatasetID, table.TableID)
+	if _, _, err := runQuerySQL(ctx, sql); err != nil {
+		t.Fatal(err)
+	}
+
+	partitions, err := table.Partitions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, p := range partitions {
+		ids = append(ids, p.ID)
+		if p.ID == "20220301" && p.TotalRows != 2 {
+			t.Errorf("%s: got %d rows, want 2", p.ID, p.TotalRows)
+		}
+		if p.LastModifiedTime.IsZero() {
+			t.Errorf("%s: got zero LastModifiedTime", p.ID)
+		}
+	}
+	if diff := testutil.Diff(ids, []string{"20220301", "20220302"}); diff != "" {
+		t.Errorf("partition IDs: got=-, want=+:\n%s", diff)
+	}
+
+	day := civil.Date{Year: 2022, Month: 3, Day: 1}.In(time.UTC)
+	if err := table.DeletePartitions(ctx, TimePartitionID(day, DayPartitioningType), "20220303"); err != nil {
+		t.Fatal(err)
+	}
+	it := table.Read(ctx)
+	checkRead(t, "after deleting a partition", it, [][]Value{{civil.Date{Year: 2022, Month: 3, Day: 2}, int64(3)}})
+
+	if err := client.UpdatePartitionExpiration(ctx, []*Table{table}, 30*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	md, err := table.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := md.TimePartitioning.Expiration, 30*24*time.Hour; got != want {
+		t.Errorf("partition expiration: got %v, want %v", got, want)
+	}
+	if got, want := md.TimePartitioning.Field, "day"; got != want {
+		t.Errorf("partitioning field: got %q, want %q", got, want)
+	}
+}
+
+func TestIntegration_Geography(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	type place struct {
+		Name     string
+		Location Geography
+		Area     *Geography
+	}
+	schema, err := InferSchema(place{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := newTable(t, schema)
+	defer table.Delete(ctx)
+
+	want := []*place{
+		{Name: "a", Location: Geography{"POINT(1 2)"}, Area: &Geography{"POLYGON((0 0, 1 0, 1 1, 0 0))"}},
+		{Name: "b", Location: Geography{"POINT(3 4)"}},
+	}
+	if err := table.Inserter().Put(ctx, want); err != nil {
+		t.Fatal(putError(err))
+	}
+	q := client.Query(fmt.Sprintf("SELECT * FROM %s WHERE ST_DWITHIN(Location, @p, 1) ORDER BY Name", table.FullyQualifiedName()))
+	q.Parameters = []QueryParameter{{Name: "p", Value: Geography{"POINT(1 2)"}}}
+	for i := 0; ; i++ {
+		it, err := q.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []*place
+		for {
+			var p place
+			err := it.Next(&p)
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, &p)
+		}
+		// Rows inserted by streaming may not be visible to queries at once.
+		if len(got) == 0 && i < 5 {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if diff := testutil.Diff(got, want[:1]); diff != "" {
+			t.Errorf("got=-, want=+:\n%s", diff)
+		}
+		break
+	}
+}
+
+func TestIntegration_ExtractFiles(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	schema := Schema{
+		{Name: "name", Type: StringFieldType},
+		{Name: "num", Type: IntegerFieldType},
+	}
+	table := newTable(t, schema)
+	defer table.Delete(ctx)
+	sql := fmt.Sprintf(`INSERT %s.%s (name, num)
+		                VALUES ('a', 1), ('b', 2), ('c', 3)`,
+		table.DatasetID, table.TableID)
+	if _, _, err := runQuerySQL(ctx, sql); err != nil {
+		t.Fatal(err)
+	}
+
+	bucketName := testutil.ProjID()
+	uri := fmt.Sprintf("gs://%s/bq-test-%s-*.csv", bucketName, table.TableID)
+	gr := NewGCSReference(uri)
+	gr.DestinationFormat = CSV
+	files, err := table.ExtractorTo(gr).Extract(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("got no extracted files")
+	}
+	for _, f := range files {
+		defer storageClient.Bucket(f.Bucket).Object(f.Object).Delete(ctx)
+		attrs, err := storageClient.Bucket(f.Bucket).Object(f.Object).Attrs(ctx)
+		if err != nil {
+			t.Errorf("%s: %v", f.URI, err)
+			continue
+		}
+		if f.Size != attrs.Size || f.Size == 0 {
+			t.Errorf("%s: got size %d, want %d", f.URI, f.Size, attrs.Size)
+		}
+	}
+
+	gr = NewGCSReference(fmt.Sprintf("gs://%s/*-*.csv", bucketName))
+	if _, err := table.ExtractorTo(gr).Extract(ctx); err == nil {
+		t.Error("URI with two wildcards: got nil, want error")
+	}
+}
+
 func TestIntegration_ExtractExternal(t *testing.T) {
 	// Create a table, extract it to GCS, then query it externally.
 	if client == nil {
@@ -3211,11 +4027,127 @@ func TestIntegration_MaterializedViewLifecycle(t *testing.T) {
 		t.Error("materialized view not listed in dataset")
 	}
 
+	// Verify manual refresh
+	if err := view.RefreshMaterializedView(ctx); err != nil {
+		t.Fatalf("failed to refresh materialized view: %v", err)
+	}
+	refreshedMeta, err := view.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshedMeta.MaterializedView.LastRefreshTime.IsZero() {
+		t.Error("expected LastRefreshTime after refresh")
+	}
+
 	// Verify deletion
 	if err := view.Delete(ctx); err != nil {
 		t.Errorf("failed to delete materialized view: %v", err)
 	}
 
+	// Create a view with options that require DDL
+	staleView := dataset.Table(tableIDs.New())
+	if err := staleView.Create(ctx, &TableMetadata{
+		Description: "stale view",
+		MaterializedView: &MaterializedViewDefinition{
+			Query:                         sql,
+			EnableRefresh:                 true,
+			RefreshInterval:               wantRefresh,
+			MaxStaleness:                  &IntervalValue{Hours: 4},
+			AllowNonIncrementalDefinition: true,
+		}}); err != nil {
+		t.Fatal(err)
+	}
+	defer staleView.Delete(ctx)
+	staleMeta, err := staleView.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if staleMeta.Type != MaterializedView || staleMeta.Description != "stale view" || !staleMeta.MaterializedView.EnableRefresh {
+		t.Errorf("unexpected metadata for DDL-created view: %+v", staleMeta)
+	}
+	if err := staleView.SetMaterializedViewMaxStaleness(ctx, &IntervalValue{Hours: 8}); err != nil {
+		t.Errorf("failed to set max staleness: %v", err)
+	}
+}
+
+func TestIntegration_SearchIndex(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+
+	table := dataset.Table(tableIDs.New())
+	sql := fmt.Sprintf("CREATE TABLE `%s`.%s.%s AS SELECT CONCAT('word', CAST(n AS STRING)) AS text FROM UNNEST(GENERATE_ARRAY(1, 100)) AS n",
+		testutil.ProjID(), dataset.DatasetID, table.TableID)
+	if _, _, err := runQuerySQL(ctx, sql); err != nil {
+		t.Fatalf("couldn't instantiate table: %v", err)
+	}
+	defer table.Delete(ctx)
+
+	if err := table.CreateSearchIndex(ctx, "text_index", &SearchIndexOptions{Columns: []string{"text"}}); err != nil {
+		t.Fatal(err)
+	}
+	indexes, err := table.SearchIndexes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 1 || indexes[0].Name != "text_index" || indexes[0].DDL == "" || indexes[0].CreationTime.IsZero() {
+		t.Errorf("got indexes %+v, want text_index", indexes)
+	}
+	if err := table.DropSearchIndex(ctx, "text_index"); err != nil {
+		t.Fatal(err)
+	}
+	indexes, err = table.SearchIndexes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != 0 {
+		t.Errorf("after drop: got indexes %+v, want none", indexes)
+	}
+}
+
+func TestIntegration_BulkUpdateLabels(t *testing.T) {
+	if client == nil {
+		t.Skip("Integration tests skipped")
+	}
+	ctx := context.Background()
+	t1, t2 := newTable(t, schema), newTable(t, schema)
+	defer t1.Delete(ctx)
+	defer t2.Delete(ctx)
+
+	err := client.UpdateLabels(ctx, &BulkLabelUpdate{
+		Tables:    []*Table{t1, t2},
+		SetLabels: map[string]string{"owner": "bulk"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []*Table{t1, t2} {
+		md, err := table.Metadata(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := md.Labels["owner"]; got != "bulk" {
+			t.Errorf("%s: got owner label %q, want %q", table.TableID, got, "bulk")
+		}
+	}
+
+	missing := dataset.Table(tableIDs.New())
+	err = client.UpdateLabels(ctx, &BulkLabelUpdate{
+		Tables:       []*Table{t1, missing},
+		DeleteLabels: []string{"owner"},
+	})
+	var me MultiError
+	if !xerrors.As(err, &me) || len(me) != 1 {
+		t.Fatalf("got %v, want one error for the missing table", err)
+	}
+	md, err := t1.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := md.Labels["owner"]; ok {
+		t.Error("owner label was not deleted")
+	}
 }
 
 func TestIntegration_ModelLifecycle(t *testing.T) {
@@ -0,0 +1,136 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IntervalValue is a Go type for representing BigQuery INTERVAL values.
+// Intervals are represented by three independent parts: year-month, day, and
+// an hour-minute-second time. Within each part all fields must have the same
+// sign, which is the sign of the part.
+//
+// See https://cloud.google.com/bigquery/docs/reference/standard-sql/data-types#interval_type
+// for more information.
+type IntervalValue struct {
+	// In canonical form, Years and Months share a consistent sign and are reduced
+	// to avoid large month values.
+	Years  int32
+	Months int32
+
+	// In canonical form, Days are independent of the other parts and can have
+	// its own sign. There is no attempt to reduce larger Day values into the
+	// Y-M part.
+	Days int32
+
+	// In canonical form, the time parts all share a consistent sign and are
+	// reduced.
+	Hours   int32
+	Minutes int32
+	Seconds int32
+	// This represents the fractional seconds as nanoseconds.
+	SubSecondNanos int32
+}
+
+// String returns the canonical format of an interval, which is
+// "Y-M D H:M:S[.F]", with an optional sign before each part.
+func (iv *IntervalValue) String() string {
+	ym := fmt.Sprintf("%d-%d", abs32(iv.Years), abs32(iv.Months))
+	if iv.Years < 0 || iv.Months < 0 {
+		ym = "-" + ym
+	}
+	hms := fmt.Sprintf("%d:%d:%d", abs32(iv.Hours), abs32(iv.Minutes), abs32(iv.Seconds))
+	if iv.SubSecondNanos != 0 {
+		hms += strings.TrimRight(fmt.Sprintf(".%09d", abs32(iv.SubSecondNanos)), "0")
+	}
+	if iv.Hours < 0 || iv.Minutes < 0 || iv.Seconds < 0 || iv.SubSecondNanos < 0 {
+		hms = "-" + hms
+	}
+	return fmt.Sprintf("%s %d %s", ym, iv.Days, hms)
+}
+
+func abs32(x int32) int64 {
+	if x < 0 {
+		return -int64(x)
+	}
+	return int64(x)
+}
+
+var intervalRE = regexp.MustCompile(`^([+-]?)(\d+)-(\d+) ([+-]?\d+) ([+-]?)(\d+):(\d+):(\d+)(?:\.(\d{1,9}))?$`)
+
+// ParseInterval parses an interval in the canonical format "Y-M D H:M:S[.F]",
+// in which each of the three parts may have a sign.
+func ParseInterval(value string) (*IntervalValue, error) {
+	m := intervalRE.FindStringSubmatch(value)
+	if m == nil {
+		return nil, fmt.Errorf("bigquery: invalid INTERVAL value %q", value)
+	}
+	var n [7]int32
+	for i, s := range []string{m[2], m[3], m[4], m[6], m[7], m[8], m[9]} {
+		if i == 6 {
+			// Right-pad the fraction to nanoseconds.
+			s += strings.Repeat("0", 9-len(s))
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bigquery: invalid INTERVAL value %q: %v", value, err)
+		}
+		n[i] = int32(v)
+	}
+	iv := &IntervalValue{
+		Years:          n[0],
+		Months:         n[1],
+		Days:           n[2],
+		Hours:          n[3],
+		Minutes:        n[4],
+		Seconds:        n[5],
+		SubSecondNanos: n[6],
+	}
+	if m[1] == "-" {
+		iv.Years, iv.Months = -iv.Years, -iv.Months
+	}
+	if m[5] == "-" {
+		iv.Hours, iv.Minutes, iv.Seconds, iv.SubSecondNanos = -iv.Hours, -iv.Minutes, -iv.Seconds, -iv.SubSecondNanos
+	}
+	return iv, nil
+}
+
+// IntervalValueFromDuration converts a time.Duration to an IntervalValue
+// with only the time part set.
+func IntervalValueFromDuration(in time.Duration) *IntervalValue {
+	return &IntervalValue{
+		Hours:          int32(in / time.Hour),
+		Minutes:        int32(in % time.Hour / time.Minute),
+		Seconds:        int32(in % time.Minute / time.Second),
+		SubSecondNanos: int32(in % time.Second),
+	}
+}
+
+// ToDuration converts an interval to a time.Duration, approximating a month
+// as 30 days, a year as 12 months and a day as 24 hours, the conventions
+// BigQuery uses when comparing intervals.
+func (iv *IntervalValue) ToDuration() time.Duration {
+	days := (int64(iv.Years)*12+int64(iv.Months))*30 + int64(iv.Days)
+	d := time.Duration(days) * 24 * time.Hour
+	d += time.Duration(iv.Hours) * time.Hour
+	d += time.Duration(iv.Minutes) * time.Minute
+	d += time.Duration(iv.Seconds) * time.Second
+	return d + time.Duration(iv.SubSecondNanos)
+}
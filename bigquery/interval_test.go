@@ -0,0 +1,73 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/internal/testutil"
+)
+
+func TestParseInterval(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want *IntervalValue
+		str  string // canonical form, if different from in
+	}{
+		{"0-0 0 0:0:0", &IntervalValue{}, ""},
+		{"1-2 3 4:5:6.7", &IntervalValue{Years: 1, Months: 2, Days: 3, Hours: 4, Minutes: 5, Seconds: 6, SubSecondNanos: 700000000}, ""},
+		{"-1-2 -3 -4:5:6.000000789", &IntervalValue{Years: -1, Months: -2, Days: -3, Hours: -4, Minutes: -5, Seconds: -6, SubSecondNanos: -789}, ""},
+		{"+0-11 +30 +0:0:1.5", &IntervalValue{Months: 11, Days: 30, Seconds: 1, SubSecondNanos: 500000000}, "0-11 30 0:0:1.5"},
+		{"10000-0 3660000 87840000:0:0", &IntervalValue{Years: 10000, Days: 3660000, Hours: 87840000}, ""},
+	} {
+		got, err := ParseInterval(test.in)
+		if err != nil {
+			t.Fatalf("%q: %v", test.in, err)
+		}
+		if !testutil.Equal(got, test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.in, got, test.want)
+		}
+		str := test.str
+		if str == "" {
+			str = test.in
+		}
+		if got := test.want.String(); got != str {
+			t.Errorf("%+v: got string %q, want %q", test.want, got, str)
+		}
+	}
+
+	for _, bad := range []string{"", "1-2", "1-2 3", "1-2 3 4:5", "1 2 3:4:5", "-1--2 3 4:5:6", "1-2 3 4:5:6.", "1-2 3 4:5:6.1234567890", "99999999999-0 0 0:0:0"} {
+		if _, err := ParseInterval(bad); err == nil {
+			t.Errorf("%q: got nil error, want non-nil", bad)
+		}
+	}
+}
+
+func TestIntervalDuration(t *testing.T) {
+	d := -(26*time.Hour + 3*time.Minute + 4*time.Second + 5*time.Millisecond)
+	iv := IntervalValueFromDuration(d)
+	want := &IntervalValue{Hours: -26, Minutes: -3, Seconds: -4, SubSecondNanos: -5000000}
+	if !testutil.Equal(iv, want) {
+		t.Errorf("IntervalValueFromDuration(%v): got %+v, want %+v", d, iv, want)
+	}
+	if got := iv.ToDuration(); got != d {
+		t.Errorf("ToDuration: got %v, want %v", got, d)
+	}
+	iv = &IntervalValue{Years: 1, Months: 1, Days: -2, Hours: -1}
+	if got, want := iv.ToDuration(), (13*30-2)*24*time.Hour-time.Hour; got != want {
+		t.Errorf("%+v: got %v, want %v", iv, got, want)
+	}
+}
@@ -61,6 +61,14 @@ type RowIterator struct {
 
 	rows         [][]Value
 	structLoader structLoader // used to populate a pointer to a struct
+
+	// index is the position in the result of the next row that Next returns,
+	// if indexKnown. It is not known if iteration starts from a page token
+	// that did not come from a checkpoint.
+	index      uint64
+	indexKnown bool
+	fetched    bool // whether a page has been fetched
+	unordered  bool // whether rows are read with the Storage Read API
 }
 
 // SourceJob returns an instance of a Job if the RowIterator is backed by a query,
@@ -80,6 +88,25 @@ func (ri *RowIterator) SourceJob() *Job {
 	}
 }
 
+// QueryStatistics fetches the statistics of the query job that produced the
+// rows, such as whether the results came from the cache, the slot time used and
+// BI Engine acceleration. It returns an error if the iterator reads a table.
+func (ri *RowIterator) QueryStatistics(ctx context.Context) (*QueryStatistics, error) {
+	job := ri.SourceJob()
+	if job == nil {
+		return nil, errors.New("bigquery: RowIterator is not backed by a query job")
+	}
+	status, err := job.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	qs := status.Statistics.QueryStatistics()
+	if qs == nil {
+		return nil, errors.New("bigquery: job has no query statistics")
+	}
+	return qs, nil
+}
+
 // We declare a function signature for fetching results.  The primary reason
 // for this is to enable us to swap out the fetch function with alternate
 // implementations (e.g. to enable testing).
@@ -143,6 +170,7 @@ func (it *RowIterator) Next(dst interface{}) error {
 	}
 	row := it.rows[0]
 	it.rows = it.rows[1:]
+	it.index++
 
 	if vl == nil {
 		// This can only happen if dst is a pointer to a struct. We couldn't
@@ -168,6 +196,15 @@ func (it *RowIterator) fetch(pageSize int, pageToken string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if !it.fetched {
+		it.fetched = true
+		if pageToken == "" {
+			it.index, it.indexKnown = it.StartIndex, true
+		}
+	}
+	if res.pageToken == storageReadPageToken {
+		it.unordered = true
+	}
 	it.rows = append(it.rows, res.rows...)
 	if it.Schema == nil {
 		it.Schema = res.schema
@@ -510,3 +510,11 @@ func TestIteratorSourceJob(t *testing.T) {
 		}
 	}
 }
+
+func TestIteratorQueryStatisticsWithoutJob(t *testing.T) {
+	src := &rowSource{t: &Table{ProjectID: "p", DatasetID: "d", TableID: "t"}}
+	it := newRowIterator(context.Background(), src, nil)
+	if _, err := it.QueryStatistics(context.Background()); err == nil {
+		t.Error("table source: got nil, want error")
+	}
+}
@@ -37,6 +37,7 @@ type Job struct {
 	email      string
 	config     *bq.JobConfiguration
 	lastStatus *JobStatus
+	retry      *RetryConfig
 }
 
 // JobFromID creates a Job which refers to an existing BigQuery job. The job
@@ -273,7 +274,11 @@ func (j *Job) Wait(ctx context.Context) (js *JobStatus, err error) {
 
 	if j.isQuery() {
 		// We can avoid polling for query jobs.
-		if _, _, err := j.waitForQuery(ctx, j.projectID); err != nil {
+		err := j.withJobRetries(ctx, func() error {
+			_, _, err := j.waitForQuery(ctx, j.projectID)
+			return err
+		})
+		if err != nil {
 			return nil, err
 		}
 		// Note: extra RPC even if you just want to wait for the query to finish.
@@ -284,17 +289,25 @@ func (j *Job) Wait(ctx context.Context) (js *JobStatus, err error) {
 		return js, nil
 	}
 	// Non-query jobs must poll.
-	err = internal.Retry(ctx, gax.Backoff{}, func() (stop bool, err error) {
-		js, err = j.Status(ctx)
+	err = j.withJobRetries(ctx, func() error {
+		js = nil
+		err := internal.Retry(ctx, j.retry.backoff(gax.Backoff{}), func() (stop bool, err error) {
+			js, err = j.Status(ctx)
+			if err != nil {
+				return j.retry == nil || !j.retry.retryable(err, defaultRetryReasons), err
+			}
+			if js.Done() {
+				return true, nil
+			}
+			return false, nil
+		})
 		if err != nil {
-			return true, err
+			return err
 		}
-		if js.Done() {
-			return true, nil
-		}
-		return false, nil
+		return js.Err()
 	})
-	if err != nil {
+	// A job that fails is reported by its status.
+	if err != nil && (js == nil || js.Err() != err) {
 		return nil, err
 	}
 	return js, nil
@@ -306,14 +319,21 @@ func (j *Job) Read(ctx context.Context) (ri *RowIterator, err error) {
 	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Job.Read")
 	defer func() { trace.EndSpan(ctx, err) }()
 
-	return j.read(ctx, j.waitForQuery, fetchPage)
+	return j.read(ctx, j.waitForQuery, j.c.readPageFetcher())
 }
 
 func (j *Job) read(ctx context.Context, waitForQuery func(context.Context, string) (Schema, uint64, error), pf pageFetcher) (*RowIterator, error) {
 	if !j.isQuery() {
 		return nil, errors.New("bigquery: cannot read from a non-query job")
 	}
-	schema, totalRows, err := waitForQuery(ctx, j.projectID)
+	var (
+		schema    Schema
+		totalRows uint64
+	)
+	err := j.withJobRetries(ctx, func() (err error) {
+		schema, totalRows, err = waitForQuery(ctx, j.projectID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -342,10 +362,20 @@ func (j *Job) waitForQuery(ctx context.Context, projectID string) (Schema, uint6
 		Max:        60 * time.Second,
 	}
 	var res *bq.GetQueryResultsResponse
-	err := internal.Retry(ctx, backoff, func() (stop bool, err error) {
+	err := internal.Retry(ctx, j.retry.backoff(backoff), func() (stop bool, err error) {
 		res, err = call.Do()
 		if err != nil {
-			return !retryableError(err, jobRetryReasons), err
+			if !j.retry.retryable(err, jobRetryReasons) {
+				return true, err
+			}
+			if j.retry.rerunsJobs() {
+				// Stop if the error is that of a failed job, which
+				// is run again instead.
+				if js, serr := j.Status(ctx); serr == nil && js.Err() != nil {
+					return true, js.Err()
+				}
+			}
+			return false, err
 		}
 		if !res.JobComplete { // GetQueryResults may return early without error; retry.
 			return false, nil
@@ -487,6 +517,27 @@ type QueryStatistics struct {
 	DDLTargetRoutine *Routine
 }
 
+// QueryStatistics returns the statistics of a query job, or nil if the job is
+// not a query.
+func (s *JobStatistics) QueryStatistics() *QueryStatistics {
+	if s == nil {
+		return nil
+	}
+	qs, _ := s.Details.(*QueryStatistics)
+	return qs
+}
+
+// ShuffleOutputBytesSpilled returns the number of bytes that the stages of the
+// query plan spilled to disk while shuffling, which indicates that the query
+// needed more memory than its slots had.
+func (s *QueryStatistics) ShuffleOutputBytesSpilled() int64 {
+	var n int64
+	for _, stage := range s.QueryPlan {
+		n += stage.ShuffleOutputBytesSpilled
+	}
+	return n
+}
+
 // BIEngineStatistics contains query statistics specific to the use of BI Engine.
 type BIEngineStatistics struct {
 	// Specifies which mode of BI Engine acceleration was performed.
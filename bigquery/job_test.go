@@ -93,3 +93,26 @@ func checkJob(t *testing.T, i int, got, want *bq.Job) {
 		t.Errorf("#%d: (got=-, want=+) %s", i, d)
 	}
 }
+
+func TestJobStatisticsQueryStatistics(t *testing.T) {
+	var nilStats *JobStatistics
+	if got := nilStats.QueryStatistics(); got != nil {
+		t.Errorf("nil JobStatistics: got %v, want nil", got)
+	}
+	if got := (&JobStatistics{Details: &LoadStatistics{}}).QueryStatistics(); got != nil {
+		t.Errorf("load job: got %v, want nil", got)
+	}
+	qs := &QueryStatistics{
+		CacheHit: true,
+		QueryPlan: []*ExplainQueryStage{
+			{ShuffleOutputBytesSpilled: 10},
+			{ShuffleOutputBytesSpilled: 5},
+		},
+	}
+	if got := (&JobStatistics{Details: qs}).QueryStatistics(); got != qs {
+		t.Errorf("query job: got %v, want %v", got, qs)
+	}
+	if got, want := qs.ShuffleOutputBytesSpilled(), int64(15); got != want {
+		t.Errorf("ShuffleOutputBytesSpilled: got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"cloud.google.com/go/internal/trace"
+	"google.golang.org/api/googleapi"
+)
+
+// defaultLabelConcurrency is the default number of datasets and tables whose
+// labels are updated at once.
+const defaultLabelConcurrency = 10
+
+// maxLabelAttempts is the number of times the labels of a resource are read and
+// updated if another update changes the resource in between.
+const maxLabelAttempts = 5
+
+// A BulkLabelUpdate describes changes to the labels of many datasets and
+// tables, which Client.UpdateLabels applies.
+type BulkLabelUpdate struct {
+	// Datasets and Tables are the resources to update.
+	Datasets []*Dataset
+	Tables   []*Table
+
+	// SetLabels are the labels to add or change.
+	SetLabels map[string]string
+
+	// DeleteLabels are the names of the labels to remove.
+	DeleteLabels []string
+
+	// MaxConcurrency is the maximum number of resources updated at once. The
+	// default is 10.
+	MaxConcurrency int
+}
+
+// A LabelUpdateError is the error of updating the labels of one dataset or
+// table.
+type LabelUpdateError struct {
+	// Resource is the ID of the dataset or table, in the format of
+	// Table.FullyQualifiedName.
+	Resource string
+
+	// Err is the error of the update.
+	Err error
+}
+
+func (e *LabelUpdateError) Error() string {
+	return fmt.Sprintf("bigquery: updating labels of %s: %v", e.Resource, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LabelUpdateError) Unwrap() error { return e.Err }
+
+// UpdateLabels applies label changes to many datasets and tables concurrently.
+//
+// Each resource is read and then updated with its ETag, which is retried if
+// the resource is changed in between, so the update does not overwrite other
+// changes. Resources that already have the labels are not updated.
+//
+// UpdateLabels attempts to update every resource. If some updates fail, it
+// returns a MultiError with a *LabelUpdateError for each of them.
+func (c *Client) UpdateLabels(ctx context.Context, u *BulkLabelUpdate) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Client.UpdateLabels")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	var targets []labelTarget
+	for _, d := range u.Datasets {
+		targets = append(targets, datasetLabels{d})
+	}
+	for _, t := range u.Tables {
+		targets = append(targets, tableLabels{t})
+	}
+	concurrency := u.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultLabelConcurrency
+	}
+	return updateLabels(ctx, targets, u.SetLabels, u.DeleteLabels, concurrency)
+}
+
+// labelTarget is a resource with labels.
+type labelTarget interface {
+	name() string
+	// labels returns the labels and ETag of the resource.
+	labels(ctx context.Context) (map[string]string, string, error)
+	// updateLabels changes the labels of the resource, if its ETag matches.
+	updateLabels(ctx context.Context, lu labelUpdater, etag string) error
+}
+
+func updateLabels(ctx context.Context, targets []labelTarget, set map[string]string, del []string, concurrency int) error {
+	var (
+		mu   sync.Mutex
+		errs MultiError
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, t := range targets {
+		t := t
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if err := updateTargetLabels(ctx, t, set, del); err != nil {
+				mu.Lock()
+				errs = append(errs, &LabelUpdateError{Resource: t.name(), Err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func updateTargetLabels(ctx context.Context, t labelTarget, set map[string]string, del []string) error {
+	var err error
+	for attempt := 0; attempt < maxLabelAttempts; attempt++ {
+		var (
+			labels map[string]string
+			etag   string
+		)
+		labels, etag, err = t.labels(ctx)
+		if err != nil {
+			return err
+		}
+		var lu labelUpdater
+		for k, v := range set {
+			if cur, ok := labels[k]; !ok || cur != v {
+				lu.SetLabel(k, v)
+			}
+		}
+		for _, k := range del {
+			if _, ok := labels[k]; ok {
+				lu.DeleteLabel(k)
+			}
+		}
+		if lu.setLabels == nil && lu.deleteLabels == nil {
+			return nil
+		}
+		err = t.updateLabels(ctx, lu, etag)
+		if e, ok := err.(*googleapi.Error); !ok || e.Code != http.StatusPreconditionFailed {
+			return err
+		}
+		// The resource changed since it was read; read it again.
+	}
+	return err
+}
+
+type datasetLabels struct{ d *Dataset }
+
+func (d datasetLabels) name() string {
+	return fmt.Sprintf("%s:%s", d.d.ProjectID, d.d.DatasetID)
+}
+
+func (d datasetLabels) labels(ctx context.Context) (map[string]string, string, error) {
+	md, err := d.d.Metadata(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return md.Labels, md.ETag, nil
+}
+
+func (d datasetLabels) updateLabels(ctx context.Context, lu labelUpdater, etag string) error {
+	_, err := d.d.Update(ctx, DatasetMetadataToUpdate{labelUpdater: lu}, etag)
+	return err
+}
+
+type tableLabels struct{ t *Table }
+
+func (t tableLabels) name() string { return t.t.FullyQualifiedName() }
+
+func (t tableLabels) labels(ctx context.Context) (map[string]string, string, error) {
+	md, err := t.t.Metadata(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return md.Labels, md.ETag, nil
+}
+
+func (t tableLabels) updateLabels(ctx context.Context, lu labelUpdater, etag string) error {
+	_, err := t.t.Update(ctx, TableMetadataToUpdate{labelUpdater: lu}, etag)
+	return err
+}
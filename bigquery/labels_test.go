@@ -0,0 +1,178 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+	"google.golang.org/api/googleapi"
+)
+
+// fakeLabelTarget is an in-memory resource with labels. If conflicts is
+// positive, that many updates fail because the resource changed.
+type fakeLabelTarget struct {
+	id        string
+	readErr   error
+	conflicts int
+
+	mu      sync.Mutex
+	current map[string]string
+	etag    int
+	updates int
+}
+
+func (f *fakeLabelTarget) name() string { return f.id }
+
+func (f *fakeLabelTarget) labels(context.Context) (map[string]string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.readErr != nil {
+		return nil, "", f.readErr
+	}
+	labels := map[string]string{}
+	for k, v := range f.current {
+		labels[k] = v
+	}
+	return labels, string(rune('a' + f.etag)), nil
+}
+
+func (f *fakeLabelTarget) updateLabels(_ context.Context, lu labelUpdater, etag string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates++
+	if f.conflicts > 0 {
+		f.conflicts--
+		f.etag++
+		return &googleapi.Error{Code: http.StatusPreconditionFailed}
+	}
+	if etag != string(rune('a'+f.etag)) {
+		return errors.New("stale etag")
+	}
+	for k, v := range lu.setLabels {
+		f.current[k] = v
+	}
+	for k := range lu.deleteLabels {
+		delete(f.current, k)
+	}
+	f.etag++
+	return nil
+}
+
+func TestUpdateLabels(t *testing.T) {
+	readErr := errors.New("not found")
+	targets := []*fakeLabelTarget{
+		{id: "changed", current: map[string]string{"team": "a", "old": "x"}},
+		{id: "unchanged", current: map[string]string{"team": "b"}},
+		{id: "conflict", current: map[string]string{}, conflicts: 2},
+		{id: "missing", readErr: readErr},
+		{id: "always-conflict", current: map[string]string{}, conflicts: maxLabelAttempts},
+	}
+	var lts []labelTarget
+	for _, f := range targets {
+		lts = append(lts, f)
+	}
+	err := updateLabels(context.Background(), lts, map[string]string{"team": "b"}, []string{"old"}, 2)
+
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 2 {
+		t.Fatalf("got %v, want MultiError with 2 errors", err)
+	}
+	failed := map[string]error{}
+	for _, e := range me {
+		lue := e.(*LabelUpdateError)
+		failed[lue.Resource] = lue.Err
+	}
+	if !errors.Is(failed["missing"], readErr) {
+		t.Errorf("missing: got %v, want %v", failed["missing"], readErr)
+	}
+	if _, ok := failed["always-conflict"]; !ok {
+		t.Error("always-conflict: got no error")
+	}
+
+	for _, test := range []struct {
+		f           *fakeLabelTarget
+		wantLabels  map[string]string
+		wantUpdates int
+	}{
+		{targets[0], map[string]string{"team": "b"}, 1},
+		{targets[1], map[string]string{"team": "b"}, 0},
+		{targets[2], map[string]string{"team": "b"}, 3},
+		{targets[4], map[string]string{}, maxLabelAttempts},
+	} {
+		if diff := testutil.Diff(test.f.current, test.wantLabels); diff != "" {
+			t.Errorf("%s: labels: got=-, want=+:\n%s", test.f.id, diff)
+		}
+		if test.f.updates != test.wantUpdates {
+			t.Errorf("%s: got %d updates, want %d", test.f.id, test.f.updates, test.wantUpdates)
+		}
+	}
+}
+
+// concurrencyTarget records the number of concurrent updates.
+type concurrencyTarget struct {
+	fakeLabelTarget
+	mu     *sync.Mutex
+	active *int
+	max    *int
+	block  chan struct{}
+}
+
+func (c *concurrencyTarget) updateLabels(ctx context.Context, lu labelUpdater, etag string) error {
+	c.mu.Lock()
+	*c.active++
+	if *c.active > *c.max {
+		*c.max = *c.active
+	}
+	c.mu.Unlock()
+	<-c.block
+	c.mu.Lock()
+	*c.active--
+	c.mu.Unlock()
+	return c.fakeLabelTarget.updateLabels(ctx, lu, etag)
+}
+
+func TestUpdateLabelsConcurrency(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		active, max int
+	)
+	block := make(chan struct{})
+	var lts []labelTarget
+	for i := 0; i < 10; i++ {
+		lts = append(lts, &concurrencyTarget{
+			fakeLabelTarget: fakeLabelTarget{id: string(rune('a' + i)), current: map[string]string{}},
+			mu:              &mu,
+			active:          &active,
+			max:             &max,
+			block:           block,
+		})
+	}
+	done := make(chan error)
+	go func() { done <- updateLabels(context.Background(), lts, map[string]string{"k": "v"}, nil, 3) }()
+	for i := 0; i < 10; i++ {
+		block <- struct{}{}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if max > 3 {
+		t.Errorf("got %d concurrent updates, want at most 3", max)
+	}
+}
@@ -16,7 +16,6 @@ package bigquery
 
 import (
 	"context"
-	"io"
 
 	"cloud.google.com/go/internal/trace"
 	bq "google.golang.org/api/bigquery/v2"
@@ -38,7 +37,8 @@ type LoadConfig struct {
 	// The default is WriteAppend.
 	WriteDisposition TableWriteDisposition
 
-	// The labels associated with this job.
+	// The labels associated with this job. The client's DefaultJobLabels are
+	// added to them.
 	Labels map[string]string
 
 	// If non-nil, the destination table is partitioned by time.
@@ -79,7 +79,7 @@ type LoadConfig struct {
 	DecimalTargetTypes []DecimalTargetType
 }
 
-func (l *LoadConfig) toBQ() (*bq.JobConfiguration, io.Reader) {
+func (l *LoadConfig) toBQ() (*bq.JobConfiguration, *mediaUpload) {
 	config := &bq.JobConfiguration{
 		Labels: l.Labels,
 		Load: &bq.JobConfigurationLoad{
@@ -149,7 +149,7 @@ type Loader struct {
 // objects, and ReaderSource, for data read from an io.Reader.
 type LoadSource interface {
 	// populates config, returns media
-	populateLoadConfig(*bq.JobConfigurationLoad) io.Reader
+	populateLoadConfig(*bq.JobConfigurationLoad) *mediaUpload
 }
 
 // LoaderFrom returns a Loader which can be used to load data into a BigQuery table.
@@ -172,10 +172,10 @@ func (l *Loader) Run(ctx context.Context) (j *Job, err error) {
 	defer func() { trace.EndSpan(ctx, err) }()
 
 	job, media := l.newJob()
-	return l.c.insertJob(ctx, job, media)
+	return l.c.insertJob(ctx, job, media, nil)
 }
 
-func (l *Loader) newJob() (*bq.Job, io.Reader) {
+func (l *Loader) newJob() (*bq.Job, *mediaUpload) {
 	config, media := l.LoadConfig.toBQ()
 	return &bq.Job{
 		JobReference:  l.JobIDConfig.createJobRef(l.c),
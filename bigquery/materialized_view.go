@@ -0,0 +1,144 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+// needsDDL reports whether the materialized view has options that can only be
+// set with DDL.
+func (mvd *MaterializedViewDefinition) needsDDL() bool {
+	return mvd != nil && (mvd.MaxStaleness != nil || mvd.AllowNonIncrementalDefinition)
+}
+
+// createMaterializedView creates the materialized view of tm with a CREATE
+// MATERIALIZED VIEW statement.
+func (t *Table) createMaterializedView(ctx context.Context, tm *TableMetadata) error {
+	stmt, err := t.createMaterializedViewStatement(tm)
+	if err != nil {
+		return err
+	}
+	return t.c.Query(stmt).exec(ctx)
+}
+
+func (t *Table) createMaterializedViewStatement(tm *TableMetadata) (string, error) {
+	// Check the metadata for fields that cannot be set on create.
+	if _, err := tm.toBQ(); err != nil {
+		return "", err
+	}
+	switch {
+	case tm.Schema != nil, tm.ViewQuery != "", tm.TimePartitioning != nil, tm.RangePartitioning != nil,
+		tm.RequirePartitionFilter, tm.ExternalDataConfig != nil, tm.EncryptionConfig != nil, tm.SnapshotDefinition != nil:
+		return "", errors.New("bigquery: a materialized view with MaxStaleness or AllowNonIncrementalDefinition supports only Name, Description, Labels, ExpirationTime and Clustering")
+	}
+	mvd := tm.MaterializedView
+	if mvd.Query == "" {
+		return "", errors.New("bigquery: materialized view needs a Query")
+	}
+
+	opts := []string{"enable_refresh = " + strconv.FormatBool(mvd.EnableRefresh)}
+	if mvd.RefreshInterval != 0 {
+		opts = append(opts, "refresh_interval_minutes = "+strconv.FormatFloat(mvd.RefreshInterval.Minutes(), 'f', -1, 64))
+	}
+	if mvd.MaxStaleness != nil {
+		opts = append(opts, "max_staleness = "+intervalLiteral(mvd.MaxStaleness))
+	}
+	if mvd.AllowNonIncrementalDefinition {
+		opts = append(opts, "allow_non_incremental_definition = true")
+	}
+	opts = append(opts, tm.ddlOptions()...)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CREATE MATERIALIZED VIEW %s", t.sqlIdentifier())
+	if tm.Clustering != nil && len(tm.Clustering.Fields) > 0 {
+		fields := make([]string, len(tm.Clustering.Fields))
+		for i, f := range tm.Clustering.Fields {
+			fields[i] = quoteIdentifier(f)
+		}
+		fmt.Fprintf(&sb, " CLUSTER BY %s", strings.Join(fields, ", "))
+	}
+	fmt.Fprintf(&sb, " OPTIONS(%s) AS %s", strings.Join(opts, ", "), mvd.Query)
+	return sb.String(), nil
+}
+
+// SetMaterializedViewMaxStaleness sets the MaxStaleness option of a
+// materialized view. A nil staleness removes the option.
+func (t *Table) SetMaterializedViewMaxStaleness(ctx context.Context, staleness *IntervalValue) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.SetMaterializedViewMaxStaleness")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	value := "NULL"
+	if staleness != nil {
+		value = intervalLiteral(staleness)
+	}
+	return t.c.Query(fmt.Sprintf("ALTER MATERIALIZED VIEW %s SET OPTIONS(max_staleness = %s)", t.sqlIdentifier(), value)).exec(ctx)
+}
+
+// RefreshMaterializedView refreshes a materialized view, and waits for the
+// refresh to finish. The time of the refresh is reported by the
+// LastRefreshTime of the view's MaterializedViewDefinition.
+func (t *Table) RefreshMaterializedView(ctx context.Context) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.RefreshMaterializedView")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	id, _ := t.Identifier(StandardSQLID)
+	return t.c.Query(fmt.Sprintf("CALL BQ.REFRESH_MATERIALIZED_VIEW(%s)", quoteString(id))).exec(ctx)
+}
+
+// ddlOptions returns the DDL options for the Name, Description, Labels and
+// ExpirationTime of tm.
+func (tm *TableMetadata) ddlOptions() []string {
+	var opts []string
+	if tm.Name != "" {
+		opts = append(opts, "friendly_name = "+quoteString(tm.Name))
+	}
+	if tm.Description != "" {
+		opts = append(opts, "description = "+quoteString(tm.Description))
+	}
+	if len(tm.Labels) > 0 {
+		var keys []string
+		for k := range tm.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var labels []string
+		for _, k := range keys {
+			labels = append(labels, fmt.Sprintf("(%s, %s)", quoteString(k), quoteString(tm.Labels[k])))
+		}
+		opts = append(opts, "labels = ["+strings.Join(labels, ", ")+"]")
+	}
+	if !tm.ExpirationTime.IsZero() && tm.ExpirationTime != NeverExpire {
+		opts = append(opts, "expiration_timestamp = TIMESTAMP "+quoteString(tm.ExpirationTime.UTC().Format("2006-01-02 15:04:05.999999")+" UTC"))
+	}
+	return opts
+}
+
+// sqlIdentifier returns the quoted Standard SQL identifier of the table.
+func (t *Table) sqlIdentifier() string {
+	id, _ := t.Identifier(StandardSQLID)
+	return quoteIdentifier(id)
+}
+
+func intervalLiteral(iv *IntervalValue) string {
+	return "INTERVAL " + quoteString(iv.String()) + " YEAR TO SECOND"
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateMaterializedViewStatement(t *testing.T) {
+	c := &Client{projectID: "p"}
+	table := c.Dataset("d").Table("mv")
+	for _, test := range []struct {
+		tm   *TableMetadata
+		want string
+	}{
+		{
+			tm: &TableMetadata{
+				MaterializedView: &MaterializedViewDefinition{
+					Query:        "SELECT a FROM d.t",
+					MaxStaleness: &IntervalValue{Hours: 4},
+				},
+			},
+			want: "CREATE MATERIALIZED VIEW `p.d.mv` OPTIONS(enable_refresh = false, " +
+				`max_staleness = INTERVAL "0-0 0 4:0:0" YEAR TO SECOND) AS SELECT a FROM d.t`,
+		},
+		{
+			tm: &TableMetadata{
+				Name:           "My view",
+				Description:    `A "view"`,
+				Labels:         map[string]string{"b": "2", "a": "1"},
+				ExpirationTime: time.Date(2030, 1, 2, 3, 4, 5, 6000, time.UTC),
+				Clustering:     &Clustering{Fields: []string{"a", "b"}},
+				MaterializedView: &MaterializedViewDefinition{
+					Query:                         "SELECT a, b, COUNT(*) c FROM d.t GROUP BY a, b",
+					EnableRefresh:                 true,
+					RefreshInterval:               90 * time.Second,
+					MaxStaleness:                  &IntervalValue{Days: 1},
+					AllowNonIncrementalDefinition: true,
+				},
+			},
+			want: "CREATE MATERIALIZED VIEW `p.d.mv` CLUSTER BY `a`, `b` OPTIONS(enable_refresh = true, " +
+				"refresh_interval_minutes = 1.5, " +
+				`max_staleness = INTERVAL "0-0 1 0:0:0" YEAR TO SECOND, ` +
+				"allow_non_incremental_definition = true, " +
+				`friendly_name = "My view", description = "A \"view\"", ` +
+				`labels = [("a", "1"), ("b", "2")], ` +
+				`expiration_timestamp = TIMESTAMP "2030-01-02 03:04:05.000006 UTC") ` +
+				"AS SELECT a, b, COUNT(*) c FROM d.t GROUP BY a, b",
+		},
+	} {
+		if !test.tm.MaterializedView.needsDDL() {
+			t.Fatalf("%+v: needsDDL is false", test.tm.MaterializedView)
+		}
+		got, err := table.createMaterializedViewStatement(test.tm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("got\n%s\nwant\n%s", got, test.want)
+		}
+	}
+
+	staleness := &IntervalValue{Hours: 1}
+	for _, tm := range []*TableMetadata{
+		{MaterializedView: &MaterializedViewDefinition{MaxStaleness: staleness}},
+		{
+			TimePartitioning: &TimePartitioning{},
+			MaterializedView: &MaterializedViewDefinition{Query: "SELECT 1", MaxStaleness: staleness},
+		},
+		{
+			ETag:             "e",
+			MaterializedView: &MaterializedViewDefinition{Query: "SELECT 1", MaxStaleness: staleness},
+		},
+	} {
+		if _, err := table.createMaterializedViewStatement(tm); err == nil {
+			t.Errorf("%+v: got nil error, want non-nil", tm)
+		}
+	}
+
+	if (&MaterializedViewDefinition{Query: "SELECT 1", EnableRefresh: true}).needsDDL() {
+		t.Error("needsDDL is true for a view without DDL-only options")
+	}
+}
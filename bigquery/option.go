@@ -0,0 +1,81 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// clientConfig holds the Client settings given by bigquery-specific client
+// options.
+type clientConfig struct {
+	emulatorHost string
+}
+
+// bigqueryOption is a ClientOption that configures the Client itself rather
+// than its transport. It is removed from the options used to create the
+// transport.
+type bigqueryOption interface {
+	option.ClientOption
+	applyBigQuery(*clientConfig)
+}
+
+// noopOption returns the ClientOption that bigquery options embed to satisfy
+// option.ClientOption. It leaves the dial settings unchanged.
+func noopOption() option.ClientOption {
+	return option.WithGRPCDialOption(grpc.EmptyDialOption{})
+}
+
+// newClientConfig applies the bigquery-specific options in opts, and returns
+// the remaining transport options.
+func newClientConfig(opts []option.ClientOption) (*clientConfig, []option.ClientOption) {
+	config := &clientConfig{}
+	var transportOpts []option.ClientOption
+	for _, opt := range opts {
+		if bo, ok := opt.(bigqueryOption); ok {
+			bo.applyBigQuery(config)
+		} else {
+			transportOpts = append(transportOpts, opt)
+		}
+	}
+	return config, transportOpts
+}
+
+// WithEmulator returns a ClientOption that sends the BigQuery API requests of
+// the client to an emulator, such as bigquery-emulator, at the given address,
+// without authentication. The address is a host and port, or a URL with an
+// http or https scheme; the scheme defaults to http.
+//
+// All requests of the client use the emulator, including job polling, table
+// data reads, and uploads from a ReaderSource, even if the emulator returns
+// upload session URIs for another address. The BigQuery Storage APIs, used by
+// StorageRead and StorageInserter, are not supported with an emulator.
+//
+// WithEmulator takes precedence over the BIGQUERY_EMULATOR_HOST environment
+// variable, and is convenient for hermetic tests that start their own
+// emulator.
+func WithEmulator(host string) option.ClientOption {
+	return &withEmulator{ClientOption: noopOption(), host: host}
+}
+
+type withEmulator struct {
+	option.ClientOption
+	host string
+}
+
+func (w *withEmulator) applyBigQuery(c *clientConfig) {
+	c.emulatorHost = w.host
+}
@@ -16,6 +16,7 @@ package bigquery
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -36,7 +37,10 @@ var (
 	validFieldName = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]{0,127}$")
 )
 
-const nullableTagOption = "nullable"
+const (
+	nullableTagOption = "nullable"
+	jsonTagOption     = "json"
+)
 
 func bqTagParser(t reflect.StructTag) (name string, keep bool, other interface{}, err error) {
 	name, keep, opts, err := fields.ParseStandardTag("bigquery", t)
@@ -47,15 +51,25 @@ func bqTagParser(t reflect.StructTag) (name string, keep bool, other interface{}
 		return "", false, nil, invalidFieldNameError(name)
 	}
 	for _, opt := range opts {
-		if opt != nullableTagOption {
+		if opt != nullableTagOption && opt != jsonTagOption {
 			return "", false, nil, fmt.Errorf(
-				"bigquery: invalid tag option %q. The only valid option is %q",
-				opt, nullableTagOption)
+				"bigquery: invalid tag option %q. The valid options are %q and %q",
+				opt, nullableTagOption, jsonTagOption)
 		}
 	}
 	return name, keep, opts, nil
 }
 
+// hasTagOption reports whether the bigquery tag of f has the option opt.
+func hasTagOption(f fields.Field, opt string) bool {
+	for _, o := range f.ParsedTag.([]string) {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
 type invalidFieldNameError string
 
 func (e invalidFieldNameError) Error() string {
@@ -77,6 +91,8 @@ var (
 	numericParamType    = &bq.QueryParameterType{Type: "NUMERIC"}
 	bigNumericParamType = &bq.QueryParameterType{Type: "BIGNUMERIC"}
 	geographyParamType  = &bq.QueryParameterType{Type: "GEOGRAPHY"}
+	jsonParamType       = &bq.QueryParameterType{Type: "JSON"}
+	intervalParamType   = &bq.QueryParameterType{Type: "INTERVAL"}
 )
 
 var (
@@ -106,6 +122,9 @@ type QueryParameter struct {
 	// []byte: BYTES
 	// time.Time: TIMESTAMP
 	// *big.Rat: NUMERIC
+	// json.RawMessage: JSON
+	// IntervalValue, *IntervalValue: INTERVAL
+	// Geography, *Geography: GEOGRAPHY
 	// Arrays and slices of the above.
 	// Structs of the above. Only the exported fields are used.
 	//
@@ -118,6 +137,9 @@ type QueryParameter struct {
 	// Floating-point values are of type float64.
 	// Arrays are of type []interface{}, regardless of the array element type.
 	// Structs are of type map[string]interface{}.
+	// JSON values are of type json.RawMessage.
+	// INTERVAL values are of type *IntervalValue.
+	// GEOGRAPHY values are of type string, holding their WKT.
 	//
 	// When valid (non-null) Null types are sent, they come back as the Go types indicated
 	// above.  Null strings will report in query statistics as a valid empty
@@ -156,6 +178,12 @@ func paramType(t reflect.Type) (*bq.QueryParameterType, error) {
 		return timestampParamType, nil
 	case typeOfRat:
 		return numericParamType, nil
+	case typeOfRawMessage:
+		return jsonParamType, nil
+	case typeOfInterval, typeOfIntervalPtr:
+		return intervalParamType, nil
+	case typeOfGeography, typeOfGeographyPtr:
+		return geographyParamType, nil
 	case typeOfNullBool:
 		return boolParamType, nil
 	case typeOfNullFloat64:
@@ -211,6 +239,14 @@ func paramType(t reflect.Type) (*bq.QueryParameterType, error) {
 			if err != nil {
 				return nil, err
 			}
+			if hasTagOption(f, jsonTagOption) {
+				switch {
+				case pt == stringParamType:
+					pt = jsonParamType
+				case pt.Type == "ARRAY" && pt.ArrayType == stringParamType:
+					pt = &bq.QueryParameterType{Type: "ARRAY", ArrayType: jsonParamType}
+				}
+			}
 			fts = append(fts, &bq.QueryParameterTypeStructTypes{
 				Name: f.Name,
 				Type: pt,
@@ -300,6 +336,42 @@ func paramValue(v reflect.Value) (*bq.QueryParameterValue, error) {
 		// to honor previous behavior and send as Numeric type.
 		res.Value = NumericString(v.Interface().(*big.Rat))
 		return res, nil
+
+	case typeOfRawMessage:
+		raw := v.Interface().(json.RawMessage)
+		if raw == nil {
+			res.NullFields = append(res.NullFields, "Value")
+			return res, nil
+		}
+		res.Value = string(raw)
+		return res, nil
+
+	case typeOfInterval:
+		iv := v.Interface().(IntervalValue)
+		res.Value = iv.String()
+		return res, nil
+
+	case typeOfIntervalPtr:
+		iv := v.Interface().(*IntervalValue)
+		if iv == nil {
+			res.NullFields = append(res.NullFields, "Value")
+			return res, nil
+		}
+		res.Value = iv.String()
+		return res, nil
+
+	case typeOfGeography:
+		res.Value = v.Interface().(Geography).WKT
+		return res, nil
+
+	case typeOfGeographyPtr:
+		g := v.Interface().(*Geography)
+		if g == nil {
+			res.NullFields = append(res.NullFields, "Value")
+			return res, nil
+		}
+		res.Value = g.WKT
+		return res, nil
 	}
 	switch t.Kind() {
 	case reflect.Slice:
@@ -379,6 +451,8 @@ var paramTypeToFieldType = map[string]FieldType{
 	numericParamType.Type:    NumericFieldType,
 	bigNumericParamType.Type: BigNumericFieldType,
 	geographyParamType.Type:  GeographyFieldType,
+	jsonParamType.Type:       JSONFieldType,
+	intervalParamType.Type:   IntervalFieldType,
 }
 
 // Convert a parameter value from the service to a Go value. This is similar to, but
@@ -423,6 +497,10 @@ func convertParamValue(qval *bq.QueryParameterValue, qtype *bq.QueryParameterTyp
 				return NullTime{Valid: false}, nil
 			case "GEOGRAPHY":
 				return NullGeography{Valid: false}, nil
+			case "JSON":
+				return json.RawMessage(nil), nil
+			case "INTERVAL":
+				return (*IntervalValue)(nil), nil
 			}
 
 		}
@@ -16,6 +16,7 @@ package bigquery
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"math"
 	"math/big"
@@ -118,6 +119,18 @@ var scalarTests = []struct {
 	{big.NewRat(12345, 1000), false, "12.345000000", numericParamType, big.NewRat(12345, 1000)},
 	{NullGeography{GeographyVal: "POINT(-122.335503 47.625536)", Valid: true}, false, "POINT(-122.335503 47.625536)", geographyParamType, "POINT(-122.335503 47.625536)"},
 	{NullGeography{Valid: false}, true, "", geographyParamType, NullGeography{Valid: false}},
+	{Geography{WKT: "POINT(-122.335503 47.625536)"}, false, "POINT(-122.335503 47.625536)", geographyParamType, "POINT(-122.335503 47.625536)"},
+	{&Geography{WKT: "POINT(1 2)"}, false, "POINT(1 2)", geographyParamType, "POINT(1 2)"},
+	{(*Geography)(nil), true, "", geographyParamType, NullGeography{Valid: false}},
+	{json.RawMessage(`{"a":[1,2]}`), false, `{"a":[1,2]}`, jsonParamType, json.RawMessage(`{"a":[1,2]}`)},
+	{json.RawMessage(nil), true, "", jsonParamType, json.RawMessage(nil)},
+	{&IntervalValue{Years: 1, Months: 2, Days: 3, Hours: 4, Minutes: 5, Seconds: 6, SubSecondNanos: 7000},
+		false,
+		"1-2 3 4:5:6.000007",
+		intervalParamType,
+		&IntervalValue{Years: 1, Months: 2, Days: 3, Hours: 4, Minutes: 5, Seconds: 6, SubSecondNanos: 7000}},
+	{IntervalValue{Days: -10, Hours: -1}, false, "0-0 -10 -1:0:0", intervalParamType, &IntervalValue{Days: -10, Hours: -1}},
+	{(*IntervalValue)(nil), true, "", intervalParamType, (*IntervalValue)(nil)},
 }
 
 type (
@@ -264,6 +277,21 @@ func TestParamType(t *testing.T) {
 		{[]int{}, &bq.QueryParameterType{Type: "ARRAY", ArrayType: int64ParamType}},
 		{[3]bool{}, &bq.QueryParameterType{Type: "ARRAY", ArrayType: boolParamType}},
 		{S1{}, s1ParamType},
+		{
+			struct {
+				J  string   `bigquery:",json"`
+				JS []string `bigquery:"js,json"`
+				S  string
+			}{},
+			&bq.QueryParameterType{
+				Type: "STRUCT",
+				StructTypes: []*bq.QueryParameterTypeStructTypes{
+					{Name: "J", Type: jsonParamType},
+					{Name: "js", Type: &bq.QueryParameterType{Type: "ARRAY", ArrayType: jsonParamType}},
+					{Name: "S", Type: stringParamType},
+				},
+			},
+		},
 	} {
 		got, err := paramType(reflect.TypeOf(test.val))
 		if err != nil {
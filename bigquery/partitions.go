@@ -0,0 +1,305 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// defaultPartitionConcurrency is the number of partitions deleted, or tables
+// updated, at once.
+const defaultPartitionConcurrency = 10
+
+// maxPartitionExpirationAttempts is the number of times the partitioning of a
+// table is read and updated if another update changes the table in between.
+const maxPartitionExpirationAttempts = 5
+
+// A TablePartition describes a partition of a table, as reported by the
+// INFORMATION_SCHEMA.PARTITIONS view.
+type TablePartition struct {
+	// ID is the ID of the partition, such as "20220301" for a daily
+	// partition. Rows whose partitioning column is NULL are in the "__NULL__"
+	// partition, and rows outside the partitioning range, or in the streaming
+	// buffer of an ingestion-time partitioned table, are in the
+	// "__UNPARTITIONED__" partition.
+	ID string
+
+	// TotalRows is the number of rows in the partition.
+	TotalRows int64
+
+	// TotalLogicalBytes is the number of logical bytes in the partition.
+	TotalLogicalBytes int64
+
+	// LastModifiedTime is when data was last written to the partition.
+	LastModifiedTime time.Time
+
+	// StorageTier is the storage tier of the partition, "ACTIVE" or
+	// "LONG_TERM".
+	StorageTier string
+}
+
+// A PartitionError is the error of deleting a partition of a table, or of
+// updating the partition expiration of a table.
+type PartitionError struct {
+	// Table is the ID of the table, in the format of Table.FullyQualifiedName.
+	Table string
+
+	// PartitionID is the ID of the partition that was not deleted. It is empty
+	// for errors of Client.UpdatePartitionExpiration.
+	PartitionID string
+
+	// Err is the error of the deletion or update.
+	Err error
+}
+
+func (e *PartitionError) Error() string {
+	if e.PartitionID == "" {
+		return fmt.Sprintf("bigquery: updating partition expiration of %s: %v", e.Table, e.Err)
+	}
+	return fmt.Sprintf("bigquery: deleting partition %s of %s: %v", e.PartitionID, e.Table, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PartitionError) Unwrap() error { return e.Err }
+
+// Partition returns the partition of the table with the given ID, using the
+// "table$partition" decorator. Use it as the destination of a load, copy or
+// query to replace the data of the partition, or call its Delete method to
+// delete the partition.
+func (t *Table) Partition(id string) *Table {
+	return &Table{
+		ProjectID: t.ProjectID,
+		DatasetID: t.DatasetID,
+		TableID:   t.TableID + "$" + id,
+		c:         t.c,
+	}
+}
+
+// TimePartitionID returns the ID of the partition of a time-partitioned table
+// with partitioning type typ that holds the time tm. An empty typ is
+// DayPartitioningType.
+func TimePartitionID(tm time.Time, typ TimePartitioningType) string {
+	switch typ {
+	case HourPartitioningType:
+		return tm.Format("2006010215")
+	case MonthPartitioningType:
+		return tm.Format("200601")
+	case YearPartitioningType:
+		return tm.Format("2006")
+	default:
+		return tm.Format("20060102")
+	}
+}
+
+// Partitions returns the partitions of the table, ordered by ID.
+func (t *Table) Partitions(ctx context.Context) (partitions []*TablePartition, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.Partitions")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	it, err := t.partitionsQuery().Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var r partitionRow
+		err := it.Next(&r)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, r.toTablePartition())
+	}
+	return partitions, nil
+}
+
+// partitionRow is a row of the INFORMATION_SCHEMA.PARTITIONS view.
+type partitionRow struct {
+	PartitionID       NullString    `bigquery:"partition_id"`
+	TotalRows         NullInt64     `bigquery:"total_rows"`
+	TotalLogicalBytes NullInt64     `bigquery:"total_logical_bytes"`
+	LastModifiedTime  NullTimestamp `bigquery:"last_modified_time"`
+	StorageTier       NullString    `bigquery:"storage_tier"`
+}
+
+func (r *partitionRow) toTablePartition() *TablePartition {
+	return &TablePartition{
+		ID:                r.PartitionID.StringVal,
+		TotalRows:         r.TotalRows.Int64,
+		TotalLogicalBytes: r.TotalLogicalBytes.Int64,
+		LastModifiedTime:  r.LastModifiedTime.Timestamp,
+		StorageTier:       r.StorageTier.StringVal,
+	}
+}
+
+// partitionsQuery returns the query that reads the partitions of the table
+// from the INFORMATION_SCHEMA view.
+func (t *Table) partitionsQuery() *Query {
+	q := t.c.Query(fmt.Sprintf(
+		"SELECT partition_id, total_rows, total_logical_bytes, last_modified_time, storage_tier "+
+			"FROM %s.INFORMATION_SCHEMA.PARTITIONS WHERE table_name = @table ORDER BY partition_id",
+		quoteIdentifier(t.ProjectID+"."+t.DatasetID)))
+	q.Parameters = []QueryParameter{{Name: "table", Value: t.TableID}}
+	return q
+}
+
+// DeletePartitions deletes the partitions of the table with the given IDs,
+// several at once. Deleting a partition that does not exist is not an error.
+//
+// DeletePartitions attempts to delete every partition. If some deletions
+// fail, it returns a MultiError with a *PartitionError for each of them.
+func (t *Table) DeletePartitions(ctx context.Context, ids ...string) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.DeletePartitions")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return forEachConcurrently(len(ids), defaultPartitionConcurrency, func(i int) error {
+		err := t.Partition(ids[i]).Delete(ctx)
+		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+			return nil
+		}
+		if err != nil {
+			return &PartitionError{Table: t.FullyQualifiedName(), PartitionID: ids[i], Err: err}
+		}
+		return nil
+	})
+}
+
+// UpdatePartitionExpiration sets the partition expiration of many
+// time-partitioned tables concurrently. Partitions older than the expiration
+// are deleted by BigQuery. An expiration of zero removes it, so that
+// partitions do not expire.
+//
+// Each table is read and then updated with its ETag, which is retried if the
+// table is changed in between, so the update does not overwrite other
+// changes. Tables that already have the expiration are not updated.
+//
+// UpdatePartitionExpiration attempts to update every table. If some updates
+// fail, it returns a MultiError with a *PartitionError for each of them.
+func (c *Client) UpdatePartitionExpiration(ctx context.Context, tables []*Table, expiration time.Duration) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Client.UpdatePartitionExpiration")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	targets := make([]partitionTarget, len(tables))
+	for i, t := range tables {
+		targets[i] = partitionedTable{t}
+	}
+	return updatePartitionExpirations(ctx, targets, expiration)
+}
+
+func updatePartitionExpirations(ctx context.Context, targets []partitionTarget, expiration time.Duration) error {
+	return forEachConcurrently(len(targets), defaultPartitionConcurrency, func(i int) error {
+		if err := updatePartitionExpiration(ctx, targets[i], expiration); err != nil {
+			return &PartitionError{Table: targets[i].name(), Err: err}
+		}
+		return nil
+	})
+}
+
+// partitionTarget is a time-partitioned table.
+type partitionTarget interface {
+	name() string
+	// timePartitioning returns the time partitioning and ETag of the table.
+	timePartitioning(ctx context.Context) (*TimePartitioning, string, error)
+	// updateTimePartitioning changes the time partitioning of the table, if
+	// its ETag matches.
+	updateTimePartitioning(ctx context.Context, tp *TimePartitioning, etag string) error
+}
+
+func updatePartitionExpiration(ctx context.Context, t partitionTarget, expiration time.Duration) error {
+	var err error
+	for attempt := 0; attempt < maxPartitionExpirationAttempts; attempt++ {
+		var (
+			tp   *TimePartitioning
+			etag string
+		)
+		tp, etag, err = t.timePartitioning(ctx)
+		if err != nil {
+			return err
+		}
+		if tp == nil {
+			return errors.New("table is not time-partitioned")
+		}
+		if tp.Expiration == expiration {
+			return nil
+		}
+		// The update replaces the whole time partitioning, so keep its other
+		// fields.
+		tp2 := *tp
+		tp2.Expiration = expiration
+		err = t.updateTimePartitioning(ctx, &tp2, etag)
+		if e, ok := err.(*googleapi.Error); !ok || e.Code != http.StatusPreconditionFailed {
+			return err
+		}
+		// The table changed since it was read; read it again.
+	}
+	return err
+}
+
+type partitionedTable struct{ t *Table }
+
+func (t partitionedTable) name() string { return t.t.FullyQualifiedName() }
+
+func (t partitionedTable) timePartitioning(ctx context.Context) (*TimePartitioning, string, error) {
+	md, err := t.t.Metadata(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return md.TimePartitioning, md.ETag, nil
+}
+
+func (t partitionedTable) updateTimePartitioning(ctx context.Context, tp *TimePartitioning, etag string) error {
+	_, err := t.t.Update(ctx, TableMetadataToUpdate{TimePartitioning: tp}, etag)
+	return err
+}
+
+// forEachConcurrently calls f for each of 0 to n-1, with at most concurrency
+// calls at once. It returns the errors of the calls as a MultiError, or nil if
+// there are none.
+func forEachConcurrently(n, concurrency int, f func(i int) error) error {
+	var (
+		mu   sync.Mutex
+		errs MultiError
+		wg   sync.WaitGroup
+	)
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if err := f(i); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
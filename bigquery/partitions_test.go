@@ -0,0 +1,224 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/internal/testutil"
+	"google.golang.org/api/googleapi"
+)
+
+func TestPartition(t *testing.T) {
+	c := &Client{projectID: "p"}
+	table := c.Dataset("d").Table("t")
+	p := table.Partition("20220301")
+	if p.ProjectID != "p" || p.DatasetID != "d" || p.TableID != "t$20220301" || p.c != c {
+		t.Errorf("got %+v", p)
+	}
+
+	q := table.partitionsQuery()
+	if want := "FROM `p.d`.INFORMATION_SCHEMA.PARTITIONS WHERE table_name = @table"; !strings.Contains(q.Q, want) {
+		t.Errorf("query %q does not contain %q", q.Q, want)
+	}
+	if len(q.Parameters) != 1 || q.Parameters[0].Value != "t" {
+		t.Errorf("got parameters %+v", q.Parameters)
+	}
+}
+
+func TestTimePartitionID(t *testing.T) {
+	tm := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	for _, test := range []struct {
+		typ  TimePartitioningType
+		want string
+	}{
+		{"", "20220304"},
+		{DayPartitioningType, "20220304"},
+		{HourPartitioningType, "2022030405"},
+		{MonthPartitioningType, "202203"},
+		{YearPartitioningType, "2022"},
+	} {
+		if got := TimePartitionID(tm, test.typ); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.typ, got, test.want)
+		}
+	}
+}
+
+func TestPartitionRowToTablePartition(t *testing.T) {
+	schema := Schema{
+		{Name: "partition_id", Type: StringFieldType},
+		{Name: "total_rows", Type: IntegerFieldType},
+		{Name: "total_logical_bytes", Type: IntegerFieldType},
+		{Name: "last_modified_time", Type: TimestampFieldType},
+		{Name: "storage_tier", Type: StringFieldType},
+	}
+	ts := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	row := []Value{"20220304", int64(10), int64(420), ts, "ACTIVE"}
+
+	var r partitionRow
+	var sl structLoader
+	if err := sl.set(&r, schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := sl.Load(row, schema); err != nil {
+		t.Fatal(err)
+	}
+	want := &TablePartition{
+		ID:                "20220304",
+		TotalRows:         10,
+		TotalLogicalBytes: 420,
+		LastModifiedTime:  ts,
+		StorageTier:       "ACTIVE",
+	}
+	if diff := testutil.Diff(r.toTablePartition(), want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
+
+// fakePartitionTarget is an in-memory table. If conflicts is positive, that
+// many updates fail because the table changed.
+type fakePartitionTarget struct {
+	id        string
+	conflicts int
+
+	mu      sync.Mutex
+	current *TimePartitioning
+	etag    int
+	updates int
+}
+
+func (f *fakePartitionTarget) name() string { return f.id }
+
+func (f *fakePartitionTarget) timePartitioning(context.Context) (*TimePartitioning, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.current == nil {
+		return nil, "", nil
+	}
+	tp := *f.current
+	return &tp, string(rune('a' + f.etag)), nil
+}
+
+func (f *fakePartitionTarget) updateTimePartitioning(_ context.Context, tp *TimePartitioning, etag string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates++
+	if f.conflicts > 0 {
+		f.conflicts--
+		f.etag++
+		return &googleapi.Error{Code: http.StatusPreconditionFailed}
+	}
+	if etag != string(rune('a'+f.etag)) {
+		return errors.New("stale etag")
+	}
+	f.current = tp
+	f.etag++
+	return nil
+}
+
+func TestUpdatePartitionExpirations(t *testing.T) {
+	day := 24 * time.Hour
+	targets := []*fakePartitionTarget{
+		{id: "changed", current: &TimePartitioning{Type: DayPartitioningType, Field: "f", Expiration: day}},
+		{id: "unchanged", current: &TimePartitioning{Type: HourPartitioningType, Expiration: 7 * day}},
+		{id: "conflict", current: &TimePartitioning{Type: DayPartitioningType}, conflicts: 2},
+		{id: "unpartitioned"},
+		{id: "always-conflict", current: &TimePartitioning{}, conflicts: maxPartitionExpirationAttempts},
+	}
+	var pts []partitionTarget
+	for _, f := range targets {
+		pts = append(pts, f)
+	}
+	err := updatePartitionExpirations(context.Background(), pts, 7*day)
+
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 2 {
+		t.Fatalf("got %v, want MultiError with 2 errors", err)
+	}
+	failed := map[string]bool{}
+	for _, e := range me {
+		pe := e.(*PartitionError)
+		if pe.PartitionID != "" {
+			t.Errorf("%s: got partition ID %q, want none", pe.Table, pe.PartitionID)
+		}
+		failed[pe.Table] = true
+	}
+	if !failed["unpartitioned"] || !failed["always-conflict"] {
+		t.Errorf("got errors for %v, want unpartitioned and always-conflict", failed)
+	}
+
+	for _, test := range []struct {
+		f           *fakePartitionTarget
+		want        *TimePartitioning
+		wantUpdates int
+	}{
+		{targets[0], &TimePartitioning{Type: DayPartitioningType, Field: "f", Expiration: 7 * day}, 1},
+		{targets[1], &TimePartitioning{Type: HourPartitioningType, Expiration: 7 * day}, 0},
+		{targets[2], &TimePartitioning{Type: DayPartitioningType, Expiration: 7 * day}, 3},
+		{targets[4], &TimePartitioning{}, maxPartitionExpirationAttempts},
+	} {
+		if diff := testutil.Diff(test.f.current, test.want); diff != "" {
+			t.Errorf("%s: partitioning: got=-, want=+:\n%s", test.f.id, diff)
+		}
+		if test.f.updates != test.wantUpdates {
+			t.Errorf("%s: got %d updates, want %d", test.f.id, test.f.updates, test.wantUpdates)
+		}
+	}
+}
+
+func TestForEachConcurrently(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		active, max int
+		calls       = map[int]bool{}
+	)
+	failed := errors.New("failed")
+	err := forEachConcurrently(10, 3, func(i int) error {
+		mu.Lock()
+		active++
+		if active > max {
+			max = active
+		}
+		calls[i] = true
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		if i%5 == 0 {
+			return failed
+		}
+		return nil
+	})
+	var me MultiError
+	if !errors.As(err, &me) || len(me) != 2 {
+		t.Fatalf("got %v, want MultiError with 2 errors", err)
+	}
+	if len(calls) != 10 {
+		t.Errorf("got %d calls, want 10", len(calls))
+	}
+	if max > 3 {
+		t.Errorf("got %d concurrent calls, want at most 3", max)
+	}
+	if err := forEachConcurrently(0, 3, func(int) error { return failed }); err != nil {
+		t.Errorf("no calls: got %v, want nil", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cloud.google.com/go/internal/trace"
+	"google.golang.org/api/googleapi"
+)
+
+// maxPolicyTagAttempts is the number of times UpdatePolicyTags reads and
+// updates a table if another update changes the table in between.
+const maxPolicyTagAttempts = 5
+
+// PolicyTags returns the policy tags of the columns of the schema that have
+// any, keyed by column path. The path of a nested column joins the names of
+// its enclosing RECORD columns and its own name with dots, as in
+// "address.zip".
+func (s Schema) PolicyTags() map[string]*PolicyTagList {
+	tags := map[string]*PolicyTagList{}
+	s.collectPolicyTags("", tags)
+	return tags
+}
+
+func (s Schema) collectPolicyTags(prefix string, tags map[string]*PolicyTagList) {
+	for _, f := range s {
+		path := prefix + f.Name
+		if f.PolicyTags != nil && len(f.PolicyTags.Names) > 0 {
+			tags[path] = f.PolicyTags
+		}
+		f.Schema.collectPolicyTags(path+".", tags)
+	}
+}
+
+// WithPolicyTags returns a copy of the schema in which the columns named by
+// the keys of tags, which are column paths as returned by Schema.PolicyTags,
+// have the given policy tags. A nil or empty PolicyTagList detaches the policy
+// tags of its column. Column names are matched without regard to case. The
+// schema itself is not modified.
+func (s Schema) WithPolicyTags(tags map[string]*PolicyTagList) (Schema, error) {
+	out := s.copy()
+	for path, ptl := range tags {
+		f, err := out.lookupPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if ptl == nil {
+			// An empty list, unlike a nil one, is sent to BigQuery, which
+			// removes the column's policy tags.
+			ptl = &PolicyTagList{}
+		}
+		f.PolicyTags = ptl
+	}
+	return out, nil
+}
+
+// PreservePolicyTags returns a copy of the schema in which each column whose
+// PolicyTags is nil has the policy tags of the column with the same path in
+// from. Schema updates replace the policy tags of every column, so use it to
+// keep the policy tags of a table's current schema when updating the schema
+// from another source, such as a schema inferred from a Go struct. Columns
+// whose PolicyTags is an empty PolicyTagList keep no policy tags.
+func (s Schema) PreservePolicyTags(from Schema) Schema {
+	out := s.copy()
+	out.preservePolicyTags(from)
+	return out
+}
+
+func (s Schema) preservePolicyTags(from Schema) {
+	for _, f := range s {
+		prev := from.lookup(f.Name)
+		if prev == nil {
+			continue
+		}
+		if f.PolicyTags == nil {
+			f.PolicyTags = prev.PolicyTags
+		}
+		f.Schema.preservePolicyTags(prev.Schema)
+	}
+}
+
+// copy returns a deep copy of the schema's fields.
+func (s Schema) copy() Schema {
+	if s == nil {
+		return nil
+	}
+	out := make(Schema, len(s))
+	for i, f := range s {
+		c := *f
+		c.Schema = f.Schema.copy()
+		out[i] = &c
+	}
+	return out
+}
+
+// lookup returns the field of the schema with the given name, or nil.
+func (s Schema) lookup(name string) *FieldSchema {
+	for _, f := range s {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// lookupPath returns the field of the schema with the given column path.
+func (s Schema) lookupPath(path string) (*FieldSchema, error) {
+	var f *FieldSchema
+	fields := s
+	for _, name := range strings.Split(path, ".") {
+		if f = fields.lookup(name); f == nil {
+			return nil, fmt.Errorf("bigquery: no column %q in schema", path)
+		}
+		fields = f.Schema
+	}
+	return f, nil
+}
+
+// UpdatePolicyTags attaches policy tags to, or detaches them from, columns of
+// the table, as described by Schema.WithPolicyTags, and leaves the policy tags
+// of other columns as they are.
+//
+// The table's schema is read and then updated with its ETag, which is retried
+// if the table is changed in between, so the update does not overwrite other
+// changes to the schema.
+func (t *Table) UpdatePolicyTags(ctx context.Context, tags map[string]*PolicyTagList) (md *TableMetadata, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.UpdatePolicyTags")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	for attempt := 0; attempt < maxPolicyTagAttempts; attempt++ {
+		md, err = t.Metadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var schema Schema
+		schema, err = md.Schema.WithPolicyTags(tags)
+		if err != nil {
+			return nil, err
+		}
+		md, err = t.Update(ctx, TableMetadataToUpdate{Schema: schema}, md.ETag)
+		if e, ok := err.(*googleapi.Error); !ok || e.Code != http.StatusPreconditionFailed {
+			return md, err
+		}
+		// The table changed since it was read; read it again.
+	}
+	return nil, err
+}
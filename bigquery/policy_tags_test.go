@@ -0,0 +1,129 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+)
+
+func policyTagSchema() Schema {
+	return Schema{
+		{Name: "name", Type: StringFieldType},
+		{Name: "ssn", Type: StringFieldType, PolicyTags: &PolicyTagList{Names: []string{"pii"}}},
+		{Name: "address", Type: RecordFieldType, Schema: Schema{
+			{Name: "street", Type: StringFieldType},
+			{Name: "zip", Type: StringFieldType, PolicyTags: &PolicyTagList{Names: []string{"location"}}},
+		}},
+	}
+}
+
+func TestSchemaPolicyTags(t *testing.T) {
+	got := policyTagSchema().PolicyTags()
+	want := map[string]*PolicyTagList{
+		"ssn":         {Names: []string{"pii"}},
+		"address.zip": {Names: []string{"location"}},
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
+
+func TestSchemaWithPolicyTags(t *testing.T) {
+	s := policyTagSchema()
+	got, err := s.WithPolicyTags(map[string]*PolicyTagList{
+		"NAME":           {Names: []string{"public"}},
+		"ssn":            nil,
+		"address.street": {Names: []string{"location"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := policyTagSchema()
+	want[0].PolicyTags = &PolicyTagList{Names: []string{"public"}}
+	want[1].PolicyTags = &PolicyTagList{}
+	want[2].Schema[0].PolicyTags = &PolicyTagList{Names: []string{"location"}}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+	if diff := testutil.Diff(s, policyTagSchema()); diff != "" {
+		t.Errorf("WithPolicyTags modified the schema: got=-, want=+:\n%s", diff)
+	}
+
+	for _, path := range []string{"missing", "address.missing", "name.inner"} {
+		if _, err := s.WithPolicyTags(map[string]*PolicyTagList{path: nil}); err == nil {
+			t.Errorf("%s: got nil, want error", path)
+		}
+	}
+}
+
+func TestSchemaPreservePolicyTags(t *testing.T) {
+	// A schema from another source, which has no policy tags, except for a
+	// column whose tags are detached.
+	s := Schema{
+		{Name: "name", Type: StringFieldType},
+		{Name: "SSN", Type: StringFieldType},
+		{Name: "address", Type: RecordFieldType, Schema: Schema{
+			{Name: "street", Type: StringFieldType},
+			{Name: "zip", Type: StringFieldType, PolicyTags: &PolicyTagList{}},
+		}},
+		{Name: "new", Type: IntegerFieldType},
+	}
+	got := s.PreservePolicyTags(policyTagSchema())
+	want := Schema{
+		{Name: "name", Type: StringFieldType},
+		{Name: "SSN", Type: StringFieldType, PolicyTags: &PolicyTagList{Names: []string{"pii"}}},
+		{Name: "address", Type: RecordFieldType, Schema: Schema{
+			{Name: "street", Type: StringFieldType},
+			{Name: "zip", Type: StringFieldType, PolicyTags: &PolicyTagList{}},
+		}},
+		{Name: "new", Type: IntegerFieldType},
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+	if s[1].PolicyTags != nil {
+		t.Error("PreservePolicyTags modified the schema")
+	}
+}
+
+func TestEmptyPolicyTagListToBQ(t *testing.T) {
+	for _, tc := range []struct {
+		ptl  *PolicyTagList
+		want string
+	}{
+		{&PolicyTagList{}, `{"name":"ssn","policyTags":{"names":[]},"type":"STRING"}`},
+		{&PolicyTagList{Names: []string{"pii"}}, `{"name":"ssn","policyTags":{"names":["pii"]},"type":"STRING"}`},
+		{nil, `{"name":"ssn","type":"STRING"}`},
+	} {
+		fs := &FieldSchema{Name: "ssn", Type: StringFieldType, PolicyTags: tc.ptl}
+		b, err := json.Marshal(fs.toBQ())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(b); got != tc.want {
+			t.Errorf("%+v: got %s, want %s", tc.ptl, got, tc.want)
+		}
+	}
+}
+
+func TestRelaxKeepsPolicyTags(t *testing.T) {
+	got := policyTagSchema().Relax().PolicyTags()
+	if diff := testutil.Diff(got, policyTagSchema().PolicyTags()); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
@@ -73,10 +73,16 @@ type QueryConfig struct {
 	AllowLargeResults bool
 
 	// Priority specifies the priority with which to schedule the query.
-	// The default priority is InteractivePriority.
+	// The default priority is the client's DefaultQueryPriority, or
+	// InteractivePriority if that is empty.
 	// For more information, see https://cloud.google.com/bigquery/querying-data#batchqueries
 	Priority QueryPriority
 
+	// JobCreationMode specifies whether Query.Read may run the query without
+	// inserting a job. If empty, the client's DefaultJobCreationMode is used.
+	// It is not reported by Job.Config.
+	JobCreationMode JobCreationMode
+
 	// MaxBillingTier sets the maximum billing tier for a Query.
 	// Queries that have resource usage beyond this tier will fail (without
 	// incurring a charge). If this field is zero, the project default will be used.
@@ -89,6 +95,13 @@ type QueryConfig struct {
 	// used.
 	MaxBytesBilled int64
 
+	// RequireCacheHit causes the query to fail unless its results are served
+	// from the query cache, which is not billed. The query is sent with a
+	// MaxBytesBilled of 1, so a query that would be billed fails with an error
+	// whose reason is "bytesBilledLimitExceeded", without incurring a charge.
+	// It cannot be combined with DisableQueryCache.
+	RequireCacheHit bool
+
 	// UseStandardSQL causes the query to use standard SQL. The default.
 	// Deprecated: use UseLegacySQL.
 	UseStandardSQL bool
@@ -114,7 +127,8 @@ type QueryConfig struct {
 	// Clustering specifies the data clustering configuration for the destination table.
 	Clustering *Clustering
 
-	// The labels associated with this job.
+	// The labels associated with this job. The client's DefaultJobLabels are
+	// added to them.
 	Labels map[string]string
 
 	// If true, don't actually run this job. A valid query will return a mostly
@@ -138,6 +152,19 @@ type QueryConfig struct {
 
 	// ConnectionProperties are optional key-values settings.
 	ConnectionProperties []*ConnectionProperty
+
+	// SessionID runs the query in the session with this ID, by sending it as
+	// the "session_id" connection property. Queries created with
+	// Session.Query set it.
+	SessionID string
+}
+
+// maxBytesBilled returns the limit of billed bytes to send.
+func (qc *QueryConfig) maxBytesBilled() int64 {
+	if qc.RequireCacheHit {
+		return 1
+	}
+	return qc.MaxBytesBilled
 }
 
 func (qc *QueryConfig) toBQ() (*bq.JobConfiguration, error) {
@@ -147,7 +174,7 @@ func (qc *QueryConfig) toBQ() (*bq.JobConfiguration, error) {
 		WriteDisposition:                   string(qc.WriteDisposition),
 		AllowLargeResults:                  qc.AllowLargeResults,
 		Priority:                           string(qc.Priority),
-		MaximumBytesBilled:                 qc.MaxBytesBilled,
+		MaximumBytesBilled:                 qc.maxBytesBilled(),
 		TimePartitioning:                   qc.TimePartitioning.toBQ(),
 		RangePartitioning:                  qc.RangePartitioning.toBQ(),
 		Clustering:                         qc.Clustering.toBQ(),
@@ -172,6 +199,9 @@ func (qc *QueryConfig) toBQ() (*bq.JobConfiguration, error) {
 	}
 	f := false
 	if qc.DisableQueryCache {
+		if qc.RequireCacheHit {
+			return nil, errors.New("bigquery: cannot provide both RequireCacheHit and DisableQueryCache")
+		}
 		qconf.UseQueryCache = &f
 	}
 	if qc.DisableFlattenedResults {
@@ -202,13 +232,7 @@ func (qc *QueryConfig) toBQ() (*bq.JobConfiguration, error) {
 		}
 		qconf.QueryParameters = append(qconf.QueryParameters, qp)
 	}
-	if len(qc.ConnectionProperties) > 0 {
-		bqcp := make([]*bq.ConnectionProperty, len(qc.ConnectionProperties))
-		for k, v := range qc.ConnectionProperties {
-			bqcp[k] = v.toBQ()
-		}
-		qconf.ConnectionProperties = bqcp
-	}
+	qconf.ConnectionProperties = qc.connectionPropertiesToBQ()
 	return &bq.JobConfiguration{
 		Labels: qc.Labels,
 		DryRun: qc.DryRun,
@@ -270,16 +294,29 @@ func bqToQueryConfig(q *bq.JobConfiguration, c *Client) (*QueryConfig, error) {
 		}
 		qc.Parameters = append(qc.Parameters, p)
 	}
-	if len(qq.ConnectionProperties) > 0 {
-		props := make([]*ConnectionProperty, len(qq.ConnectionProperties))
-		for k, v := range qq.ConnectionProperties {
-			props[k] = bqToConnectionProperty(v)
+	for _, v := range qq.ConnectionProperties {
+		if v != nil && v.Key == sessionIDProperty && qc.SessionID == "" {
+			qc.SessionID = v.Value
+			continue
 		}
-		qc.ConnectionProperties = props
+		qc.ConnectionProperties = append(qc.ConnectionProperties, bqToConnectionProperty(v))
 	}
 	return qc, nil
 }
 
+// connectionPropertiesToBQ returns the connection properties of qc, including
+// the session ID.
+func (qc *QueryConfig) connectionPropertiesToBQ() []*bq.ConnectionProperty {
+	var bqcp []*bq.ConnectionProperty
+	for _, v := range qc.ConnectionProperties {
+		bqcp = append(bqcp, v.toBQ())
+	}
+	if qc.SessionID != "" {
+		bqcp = append(bqcp, &bq.ConnectionProperty{Key: sessionIDProperty, Value: qc.SessionID})
+	}
+	return bqcp
+}
+
 // QueryPriority specifies a priority with which a query is to be executed.
 type QueryPriority string
 
@@ -304,10 +341,29 @@ const (
 	InteractivePriority QueryPriority = "INTERACTIVE"
 )
 
+// JobCreationMode specifies how Query.Read runs a query.
+type JobCreationMode string
+
+const (
+	// JobCreationOptional lets Query.Read run a query with the jobs.query
+	// API, which returns small results faster, when the query's config allows
+	// it. It is the default.
+	JobCreationOptional JobCreationMode = "JOB_CREATION_OPTIONAL"
+	// JobCreationRequired makes Query.Read insert a job for the query with the
+	// jobs.insert API, as Query.Run does.
+	JobCreationRequired JobCreationMode = "JOB_CREATION_REQUIRED"
+)
+
 // A Query queries data from a BigQuery table. Use Client.Query to create a Query.
 type Query struct {
 	JobIDConfig
 	QueryConfig
+
+	// Retry configures how the requests of the query are retried, and whether
+	// the query is run again if it fails. If nil, the default retries are
+	// used. The Job returned by Run uses Retry as it waits for the query.
+	Retry *RetryConfig
+
 	client *Client
 }
 
@@ -329,7 +385,7 @@ func (q *Query) Run(ctx context.Context) (j *Job, err error) {
 	if err != nil {
 		return nil, err
 	}
-	j, err = q.client.insertJob(ctx, job, nil)
+	j, err = q.client.insertJob(ctx, job, nil, q.Retry)
 	if err != nil {
 		return nil, err
 	}
@@ -341,12 +397,31 @@ func (q *Query) newJob() (*bq.Job, error) {
 	if err != nil {
 		return nil, err
 	}
+	config.Query.Priority = string(q.priority())
 	return &bq.Job{
 		JobReference:  q.JobIDConfig.createJobRef(q.client),
 		Configuration: config,
 	}, nil
 }
 
+// priority returns the priority of the query, which defaults to the client's
+// DefaultQueryPriority.
+func (q *Query) priority() QueryPriority {
+	if q.Priority == "" && q.client != nil {
+		return q.client.DefaultQueryPriority
+	}
+	return q.Priority
+}
+
+// jobCreationMode returns the JobCreationMode of the query, which defaults to
+// the client's DefaultJobCreationMode.
+func (q *Query) jobCreationMode() JobCreationMode {
+	if q.JobCreationMode == "" && q.client != nil {
+		return q.client.DefaultJobCreationMode
+	}
+	return q.JobCreationMode
+}
+
 // Read submits a query for execution and returns the results via a RowIterator.
 // If the request can be satisfied by running using the optimized query path, it
 // is used in place of the jobs.insert path as this path does not expose a job
@@ -364,7 +439,7 @@ func (q *Query) Read(ctx context.Context) (it *RowIterator, err error) {
 		return job.Read(ctx)
 	}
 	// we have a config, run on fastPath.
-	resp, err := q.client.runQuery(ctx, queryRequest)
+	resp, err := q.client.runQuery(ctx, queryRequest, q.Retry)
 	if err != nil {
 		return nil, err
 	}
@@ -374,6 +449,7 @@ func (q *Query) Read(ctx context.Context) (it *RowIterator, err error) {
 		jobID:     resp.JobReference.JobId,
 		location:  resp.JobReference.Location,
 		projectID: resp.JobReference.ProjectId,
+		retry:     q.Retry,
 	}
 	if resp.JobComplete {
 		rowSource := &rowSource{
@@ -383,7 +459,7 @@ func (q *Query) Read(ctx context.Context) (it *RowIterator, err error) {
 			cachedSchema:    resp.Schema,
 			cachedNextToken: resp.PageToken,
 		}
-		return newRowIterator(ctx, rowSource, fetchPage), nil
+		return newRowIterator(ctx, rowSource, q.client.readPageFetcher()), nil
 	}
 	// We're on the fastPath, but we need to poll because the job is incomplete.
 	// Fallback to job-based Read().
@@ -398,6 +474,20 @@ func (q *Query) Read(ctx context.Context) (it *RowIterator, err error) {
 	return minimalJob.Read(ctx)
 }
 
+// exec runs the query and waits for it to finish. It is used for statements
+// whose results are not needed, such as DDL.
+func (q *Query) exec(ctx context.Context) error {
+	job, err := q.Run(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
 // probeFastPath is used to attempt configuring a jobs.Query request based on a
 // user's Query configuration.  If all the options set on the job are supported on the
 // faster query path, this method returns a QueryRequest suitable for execution.
@@ -409,7 +499,8 @@ func (q *Query) probeFastPath() (*bq.QueryRequest, error) {
 		q.QueryConfig.TableDefinitions != nil ||
 		q.QueryConfig.CreateDisposition != "" ||
 		q.QueryConfig.WriteDisposition != "" ||
-		!(q.QueryConfig.Priority == "" || q.QueryConfig.Priority == InteractivePriority) ||
+		!(q.priority() == "" || q.priority() == InteractivePriority) ||
+		q.jobCreationMode() == JobCreationRequired ||
 		q.QueryConfig.UseLegacySQL ||
 		q.QueryConfig.MaxBillingTier != 0 ||
 		q.QueryConfig.TimePartitioning != nil ||
@@ -423,15 +514,19 @@ func (q *Query) probeFastPath() (*bq.QueryRequest, error) {
 	}
 	pfalse := false
 	qRequest := &bq.QueryRequest{
-		Query:              q.QueryConfig.Q,
-		CreateSession:      q.CreateSession,
-		Location:           q.Location,
-		UseLegacySql:       &pfalse,
-		MaximumBytesBilled: q.QueryConfig.MaxBytesBilled,
-		RequestId:          uid.NewSpace("request", nil).New(),
-		Labels:             q.Labels,
+		Query:                q.QueryConfig.Q,
+		CreateSession:        q.CreateSession,
+		Location:             q.Location,
+		UseLegacySql:         &pfalse,
+		MaximumBytesBilled:   q.QueryConfig.maxBytesBilled(),
+		RequestId:            uid.NewSpace("request", nil).New(),
+		Labels:               q.Labels,
+		ConnectionProperties: q.QueryConfig.connectionPropertiesToBQ(),
 	}
 	if q.QueryConfig.DisableQueryCache {
+		if q.QueryConfig.RequireCacheHit {
+			return nil, errors.New("bigquery: cannot provide both RequireCacheHit and DisableQueryCache")
+		}
 		qRequest.UseQueryCache = &pfalse
 	}
 	// Convert query parameters
@@ -344,6 +344,27 @@ func TestQuery(t *testing.T) {
 				return j
 			}(),
 		},
+		{
+			dst: c.Dataset("dataset-id").Table("table-id"),
+			src: &QueryConfig{
+				Q:                "query string",
+				DefaultProjectID: "def-project-id",
+				DefaultDatasetID: "def-dataset-id",
+				ConnectionProperties: []*ConnectionProperty{
+					{Key: "key-a", Value: "value-a"},
+				},
+				SessionID: "session-id",
+			},
+			want: func() *bq.Job {
+				j := defaultQueryJob()
+				j.Configuration.Query.ForceSendFields = nil
+				j.Configuration.Query.ConnectionProperties = []*bq.ConnectionProperty{
+					{Key: "key-a", Value: "value-a"},
+					{Key: "session_id", Value: "session-id"},
+				}
+				return j
+			}(),
+		},
 	}
 	for i, tc := range testCases {
 		query := c.Query("")
@@ -462,6 +483,34 @@ func TestProbeFastPath(t *testing.T) {
 				UseQueryCache: &pfalse,
 			},
 		},
+		{
+			inCfg: QueryConfig{
+				Q:             "foo",
+				CreateSession: true,
+			},
+			wantReq: &bq.QueryRequest{
+				Query:         "foo",
+				CreateSession: true,
+				UseLegacySql:  &pfalse,
+			},
+		},
+		{
+			inCfg: QueryConfig{
+				Q: "foo",
+				ConnectionProperties: []*ConnectionProperty{
+					{Key: "key", Value: "val"},
+				},
+				SessionID: "session-id",
+			},
+			wantReq: &bq.QueryRequest{
+				Query: "foo",
+				ConnectionProperties: []*bq.ConnectionProperty{
+					{Key: "key", Value: "val"},
+					{Key: "session_id", Value: "session-id"},
+				},
+				UseLegacySql: &pfalse,
+			},
+		},
 		{
 			// fail, sets destination via API
 			inCfg: QueryConfig{
@@ -489,7 +538,7 @@ func TestProbeFastPath(t *testing.T) {
 		},
 	}
 	for i, tc := range testCases {
-		in := &Query{tc.inJobCfg, tc.inCfg, c}
+		in := &Query{JobIDConfig: tc.inJobCfg, QueryConfig: tc.inCfg, client: c}
 		gotReq, err := in.probeFastPath()
 		if tc.wantErr && err == nil {
 			t.Errorf("case %d wanted error, got nil", i)
@@ -567,3 +616,68 @@ func TestQueryLegacySQL(t *testing.T) {
 		t.Error("Parameters and UseLegacySQL: got nil, want error")
 	}
 }
+
+func TestQueryRequireCacheHit(t *testing.T) {
+	c := &Client{projectID: "project-id"}
+	q := c.Query("q")
+	q.MaxBytesBilled = 1000
+	q.RequireCacheHit = true
+	job, err := q.newJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := job.Configuration.Query.MaximumBytesBilled; got != 1 {
+		t.Errorf("job: got MaximumBytesBilled %d, want 1", got)
+	}
+	req, err := q.probeFastPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.MaximumBytesBilled; got != 1 {
+		t.Errorf("fast path: got MaximumBytesBilled %d, want 1", got)
+	}
+
+	q.DisableQueryCache = true
+	if _, err := q.newJob(); err == nil {
+		t.Error("RequireCacheHit and DisableQueryCache: got nil, want error")
+	}
+	if _, err := q.probeFastPath(); err == nil {
+		t.Error("fast path with RequireCacheHit and DisableQueryCache: got nil, want error")
+	}
+}
+
+func TestQueryClientDefaults(t *testing.T) {
+	c := &Client{projectID: "project-id", DefaultQueryPriority: BatchPriority}
+	q := c.Query("q")
+	job, err := q.newJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := job.Configuration.Query.Priority; got != "BATCH" {
+		t.Errorf("default priority: got %q, want BATCH", got)
+	}
+	if _, err := q.probeFastPath(); err == nil {
+		t.Error("fast path with default batch priority: got nil, want error")
+	}
+
+	q.Priority = InteractivePriority
+	job, err = q.newJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := job.Configuration.Query.Priority; got != "INTERACTIVE" {
+		t.Errorf("query priority: got %q, want INTERACTIVE", got)
+	}
+	if _, err := q.probeFastPath(); err != nil {
+		t.Errorf("fast path with interactive priority: %v", err)
+	}
+
+	c.DefaultJobCreationMode = JobCreationRequired
+	if _, err := q.probeFastPath(); err == nil {
+		t.Error("fast path with default JobCreationRequired: got nil, want error")
+	}
+	q.JobCreationMode = JobCreationOptional
+	if _, err := q.probeFastPath(); err != nil {
+		t.Errorf("fast path with JobCreationOptional: %v", err)
+	}
+}
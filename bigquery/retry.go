@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/internal"
+	gax "github.com/googleapis/gax-go/v2"
+	bq "google.golang.org/api/bigquery/v2"
+)
+
+// A RetryConfig configures how the requests of a job are retried, and whether
+// a job that fails is run again. Set it with Query.Retry or Job.WithRetry.
+type RetryConfig struct {
+	// Backoff is the backoff between retries of a request, and between polls
+	// of a job that is not done. If zero, each request uses its default
+	// backoff, which starts at one second and grows to at most a minute.
+	Backoff gax.Backoff
+
+	// Reasons are the reasons of errors that are retried, such as
+	// "backendError", "rateLimitExceeded" and "internalError", which are
+	// retried by default. An error's reason is the Reason of the first item
+	// of its googleapi.Error Errors. Errors with HTTP status 502 and 503, and
+	// temporary network errors, are always retried.
+	Reasons []string
+
+	// ShouldRetry, if not nil, reports whether an error is retried. It
+	// replaces Reasons and the default checks.
+	ShouldRetry func(err error) bool
+
+	// JobRetries is the number of times that Job.Wait and Job.Read run a job
+	// again, as a new job, if it fails with an error that is retried. After a
+	// job is run again, the Job refers to the new job, which is billed.
+	// If zero, failed jobs are not run again.
+	//
+	// A failed job that runs a single statement makes no changes, so it can
+	// be run again safely. Scripts, which run several statements as child
+	// jobs, are never run again: the changes of the statements that completed
+	// before the failure are kept, and would be applied twice. Loads from a
+	// ReaderSource are not run again either, as their data cannot be
+	// uploaded again.
+	JobRetries int
+}
+
+// retryable reports whether err is retried, using the defaultReasons if the
+// RetryConfig does not list any. rc may be nil.
+func (rc *RetryConfig) retryable(err error, defaultReasons []string) bool {
+	if rc == nil {
+		return retryableError(err, defaultReasons)
+	}
+	if rc.ShouldRetry != nil {
+		return err != nil && rc.ShouldRetry(err)
+	}
+	if rc.Reasons != nil {
+		return retryableError(err, rc.Reasons)
+	}
+	return retryableError(err, defaultReasons)
+}
+
+// backoff returns the backoff of the RetryConfig, or def if it is not set. rc
+// may be nil.
+func (rc *RetryConfig) backoff(def gax.Backoff) gax.Backoff {
+	if rc == nil || rc.Backoff == (gax.Backoff{}) {
+		return def
+	}
+	return gax.Backoff{
+		Initial:    rc.Backoff.Initial,
+		Max:        rc.Backoff.Max,
+		Multiplier: rc.Backoff.Multiplier,
+	}
+}
+
+// rerunsJobs reports whether failed jobs are run again. rc may be nil.
+func (rc *RetryConfig) rerunsJobs() bool {
+	return rc != nil && rc.JobRetries > 0
+}
+
+// runWithRetryConfig calls the function until it returns nil or an error that
+// rc does not retry, or the context is done.
+func runWithRetryConfig(ctx context.Context, call func() error, rc *RetryConfig, defaultReasons []string) error {
+	return internal.Retry(ctx, rc.backoff(defaultBackoff()), func() (stop bool, err error) {
+		err = call()
+		if err == nil {
+			return true, nil
+		}
+		return !rc.retryable(err, defaultReasons), err
+	})
+}
+
+// WithRetry returns a copy of the job that retries its requests, and reruns
+// it if it fails, as configured by rc. A nil rc restores the default retries.
+func (j *Job) WithRetry(rc *RetryConfig) *Job {
+	j2 := *j
+	j2.retry = rc
+	return &j2
+}
+
+// withJobRetries calls wait, which waits for the job and returns its error.
+// If the job failed with an error that is retried, it runs the job again, up
+// to the JobRetries of the job's RetryConfig.
+func (j *Job) withJobRetries(ctx context.Context, wait func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := wait()
+		if err == nil || !j.retry.rerunsJobs() || attempt >= j.retry.JobRetries || !j.retry.retryable(err, jobRetryReasons) {
+			return err
+		}
+		if rerr := j.rerun(ctx); rerr != nil {
+			return err
+		}
+	}
+}
+
+// rerun inserts a new job with the configuration of j, and makes j refer to
+// it.
+func (j *Job) rerun(ctx context.Context) error {
+	// Fetch the configuration, which is not always known, such as for queries
+	// that ran on the fast path.
+	bqjob, err := j.c.getJobInternal(ctx, j.jobID, j.location, j.projectID, "configuration", "statistics")
+	if err != nil {
+		return err
+	}
+	if !rerunnable(bqjob) {
+		return errors.New("bigquery: job cannot be run again")
+	}
+	nj, err := j.c.insertJob(ctx, &bq.Job{
+		JobReference:  (&JobIDConfig{Location: j.location}).createJobRef(j.c),
+		Configuration: bqjob.Configuration,
+	}, nil, j.retry)
+	if err != nil {
+		return err
+	}
+	j.projectID, j.jobID, j.location = nj.projectID, nj.jobID, nj.location
+	j.config, j.lastStatus = nj.config, nj.lastStatus
+	return nil
+}
+
+// rerunnable reports whether a failed job can be run again as a new job.
+func rerunnable(job *bq.Job) bool {
+	config := job.Configuration
+	if config == nil {
+		return false
+	}
+	if config.Load != nil && len(config.Load.SourceUris) == 0 {
+		// The data of a load from a reader cannot be uploaded again.
+		return false
+	}
+	if s := job.Statistics; s != nil && (s.NumChildJobs > 0 || (s.Query != nil && s.Query.StatementType == "SCRIPT")) {
+		// The statements of a script that completed before it failed are
+		// not rolled back.
+		return false
+	}
+	return true
+}
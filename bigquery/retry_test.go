@@ -0,0 +1,136 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gax "github.com/googleapis/gax-go/v2"
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+func reasonError(reason string) error {
+	return &googleapi.Error{Code: 400, Errors: []googleapi.ErrorItem{{Reason: reason}}}
+}
+
+func TestRetryConfigRetryable(t *testing.T) {
+	custom := errors.New("custom")
+	for _, tc := range []struct {
+		description string
+		rc          *RetryConfig
+		err         error
+		want        bool
+	}{
+		{"nil config, default reason", nil, reasonError("backendError"), true},
+		{"nil config, other reason", nil, reasonError("quotaExceeded"), false},
+		{"empty config, default reason", &RetryConfig{}, reasonError("internalError"), true},
+		{"reasons replace defaults", &RetryConfig{Reasons: []string{"quotaExceeded"}}, reasonError("backendError"), false},
+		{"listed reason", &RetryConfig{Reasons: []string{"quotaExceeded"}}, reasonError("quotaExceeded"), true},
+		{"empty reasons keep status checks", &RetryConfig{Reasons: []string{}}, &googleapi.Error{Code: 503}, true},
+		{"ShouldRetry", &RetryConfig{ShouldRetry: func(err error) bool { return err == custom }}, custom, true},
+		{"ShouldRetry replaces defaults", &RetryConfig{ShouldRetry: func(err error) bool { return err == custom }}, reasonError("backendError"), false},
+		{"ShouldRetry, nil error", &RetryConfig{ShouldRetry: func(error) bool { return true }}, nil, false},
+	} {
+		if got := tc.rc.retryable(tc.err, jobRetryReasons); got != tc.want {
+			t.Errorf("%s: got %t, want %t", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestRetryConfigBackoff(t *testing.T) {
+	def := gax.Backoff{Initial: time.Second, Max: time.Minute, Multiplier: 2}
+	var nilConfig *RetryConfig
+	for _, rc := range []*RetryConfig{nilConfig, {}} {
+		if got := rc.backoff(def); got != def {
+			t.Errorf("%+v: got %+v, want %+v", rc, got, def)
+		}
+	}
+	want := gax.Backoff{Initial: time.Millisecond, Max: time.Second, Multiplier: 3}
+	if got := (&RetryConfig{Backoff: want}).backoff(def); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWithJobRetriesStops(t *testing.T) {
+	failed := reasonError("invalidQuery")
+	for _, tc := range []struct {
+		description string
+		rc          *RetryConfig
+		err         error
+	}{
+		{"success", &RetryConfig{JobRetries: 3}, nil},
+		{"no config", nil, reasonError("backendError")},
+		{"no job retries", &RetryConfig{}, reasonError("backendError")},
+		{"not retryable", &RetryConfig{JobRetries: 3}, failed},
+	} {
+		j := (&Job{}).WithRetry(tc.rc)
+		calls := 0
+		err := j.withJobRetries(context.Background(), func() error {
+			calls++
+			return tc.err
+		})
+		if err != tc.err || calls != 1 {
+			t.Errorf("%s: got error %v after %d calls, want %v after 1", tc.description, err, calls, tc.err)
+		}
+	}
+}
+
+func TestRerunnable(t *testing.T) {
+	query := &bq.JobConfiguration{Query: &bq.JobConfigurationQuery{Query: "SELECT 1"}}
+	for _, tc := range []struct {
+		description string
+		job         *bq.Job
+		want        bool
+	}{
+		{"query", &bq.Job{Configuration: query}, true},
+		{"DML statement", &bq.Job{
+			Configuration: query,
+			Statistics:    &bq.JobStatistics{Query: &bq.JobStatistics2{StatementType: "INSERT"}},
+		}, true},
+		{"load from URIs", &bq.Job{Configuration: &bq.JobConfiguration{
+			Load: &bq.JobConfigurationLoad{SourceUris: []string{"gs://b/o"}},
+		}}, true},
+		{"no configuration", &bq.Job{}, false},
+		{"load from reader", &bq.Job{Configuration: &bq.JobConfiguration{Load: &bq.JobConfigurationLoad{}}}, false},
+		{"script", &bq.Job{
+			Configuration: query,
+			Statistics:    &bq.JobStatistics{Query: &bq.JobStatistics2{StatementType: "SCRIPT"}},
+		}, false},
+		{"script with child jobs", &bq.Job{
+			Configuration: query,
+			Statistics:    &bq.JobStatistics{NumChildJobs: 2},
+		}, false},
+	} {
+		if got := rerunnable(tc.job); got != tc.want {
+			t.Errorf("%s: got %t, want %t", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestJobWithRetry(t *testing.T) {
+	rc := &RetryConfig{JobRetries: 1}
+	j := &Job{jobID: "j"}
+	j2 := j.WithRetry(rc)
+	if j.retry != nil {
+		t.Error("WithRetry changed the original job")
+	}
+	if j2.retry != rc || j2.jobID != "j" {
+		t.Errorf("got %+v, want job j with the retry config", j2)
+	}
+}
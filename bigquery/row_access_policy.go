@@ -0,0 +1,254 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/iterator"
+)
+
+// A RowAccessPolicy is a reference to a row-level access policy of a table.
+// Each policy grants some principals read access to the rows of the table that
+// match its filter. Once a table has a row access policy, principals that are
+// not granted any policy of the table cannot read any of its rows.
+//
+// BigQuery manages row access policies with DDL statements, so Create, Replace
+// and Delete run a query job in the project of the client.
+//
+// See https://cloud.google.com/bigquery/docs/row-level-security-intro for more
+// information.
+type RowAccessPolicy struct {
+	ProjectID string
+	DatasetID string
+	TableID   string
+	PolicyID  string
+
+	c *Client
+}
+
+// RowAccessPolicyMetadata holds the metadata of a row access policy.
+type RowAccessPolicyMetadata struct {
+	// The ID of the policy. This field is read-only.
+	PolicyID string
+
+	// FilterPredicate is a SQL boolean expression over the columns of the table,
+	// such as `region = "US"`. Grantees of the policy can read only the rows for
+	// which it is true. A filter of TRUE grants access to all rows.
+	FilterPredicate string
+
+	// Grantees are the principals that are granted the policy, such as
+	// "user:alice@example.com", "group:sales@example.com",
+	// "serviceAccount:robot@example.iam.gserviceaccount.com",
+	// "domain:example.com" or "allAuthenticatedUsers".
+	//
+	// Grantees are used by Create and Replace only; BigQuery does not report them
+	// when listing policies.
+	Grantees []string
+
+	// ETag is the ETag of the policy. This field is read-only.
+	ETag string
+
+	CreationTime     time.Time // When the policy was created. This field is read-only.
+	LastModifiedTime time.Time // When the policy was last modified. This field is read-only.
+}
+
+// RowAccessPolicy creates a handle to a row access policy of the table.
+func (t *Table) RowAccessPolicy(policyID string) *RowAccessPolicy {
+	return &RowAccessPolicy{
+		ProjectID: t.ProjectID,
+		DatasetID: t.DatasetID,
+		TableID:   t.TableID,
+		PolicyID:  policyID,
+		c:         t.c,
+	}
+}
+
+// Create creates the row access policy. It is an error if a policy with the same
+// ID already exists on the table.
+func (p *RowAccessPolicy) Create(ctx context.Context, md *RowAccessPolicyMetadata) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.RowAccessPolicy.Create")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	stmt, err := p.createStatement(md, false)
+	if err != nil {
+		return err
+	}
+	return p.c.Query(stmt).exec(ctx)
+}
+
+// Replace creates the row access policy, or replaces the filter and grantees of
+// the policy if it exists.
+func (p *RowAccessPolicy) Replace(ctx context.Context, md *RowAccessPolicyMetadata) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.RowAccessPolicy.Replace")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	stmt, err := p.createStatement(md, true)
+	if err != nil {
+		return err
+	}
+	return p.c.Query(stmt).exec(ctx)
+}
+
+// Delete deletes the row access policy.
+func (p *RowAccessPolicy) Delete(ctx context.Context) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.RowAccessPolicy.Delete")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return p.c.Query(fmt.Sprintf("DROP ROW ACCESS POLICY %s ON %s", quoteIdentifier(p.PolicyID), p.tableIdentifier())).exec(ctx)
+}
+
+// DeleteRowAccessPolicies deletes all row access policies of the table, which
+// makes all of its rows readable again to principals with access to the table.
+func (t *Table) DeleteRowAccessPolicies(ctx context.Context) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.DeleteRowAccessPolicies")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return t.c.Query("DROP ALL ROW ACCESS POLICIES ON " + t.RowAccessPolicy("").tableIdentifier()).exec(ctx)
+}
+
+func (p *RowAccessPolicy) createStatement(md *RowAccessPolicyMetadata, replace bool) (string, error) {
+	if md == nil || md.FilterPredicate == "" {
+		return "", errors.New("bigquery: row access policy needs a FilterPredicate")
+	}
+	var sb strings.Builder
+	sb.WriteString("CREATE ")
+	if replace {
+		sb.WriteString("OR REPLACE ")
+	}
+	fmt.Fprintf(&sb, "ROW ACCESS POLICY %s ON %s", quoteIdentifier(p.PolicyID), p.tableIdentifier())
+	if len(md.Grantees) > 0 {
+		grantees := make([]string, len(md.Grantees))
+		for i, g := range md.Grantees {
+			grantees[i] = quoteString(g)
+		}
+		fmt.Fprintf(&sb, " GRANT TO (%s)", strings.Join(grantees, ", "))
+	}
+	fmt.Fprintf(&sb, " FILTER USING (%s)", md.FilterPredicate)
+	return sb.String(), nil
+}
+
+func (p *RowAccessPolicy) tableIdentifier() string {
+	return quoteIdentifier(fmt.Sprintf("%s.%s.%s", p.ProjectID, p.DatasetID, p.TableID))
+}
+
+// quoteIdentifier quotes s as a Standard SQL identifier.
+func quoteIdentifier(s string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(s) + "`"
+}
+
+// quoteString quotes s as a Standard SQL string literal.
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// RowAccessPolicies returns an iterator over the row access policies of the
+// table.
+func (t *Table) RowAccessPolicies(ctx context.Context) *RowAccessPolicyIterator {
+	it := &RowAccessPolicyIterator{
+		ctx:   ctx,
+		table: t,
+	}
+	it.pageInfo, it.nextFunc = iterator.NewPageInfo(
+		it.fetch,
+		func() int { return len(it.policies) },
+		func() interface{} { b := it.policies; it.policies = nil; return b })
+	return it
+}
+
+// A RowAccessPolicyIterator is an iterator over the metadata of row access
+// policies.
+type RowAccessPolicyIterator struct {
+	ctx      context.Context
+	table    *Table
+	policies []*RowAccessPolicyMetadata
+	pageInfo *iterator.PageInfo
+	nextFunc func() error
+}
+
+// Next returns the next result. Its second return value is Done if there are
+// no more results. Once Next returns Done, all subsequent calls will return
+// Done.
+func (it *RowAccessPolicyIterator) Next() (*RowAccessPolicyMetadata, error) {
+	if err := it.nextFunc(); err != nil {
+		return nil, err
+	}
+	p := it.policies[0]
+	it.policies = it.policies[1:]
+	return p, nil
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+func (it *RowAccessPolicyIterator) PageInfo() *iterator.PageInfo { return it.pageInfo }
+
+// listRowAccessPolicies exists to aid testing.
+var listRowAccessPolicies = func(it *RowAccessPolicyIterator, pageSize int, pageToken string) (*bq.ListRowAccessPoliciesResponse, error) {
+	call := it.table.c.bqs.RowAccessPolicies.List(it.table.ProjectID, it.table.DatasetID, it.table.TableID).
+		PageToken(pageToken).
+		Context(it.ctx)
+	setClientHeader(call.Header())
+	if pageSize > 0 {
+		call.PageSize(int64(pageSize))
+	}
+	var res *bq.ListRowAccessPoliciesResponse
+	err := runWithRetry(it.ctx, func() (err error) {
+		res, err = call.Do()
+		return err
+	})
+	return res, err
+}
+
+func (it *RowAccessPolicyIterator) fetch(pageSize int, pageToken string) (string, error) {
+	res, err := listRowAccessPolicies(it, pageSize, pageToken)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range res.RowAccessPolicies {
+		md, err := bqToRowAccessPolicyMetadata(p)
+		if err != nil {
+			return "", err
+		}
+		it.policies = append(it.policies, md)
+	}
+	return res.NextPageToken, nil
+}
+
+func bqToRowAccessPolicyMetadata(p *bq.RowAccessPolicy) (*RowAccessPolicyMetadata, error) {
+	md := &RowAccessPolicyMetadata{
+		FilterPredicate: p.FilterPredicate,
+		ETag:            p.Etag,
+	}
+	if p.RowAccessPolicyReference != nil {
+		md.PolicyID = p.RowAccessPolicyReference.PolicyId
+	}
+	var err error
+	if p.CreationTime != "" {
+		if md.CreationTime, err = time.Parse(time.RFC3339Nano, p.CreationTime); err != nil {
+			return nil, fmt.Errorf("bigquery: invalid row access policy creation time %q: %v", p.CreationTime, err)
+		}
+	}
+	if p.LastModifiedTime != "" {
+		if md.LastModifiedTime, err = time.Parse(time.RFC3339Nano, p.LastModifiedTime); err != nil {
+			return nil, fmt.Errorf("bigquery: invalid row access policy last modified time %q: %v", p.LastModifiedTime, err)
+		}
+	}
+	return md, nil
+}
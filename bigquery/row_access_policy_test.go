@@ -0,0 +1,143 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	bq "google.golang.org/api/bigquery/v2"
+	itest "google.golang.org/api/iterator/testing"
+)
+
+func TestRowAccessPolicyCreateStatement(t *testing.T) {
+	c := &Client{projectID: "p"}
+	p := c.Dataset("d").Table("t").RowAccessPolicy("us_only")
+	for _, test := range []struct {
+		md      *RowAccessPolicyMetadata
+		replace bool
+		want    string
+	}{
+		{
+			md:   &RowAccessPolicyMetadata{FilterPredicate: "TRUE"},
+			want: "CREATE ROW ACCESS POLICY `us_only` ON `p.d.t` FILTER USING (TRUE)",
+		},
+		{
+			md: &RowAccessPolicyMetadata{
+				FilterPredicate: `region = "US"`,
+				Grantees:        []string{"user:a@example.com", `group:"odd"\name`},
+			},
+			replace: true,
+			want:    "CREATE OR REPLACE ROW ACCESS POLICY `us_only` ON `p.d.t` GRANT TO (\"user:a@example.com\", \"group:\\\"odd\\\"\\\\name\") FILTER USING (region = \"US\")",
+		},
+	} {
+		got, err := p.createStatement(test.md, test.replace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("got\n%s\nwant\n%s", got, test.want)
+		}
+	}
+
+	for _, md := range []*RowAccessPolicyMetadata{nil, {Grantees: []string{"domain:example.com"}}} {
+		if _, err := p.createStatement(md, false); err == nil {
+			t.Errorf("%+v: got nil error, want non-nil", md)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got, want := quoteIdentifier("a`b\\c"), "`a\\`b\\\\c`"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// listRowAccessPoliciesStub services list requests by returning data from an in-memory list of values.
+type listRowAccessPoliciesStub struct {
+	policies []*bq.RowAccessPolicy
+}
+
+func (s *listRowAccessPoliciesStub) listRowAccessPolicies(it *RowAccessPolicyIterator, pageSize int, pageToken string) (*bq.ListRowAccessPoliciesResponse, error) {
+	const maxPageSize = 2
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	start := 0
+	if pageToken != "" {
+		var err error
+		start, err = strconv.Atoi(pageToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+	end := start + pageSize
+	if end > len(s.policies) {
+		end = len(s.policies)
+	}
+	nextPageToken := ""
+	if end < len(s.policies) {
+		nextPageToken = strconv.Itoa(end)
+	}
+	return &bq.ListRowAccessPoliciesResponse{
+		RowAccessPolicies: s.policies[start:end],
+		NextPageToken:     nextPageToken,
+	}, nil
+}
+
+func TestRowAccessPolicies(t *testing.T) {
+	c := &Client{projectID: "p1"}
+	ref := func(id string) *bq.RowAccessPolicyReference {
+		return &bq.RowAccessPolicyReference{ProjectId: "p1", DatasetId: "d1", TableId: "t1", PolicyId: id}
+	}
+	inPolicies := []*bq.RowAccessPolicy{
+		{
+			RowAccessPolicyReference: ref("r1"),
+			FilterPredicate:          "TRUE",
+			Etag:                     "e1",
+			CreationTime:             "2022-01-02T03:04:05.678Z",
+			LastModifiedTime:         "2022-02-03T04:05:06Z",
+		},
+		{RowAccessPolicyReference: ref("r2"), FilterPredicate: "x > 1"},
+		{RowAccessPolicyReference: ref("r3"), FilterPredicate: "y = 'a'"},
+	}
+	outPolicies := []*RowAccessPolicyMetadata{
+		{
+			PolicyID:         "r1",
+			FilterPredicate:  "TRUE",
+			ETag:             "e1",
+			CreationTime:     time.Date(2022, 1, 2, 3, 4, 5, 678e6, time.UTC),
+			LastModifiedTime: time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC),
+		},
+		{PolicyID: "r2", FilterPredicate: "x > 1"},
+		{PolicyID: "r3", FilterPredicate: "y = 'a'"},
+	}
+
+	lps := &listRowAccessPoliciesStub{
+		policies: inPolicies,
+	}
+	old := listRowAccessPolicies
+	listRowAccessPolicies = lps.listRowAccessPolicies // cannot use t.Parallel with this test
+	defer func() { listRowAccessPolicies = old }()
+
+	msg, ok := itest.TestIterator(outPolicies,
+		func() interface{} { return c.Dataset("d1").Table("t1").RowAccessPolicies(context.Background()) },
+		func(it interface{}) (interface{}, error) { return it.(*RowAccessPolicyIterator).Next() })
+	if !ok {
+		t.Error(msg)
+	}
+}
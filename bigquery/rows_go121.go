@@ -0,0 +1,133 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package bigquery
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/api/iterator"
+)
+
+// Rows is a type-safe iterator over the rows of a RowIterator, which decodes
+// each row into a value of type T.
+//
+// T must be a struct type or a pointer to a struct type. Columns are matched
+// with the fields of the struct as described for RowIterator.Next, including
+// nested and repeated fields. The mapping from columns to fields is computed
+// once, from the schema of the first page, and reused for every row.
+type Rows[T any] struct {
+	it  *RowIterator
+	err error // sticky error
+
+	ptr      bool // whether T is a pointer to a struct
+	compiled bool
+	ops      []structLoaderOp
+}
+
+// Iterate returns an iterator that decodes the rows of it into values of type
+// T. Its arguments match the results of Query.Read and Job.Read, so the calls
+// can be combined:
+//
+//	rows := bigquery.Iterate[Item](q.Read(ctx))
+//
+// If err is non-nil, Next returns it.
+func Iterate[T any](it *RowIterator, err error) *Rows[T] {
+	r := &Rows[T]{it: it, err: err}
+	if err == nil && it == nil {
+		r.err = errors.New("bigquery: Iterate needs a non-nil RowIterator")
+	}
+	if r.err != nil {
+		return r
+	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() == reflect.Ptr {
+		r.ptr = true
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		r.err = fmt.Errorf("bigquery: cannot iterate over rows of %s (need a struct or pointer to struct)", reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return r
+}
+
+// Next returns the next row. Its second return value is iterator.Done if there
+// are no more results. Once Next returns iterator.Done, all subsequent calls
+// will return iterator.Done.
+//
+// As with RowIterator.Next, it is an error to read a NULL value into a field
+// that cannot hold it.
+func (r *Rows[T]) Next() (T, error) {
+	var row T
+	if r.err != nil {
+		return row, r.err
+	}
+	if err := r.it.nextFunc(); err != nil {
+		return row, err
+	}
+	values := r.it.rows[0]
+	r.it.rows = r.it.rows[1:]
+	r.it.index++
+
+	v := reflect.ValueOf(&row).Elem()
+	if r.ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	if !r.compiled {
+		ops, err := compileToOps(v.Type(), r.it.Schema)
+		if err != nil {
+			r.err = err
+			return row, err
+		}
+		r.ops = ops
+		r.compiled = true
+	}
+	if err := runOps(r.ops, v, values); err != nil {
+		var zero T
+		return zero, err
+	}
+	return row, nil
+}
+
+// Schema returns the schema of the rows. It is available after the first call
+// to Next.
+func (r *Rows[T]) Schema() Schema {
+	if r.it == nil {
+		return nil
+	}
+	return r.it.Schema
+}
+
+// TotalRows returns the total number of rows in the result. It is available
+// after the first call to Next.
+func (r *Rows[T]) TotalRows() uint64 {
+	if r.it == nil {
+		return 0
+	}
+	return r.it.TotalRows
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+func (r *Rows[T]) PageInfo() *iterator.PageInfo {
+	if r.it == nil {
+		return nil
+	}
+	return r.it.PageInfo()
+}
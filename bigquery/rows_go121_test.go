@@ -0,0 +1,185 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+	"google.golang.org/api/iterator"
+)
+
+type typedRowsItem struct {
+	Name   string
+	Nums   []int
+	Nested *typedRowsNested
+	Tags   []typedRowsNested
+}
+
+type typedRowsNested struct {
+	A int
+}
+
+func typedRowsIterator() *RowIterator {
+	schema := Schema{
+		{Name: "name", Type: StringFieldType},
+		{Name: "nums", Type: IntegerFieldType, Repeated: true},
+		{Name: "nested", Type: RecordFieldType, Schema: Schema{{Name: "a", Type: IntegerFieldType}}},
+		{Name: "tags", Type: RecordFieldType, Repeated: true, Schema: Schema{{Name: "a", Type: IntegerFieldType}}},
+		{Name: "extra", Type: StringFieldType},
+	}
+	pf := &pageFetcherStub{
+		fetchResponses: map[string]fetchResponse{
+			"": {
+				result: &fetchPageResult{
+					pageToken: "a",
+					rows: [][]Value{
+						{"x", []Value{int64(1), int64(2)}, []Value{int64(3)}, []Value{[]Value{int64(4)}}, "e"},
+					},
+					schema:    schema,
+					totalRows: 2,
+				},
+			},
+			"a": {
+				result: &fetchPageResult{
+					rows: [][]Value{
+						{"y", []Value{}, nil, []Value{}, nil},
+					},
+					schema:    schema,
+					totalRows: 2,
+				},
+			},
+		},
+	}
+	return newRowIterator(context.Background(), nil, pf.fetchPage)
+}
+
+func TestRowsIterate(t *testing.T) {
+	want := []typedRowsItem{
+		{Name: "x", Nums: []int{1, 2}, Nested: &typedRowsNested{A: 3}, Tags: []typedRowsNested{{A: 4}}},
+		{Name: "y"},
+	}
+
+	rows := Iterate[typedRowsItem](typedRowsIterator(), nil)
+	var got []typedRowsItem
+	for {
+		row, err := rows.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+	if got, want := rows.TotalRows(), uint64(2); got != want {
+		t.Errorf("TotalRows: got %d, want %d", got, want)
+	}
+	if got, want := len(rows.Schema()), 5; got != want {
+		t.Errorf("got %d schema fields, want %d", got, want)
+	}
+	if _, err := rows.Next(); err != iterator.Done {
+		t.Errorf("after Done: got %v, want iterator.Done", err)
+	}
+
+	ptrRows := Iterate[*typedRowsItem](typedRowsIterator(), nil)
+	for i := range want {
+		row, err := ptrRows.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := testutil.Diff(row, &want[i]); diff != "" {
+			t.Errorf("row %d: got=-, want=+:\n%s", i, diff)
+		}
+	}
+}
+
+func TestRowsIterateErrors(t *testing.T) {
+	readErr := errors.New("read failed")
+	if _, err := Iterate[typedRowsItem](nil, readErr).Next(); err != readErr {
+		t.Errorf("got %v, want %v", err, readErr)
+	}
+	if _, err := Iterate[typedRowsItem](nil, nil).Next(); err == nil {
+		t.Error("nil iterator: got nil error, want non-nil")
+	}
+	if _, err := Iterate[[]Value](typedRowsIterator(), nil).Next(); err == nil {
+		t.Error("non-struct type: got nil error, want non-nil")
+	}
+
+	// A field of the wrong type is reported by the first call to Next.
+	type badItem struct {
+		Name int
+	}
+	rows := Iterate[badItem](typedRowsIterator(), nil)
+	if _, err := rows.Next(); err == nil {
+		t.Error("bad field type: got nil error, want non-nil")
+	}
+	if _, err := rows.Next(); err == nil {
+		t.Error("bad field type, second call: got nil error, want non-nil")
+	}
+
+	// NULLs cannot be read into non-nullable fields.
+	type nullItem struct {
+		Extra string
+	}
+	rows2 := Iterate[nullItem](typedRowsIterator(), nil)
+	if _, err := rows2.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rows2.Next(); err == nil {
+		t.Error("NULL value: got nil error, want non-nil")
+	}
+}
+
+func TestRowsCheckpoint(t *testing.T) {
+	c := &Client{projectID: "p"}
+	job := &Job{c: c, projectID: "p", jobID: "j"}
+	it := newRowIterator(context.Background(), &rowSource{j: job}, indexedPageFetcher)
+	rows := Iterate[struct{ N int64 }](it, nil)
+	for i := int64(0); i < 4; i++ {
+		row, err := rows.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.N != i {
+			t.Fatalf("got row %d, want %d", row.N, i)
+		}
+	}
+
+	// The checkpoint of the underlying RowIterator counts the rows read
+	// through Rows.
+	cp, err := it.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.StartIndex != 4 {
+		t.Errorf("got StartIndex %d, want 4", cp.StartIndex)
+	}
+	it2, err := c.resumeRowIterator(context.Background(), cp, indexedPageFetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := testutil.Diff(readNumbers(t, it2, -1), []int64{4, 5, 6, 7, 8, 9}); diff != "" {
+		t.Errorf("resumed rows: got=-, want=+:\n%s", diff)
+	}
+}
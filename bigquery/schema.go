@@ -28,19 +28,15 @@ import (
 type Schema []*FieldSchema
 
 // Relax returns a version of the schema where no fields are marked
-// as Required.
+// as Required. Other properties of the fields, such as their policy tags,
+// are kept.
 func (s Schema) Relax() Schema {
 	var out Schema
 	for _, v := range s {
-		relaxed := &FieldSchema{
-			Name:        v.Name,
-			Description: v.Description,
-			Repeated:    v.Repeated,
-			Required:    false,
-			Type:        v.Type,
-			Schema:      v.Schema.Relax(),
-		}
-		out = append(out, relaxed)
+		relaxed := *v
+		relaxed.Required = false
+		relaxed.Schema = v.Schema.Relax()
+		out = append(out, &relaxed)
 	}
 	return out
 }
@@ -133,6 +129,10 @@ func (fs *FieldSchema) toBQ() *bq.TableFieldSchema {
 
 // PolicyTagList represents the annotations on a schema column for enforcing column-level security.
 // For more information, see https://cloud.google.com/bigquery/docs/column-level-security-intro
+//
+// An empty PolicyTagList removes the policy tags of a column when the schema
+// is updated. See Schema.WithPolicyTags and Schema.PreservePolicyTags for
+// changing the policy tags of a schema without losing those of other columns.
 type PolicyTagList struct {
 	Names []string
 }
@@ -141,9 +141,14 @@ func (ptl *PolicyTagList) toBQ() *bq.TableFieldSchemaPolicyTags {
 	if ptl == nil {
 		return nil
 	}
-	return &bq.TableFieldSchemaPolicyTags{
+	pt := &bq.TableFieldSchemaPolicyTags{
 		Names: ptl.Names,
 	}
+	if len(ptl.Names) == 0 {
+		// Send an empty list, which removes the policy tags of the column.
+		pt.ForceSendFields = []string{"Names"}
+	}
+	return pt
 }
 
 func bqToPolicyTagList(pt *bq.TableFieldSchemaPolicyTags) *PolicyTagList {
@@ -226,6 +231,12 @@ const (
 	// BigNumericFieldType is a numeric field type that supports values of larger precision
 	// and scale than the NumericFieldType.
 	BigNumericFieldType FieldType = "BIGNUMERIC"
+	// JSONFieldType is a field type for JSON data. Values are read as
+	// json.RawMessage.
+	JSONFieldType FieldType = "JSON"
+	// IntervalFieldType is a field type for a duration of time that is made of
+	// year-month, day and time parts. Values are read as *IntervalValue.
+	IntervalFieldType FieldType = "INTERVAL"
 )
 
 var (
@@ -244,6 +255,8 @@ var (
 		NumericFieldType:    true,
 		GeographyFieldType:  true,
 		BigNumericFieldType: true,
+		JSONFieldType:       true,
+		IntervalFieldType:   true,
 	}
 	// The API will accept alias names for the types based on the Standard SQL type names.
 	fieldAliases = map[FieldType]FieldType{
@@ -256,7 +269,14 @@ var (
 	}
 )
 
-var typeOfByteSlice = reflect.TypeOf([]byte{})
+var (
+	typeOfByteSlice    = reflect.TypeOf([]byte{})
+	typeOfRawMessage   = reflect.TypeOf(json.RawMessage{})
+	typeOfInterval     = reflect.TypeOf(IntervalValue{})
+	typeOfIntervalPtr  = reflect.TypeOf(&IntervalValue{})
+	typeOfGeography    = reflect.TypeOf(Geography{})
+	typeOfGeographyPtr = reflect.TypeOf(&Geography{})
+)
 
 // InferSchema tries to derive a BigQuery schema from the supplied struct value.
 // Each exported struct field is mapped to a field in the schema.
@@ -275,6 +295,9 @@ var typeOfByteSlice = reflect.TypeOf([]byte{})
 //   TIME        civil.Time
 //   DATETIME    civil.DateTime
 //   NUMERIC     *big.Rat
+//   JSON        json.RawMessage
+//   INTERVAL    IntervalValue, *IntervalValue
+//   GEOGRAPHY   Geography, *Geography
 //
 // The big.Rat type supports numbers of arbitrary size and precision. Values
 // will be rounded to 9 digits after the decimal point before being transmitted
@@ -284,9 +307,6 @@ var typeOfByteSlice = reflect.TypeOf([]byte{})
 // A Go slice or array type is inferred to be a BigQuery repeated field of the
 // element type. The element type must be one of the above listed types.
 //
-// Due to lack of unique native Go type for GEOGRAPHY, there is no schema
-// inference to GEOGRAPHY at this time.
-//
 // Nullable fields are inferred from the NullXXX types, declared in this package:
 //
 //   STRING      NullString
@@ -301,6 +321,9 @@ var typeOfByteSlice = reflect.TypeOf([]byte{})
 //
 // For a nullable BYTES field, use the type []byte and tag the field "nullable" (see below).
 // For a nullable NUMERIC field, use the type *big.Rat and tag the field "nullable".
+// For a nullable JSON field, use the type json.RawMessage and tag the field "nullable".
+// For a nullable INTERVAL field, use the type *IntervalValue and tag the field "nullable".
+// For a nullable GEOGRAPHY field, use NullGeography, or the type *Geography and tag the field "nullable".
 //
 // A struct field that is of struct type is inferred to be a required field of type
 // RECORD with a schema inferred recursively. For backwards compatibility, a field of
@@ -321,9 +344,13 @@ var typeOfByteSlice = reflect.TypeOf([]byte{})
 //     bigquery:"-"
 // omits the field from the inferred schema.
 // The "nullable" option marks the field as nullable (not required). It is only
-// needed for []byte, *big.Rat and pointer-to-struct fields, and cannot appear on other
-// fields. In this example, the Go name of the field is retained:
+// needed for []byte, *big.Rat, json.RawMessage, *IntervalValue and pointer-to-struct
+// fields, and cannot appear on other fields. In this example, the Go name of the field
+// is retained:
 //     bigquery:",nullable"
+// The "json" option infers a JSON field for a string or []string field, which
+// holds JSON-encoded data:
+//     bigquery:"payload,json"
 func InferSchema(st interface{}) (Schema, error) {
 	return inferSchemaReflectCached(reflect.TypeOf(st))
 }
@@ -376,14 +403,38 @@ func inferStruct(t reflect.Type) (Schema, error) {
 }
 
 // inferFieldSchema infers the FieldSchema for a Go type
-func inferFieldSchema(fieldName string, rt reflect.Type, nullable bool) (*FieldSchema, error) {
-	// Only []byte and struct pointers can be tagged nullable.
-	if nullable && !(rt == typeOfByteSlice || rt.Kind() == reflect.Ptr && rt.Elem().Kind() == reflect.Struct) {
+func inferFieldSchema(fieldName string, rt reflect.Type, nullable, isJSON bool) (*FieldSchema, error) {
+	// Only []byte, json.RawMessage and struct pointers can be tagged nullable.
+	if nullable && !(rt == typeOfByteSlice || rt == typeOfRawMessage || rt.Kind() == reflect.Ptr && rt.Elem().Kind() == reflect.Struct) {
 		return nil, badNullableError{fieldName, rt}
 	}
+	if isJSON {
+		// Only strings and slices of strings can be tagged json.
+		if rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+			rt = rt.Elem()
+			if rt.Kind() != reflect.String {
+				return nil, badJSONError{fieldName, rt}
+			}
+			return &FieldSchema{Repeated: true, Type: JSONFieldType}, nil
+		}
+		if rt.Kind() != reflect.String {
+			return nil, badJSONError{fieldName, rt}
+		}
+		return &FieldSchema{Required: true, Type: JSONFieldType}, nil
+	}
 	switch rt {
 	case typeOfByteSlice:
 		return &FieldSchema{Required: !nullable, Type: BytesFieldType}, nil
+	case typeOfRawMessage:
+		return &FieldSchema{Required: !nullable, Type: JSONFieldType}, nil
+	case typeOfInterval:
+		return &FieldSchema{Required: true, Type: IntervalFieldType}, nil
+	case typeOfIntervalPtr:
+		return &FieldSchema{Required: !nullable, Type: IntervalFieldType}, nil
+	case typeOfGeography:
+		return &FieldSchema{Required: true, Type: GeographyFieldType}, nil
+	case typeOfGeographyPtr:
+		return &FieldSchema{Required: !nullable, Type: GeographyFieldType}, nil
 	case typeOfGoTime:
 		return &FieldSchema{Required: true, Type: TimestampFieldType}, nil
 	case typeOfDate:
@@ -408,7 +459,7 @@ func inferFieldSchema(fieldName string, rt reflect.Type, nullable bool) (*FieldS
 	switch rt.Kind() {
 	case reflect.Slice, reflect.Array:
 		et := rt.Elem()
-		if et != typeOfByteSlice && (et.Kind() == reflect.Slice || et.Kind() == reflect.Array) {
+		if et != typeOfByteSlice && et != typeOfRawMessage && (et.Kind() == reflect.Slice || et.Kind() == reflect.Array) {
 			// Multi dimensional slices/arrays are not supported by BigQuery
 			return nil, unsupportedFieldTypeError{fieldName, rt}
 		}
@@ -416,7 +467,7 @@ func inferFieldSchema(fieldName string, rt reflect.Type, nullable bool) (*FieldS
 			// Repeated nullable types are not supported by BigQuery.
 			return nil, unsupportedFieldTypeError{fieldName, rt}
 		}
-		f, err := inferFieldSchema(fieldName, et, false)
+		f, err := inferFieldSchema(fieldName, et, false, false)
 		if err != nil {
 			return nil, err
 		}
@@ -453,14 +504,16 @@ func inferFields(rt reflect.Type) (Schema, error) {
 		return nil, err
 	}
 	for _, field := range fields {
-		var nullable bool
+		var nullable, isJSON bool
 		for _, opt := range field.ParsedTag.([]string) {
-			if opt == nullableTagOption {
+			switch opt {
+			case nullableTagOption:
 				nullable = true
-				break
+			case jsonTagOption:
+				isJSON = true
 			}
 		}
-		f, err := inferFieldSchema(field.Name, field.Type, nullable)
+		f, err := inferFieldSchema(field.Name, field.Type, nullable, isJSON)
 		if err != nil {
 			return nil, err
 		}
@@ -623,7 +676,16 @@ type badNullableError struct {
 }
 
 func (e badNullableError) Error() string {
-	return fmt.Sprintf(`bigquery: field %q of type %s: use "nullable" only for []byte and struct pointers; for all other types, use a NullXXX type`, e.name, e.typ)
+	return fmt.Sprintf(`bigquery: field %q of type %s: use "nullable" only for []byte, json.RawMessage and struct pointers; for all other types, use a NullXXX type`, e.name, e.typ)
+}
+
+type badJSONError struct {
+	name string
+	typ  reflect.Type
+}
+
+func (e badJSONError) Error() string {
+	return fmt.Sprintf(`bigquery: field %q of type %s: use "json" only for string and []string fields; for JSON data in other forms, use json.RawMessage`, e.name, e.typ)
 }
 
 type unsupportedFieldTypeError struct {
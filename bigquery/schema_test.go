@@ -15,6 +15,7 @@
 package bigquery
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"reflect"
@@ -414,6 +415,24 @@ type allNumeric struct {
 	Numeric *big.Rat
 }
 
+type allJSONAndInterval struct {
+	JSON             json.RawMessage
+	NullableJSON     json.RawMessage `bigquery:",nullable"`
+	JSONString       string          `bigquery:",json"`
+	JSONStrings      []string        `bigquery:",json"`
+	Interval         IntervalValue
+	IntervalPtr      *IntervalValue
+	NullableInterval *IntervalValue `bigquery:",nullable"`
+	Intervals        []IntervalValue
+}
+
+type allGeography struct {
+	Geography         Geography
+	GeographyPtr      *Geography
+	NullableGeography *Geography `bigquery:",nullable"`
+	Geographies       []Geography
+}
+
 func reqField(name, typ string) *FieldSchema {
 	return &FieldSchema{
 		Name:     name,
@@ -494,6 +513,28 @@ func TestSimpleInference(t *testing.T) {
 				reqField("ByteSlice", "BYTES"),
 			},
 		},
+		{
+			in: allJSONAndInterval{},
+			want: Schema{
+				reqField("JSON", "JSON"),
+				optField("NullableJSON", "JSON"),
+				reqField("JSONString", "JSON"),
+				repField("JSONStrings", "JSON"),
+				reqField("Interval", "INTERVAL"),
+				reqField("IntervalPtr", "INTERVAL"),
+				optField("NullableInterval", "INTERVAL"),
+				repField("Intervals", "INTERVAL"),
+			},
+		},
+		{
+			in: allGeography{},
+			want: Schema{
+				reqField("Geography", "GEOGRAPHY"),
+				reqField("GeographyPtr", "GEOGRAPHY"),
+				optField("NullableGeography", "GEOGRAPHY"),
+				repField("Geographies", "GEOGRAPHY"),
+			},
+		},
 	}
 	for _, tc := range testCases {
 		got, err := InferSchema(tc.in)
@@ -993,6 +1034,24 @@ func TestSchemaErrors(t *testing.T) {
 			}{},
 			want: badNullableError{},
 		},
+		{
+			in: struct {
+				X IntervalValue `bigquery:",nullable"`
+			}{},
+			want: badNullableError{},
+		},
+		{
+			in: struct {
+				X int `bigquery:",json"`
+			}{},
+			want: badJSONError{},
+		},
+		{
+			in: struct {
+				X []byte `bigquery:",json"`
+			}{},
+			want: badJSONError{},
+		},
 		{
 			in:   struct{ X *[]byte }{},
 			want: unsupportedFieldTypeError{},
@@ -0,0 +1,94 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+
+	"google.golang.org/api/iterator"
+)
+
+// A ScriptStatement describes a statement of a multi-statement query (a
+// script), which BigQuery runs as a child job of the script's job.
+type ScriptStatement struct {
+	// Job is the child job that ran the statement. Use its Read method to
+	// read the results of a SELECT statement.
+	Job *Job
+
+	// SQL is the text of the statement.
+	SQL string
+
+	// StackFrames locate the statement in the script, starting with the
+	// innermost frame. They have more than one frame if the statement is in a
+	// procedure called by the script.
+	StackFrames []*ScriptStackFrame
+
+	// EvaluationKind is "STATEMENT" if the job ran a statement, or
+	// "EXPRESSION" if it evaluated an expression, such as a condition.
+	EvaluationKind string
+
+	// Statistics are the statistics of the child job, such as the bytes it
+	// processed and its slot time. Details is a *QueryStatistics.
+	Statistics *JobStatistics
+
+	// Err is the error of the statement, or nil if it succeeded or has not
+	// finished.
+	Err error
+}
+
+// ScriptStatements returns an iterator over the statements of a script job,
+// which lets you find the statement of a script that failed or was expensive.
+// BigQuery lists the statements in reverse order of execution, so the first
+// statement is the last that ran.
+func (j *Job) ScriptStatements(ctx context.Context) *ScriptStatementIterator {
+	it := j.Children(ctx)
+	it.ProjectID = j.projectID
+	return &ScriptStatementIterator{it: it}
+}
+
+// A ScriptStatementIterator is an iterator over the statements of a script.
+type ScriptStatementIterator struct {
+	it *JobIterator
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+func (it *ScriptStatementIterator) PageInfo() *iterator.PageInfo { return it.it.PageInfo() }
+
+// Next returns the next ScriptStatement. Its second return value is
+// iterator.Done if there are no more results. Once Next returns Done, all
+// subsequent calls will return Done.
+func (it *ScriptStatementIterator) Next() (*ScriptStatement, error) {
+	j, err := it.it.Next()
+	if err != nil {
+		return nil, err
+	}
+	return newScriptStatement(j), nil
+}
+
+func newScriptStatement(j *Job) *ScriptStatement {
+	s := &ScriptStatement{Job: j}
+	if j.config != nil && j.config.Query != nil {
+		s.SQL = j.config.Query.Query
+	}
+	if status := j.LastStatus(); status != nil {
+		s.Err = status.Err()
+		s.Statistics = status.Statistics
+		if status.Statistics != nil && status.Statistics.ScriptStatistics != nil {
+			s.EvaluationKind = status.Statistics.ScriptStatistics.EvaluationKind
+			s.StackFrames = status.Statistics.ScriptStatistics.StackFrames
+		}
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+	bq "google.golang.org/api/bigquery/v2"
+)
+
+func TestNewScriptStatement(t *testing.T) {
+	c := &Client{projectID: "p"}
+	j, err := convertListedJob(&bq.JobListJobs{
+		JobReference:  &bq.JobReference{ProjectId: "p", JobId: "child"},
+		Configuration: &bq.JobConfiguration{Query: &bq.JobConfigurationQuery{Query: "SELECT ERROR('x')"}},
+		Status: &bq.JobStatus{
+			State:       "DONE",
+			ErrorResult: &bq.ErrorProto{Reason: "invalidQuery", Message: "x"},
+		},
+		Statistics: &bq.JobStatistics{
+			ParentJobId: "parent",
+			Query:       &bq.JobStatistics2{TotalSlotMs: 10},
+			ScriptStatistics: &bq.ScriptStatistics{
+				EvaluationKind: "STATEMENT",
+				StackFrames:    []*bq.ScriptStackFrame{{StartLine: 2, EndLine: 2, Text: "SELECT ERROR('x')"}},
+			},
+		},
+	}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newScriptStatement(j)
+	if s.Job != j || s.SQL != "SELECT ERROR('x')" || s.EvaluationKind != "STATEMENT" {
+		t.Errorf("got %+v", s)
+	}
+	if e, ok := s.Err.(*Error); !ok || e.Reason != "invalidQuery" {
+		t.Errorf("got error %v, want invalidQuery", s.Err)
+	}
+	want := []*ScriptStackFrame{{StartLine: 2, EndLine: 2, Text: "SELECT ERROR('x')"}}
+	if diff := testutil.Diff(s.StackFrames, want); diff != "" {
+		t.Errorf("stack frames: got=-, want=+:\n%s", diff)
+	}
+	if qs := s.Statistics.QueryStatistics(); qs == nil || qs.SlotMillis != 10 || s.Statistics.ParentJobID != "parent" {
+		t.Errorf("got statistics %+v", s.Statistics)
+	}
+
+	// A job that has not finished has no error or statistics yet.
+	s = newScriptStatement(&Job{c: c, jobID: "running"})
+	if s.Err != nil || s.Statistics != nil || s.SQL != "" {
+		t.Errorf("got %+v, want an empty statement", s)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	"google.golang.org/api/iterator"
+)
+
+// SearchIndexOptions configure a search index created with
+// Table.CreateSearchIndex.
+type SearchIndexOptions struct {
+	// Columns are the STRING, JSON, ARRAY<STRING> or STRUCT columns to index.
+	// If empty, all columns of those types are indexed.
+	Columns []string
+
+	// Analyzer is the text analyzer that splits text into tokens, such as
+	// "LOG_ANALYZER", "NO_OP_ANALYZER" or "PATTERN_ANALYZER". If empty,
+	// BigQuery uses LOG_ANALYZER.
+	Analyzer string
+}
+
+// VectorIndexOptions configure a vector index created with
+// Table.CreateVectorIndex.
+type VectorIndexOptions struct {
+	// IndexType is the algorithm of the index, such as "IVF". It is required.
+	IndexType string
+
+	// DistanceType is the distance used by vector searches of the index, such
+	// as "EUCLIDEAN", "COSINE" or "DOT_PRODUCT". If empty, BigQuery uses
+	// EUCLIDEAN.
+	DistanceType string
+
+	// NumLists is the number of lists of an IVF index. If zero, BigQuery picks
+	// a number based on the size of the table.
+	NumLists int
+}
+
+// IndexStatus describes a search or vector index of a table, as reported by
+// the INFORMATION_SCHEMA.SEARCH_INDEXES and INFORMATION_SCHEMA.VECTOR_INDEXES
+// views.
+type IndexStatus struct {
+	// Name is the name of the index.
+	Name string
+
+	// DDL is the statement that created the index.
+	DDL string
+
+	// Status is the status of the index, "ACTIVE", "PENDING DISABLEMENT",
+	// "TEMPORARILY DISABLED" or "PERMANENTLY DISABLED".
+	Status string
+
+	// CoveragePercentage is the percentage of the table's data that has been
+	// indexed.
+	CoveragePercentage int64
+
+	// UnindexedRowCount is the number of rows of the table that have not been
+	// indexed.
+	UnindexedRowCount int64
+
+	// TotalLogicalBytes is the number of billable logical bytes of the index.
+	TotalLogicalBytes int64
+
+	// DisableReason is why the index was disabled, if it was.
+	DisableReason string
+
+	CreationTime         time.Time // When the index was created.
+	LastModificationTime time.Time // When the index configuration was last modified.
+	LastRefreshTime      time.Time // When the data of the table was last indexed.
+	DisableTime          time.Time // When the index was disabled; zero if it is active.
+}
+
+// CreateSearchIndex creates a search index on the table, which speeds up
+// queries that use the SEARCH function. opts may be nil.
+func (t *Table) CreateSearchIndex(ctx context.Context, name string, opts *SearchIndexOptions) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.CreateSearchIndex")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return t.c.Query(t.createSearchIndexStatement(name, opts)).exec(ctx)
+}
+
+func (t *Table) createSearchIndexStatement(name string, opts *SearchIndexOptions) string {
+	if opts == nil {
+		opts = &SearchIndexOptions{}
+	}
+	columns := "ALL COLUMNS"
+	if len(opts.Columns) > 0 {
+		cols := make([]string, len(opts.Columns))
+		for i, c := range opts.Columns {
+			cols[i] = quoteIdentifier(c)
+		}
+		columns = strings.Join(cols, ", ")
+	}
+	stmt := fmt.Sprintf("CREATE SEARCH INDEX %s ON %s(%s)", quoteIdentifier(name), t.sqlIdentifier(), columns)
+	if opts.Analyzer != "" {
+		stmt += fmt.Sprintf(" OPTIONS(analyzer = %s)", quoteString(opts.Analyzer))
+	}
+	return stmt
+}
+
+// DropSearchIndex deletes a search index of the table.
+func (t *Table) DropSearchIndex(ctx context.Context, name string) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.DropSearchIndex")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return t.c.Query(fmt.Sprintf("DROP SEARCH INDEX %s ON %s", quoteIdentifier(name), t.sqlIdentifier())).exec(ctx)
+}
+
+// CreateVectorIndex creates a vector index on an ARRAY<FLOAT64> column of the
+// table, which speeds up queries that use the VECTOR_SEARCH function.
+func (t *Table) CreateVectorIndex(ctx context.Context, name, column string, opts *VectorIndexOptions) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.CreateVectorIndex")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	stmt, err := t.createVectorIndexStatement(name, column, opts)
+	if err != nil {
+		return err
+	}
+	return t.c.Query(stmt).exec(ctx)
+}
+
+func (t *Table) createVectorIndexStatement(name, column string, opts *VectorIndexOptions) (string, error) {
+	if opts == nil || opts.IndexType == "" {
+		return "", errors.New("bigquery: vector index needs an IndexType")
+	}
+	o := []string{"index_type = " + quoteString(opts.IndexType)}
+	if opts.DistanceType != "" {
+		o = append(o, "distance_type = "+quoteString(opts.DistanceType))
+	}
+	if opts.NumLists != 0 {
+		o = append(o, "ivf_options = "+quoteString(fmt.Sprintf(`{"num_lists": %d}`, opts.NumLists)))
+	}
+	return fmt.Sprintf("CREATE VECTOR INDEX %s ON %s(%s) OPTIONS(%s)",
+		quoteIdentifier(name), t.sqlIdentifier(), quoteIdentifier(column), strings.Join(o, ", ")), nil
+}
+
+// DropVectorIndex deletes a vector index of the table.
+func (t *Table) DropVectorIndex(ctx context.Context, name string) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.DropVectorIndex")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return t.c.Query(fmt.Sprintf("DROP VECTOR INDEX %s ON %s", quoteIdentifier(name), t.sqlIdentifier())).exec(ctx)
+}
+
+// SearchIndexes returns the status of the search indexes of the table.
+func (t *Table) SearchIndexes(ctx context.Context) (indexes []*IndexStatus, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.SearchIndexes")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return t.indexes(ctx, "SEARCH_INDEXES")
+}
+
+// VectorIndexes returns the status of the vector indexes of the table.
+func (t *Table) VectorIndexes(ctx context.Context) (indexes []*IndexStatus, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.VectorIndexes")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return t.indexes(ctx, "VECTOR_INDEXES")
+}
+
+// indexRow is a row of the INFORMATION_SCHEMA views of indexes.
+type indexRow struct {
+	IndexName            string        `bigquery:"index_name"`
+	DDL                  NullString    `bigquery:"ddl"`
+	IndexStatus          NullString    `bigquery:"index_status"`
+	CoveragePercentage   NullInt64     `bigquery:"coverage_percentage"`
+	UnindexedRowCount    NullInt64     `bigquery:"unindexed_row_count"`
+	TotalLogicalBytes    NullInt64     `bigquery:"total_logical_bytes"`
+	DisableReason        NullString    `bigquery:"disable_reason"`
+	CreationTime         NullTimestamp `bigquery:"creation_time"`
+	LastModificationTime NullTimestamp `bigquery:"last_modification_time"`
+	LastRefreshTime      NullTimestamp `bigquery:"last_refresh_time"`
+	DisableTime          NullTimestamp `bigquery:"disable_time"`
+}
+
+func (r *indexRow) toIndexStatus() *IndexStatus {
+	return &IndexStatus{
+		Name:                 r.IndexName,
+		DDL:                  r.DDL.StringVal,
+		Status:               r.IndexStatus.StringVal,
+		CoveragePercentage:   r.CoveragePercentage.Int64,
+		UnindexedRowCount:    r.UnindexedRowCount.Int64,
+		TotalLogicalBytes:    r.TotalLogicalBytes.Int64,
+		DisableReason:        r.DisableReason.StringVal,
+		CreationTime:         r.CreationTime.Timestamp,
+		LastModificationTime: r.LastModificationTime.Timestamp,
+		LastRefreshTime:      r.LastRefreshTime.Timestamp,
+		DisableTime:          r.DisableTime.Timestamp,
+	}
+}
+
+// indexesQuery returns the query that reads the rows of the table from the
+// INFORMATION_SCHEMA view.
+func (t *Table) indexesQuery(view string) *Query {
+	q := t.c.Query(fmt.Sprintf(
+		"SELECT index_name, ddl, index_status, coverage_percentage, unindexed_row_count, total_logical_bytes, "+
+			"disable_reason, creation_time, last_modification_time, last_refresh_time, disable_time "+
+			"FROM %s.INFORMATION_SCHEMA.%s WHERE table_name = @table ORDER BY index_name",
+		quoteIdentifier(t.ProjectID+"."+t.DatasetID), view))
+	q.Parameters = []QueryParameter{{Name: "table", Value: t.TableID}}
+	return q
+}
+
+func (t *Table) indexes(ctx context.Context, view string) ([]*IndexStatus, error) {
+	it, err := t.indexesQuery(view).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []*IndexStatus
+	for {
+		var r indexRow
+		err := it.Next(&r)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, r.toIndexStatus())
+	}
+	return indexes, nil
+}
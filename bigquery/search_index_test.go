@@ -0,0 +1,104 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/internal/testutil"
+)
+
+func TestIndexStatements(t *testing.T) {
+	c := &Client{projectID: "p"}
+	table := c.Dataset("d").Table("t")
+
+	for _, test := range []struct {
+		opts *SearchIndexOptions
+		want string
+	}{
+		{nil, "CREATE SEARCH INDEX `idx` ON `p.d.t`(ALL COLUMNS)"},
+		{
+			&SearchIndexOptions{Columns: []string{"a", "b"}, Analyzer: "NO_OP_ANALYZER"},
+			"CREATE SEARCH INDEX `idx` ON `p.d.t`(`a`, `b`) OPTIONS(analyzer = \"NO_OP_ANALYZER\")",
+		},
+	} {
+		if got := table.createSearchIndexStatement("idx", test.opts); got != test.want {
+			t.Errorf("%+v: got\n%s\nwant\n%s", test.opts, got, test.want)
+		}
+	}
+
+	got, err := table.createVectorIndexStatement("vidx", "embedding", &VectorIndexOptions{IndexType: "IVF", DistanceType: "COSINE", NumLists: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE VECTOR INDEX `vidx` ON `p.d.t`(`embedding`) OPTIONS(index_type = \"IVF\", distance_type = \"COSINE\", ivf_options = \"{\\\"num_lists\\\": 100}\")"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	for _, opts := range []*VectorIndexOptions{nil, {DistanceType: "COSINE"}} {
+		if _, err := table.createVectorIndexStatement("vidx", "embedding", opts); err == nil {
+			t.Errorf("%+v: got nil error, want non-nil", opts)
+		}
+	}
+
+	q := table.indexesQuery("VECTOR_INDEXES")
+	if want := "FROM `p.d`.INFORMATION_SCHEMA.VECTOR_INDEXES WHERE table_name = @table"; !strings.Contains(q.Q, want) {
+		t.Errorf("query %q does not contain %q", q.Q, want)
+	}
+	if len(q.Parameters) != 1 || q.Parameters[0].Value != "t" {
+		t.Errorf("got parameters %+v", q.Parameters)
+	}
+}
+
+func TestIndexRowToIndexStatus(t *testing.T) {
+	schema := Schema{
+		{Name: "index_name", Type: StringFieldType},
+		{Name: "ddl", Type: StringFieldType},
+		{Name: "index_status", Type: StringFieldType},
+		{Name: "coverage_percentage", Type: IntegerFieldType},
+		{Name: "unindexed_row_count", Type: IntegerFieldType},
+		{Name: "total_logical_bytes", Type: IntegerFieldType},
+		{Name: "disable_reason", Type: StringFieldType},
+		{Name: "creation_time", Type: TimestampFieldType},
+		{Name: "last_modification_time", Type: TimestampFieldType},
+		{Name: "last_refresh_time", Type: TimestampFieldType},
+		{Name: "disable_time", Type: TimestampFieldType},
+	}
+	ts := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+	row := []Value{"idx", "CREATE SEARCH INDEX ...", "ACTIVE", int64(100), int64(0), int64(42), nil, ts, ts, nil, nil}
+
+	var r indexRow
+	var sl structLoader
+	if err := sl.set(&r, schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := sl.Load(row, schema); err != nil {
+		t.Fatal(err)
+	}
+	want := &IndexStatus{
+		Name:                 "idx",
+		DDL:                  "CREATE SEARCH INDEX ...",
+		Status:               "ACTIVE",
+		CoveragePercentage:   100,
+		TotalLogicalBytes:    42,
+		CreationTime:         ts,
+		LastModificationTime: ts,
+	}
+	if diff := testutil.Diff(r.toIndexStatus(), want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
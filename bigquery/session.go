@@ -0,0 +1,130 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+// sessionIDProperty is the connection property that runs a query in a
+// session.
+const sessionIDProperty = "session_id"
+
+// A Session is a BigQuery session. Queries in a session share temporary
+// tables and variables, and multi-statement transactions can span several of
+// them. Temporary tables created with CREATE TEMP TABLE can be referred to by
+// name, or as _SESSION.name, in the later queries of the session.
+//
+// A session ends when it is aborted, or when it expires after 24 hours or
+// after being inactive for a while.
+//
+// See https://cloud.google.com/bigquery/docs/sessions-intro for more
+// information.
+type Session struct {
+	c        *Client
+	id       string
+	location string
+}
+
+// CreateSession creates a new session by running a query in it. The session
+// is in the location of the client, if set.
+func (c *Client) CreateSession(ctx context.Context) (s *Session, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Client.CreateSession")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	q := c.Query("SELECT 1")
+	q.CreateSession = true
+	job, err := q.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+	if status.Statistics == nil || status.Statistics.SessionInfo == nil || status.Statistics.SessionInfo.SessionID == "" {
+		return nil, errors.New("bigquery: query job did not create a session")
+	}
+	return c.SessionFromID(status.Statistics.SessionInfo.SessionID, job.Location()), nil
+}
+
+// SessionFromID returns a Session that refers to an existing session with
+// the given ID, in the given location.
+func (c *Client) SessionFromID(id, location string) *Session {
+	return &Session{c: c, id: id, location: location}
+}
+
+// ID returns the session's ID.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Location returns the location of the session.
+func (s *Session) Location() string {
+	return s.location
+}
+
+// Query creates a query with string q that runs in the session.
+// The returned Query may optionally be further configured before its Run
+// method is called.
+func (s *Session) Query(q string) *Query {
+	query := s.c.Query(q)
+	query.SessionID = s.id
+	query.Location = s.location
+	return query
+}
+
+// RunInTransaction runs f in a multi-statement transaction. Every query that
+// f creates with s.Query is part of the transaction. The transaction is
+// committed if f returns nil, and rolled back otherwise.
+//
+// The queries f runs must have finished before f returns; use Job.Wait or
+// Query.Read and read all rows. If a query of the transaction fails,
+// BigQuery may roll back the transaction itself.
+func (s *Session) RunInTransaction(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Session.RunInTransaction")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if err := s.exec(ctx, "BEGIN TRANSACTION"); err != nil {
+		return err
+	}
+	if err := f(ctx); err != nil {
+		// Rolling back fails if BigQuery rolled back the transaction
+		// already, so its error is not reported.
+		s.exec(ctx, "ROLLBACK TRANSACTION")
+		return err
+	}
+	return s.exec(ctx, "COMMIT TRANSACTION")
+}
+
+// Abort ends the session. Its temporary tables are deleted, and open
+// transactions are rolled back.
+func (s *Session) Abort(ctx context.Context) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Session.Abort")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	return s.exec(ctx, "CALL BQ.ABORT_SESSION()")
+}
+
+// exec runs the statement in the session and waits for it to finish.
+func (s *Session) exec(ctx context.Context, statement string) error {
+	return s.Query(statement).exec(ctx)
+}
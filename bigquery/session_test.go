@@ -0,0 +1,42 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+	bq "google.golang.org/api/bigquery/v2"
+)
+
+func TestSessionQuery(t *testing.T) {
+	c := &Client{projectID: "client-project-id", Location: "US"}
+	s := c.SessionFromID("session-id", "asia-northeast1")
+	q := s.Query("SELECT 1")
+	if q.SessionID != "session-id" || q.Location != "asia-northeast1" {
+		t.Errorf("got session ID %q and location %q, want %q and %q", q.SessionID, q.Location, "session-id", "asia-northeast1")
+	}
+	job, err := q.newJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := job.JobReference.Location, "asia-northeast1"; got != want {
+		t.Errorf("job location: got %q, want %q", got, want)
+	}
+	want := []*bq.ConnectionProperty{{Key: "session_id", Value: "session-id"}}
+	if diff := testutil.Diff(job.Configuration.Query.ConnectionProperties, want); diff != "" {
+		t.Errorf("connection properties (-got +want):\n%s", diff)
+	}
+}
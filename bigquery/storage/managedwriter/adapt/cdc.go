@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adapt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/bigquery/storage/managedwriter/internal/cdc"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// Pseudo-columns used for change data capture (CDC) writes to a table with a
+// primary key. They are not columns of the table, but each row written has
+// them to describe the change it applies.
+const (
+	// ChangeTypeColumn is the name of the pseudo-column that holds the type
+	// of change of a row, ChangeTypeUpsert or ChangeTypeDelete.
+	ChangeTypeColumn = cdc.ChangeTypeColumn
+
+	// ChangeSequenceNumberColumn is the name of the optional pseudo-column
+	// that orders changes to the same primary key. See ChangeSequenceNumber.
+	ChangeSequenceNumberColumn = "_CHANGE_SEQUENCE_NUMBER"
+)
+
+// Values of the ChangeTypeColumn pseudo-column.
+const (
+	// ChangeTypeUpsert inserts the row, or replaces the row with the same
+	// primary key.
+	ChangeTypeUpsert = "UPSERT"
+
+	// ChangeTypeDelete deletes the row with the same primary key. Only the
+	// primary key columns of the row are used.
+	ChangeTypeDelete = "DELETE"
+)
+
+// maxChangeSequenceSections is the most sections a change sequence number may
+// have.
+const maxChangeSequenceSections = 4
+
+// WithChangeDataCaptureColumns returns a copy of a table schema with the
+// ChangeTypeColumn pseudo-column added, and the ChangeSequenceNumberColumn
+// pseudo-column if withSequenceNumber is true, so that the descriptor built
+// from it by StorageSchemaToProto2Descriptor can describe CDC writes. Columns
+// the schema already has are not added again.
+//
+// CDC writes must use the default stream of a table that has a primary key.
+func WithChangeDataCaptureColumns(in *storagepb.TableSchema, withSequenceNumber bool) *storagepb.TableSchema {
+	out := &storagepb.TableSchema{}
+	if in != nil {
+		out = proto.Clone(in).(*storagepb.TableSchema)
+	}
+	cols := []string{ChangeTypeColumn}
+	if withSequenceNumber {
+		cols = append(cols, ChangeSequenceNumberColumn)
+	}
+	for _, col := range cols {
+		if hasField(out, col) {
+			continue
+		}
+		out.Fields = append(out.Fields, &storagepb.TableFieldSchema{
+			Name: col,
+			Type: storagepb.TableFieldSchema_STRING,
+			Mode: storagepb.TableFieldSchema_NULLABLE,
+		})
+	}
+	return out
+}
+
+func hasField(schema *storagepb.TableSchema, name string) bool {
+	for _, f := range schema.GetFields() {
+		if strings.EqualFold(f.GetName(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeSequenceNumber formats a value of the ChangeSequenceNumberColumn
+// pseudo-column. It has one to four sections, such as the components of a
+// log position from the source database, which BigQuery compares in order to
+// apply the changes to a primary key in sequence, whatever order they are
+// written in.
+func ChangeSequenceNumber(sections ...uint64) (string, error) {
+	if len(sections) == 0 || len(sections) > maxChangeSequenceSections {
+		return "", fmt.Errorf("a change sequence number has 1 to %d sections, not %d", maxChangeSequenceSections, len(sections))
+	}
+	parts := make([]string, len(sections))
+	for i, s := range sections {
+		parts[i] = strings.ToUpper(strconv.FormatUint(s, 16))
+	}
+	return strings.Join(parts, "/"), nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adapt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+func TestWithChangeDataCaptureColumns(t *testing.T) {
+	in := &storagepb.TableSchema{
+		Fields: []*storagepb.TableFieldSchema{
+			{Name: "id", Type: storagepb.TableFieldSchema_INT64, Mode: storagepb.TableFieldSchema_REQUIRED},
+		},
+	}
+	changeType := &storagepb.TableFieldSchema{Name: "_CHANGE_TYPE", Type: storagepb.TableFieldSchema_STRING, Mode: storagepb.TableFieldSchema_NULLABLE}
+	sequence := &storagepb.TableFieldSchema{Name: "_CHANGE_SEQUENCE_NUMBER", Type: storagepb.TableFieldSchema_STRING, Mode: storagepb.TableFieldSchema_NULLABLE}
+
+	got := WithChangeDataCaptureColumns(in, false)
+	want := &storagepb.TableSchema{Fields: []*storagepb.TableFieldSchema{in.Fields[0], changeType}}
+	if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+		t.Errorf("without sequence number: -got +want:\n%s", diff)
+	}
+	if len(in.Fields) != 1 {
+		t.Errorf("input schema was modified: %v", in)
+	}
+
+	// Adding the columns again adds only the missing sequence number.
+	got = WithChangeDataCaptureColumns(got, true)
+	want.Fields = append(want.Fields, sequence)
+	if diff := cmp.Diff(got, want, protocmp.Transform()); diff != "" {
+		t.Errorf("with sequence number: -got +want:\n%s", diff)
+	}
+
+	desc, err := StorageSchemaToProto2Descriptor(got, "root")
+	if err != nil {
+		t.Fatalf("StorageSchemaToProto2Descriptor: %v", err)
+	}
+	fields := desc.(protoreflect.MessageDescriptor).Fields()
+	for _, name := range []protoreflect.Name{"_change_type", "_change_sequence_number"} {
+		if f := fields.ByName(name); f == nil || f.Kind() != protoreflect.StringKind {
+			t.Errorf("descriptor has no string field %s", name)
+		}
+	}
+}
+
+func TestChangeSequenceNumber(t *testing.T) {
+	for _, tc := range []struct {
+		sections []uint64
+		want     string
+	}{
+		{[]uint64{0}, "0"},
+		{[]uint64{255}, "FF"},
+		{[]uint64{1, 0xabc, 0, 1<<64 - 1}, "1/ABC/0/FFFFFFFFFFFFFFFF"},
+	} {
+		got, err := ChangeSequenceNumber(tc.sections...)
+		if err != nil {
+			t.Errorf("%v: %v", tc.sections, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%v: got %q, want %q", tc.sections, got, tc.want)
+		}
+	}
+	for _, sections := range [][]uint64{nil, {1, 2, 3, 4, 5}} {
+		if _, err := ChangeSequenceNumber(sections...); err == nil {
+			t.Errorf("%v: got nil, want error", sections)
+		}
+	}
+}
@@ -21,12 +21,14 @@ import (
 	"strings"
 
 	storage "cloud.google.com/go/bigquery/storage/apiv1"
+	"cloud.google.com/go/bigquery/storage/managedwriter/internal/cdc"
 	"cloud.google.com/go/internal/detect"
 	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/option"
 	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 // DetectProjectID is a sentinel value that instructs NewClient to detect the
@@ -176,9 +178,23 @@ func (c *Client) validateOptions(ctx context.Context, ms *ManagedStream) error {
 	if ms.StreamType() == "" {
 		return fmt.Errorf("stream type wasn't specified")
 	}
+	if ms.StreamType() != DefaultStream && hasChangeTypeField(ms.schemaDescriptor) {
+		return fmt.Errorf("change data capture writes (the %s pseudo-column) are only supported on the default stream", cdc.ChangeTypeColumn)
+	}
 	return nil
 }
 
+// hasChangeTypeField reports whether the descriptor describes change data
+// capture writes.
+func hasChangeTypeField(dp *descriptorpb.DescriptorProto) bool {
+	for _, f := range dp.GetField() {
+		if strings.EqualFold(f.GetName(), cdc.ChangeTypeColumn) {
+			return true
+		}
+	}
+	return false
+}
+
 // BatchCommitWriteStreams atomically commits a group of PENDING streams that belong to the same
 // parent table.
 //
@@ -14,7 +14,13 @@
 
 package managedwriter
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
 
 func TestTableParentFromStreamName(t *testing.T) {
 	testCases := []struct {
@@ -46,3 +52,32 @@ func TestTableParentFromStreamName(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateOptionsChangeDataCapture(t *testing.T) {
+	cdc := &descriptorpb.DescriptorProto{
+		Name: proto.String("root"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()},
+			{Name: proto.String("_change_type"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+		},
+	}
+	c := &Client{}
+	for _, tc := range []struct {
+		streamType StreamType
+		wantErr    bool
+	}{
+		{DefaultStream, false},
+		{CommittedStream, true},
+		{PendingStream, true},
+	} {
+		ms := &ManagedStream{
+			streamSettings:   defaultStreamSettings(),
+			destinationTable: "projects/p/datasets/d/tables/t",
+			schemaDescriptor: cdc,
+		}
+		ms.streamSettings.streamType = tc.streamType
+		if err := c.validateOptions(context.Background(), ms); (err != nil) != tc.wantErr {
+			t.Errorf("%s: got error %v, want error %t", tc.streamType, err, tc.wantErr)
+		}
+	}
+}
@@ -146,6 +146,22 @@ an error.
 	}
 
 
+Change Data Capture
+
+To replicate changes from another database, write rows that upsert or delete rows of a table
+with a primary key.  Each row has the _CHANGE_TYPE pseudo-column, which is "UPSERT" or "DELETE",
+and may have the _CHANGE_SEQUENCE_NUMBER pseudo-column, which orders the changes to a key.
+The adapt subpackage adds the pseudo-columns to a table's schema before the descriptor is built:
+
+	storageSchema, err := adapt.BQSchemaToStorageTableSchema(tableSchema)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	storageSchema = adapt.WithChangeDataCaptureColumns(storageSchema, true)
+	descriptor, err := adapt.StorageSchemaToProto2Descriptor(storageSchema, "root")
+
+Change data capture writes are only supported on the default stream.
+
 Buffered Stream Management
 
 For Buffered streams, users control when data is made visible in the destination table/stream
@@ -0,0 +1,24 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package managedwriter
+
+// GetWriteStream exposes Client.getWriteStream to the integration tests, which
+// are in the managedwriter_test package because they use the bigquery
+// package, which imports this one.
+var GetWriteStream = (*Client).getWriteStream
+
+// ClientProjectID returns the project ID of a client, for the integration
+// tests.
+func ClientProjectID(c *Client) string { return c.projectID }
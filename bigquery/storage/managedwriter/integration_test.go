@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package managedwriter
+package managedwriter_test
 
 import (
 	"context"
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
 	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
 	"cloud.google.com/go/bigquery/storage/managedwriter/testdata"
 	"cloud.google.com/go/internal/testutil"
@@ -53,7 +54,7 @@ var testSimpleData = []*testdata.SimpleMessageProto2{
 	{Name: proto.String("five"), Value: proto.Int64(2)},
 }
 
-func getTestClients(ctx context.Context, t *testing.T, opts ...option.ClientOption) (*Client, *bigquery.Client) {
+func getTestClients(ctx context.Context, t *testing.T, opts ...option.ClientOption) (*managedwriter.Client, *bigquery.Client) {
 	if testing.Short() {
 		t.Skip("Integration tests skipped in short mode")
 	}
@@ -66,7 +67,7 @@ func getTestClients(ctx context.Context, t *testing.T, opts ...option.ClientOpti
 		t.Skip("Integration tests skipped. See CONTRIBUTING.md for details")
 	}
 	opts = append(opts, option.WithTokenSource(ts))
-	client, err := NewClient(ctx, projID, opts...)
+	client, err := managedwriter.NewClient(ctx, projID, opts...)
 	if err != nil {
 		t.Fatalf("couldn't create managedwriter client: %v", err)
 	}
@@ -132,6 +133,10 @@ func TestIntegration_ManagedWriter(t *testing.T) {
 			t.Parallel()
 			testDefaultStreamDynamicJSON(ctx, t, mwClient, bqClient, dataset)
 		})
+		t.Run("DefaultStreamCDC", func(t *testing.T) {
+			t.Parallel()
+			testDefaultStreamCDC(ctx, t, mwClient, bqClient, dataset)
+		})
 		t.Run("CommittedStream", func(t *testing.T) {
 			t.Parallel()
 			testCommittedStream(ctx, t, mwClient, bqClient, dataset)
@@ -155,7 +160,7 @@ func TestIntegration_ManagedWriter(t *testing.T) {
 	})
 }
 
-func testDefaultStream(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+func testDefaultStream(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
 	testTable := dataset.Table(tableIDs.New())
 	if err := testTable.Create(ctx, &bigquery.TableMetadata{Schema: testdata.SimpleMessageSchema}); err != nil {
 		t.Fatalf("failed to create test table %q: %v", testTable.FullyQualifiedName(), err)
@@ -167,9 +172,9 @@ func testDefaultStream(ctx context.Context, t *testing.T, mwClient *Client, bqCl
 
 	// setup a new stream.
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(DefaultStream),
-		WithSchemaDescriptor(descriptorProto),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
@@ -178,7 +183,7 @@ func testDefaultStream(ctx context.Context, t *testing.T, mwClient *Client, bqCl
 		withExactRowCount(0))
 
 	// First, send the test rows individually.
-	var result *AppendResult
+	var result *managedwriter.AppendResult
 	for k, mesg := range testSimpleData {
 		b, err := proto.Marshal(mesg)
 		if err != nil {
@@ -219,7 +224,7 @@ func testDefaultStream(ctx context.Context, t *testing.T, mwClient *Client, bqCl
 	)
 }
 
-func testDefaultStreamDynamicJSON(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+func testDefaultStreamDynamicJSON(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
 	testTable := dataset.Table(tableIDs.New())
 	if err := testTable.Create(ctx, &bigquery.TableMetadata{Schema: testdata.SimpleMessageSchema}); err != nil {
 		t.Fatalf("failed to create test table %s: %v", testTable.FullyQualifiedName(), err)
@@ -228,9 +233,9 @@ func testDefaultStreamDynamicJSON(ctx context.Context, t *testing.T, mwClient *C
 	md, descriptorProto := setupDynamicDescriptors(t, testdata.SimpleMessageSchema)
 
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(DefaultStream),
-		WithSchemaDescriptor(descriptorProto),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
@@ -246,7 +251,7 @@ func testDefaultStreamDynamicJSON(ctx context.Context, t *testing.T, mwClient *C
 		[]byte(`{"name": "five", "value": 5}`),
 	}
 
-	var result *AppendResult
+	var result *managedwriter.AppendResult
 	for k, v := range sampleJSONData {
 		message := dynamicpb.NewMessage(md)
 
@@ -274,7 +279,73 @@ func testDefaultStreamDynamicJSON(ctx context.Context, t *testing.T, mwClient *C
 		withDistinctValues("value", int64(len(sampleJSONData))))
 }
 
-func testBufferedStream(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+func testDefaultStreamCDC(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+	testTable := dataset.Table(tableIDs.New())
+	// Change data capture needs a table with a primary key.
+	ddl := fmt.Sprintf("CREATE TABLE `%s.%s.%s` (name STRING, value INT64, PRIMARY KEY (name) NOT ENFORCED)",
+		testTable.ProjectID, testTable.DatasetID, testTable.TableID)
+	job, err := bqClient.Query(ddl).Run(ctx)
+	if err != nil {
+		t.Fatalf("failed to create test table %s: %v", testTable.FullyQualifiedName(), err)
+	}
+	if status, err := job.Wait(ctx); err != nil || status.Err() != nil {
+		t.Fatalf("failed to create test table %s: %v %v", testTable.FullyQualifiedName(), err, status.Err())
+	}
+
+	storageSchema, err := adapt.BQSchemaToStorageTableSchema(testdata.SimpleMessageSchema)
+	if err != nil {
+		t.Fatalf("adapt.BQSchemaToStorageTableSchema: %v", err)
+	}
+	descriptor, err := adapt.StorageSchemaToProto2Descriptor(adapt.WithChangeDataCaptureColumns(storageSchema, true), "root")
+	if err != nil {
+		t.Fatalf("adapt.StorageSchemaToProto2Descriptor: %v", err)
+	}
+	md := descriptor.(protoreflect.MessageDescriptor)
+
+	ms, err := mwClient.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(protodesc.ToDescriptorProto(md)),
+	)
+	if err != nil {
+		t.Fatalf("NewManagedStream: %v", err)
+	}
+
+	// The sequence numbers apply the changes to "two" in order, although the
+	// rows are written in another order.
+	sampleJSONData := [][]byte{
+		[]byte(`{"name": "one", "value": 1, "_change_type": "UPSERT", "_change_sequence_number": "1"}`),
+		[]byte(`{"name": "two", "value": 22, "_change_type": "UPSERT", "_change_sequence_number": "3"}`),
+		[]byte(`{"name": "two", "value": 2, "_change_type": "UPSERT", "_change_sequence_number": "2"}`),
+		[]byte(`{"name": "three", "value": 3, "_change_type": "UPSERT", "_change_sequence_number": "4"}`),
+		[]byte(`{"name": "three", "_change_type": "DELETE", "_change_sequence_number": "5"}`),
+	}
+	var rows [][]byte
+	for k, v := range sampleJSONData {
+		message := dynamicpb.NewMessage(md)
+		if err := protojson.Unmarshal(v, message); err != nil {
+			t.Fatalf("failed to Unmarshal json message for row %d: %v", k, err)
+		}
+		b, err := proto.Marshal(message)
+		if err != nil {
+			t.Fatalf("failed to marshal proto bytes for row %d: %v", k, err)
+		}
+		rows = append(rows, b)
+	}
+	result, err := ms.AppendRows(ctx, rows)
+	if err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	if _, err := result.GetResult(ctx); err != nil {
+		t.Fatalf("append result: %v", err)
+	}
+	validateTableConstraints(ctx, t, bqClient, testTable, "after send",
+		withExactRowCount(2),
+		withDistinctValues("name", 2),
+		withDistinctValues("value", 2))
+}
+
+func testBufferedStream(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
 	testTable := dataset.Table(tableIDs.New())
 	if err := testTable.Create(ctx, &bigquery.TableMetadata{Schema: testdata.SimpleMessageSchema}); err != nil {
 		t.Fatalf("failed to create test table %s: %v", testTable.FullyQualifiedName(), err)
@@ -284,15 +355,15 @@ func testBufferedStream(ctx context.Context, t *testing.T, mwClient *Client, bqC
 	descriptorProto := protodesc.ToDescriptorProto(m.ProtoReflect().Descriptor())
 
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(BufferedStream),
-		WithSchemaDescriptor(descriptorProto),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.BufferedStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
 	}
 
-	info, err := ms.c.getWriteStream(ctx, ms.streamSettings.streamID)
+	info, err := managedwriter.GetWriteStream(mwClient, ctx, ms.StreamName())
 	if err != nil {
 		t.Errorf("couldn't get stream info: %v", err)
 	}
@@ -334,7 +405,7 @@ func testBufferedStream(ctx context.Context, t *testing.T, mwClient *Client, bqC
 	}
 }
 
-func testCommittedStream(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+func testCommittedStream(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
 	testTable := dataset.Table(tableIDs.New())
 	if err := testTable.Create(ctx, &bigquery.TableMetadata{Schema: testdata.SimpleMessageSchema}); err != nil {
 		t.Fatalf("failed to create test table %s: %v", testTable.FullyQualifiedName(), err)
@@ -345,9 +416,9 @@ func testCommittedStream(ctx context.Context, t *testing.T, mwClient *Client, bq
 
 	// setup a new stream.
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(CommittedStream),
-		WithSchemaDescriptor(descriptorProto),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.CommittedStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
@@ -355,14 +426,14 @@ func testCommittedStream(ctx context.Context, t *testing.T, mwClient *Client, bq
 	validateTableConstraints(ctx, t, bqClient, testTable, "before send",
 		withExactRowCount(0))
 
-	var result *AppendResult
+	var result *managedwriter.AppendResult
 	for k, mesg := range testSimpleData {
 		b, err := proto.Marshal(mesg)
 		if err != nil {
 			t.Errorf("failed to marshal message %d: %v", k, err)
 		}
 		data := [][]byte{b}
-		result, err = ms.AppendRows(ctx, data, WithOffset(int64(k)))
+		result, err = ms.AppendRows(ctx, data, managedwriter.WithOffset(int64(k)))
 		if err != nil {
 			t.Errorf("single-row append %d failed: %v", k, err)
 		}
@@ -373,7 +444,7 @@ func testCommittedStream(ctx context.Context, t *testing.T, mwClient *Client, bq
 		withExactRowCount(int64(len(testSimpleData))))
 }
 
-func testPendingStream(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+func testPendingStream(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
 	testTable := dataset.Table(tableIDs.New())
 	if err := testTable.Create(ctx, &bigquery.TableMetadata{Schema: testdata.SimpleMessageSchema}); err != nil {
 		t.Fatalf("failed to create test table %s: %v", testTable.FullyQualifiedName(), err)
@@ -383,9 +454,9 @@ func testPendingStream(ctx context.Context, t *testing.T, mwClient *Client, bqCl
 	descriptorProto := protodesc.ToDescriptorProto(m.ProtoReflect().Descriptor())
 
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(PendingStream),
-		WithSchemaDescriptor(descriptorProto),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.PendingStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
@@ -394,14 +465,14 @@ func testPendingStream(ctx context.Context, t *testing.T, mwClient *Client, bqCl
 		withExactRowCount(0))
 
 	// Send data.
-	var result *AppendResult
+	var result *managedwriter.AppendResult
 	for k, mesg := range testSimpleData {
 		b, err := proto.Marshal(mesg)
 		if err != nil {
 			t.Errorf("failed to marshal message %d: %v", k, err)
 		}
 		data := [][]byte{b}
-		result, err = ms.AppendRows(ctx, data, WithOffset(int64(k)))
+		result, err = ms.AppendRows(ctx, data, managedwriter.WithOffset(int64(k)))
 		if err != nil {
 			t.Errorf("single-row append %d failed: %v", k, err)
 		}
@@ -428,7 +499,7 @@ func testPendingStream(ctx context.Context, t *testing.T, mwClient *Client, bqCl
 
 	// Commit stream and validate.
 	req := &storagepb.BatchCommitWriteStreamsRequest{
-		Parent:       TableParentFromStreamName(ms.StreamName()),
+		Parent:       managedwriter.TableParentFromStreamName(ms.StreamName()),
 		WriteStreams: []string{ms.StreamName()},
 	}
 
@@ -443,11 +514,11 @@ func testPendingStream(ctx context.Context, t *testing.T, mwClient *Client, bqCl
 		withExactRowCount(int64(len(testSimpleData))))
 }
 
-func testInstrumentation(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+func testInstrumentation(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
 	testedViews := []*view.View{
-		AppendRequestsView,
-		AppendResponsesView,
-		AppendClientOpenView,
+		managedwriter.AppendRequestsView,
+		managedwriter.AppendResponsesView,
+		managedwriter.AppendClientOpenView,
 	}
 
 	if err := view.Register(testedViews...); err != nil {
@@ -464,15 +535,15 @@ func testInstrumentation(ctx context.Context, t *testing.T, mwClient *Client, bq
 
 	// setup a new stream.
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(DefaultStream),
-		WithSchemaDescriptor(descriptorProto),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
 	}
 
-	var result *AppendResult
+	var result *managedwriter.AppendResult
 	for k, mesg := range testSimpleData {
 		b, err := proto.Marshal(mesg)
 		if err != nil {
@@ -509,11 +580,11 @@ func testInstrumentation(ctx context.Context, t *testing.T, mwClient *Client, bq
 		got := sum.Value
 		var want int64
 		switch tv {
-		case AppendRequestsView:
+		case managedwriter.AppendRequestsView:
 			want = int64(len(testSimpleData))
-		case AppendResponsesView:
+		case managedwriter.AppendResponsesView:
 			want = int64(len(testSimpleData))
-		case AppendClientOpenView:
+		case managedwriter.AppendClientOpenView:
 			want = 1
 		}
 
@@ -524,7 +595,7 @@ func testInstrumentation(ctx context.Context, t *testing.T, mwClient *Client, bq
 	}
 }
 
-func testSchemaEvolution(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
+func testSchemaEvolution(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset) {
 	testTable := dataset.Table(tableIDs.New())
 	if err := testTable.Create(ctx, &bigquery.TableMetadata{Schema: testdata.SimpleMessageSchema}); err != nil {
 		t.Fatalf("failed to create test table %s: %v", testTable.FullyQualifiedName(), err)
@@ -535,9 +606,9 @@ func testSchemaEvolution(ctx context.Context, t *testing.T, mwClient *Client, bq
 
 	// setup a new stream.
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(CommittedStream),
-		WithSchemaDescriptor(descriptorProto),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.CommittedStream),
+		managedwriter.WithSchemaDescriptor(descriptorProto),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
@@ -545,7 +616,7 @@ func testSchemaEvolution(ctx context.Context, t *testing.T, mwClient *Client, bq
 	validateTableConstraints(ctx, t, bqClient, testTable, "before send",
 		withExactRowCount(0))
 
-	var result *AppendResult
+	var result *managedwriter.AppendResult
 	var curOffset int64
 	var latestRow []byte
 	for k, mesg := range testSimpleData {
@@ -555,7 +626,7 @@ func testSchemaEvolution(ctx context.Context, t *testing.T, mwClient *Client, bq
 		}
 		latestRow = b
 		data := [][]byte{b}
-		result, err = ms.AppendRows(ctx, data, WithOffset(curOffset))
+		result, err = ms.AppendRows(ctx, data, managedwriter.WithOffset(curOffset))
 		if err != nil {
 			t.Errorf("single-row append %d failed: %v", k, err)
 		}
@@ -580,7 +651,7 @@ func testSchemaEvolution(ctx context.Context, t *testing.T, mwClient *Client, bq
 	// It _should_ be possible to send duplicates, but this currently will not propagate the schema error.
 	// Internal issue: b/211899346
 	for {
-		resp, err := ms.AppendRows(ctx, [][]byte{latestRow}, WithOffset(curOffset))
+		resp, err := ms.AppendRows(ctx, [][]byte{latestRow}, managedwriter.WithOffset(curOffset))
 		if err != nil {
 			t.Errorf("got error on dupe append: %v", err)
 			break
@@ -612,7 +683,7 @@ func testSchemaEvolution(ctx context.Context, t *testing.T, mwClient *Client, bq
 	if err != nil {
 		t.Errorf("failed to marshal evolved message: %v", err)
 	}
-	result, err = ms.AppendRows(ctx, [][]byte{b}, UpdateSchemaDescriptor(descriptorProto), WithOffset(curOffset))
+	result, err = ms.AppendRows(ctx, [][]byte{b}, managedwriter.UpdateSchemaDescriptor(descriptorProto), managedwriter.WithOffset(curOffset))
 	if err != nil {
 		t.Errorf("failed evolved append: %v", err)
 	}
@@ -635,14 +706,14 @@ func TestIntegration_DetectProjectID(t *testing.T) {
 		t.Skip("test credentials not present, skipping")
 	}
 
-	if _, err := NewClient(ctx, DetectProjectID, option.WithCredentials(testCreds)); err != nil {
+	if _, err := managedwriter.NewClient(ctx, managedwriter.DetectProjectID, option.WithCredentials(testCreds)); err != nil {
 		t.Errorf("test NewClient: %v", err)
 	}
 
 	badTS := testutil.ErroringTokenSource{}
 
-	if badClient, err := NewClient(ctx, DetectProjectID, option.WithTokenSource(badTS)); err == nil {
-		t.Errorf("expected error from bad token source, NewClient succeeded with project: %s", badClient.projectID)
+	if badClient, err := managedwriter.NewClient(ctx, managedwriter.DetectProjectID, option.WithTokenSource(badTS)); err == nil {
+		t.Errorf("expected error from bad token source, NewClient succeeded with project: %s", managedwriter.ClientProjectID(badClient))
 	}
 }
 
@@ -708,7 +779,7 @@ func TestIntegration_ProtoNormalization(t *testing.T) {
 	})
 }
 
-func testProtoNormalization(ctx context.Context, t *testing.T, mwClient *Client, bqClient *bigquery.Client, dataset *bigquery.Dataset, schema bigquery.Schema, descriptor protoreflect.MessageDescriptor, sampleRow []byte) {
+func testProtoNormalization(ctx context.Context, t *testing.T, mwClient *managedwriter.Client, bqClient *bigquery.Client, dataset *bigquery.Dataset, schema bigquery.Schema, descriptor protoreflect.MessageDescriptor, sampleRow []byte) {
 	testTable := dataset.Table(tableIDs.New())
 	if err := testTable.Create(ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
 		t.Fatalf("failed to create test table %q: %v", testTable.FullyQualifiedName(), err)
@@ -721,9 +792,9 @@ func testProtoNormalization(ctx context.Context, t *testing.T, mwClient *Client,
 
 	// setup a new stream.
 	ms, err := mwClient.NewManagedStream(ctx,
-		WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
-		WithType(DefaultStream),
-		WithSchemaDescriptor(dp),
+		managedwriter.WithDestinationTable(fmt.Sprintf("projects/%s/datasets/%s/tables/%s", testTable.ProjectID, testTable.DatasetID, testTable.TableID)),
+		managedwriter.WithType(managedwriter.DefaultStream),
+		managedwriter.WithSchemaDescriptor(dp),
 	)
 	if err != nil {
 		t.Fatalf("NewManagedStream: %v", err)
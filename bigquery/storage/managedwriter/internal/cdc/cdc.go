@@ -0,0 +1,23 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cdc holds the names of the change data capture pseudo-columns
+// exported by the adapt package. They are defined here so that managedwriter
+// can use them too: it cannot import adapt, which imports the bigquery
+// package, which imports managedwriter.
+package cdc
+
+// ChangeTypeColumn is the name of the pseudo-column that holds the type of
+// change of a row.
+const ChangeTypeColumn = "_CHANGE_TYPE"
@@ -0,0 +1,219 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v9/arrow"
+	"github.com/apache/arrow/go/v9/arrow/ipc"
+	"github.com/apache/arrow/go/v9/parquet"
+	"github.com/apache/arrow/go/v9/parquet/pqarrow"
+	"google.golang.org/api/iterator"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+)
+
+// An ArrowIterator reads the rows of a table or query result as Apache Arrow
+// records, without converting them to Values.
+//
+// The records are read with the BigQuery Storage Read API, from several
+// streams in parallel, so they are in the order of the result only for
+// query results with an ORDER BY clause.
+type ArrowIterator struct {
+	ctx    context.Context
+	r      *storageReader
+	schema *arrow.Schema
+	err    error
+}
+
+// ArrowRecords returns an ArrowIterator over all rows of the table or query
+// result of it, which must not have been used. The number of streams is
+// limited by the MaxStreams of the client's StorageRead, if set. Rows cached
+// from a query are read again.
+//
+// ArrowRecords returns an error if the rows cannot be read with the Storage
+// Read API, for example because the table is a view or the result is that of
+// a script. Canceling the context given to Read stops the background reading
+// of the streams; it should be canceled if iteration stops before
+// iterator.Done.
+func (it *RowIterator) ArrowRecords() (*ArrowIterator, error) {
+	if it.src == nil {
+		return nil, errors.New("bigquery: RowIterator has no source to read Arrow records from")
+	}
+	var c *Client
+	switch {
+	case it.src.j != nil:
+		c = it.src.j.c
+	case it.src.t != nil:
+		c = it.src.t.c
+	}
+	if c == nil {
+		return nil, errors.New("bigquery: RowIterator has no source to read Arrow records from")
+	}
+	t, ordered, err := storageReadTable(it.ctx, c, it.src)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.New("bigquery: result cannot be read with the Storage Read API")
+	}
+	var maxStreams int
+	if c.StorageRead != nil {
+		maxStreams = c.StorageRead.MaxStreams
+	}
+	rc, session, err := newReadSession(it.ctx, c, t, storagepb.DataFormat_ARROW, maxStreams, ordered)
+	if err != nil {
+		return nil, err
+	}
+	serializedSchema := session.GetArrowSchema().GetSerializedSchema()
+	schema, err := decodeArrowSchema(serializedSchema)
+	if err != nil {
+		return nil, err
+	}
+	r := &storageReader{
+		rc: rc,
+		decode: func(resp *storagepb.ReadRowsResponse) (storagePage, error) {
+			return decodeArrowBatch(serializedSchema, schema, resp)
+		},
+	}
+	r.start(it.ctx, session.GetStreams())
+	return &ArrowIterator{ctx: it.ctx, r: r, schema: schema}, nil
+}
+
+// decodeArrowSchema decodes an Arrow schema serialized as an encapsulated
+// Arrow IPC message.
+func decodeArrowSchema(serializedSchema []byte) (*arrow.Schema, error) {
+	r, err := ipc.NewReader(bytes.NewReader(serializedSchema))
+	if err != nil {
+		return nil, fmt.Errorf("bigquery: decoding Arrow schema: %w", err)
+	}
+	defer r.Release()
+	return r.Schema(), nil
+}
+
+// decodeArrowBatch decodes the Arrow record batch of resp. The service sends
+// record batches without the schema, so the serialized schema is read before
+// the batch, as in an Arrow IPC stream.
+func decodeArrowBatch(serializedSchema []byte, schema *arrow.Schema, resp *storagepb.ReadRowsResponse) (storagePage, error) {
+	b := resp.GetArrowRecordBatch()
+	buf := io.MultiReader(bytes.NewReader(serializedSchema), bytes.NewReader(b.GetSerializedRecordBatch()))
+	r, err := ipc.NewReader(buf, ipc.WithSchema(schema))
+	if err != nil {
+		return storagePage{}, fmt.Errorf("bigquery: decoding Arrow record batch: %w", err)
+	}
+	defer r.Release()
+	if !r.Next() {
+		if err := r.Err(); err != nil {
+			return storagePage{}, fmt.Errorf("bigquery: decoding Arrow record batch: %w", err)
+		}
+		return storagePage{}, errors.New("bigquery: Arrow record batch has no record")
+	}
+	rec := r.Record()
+	rec.Retain()
+	return storagePage{record: rec, n: rec.NumRows()}, nil
+}
+
+// Schema returns the Arrow schema of the records.
+func (ai *ArrowIterator) Schema() *arrow.Schema {
+	return ai.schema
+}
+
+// Next returns the next record. Its second return value is iterator.Done if
+// there are no more records. Once Next returns Done, all subsequent calls
+// will return Done.
+//
+// The caller owns the record and should call its Release method once done
+// with it.
+func (ai *ArrowIterator) Next() (arrow.Record, error) {
+	if ai.err != nil {
+		return nil, ai.err
+	}
+	p, ok, err := ai.r.page(ai.ctx)
+	switch {
+	case err != nil:
+		ai.err = err
+	case !ok:
+		ai.err = iterator.Done
+	default:
+		return p.record, nil
+	}
+	return nil, ai.err
+}
+
+// each calls f with each remaining record, and releases it afterwards.
+func (ai *ArrowIterator) each(f func(arrow.Record) error) error {
+	for {
+		rec, err := ai.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = f(rec)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// WriteTo writes the remaining records to w in the Arrow IPC streaming
+// format, which readers such as pyarrow.ipc.open_stream accept. It returns
+// the number of bytes written.
+func (ai *ArrowIterator) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	iw := ipc.NewWriter(cw, ipc.WithSchema(ai.schema))
+	if err := ai.each(iw.Write); err != nil {
+		return cw.n, err
+	}
+	err := iw.Close()
+	return cw.n, err
+}
+
+// WriteParquet writes the remaining records to w as a Parquet file. If props
+// is nil, the default Parquet writer properties are used. WriteParquet does
+// not close w.
+func (ai *ArrowIterator) WriteParquet(w io.Writer, props *parquet.WriterProperties) error {
+	if props == nil {
+		props = parquet.NewWriterProperties()
+	}
+	// The Parquet writer closes its destination if it is an io.Closer, so
+	// hide the Close method of w.
+	fw, err := pqarrow.NewFileWriter(ai.schema, struct{ io.Writer }{w}, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return err
+	}
+	if err := ai.each(fw.Write); err != nil {
+		return err
+	}
+	return fw.Close()
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
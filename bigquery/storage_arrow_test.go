@@ -0,0 +1,195 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/arrow/go/v9/arrow"
+	"github.com/apache/arrow/go/v9/arrow/array"
+	"github.com/apache/arrow/go/v9/arrow/ipc"
+	"github.com/apache/arrow/go/v9/arrow/memory"
+	"github.com/apache/arrow/go/v9/parquet"
+	"github.com/apache/arrow/go/v9/parquet/pqarrow"
+	"google.golang.org/api/iterator"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+)
+
+var testArrowSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "name", Type: arrow.BinaryTypes.String},
+	{Name: "num", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+}, nil)
+
+func newTestArrowRecord(names []string, nums []int64) arrow.Record {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, testArrowSchema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues(names, nil)
+	b.Field(1).(*array.Int64Builder).AppendValues(nums, nil)
+	return b.NewRecord()
+}
+
+// serializeArrow returns the schema and the record batch of rec as
+// encapsulated Arrow IPC messages, as sent by the Storage Read API.
+func serializeArrow(t *testing.T, rec arrow.Record) (schema, batch []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := ipc.NewWriter(&buf, ipc.WithSchema(rec.Schema()))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	schema = append([]byte(nil), buf.Bytes()[:buf.Len()-len(arrowEOS)]...)
+	buf.Reset()
+	w = ipc.NewWriter(&buf, ipc.WithSchema(rec.Schema()))
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return schema, buf.Bytes()[len(schema) : buf.Len()-len(arrowEOS)]
+}
+
+// arrowEOS is the end-of-stream marker of the Arrow IPC streaming format.
+var arrowEOS = []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}
+
+func newTestArrowIterator(pages ...storagePage) *ArrowIterator {
+	r := &storageReader{
+		pages:  make(chan storagePage, len(pages)),
+		cancel: func() {},
+	}
+	for _, p := range pages {
+		r.pages <- p
+	}
+	close(r.pages)
+	return &ArrowIterator{ctx: context.Background(), r: r, schema: testArrowSchema}
+}
+
+func TestDecodeArrowBatch(t *testing.T) {
+	rec := newTestArrowRecord([]string{"a", "b"}, []int64{1, 2})
+	defer rec.Release()
+	serializedSchema, batch := serializeArrow(t, rec)
+
+	schema, err := decodeArrowSchema(serializedSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !schema.Equal(testArrowSchema) {
+		t.Errorf("got schema %v, want %v", schema, testArrowSchema)
+	}
+	resp := &storagepb.ReadRowsResponse{
+		Rows: &storagepb.ReadRowsResponse_ArrowRecordBatch{
+			ArrowRecordBatch: &storagepb.ArrowRecordBatch{SerializedRecordBatch: batch},
+		},
+	}
+	p, err := decodeArrowBatch(serializedSchema, schema, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.record.Release()
+	if p.n != 2 || !array.RecordEqual(p.record, rec) {
+		t.Errorf("got record %v with %d rows, want %v", p.record, p.n, rec)
+	}
+
+	if _, err := decodeArrowSchema([]byte("not a schema")); err == nil {
+		t.Error("decoding invalid schema: got nil error, want non-nil")
+	}
+	resp.GetArrowRecordBatch().SerializedRecordBatch = []byte("not a batch")
+	if _, err := decodeArrowBatch(serializedSchema, schema, resp); err == nil {
+		t.Error("decoding invalid batch: got nil error, want non-nil")
+	}
+}
+
+func TestArrowIteratorWriteTo(t *testing.T) {
+	ai := newTestArrowIterator(
+		storagePage{record: newTestArrowRecord([]string{"a", "b"}, []int64{1, 2}), n: 2},
+		storagePage{record: newTestArrowRecord([]string{"c"}, []int64{3}), n: 1},
+	)
+	var buf bytes.Buffer
+	n, err := ai.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("got %d bytes written, want %d", n, buf.Len())
+	}
+	r, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+	var rows int64
+	for r.Next() {
+		rows += r.Record().NumRows()
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 3 {
+		t.Errorf("got %d rows in Arrow stream, want 3", rows)
+	}
+	if _, err := ai.Next(); err != iterator.Done {
+		t.Errorf("after WriteTo: got %v, want iterator.Done", err)
+	}
+
+	wantErr := errors.New("stream failed")
+	ai = newTestArrowIterator(
+		storagePage{record: newTestArrowRecord([]string{"a"}, []int64{1}), n: 1},
+		storagePage{err: wantErr},
+	)
+	if _, err := ai.WriteTo(&buf); err != wantErr {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+	if _, err := ai.Next(); err != wantErr {
+		t.Errorf("after failure: got %v, want %v", err, wantErr)
+	}
+}
+
+func TestArrowIteratorWriteParquet(t *testing.T) {
+	ai := newTestArrowIterator(
+		storagePage{record: newTestArrowRecord([]string{"a", "b"}, []int64{1, 2}), n: 2},
+		storagePage{record: newTestArrowRecord([]string{"c"}, []int64{3}), n: 1},
+	)
+	var buf bytes.Buffer
+	if err := ai.WriteParquet(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	tbl, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), parquet.NewReaderProperties(memory.DefaultAllocator), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+	if got := tbl.NumRows(); got != 3 {
+		t.Errorf("got %d rows in Parquet file, want 3", got)
+	}
+	for i, f := range tbl.Schema().Fields() {
+		if want := testArrowSchema.Field(i); f.Name != want.Name || !arrow.TypeEqual(f.Type, want.Type) {
+			t.Errorf("got Parquet field %v, want %v", f, want)
+		}
+	}
+}
+
+func TestArrowRecordsErrors(t *testing.T) {
+	for _, it := range []*RowIterator{
+		newRowIterator(context.Background(), &rowSource{}, fetchPage),
+		newRowIterator(context.Background(), nil, fetchPage),
+	} {
+		if _, err := it.ArrowRecords(); err == nil {
+			t.Error("got nil error, want non-nil")
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// avroType is the part of an Avro schema needed to decode the rows that the
+// Storage Read API sends in Avro format.
+type avroType struct {
+	kind     string      // a primitive type name, or "record", "array" or "union"
+	logical  string      // the logicalType attribute, if any
+	scale    int         // the scale of a decimal
+	fields   []*avroType // the field types of a record, in order
+	items    *avroType   // the item type of an array
+	branches []*avroType // the types of a union
+}
+
+// parseAvroSchema parses the JSON form of an Avro schema.
+func parseAvroSchema(s string) (*avroType, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("bigquery: parsing Avro schema: %v", err)
+	}
+	t, err := newAvroType(v, map[string]*avroType{})
+	if err != nil {
+		return nil, fmt.Errorf("bigquery: parsing Avro schema: %v", err)
+	}
+	return t, nil
+}
+
+func newAvroType(v interface{}, named map[string]*avroType) (*avroType, error) {
+	switch v := v.(type) {
+	case string:
+		switch v {
+		case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+			return &avroType{kind: v}, nil
+		}
+		if t, ok := named[v]; ok {
+			return t, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", v)
+	case []interface{}:
+		t := &avroType{kind: "union"}
+		for _, b := range v {
+			bt, err := newAvroType(b, named)
+			if err != nil {
+				return nil, err
+			}
+			t.branches = append(t.branches, bt)
+		}
+		return t, nil
+	case map[string]interface{}:
+		switch v["type"] {
+		case "record":
+			t := &avroType{kind: "record"}
+			if name, ok := v["name"].(string); ok {
+				named[name] = t
+			}
+			fields, _ := v["fields"].([]interface{})
+			for _, f := range fields {
+				fm, ok := f.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("malformed record field")
+				}
+				ft, err := newAvroType(fm["type"], named)
+				if err != nil {
+					return nil, err
+				}
+				t.fields = append(t.fields, ft)
+			}
+			return t, nil
+		case "array":
+			items, err := newAvroType(v["items"], named)
+			if err != nil {
+				return nil, err
+			}
+			return &avroType{kind: "array", items: items}, nil
+		}
+		t, err := newAvroType(v["type"], named)
+		if err != nil {
+			return nil, err
+		}
+		if t.kind == "record" || t.kind == "array" || t.kind == "union" {
+			return t, nil
+		}
+		// Annotated primitives are copied, so that they don't modify a
+		// shared named type.
+		pt := *t
+		pt.logical, _ = v["logicalType"].(string)
+		if scale, ok := v["scale"].(float64); ok {
+			pt.scale = int(scale)
+		}
+		return &pt, nil
+	}
+	return nil, fmt.Errorf("unexpected %T in schema", v)
+}
+
+// avroDecoder decodes Avro binary data.
+type avroDecoder struct {
+	buf []byte
+}
+
+var errAvroShort = errors.New("bigquery: Avro data is truncated")
+
+func (d *avroDecoder) long() (int64, error) {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		return 0, errAvroShort
+	}
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+func (d *avroDecoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > int64(len(d.buf)) {
+		return nil, errAvroShort
+	}
+	b := d.buf[:n:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+// decodeAvroRows decodes the rows of a ReadRowsResponse, which are Avro
+// records of type t without any framing, into the Values of schema.
+func decodeAvroRows(b []byte, t *avroType, schema Schema) ([][]Value, error) {
+	if t.kind != "record" || len(t.fields) != len(schema) {
+		return nil, errors.New("bigquery: Avro schema does not match table schema")
+	}
+	d := &avroDecoder{buf: b}
+	var rows [][]Value
+	for len(d.buf) > 0 {
+		row, err := d.record(t, schema)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (d *avroDecoder) record(t *avroType, schema Schema) ([]Value, error) {
+	if len(t.fields) != len(schema) {
+		return nil, errors.New("bigquery: Avro schema does not match table schema")
+	}
+	values := make([]Value, len(schema))
+	for i, fs := range schema {
+		v, err := d.value(t.fields[i], fs)
+		if err != nil {
+			return nil, fmt.Errorf("%v (field %s)", err, fs.Name)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// value decodes a value of type t and converts it to the Value that
+// tabledata.list would produce for fs.
+func (d *avroDecoder) value(t *avroType, fs *FieldSchema) (Value, error) {
+	switch t.kind {
+	case "null":
+		return nil, nil
+	case "union":
+		i, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(t.branches)) {
+			return nil, fmt.Errorf("bigquery: Avro union index %d out of range", i)
+		}
+		return d.value(t.branches[i], fs)
+	case "array":
+		// Items of repeated fields are never NULL, so fs describes each of them.
+		var values []Value
+		for {
+			n, err := d.long()
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return values, nil
+			}
+			if n < 0 {
+				// A negative count is followed by the size of the block in bytes.
+				n = -n
+				if _, err := d.long(); err != nil {
+					return nil, err
+				}
+			}
+			for ; n > 0; n-- {
+				v, err := d.value(t.items, fs)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, v)
+			}
+		}
+	case "record":
+		return d.record(t, fs.Schema)
+	case "boolean":
+		if len(d.buf) == 0 {
+			return nil, errAvroShort
+		}
+		v := d.buf[0] != 0
+		d.buf = d.buf[1:]
+		return v, nil
+	case "int", "long":
+		v, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		return avroIntValue(v, fs.Type), nil
+	case "float":
+		if len(d.buf) < 4 {
+			return nil, errAvroShort
+		}
+		v := math.Float32frombits(binary.LittleEndian.Uint32(d.buf))
+		d.buf = d.buf[4:]
+		return float64(v), nil
+	case "double":
+		if len(d.buf) < 8 {
+			return nil, errAvroShort
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.buf))
+		d.buf = d.buf[8:]
+		return v, nil
+	case "bytes":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if t.logical == "decimal" {
+			return avroDecimal(b, t.scale), nil
+		}
+		return append([]byte(nil), b...), nil
+	case "string":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if fs.Type == DateTimeFieldType {
+			// Some DATETIME values are sent with a space between the date
+			// and the time.
+			return civil.ParseDateTime(strings.Replace(string(b), " ", "T", 1))
+		}
+		switch fs.Type {
+		case JSONFieldType:
+			return json.RawMessage(string(b)), nil
+		case IntervalFieldType:
+			return ParseInterval(string(b))
+		}
+		return string(b), nil
+	}
+	return nil, fmt.Errorf("bigquery: unsupported Avro type %q", t.kind)
+}
+
+// avroIntValue converts an Avro int or long to a Value of the given type.
+// TIMESTAMP and TIME values are in microseconds, and DATE values are days
+// since the Unix epoch.
+func avroIntValue(v int64, typ FieldType) Value {
+	switch typ {
+	case TimestampFieldType:
+		secs, micros := v/1e6, v%1e6
+		if micros < 0 {
+			secs--
+			micros += 1e6
+		}
+		return time.Unix(secs, micros*1000).UTC()
+	case DateFieldType:
+		return unixEpochDate.AddDays(int(v))
+	case TimeFieldType:
+		return civil.TimeOf(time.Unix(0, v*1000).UTC())
+	}
+	return v
+}
+
+// avroDecimal converts the big-endian two's-complement unscaled value of an
+// Avro decimal to a *big.Rat.
+func avroDecimal(b []byte, scale int) Value {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+	}
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return new(big.Rat).SetFrac(n, denom)
+}
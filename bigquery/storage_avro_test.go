@@ -0,0 +1,156 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"cloud.google.com/go/internal/testutil"
+)
+
+// avroEncoder builds Avro binary data for tests.
+type avroEncoder []byte
+
+func (e *avroEncoder) long(v int64) *avroEncoder {
+	*e = append(*e, make([]byte, binary.MaxVarintLen64)...)
+	n := binary.PutVarint((*e)[len(*e)-binary.MaxVarintLen64:], v)
+	*e = (*e)[:len(*e)-binary.MaxVarintLen64+n]
+	return e
+}
+
+func (e *avroEncoder) bytes(b []byte) *avroEncoder {
+	e.long(int64(len(b)))
+	*e = append(*e, b...)
+	return e
+}
+
+func (e *avroEncoder) double(f float64) *avroEncoder {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+	*e = append(*e, b[:]...)
+	return e
+}
+
+const testAvroSchema = `{"type": "record", "name": "__root__", "fields": [
+	{"name": "s", "type": ["null", "string"]},
+	{"name": "i", "type": "long"},
+	{"name": "f", "type": ["null", "double"]},
+	{"name": "b", "type": ["null", "boolean"]},
+	{"name": "by", "type": ["null", "bytes"]},
+	{"name": "ts", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}]},
+	{"name": "d", "type": ["null", {"type": "int", "logicalType": "date"}]},
+	{"name": "t", "type": ["null", {"type": "long", "logicalType": "time-micros"}]},
+	{"name": "dt", "type": ["null", {"type": "string", "logicalType": "datetime"}]},
+	{"name": "n", "type": ["null", {"type": "bytes", "logicalType": "decimal", "precision": 38, "scale": 9}]},
+	{"name": "g", "type": ["null", {"type": "string", "sqlType": "GEOGRAPHY"}]},
+	{"name": "r", "type": ["null", {"type": "record", "name": "__r", "fields": [{"name": "x", "type": ["null", "long"]}]}]},
+	{"name": "a", "type": {"type": "array", "items": "string"}}
+]}`
+
+func TestDecodeAvroRows(t *testing.T) {
+	schema := Schema{
+		{Name: "s", Type: StringFieldType},
+		{Name: "i", Type: IntegerFieldType, Required: true},
+		{Name: "f", Type: FloatFieldType},
+		{Name: "b", Type: BooleanFieldType},
+		{Name: "by", Type: BytesFieldType},
+		{Name: "ts", Type: TimestampFieldType},
+		{Name: "d", Type: DateFieldType},
+		{Name: "t", Type: TimeFieldType},
+		{Name: "dt", Type: DateTimeFieldType},
+		{Name: "n", Type: NumericFieldType},
+		{Name: "g", Type: GeographyFieldType},
+		{Name: "r", Type: RecordFieldType, Schema: Schema{{Name: "x", Type: IntegerFieldType}}},
+		{Name: "a", Type: StringFieldType, Repeated: true},
+	}
+	at, err := parseAvroSchema(testAvroSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &avroEncoder{}
+	// A row with every value set.
+	e.long(1).bytes([]byte("str"))
+	e.long(-42)
+	e.long(1).double(1.5)
+	e.long(1)
+	*e = append(*e, 1)
+	e.long(1).bytes([]byte{0, 1})
+	e.long(1).long(-1500000) // 1.5s before the epoch
+	e.long(1).long(10)
+	e.long(1).long(((1*60+2)*60+3)*1e6 + 4)
+	e.long(1).bytes([]byte("2022-03-01 04:05:06.000007"))
+	e.long(1).bytes([]byte{0xfe, 0x0c}) // -500 nanos, i.e. -0.0000005
+	e.long(1).bytes([]byte("POINT(1 2)"))
+	e.long(1).long(1).long(9)
+	// Two blocks, the second with its size in bytes.
+	e.long(1).bytes([]byte("x"))
+	e.long(-1).long(2).bytes([]byte("y"))
+	e.long(0)
+	// A row with NULLs and an empty array.
+	e.long(0)
+	e.long(7)
+	for i := 0; i < 10; i++ {
+		e.long(0)
+	}
+	e.long(0)
+
+	got, err := decodeAvroRows(*e, at, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]Value{
+		{
+			"str", int64(-42), 1.5, true, []byte{0, 1},
+			time.Date(1969, 12, 31, 23, 59, 58, 500000000, time.UTC),
+			civil.Date{Year: 1970, Month: 1, Day: 11},
+			civil.Time{Hour: 1, Minute: 2, Second: 3, Nanosecond: 4000},
+			civil.DateTime{Date: civil.Date{Year: 2022, Month: 3, Day: 1}, Time: civil.Time{Hour: 4, Minute: 5, Second: 6, Nanosecond: 7000}},
+			big.NewRat(-1, 2000000),
+			"POINT(1 2)",
+			[]Value{int64(9)},
+			[]Value{"x", "y"},
+		},
+		{nil, int64(7), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, []Value(nil)},
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Errorf("rows mismatch (-got +want):\n%s", diff)
+	}
+
+	if _, err := decodeAvroRows((*e)[:len(*e)-2], at, schema); err == nil {
+		t.Error("truncated data: got nil error, want non-nil")
+	}
+	if _, err := decodeAvroRows(*e, at, schema[:3]); err == nil {
+		t.Error("mismatched schema: got nil error, want non-nil")
+	}
+}
+
+func TestParseAvroSchemaErrors(t *testing.T) {
+	for _, s := range []string{
+		`{`,
+		`"fixed"`,
+		`{"type": "record", "fields": [{"name": "x", "type": "unknown"}]}`,
+		`{"type": "array", "items": 3}`,
+	} {
+		if _, err := parseAvroSchema(s); err == nil {
+			t.Errorf("%s: got nil error, want non-nil", s)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/internal/trace"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxStorageAppendBytes bounds the size of the rows sent in one append
+// request, keeping requests below the Storage Write API limit of 10MB.
+const maxStorageAppendBytes = 8 << 20
+
+// A StorageWriteMode selects the kind of Storage Write API stream that a
+// StorageInserter writes to. See
+// https://cloud.google.com/bigquery/docs/write-api#application-created_streams.
+type StorageWriteMode string
+
+const (
+	// DefaultStreamMode writes to the table's default stream. Rows are
+	// visible as soon as Put returns, with at-least-once semantics, and any
+	// number of inserters can write to the stream at the same time.
+	DefaultStreamMode StorageWriteMode = "DEFAULT"
+
+	// CommittedStreamMode writes to a new stream whose rows are visible as
+	// soon as Put returns. Rows are written at explicit offsets, so they are
+	// written exactly once.
+	CommittedStreamMode StorageWriteMode = "COMMITTED"
+
+	// PendingStreamMode writes to a new stream whose rows are not visible
+	// until Commit is called, when they become visible atomically. Rows are
+	// written at explicit offsets, so they are written exactly once.
+	PendingStreamMode StorageWriteMode = "PENDING"
+
+	// BufferedStreamMode writes to a new stream whose rows become visible
+	// when Flush is called. Rows are written at explicit offsets, so they are
+	// written exactly once.
+	BufferedStreamMode StorageWriteMode = "BUFFERED"
+)
+
+var storageStreamTypes = map[StorageWriteMode]managedwriter.StreamType{
+	DefaultStreamMode:   managedwriter.DefaultStream,
+	CommittedStreamMode: managedwriter.CommittedStream,
+	PendingStreamMode:   managedwriter.PendingStream,
+	BufferedStreamMode:  managedwriter.BufferedStream,
+}
+
+// A StorageInserter writes rows to a BigQuery table with the BigQuery Storage
+// Write API, which has higher throughput than the streaming inserts of an
+// Inserter and, except in DefaultStreamMode, writes each row exactly once.
+// It is safe for concurrent use.
+//
+// Rows are encoded as protocol buffer messages whose descriptor is generated
+// from the table's schema when the StorageInserter is created. The values of
+// a row are taken from ValueSavers and structs in the same way as
+// Inserter.Put, and are matched to the table's columns by name.
+//
+// In the modes that write at explicit offsets, a failed Put may leave the
+// stream with rows missing, so every later call returns the error of the
+// failed Put. To retry, close the StorageInserter and write the rows that
+// were not acknowledged with a new one. In PendingStreamMode nothing is
+// visible until Commit, so the rows of the failed StorageInserter can simply
+// be written again.
+type StorageInserter struct {
+	// IgnoreUnknownValues causes values of fields that are not in the table's
+	// schema to be ignored. The default value is false, which causes Put to
+	// return an error for rows containing such values.
+	IgnoreUnknownValues bool
+
+	t      *Table
+	mode   StorageWriteMode
+	schema Schema
+	md     protoreflect.MessageDescriptor
+	wc     *managedwriter.Client
+	ms     *managedwriter.ManagedStream
+
+	mu     sync.Mutex
+	offset int64 // offset of the next row, except in DefaultStreamMode
+	err    error // error of a failed Put, except in DefaultStreamMode
+}
+
+// StorageInserter returns a StorageInserter that writes rows to t with the
+// BigQuery Storage Write API, using a stream of the given mode. It reads the
+// table's schema to generate the descriptor of the rows.
+//
+// The Storage Write API client is created with the options given to
+// NewClient. ctx is retained for the connections of the StorageInserter, so
+// canceling it stops the StorageInserter. Close must be called when done.
+func (t *Table) StorageInserter(ctx context.Context, mode StorageWriteMode) (si *StorageInserter, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.StorageInserter")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	streamType, ok := storageStreamTypes[mode]
+	if !ok {
+		return nil, fmt.Errorf("bigquery: unknown StorageWriteMode %q", mode)
+	}
+	meta, err := t.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	md, dp, err := storageRowDescriptor(meta.Schema)
+	if err != nil {
+		return nil, err
+	}
+	wc, err := t.c.storageWriteClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := t.Identifier(StorageAPIResourceID)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := wc.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(dest),
+		managedwriter.WithType(streamType),
+		managedwriter.WithSchemaDescriptor(dp))
+	if err != nil {
+		return nil, fmt.Errorf("bigquery: opening write stream: %v", err)
+	}
+	return &StorageInserter{
+		t:      t,
+		mode:   mode,
+		schema: meta.Schema,
+		md:     md,
+		wc:     wc,
+		ms:     ms,
+	}, nil
+}
+
+// Put writes one or more rows and waits until they have been acknowledged.
+// src is interpreted as by Inserter.Put: it may be a ValueSaver, a struct or
+// struct pointer, or a slice of those. Insert IDs are ignored.
+//
+// Large slices are sent in several requests. Calls to Put from different
+// goroutines are written in the order in which they start.
+func (si *StorageInserter) Put(ctx context.Context, src interface{}) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.StorageInserter.Put")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	savers, err := valueSavers(src)
+	if err != nil {
+		return err
+	}
+	var batches [][][]byte
+	var batch [][]byte
+	var size int
+	for i, saver := range savers {
+		row, _, err := saver.Save()
+		if err != nil {
+			return err
+		}
+		b, err := encodeStorageRow(si.md, si.schema, row, si.IgnoreUnknownValues)
+		if err != nil {
+			return fmt.Errorf("%v (row %d)", err, i)
+		}
+		if len(batch) > 0 && size+len(b) > maxStorageAppendBytes {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, b)
+		size += len(b)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	if len(batches) == 0 {
+		return nil
+	}
+
+	results, err := si.append(ctx, batches)
+	for _, res := range results {
+		if _, rerr := res.GetResult(ctx); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if err != nil && si.mode != DefaultStreamMode {
+		si.mu.Lock()
+		if si.err == nil {
+			si.err = err
+		}
+		si.mu.Unlock()
+	}
+	return err
+}
+
+// append sends the batches of rows, at the next offsets unless this is the
+// default stream. It returns the results of the batches that were sent.
+func (si *StorageInserter) append(ctx context.Context, batches [][][]byte) ([]*managedwriter.AppendResult, error) {
+	var results []*managedwriter.AppendResult
+	if si.mode == DefaultStreamMode {
+		for _, rows := range batches {
+			res, err := si.ms.AppendRows(ctx, rows)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, res)
+		}
+		return results, nil
+	}
+
+	// Hold the lock while sending, so that offsets are sent in order.
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	if err := si.failed(); err != nil {
+		return nil, err
+	}
+	for _, rows := range batches {
+		res, err := si.ms.AppendRows(ctx, rows, managedwriter.WithOffset(si.offset))
+		if err != nil {
+			si.err = err
+			return results, err
+		}
+		si.offset += int64(len(rows))
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// failed returns an error if an earlier Put failed. si.mu must be held.
+func (si *StorageInserter) failed() error {
+	if si.err == nil {
+		return nil
+	}
+	return fmt.Errorf("bigquery: StorageInserter stopped after an earlier error: %v", si.err)
+}
+
+// Flush makes the rows of the Puts that have returned visible. It may only be
+// used in BufferedStreamMode.
+func (si *StorageInserter) Flush(ctx context.Context) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.StorageInserter.Flush")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if si.mode != BufferedStreamMode {
+		return errors.New("bigquery: Flush requires BufferedStreamMode")
+	}
+	si.mu.Lock()
+	offset, err := si.offset, si.failed()
+	si.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if offset == 0 {
+		return nil
+	}
+	_, err = si.ms.FlushRows(ctx, offset-1)
+	return err
+}
+
+// Commit finalizes the stream and makes all of its rows visible atomically.
+// It may only be used in PendingStreamMode, after every Put has returned, and
+// no rows can be written after it. It returns the number of rows committed.
+func (si *StorageInserter) Commit(ctx context.Context) (rows int64, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.StorageInserter.Commit")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if si.mode != PendingStreamMode {
+		return 0, errors.New("bigquery: Commit requires PendingStreamMode")
+	}
+	si.mu.Lock()
+	err = si.failed()
+	si.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	rows, err = si.ms.Finalize(ctx)
+	if err != nil {
+		return 0, err
+	}
+	parent, err := si.t.Identifier(StorageAPIResourceID)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := si.wc.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       parent,
+		WriteStreams: []string{si.ms.StreamName()},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if serrs := resp.GetStreamErrors(); len(serrs) > 0 {
+		return 0, fmt.Errorf("bigquery: committing stream %s: %s", serrs[0].GetEntity(), serrs[0].GetErrorMessage())
+	}
+	return rows, nil
+}
+
+// Close closes the stream. Rows of a StorageInserter in PendingStreamMode that
+// have not been committed are discarded.
+func (si *StorageInserter) Close() error {
+	return si.ms.Close()
+}
@@ -0,0 +1,341 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	bqstorage "cloud.google.com/go/bigquery/storage/apiv1"
+	"github.com/apache/arrow/go/v9/arrow"
+	"golang.org/x/sync/errgroup"
+	bq "google.golang.org/api/bigquery/v2"
+	storagepb "google.golang.org/genproto/googleapis/cloud/bigquery/storage/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultStorageReadMinRows is the default of StorageReadConfig.MinRows.
+	defaultStorageReadMinRows = 10000
+
+	// maxStorageReadResumes is the number of times in a row that reading a
+	// stream is resumed after a transient error without receiving any rows.
+	maxStorageReadResumes = 3
+
+	// storageReadPageToken is the page token of the pages read with the
+	// Storage Read API. These pages cannot be fetched again, so the token
+	// only tells the iterator that there are more rows.
+	storageReadPageToken = "bigquery-storage-read"
+)
+
+// StorageReadConfig configures how RowIterators use the BigQuery Storage Read
+// API. See Client.StorageRead.
+//
+// A RowIterator uses the Storage Read API only if the result has at least
+// MinRows rows, and only if neither RowIterator.StartIndex nor the PageInfo
+// Token or MaxSize is set before the first call to Next. Otherwise, or if a
+// read session cannot be created, for example because the table is a view or
+// the caller lacks the bigquery.readsessions.create permission, rows are read
+// with tabledata.list as usual.
+//
+// Rows read with the Storage Read API arrive in the order of the table only
+// for query results with an ORDER BY clause, which are read with a single
+// stream. The pages of such a RowIterator have a PageInfo Token that tells
+// only whether there are more rows; it cannot be used to resume reading.
+//
+// Canceling the context given to Read stops the background reading of the
+// streams. It should be canceled if iteration stops before iterator.Done.
+type StorageReadConfig struct {
+	// MaxStreams is the maximum number of streams read in parallel. BigQuery
+	// may use fewer. The default is 0, which lets BigQuery choose.
+	MaxStreams int
+
+	// MinRows is the minimum number of rows of a result that is read with the
+	// Storage Read API. Smaller results are read with tabledata.list, which
+	// has less overhead. The default is 10000.
+	MinRows int64
+}
+
+// readPageFetcher returns the pageFetcher of a new RowIterator, which uses
+// the Storage Read API if c.StorageRead is set.
+func (c *Client) readPageFetcher() pageFetcher {
+	if c == nil || c.StorageRead == nil {
+		return fetchPage
+	}
+	f := &storageReadFetcher{c: c, cfg: *c.StorageRead, fallback: fetchPage}
+	return f.fetch
+}
+
+// A storageReadFetcher reads the rows of one RowIterator. On the first fetch
+// it decides whether to read the rows with the Storage Read API, and
+// otherwise delegates every fetch to fallback.
+type storageReadFetcher struct {
+	c        *Client
+	cfg      StorageReadConfig
+	fallback pageFetcher
+
+	started bool
+	r       *storageReader
+}
+
+func (f *storageReadFetcher) fetch(ctx context.Context, src *rowSource, schema Schema, startIndex uint64, pageSize int64, pageToken string) (*fetchPageResult, error) {
+	if f.r != nil {
+		return f.r.next(ctx)
+	}
+	if !f.started {
+		f.started = true
+		if pageToken == "" && startIndex == 0 && pageSize <= 0 {
+			// Errors are not returned: fallback either works or reports them.
+			if r, _ := f.start(ctx, src, schema); r != nil {
+				f.r = r
+				// Rows cached from the query are read again from the stream.
+				src.cachedRows, src.cachedSchema, src.cachedNextToken = nil, nil, ""
+				return r.next(ctx)
+			}
+		}
+	}
+	return f.fallback(ctx, src, schema, startIndex, pageSize, pageToken)
+}
+
+var orderByRE = regexp.MustCompile(`(?i)\bORDER\s+BY\b`)
+
+// storageReadTable returns the table that holds the rows of src, and whether
+// they are ordered. It returns a nil Table if the rows of src are not all the
+// rows of a table, for example for scripts, which have no destination table,
+// and for query results appended to an existing table.
+func storageReadTable(ctx context.Context, c *Client, src *rowSource) (*Table, bool, error) {
+	if src.j == nil {
+		return src.t, false, nil
+	}
+	var job *bq.Job
+	err := runWithRetry(ctx, func() (err error) {
+		call := c.bqs.Jobs.Get(src.j.projectID, src.j.jobID).Location(src.j.location).
+			Fields("configuration(query(destinationTable,query,writeDisposition))").
+			Context(ctx)
+		setClientHeader(call.Header())
+		job, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	qc := job.Configuration.Query
+	if qc == nil || qc.DestinationTable == nil || qc.WriteDisposition == string(WriteAppend) {
+		return nil, false, nil
+	}
+	t := c.DatasetInProject(qc.DestinationTable.ProjectId, qc.DestinationTable.DatasetId).Table(qc.DestinationTable.TableId)
+	return t, orderByRE.MatchString(qc.Query), nil
+}
+
+// newReadSession creates a read session for all rows of t in the given
+// format. Ordered rows are read from a single stream.
+func newReadSession(ctx context.Context, c *Client, t *Table, format storagepb.DataFormat, maxStreams int, ordered bool) (*bqstorage.BigQueryReadClient, *storagepb.ReadSession, error) {
+	rc, err := c.storageReadClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tableID, err := t.Identifier(StorageAPIResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ordered {
+		maxStreams = 1
+	}
+	session, err := rc.CreateReadSession(ctx, &storagepb.CreateReadSessionRequest{
+		Parent: fmt.Sprintf("projects/%s", c.projectID),
+		ReadSession: &storagepb.ReadSession{
+			Table:      tableID,
+			DataFormat: format,
+		},
+		MaxStreamCount: int32(maxStreams),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc, session, nil
+}
+
+// start creates a read session for the rows of src and starts reading its
+// streams. It returns a nil storageReader if the result is too small or
+// cannot be read with the Storage Read API.
+func (f *storageReadFetcher) start(ctx context.Context, src *rowSource, schema Schema) (*storageReader, error) {
+	if src.cachedRows != nil && src.cachedNextToken == "" {
+		// The query returned every row already.
+		return nil, nil
+	}
+	t, ordered, err := storageReadTable(ctx, f.c, src)
+	if err != nil || t == nil {
+		return nil, err
+	}
+
+	var bqt *bq.Table
+	err = runWithRetry(ctx, func() (err error) {
+		call := f.c.bqs.Tables.Get(t.ProjectID, t.DatasetID, t.TableID).Fields("schema", "numRows", "type").Context(ctx)
+		setClientHeader(call.Header())
+		bqt, err = call.Do()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	minRows := f.cfg.MinRows
+	if minRows <= 0 {
+		minRows = defaultStorageReadMinRows
+	}
+	if bqt.Type != "TABLE" || int64(bqt.NumRows) < minRows {
+		return nil, nil
+	}
+	if schema == nil {
+		schema = bqToSchema(bqt.Schema)
+	}
+
+	rc, session, err := newReadSession(ctx, f.c, t, storagepb.DataFormat_AVRO, f.cfg.MaxStreams, ordered)
+	if err != nil {
+		return nil, err
+	}
+	at, err := parseAvroSchema(session.GetAvroSchema().GetSchema())
+	if err != nil {
+		return nil, err
+	}
+	r := &storageReader{
+		rc:        rc,
+		schema:    schema,
+		totalRows: bqt.NumRows,
+		decode: func(resp *storagepb.ReadRowsResponse) (storagePage, error) {
+			rows, err := decodeAvroRows(resp.GetAvroRows().GetSerializedBinaryRows(), at, schema)
+			return storagePage{rows: rows, n: int64(len(rows))}, err
+		},
+	}
+	r.start(ctx, session.GetStreams())
+	return r, nil
+}
+
+// A storageReader reads the streams of a read session in the background.
+type storageReader struct {
+	rc        *bqstorage.BigQueryReadClient
+	schema    Schema
+	totalRows uint64
+	decode    func(*storagepb.ReadRowsResponse) (storagePage, error)
+
+	// pages receives the rows of every stream, and then an error if reading
+	// failed. It is closed when all streams have been read.
+	pages  chan storagePage
+	cancel context.CancelFunc
+}
+
+// A storagePage holds the rows of one ReadRowsResponse, either as Values or
+// as an Arrow record.
+type storagePage struct {
+	rows   [][]Value
+	record arrow.Record
+	n      int64 // the number of rows
+	err    error
+}
+
+func (r *storageReader) start(ctx context.Context, streams []*storagepb.ReadStream) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.pages = make(chan storagePage, len(streams))
+	g, gctx := errgroup.WithContext(ctx)
+	for _, s := range streams {
+		name := s.GetName()
+		g.Go(func() error { return r.readStream(gctx, name) })
+	}
+	go func() {
+		if err := g.Wait(); err != nil {
+			select {
+			case r.pages <- storagePage{err: err}:
+			case <-ctx.Done():
+			}
+		}
+		close(r.pages)
+	}()
+}
+
+// readStream sends the rows of the named stream to r.pages, resuming from
+// the last row received after transient errors.
+func (r *storageReader) readStream(ctx context.Context, name string) error {
+	var offset int64
+	resumes := 0
+	for {
+		rows, err := r.rc.ReadRows(ctx, &storagepb.ReadRowsRequest{ReadStream: name, Offset: offset})
+		if err != nil {
+			return err
+		}
+		for {
+			resp, err := rows.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				if status.Code(err) == codes.Unavailable && resumes < maxStorageReadResumes && ctx.Err() == nil {
+					resumes++
+					break
+				}
+				return err
+			}
+			p, err := r.decode(resp)
+			if err != nil {
+				return err
+			}
+			if p.n == 0 {
+				continue
+			}
+			offset += p.n
+			resumes = 0
+			select {
+			case r.pages <- p:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// page returns the next page. It returns ok == false when all streams have
+// been read.
+func (r *storageReader) page(ctx context.Context) (p storagePage, ok bool, err error) {
+	select {
+	case p, ok := <-r.pages:
+		if !ok {
+			r.cancel()
+			return p, false, nil
+		}
+		if p.err != nil {
+			r.cancel()
+			return p, false, p.err
+		}
+		return p, true, nil
+	case <-ctx.Done():
+		r.cancel()
+		return p, false, ctx.Err()
+	}
+}
+
+// next returns the next page of rows. The last page has an empty page token.
+func (r *storageReader) next(ctx context.Context) (*fetchPageResult, error) {
+	p, ok, err := r.page(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &fetchPageResult{schema: r.schema, totalRows: r.totalRows}
+	if ok {
+		res.rows = p.rows
+		res.pageToken = storageReadPageToken
+	}
+	return res, nil
+}
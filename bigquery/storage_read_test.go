@@ -0,0 +1,113 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+	bq "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/iterator"
+)
+
+func TestStorageReadFetcherFallback(t *testing.T) {
+	// None of these cases may start a read session; the client has no
+	// services, so trying to would panic.
+	c := &Client{StorageRead: &StorageReadConfig{}}
+	for _, test := range []struct {
+		desc       string
+		src        *rowSource
+		startIndex uint64
+		pageSize   int64
+		pageToken  string
+	}{
+		{desc: "start index", src: &rowSource{t: &Table{}}, startIndex: 5},
+		{desc: "page size", src: &rowSource{t: &Table{}}, pageSize: 10},
+		{desc: "page token", src: &rowSource{t: &Table{}}, pageToken: "tok"},
+		{desc: "complete cached result", src: &rowSource{j: &Job{}, cachedRows: []*bq.TableRow{{}}}},
+		{desc: "no source", src: &rowSource{}},
+	} {
+		var calls int
+		f := &storageReadFetcher{c: c, cfg: *c.StorageRead, fallback: func(context.Context, *rowSource, Schema, uint64, int64, string) (*fetchPageResult, error) {
+			calls++
+			return &fetchPageResult{}, nil
+		}}
+		for i := 0; i < 2; i++ {
+			if _, err := f.fetch(context.Background(), test.src, nil, test.startIndex, test.pageSize, test.pageToken); err != nil {
+				t.Fatalf("%s: %v", test.desc, err)
+			}
+		}
+		// Only the first fetch may start a read session.
+		if _, err := f.fetch(context.Background(), test.src, nil, 0, 0, ""); err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if calls != 3 || f.r != nil {
+			t.Errorf("%s: got %d fallback calls (storage reader %v), want 3", test.desc, calls, f.r)
+		}
+	}
+}
+
+func TestStorageReaderPages(t *testing.T) {
+	schema := Schema{{Name: "n", Type: IntegerFieldType}}
+	newIterator := func(pages ...storagePage) *RowIterator {
+		r := &storageReader{
+			schema:    schema,
+			totalRows: 3,
+			pages:     make(chan storagePage, len(pages)),
+			cancel:    func() {},
+		}
+		for _, p := range pages {
+			r.pages <- p
+		}
+		close(r.pages)
+		f := &storageReadFetcher{started: true, r: r}
+		return newRowIterator(context.Background(), &rowSource{}, f.fetch)
+	}
+
+	it := newIterator(
+		storagePage{rows: [][]Value{{int64(1)}, {int64(2)}}},
+		storagePage{rows: [][]Value{{int64(3)}}},
+	)
+	var got [][]Value
+	for {
+		var row []Value
+		err := it.Next(&row)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	if diff := testutil.Diff(got, [][]Value{{int64(1)}, {int64(2)}, {int64(3)}}); diff != "" {
+		t.Errorf("rows mismatch (-got +want):\n%s", diff)
+	}
+	if it.TotalRows != 3 || !testutil.Equal(it.Schema, schema) {
+		t.Errorf("got TotalRows %d and schema %v, want 3 and %v", it.TotalRows, it.Schema, schema)
+	}
+
+	wantErr := errors.New("stream failed")
+	it = newIterator(storagePage{rows: [][]Value{{int64(1)}}}, storagePage{err: wantErr})
+	var row []Value
+	if err := it.Next(&row); err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Next(&row); err != wantErr {
+		t.Errorf("got error %v, want %v", err, wantErr)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// storageRowMessageName is the name of the top-level message of the
+// descriptors generated for Storage Write API rows.
+const storageRowMessageName = "Row"
+
+// storageFieldTypes maps BigQuery types to the protocol buffer types used to
+// write them with the Storage Write API. See
+// https://cloud.google.com/bigquery/docs/write-api#data_type_conversions.
+var storageFieldTypes = map[FieldType]descriptorpb.FieldDescriptorProto_Type{
+	StringFieldType:     descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	BytesFieldType:      descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	IntegerFieldType:    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	FloatFieldType:      descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	BooleanFieldType:    descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	TimestampFieldType:  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	RecordFieldType:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+	DateFieldType:       descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	TimeFieldType:       descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	DateTimeFieldType:   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	NumericFieldType:    descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	BigNumericFieldType: descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	GeographyFieldType:  descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	JSONFieldType:       descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	IntervalFieldType:   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+}
+
+// storageRowDescriptor generates the protocol buffer descriptor of rows of
+// a table with the given schema. The DescriptorProto is self-contained, with
+// the messages of RECORD fields nested in the messages that use them, as the
+// Storage Write API requires.
+func storageRowDescriptor(schema Schema) (protoreflect.MessageDescriptor, *descriptorpb.DescriptorProto, error) {
+	dp, err := storageMessageProto(storageRowMessageName, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("bigquery_storage_row.proto"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{dp},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bigquery: building row descriptor: %v", err)
+	}
+	return fd.Messages().Get(0), dp, nil
+}
+
+func storageMessageProto(name string, schema Schema) (*descriptorpb.DescriptorProto, error) {
+	if len(schema) == 0 {
+		return nil, fmt.Errorf("bigquery: message %s has no fields", name)
+	}
+	dp := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	// Nested message names share a scope with field names, so pick names
+	// that cannot collide with any field.
+	used := map[string]bool{}
+	for _, fs := range schema {
+		used[fs.Name] = true
+	}
+	for i, fs := range schema {
+		typ, ok := storageFieldTypes[fs.Type]
+		if !ok {
+			return nil, fmt.Errorf("bigquery: field %s has type %s, which the Storage Write API does not support", fs.Name, fs.Type)
+		}
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(fs.Name),
+			Number: proto.Int32(int32(i + 1)),
+			Type:   typ.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		switch {
+		case fs.Repeated:
+			f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		case fs.Required:
+			f.Label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum()
+		}
+		if fs.Type == RecordFieldType {
+			nestedName := "Record_" + fs.Name
+			for used[nestedName] {
+				nestedName += "_"
+			}
+			used[nestedName] = true
+			nested, err := storageMessageProto(nestedName, fs.Schema)
+			if err != nil {
+				return nil, err
+			}
+			dp.NestedType = append(dp.NestedType, nested)
+			f.TypeName = proto.String(nestedName)
+		}
+		dp.Field = append(dp.Field, f)
+	}
+	return dp, nil
+}
+
+// encodeStorageRow serializes a row, as returned by ValueSaver.Save, as a
+// message of md, the descriptor generated from schema by
+// storageRowDescriptor. Field names are matched case-insensitively, like
+// BigQuery column names. Values that are nil or invalid Null types are
+// omitted. Values for fields that are not in the schema cause an error unless
+// ignoreUnknown is true.
+func encodeStorageRow(md protoreflect.MessageDescriptor, schema Schema, row map[string]Value, ignoreUnknown bool) ([]byte, error) {
+	m, err := storageMessage(md, schema, row, ignoreUnknown)
+	if err != nil {
+		return nil, err
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("bigquery: %v", err)
+	}
+	return b, nil
+}
+
+func storageMessage(md protoreflect.MessageDescriptor, schema Schema, row map[string]Value, ignoreUnknown bool) (*dynamicpb.Message, error) {
+	m := dynamicpb.NewMessage(md)
+	for name, v := range row {
+		i := storageFieldIndex(schema, name)
+		if i < 0 {
+			if ignoreUnknown {
+				continue
+			}
+			return nil, fmt.Errorf("bigquery: no field %q in table schema", name)
+		}
+		fs := schema[i]
+		fd := md.Fields().ByNumber(protoreflect.FieldNumber(i + 1))
+		v = nullValue(v)
+		if v == nil {
+			continue
+		}
+		if !fs.Repeated {
+			pv, err := storageValue(fd, fs, v, ignoreUnknown)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(fd, pv)
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("bigquery: repeated field %s: got %T, want a slice", fs.Name, v)
+		}
+		list := m.Mutable(fd).List()
+		for j := 0; j < rv.Len(); j++ {
+			ev := nullValue(rv.Index(j).Interface())
+			if ev == nil {
+				return nil, fmt.Errorf("bigquery: repeated field %s: element %d is NULL", fs.Name, j)
+			}
+			pv, err := storageValue(fd, fs, ev, ignoreUnknown)
+			if err != nil {
+				return nil, err
+			}
+			list.Append(pv)
+		}
+	}
+	return m, nil
+}
+
+func storageFieldIndex(schema Schema, name string) int {
+	for i, fs := range schema {
+		if fs.Name == name {
+			return i
+		}
+	}
+	for i, fs := range schema {
+		if strings.EqualFold(fs.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// nullValue returns the value of a Null type, or nil if it is invalid. Other
+// values are returned unchanged.
+func nullValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case NullInt64:
+		return nullOr(n.Valid, n.Int64)
+	case NullString:
+		return nullOr(n.Valid, n.StringVal)
+	case NullGeography:
+		return nullOr(n.Valid, n.GeographyVal)
+	case NullFloat64:
+		return nullOr(n.Valid, n.Float64)
+	case NullBool:
+		return nullOr(n.Valid, n.Bool)
+	case NullTimestamp:
+		return nullOr(n.Valid, n.Timestamp)
+	case NullDate:
+		return nullOr(n.Valid, n.Date)
+	case NullTime:
+		return nullOr(n.Valid, n.Time)
+	case NullDateTime:
+		return nullOr(n.Valid, n.DateTime)
+	}
+	if rv := reflect.ValueOf(v); rv.IsValid() {
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+			if rv.IsNil() {
+				return nil
+			}
+		}
+	}
+	return v
+}
+
+func nullOr(valid bool, v interface{}) interface{} {
+	if !valid {
+		return nil
+	}
+	return v
+}
+
+func storageValue(fd protoreflect.FieldDescriptor, fs *FieldSchema, v interface{}, ignoreUnknown bool) (protoreflect.Value, error) {
+	if fs.Type == RecordFieldType {
+		nested, err := storageRecord(fd.Message(), fs, v, ignoreUnknown)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfMessage(nested), nil
+	}
+	return storageScalar(fs, v)
+}
+
+func storageRecord(md protoreflect.MessageDescriptor, fs *FieldSchema, v interface{}, ignoreUnknown bool) (*dynamicpb.Message, error) {
+	var row map[string]Value
+	switch r := v.(type) {
+	case map[string]Value:
+		row = r
+	case map[string]interface{}:
+		row = make(map[string]Value, len(r))
+		for k, v := range r {
+			row[k] = v
+		}
+	default:
+		return nil, fmt.Errorf("bigquery: record field %s: got %T, want map[string]Value", fs.Name, v)
+	}
+	return storageMessage(md, fs.Schema, row, ignoreUnknown)
+}
+
+var (
+	typeOfCivilTime     = reflect.TypeOf(civil.Time{})
+	typeOfCivilDateTime = reflect.TypeOf(civil.DateTime{})
+	unixEpochDate       = civil.Date{Year: 1970, Month: time.January, Day: 1}
+)
+
+// storageScalar converts a non-RECORD value to the protocol buffer value of
+// its field. It accepts the Go types that Inserter.Put accepts for the field's
+// type, including the strings that ValueSavers produce for TIME, DATETIME,
+// NUMERIC, BIGNUMERIC, JSON and INTERVAL values.
+func storageScalar(fs *FieldSchema, v interface{}) (protoreflect.Value, error) {
+	bad := func() (protoreflect.Value, error) {
+		return protoreflect.Value{}, fmt.Errorf("bigquery: field %s of type %s: cannot write a value of type %T", fs.Name, fs.Type, v)
+	}
+	rv := reflect.ValueOf(v)
+	switch fs.Type {
+	case StringFieldType, GeographyFieldType:
+		switch x := v.(type) {
+		case Geography:
+			return protoreflect.ValueOfString(x.WKT), nil
+		case *Geography:
+			return protoreflect.ValueOfString(x.WKT), nil
+		}
+		if rv.Kind() != reflect.String {
+			return bad()
+		}
+		return protoreflect.ValueOfString(rv.String()), nil
+	case BytesFieldType:
+		b, ok := v.([]byte)
+		if !ok {
+			return bad()
+		}
+		return protoreflect.ValueOfBytes(b), nil
+	case IntegerFieldType:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return protoreflect.ValueOfInt64(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rv.Uint() > math.MaxInt64 {
+				return protoreflect.Value{}, fmt.Errorf("bigquery: field %s: value %d overflows INTEGER", fs.Name, rv.Uint())
+			}
+			return protoreflect.ValueOfInt64(int64(rv.Uint())), nil
+		}
+		return bad()
+	case FloatFieldType:
+		switch rv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return protoreflect.ValueOfFloat64(rv.Float()), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return protoreflect.ValueOfFloat64(float64(rv.Int())), nil
+		}
+		return bad()
+	case BooleanFieldType:
+		if rv.Kind() != reflect.Bool {
+			return bad()
+		}
+		return protoreflect.ValueOfBool(rv.Bool()), nil
+	case TimestampFieldType:
+		t, ok := v.(time.Time)
+		if !ok {
+			return bad()
+		}
+		return protoreflect.ValueOfInt64(t.Unix()*1e6 + int64(t.Nanosecond()/1e3)), nil
+	case DateFieldType:
+		d, ok := v.(civil.Date)
+		if !ok {
+			return bad()
+		}
+		return protoreflect.ValueOfInt32(int32(d.DaysSince(unixEpochDate))), nil
+	case TimeFieldType:
+		switch {
+		case rv.Type() == typeOfCivilTime:
+			return protoreflect.ValueOfString(CivilTimeString(v.(civil.Time))), nil
+		case rv.Kind() == reflect.String:
+			return protoreflect.ValueOfString(rv.String()), nil
+		}
+		return bad()
+	case DateTimeFieldType:
+		switch {
+		case rv.Type() == typeOfCivilDateTime:
+			return protoreflect.ValueOfString(CivilDateTimeString(v.(civil.DateTime))), nil
+		case rv.Kind() == reflect.String:
+			return protoreflect.ValueOfString(rv.String()), nil
+		}
+		return bad()
+	case NumericFieldType, BigNumericFieldType:
+		switch x := v.(type) {
+		case *big.Rat:
+			if fs.Type == NumericFieldType {
+				return protoreflect.ValueOfString(NumericString(x)), nil
+			}
+			return protoreflect.ValueOfString(BigNumericString(x)), nil
+		case string:
+			return protoreflect.ValueOfString(x), nil
+		}
+		return bad()
+	case JSONFieldType:
+		switch x := v.(type) {
+		case json.RawMessage:
+			return protoreflect.ValueOfString(string(x)), nil
+		case string:
+			return protoreflect.ValueOfString(x), nil
+		}
+		return bad()
+	case IntervalFieldType:
+		switch x := v.(type) {
+		case IntervalValue:
+			return protoreflect.ValueOfString(x.String()), nil
+		case *IntervalValue:
+			return protoreflect.ValueOfString(x.String()), nil
+		case string:
+			return protoreflect.ValueOfString(x), nil
+		}
+		return bad()
+	}
+	return bad()
+}
@@ -0,0 +1,244 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigquery
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestStorageRowDescriptor(t *testing.T) {
+	schema := Schema{
+		{Name: "name", Type: StringFieldType, Required: true},
+		{Name: "n", Type: IntegerFieldType},
+		{Name: "tags", Type: StringFieldType, Repeated: true},
+		{Name: "when", Type: TimestampFieldType},
+		{Name: "day", Type: DateFieldType},
+		{Name: "price", Type: NumericFieldType},
+		{Name: "addr", Type: RecordFieldType, Schema: Schema{
+			{Name: "city", Type: StringFieldType},
+		}},
+		{Name: "Record_parts", Type: StringFieldType},
+		{Name: "parts", Type: RecordFieldType, Repeated: true, Schema: Schema{
+			{Name: "id", Type: IntegerFieldType},
+		}},
+	}
+	_, got, err := storageRowDescriptor(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantText := `
+		name: "Row"
+		field: {name: "name" number: 1 label: LABEL_REQUIRED type: TYPE_STRING}
+		field: {name: "n" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64}
+		field: {name: "tags" number: 3 label: LABEL_REPEATED type: TYPE_STRING}
+		field: {name: "when" number: 4 label: LABEL_OPTIONAL type: TYPE_INT64}
+		field: {name: "day" number: 5 label: LABEL_OPTIONAL type: TYPE_INT32}
+		field: {name: "price" number: 6 label: LABEL_OPTIONAL type: TYPE_STRING}
+		field: {name: "addr" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: "Record_addr"}
+		field: {name: "Record_parts" number: 8 label: LABEL_OPTIONAL type: TYPE_STRING}
+		field: {name: "parts" number: 9 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: "Record_parts_"}
+		nested_type: {
+			name: "Record_addr"
+			field: {name: "city" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING}
+		}
+		nested_type: {
+			name: "Record_parts_"
+			field: {name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64}
+		}`
+	wantDP := got.ProtoReflect().New().Interface()
+	if err := prototext.Unmarshal([]byte(wantText), wantDP); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got, wantDP, protocmp.Transform()); diff != "" {
+		t.Errorf("descriptor mismatch (-got +want):\n%s", diff)
+	}
+
+	for _, bad := range []Schema{
+		{},
+		{{Name: "r", Type: RecordFieldType}},
+		{{Name: "x", Type: FieldType("RANGE")}},
+	} {
+		if _, _, err := storageRowDescriptor(bad); err == nil {
+			t.Errorf("%v: got nil error, want non-nil", bad)
+		}
+	}
+}
+
+type storageRowStruct struct {
+	Name   string
+	N      NullInt64
+	F      float32
+	OK     bool
+	Blob   []byte
+	When   time.Time
+	Day    civil.Date
+	Clock  civil.Time
+	Stamp  civil.DateTime
+	Price  *big.Rat
+	Tags   []string
+	Addr   *storageRowAddr
+	Parts  []storageRowAddr
+	Absent NullString
+}
+
+type storageRowAddr struct {
+	City string
+}
+
+func TestEncodeStorageRow(t *testing.T) {
+	schema := Schema{
+		{Name: "name", Type: StringFieldType, Required: true},
+		{Name: "n", Type: IntegerFieldType},
+		{Name: "f", Type: FloatFieldType},
+		{Name: "ok", Type: BooleanFieldType},
+		{Name: "blob", Type: BytesFieldType},
+		{Name: "when", Type: TimestampFieldType},
+		{Name: "day", Type: DateFieldType},
+		{Name: "clock", Type: TimeFieldType},
+		{Name: "stamp", Type: DateTimeFieldType},
+		{Name: "price", Type: NumericFieldType},
+		{Name: "big", Type: BigNumericFieldType},
+		{Name: "tags", Type: StringFieldType, Repeated: true},
+		{Name: "addr", Type: RecordFieldType, Schema: Schema{{Name: "city", Type: StringFieldType}}},
+		{Name: "parts", Type: RecordFieldType, Repeated: true, Schema: Schema{{Name: "city", Type: StringFieldType}}},
+		{Name: "absent", Type: StringFieldType},
+		{Name: "doc", Type: JSONFieldType},
+		{Name: "span", Type: IntervalFieldType},
+		{Name: "loc", Type: GeographyFieldType},
+	}
+	md, _, err := storageRowDescriptor(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode := func(b []byte) string {
+		m := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(b, m); err != nil {
+			t.Fatal(err)
+		}
+		js, err := protojson.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Normalize the unstable spacing of protojson.
+		return strings.Join(strings.Fields(string(js)), "")
+	}
+
+	s := &storageRowStruct{
+		Name:  "a",
+		N:     NullInt64{Int64: 7, Valid: true},
+		F:     1.5,
+		OK:    true,
+		Blob:  []byte("xy"),
+		When:  time.Date(2022, 3, 1, 0, 0, 1, 500000000, time.UTC),
+		Day:   civil.Date{Year: 1970, Month: 1, Day: 11},
+		Clock: civil.Time{Hour: 1, Minute: 2, Second: 3, Nanosecond: 4000},
+		Stamp: civil.DateTime{Date: civil.Date{Year: 2022, Month: 3, Day: 1}, Time: civil.Time{Hour: 4}},
+		Price: big.NewRat(3, 2),
+		Tags:  []string{"x", "y"},
+		Addr:  &storageRowAddr{City: "c"},
+		Parts: []storageRowAddr{{City: "p1"}, {City: "p2"}},
+	}
+	saver, _, err := toValueSaver(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, _, err := saver.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := encodeStorageRow(md, schema, row, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"a","n":"7","f":1.5,"ok":true,"blob":"eHk=","when":"1646092801500000","day":10,` +
+		`"clock":"01:02:03.000004","stamp":"2022-03-0104:00:00","price":"1.500000000",` +
+		`"tags":["x","y"],` +
+		`"addr":{"city":"c"},"parts":[{"city":"p1"},{"city":"p2"}]}`
+	if got := decode(b); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// Inferred schemas never contain BIGNUMERIC, so such values come from maps.
+	b, err = encodeStorageRow(md, schema, map[string]Value{"name": "a", "big": big.NewRat(1, 4)}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decode(b), `{"name":"a","big":"0.25000000000000000000000000000000000000"}`; got != want {
+		t.Errorf("BIGNUMERIC: got %s, want %s", got, want)
+	}
+
+	b, err = encodeStorageRow(md, schema, map[string]Value{"name": "a", "doc": json.RawMessage(`{"k":1}`), "span": &IntervalValue{Days: 2}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decode(b), `{"name":"a","doc":"{\"k\":1}","span":"0-020:0:0"}`; got != want {
+		t.Errorf("JSON and INTERVAL: got %s, want %s", got, want)
+	}
+
+	b, err = encodeStorageRow(md, schema, map[string]Value{"name": "a", "loc": Geography{"POINT(1 2)"}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decode(b), `{"name":"a","loc":"POINT(12)"}`; got != want {
+		t.Errorf("GEOGRAPHY: got %s, want %s", got, want)
+	}
+
+	// Values from a ValuesSaver, with a field missing from the table.
+	vs := &ValuesSaver{
+		Schema: Schema{{Name: "Name", Type: StringFieldType}, {Name: "extra", Type: StringFieldType}},
+		Row:    []Value{"b", "z"},
+	}
+	row, _, err = vs.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := encodeStorageRow(md, schema, row, false); err == nil || !strings.Contains(err.Error(), "extra") {
+		t.Errorf("unknown field: got error %v, want one naming the field", err)
+	}
+	b, err = encodeStorageRow(md, schema, row, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decode(b), `{"name":"b"}`; got != want {
+		t.Errorf("ignoring unknown values: got %s, want %s", got, want)
+	}
+
+	for _, test := range []struct {
+		desc string
+		row  map[string]Value
+	}{
+		{"missing required field", map[string]Value{"n": 1}},
+		{"wrong type", map[string]Value{"name": "a", "n": "1"}},
+		{"scalar for repeated field", map[string]Value{"name": "a", "tags": "x"}},
+		{"NULL in repeated field", map[string]Value{"name": "a", "tags": []Value{"x", nil}}},
+		{"bad record", map[string]Value{"name": "a", "addr": "c"}},
+	} {
+		if _, err := encodeStorageRow(md, schema, test.row, false); err == nil {
+			t.Errorf("%s: got nil error, want non-nil", test.desc)
+		}
+	}
+}
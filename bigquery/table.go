@@ -97,6 +97,16 @@ type TableMetadata struct {
 	// Information about a table stored outside of BigQuery.
 	ExternalDataConfig *ExternalDataConfig
 
+	// MaxStaleness is how stale the data of the table may be when it is
+	// queried. For a BigLake table, it is how old the cached metadata of its
+	// files may be, and it must be set for ExternalDataConfig.MetadataCacheMode
+	// to take effect. It can only be set when a table is created, and is not
+	// reported by Table.Metadata.
+	//
+	// MaxStaleness cannot be set for a view. The staleness of a materialized
+	// view is set with MaterializedViewDefinition.MaxStaleness instead.
+	MaxStaleness *IntervalValue
+
 	// Custom encryption configuration (e.g., Cloud KMS keys).
 	EncryptionConfig *EncryptionConfig
 
@@ -202,6 +212,22 @@ type MaterializedViewDefinition struct {
 	// RefreshInterval defines the maximum frequency, in millisecond precision,
 	// at which this this materialized view will be refreshed.
 	RefreshInterval time.Duration
+
+	// MaxStaleness is the maximum staleness of data that queries of the
+	// materialized view may return. If the view was refreshed within this
+	// interval, queries read it without reading the base tables.
+	//
+	// The BigQuery API does not support this option, so a materialized view
+	// with MaxStaleness or AllowNonIncrementalDefinition is created with a
+	// CREATE MATERIALIZED VIEW statement, and Table.Metadata does not report
+	// either field. Use Table.SetMaterializedViewMaxStaleness to change it.
+	MaxStaleness *IntervalValue
+
+	// AllowNonIncrementalDefinition allows the query of the materialized view
+	// to use SQL that cannot be refreshed incrementally, such as outer joins
+	// and analytic functions. Such a view must have a MaxStaleness, and it can
+	// be set only when the view is created.
+	AllowNonIncrementalDefinition bool
 }
 
 func (mvd *MaterializedViewDefinition) toBQ() *bq.MaterializedViewDefinition {
@@ -528,6 +554,12 @@ func (t *Table) Create(ctx context.Context, tm *TableMetadata) (err error) {
 	ctx = trace.StartSpan(ctx, "cloud.google.com/go/bigquery.Table.Create")
 	defer func() { trace.EndSpan(ctx, err) }()
 
+	if tm != nil && tm.MaterializedView.needsDDL() {
+		return t.createMaterializedView(ctx, tm)
+	}
+	if tm.isObjectTable() {
+		return t.createObjectTable(ctx, tm)
+	}
 	table, err := tm.toBQ()
 	if err != nil {
 		return err
@@ -539,8 +571,13 @@ func (t *Table) Create(ctx context.Context, tm *TableMetadata) (err error) {
 	}
 	req := t.c.bqs.Tables.Insert(t.ProjectID, t.DatasetID, table).Context(ctx)
 	setClientHeader(req.Header())
-	_, err = req.Do()
-	return err
+	if _, err = req.Do(); err != nil {
+		return err
+	}
+	if tm.needsCacheOptions() {
+		return t.setCacheOptions(ctx, tm)
+	}
+	return nil
 }
 
 func (tm *TableMetadata) toBQ() (*bq.Table, error) {
@@ -551,6 +588,9 @@ func (tm *TableMetadata) toBQ() (*bq.Table, error) {
 	if tm.Schema != nil && tm.ViewQuery != "" {
 		return nil, errors.New("bigquery: provide Schema or ViewQuery, not both")
 	}
+	if tm.MaxStaleness != nil && tm.isView() {
+		return nil, errors.New("bigquery: MaxStaleness cannot be set for a view; use MaterializedViewDefinition.MaxStaleness for a materialized view")
+	}
 	t.FriendlyName = tm.Name
 	t.Description = tm.Description
 	t.Labels = tm.Labels
@@ -703,7 +743,7 @@ func (t *Table) Delete(ctx context.Context) (err error) {
 
 // Read fetches the contents of the table.
 func (t *Table) Read(ctx context.Context) *RowIterator {
-	return t.read(ctx, fetchPage)
+	return t.read(ctx, t.c.readPageFetcher())
 }
 
 func (t *Table) read(ctx context.Context, pf pageFetcher) *RowIterator {
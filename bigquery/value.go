@@ -16,6 +16,7 @@ package bigquery
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -187,6 +188,23 @@ func setBytes(v reflect.Value, x interface{}) error {
 	return nil
 }
 
+func setJSON(v reflect.Value, x interface{}) error {
+	if x == nil {
+		if v.Kind() == reflect.String {
+			return errNoNulls
+		}
+		v.SetBytes(nil)
+		return nil
+	}
+	raw := x.(json.RawMessage)
+	if v.Kind() == reflect.String {
+		v.SetString(string(raw))
+	} else {
+		v.SetBytes(raw)
+	}
+	return nil
+}
+
 func setNull(v reflect.Value, x interface{}, build func() interface{}) error {
 	if x == nil {
 		v.Set(reflect.Zero(v.Type()))
@@ -308,6 +326,20 @@ func determineSetFunc(ftype reflect.Type, stype FieldType) setFunc {
 				})
 			}
 		}
+		if ftype == typeOfGeographyPtr {
+			return func(v reflect.Value, x interface{}) error {
+				return setNull(v, x, func() interface{} { return &Geography{WKT: x.(string)} })
+			}
+		}
+		if ftype == typeOfGeography {
+			return func(v reflect.Value, x interface{}) error {
+				if x == nil {
+					return errNoNulls
+				}
+				v.Set(reflect.ValueOf(Geography{WKT: x.(string)}))
+				return nil
+			}
+		}
 
 	case BytesFieldType:
 		if ftype == typeOfByteSlice {
@@ -414,6 +446,27 @@ func determineSetFunc(ftype reflect.Type, stype FieldType) setFunc {
 				return setNull(v, x, func() interface{} { return x.(*big.Rat) })
 			}
 		}
+
+	case JSONFieldType:
+		if ftype == typeOfRawMessage || ftype.Kind() == reflect.String {
+			return setJSON
+		}
+
+	case IntervalFieldType:
+		if ftype == typeOfIntervalPtr {
+			return func(v reflect.Value, x interface{}) error {
+				return setNull(v, x, func() interface{} { return x.(*IntervalValue) })
+			}
+		}
+		if ftype == typeOfInterval {
+			return func(v reflect.Value, x interface{}) error {
+				if x == nil {
+					return errNoNulls
+				}
+				v.Set(reflect.ValueOf(*x.(*IntervalValue)))
+				return nil
+			}
+		}
 	}
 	return nil
 }
@@ -699,7 +752,8 @@ func structFieldToUploadValue(vfield reflect.Value, schemaField *FieldSchema) (i
 }
 
 func toUploadValue(val interface{}, fs *FieldSchema) interface{} {
-	if fs.Type == TimeFieldType || fs.Type == DateTimeFieldType || fs.Type == NumericFieldType || fs.Type == BigNumericFieldType {
+	switch fs.Type {
+	case TimeFieldType, DateTimeFieldType, NumericFieldType, BigNumericFieldType, JSONFieldType, IntervalFieldType, GeographyFieldType:
 		return toUploadValueReflect(reflect.ValueOf(val), fs)
 	}
 	return val
@@ -735,6 +789,53 @@ func toUploadValueReflect(v reflect.Value, fs *FieldSchema) interface{} {
 		return formatUploadValue(v, fs, func(v reflect.Value) string {
 			return BigNumericString(v.Interface().(*big.Rat))
 		})
+	case JSONFieldType:
+		// JSON values are sent as strings, so that they are not mistaken
+		// for records.
+		if raw, ok := v.Interface().(json.RawMessage); ok && raw == nil {
+			return nil
+		}
+		return formatUploadValue(v, fs, func(v reflect.Value) string {
+			switch x := v.Interface().(type) {
+			case json.RawMessage:
+				return string(x)
+			case []byte:
+				return string(x)
+			}
+			return fmt.Sprint(v.Interface())
+		})
+	case IntervalFieldType:
+		if iv, ok := v.Interface().(*IntervalValue); ok && iv == nil {
+			return nil
+		}
+		return formatUploadValue(v, fs, func(v reflect.Value) string {
+			switch x := v.Interface().(type) {
+			case IntervalValue:
+				return x.String()
+			case *IntervalValue:
+				return x.String()
+			}
+			return fmt.Sprint(v.Interface())
+		})
+	case GeographyFieldType:
+		if !v.IsValid() {
+			return nil
+		}
+		switch x := v.Interface().(type) {
+		case Geography:
+			return x.WKT
+		case *Geography:
+			if x == nil {
+				return nil
+			}
+			return x.WKT
+		}
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem() == typeOfGeography {
+			return formatUploadValue(v, fs, func(v reflect.Value) string {
+				return v.Interface().(Geography).WKT
+			})
+		}
+		fallthrough
 	default:
 		if !fs.Repeated || v.Len() > 0 {
 			return v.Interface()
@@ -947,6 +1048,10 @@ func convertBasicType(val string, typ FieldType) (Value, error) {
 		return Value(r), nil
 	case GeographyFieldType:
 		return val, nil
+	case JSONFieldType:
+		return json.RawMessage(val), nil
+	case IntervalFieldType:
+		return ParseInterval(val)
 	default:
 		return nil, fmt.Errorf("unrecognized type: %s", typ)
 	}
@@ -16,6 +16,7 @@ package bigquery
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
@@ -38,6 +39,8 @@ func TestConvertBasicValues(t *testing.T) {
 		{Type: NumericFieldType},
 		{Type: BigNumericFieldType},
 		{Type: GeographyFieldType},
+		{Type: JSONFieldType},
+		{Type: IntervalFieldType},
 	}
 	row := &bq.TableRow{
 		F: []*bq.TableCell{
@@ -49,6 +52,8 @@ func TestConvertBasicValues(t *testing.T) {
 			{V: "123.123456789"},
 			{V: "99999999999999999999999999999999999999.99999999999999999999999999999999999999"},
 			{V: testGeography},
+			{V: `{"a":[1,2]}`},
+			{V: "1-2 -3 4:5:6.7"},
 		},
 	}
 	got, err := convertRow(row, schema)
@@ -58,7 +63,8 @@ func TestConvertBasicValues(t *testing.T) {
 
 	bigRatVal := new(big.Rat)
 	bigRatVal.SetString("99999999999999999999999999999999999999.99999999999999999999999999999999999999")
-	want := []Value{"a", int64(1), 1.2, true, []byte("foo"), big.NewRat(123123456789, 1e9), bigRatVal, testGeography}
+	want := []Value{"a", int64(1), 1.2, true, []byte("foo"), big.NewRat(123123456789, 1e9), bigRatVal, testGeography,
+		json.RawMessage(`{"a":[1,2]}`), &IntervalValue{Years: 1, Months: 2, Days: -3, Hours: 4, Minutes: 5, Seconds: 6, SubSecondNanos: 700000000}}
 	if !testutil.Equal(got, want) {
 		t.Errorf("converting basic values: got:\n%v\nwant:\n%v", got, want)
 	}
@@ -614,6 +620,10 @@ func TestStructSaver(t *testing.T) {
 		{Name: "bnr", Type: BigNumericFieldType, Repeated: true},
 		{Name: "g", Type: GeographyFieldType, Required: false},
 		{Name: "gr", Type: GeographyFieldType, Repeated: true},
+		{Name: "j", Type: JSONFieldType, Required: false},
+		{Name: "jr", Type: JSONFieldType, Repeated: true},
+		{Name: "iv", Type: IntervalFieldType, Required: false},
+		{Name: "ivr", Type: IntervalFieldType, Repeated: true},
 	}
 
 	type (
@@ -632,6 +642,10 @@ func TestStructSaver(t *testing.T) {
 			BNR     []*big.Rat
 			G       NullGeography
 			GR      []string // Repeated Geography
+			J       json.RawMessage
+			JR      []string // Repeated JSON
+			IV      *IntervalValue
+			IVR     []IntervalValue
 		}
 	)
 
@@ -669,6 +683,10 @@ func TestStructSaver(t *testing.T) {
 		BNR:     []*big.Rat{big.NewRat(1, 3), big.NewRat(1, 2)},
 		G:       NullGeography{Valid: true, GeographyVal: "POINT(-122.350220 47.649154)"},
 		GR:      []string{"POINT(-122.350220 47.649154)", "POINT(-122.198939 47.669865)"},
+		J:       json.RawMessage(`{"a":1}`),
+		JR:      []string{`[1]`, `"x"`},
+		IV:      &IntervalValue{Days: 1},
+		IVR:     []IntervalValue{{Years: -1}, {Seconds: 1, SubSecondNanos: 500000000}},
 	}
 	want := map[string]Value{
 		"s":       "x",
@@ -684,6 +702,10 @@ func TestStructSaver(t *testing.T) {
 		"bnr":     []string{"0.33333333333333333333333333333333333333", "0.50000000000000000000000000000000000000"},
 		"g":       NullGeography{Valid: true, GeographyVal: "POINT(-122.350220 47.649154)"},
 		"gr":      []string{"POINT(-122.350220 47.649154)", "POINT(-122.198939 47.669865)"},
+		"j":       `{"a":1}`,
+		"jr":      []string{`[1]`, `"x"`},
+		"iv":      "0-0 1 0:0:0",
+		"ivr":     []string{"-1-0 0 0:0:0", "0-0 0 0:0:1.5"},
 	}
 	check("all values", in, want)
 	check("all values, ptr", &in, want)
@@ -1147,6 +1169,104 @@ func TestStructLoaderNullable(t *testing.T) {
 	}
 }
 
+func TestStructLoaderJSONAndInterval(t *testing.T) {
+	schema := Schema{
+		{Name: "J", Type: JSONFieldType},
+		{Name: "JS", Type: JSONFieldType},
+		{Name: "I", Type: IntervalFieldType},
+		{Name: "IP", Type: IntervalFieldType},
+	}
+	type T struct {
+		J  json.RawMessage
+		JS string
+		I  IntervalValue
+		IP *IntervalValue
+	}
+	var ts T
+	iv := &IntervalValue{Years: 1, Hours: -2}
+	mustLoad(t, &ts, schema, []Value{json.RawMessage(`{"a":1}`), json.RawMessage(`[1]`), iv, iv})
+	want := T{J: json.RawMessage(`{"a":1}`), JS: `[1]`, I: *iv, IP: iv}
+	if diff := testutil.Diff(ts, want); diff != "" {
+		t.Error(diff)
+	}
+
+	mustLoad(t, &ts, schema, []Value{nil, json.RawMessage(`[1]`), iv, nil})
+	want = T{JS: `[1]`, I: *iv}
+	if diff := testutil.Diff(ts, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestStructLoaderGeography(t *testing.T) {
+	schema := Schema{
+		{Name: "G", Type: GeographyFieldType},
+		{Name: "GP", Type: GeographyFieldType},
+		{Name: "GR", Type: GeographyFieldType, Repeated: true},
+	}
+	type T struct {
+		G  Geography
+		GP *Geography
+		GR []Geography
+	}
+	var ts T
+	const p1, p2 = "POINT(1 2)", "POINT(3 4)"
+	mustLoad(t, &ts, schema, []Value{p1, p2, []Value{p1, p2}})
+	want := T{G: Geography{p1}, GP: &Geography{p2}, GR: []Geography{{p1}, {p2}}}
+	if diff := testutil.Diff(ts, want); diff != "" {
+		t.Error(diff)
+	}
+
+	mustLoad(t, &ts, schema, []Value{p1, nil, []Value{}})
+	want = T{G: Geography{p1}, GR: []Geography{}}
+	if diff := testutil.Diff(ts, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestStructSaverGeography(t *testing.T) {
+	schema := Schema{
+		{Name: "g", Type: GeographyFieldType},
+		{Name: "gp", Type: GeographyFieldType},
+		{Name: "gn", Type: GeographyFieldType},
+		{Name: "gr", Type: GeographyFieldType, Repeated: true},
+	}
+	type T struct {
+		G  Geography
+		GP *Geography
+		GN *Geography
+		GR []Geography
+	}
+	ss := StructSaver{
+		Schema: schema,
+		Struct: T{G: Geography{"POINT(1 2)"}, GP: &Geography{"POINT(3 4)"}, GR: []Geography{{"POINT(5 6)"}}},
+	}
+	got, _, err := ss.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Value{
+		"g":  "POINT(1 2)",
+		"gp": "POINT(3 4)",
+		"gr": []string{"POINT(5 6)"},
+	}
+	if diff := testutil.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+
+	vs := ValuesSaver{
+		Schema: schema,
+		Row:    []Value{Geography{"POINT(1 2)"}, (*Geography)(nil), "POINT(3 4)", []Geography{}},
+	}
+	gotVals, _, err := vs.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]Value{"g": "POINT(1 2)", "gp": nil, "gn": "POINT(3 4)", "gr": nil}
+	if diff := testutil.Diff(gotVals, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
 func TestStructLoaderOverflow(t *testing.T) {
 	type S struct {
 		I int16
@@ -1250,16 +1370,20 @@ func TestStructLoaderErrors(t *testing.T) {
 		{Name: "b", Type: BooleanFieldType},
 		{Name: "s", Type: StringFieldType},
 		{Name: "d", Type: DateFieldType},
+		{Name: "j", Type: JSONFieldType},
+		{Name: "iv", Type: IntervalFieldType},
 		{Name: "r", Type: RecordFieldType, Schema: Schema{{Name: "X", Type: IntegerFieldType}}},
 	}
 	type s struct {
-		I int
-		F float64
-		B bool
-		S string
-		D civil.Date
-	}
-	vals := []Value{int64(0), 0.0, false, "", testDate}
+		I  int
+		F  float64
+		B  bool
+		S  string
+		D  civil.Date
+		J  string
+		IV IntervalValue
+	}
+	vals := []Value{int64(0), 0.0, false, "", testDate, json.RawMessage("{}"), &IntervalValue{}}
 	mustLoad(t, &s{}, schema, vals)
 	for i, e := range vals {
 		vals[i] = nil
@@ -0,0 +1,122 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheConfig configures the cache of a Client. See WithCache.
+type CacheConfig struct {
+	// TTL is how long a value is cached for. If TTL is zero or negative,
+	// values are only cached for the suffixes in KeyTTL.
+	TTL time.Duration
+
+	// KeyTTL overrides TTL for individual suffixes, such as
+	// "project/project-id". A zero or negative duration disables caching
+	// for that suffix.
+	KeyTTL map[string]time.Duration
+}
+
+// WithCache returns a ClientOption that makes the Client cache the values it
+// fetches from the metadata service, so repeated calls such as Zone do not
+// each make a request.
+//
+// Only successful responses are cached. Long-polling calls, such as Subscribe
+// and Watch, always go to the metadata service. Use Client.Invalidate or
+// Client.InvalidateAll to discard cached values early.
+func WithCache(cfg CacheConfig) ClientOption {
+	return clientOptionFunc(func(c *Client) {
+		keyTTL := make(map[string]time.Duration, len(cfg.KeyTTL))
+		for k, ttl := range cfg.KeyTTL {
+			keyTTL[strings.TrimLeft(k, "/")] = ttl
+		}
+		c.cache = &cache{
+			ttl:     cfg.TTL,
+			keyTTL:  keyTTL,
+			entries: map[string]cacheEntry{},
+			now:     time.Now,
+		}
+	})
+}
+
+// Invalidate discards the cached value for suffix, if any.
+func (c *Client) Invalidate(suffix string) {
+	if c.cache != nil {
+		c.cache.delete(strings.TrimLeft(suffix, "/"))
+	}
+}
+
+// InvalidateAll discards all cached values.
+func (c *Client) InvalidateAll() {
+	if c.cache != nil {
+		c.cache.clear()
+	}
+}
+
+type cache struct {
+	ttl    time.Duration
+	keyTTL map[string]time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	v       string
+	expires time.Time
+}
+
+func (c *cache) get(suffix string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[suffix]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, suffix)
+		return "", false
+	}
+	return e.v, true
+}
+
+func (c *cache) put(suffix, v string) {
+	ttl, ok := c.keyTTL[suffix]
+	if !ok {
+		ttl = c.ttl
+	}
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[suffix] = cacheEntry{v: v, expires: c.now().Add(ttl)}
+}
+
+func (c *cache) delete(suffix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, suffix)
+}
+
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]cacheEntry{}
+}
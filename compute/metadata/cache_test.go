@@ -0,0 +1,88 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingTransport answers every request with the number of requests it
+// has received so far.
+type countingTransport struct {
+	calls int
+}
+
+func (ct *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.calls++
+	body := ioutil.NopCloser(strings.NewReader(fmt.Sprint(ct.calls)))
+	return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+}
+
+func TestCache(t *testing.T) {
+	ct := &countingTransport{}
+	c := NewClient(&http.Client{Transport: ct}, WithCache(CacheConfig{
+		TTL: time.Minute,
+		KeyTTL: map[string]time.Duration{
+			"/instance/long":    time.Hour,
+			"instance/uncached": 0,
+		},
+	}))
+	now := time.Now()
+	c.cache.now = func() time.Time { return now }
+
+	get := func(suffix, want string) {
+		t.Helper()
+		got, err := c.Get(suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Get(%q) = %q, want %q", suffix, got, want)
+		}
+	}
+
+	get("instance/zone", "1")
+	get("/instance/zone", "1")
+	get("instance/long", "2")
+	get("instance/uncached", "3")
+	get("instance/uncached", "4")
+
+	now = now.Add(2 * time.Minute)
+	get("instance/zone", "5")
+	get("instance/long", "2")
+
+	c.Invalidate("instance/zone")
+	get("instance/zone", "6")
+
+	c.InvalidateAll()
+	get("instance/long", "7")
+	get("instance/zone", "8")
+}
+
+func TestCache_Disabled(t *testing.T) {
+	ct := &countingTransport{}
+	c := NewClient(&http.Client{Transport: ct})
+	c.Get("instance/zone")
+	c.Get("instance/zone")
+	c.Invalidate("instance/zone")
+	if ct.calls != 2 {
+		t.Errorf("got %d requests, want 2", ct.calls)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"strings"
+)
+
+// List calls Client.List on the default client.
+func List(ctx context.Context, dir string) ([]string, error) { return defaultClient.List(ctx, dir) }
+
+// GetRecursive calls Client.GetRecursive on the default client.
+func GetRecursive(ctx context.Context, dir string) (interface{}, error) {
+	return defaultClient.GetRecursive(ctx, dir)
+}
+
+// List returns the entries of a metadata directory, such as
+// "instance/attributes". Entries that are themselves directories end with a
+// slash, as in "network-interfaces/".
+//
+// If the directory is not defined, the returned error will be of type
+// NotDefinedError.
+func (c *Client) List(ctx context.Context, dir string) ([]string, error) {
+	s, err := c.GetWithContext(ctx, dirSuffix(dir))
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	return entries, nil
+}
+
+// GetRecursive returns the contents of a metadata directory, such as
+// "instance/network-interfaces", and all of its subdirectories as a tree, as
+// decoded by encoding/json. Directories with named entries are represented as
+// map[string]interface{}, and directories with numbered entries, such as
+// "instance/network-interfaces" itself, as []interface{}; other values are
+// strings, float64s or bools.
+//
+// If the directory is not defined, the returned error will be of type
+// NotDefinedError.
+func (c *Client) GetRecursive(ctx context.Context, dir string) (interface{}, error) {
+	var tree interface{}
+	if err := c.getJSON(ctx, dirSuffix(dir)+"?recursive=true&alt=json", &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// dirSuffix returns dir with exactly one trailing slash.
+func dirSuffix(dir string) string {
+	return strings.TrimRight(dir, "/") + "/"
+}
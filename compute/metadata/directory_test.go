@@ -0,0 +1,107 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "entries", body: "0/\n1/\n", want: []string{"0/", "1/"}},
+		{name: "empty", body: "", want: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotPath string
+			c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				fmt.Fprint(w, tc.body)
+			}))
+			got, err := c.List(context.Background(), "instance/network-interfaces")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "/computeMetadata/v1/instance/network-interfaces/"; gotPath != want {
+				t.Errorf("got path %q, want %q", gotPath, want)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("List mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetRecursive(t *testing.T) {
+	var gotURL string
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		fmt.Fprint(w, `{"default": {"aliases": ["default"], "email": "sa@example.com"}}`)
+	}))
+	got, err := c.GetRecursive(context.Background(), "instance/service-accounts/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/computeMetadata/v1/instance/service-accounts/?recursive=true&alt=json"; gotURL != want {
+		t.Errorf("got URL %q, want %q", gotURL, want)
+	}
+	want := map[string]interface{}{
+		"default": map[string]interface{}{
+			"aliases": []interface{}{"default"},
+			"email":   "sa@example.com",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetRecursive mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetRecursive_List(t *testing.T) {
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"ip": "10.0.0.2", "accessConfigs": [{"type": "ONE_TO_ONE_NAT"}]}]`)
+	}))
+	got, err := c.GetRecursive(context.Background(), "instance/network-interfaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		map[string]interface{}{
+			"ip": "10.0.0.2",
+			"accessConfigs": []interface{}{
+				map[string]interface{}{"type": "ONE_TO_ONE_NAT"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetRecursive mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetRecursive_NotDefined(t *testing.T) {
+	c := withTestServer(t, http.NotFoundHandler())
+	_, err := c.GetRecursive(context.Background(), "instance/nope")
+	if _, ok := err.(NotDefinedError); !ok {
+		t.Errorf("got error %v, want NotDefinedError", err)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import "context"
+
+// GetWithETag calls Client.GetWithETag on the default client.
+func GetWithETag(ctx context.Context, suffix, lastETag string) (value, etag string, notModified bool, err error) {
+	return defaultClient.GetWithETag(ctx, suffix, lastETag)
+}
+
+// GetWithETag is like GetWithContext, but also returns the ETag of the value.
+// If lastETag is not empty, the request is conditional: if the value still
+// has the ETag lastETag, GetWithETag returns an empty value, lastETag and
+// notModified true, so pollers need not process an unchanged payload again.
+//
+// GetWithETag always makes a request; it does not use the Client's cache.
+func (c *Client) GetWithETag(ctx context.Context, suffix, lastETag string) (value, etag string, notModified bool, err error) {
+	return c.getConditional(ctx, suffix, lastETag)
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestGetWithETag(t *testing.T) {
+	tests := []struct {
+		name string
+		// honorIfNoneMatch makes the server answer 304 to matching
+		// conditional requests instead of sending the value again.
+		honorIfNoneMatch bool
+		current          string
+		lastETag         string
+		wantValue        string
+		wantETag         string
+		wantNotModified  bool
+	}{
+		{
+			name:      "unconditional",
+			current:   "e1",
+			wantValue: "payload-e1",
+			wantETag:  "e1",
+		},
+		{
+			name:      "modified",
+			current:   "e2",
+			lastETag:  "e1",
+			wantValue: "payload-e2",
+			wantETag:  "e2",
+		},
+		{
+			name:             "not modified, 304",
+			honorIfNoneMatch: true,
+			current:          "e1",
+			lastETag:         "e1",
+			wantETag:         "e1",
+			wantNotModified:  true,
+		},
+		{
+			name:            "not modified, same ETag",
+			current:         "e1",
+			lastETag:        "e1",
+			wantETag:        "e1",
+			wantNotModified: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("If-None-Match"); got != tc.lastETag {
+					t.Errorf("got If-None-Match %q, want %q", got, tc.lastETag)
+				}
+				w.Header().Set("Etag", tc.current)
+				if tc.honorIfNoneMatch && r.Header.Get("If-None-Match") == tc.current {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				fmt.Fprint(w, "payload-"+tc.current)
+			}))
+			v, etag, notModified, err := c.GetWithETag(context.Background(), "instance/attributes/config", tc.lastETag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tc.wantValue || etag != tc.wantETag || notModified != tc.wantNotModified {
+				t.Errorf("GetWithETag() = %q, %q, %v; want %q, %q, %v", v, etag, notModified, tc.wantValue, tc.wantETag, tc.wantNotModified)
+			}
+		})
+	}
+}
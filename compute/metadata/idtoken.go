@@ -0,0 +1,150 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// idTokenRefreshWindow is how long before its expiry a cached identity token
+// is refreshed.
+const idTokenRefreshWindow = 5 * time.Minute
+
+// idTokenFetchTimeout bounds a shared identity token fetch, which does not
+// run on the context of any one caller.
+const idTokenFetchTimeout = time.Minute
+
+// timeNow is a variable so tests can control the clock.
+var timeNow = time.Now
+
+type idToken struct {
+	token  string
+	expiry time.Time
+}
+
+// idTokenFetch is a fetch of an identity token that callers asking for the
+// same audience wait for, instead of fetching their own.
+type idTokenFetch struct {
+	done chan struct{} // closed once tok and err are set
+	tok  idToken
+	err  error
+}
+
+// IDToken calls Client.IDToken on the default client.
+func IDToken(ctx context.Context, audience string) (string, error) {
+	return defaultClient.IDToken(ctx, audience)
+}
+
+// IDToken returns an OpenID Connect identity token for the instance's default
+// service account, with its audience set to audience. Such tokens are used to
+// call services like Cloud Run and Identity-Aware Proxy.
+//
+// Tokens are cached per audience, and a new token is fetched when the cached
+// one is about to expire. Concurrent calls for the same audience share a
+// single fetch.
+func (c *Client) IDToken(ctx context.Context, audience string) (string, error) {
+	if audience == "" {
+		return "", errors.New("metadata: IDToken requires a non-empty audience")
+	}
+	// The lock is not held while fetching, so that a slow fetch does not
+	// delay callers that ask for other audiences.
+	c.idTokenMu.Lock()
+	if tok, ok := c.idTokens[audience]; ok && timeNow().Add(idTokenRefreshWindow).Before(tok.expiry) {
+		c.idTokenMu.Unlock()
+		return tok.token, nil
+	}
+	f, ok := c.idTokenFetches[audience]
+	if !ok {
+		f = &idTokenFetch{done: make(chan struct{})}
+		if c.idTokenFetches == nil {
+			c.idTokenFetches = map[string]*idTokenFetch{}
+		}
+		c.idTokenFetches[audience] = f
+		go c.runIDTokenFetch(audience, f)
+	}
+	c.idTokenMu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.tok.token, f.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// runIDTokenFetch fetches a token for audience into f, caches it and closes
+// f.done. The fetch is shared by all the callers waiting on f, so it does not
+// use the context of any of them: a caller that gives up does not fail the
+// others.
+func (c *Client) runIDTokenFetch(audience string, f *idTokenFetch) {
+	ctx, cancel := context.WithTimeout(context.Background(), idTokenFetchTimeout)
+	defer cancel()
+	f.tok, f.err = c.fetchIDToken(ctx, audience)
+
+	c.idTokenMu.Lock()
+	delete(c.idTokenFetches, audience)
+	if f.err == nil {
+		if c.idTokens == nil {
+			c.idTokens = map[string]idToken{}
+		}
+		c.idTokens[audience] = f.tok
+	}
+	c.idTokenMu.Unlock()
+	close(f.done)
+}
+
+// fetchIDToken fetches a new identity token for audience from the metadata
+// server.
+func (c *Client) fetchIDToken(ctx context.Context, audience string) (idToken, error) {
+	s, _, err := c.getETag(ctx, "instance/service-accounts/default/identity?audience="+url.QueryEscape(audience))
+	if err != nil {
+		return idToken{}, err
+	}
+	s = strings.TrimSpace(s)
+	expiry, err := idTokenExpiry(s)
+	if err != nil {
+		return idToken{}, err
+	}
+	return idToken{token: s, expiry: expiry}, nil
+}
+
+// idTokenExpiry returns the expiry time in the "exp" claim of a JWT.
+func idTokenExpiry(tok string) (time.Time, error) {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("metadata: identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("metadata: decoding identity token payload: %v", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("metadata: decoding identity token claims: %v", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("metadata: identity token has no expiry")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
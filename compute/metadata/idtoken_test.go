@@ -0,0 +1,184 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fakeIDToken(aud string, exp time.Time, n int) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	payload := fmt.Sprintf(`{"aud":%q,"exp":%d,"n":%d}`, aud, exp.Unix(), n)
+	return enc([]byte(`{"alg":"RS256"}`)) + "." + enc([]byte(payload)) + "." + enc([]byte("sig"))
+}
+
+func TestIDToken(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	oldNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = oldNow }()
+
+	var calls int
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if got, want := r.URL.Path, "/computeMetadata/v1/instance/service-accounts/default/identity"; got != want {
+			t.Errorf("got path %q, want %q", got, want)
+		}
+		fmt.Fprint(w, fakeIDToken(r.URL.Query().Get("audience"), now.Add(time.Hour), calls))
+	}))
+	ctx := context.Background()
+
+	tok1, err := c.IDToken(ctx, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, _ := c.IDToken(ctx, "https://example.com"); tok != tok1 {
+		t.Errorf("got a new token before expiry")
+	}
+	if tok, _ := c.IDToken(ctx, "https://other.example.com"); tok == tok1 {
+		t.Errorf("got the same token for a different audience")
+	}
+	if calls != 2 {
+		t.Errorf("got %d requests, want 2", calls)
+	}
+
+	// Within the refresh window, a new token is fetched.
+	now = now.Add(time.Hour - idTokenRefreshWindow)
+	tok2, err := c.IDToken(ctx, "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok2 == tok1 {
+		t.Errorf("got the cached token within the refresh window")
+	}
+	if calls != 3 {
+		t.Errorf("got %d requests, want 3", calls)
+	}
+}
+
+func TestIDToken_Concurrent(t *testing.T) {
+	now := time.Now()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := map[string]int{}
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		aud := r.URL.Query().Get("audience")
+		mu.Lock()
+		calls[aud]++
+		mu.Unlock()
+		if aud == "slow" {
+			<-release
+		}
+		fmt.Fprint(w, fakeIDToken(aud, now.Add(time.Hour), 0))
+	}))
+	ctx := context.Background()
+	if _, err := c.IDToken(ctx, "fast"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Callers for the same audience share the fetch in flight.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.IDToken(ctx, "slow"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	// A slow fetch does not block other audiences.
+	done := make(chan error)
+	go func() {
+		_, err := c.IDToken(ctx, "fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("IDToken for a cached audience blocked behind a fetch for another audience")
+	}
+	close(release)
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["slow"] != 1 || calls["fast"] != 1 {
+		t.Errorf("got requests %v, want one per audience", calls)
+	}
+}
+
+func TestIDToken_CanceledCaller(t *testing.T) {
+	now := time.Now()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		fmt.Fprint(w, fakeIDToken("aud", now.Add(time.Hour), 0))
+	}))
+
+	// The first caller starts the fetch, then gives up.
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := c.IDToken(ctx, "aud")
+		first <- err
+	}()
+	<-started
+
+	// A second caller waits for the same fetch with a live context.
+	second := make(chan error)
+	go func() {
+		_, err := c.IDToken(context.Background(), "aud")
+		second <- err
+	}()
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Errorf("canceled caller: got err %v, want %v", err, context.Canceled)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("live caller: got err %v, want nil", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestIDToken_Errors(t *testing.T) {
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "not-a-jwt")
+	}))
+	if _, err := c.IDToken(context.Background(), ""); err == nil {
+		t.Error("IDToken with empty audience: got nil error")
+	}
+	if _, err := c.IDToken(context.Background(), "aud"); err == nil {
+		t.Error("IDToken with malformed token: got nil error")
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Instance is the metadata of the current VM instance, as returned by
+// Client.InstanceMetadata.
+type Instance struct {
+	// ID is the numeric instance ID.
+	ID uint64 `json:"id"`
+	// Name is the instance name.
+	Name string `json:"name"`
+	// Hostname is of the form "<instanceID>.c.<projID>.internal".
+	Hostname    string `json:"hostname"`
+	Description string `json:"description"`
+	// Zone is of the form "projects/<projNum>/zones/<zoneName>".
+	Zone string `json:"zone"`
+	// MachineType is of the form "projects/<projNum>/machineTypes/<type>".
+	MachineType string `json:"machineType"`
+	CPUPlatform string `json:"cpuPlatform"`
+	Image       string `json:"image"`
+	// MaintenanceEvent is the upcoming host maintenance event, or "NONE".
+	MaintenanceEvent string `json:"maintenanceEvent"`
+	// Tags are the user-defined instance tags.
+	Tags []string `json:"tags"`
+	// Attributes are the user-defined instance attributes.
+	Attributes        map[string]string  `json:"attributes"`
+	NetworkInterfaces []NetworkInterface `json:"networkInterfaces"`
+	Disks             []Disk             `json:"disks"`
+	Scheduling        Scheduling         `json:"scheduling"`
+	// ServiceAccounts is keyed by the service account alias or email, so the
+	// instance's main account appears under both "default" and its email.
+	ServiceAccounts map[string]ServiceAccount `json:"serviceAccounts"`
+}
+
+// NetworkInterface describes a network interface of a VM instance.
+type NetworkInterface struct {
+	// IP is the internal IP address of the interface.
+	IP  string `json:"ip"`
+	MAC string `json:"mac"`
+	// Network is of the form "projects/<projNum>/networks/<network>".
+	Network       string         `json:"network"`
+	Gateway       string         `json:"gateway"`
+	Subnetmask    string         `json:"subnetmask"`
+	MTU           int            `json:"mtu"`
+	DNSServers    []string       `json:"dnsServers"`
+	ForwardedIPs  []string       `json:"forwardedIps"`
+	IPAliases     []string       `json:"ipAliases"`
+	AccessConfigs []AccessConfig `json:"accessConfigs"`
+}
+
+// AccessConfig describes an external access configuration of a network
+// interface.
+type AccessConfig struct {
+	// ExternalIP is the external (public) IP address.
+	ExternalIP string `json:"externalIp"`
+	// Type is the access configuration type, such as "ONE_TO_ONE_NAT".
+	Type string `json:"type"`
+}
+
+// Disk describes a disk attached to a VM instance.
+type Disk struct {
+	DeviceName string `json:"deviceName"`
+	Index      int    `json:"index"`
+	// Interface is the disk interface, such as "SCSI" or "NVME".
+	Interface string `json:"interface"`
+	// Mode is "READ_WRITE" or "READ_ONLY".
+	Mode string `json:"mode"`
+	// Type is "PERSISTENT" or "EPHEMERAL".
+	Type string `json:"type"`
+}
+
+// Scheduling describes the scheduling options of a VM instance.
+type Scheduling struct {
+	AutomaticRestart bool
+	// OnHostMaintenance is "MIGRATE" or "TERMINATE".
+	OnHostMaintenance string
+	Preemptible       bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The metadata server reports the
+// boolean fields as the strings "TRUE" and "FALSE".
+func (s *Scheduling) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		AutomaticRestart  string `json:"automaticRestart"`
+		OnHostMaintenance string `json:"onHostMaintenance"`
+		Preemptible       string `json:"preemptible"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = Scheduling{
+		AutomaticRestart:  strings.EqualFold(raw.AutomaticRestart, "TRUE"),
+		OnHostMaintenance: raw.OnHostMaintenance,
+		Preemptible:       strings.EqualFold(raw.Preemptible, "TRUE"),
+	}
+	return nil
+}
+
+// ServiceAccount describes a service account available to a VM instance.
+type ServiceAccount struct {
+	Email   string   `json:"email"`
+	Aliases []string `json:"aliases"`
+	Scopes  []string `json:"scopes"`
+}
+
+// Project is the metadata of the project the current VM instance belongs to,
+// as returned by Client.ProjectMetadata.
+type Project struct {
+	ProjectID        string `json:"projectId"`
+	NumericProjectID int64  `json:"numericProjectId"`
+	// Attributes are the user-defined project attributes.
+	Attributes map[string]string `json:"attributes"`
+}
+
+// InstanceMetadata calls Client.InstanceMetadata on the default client.
+func InstanceMetadata(ctx context.Context) (*Instance, error) {
+	return defaultClient.InstanceMetadata(ctx)
+}
+
+// ProjectMetadata calls Client.ProjectMetadata on the default client.
+func ProjectMetadata(ctx context.Context) (*Project, error) {
+	return defaultClient.ProjectMetadata(ctx)
+}
+
+// InstanceMetadata returns all of the current VM instance's metadata in a
+// single request.
+func (c *Client) InstanceMetadata(ctx context.Context) (*Instance, error) {
+	var inst Instance
+	if err := c.getJSON(ctx, "instance/?recursive=true&alt=json", &inst); err != nil {
+		return nil, err
+	}
+	return &inst, nil
+}
+
+// ProjectMetadata returns all of the current project's metadata in a single
+// request.
+func (c *Client) ProjectMetadata(ctx context.Context) (*Project, error) {
+	var proj Project
+	if err := c.getJSON(ctx, "project/?recursive=true&alt=json", &proj); err != nil {
+		return nil, err
+	}
+	return &proj, nil
+}
+
+// getJSON fetches suffix and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, suffix string, v interface{}) error {
+	j, err := c.getCached(ctx, suffix)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(j), v); err != nil {
+		return fmt.Errorf("metadata: decoding %q: %v", suffix, err)
+	}
+	return nil
+}
+
+// InstanceAttributeJSON calls Client.InstanceAttributeJSON on the default client.
+func InstanceAttributeJSON(ctx context.Context, attr string, v interface{}) error {
+	return defaultClient.InstanceAttributeJSON(ctx, attr, v)
+}
+
+// ProjectAttributeJSON calls Client.ProjectAttributeJSON on the default client.
+func ProjectAttributeJSON(ctx context.Context, attr string, v interface{}) error {
+	return defaultClient.ProjectAttributeJSON(ctx, attr, v)
+}
+
+// InstanceAttributeJSON decodes the JSON value of the provided VM instance
+// attribute into v, as by json.Unmarshal.
+//
+// If the requested attribute is not defined, the returned error will be of
+// type NotDefinedError. If the value is not valid JSON for v, the returned
+// error wraps the error from encoding/json.
+func (c *Client) InstanceAttributeJSON(ctx context.Context, attr string, v interface{}) error {
+	return c.attributeJSON(ctx, "instance", attr, v)
+}
+
+// ProjectAttributeJSON decodes the JSON value of the provided project
+// attribute into v, as by json.Unmarshal.
+//
+// If the requested attribute is not defined, the returned error will be of
+// type NotDefinedError. If the value is not valid JSON for v, the returned
+// error wraps the error from encoding/json.
+func (c *Client) ProjectAttributeJSON(ctx context.Context, attr string, v interface{}) error {
+	return c.attributeJSON(ctx, "project", attr, v)
+}
+
+func (c *Client) attributeJSON(ctx context.Context, scope, attr string, v interface{}) error {
+	s, err := c.GetWithContext(ctx, scope+"/attributes/"+attr)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		return fmt.Errorf("metadata: decoding %s attribute %q: %w", scope, attr, err)
+	}
+	return nil
+}
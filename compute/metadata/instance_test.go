@@ -0,0 +1,184 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const instanceJSON = `{
+  "attributes": {"startup-script": "echo hi"},
+  "cpuPlatform": "Intel Broadwell",
+  "description": "",
+  "disks": [{"deviceName": "persistent-disk-0", "index": 0, "interface": "SCSI", "mode": "READ_WRITE", "type": "PERSISTENT"}],
+  "hostname": "inst.c.proj.internal",
+  "id": 1234567890123456789,
+  "image": "projects/debian-cloud/global/images/debian-11",
+  "machineType": "projects/123/machineTypes/e2-medium",
+  "maintenanceEvent": "NONE",
+  "name": "inst",
+  "networkInterfaces": [{
+    "accessConfigs": [{"externalIp": "203.0.113.1", "type": "ONE_TO_ONE_NAT"}],
+    "dnsServers": ["169.254.169.254"],
+    "forwardedIps": [],
+    "gateway": "10.128.0.1",
+    "ip": "10.128.0.2",
+    "ipAliases": [],
+    "mac": "42:01:0a:80:00:02",
+    "mtu": 1460,
+    "network": "projects/123/networks/default",
+    "subnetmask": "255.255.240.0"
+  }],
+  "preempted": "FALSE",
+  "scheduling": {"automaticRestart": "TRUE", "onHostMaintenance": "MIGRATE", "preemptible": "FALSE"},
+  "serviceAccounts": {
+    "default": {"aliases": ["default"], "email": "sa@proj.iam.gserviceaccount.com", "scopes": ["https://www.googleapis.com/auth/cloud-platform"]}
+  },
+  "tags": ["http-server"],
+  "zone": "projects/123/zones/us-central1-a"
+}`
+
+func TestInstanceMetadata(t *testing.T) {
+	var gotURL string
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		fmt.Fprint(w, instanceJSON)
+	}))
+	inst, err := c.InstanceMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/computeMetadata/v1/instance/?recursive=true&alt=json"; gotURL != want {
+		t.Errorf("got URL %q, want %q", gotURL, want)
+	}
+	want := &Instance{
+		ID:               1234567890123456789,
+		Name:             "inst",
+		Hostname:         "inst.c.proj.internal",
+		Zone:             "projects/123/zones/us-central1-a",
+		MachineType:      "projects/123/machineTypes/e2-medium",
+		CPUPlatform:      "Intel Broadwell",
+		Image:            "projects/debian-cloud/global/images/debian-11",
+		MaintenanceEvent: "NONE",
+		Tags:             []string{"http-server"},
+		Attributes:       map[string]string{"startup-script": "echo hi"},
+		NetworkInterfaces: []NetworkInterface{{
+			IP:            "10.128.0.2",
+			MAC:           "42:01:0a:80:00:02",
+			Network:       "projects/123/networks/default",
+			Gateway:       "10.128.0.1",
+			Subnetmask:    "255.255.240.0",
+			MTU:           1460,
+			DNSServers:    []string{"169.254.169.254"},
+			ForwardedIPs:  []string{},
+			IPAliases:     []string{},
+			AccessConfigs: []AccessConfig{{ExternalIP: "203.0.113.1", Type: "ONE_TO_ONE_NAT"}},
+		}},
+		Disks: []Disk{{DeviceName: "persistent-disk-0", Interface: "SCSI", Mode: "READ_WRITE", Type: "PERSISTENT"}},
+		Scheduling: Scheduling{
+			AutomaticRestart:  true,
+			OnHostMaintenance: "MIGRATE",
+		},
+		ServiceAccounts: map[string]ServiceAccount{
+			"default": {
+				Email:   "sa@proj.iam.gserviceaccount.com",
+				Aliases: []string{"default"},
+				Scopes:  []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, inst); diff != "" {
+		t.Errorf("InstanceMetadata mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProjectMetadata(t *testing.T) {
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"attributes": {"ssh-keys": "k"}, "numericProjectId": 123, "projectId": "proj"}`)
+	}))
+	proj, err := c.ProjectMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Project{
+		ProjectID:        "proj",
+		NumericProjectID: 123,
+		Attributes:       map[string]string{"ssh-keys": "k"},
+	}
+	if diff := cmp.Diff(want, proj); diff != "" {
+		t.Errorf("ProjectMetadata mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInstanceMetadata_BadJSON(t *testing.T) {
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `not json`)
+	}))
+	if _, err := c.InstanceMetadata(context.Background()); err == nil {
+		t.Error("got nil error, want non-nil")
+	}
+}
+
+func TestAttributeJSON(t *testing.T) {
+	attrs := map[string]string{
+		"/computeMetadata/v1/instance/attributes/config": `{"name": "svc", "replicas": 3}`,
+		"/computeMetadata/v1/project/attributes/config":  `{"name": "proj", "replicas": 1}`,
+		"/computeMetadata/v1/instance/attributes/bad":    `{"name": `,
+	}
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := attrs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, v)
+	}))
+	type config struct {
+		Name     string `json:"name"`
+		Replicas int    `json:"replicas"`
+	}
+	ctx := context.Background()
+
+	var got config
+	if err := c.InstanceAttributeJSON(ctx, "config", &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (config{Name: "svc", Replicas: 3}); got != want {
+		t.Errorf("InstanceAttributeJSON got %+v, want %+v", got, want)
+	}
+	if err := c.ProjectAttributeJSON(ctx, "config", &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := (config{Name: "proj", Replicas: 1}); got != want {
+		t.Errorf("ProjectAttributeJSON got %+v, want %+v", got, want)
+	}
+
+	err := c.InstanceAttributeJSON(ctx, "missing", &got)
+	if _, ok := err.(NotDefinedError); !ok {
+		t.Errorf("missing attribute: got error %v, want NotDefinedError", err)
+	}
+	err = c.InstanceAttributeJSON(ctx, "bad", &got)
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("malformed attribute: got error %v, want a *json.SyntaxError", err)
+	}
+}
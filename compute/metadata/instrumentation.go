@@ -0,0 +1,94 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Instrumentation holds callbacks that observe the requests a Client makes
+// to the metadata service. They can be used to record OpenTelemetry spans and
+// metrics, such as request counts, latencies and status codes per path,
+// without this package depending on a telemetry library. See
+// WithInstrumentation, and the internal/examples/metadataotel module in this
+// repository for a bridge to OpenTelemetry.
+//
+// Values served from the Client's cache are not reported.
+type Instrumentation struct {
+	// RequestStarted, if non-nil, is called before each request. The
+	// returned context is used for the request and passed to
+	// RequestFinished, so it can carry a span.
+	RequestStarted func(ctx context.Context, path string) context.Context
+
+	// RequestFinished, if non-nil, is called once each request has
+	// completed, after any retries.
+	RequestFinished func(ctx context.Context, info RequestInfo)
+}
+
+// RequestInfo describes a completed request to the metadata service.
+type RequestInfo struct {
+	// Path is the requested suffix after "/computeMetadata/v1/", without
+	// query parameters.
+	Path string
+	// WaitForChange reports whether the request was a long poll for a
+	// change, such as those made by Subscribe and Watch. Their latency
+	// reflects how long the value stayed unchanged.
+	WaitForChange bool
+	// StatusCode is the HTTP status code of the last attempt, or 0 if no
+	// response was received.
+	StatusCode int
+	// Attempts is the number of attempts made, including retries.
+	Attempts int
+	// Latency is the time taken by all attempts.
+	Latency time.Duration
+	// Err is the error returned for the request, if any.
+	Err error
+}
+
+// WithInstrumentation returns a ClientOption that reports the requests made
+// by the Client to in.
+func WithInstrumentation(in Instrumentation) ClientOption {
+	return clientOptionFunc(func(c *Client) {
+		c.instrumentation = &in
+	})
+}
+
+// start calls RequestStarted for suffix and returns the context to use for
+// the request, along with a function to call with its outcome.
+func (in *Instrumentation) start(ctx context.Context, suffix string) (context.Context, func(status, attempts int, err error)) {
+	path, query := suffix, ""
+	if i := strings.IndexByte(suffix, '?'); i >= 0 {
+		path, query = suffix[:i], suffix[i+1:]
+	}
+	if in.RequestStarted != nil {
+		ctx = in.RequestStarted(ctx, path)
+	}
+	start := time.Now()
+	return ctx, func(status, attempts int, err error) {
+		if in.RequestFinished == nil {
+			return
+		}
+		in.RequestFinished(ctx, RequestInfo{
+			Path:          path,
+			WaitForChange: strings.Contains(query, "wait_for_change=true"),
+			StatusCode:    status,
+			Attempts:      attempts,
+			Latency:       time.Since(start),
+			Err:           err,
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+func TestInstrumentation(t *testing.T) {
+	var (
+		started []string
+		infos   []RequestInfo
+	)
+	in := Instrumentation{
+		RequestStarted: func(ctx context.Context, path string) context.Context {
+			started = append(started, path)
+			return context.WithValue(ctx, ctxKey{}, path)
+		},
+		RequestFinished: func(ctx context.Context, info RequestInfo) {
+			if got := ctx.Value(ctxKey{}); got != info.Path {
+				t.Errorf("RequestFinished got context for %v, want %q", got, info.Path)
+			}
+			infos = append(infos, info)
+		},
+	}
+	ft := &failingTransport{timesToFail: 1, failCode: 503, response: "v"}
+	c := NewClient(&http.Client{Transport: ft},
+		WithInstrumentation(in),
+		WithRetryPolicy(RetryPolicy{InitialBackoff: time.Millisecond}))
+
+	if _, err := c.Get("/instance/zone"); err != nil {
+		t.Fatal(err)
+	}
+	c.Get(waitForChangeSuffix("instance/attributes/foo", "etag"))
+
+	if len(started) != 2 || started[0] != "instance/zone" || started[1] != "instance/attributes/foo" {
+		t.Errorf("RequestStarted got paths %q", started)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("RequestFinished called %d times, want 2", len(infos))
+	}
+	if got := infos[0]; got.StatusCode != 200 || got.Attempts != 2 || got.Err != nil || got.WaitForChange {
+		t.Errorf("got first RequestInfo %+v", got)
+	}
+	if got := infos[1]; !got.WaitForChange {
+		t.Errorf("got second RequestInfo %+v, want WaitForChange", got)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"strings"
+)
+
+// MaintenanceEvent is a host maintenance event reported by the metadata
+// service for the current VM instance.
+type MaintenanceEvent string
+
+const (
+	// MaintenanceNone means no maintenance event is scheduled.
+	MaintenanceNone MaintenanceEvent = "NONE"
+	// MaintenanceMigrate means the instance is about to be live migrated to
+	// another host.
+	MaintenanceMigrate MaintenanceEvent = "MIGRATE_ON_HOST_MAINTENANCE"
+	// MaintenanceTerminate means the instance is about to be terminated for
+	// host maintenance.
+	MaintenanceTerminate MaintenanceEvent = "TERMINATE_ON_HOST_MAINTENANCE"
+)
+
+// SubscribeMaintenanceEvents calls Client.SubscribeMaintenanceEvents on the
+// default client.
+func SubscribeMaintenanceEvents(ctx context.Context, fn func(MaintenanceEvent) error) error {
+	return defaultClient.SubscribeMaintenanceEvents(ctx, fn)
+}
+
+// SubscribeMaintenanceEvents calls fn with the current host maintenance event
+// of the VM instance, and then again each time it changes. The event is
+// MaintenanceNone when no maintenance is scheduled, and returns to it once the
+// maintenance is complete.
+//
+// SubscribeMaintenanceEvents is built on Watch, so it reconnects after errors
+// and blocks until fn returns a non-nil error, which it returns, or until ctx
+// is done, in which case it returns ctx.Err().
+func (c *Client) SubscribeMaintenanceEvents(ctx context.Context, fn func(MaintenanceEvent) error) error {
+	return c.Watch(ctx, "instance/maintenance-event", func(v string, ok bool) error {
+		if !ok {
+			// The endpoint is always defined on GCE; treat a missing value as
+			// no maintenance.
+			return fn(MaintenanceNone)
+		}
+		return fn(MaintenanceEvent(strings.TrimSpace(v)))
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSubscribeMaintenanceEvents(t *testing.T) {
+	c := withTestServer(t, &fakeWatchServer{
+		values:   []string{"NONE\n", "MIGRATE_ON_HOST_MAINTENANCE\n", "NONE\n", "TERMINATE_ON_HOST_MAINTENANCE\n"},
+		failNext: 1,
+	})
+	want := []MaintenanceEvent{MaintenanceNone, MaintenanceMigrate, MaintenanceNone, MaintenanceTerminate}
+	errDone := errors.New("done")
+	var got []MaintenanceEvent
+	err := c.SubscribeMaintenanceEvents(context.Background(), func(e MaintenanceEvent) error {
+		got = append(got, e)
+		if len(got) == len(want) {
+			return errDone
+		}
+		return nil
+	})
+	if err != errDone {
+		t.Fatalf("SubscribeMaintenanceEvents returned %v, want %v", err, errDone)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+}
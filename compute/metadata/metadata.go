@@ -26,7 +26,6 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"runtime"
 	"strings"
@@ -102,16 +101,16 @@ func (suffix NotDefinedError) Error() string {
 	return fmt.Sprintf("metadata: GCE metadata %q not defined", string(suffix))
 }
 
-func (c *cachedValue) get(cl *Client) (v string, err error) {
+func (c *cachedValue) get(ctx context.Context, cl *Client) (v string, err error) {
 	defer c.mu.Unlock()
 	c.mu.Lock()
 	if c.v != "" {
 		return c.v, nil
 	}
 	if c.trim {
-		v, err = cl.getTrimmed(c.k)
+		v, err = cl.getTrimmed(ctx, c.k)
 	} else {
-		v, err = cl.Get(c.k)
+		v, err = cl.GetWithContext(ctx, c.k)
 	}
 	if err == nil {
 		c.v = v
@@ -139,16 +138,26 @@ func testOnGCE() bool {
 	if os.Getenv(metadataHostEnv) != "" {
 		return true
 	}
+	return probeOnGCE(context.Background(), metadataIP, false)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// probeOnGCE reports whether a metadata server answers at host and, unless
+// disableDNS is set, whether metadata.google.internal resolves to the
+// documented metadata server IP.
+func probeOnGCE(ctx context.Context, host string, disableDNS bool) bool {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	resc := make(chan bool, 2)
+	probes := 2
+	if disableDNS {
+		probes = 1
+	}
+	resc := make(chan bool, probes)
 
 	// Try two strategies in parallel.
 	// See https://github.com/googleapis/google-cloud-go/issues/194
 	go func() {
-		req, _ := http.NewRequest("GET", "http://"+metadataIP, nil)
+		req, _ := http.NewRequest("GET", "http://"+host, nil)
 		req.Header.Set("User-Agent", userAgent)
 		res, err := newDefaultHTTPClient().Do(req.WithContext(ctx))
 		if err != nil {
@@ -159,22 +168,24 @@ func testOnGCE() bool {
 		resc <- res.Header.Get("Metadata-Flavor") == "Google"
 	}()
 
-	go func() {
-		resolver := &net.Resolver{}
-		addrs, err := resolver.LookupHost(ctx, "metadata.google.internal")
-		if err != nil || len(addrs) == 0 {
-			resc <- false
-			return
-		}
-		resc <- strsContains(addrs, metadataIP)
-	}()
+	if !disableDNS {
+		go func() {
+			resolver := &net.Resolver{}
+			addrs, err := resolver.LookupHost(ctx, "metadata.google.internal")
+			if err != nil || len(addrs) == 0 {
+				resc <- false
+				return
+			}
+			resc <- strsContains(addrs, metadataIP)
+		}()
+	}
 
 	tryHarder := systemInfoSuggestsGCE()
 	if tryHarder {
 		res := <-resc
-		if res {
+		if res || probes == 1 {
 			// The first strategy succeeded, so let's use it.
-			return true
+			return res
 		}
 		// Wait for either the DNS or metadata server probe to
 		// contradict the other one and say we are running on
@@ -188,6 +199,8 @@ func testOnGCE() bool {
 		case <-timer.C:
 			// Too slow. Who knows what this system is.
 			return false
+		case <-ctx.Done():
+			return false
 		}
 	}
 
@@ -221,60 +234,147 @@ func Subscribe(suffix string, fn func(v string, ok bool) error) error {
 	return defaultClient.Subscribe(suffix, fn)
 }
 
+// SubscribeWithContext calls Client.SubscribeWithContext on the default client.
+func SubscribeWithContext(ctx context.Context, suffix string, fn func(v string, ok bool) error) error {
+	return defaultClient.SubscribeWithContext(ctx, suffix, fn)
+}
+
 // Get calls Client.Get on the default client.
 func Get(suffix string) (string, error) { return defaultClient.Get(suffix) }
 
+// GetWithContext calls Client.GetWithContext on the default client.
+func GetWithContext(ctx context.Context, suffix string) (string, error) {
+	return defaultClient.GetWithContext(ctx, suffix)
+}
+
 // ProjectID returns the current instance's project ID string.
 func ProjectID() (string, error) { return defaultClient.ProjectID() }
 
+// ProjectIDWithContext returns the current instance's project ID string.
+func ProjectIDWithContext(ctx context.Context) (string, error) {
+	return defaultClient.ProjectIDWithContext(ctx)
+}
+
 // NumericProjectID returns the current instance's numeric project ID.
 func NumericProjectID() (string, error) { return defaultClient.NumericProjectID() }
 
+// NumericProjectIDWithContext returns the current instance's numeric project ID.
+func NumericProjectIDWithContext(ctx context.Context) (string, error) {
+	return defaultClient.NumericProjectIDWithContext(ctx)
+}
+
 // InternalIP returns the instance's primary internal IP address.
 func InternalIP() (string, error) { return defaultClient.InternalIP() }
 
+// InternalIPWithContext returns the instance's primary internal IP address.
+func InternalIPWithContext(ctx context.Context) (string, error) {
+	return defaultClient.InternalIPWithContext(ctx)
+}
+
 // ExternalIP returns the instance's primary external (public) IP address.
 func ExternalIP() (string, error) { return defaultClient.ExternalIP() }
 
+// ExternalIPWithContext returns the instance's primary external (public) IP address.
+func ExternalIPWithContext(ctx context.Context) (string, error) {
+	return defaultClient.ExternalIPWithContext(ctx)
+}
+
 // Email calls Client.Email on the default client.
 func Email(serviceAccount string) (string, error) { return defaultClient.Email(serviceAccount) }
 
+// EmailWithContext calls Client.EmailWithContext on the default client.
+func EmailWithContext(ctx context.Context, serviceAccount string) (string, error) {
+	return defaultClient.EmailWithContext(ctx, serviceAccount)
+}
+
 // Hostname returns the instance's hostname. This will be of the form
 // "<instanceID>.c.<projID>.internal".
 func Hostname() (string, error) { return defaultClient.Hostname() }
 
+// HostnameWithContext returns the instance's hostname. This will be of the form
+// "<instanceID>.c.<projID>.internal".
+func HostnameWithContext(ctx context.Context) (string, error) {
+	return defaultClient.HostnameWithContext(ctx)
+}
+
 // InstanceTags returns the list of user-defined instance tags,
 // assigned when initially creating a GCE instance.
 func InstanceTags() ([]string, error) { return defaultClient.InstanceTags() }
 
+// InstanceTagsWithContext returns the list of user-defined instance tags,
+// assigned when initially creating a GCE instance.
+func InstanceTagsWithContext(ctx context.Context) ([]string, error) {
+	return defaultClient.InstanceTagsWithContext(ctx)
+}
+
 // InstanceID returns the current VM's numeric instance ID.
 func InstanceID() (string, error) { return defaultClient.InstanceID() }
 
+// InstanceIDWithContext returns the current VM's numeric instance ID.
+func InstanceIDWithContext(ctx context.Context) (string, error) {
+	return defaultClient.InstanceIDWithContext(ctx)
+}
+
 // InstanceName returns the current VM's instance ID string.
 func InstanceName() (string, error) { return defaultClient.InstanceName() }
 
+// InstanceNameWithContext returns the current VM's instance ID string.
+func InstanceNameWithContext(ctx context.Context) (string, error) {
+	return defaultClient.InstanceNameWithContext(ctx)
+}
+
 // Zone returns the current VM's zone, such as "us-central1-b".
 func Zone() (string, error) { return defaultClient.Zone() }
 
+// ZoneWithContext returns the current VM's zone, such as "us-central1-b".
+func ZoneWithContext(ctx context.Context) (string, error) {
+	return defaultClient.ZoneWithContext(ctx)
+}
+
 // InstanceAttributes calls Client.InstanceAttributes on the default client.
 func InstanceAttributes() ([]string, error) { return defaultClient.InstanceAttributes() }
 
+// InstanceAttributesWithContext calls Client.InstanceAttributesWithContext on the default client.
+func InstanceAttributesWithContext(ctx context.Context) ([]string, error) {
+	return defaultClient.InstanceAttributesWithContext(ctx)
+}
+
 // ProjectAttributes calls Client.ProjectAttributes on the default client.
 func ProjectAttributes() ([]string, error) { return defaultClient.ProjectAttributes() }
 
+// ProjectAttributesWithContext calls Client.ProjectAttributesWithContext on the default client.
+func ProjectAttributesWithContext(ctx context.Context) ([]string, error) {
+	return defaultClient.ProjectAttributesWithContext(ctx)
+}
+
 // InstanceAttributeValue calls Client.InstanceAttributeValue on the default client.
 func InstanceAttributeValue(attr string) (string, error) {
 	return defaultClient.InstanceAttributeValue(attr)
 }
 
+// InstanceAttributeValueWithContext calls Client.InstanceAttributeValueWithContext on the default client.
+func InstanceAttributeValueWithContext(ctx context.Context, attr string) (string, error) {
+	return defaultClient.InstanceAttributeValueWithContext(ctx, attr)
+}
+
 // ProjectAttributeValue calls Client.ProjectAttributeValue on the default client.
 func ProjectAttributeValue(attr string) (string, error) {
 	return defaultClient.ProjectAttributeValue(attr)
 }
 
+// ProjectAttributeValueWithContext calls Client.ProjectAttributeValueWithContext on the default client.
+func ProjectAttributeValueWithContext(ctx context.Context, attr string) (string, error) {
+	return defaultClient.ProjectAttributeValueWithContext(ctx, attr)
+}
+
 // Scopes calls Client.Scopes on the default client.
 func Scopes(serviceAccount string) ([]string, error) { return defaultClient.Scopes(serviceAccount) }
 
+// ScopesWithContext calls Client.ScopesWithContext on the default client.
+func ScopesWithContext(ctx context.Context, serviceAccount string) ([]string, error) {
+	return defaultClient.ScopesWithContext(ctx, serviceAccount)
+}
+
 func strsContains(ss []string, s string) bool {
 	for _, v := range ss {
 		if v == s {
@@ -286,30 +386,58 @@ func strsContains(ss []string, s string) bool {
 
 // A Client provides metadata.
 type Client struct {
-	hc HTTPClient
+	hc              HTTPClient
+	cache           *cache
+	retryPolicy     *RetryPolicy
+	host            string
+	probe           *ProbeConfig
+	instrumentation *Instrumentation
+
+	onGCEOnce sync.Once
+	onGCE     bool
+
+	idTokenMu      sync.Mutex
+	idTokens       map[string]idToken       // keyed by audience
+	idTokenFetches map[string]*idTokenFetch // in-flight fetches, keyed by audience
 }
 
 // NewClient returns a Client that can be used to fetch metadata.
 // Returns the client that uses the specified http.Client for HTTP requests.
-// If nil is specified, returns the default client.
-func NewClient(c *http.Client) *Client {
-	if c == nil {
+// If nil is specified and no options are given, returns the default client.
+func NewClient(c *http.Client, opts ...ClientOption) *Client {
+	if c == nil && len(opts) == 0 {
 		return defaultClient
 	}
 
-	return &Client{hc: c}
+	cl := &Client{hc: c}
+	if c == nil {
+		cl.hc = defaultHTTPClient
+	}
+	for _, opt := range opts {
+		opt.apply(cl)
+	}
+	return cl
 }
 
 // getETag returns a value from the metadata service as well as the associated ETag.
 // This func is otherwise equivalent to Get.
-func (c *Client) getETag(suffix string) (value, etag string, err error) {
-	ctx := context.TODO()
+func (c *Client) getETag(ctx context.Context, suffix string) (value, etag string, err error) {
+	value, etag, _, err = c.getConditional(ctx, suffix, "")
+	return value, etag, err
+}
+
+// getConditional is like getETag, but if lastETag is not empty and the value
+// still has that ETag, it returns an empty value and notModified true.
+func (c *Client) getConditional(ctx context.Context, suffix, lastETag string) (value, etag string, notModified bool, err error) {
 	// Using a fixed IP makes it very difficult to spoof the metadata service in
 	// a container, which is an important use-case for local testing of cloud
 	// deployments. To enable spoofing of the metadata service, the environment
 	// variable GCE_METADATA_HOST is first inspected to decide where metadata
-	// requests shall go.
-	host := os.Getenv(metadataHostEnv)
+	// requests shall go, unless the Client was created with WithMetadataHost.
+	host := c.host
+	if host == "" {
+		host = os.Getenv(metadataHostEnv)
+	}
 	if host == "" {
 		// Using 169.254.169.254 instead of "metadata" here because Go
 		// binaries built with the "netgo" tag and without cgo won't
@@ -319,16 +447,30 @@ func (c *Client) getETag(suffix string) (value, etag string, err error) {
 		host = metadataIP
 	}
 	suffix = strings.TrimLeft(suffix, "/")
+	var res *http.Response
+	retryer := newRetryer(c.retryPolicy)
+	if c.instrumentation != nil {
+		var finish func(status, attempts int, err error)
+		ctx, finish = c.instrumentation.start(ctx, suffix)
+		defer func() {
+			var code int
+			if res != nil {
+				code = res.StatusCode
+			}
+			finish(code, retryer.attempts+1, err)
+		}()
+	}
 	u := "http://" + host + "/computeMetadata/v1/" + suffix
-	req, err := http.NewRequest("GET", u, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
-		return "", "", err
+		return "", "", false, err
 	}
 	req.Header.Set("Metadata-Flavor", "Google")
 	req.Header.Set("User-Agent", userAgent)
-	var res *http.Response
+	if lastETag != "" {
+		req.Header.Set("If-None-Match", lastETag)
+	}
 	var reqErr error
-	retryer := newRetryer()
 	for {
 		res, reqErr = c.hc.Do(req)
 		var code int
@@ -337,27 +479,39 @@ func (c *Client) getETag(suffix string) (value, etag string, err error) {
 		}
 		if delay, shouldRetry := retryer.Retry(code, reqErr); shouldRetry {
 			if err := sleep(ctx, delay); err != nil {
-				return "", "", err
+				return "", "", false, err
 			}
 			continue
 		}
 		break
 	}
 	if reqErr != nil {
-		return "", "", reqErr
+		return "", "", false, &Error{Suffix: suffix, Err: reqErr, Retryable: retryer.retryable(0, reqErr)}
 	}
 	defer res.Body.Close()
+	if lastETag != "" && res.StatusCode == http.StatusNotModified {
+		return "", lastETag, true, nil
+	}
 	if res.StatusCode == http.StatusNotFound {
-		return "", "", NotDefinedError(suffix)
+		return "", "", false, NotDefinedError(suffix)
 	}
 	all, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return "", "", err
+		return "", "", false, &Error{Code: res.StatusCode, Suffix: suffix, Err: err, Retryable: retryer.retryable(0, err)}
 	}
 	if res.StatusCode != 200 {
-		return "", "", &Error{Code: res.StatusCode, Message: string(all)}
+		return "", "", false, &Error{
+			Code:      res.StatusCode,
+			Message:   string(all),
+			Suffix:    suffix,
+			Retryable: retryer.retryable(res.StatusCode, nil),
+		}
 	}
-	return string(all), res.Header.Get("Etag"), nil
+	etag = res.Header.Get("Etag")
+	if lastETag != "" && etag == lastETag {
+		return "", etag, true, nil
+	}
+	return string(all), etag, false, nil
 }
 
 // Get returns a value from the metadata service.
@@ -368,19 +522,45 @@ func (c *Client) getETag(suffix string) (value, etag string, err error) {
 //
 // If the requested metadata is not defined, the returned error will
 // be of type NotDefinedError.
+//
+// If the Client was created with WithCache, Get may return a cached value.
 func (c *Client) Get(suffix string) (string, error) {
-	val, _, err := c.getETag(suffix)
-	return val, err
+	return c.GetWithContext(context.Background(), suffix)
+}
+
+// GetWithContext is like Get, but the request is made with the provided
+// context.
+func (c *Client) GetWithContext(ctx context.Context, suffix string) (string, error) {
+	return c.getCached(ctx, suffix)
+}
+
+// getCached returns the value of suffix, consulting the Client's cache if it
+// has one.
+func (c *Client) getCached(ctx context.Context, suffix string) (string, error) {
+	if c.cache == nil {
+		val, _, err := c.getETag(ctx, suffix)
+		return val, err
+	}
+	suffix = strings.TrimLeft(suffix, "/")
+	if val, ok := c.cache.get(suffix); ok {
+		return val, nil
+	}
+	val, _, err := c.getETag(ctx, suffix)
+	if err != nil {
+		return "", err
+	}
+	c.cache.put(suffix, val)
+	return val, nil
 }
 
-func (c *Client) getTrimmed(suffix string) (s string, err error) {
-	s, err = c.Get(suffix)
+func (c *Client) getTrimmed(ctx context.Context, suffix string) (s string, err error) {
+	s, err = c.GetWithContext(ctx, suffix)
 	s = strings.TrimSpace(s)
 	return
 }
 
-func (c *Client) lines(suffix string) ([]string, error) {
-	j, err := c.Get(suffix)
+func (c *Client) lines(ctx context.Context, suffix string) ([]string, error) {
+	j, err := c.GetWithContext(ctx, suffix)
 	if err != nil {
 		return nil, err
 	}
@@ -392,45 +572,85 @@ func (c *Client) lines(suffix string) ([]string, error) {
 }
 
 // ProjectID returns the current instance's project ID string.
-func (c *Client) ProjectID() (string, error) { return projID.get(c) }
+func (c *Client) ProjectID() (string, error) { return c.ProjectIDWithContext(context.Background()) }
+
+// ProjectIDWithContext returns the current instance's project ID string.
+func (c *Client) ProjectIDWithContext(ctx context.Context) (string, error) {
+	return projID.get(ctx, c)
+}
 
 // NumericProjectID returns the current instance's numeric project ID.
-func (c *Client) NumericProjectID() (string, error) { return projNum.get(c) }
+func (c *Client) NumericProjectID() (string, error) {
+	return c.NumericProjectIDWithContext(context.Background())
+}
+
+// NumericProjectIDWithContext returns the current instance's numeric project ID.
+func (c *Client) NumericProjectIDWithContext(ctx context.Context) (string, error) {
+	return projNum.get(ctx, c)
+}
 
 // InstanceID returns the current VM's numeric instance ID.
-func (c *Client) InstanceID() (string, error) { return instID.get(c) }
+func (c *Client) InstanceID() (string, error) { return c.InstanceIDWithContext(context.Background()) }
+
+// InstanceIDWithContext returns the current VM's numeric instance ID.
+func (c *Client) InstanceIDWithContext(ctx context.Context) (string, error) {
+	return instID.get(ctx, c)
+}
 
 // InternalIP returns the instance's primary internal IP address.
-func (c *Client) InternalIP() (string, error) {
-	return c.getTrimmed("instance/network-interfaces/0/ip")
+func (c *Client) InternalIP() (string, error) { return c.InternalIPWithContext(context.Background()) }
+
+// InternalIPWithContext returns the instance's primary internal IP address.
+func (c *Client) InternalIPWithContext(ctx context.Context) (string, error) {
+	return c.getTrimmed(ctx, "instance/network-interfaces/0/ip")
 }
 
 // Email returns the email address associated with the service account.
 // The account may be empty or the string "default" to use the instance's
 // main account.
 func (c *Client) Email(serviceAccount string) (string, error) {
+	return c.EmailWithContext(context.Background(), serviceAccount)
+}
+
+// EmailWithContext returns the email address associated with the service
+// account. The account may be empty or the string "default" to use the
+// instance's main account.
+func (c *Client) EmailWithContext(ctx context.Context, serviceAccount string) (string, error) {
 	if serviceAccount == "" {
 		serviceAccount = "default"
 	}
-	return c.getTrimmed("instance/service-accounts/" + serviceAccount + "/email")
+	return c.getTrimmed(ctx, "instance/service-accounts/"+serviceAccount+"/email")
 }
 
 // ExternalIP returns the instance's primary external (public) IP address.
-func (c *Client) ExternalIP() (string, error) {
-	return c.getTrimmed("instance/network-interfaces/0/access-configs/0/external-ip")
+func (c *Client) ExternalIP() (string, error) { return c.ExternalIPWithContext(context.Background()) }
+
+// ExternalIPWithContext returns the instance's primary external (public) IP address.
+func (c *Client) ExternalIPWithContext(ctx context.Context) (string, error) {
+	return c.getTrimmed(ctx, "instance/network-interfaces/0/access-configs/0/external-ip")
 }
 
 // Hostname returns the instance's hostname. This will be of the form
 // "<instanceID>.c.<projID>.internal".
-func (c *Client) Hostname() (string, error) {
-	return c.getTrimmed("instance/hostname")
+func (c *Client) Hostname() (string, error) { return c.HostnameWithContext(context.Background()) }
+
+// HostnameWithContext returns the instance's hostname. This will be of the form
+// "<instanceID>.c.<projID>.internal".
+func (c *Client) HostnameWithContext(ctx context.Context) (string, error) {
+	return c.getTrimmed(ctx, "instance/hostname")
 }
 
 // InstanceTags returns the list of user-defined instance tags,
 // assigned when initially creating a GCE instance.
 func (c *Client) InstanceTags() ([]string, error) {
+	return c.InstanceTagsWithContext(context.Background())
+}
+
+// InstanceTagsWithContext returns the list of user-defined instance tags,
+// assigned when initially creating a GCE instance.
+func (c *Client) InstanceTagsWithContext(ctx context.Context) ([]string, error) {
 	var s []string
-	j, err := c.Get("instance/tags")
+	j, err := c.GetWithContext(ctx, "instance/tags")
 	if err != nil {
 		return nil, err
 	}
@@ -442,12 +662,20 @@ func (c *Client) InstanceTags() ([]string, error) {
 
 // InstanceName returns the current VM's instance ID string.
 func (c *Client) InstanceName() (string, error) {
-	return c.getTrimmed("instance/name")
+	return c.InstanceNameWithContext(context.Background())
+}
+
+// InstanceNameWithContext returns the current VM's instance ID string.
+func (c *Client) InstanceNameWithContext(ctx context.Context) (string, error) {
+	return c.getTrimmed(ctx, "instance/name")
 }
 
 // Zone returns the current VM's zone, such as "us-central1-b".
-func (c *Client) Zone() (string, error) {
-	zone, err := c.getTrimmed("instance/zone")
+func (c *Client) Zone() (string, error) { return c.ZoneWithContext(context.Background()) }
+
+// ZoneWithContext returns the current VM's zone, such as "us-central1-b".
+func (c *Client) ZoneWithContext(ctx context.Context) (string, error) {
+	zone, err := c.getTrimmed(ctx, "instance/zone")
 	// zone is of the form "projects/<projNum>/zones/<zoneName>".
 	if err != nil {
 		return "", err
@@ -458,12 +686,28 @@ func (c *Client) Zone() (string, error) {
 // InstanceAttributes returns the list of user-defined attributes,
 // assigned when initially creating a GCE VM instance. The value of an
 // attribute can be obtained with InstanceAttributeValue.
-func (c *Client) InstanceAttributes() ([]string, error) { return c.lines("instance/attributes/") }
+func (c *Client) InstanceAttributes() ([]string, error) {
+	return c.InstanceAttributesWithContext(context.Background())
+}
+
+// InstanceAttributesWithContext is like InstanceAttributes, but the request is
+// made with the provided context.
+func (c *Client) InstanceAttributesWithContext(ctx context.Context) ([]string, error) {
+	return c.lines(ctx, "instance/attributes/")
+}
 
 // ProjectAttributes returns the list of user-defined attributes
 // applying to the project as a whole, not just this VM.  The value of
 // an attribute can be obtained with ProjectAttributeValue.
-func (c *Client) ProjectAttributes() ([]string, error) { return c.lines("project/attributes/") }
+func (c *Client) ProjectAttributes() ([]string, error) {
+	return c.ProjectAttributesWithContext(context.Background())
+}
+
+// ProjectAttributesWithContext is like ProjectAttributes, but the request is
+// made with the provided context.
+func (c *Client) ProjectAttributesWithContext(ctx context.Context) ([]string, error) {
+	return c.lines(ctx, "project/attributes/")
+}
 
 // InstanceAttributeValue returns the value of the provided VM
 // instance attribute.
@@ -474,7 +718,13 @@ func (c *Client) ProjectAttributes() ([]string, error) { return c.lines("project
 // InstanceAttributeValue may return ("", nil) if the attribute was
 // defined to be the empty string.
 func (c *Client) InstanceAttributeValue(attr string) (string, error) {
-	return c.Get("instance/attributes/" + attr)
+	return c.InstanceAttributeValueWithContext(context.Background(), attr)
+}
+
+// InstanceAttributeValueWithContext is like InstanceAttributeValue, but the
+// request is made with the provided context.
+func (c *Client) InstanceAttributeValueWithContext(ctx context.Context, attr string) (string, error) {
+	return c.GetWithContext(ctx, "instance/attributes/"+attr)
 }
 
 // ProjectAttributeValue returns the value of the provided
@@ -486,17 +736,29 @@ func (c *Client) InstanceAttributeValue(attr string) (string, error) {
 // ProjectAttributeValue may return ("", nil) if the attribute was
 // defined to be the empty string.
 func (c *Client) ProjectAttributeValue(attr string) (string, error) {
-	return c.Get("project/attributes/" + attr)
+	return c.ProjectAttributeValueWithContext(context.Background(), attr)
+}
+
+// ProjectAttributeValueWithContext is like ProjectAttributeValue, but the
+// request is made with the provided context.
+func (c *Client) ProjectAttributeValueWithContext(ctx context.Context, attr string) (string, error) {
+	return c.GetWithContext(ctx, "project/attributes/"+attr)
 }
 
 // Scopes returns the service account scopes for the given account.
 // The account may be empty or the string "default" to use the instance's
 // main account.
 func (c *Client) Scopes(serviceAccount string) ([]string, error) {
+	return c.ScopesWithContext(context.Background(), serviceAccount)
+}
+
+// ScopesWithContext is like Scopes, but the request is made with the provided
+// context.
+func (c *Client) ScopesWithContext(ctx context.Context, serviceAccount string) ([]string, error) {
 	if serviceAccount == "" {
 		serviceAccount = "default"
 	}
-	return c.lines("instance/service-accounts/" + serviceAccount + "/scopes")
+	return c.lines(ctx, "instance/service-accounts/"+serviceAccount+"/scopes")
 }
 
 // Subscribe subscribes to a value from the metadata service.
@@ -509,10 +771,16 @@ func (c *Client) Scopes(serviceAccount string) ([]string, error) {
 // is deleted. Subscribe returns the error value returned from the last call to
 // fn, which may be nil when ok == false.
 func (c *Client) Subscribe(suffix string, fn func(v string, ok bool) error) error {
+	return c.SubscribeWithContext(context.Background(), suffix, fn)
+}
+
+// SubscribeWithContext is like Subscribe, but requests are made with the
+// provided context, and SubscribeWithContext also returns once ctx is done.
+func (c *Client) SubscribeWithContext(ctx context.Context, suffix string, fn func(v string, ok bool) error) error {
 	const failedSubscribeSleep = time.Second * 5
 
 	// First check to see if the metadata value exists at all.
-	val, lastETag, err := c.getETag(suffix)
+	val, lastETag, err := c.getETag(ctx, suffix)
 	if err != nil {
 		return err
 	}
@@ -522,16 +790,16 @@ func (c *Client) Subscribe(suffix string, fn func(v string, ok bool) error) erro
 	}
 
 	ok := true
-	if strings.ContainsRune(suffix, '?') {
-		suffix += "&wait_for_change=true&last_etag="
-	} else {
-		suffix += "?wait_for_change=true&last_etag="
-	}
 	for {
-		val, etag, err := c.getETag(suffix + url.QueryEscape(lastETag))
+		val, etag, err := c.getETag(ctx, waitForChangeSuffix(suffix, lastETag))
 		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			if _, deleted := err.(NotDefinedError); !deleted {
-				time.Sleep(failedSubscribeSleep)
+				if err := sleep(ctx, failedSubscribeSleep); err != nil {
+					return err
+				}
 				continue // Retry on other errors.
 			}
 			ok = false
@@ -544,14 +812,34 @@ func (c *Client) Subscribe(suffix string, fn func(v string, ok bool) error) erro
 	}
 }
 
-// Error contains an error response from the server.
+// Error is returned when a request to the metadata service fails, either
+// with an error response from the server or before a response is received.
+//
+// A request for metadata that is not defined fails with a NotDefinedError
+// instead.
 type Error struct {
-	// Code is the HTTP response status code.
+	// Code is the HTTP response status code, or 0 if no response was
+	// received.
 	Code int
 	// Message is the server response message.
 	Message string
+	// Suffix is the requested suffix after "/computeMetadata/v1/".
+	Suffix string
+	// Retryable reports whether the failure is considered transient by the
+	// Client's retry policy. The request has already been retried as many
+	// times as the policy allows.
+	Retryable bool
+	// Err is the underlying error, such as a network error, if the response
+	// could not be received or read.
+	Err error
 }
 
 func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
 	return fmt.Sprintf("compute: Received %d `%s`", e.Code, e.Message)
 }
+
+// Unwrap returns the underlying error, if any.
+func (e *Error) Unwrap() error { return e.Err }
@@ -18,11 +18,13 @@
 package metadata
 
 import (
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRetry(t *testing.T) {
@@ -88,6 +90,99 @@ func TestRetry(t *testing.T) {
 	}
 }
 
+func TestRetryPolicy(t *testing.T) {
+	ft := &failingTransport{
+		timesToFail: 10,
+		failCode:    503,
+		response:    "test",
+	}
+	c := NewClient(&http.Client{Transport: ft}, WithRetryPolicy(RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	}))
+	if _, err := c.Get(""); err == nil {
+		t.Fatal("did not receive expected error")
+	}
+	if ft.called != 2 {
+		t.Errorf("got %d attempts, want 2", ft.called)
+	}
+}
+
+func TestGetErrorDetails(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	tests := []struct {
+		name          string
+		failCode      int
+		failErr       error
+		wantCode      int
+		wantRetryable bool
+		wantErr       error
+	}{
+		{
+			name:          "transient server error",
+			failCode:      503,
+			wantCode:      503,
+			wantRetryable: true,
+		},
+		{
+			name:     "bad request",
+			failCode: 400,
+			wantCode: 400,
+		},
+		{
+			name:          "network error",
+			failErr:       io.ErrUnexpectedEOF,
+			wantRetryable: true,
+			wantErr:       io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "permanent network error",
+			failErr: errRefused,
+			wantErr: errRefused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &failingTransport{
+				timesToFail: maxRetryAttempts + 1,
+				failCode:    tt.failCode,
+				failErr:     tt.failErr,
+			}
+			c := NewClient(&http.Client{Transport: ft}, WithRetryPolicy(RetryPolicy{InitialBackoff: time.Millisecond}))
+			_, err := c.Get("/instance/zone")
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("got error %v (%T), want *Error", err, err)
+			}
+			if e.Code != tt.wantCode {
+				t.Errorf("got Code %d, want %d", e.Code, tt.wantCode)
+			}
+			if e.Suffix != "instance/zone" {
+				t.Errorf("got Suffix %q, want %q", e.Suffix, "instance/zone")
+			}
+			if e.Retryable != tt.wantRetryable {
+				t.Errorf("got Retryable %v, want %v", e.Retryable, tt.wantRetryable)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetNotDefined(t *testing.T) {
+	ft := &failingTransport{timesToFail: 1, failCode: 404}
+	c := NewClient(&http.Client{Transport: ft})
+	_, err := c.Get("instance/attributes/missing")
+	var nd NotDefinedError
+	if !errors.As(err, &nd) {
+		t.Fatalf("got error %v (%T), want NotDefinedError", err, err)
+	}
+	if got, want := string(nd), "instance/attributes/missing"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 type failingTransport struct {
 	timesToFail int
 	failCode    int
@@ -17,6 +17,7 @@ package metadata
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -152,6 +153,23 @@ func TestGet_LeadingSlash(t *testing.T) {
 	}
 }
 
+func TestGetWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("projects/123/zones/us-central1-a"))
+	}))
+	if _, err := c.GetWithContext(ctx, "instance/zone"); err == nil {
+		t.Error("GetWithContext with canceled context: got nil error")
+	}
+	if _, err := c.ZoneWithContext(ctx); err == nil {
+		t.Error("ZoneWithContext with canceled context: got nil error")
+	}
+	if got, err := c.ZoneWithContext(context.Background()); err != nil || got != "us-central1-a" {
+		t.Errorf("ZoneWithContext() = %q, %v; want %q, nil", got, err, "us-central1-a")
+	}
+}
+
 type captureTransport struct {
 	url string
 }
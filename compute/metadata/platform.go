@@ -0,0 +1,146 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PlatformType identifies the Google Cloud compute platform a process is
+// running on.
+type PlatformType int
+
+const (
+	// PlatformUnknown means the process does not appear to be running on
+	// Google Cloud.
+	PlatformUnknown PlatformType = iota
+	// PlatformGCE is a Compute Engine VM.
+	PlatformGCE
+	// PlatformGKE is a Google Kubernetes Engine node.
+	PlatformGKE
+	// PlatformCloudRunGen1 is the first generation Cloud Run execution
+	// environment.
+	PlatformCloudRunGen1
+	// PlatformCloudRunGen2 is the second generation Cloud Run execution
+	// environment.
+	PlatformCloudRunGen2
+	// PlatformCloudFunctions is Cloud Functions.
+	PlatformCloudFunctions
+	// PlatformAppEngine is App Engine, in either the standard or the
+	// flexible environment.
+	PlatformAppEngine
+)
+
+var platformTypeNames = map[PlatformType]string{
+	PlatformUnknown:        "Unknown",
+	PlatformGCE:            "GCE",
+	PlatformGKE:            "GKE",
+	PlatformCloudRunGen1:   "CloudRunGen1",
+	PlatformCloudRunGen2:   "CloudRunGen2",
+	PlatformCloudFunctions: "CloudFunctions",
+	PlatformAppEngine:      "AppEngine",
+}
+
+func (p PlatformType) String() string {
+	if s, ok := platformTypeNames[p]; ok {
+		return s
+	}
+	return "PlatformType(" + strconv.Itoa(int(p)) + ")"
+}
+
+// PlatformInfo describes the platform a process is running on, as returned by
+// Platform. Fields that do not apply to the platform are empty.
+type PlatformInfo struct {
+	Type PlatformType
+
+	// ClusterName and ClusterLocation identify the GKE cluster.
+	ClusterName     string
+	ClusterLocation string
+
+	// ServiceName is the Cloud Run service, Cloud Functions function or App
+	// Engine service.
+	ServiceName string
+
+	// Revision is the Cloud Run revision or the App Engine version.
+	Revision string
+
+	// JobName and Execution identify a Cloud Run job execution.
+	JobName   string
+	Execution string
+}
+
+// isMicroVM reports whether the kernel runs on hardware that identifies
+// itself as Google's, which distinguishes the second generation Cloud Run
+// execution environment from the sandboxed first generation. It is a variable
+// so tests can replace it.
+var isMicroVM = systemInfoSuggestsGCE
+
+// Platform calls Client.Platform on the default client.
+func Platform(ctx context.Context) *PlatformInfo { return defaultClient.Platform(ctx) }
+
+// Platform detects the platform the process is running on.
+//
+// The serverless platforms are recognized by the environment variables they
+// set. Otherwise, Platform falls back to Client.OnGCE and, on GCE, to the
+// "cluster-name" instance attribute set on GKE nodes. Detection is best
+// effort: if the metadata service cannot be queried, Platform reports
+// PlatformGCE rather than PlatformGKE.
+func (c *Client) Platform(ctx context.Context) *PlatformInfo {
+	switch {
+	case os.Getenv("FUNCTION_TARGET") != "" || os.Getenv("FUNCTION_NAME") != "":
+		// Functions on newer runtimes also set K_SERVICE, so check them first.
+		name := os.Getenv("K_SERVICE")
+		if name == "" {
+			name = os.Getenv("FUNCTION_NAME")
+		}
+		return &PlatformInfo{Type: PlatformCloudFunctions, ServiceName: name}
+	case os.Getenv("GAE_SERVICE") != "":
+		return &PlatformInfo{
+			Type:        PlatformAppEngine,
+			ServiceName: os.Getenv("GAE_SERVICE"),
+			Revision:    os.Getenv("GAE_VERSION"),
+		}
+	case os.Getenv("K_SERVICE") != "" || os.Getenv("CLOUD_RUN_JOB") != "":
+		p := &PlatformInfo{
+			Type:        PlatformCloudRunGen1,
+			ServiceName: os.Getenv("K_SERVICE"),
+			Revision:    os.Getenv("K_REVISION"),
+			JobName:     os.Getenv("CLOUD_RUN_JOB"),
+			Execution:   os.Getenv("CLOUD_RUN_EXECUTION"),
+		}
+		if isMicroVM() {
+			p.Type = PlatformCloudRunGen2
+		}
+		return p
+	}
+	if !c.OnGCE() {
+		return &PlatformInfo{Type: PlatformUnknown}
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		name, err := c.InstanceAttributeValueWithContext(ctx, "cluster-name")
+		if err == nil {
+			loc, _ := c.InstanceAttributeValueWithContext(ctx, "cluster-location")
+			return &PlatformInfo{
+				Type:            PlatformGKE,
+				ClusterName:     strings.TrimSpace(name),
+				ClusterLocation: strings.TrimSpace(loc),
+			}
+		}
+	}
+	return &PlatformInfo{Type: PlatformGCE}
+}
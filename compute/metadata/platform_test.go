@@ -0,0 +1,147 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var platformEnvVars = []string{
+	"FUNCTION_TARGET", "FUNCTION_NAME", "GAE_SERVICE", "GAE_VERSION",
+	"K_SERVICE", "K_REVISION", "CLOUD_RUN_JOB", "CLOUD_RUN_EXECUTION",
+	"KUBERNETES_SERVICE_HOST",
+}
+
+// setPlatformEnv clears the environment variables used by Platform, then
+// sets the ones in env. They are restored when the test ends.
+func setPlatformEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, k := range platformEnvVars {
+		old, ok := os.LookupEnv(k)
+		k := k
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(k, old)
+			} else {
+				os.Unsetenv(k)
+			}
+		})
+		os.Unsetenv(k)
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	attrs := map[string]string{
+		"cluster-name":     "my-cluster",
+		"cluster-location": "us-central1",
+	}
+	tests := []struct {
+		name     string
+		env      map[string]string
+		microVM  bool
+		gkeAttrs bool
+		want     *PlatformInfo
+	}{
+		{
+			name: "GCE",
+			want: &PlatformInfo{Type: PlatformGCE},
+		},
+		{
+			name: "Kubernetes on GCE",
+			env:  map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"},
+			want: &PlatformInfo{Type: PlatformGCE},
+		},
+		{
+			name:     "GKE",
+			env:      map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"},
+			gkeAttrs: true,
+			want:     &PlatformInfo{Type: PlatformGKE, ClusterName: "my-cluster", ClusterLocation: "us-central1"},
+		},
+		{
+			name: "Cloud Run gen1",
+			env:  map[string]string{"K_SERVICE": "svc", "K_REVISION": "svc-001"},
+			want: &PlatformInfo{Type: PlatformCloudRunGen1, ServiceName: "svc", Revision: "svc-001"},
+		},
+		{
+			name:    "Cloud Run gen2",
+			env:     map[string]string{"K_SERVICE": "svc", "K_REVISION": "svc-001"},
+			microVM: true,
+			want:    &PlatformInfo{Type: PlatformCloudRunGen2, ServiceName: "svc", Revision: "svc-001"},
+		},
+		{
+			name: "Cloud Run job",
+			env:  map[string]string{"CLOUD_RUN_JOB": "job", "CLOUD_RUN_EXECUTION": "job-abc"},
+			want: &PlatformInfo{Type: PlatformCloudRunGen1, JobName: "job", Execution: "job-abc"},
+		},
+		{
+			name: "Cloud Functions",
+			env:  map[string]string{"FUNCTION_TARGET": "Handler", "K_SERVICE": "fn"},
+			want: &PlatformInfo{Type: PlatformCloudFunctions, ServiceName: "fn"},
+		},
+		{
+			name: "Cloud Functions legacy runtime",
+			env:  map[string]string{"FUNCTION_NAME": "fn"},
+			want: &PlatformInfo{Type: PlatformCloudFunctions, ServiceName: "fn"},
+		},
+		{
+			name: "App Engine",
+			env:  map[string]string{"GAE_SERVICE": "default", "GAE_VERSION": "v1"},
+			want: &PlatformInfo{Type: PlatformAppEngine, ServiceName: "default", Revision: "v1"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			setPlatformEnv(t, tc.env)
+			oldMicroVM := isMicroVM
+			isMicroVM = func() bool { return tc.microVM }
+			defer func() { isMicroVM = oldMicroVM }()
+			c := withTestServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				const prefix = "/computeMetadata/v1/instance/attributes/"
+				if v, ok := attrs[r.URL.Path[len(prefix):]]; ok && tc.gkeAttrs {
+					fmt.Fprint(w, v)
+					return
+				}
+				http.NotFound(w, r)
+			}))
+			// Setting GCE_METADATA_HOST makes OnGCE report true.
+			onGCEOnce = sync.Once{}
+			defer func() { onGCEOnce = sync.Once{} }()
+
+			got := c.Platform(context.Background())
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Platform mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlatformTypeString(t *testing.T) {
+	if got, want := PlatformCloudRunGen2.String(), "CloudRunGen2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := PlatformType(42).String(), "PlatformType(42)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// ProbeConfig configures how Client.OnGCE detects whether the process is
+// running on GCE. See WithProbeConfig.
+type ProbeConfig struct {
+	// DisableDNS skips the DNS lookup of metadata.google.internal, so that
+	// only the metadata server itself is probed. This avoids slow lookups
+	// in environments where DNS requests are blackholed.
+	DisableDNS bool
+
+	// Timeout bounds the total time spent probing. If zero, probing ends
+	// when the probes complete or the dial timeout of two seconds fires.
+	Timeout time.Duration
+
+	// Probe, if non-nil, is called instead of the built-in probes.
+	Probe func(ctx context.Context) bool
+}
+
+// WithProbeConfig returns a ClientOption that configures how Client.OnGCE
+// probes for the metadata service.
+func WithProbeConfig(cfg ProbeConfig) ClientOption {
+	return clientOptionFunc(func(c *Client) {
+		c.probe = &cfg
+	})
+}
+
+// WithMetadataHost returns a ClientOption that makes the Client send requests
+// to host, such as the literal "169.254.169.254", instead of the host in the
+// GCE_METADATA_HOST environment variable or the default IP address. host may
+// include a port. Client.OnGCE probes host as well.
+func WithMetadataHost(host string) ClientOption {
+	return clientOptionFunc(func(c *Client) {
+		c.host = host
+	})
+}
+
+// OnGCE reports whether this process is running on Google Compute Engine,
+// as determined by the Client's probe configuration. The result is computed
+// once per Client.
+//
+// For a Client created without WithProbeConfig or WithMetadataHost, OnGCE is
+// equivalent to the package-level OnGCE.
+func (c *Client) OnGCE() bool {
+	if c.probe == nil && c.host == "" {
+		return OnGCE()
+	}
+	c.onGCEOnce.Do(func() {
+		c.onGCE = c.testOnGCE()
+	})
+	return c.onGCE
+}
+
+func (c *Client) testOnGCE() bool {
+	var cfg ProbeConfig
+	if c.probe != nil {
+		cfg = *c.probe
+	}
+	ctx := context.Background()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	if cfg.Probe != nil {
+		return cfg.Probe(ctx)
+	}
+	host := c.host
+	if host == "" {
+		// The user explicitly said they're on GCE, so trust them.
+		if os.Getenv(metadataHostEnv) != "" {
+			return true
+		}
+		host = metadataIP
+	}
+	return probeOnGCE(ctx, host, cfg.DisableDNS)
+}
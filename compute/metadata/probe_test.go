@@ -0,0 +1,89 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientOnGCE_CustomProbe(t *testing.T) {
+	var calls int
+	c := NewClient(nil, WithProbeConfig(ProbeConfig{
+		Probe: func(ctx context.Context) bool {
+			calls++
+			return true
+		},
+	}))
+	if !c.OnGCE() || !c.OnGCE() {
+		t.Error("OnGCE() = false, want true")
+	}
+	if calls != 1 {
+		t.Errorf("probe called %d times, want 1", calls)
+	}
+}
+
+func TestClientOnGCE_Timeout(t *testing.T) {
+	c := NewClient(nil, WithProbeConfig(ProbeConfig{
+		Timeout: 10 * time.Millisecond,
+		Probe: func(ctx context.Context) bool {
+			<-ctx.Done()
+			return false
+		},
+	}))
+	if c.OnGCE() {
+		t.Error("OnGCE() = true, want false")
+	}
+}
+
+func TestClientOnGCE_MetadataHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		flavor string
+		want   bool
+	}{
+		{name: "metadata server", flavor: "Google", want: true},
+		{name: "other server", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.flavor != "" {
+					w.Header().Set("Metadata-Flavor", tc.flavor)
+				}
+			}))
+			defer ts.Close()
+			c := NewClient(nil,
+				WithMetadataHost(strings.TrimPrefix(ts.URL, "http://")),
+				WithProbeConfig(ProbeConfig{DisableDNS: true}))
+			if got := c.OnGCE(); got != tc.want {
+				t.Errorf("OnGCE() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithMetadataHost(t *testing.T) {
+	ct := &captureTransport{}
+	c := NewClient(&http.Client{Transport: ct}, WithMetadataHost("127.0.0.1:8080"))
+	c.Get("instance/zone")
+	if want := "http://127.0.0.1:8080/computeMetadata/v1/instance/zone"; ct.url != want {
+		t.Errorf("got URL %q, want %q", ct.url, want)
+	}
+}
@@ -59,12 +59,68 @@ func sleep(ctx context.Context, d time.Duration) error {
 	}
 }
 
-func newRetryer() *metadataRetryer {
-	return &metadataRetryer{bo: &defaultBackoff{
+// RetryPolicy configures how a Client retries failed requests to the
+// metadata service. The zero value of each field selects the default
+// behavior. See WithRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is attempted,
+	// including the first attempt. A value of 1 disables retries. The
+	// default is 6.
+	MaxAttempts int
+
+	// InitialBackoff is the upper bound of the randomized delay before the
+	// first retry. The default is 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum upper bound of the randomized delay between
+	// retries. The default is 30s.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the upper bound of the delay grows
+	// after each retry. The default is 2.
+	Multiplier float64
+
+	// ShouldRetry reports whether a failed request should be retried. status
+	// is the HTTP status code of the response, or 0 if the request failed
+	// with err before a response was received. By default, 5xx responses and
+	// transient network errors are retried.
+	ShouldRetry func(status int, err error) bool
+}
+
+// WithRetryPolicy returns a ClientOption that sets the retry policy used for
+// requests made by the Client.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return clientOptionFunc(func(c *Client) {
+		c.retryPolicy = &p
+	})
+}
+
+func newRetryer(p *RetryPolicy) *metadataRetryer {
+	if p == nil {
+		p = &RetryPolicy{}
+	}
+	bo := &defaultBackoff{
 		cur: 100 * time.Millisecond,
 		max: 30 * time.Second,
 		mul: 2,
-	}}
+	}
+	if p.InitialBackoff > 0 {
+		bo.cur = p.InitialBackoff
+	}
+	if p.MaxBackoff > 0 {
+		bo.max = p.MaxBackoff
+	}
+	if p.Multiplier > 0 {
+		bo.mul = p.Multiplier
+	}
+	r := &metadataRetryer{bo: bo, shouldRetry: p.ShouldRetry}
+	switch {
+	case p.MaxAttempts == 1:
+		r.maxRetries = -1
+	case p.MaxAttempts > 1:
+		r.maxRetries = p.MaxAttempts - 1
+	}
+	return r
 }
 
 type backoff interface {
@@ -74,23 +130,40 @@ type backoff interface {
 type metadataRetryer struct {
 	bo       backoff
 	attempts int
+	// maxRetries is the number of retries allowed. Zero means
+	// maxRetryAttempts and a negative value means none.
+	maxRetries int
+	// shouldRetry, if non-nil, replaces the default retry predicate.
+	shouldRetry func(status int, err error) bool
 }
 
 func (r *metadataRetryer) Retry(status int, err error) (time.Duration, bool) {
 	if status == http.StatusOK {
 		return 0, false
 	}
-	retryOk := shouldRetry(status, err)
-	if !retryOk {
+	if !r.retryable(status, err) {
 		return 0, false
 	}
-	if r.attempts == maxRetryAttempts {
+	maxRetries := r.maxRetries
+	if maxRetries == 0 {
+		maxRetries = maxRetryAttempts
+	}
+	if r.attempts >= maxRetries {
 		return 0, false
 	}
 	r.attempts++
 	return r.bo.Pause(), true
 }
 
+// retryable reports whether a request that failed with status or err is
+// eligible for a retry, regardless of how many attempts remain.
+func (r *metadataRetryer) retryable(status int, err error) bool {
+	if r.shouldRetry != nil {
+		return r.shouldRetry(status, err)
+	}
+	return shouldRetry(status, err)
+}
+
 func shouldRetry(status int, err error) bool {
 	if 500 <= status && status <= 599 {
 		return true
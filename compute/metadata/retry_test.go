@@ -125,3 +125,80 @@ func TestMetadataRetryerAttempts(t *testing.T) {
 		}
 	}
 }
+
+func TestMetadataRetryerPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      RetryPolicy
+		code        int
+		wantRetries int
+	}{
+		{
+			name:        "default",
+			code:        500,
+			wantRetries: maxRetryAttempts,
+		},
+		{
+			name:        "max attempts",
+			policy:      RetryPolicy{MaxAttempts: 3},
+			code:        500,
+			wantRetries: 2,
+		},
+		{
+			name:        "retries disabled",
+			policy:      RetryPolicy{MaxAttempts: 1},
+			code:        500,
+			wantRetries: 0,
+		},
+		{
+			name: "custom predicate",
+			policy: RetryPolicy{
+				MaxAttempts: 4,
+				ShouldRetry: func(status int, err error) bool { return status == 429 },
+			},
+			code:        429,
+			wantRetries: 3,
+		},
+		{
+			name: "custom predicate rejects",
+			policy: RetryPolicy{
+				ShouldRetry: func(status int, err error) bool { return status == 429 },
+			},
+			code:        500,
+			wantRetries: 0,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			retryer := newRetryer(&tc.policy)
+			retryer.bo = constantBackoff{}
+			var retries int
+			for {
+				if _, shouldRetry := retryer.Retry(tc.code, nil); !shouldRetry {
+					break
+				}
+				retries++
+			}
+			if retries != tc.wantRetries {
+				t.Errorf("got %d retries, want %d", retries, tc.wantRetries)
+			}
+		})
+	}
+}
+
+func TestNewRetryerBackoff(t *testing.T) {
+	retryer := newRetryer(&RetryPolicy{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     3 * time.Millisecond,
+		Multiplier:     4,
+	})
+	bo := retryer.bo.(*defaultBackoff)
+	if bo.cur != time.Millisecond || bo.max != 3*time.Millisecond || bo.mul != 4 {
+		t.Fatalf("got backoff %+v", bo)
+	}
+	for i := 0; i < 5; i++ {
+		if d := bo.Pause(); d > 3*time.Millisecond {
+			t.Errorf("Pause() = %v, want <= %v", d, 3*time.Millisecond)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// newWatchBackoff returns the backoff used by Watch between reconnect
+// attempts. It is a variable so tests can shorten the delays.
+var newWatchBackoff = func() backoff {
+	return &defaultBackoff{
+		cur: time.Second,
+		max: 30 * time.Second,
+		mul: 2,
+	}
+}
+
+// Watch calls Client.Watch on the default client.
+func Watch(ctx context.Context, suffix string, fn func(v string, ok bool) error) error {
+	return defaultClient.Watch(ctx, suffix, fn)
+}
+
+// Watch watches a value from the metadata service for changes.
+// The suffix is appended to "http://${GCE_METADATA_HOST}/computeMetadata/v1/".
+// The suffix may contain query parameters.
+//
+// Watch calls fn with the current value indicated by the provided suffix, and
+// then again every time the value changes. If the value is not defined, or is
+// later deleted, fn is called with the empty string and ok false; unlike
+// Subscribe, Watch keeps watching and calls fn again once the value is defined.
+//
+// Watch uses the metadata server's wait_for_change long poll and tracks the
+// ETag of the last value seen, so fn is only called for real changes. If a
+// request fails, Watch reconnects with exponential backoff.
+//
+// Watch blocks until fn returns a non-nil error, which Watch returns, or until
+// ctx is done, in which case Watch returns ctx.Err().
+func (c *Client) Watch(ctx context.Context, suffix string, fn func(v string, ok bool) error) error {
+	var (
+		lastETag string
+		// defined reports whether the last value passed to fn was defined.
+		defined bool
+		// notified reports whether fn has been called at least once.
+		notified bool
+		bo       = newWatchBackoff()
+	)
+	for {
+		s := suffix
+		if notified && defined {
+			s = waitForChangeSuffix(suffix, lastETag)
+		}
+		val, etag, err := c.getETag(ctx, s)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if _, ok := err.(NotDefinedError); !ok {
+				// Retry on other errors.
+				if err := sleep(ctx, bo.Pause()); err != nil {
+					return err
+				}
+				continue
+			}
+			if !notified || defined {
+				notified, defined = true, false
+				lastETag = ""
+				if err := fn("", false); err != nil {
+					return err
+				}
+			}
+			// The value is not defined, so there is nothing to wait on.
+			// Poll for it to reappear.
+			if err := sleep(ctx, bo.Pause()); err != nil {
+				return err
+			}
+			continue
+		}
+		bo = newWatchBackoff()
+		if notified && defined && etag == lastETag {
+			// The long poll timed out without a change.
+			continue
+		}
+		notified, defined = true, true
+		lastETag = etag
+		if err := fn(val, true); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForChangeSuffix returns suffix with the query parameters needed to
+// wait for a value with an ETag other than lastETag.
+func waitForChangeSuffix(suffix, lastETag string) string {
+	if strings.ContainsRune(suffix, '?') {
+		suffix += "&wait_for_change=true&last_etag="
+	} else {
+		suffix += "?wait_for_change=true&last_etag="
+	}
+	return suffix + url.QueryEscape(lastETag)
+}
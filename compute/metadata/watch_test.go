@@ -0,0 +1,153 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWatchServer serves a sequence of values for a single key. A request
+// with wait_for_change moves on to the next value if last_etag matches the
+// current one. An empty value in the sequence means the key is not defined.
+type fakeWatchServer struct {
+	mu     sync.Mutex
+	values []string
+	i      int
+	// failNext is the number of upcoming wait_for_change requests that fail
+	// before the next value is served.
+	failNext int
+}
+
+func (f *fakeWatchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	if q.Get("wait_for_change") == "true" {
+		if f.failNext > 0 {
+			f.failNext--
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if q.Get("last_etag") == fmt.Sprint("etag-", f.i) && f.i < len(f.values)-1 {
+			f.i++
+		}
+	}
+	v := f.values[f.i]
+	if v == "" {
+		http.NotFound(w, r)
+		if f.i < len(f.values)-1 {
+			f.i++
+		}
+		return
+	}
+	w.Header().Set("Etag", fmt.Sprint("etag-", f.i))
+	fmt.Fprint(w, v)
+}
+
+func withTestServer(t *testing.T, h http.Handler) *Client {
+	t.Helper()
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	old := os.Getenv(metadataHostEnv)
+	os.Setenv(metadataHostEnv, strings.TrimPrefix(ts.URL, "http://"))
+	t.Cleanup(func() { os.Setenv(metadataHostEnv, old) })
+	oldBackoff := newWatchBackoff
+	newWatchBackoff = func() backoff { return constantBackoff{} }
+	t.Cleanup(func() { newWatchBackoff = oldBackoff })
+	return NewClient(ts.Client())
+}
+
+type watchEvent struct {
+	v  string
+	ok bool
+}
+
+func TestWatch(t *testing.T) {
+	errDone := errors.New("done")
+	tests := []struct {
+		name     string
+		values   []string
+		failNext int
+		want     []watchEvent
+	}{
+		{
+			name:   "changes",
+			values: []string{"a", "b", "c"},
+			want:   []watchEvent{{"a", true}, {"b", true}, {"c", true}},
+		},
+		{
+			name:     "reconnects after errors",
+			values:   []string{"a", "b", "c"},
+			failNext: 2,
+			want:     []watchEvent{{"a", true}, {"b", true}, {"c", true}},
+		},
+		{
+			name:   "deleted and redefined",
+			values: []string{"a", "", "c"},
+			want:   []watchEvent{{"a", true}, {"", false}, {"c", true}},
+		},
+		{
+			name:   "initially undefined",
+			values: []string{"", "b", "c"},
+			want:   []watchEvent{{"", false}, {"b", true}, {"c", true}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := withTestServer(t, &fakeWatchServer{values: tc.values, failNext: tc.failNext})
+			var got []watchEvent
+			err := c.Watch(context.Background(), "instance/attributes/foo", func(v string, ok bool) error {
+				got = append(got, watchEvent{v, ok})
+				if len(got) == len(tc.want) {
+					return errDone
+				}
+				return nil
+			})
+			if err != errDone {
+				t.Fatalf("Watch returned %v, want %v", err, errDone)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got events %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWatch_ContextCanceled(t *testing.T) {
+	c := withTestServer(t, &fakeWatchServer{values: []string{"a"}})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var calls int
+	err := c.Watch(ctx, "instance/attributes/foo", func(v string, ok bool) error {
+		calls++
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Watch returned %v, want %v", err, context.DeadlineExceeded)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,19 @@
+module cloud.google.com/go/internal/examples/metadataotel
+
+go 1.19
+
+require (
+	cloud.google.com/go/compute v1.2.0
+	go.opentelemetry.io/otel v1.16.0
+	go.opentelemetry.io/otel/metric v1.16.0
+	go.opentelemetry.io/otel/sdk v1.16.0
+	go.opentelemetry.io/otel/trace v1.16.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	golang.org/x/sys v0.8.0 // indirect
+)
+
+replace cloud.google.com/go/compute => ../../../compute
@@ -0,0 +1,75 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metadataotel shows how to report the requests made by a
+// compute/metadata Client to OpenTelemetry. The metadata package deliberately
+// has no telemetry dependency; its Instrumentation hooks are bridged here
+// instead.
+package metadataotel
+
+import (
+	"context"
+
+	"cloud.google.com/go/compute/metadata"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewInstrumentation returns metadata.Instrumentation that records a span per
+// request with tracer, and request counts and latencies with meter.
+func NewInstrumentation(tracer trace.Tracer, meter metric.Meter) (metadata.Instrumentation, error) {
+	requests, err := meter.Int64Counter("metadata.requests",
+		metric.WithDescription("Requests made to the metadata service."))
+	if err != nil {
+		return metadata.Instrumentation{}, err
+	}
+	latency, err := meter.Float64Histogram("metadata.request.latency",
+		metric.WithDescription("Latency of requests made to the metadata service."),
+		metric.WithUnit("ms"))
+	if err != nil {
+		return metadata.Instrumentation{}, err
+	}
+	return metadata.Instrumentation{
+		RequestStarted: func(ctx context.Context, path string) context.Context {
+			ctx, _ = tracer.Start(ctx, "metadata.Get",
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attribute.String("metadata.path", path)))
+			return ctx
+		},
+		RequestFinished: func(ctx context.Context, info metadata.RequestInfo) {
+			attrs := []attribute.KeyValue{
+				attribute.String("metadata.path", info.Path),
+				attribute.Int("http.status_code", info.StatusCode),
+			}
+			span := trace.SpanFromContext(ctx)
+			span.SetAttributes(append(attrs,
+				attribute.Int("metadata.attempts", info.Attempts),
+				attribute.Bool("metadata.wait_for_change", info.WaitForChange))...)
+			if info.Err != nil {
+				span.RecordError(info.Err)
+				span.SetStatus(codes.Error, info.Err.Error())
+			}
+			span.End()
+
+			// Long polls stay open until the value changes, so their
+			// latency is not recorded.
+			requests.Add(ctx, 1, metric.WithAttributes(attrs...))
+			if !info.WaitForChange {
+				latency.Record(ctx, float64(info.Latency.Microseconds())/1000, metric.WithAttributes(attrs...))
+			}
+		},
+	}, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadataotel
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/compute/metadata"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type hostTransport struct {
+	host string
+}
+
+func (t hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Host = t.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewInstrumentation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("us-central1-a"))
+	}))
+	defer srv.Close()
+
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	in, err := NewInstrumentation(tp.Tracer("metadataotel"), noop.NewMeterProvider().Meter("metadataotel"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := strings.TrimPrefix(srv.URL, "http://")
+	c := metadata.NewClient(&http.Client{Transport: hostTransport{host}}, metadata.WithInstrumentation(in))
+
+	if _, err := c.Get("instance/zone"); err != nil {
+		t.Fatal(err)
+	}
+	c.Get("instance/missing")
+
+	spans := exp.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	for i, want := range []struct {
+		path   string
+		failed bool
+	}{{"instance/zone", false}, {"instance/missing", true}} {
+		s := spans[i]
+		var path string
+		for _, kv := range s.Attributes {
+			if kv.Key == "metadata.path" {
+				path = kv.Value.AsString()
+			}
+		}
+		if path != want.path {
+			t.Errorf("span %d: got path %q, want %q", i, path, want.path)
+		}
+		if got := len(s.Events) > 0; got != want.failed {
+			t.Errorf("span %d: got error recorded %v, want %v", i, got, want.failed)
+		}
+	}
+}
+
+func ExampleNewInstrumentation() {
+	in, err := NewInstrumentation(otel.Tracer("metadata"), otel.Meter("metadata"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	c := metadata.NewClient(nil, metadata.WithInstrumentation(in))
+	zone, err := c.Zone()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(zone)
+}
@@ -48,6 +48,25 @@ background. To clean up these goroutines, call Stop:
 
   topic.Stop()
 
+By default, the client buffers every message passed to Publish until it is sent,
+which can use unbounded memory when messages are published faster than they can
+be sent. To limit the messages awaiting publication, set the topic's flow
+control settings before the first call to Publish. Publish then either blocks
+until other messages have been sent, or fails the message's PublishResult:
+
+ topic.PublishSettings.FlowControlSettings = pubsub.FlowControlSettings{
+ 	MaxOutstandingMessages: 1000,
+ 	MaxOutstandingBytes:    100 * 1024 * 1024,
+ 	LimitExceededBehavior:  pubsub.FlowControlBlock, // or pubsub.FlowControlSignalError
+ }
+
+To reduce egress for large, compressible messages such as JSON, enable
+compression. The batches of messages above the threshold are sent gzipped, and
+subscribers receive the messages unchanged:
+
+ topic.PublishSettings.EnableCompression = true
+ topic.PublishSettings.CompressionBytesThreshold = 1024 // the default is 240
+
 
 Receiving
 
@@ -88,6 +107,26 @@ for more details on how streaming pull behaves compared to the synchronous
 pull method.
 
 
+Exactly-Once Delivery
+
+Subscriptions may be created with exactly-once delivery enabled, by setting
+SubscriptionConfig.EnableExactlyOnceDelivery. A message whose acknowledgement
+succeeded is then not redelivered. As acknowledgements can still fail, for
+example when a message's ACK deadline expires first, use AckWithResult to learn
+whether it succeeded:
+
+ err := sub.Receive(context.Background(), func(ctx context.Context, m *Message) {
+ 	status, err := pubsub.AckWithResult(m).Get(ctx)
+ 	if err != nil {
+ 		log.Printf("Ack of %s failed with status %v: %v", m.ID, status, err)
+ 	}
+ })
+
+With exactly-once delivery, the client keeps ACK deadlines at least 60s long,
+and stops extending the deadline of a message once the server reports that its
+ACK ID is no longer valid.
+
+
 Deadlines
 
 The default pubsub deadlines are suitable for most use cases, but may be
@@ -130,11 +169,54 @@ subscription's AckDeadline:
 
 	sub.ReceiveSettings.MaxExtension = cfg.AckDeadline
 
+The extensions can be bounded with ReceiveSettings.MinExtensionPeriod and
+ReceiveSettings.MaxExtensionPeriod. For finer control, SetMaxExtension
+overrides MaxExtension for a single message, and ModifyAckDeadline sets the
+ACK deadline of a message directly, after which the client no longer extends
+it automatically.
+
 
 Slow Message Processing
 
 For use cases where message processing exceeds 30 minutes, we recommend using
 the base client in a pull model, since long-lived streams are periodically killed
 by firewalls. See the example at https://godoc.org/cloud.google.com/go/pubsub/apiv1#example-SubscriberClient-Pull-LengthyClientProcessing
+
+
+Tracing
+
+To trace messages from publishers to subscribers, create the clients with an
+OpenTelemetry tracer provider. Publish then adds the trace context of each
+message to its attributes, and Receive passes the span of receiving each
+message, a child of the span of publishing it, in the context of the callback:
+
+ client, err := pubsub.NewClientWithConfig(ctx, "project-id", &pubsub.ClientConfig{
+ 	OpenTelemetryTracerProvider: otel.GetTracerProvider(),
+ })
+
+Spans are exported by the exporters registered with the tracer provider, such
+as the Cloud Trace exporter.
+
+
+Interceptors
+
+To apply a concern such as encryption, compression or metrics to all messages,
+rather than at every call site, create the client with publish and receive
+interceptors. Each interceptor is called with a message and the rest of the
+chain, which it calls with the message to pass on, possibly changed:
+
+ client, err := pubsub.NewClientWithConfig(ctx, "project-id", &pubsub.ClientConfig{
+ 	PublishInterceptors: []pubsub.PublishInterceptor{
+ 		func(ctx context.Context, m *pubsub.Message, next pubsub.PublishHandler) *pubsub.PublishResult {
+ 			return next(ctx, &pubsub.Message{Data: compress(m.Data), Attributes: m.Attributes})
+ 		},
+ 	},
+ 	ReceiveInterceptors: []pubsub.ReceiveInterceptor{
+ 		func(ctx context.Context, m *pubsub.Message, next pubsub.ReceiveHandler) {
+ 			m.Data = decompress(m.Data)
+ 			next(ctx, m)
+ 		},
+ 	},
+ })
 */
 package pubsub // import "cloud.google.com/go/pubsub"
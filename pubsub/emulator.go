@@ -0,0 +1,123 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EmulatorConfig configures conveniences of a client that uses the Pub/Sub
+// emulator, for hermetic integration tests. It is used only when the
+// PUBSUB_EMULATOR_HOST environment variable is set.
+type EmulatorConfig struct {
+	// AutoCreate makes the client create topics and subscriptions on first
+	// use, if they do not exist: Topic.Publish creates its topic, and
+	// Subscription.Receive and Subscription.PullN create their
+	// subscription, and its topic.
+	AutoCreate bool
+
+	// SubscriptionTopics maps the IDs of subscriptions to the IDs of the
+	// topics, in the same project, that AutoCreate creates them for. A
+	// subscription that is not in SubscriptionTopics is created for the
+	// topic with the same ID.
+	SubscriptionTopics map[string]string
+}
+
+// autoCreator creates the topics and subscriptions used by a client, for
+// EmulatorConfig.AutoCreate. The methods of a nil *autoCreator do nothing.
+type autoCreator struct {
+	c                  *Client
+	subscriptionTopics map[string]string
+
+	mu      sync.Mutex
+	created map[string]bool // names of the resources known to exist
+}
+
+func newAutoCreator(c *Client, cfg *EmulatorConfig) *autoCreator {
+	if cfg == nil || !cfg.AutoCreate {
+		return nil
+	}
+	return &autoCreator{
+		c:                  c,
+		subscriptionTopics: cfg.SubscriptionTopics,
+		created:            map[string]bool{},
+	}
+}
+
+// topic creates the topic with the given full name, if it was not created
+// yet.
+func (a *autoCreator) topic(ctx context.Context, name string) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.createTopic(ctx, name)
+}
+
+func (a *autoCreator) createTopic(ctx context.Context, name string) error {
+	if a.created[name] {
+		return nil
+	}
+	_, err := a.c.pubc.CreateTopic(ctx, &pb.Topic{Name: name})
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		return fmt.Errorf("pubsub: creating topic %s: %v", name, err)
+	}
+	a.created[name] = true
+	return nil
+}
+
+// subscription creates the subscription with the given full name, and its
+// topic, if they were not created yet.
+func (a *autoCreator) subscription(ctx context.Context, name string) error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.created[name] {
+		return nil
+	}
+	// name is "projects/<project>/subscriptions/<id>".
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 {
+		return fmt.Errorf("pubsub: bad subscription name %q", name)
+	}
+	topicID, ok := a.subscriptionTopics[parts[3]]
+	if !ok {
+		topicID = parts[3]
+	}
+	topic := fmt.Sprintf("projects/%s/topics/%s", parts[1], topicID)
+	if err := a.createTopic(ctx, topic); err != nil {
+		return err
+	}
+	_, err := a.c.subc.CreateSubscription(ctx, &pb.Subscription{
+		Name:               name,
+		Topic:              topic,
+		AckDeadlineSeconds: 10, // the default of the service
+	})
+	if err != nil && status.Code(err) != codes.AlreadyExists {
+		return fmt.Errorf("pubsub: creating subscription %s: %v", name, err)
+	}
+	a.created[name] = true
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEmulatorAutoCreate(t *testing.T) {
+	ctx := context.Background()
+	srv := pstest.NewTestServer(t)
+	client, err := NewClientWithConfig(ctx, "P", &ClientConfig{
+		Emulator: &EmulatorConfig{
+			AutoCreate:         true,
+			SubscriptionTopics: map[string]string{"s": "t"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// PullN creates s, and its topic t, before Publish creates t.
+	sub := client.Subscription("s")
+	if msgs, err := sub.PullN(ctx, 1, &PullOptions{AckDeadline: 10 * time.Second}); err != nil || len(msgs) != 0 {
+		t.Fatalf("PullN: got %v, %v; want no messages", msgs, err)
+	}
+	topic := client.Topic("t")
+	defer topic.Stop()
+	if _, err := topic.Publish(ctx, &Message{Data: []byte("hello")}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := sub.PullN(ctx, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || string(msgs[0].Data) != "hello" {
+		t.Fatalf("got %v, want the published message", msgs)
+	}
+	msgs[0].Ack()
+
+	// Receive creates s2 for the topic s2.
+	rctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := client.Subscription("s2").Receive(rctx, func(context.Context, *Message) {}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := client.Subscription("s2").Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Topic.ID(); got != "s2" {
+		t.Errorf("got topic %s, want s2", got)
+	}
+
+	// After a reset, topics that the client created are not created again.
+	srv.Reset()
+	_, err = topic.Publish(ctx, &Message{Data: []byte("hello")}).Get(ctx)
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("publishing after Reset: got %v, want NotFound", err)
+	}
+}
+
+func TestEmulatorNoAutoCreate(t *testing.T) {
+	ctx := context.Background()
+	pstest.NewTestServer(t)
+	client, err := NewClientWithConfig(ctx, "P", &ClientConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Subscription("s").PullN(ctx, 1, nil); status.Code(err) != codes.NotFound {
+		t.Errorf("got %v, want NotFound", err)
+	}
+}
@@ -16,7 +16,12 @@ package pubsub_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"cloud.google.com/go/pubsub"
@@ -70,6 +75,31 @@ func ExampleClient_CreateTopicWithConfig() {
 	_ = topic // TODO: use the topic.
 }
 
+func ExampleClient_CreateTopicWithConfig_awsKinesis() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+
+	// Create a topic that ingests the records of an Amazon Kinesis data stream.
+	topicConfig := &pubsub.TopicConfig{
+		IngestionDataSourceSettings: &pubsub.IngestionDataSourceSettings{
+			Source: &pubsub.IngestionDataSourceAWSKinesis{
+				StreamARN:         "arn:aws:kinesis:us-west-2:111111111111:stream/my-stream",
+				ConsumerARN:       "arn:aws:kinesis:us-west-2:111111111111:stream/my-stream/consumer/my-consumer:1",
+				AWSRoleARN:        "arn:aws:iam::111111111111:role/my-role",
+				GCPServiceAccount: "ingestion@project-id.iam.gserviceaccount.com",
+			},
+		},
+	}
+	topic, err := client.CreateTopicWithConfig(ctx, "topicName", topicConfig)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = topic // TODO: use the topic.
+}
+
 // Use TopicInProject to refer to a topic that is not in the client's project, such
 // as a public topic.
 func ExampleClient_TopicInProject() {
@@ -135,6 +165,95 @@ func ExampleClient_CreateSubscription_neverExpire() {
 	_ = sub // TODO: Use the subscription
 }
 
+func ExampleClient_CreateSubscription_filter() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+
+	topic := client.Topic("topicName")
+
+	// Receive only the orders from Europe.
+	filter := pubsub.AttributeEquals("region", "eu").And(pubsub.AttributeHasPrefix("type", "order."))
+	if err := filter.Validate(); err != nil {
+		// TODO: Handle error.
+	}
+	sub, err := client.CreateSubscription(ctx, "subName", pubsub.SubscriptionConfig{
+		Topic:  topic,
+		Filter: filter.String(),
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = sub // TODO: Use the subscription
+}
+
+func ExampleParseFilter() {
+	f, err := pubsub.ParseFilter(`attributes.region = "eu" AND NOT attributes:test`)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	fmt.Println(f.Matches(map[string]string{"region": "eu"}))
+	// Output: true
+}
+
+func ExampleClient_CreateSubscription_bigQuery() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+
+	topic := client.Topic("topicName")
+
+	// Create a subscription that writes the messages published to the
+	// topic to an existing BigQuery table, with their metadata.
+	sub, err := client.CreateSubscription(ctx, "subName", pubsub.SubscriptionConfig{
+		Topic: topic,
+		BigQueryConfig: pubsub.BigQueryConfig{
+			Table:         "project-id:dataset.table",
+			WriteMetadata: true,
+		},
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	if cfg.BigQueryConfig.State != pubsub.BigQueryConfigActive {
+		// TODO: Handle the subscription being unable to write to the table.
+	}
+}
+
+func ExampleClient_CreateSubscription_cloudStorage() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+
+	topic := client.Topic("topicName")
+
+	// Create a subscription that writes the messages published to the
+	// topic to Avro files in an existing bucket, starting a new file at
+	// least every 5 minutes.
+	sub, err := client.CreateSubscription(ctx, "subName", pubsub.SubscriptionConfig{
+		Topic: topic,
+		CloudStorageConfig: pubsub.CloudStorageConfig{
+			Bucket:       "bucket-name",
+			OutputFormat: &pubsub.CloudStorageOutputFormatAvroConfig{},
+			MaxDuration:  5 * time.Minute,
+		},
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = sub // TODO: Use the subscription
+}
+
 func ExampleTopic_Delete() {
 	ctx := context.Background()
 	client, err := pubsub.NewClient(ctx, "project-id")
@@ -189,6 +308,59 @@ func ExampleTopic_Publish() {
 	}
 }
 
+func ExampleTopic_EnableSchemaValidation() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	schemaClient, err := pubsub.NewSchemaClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+
+	topic := client.Topic("topicName")
+	defer topic.Stop()
+	if err := topic.EnableSchemaValidation(ctx, schemaClient); err != nil {
+		// TODO: Handle error.
+	}
+	r := topic.Publish(ctx, &pubsub.Message{
+		Data: []byte(`{"name": "Alaska", "post_abbr": "AK"}`),
+	})
+	_, err = r.Get(ctx)
+	var verr *pubsub.SchemaValidationError
+	if errors.As(err, &verr) {
+		fmt.Printf("Message does not match schema %s: %v\n", verr.Schema, verr.Err)
+	} else if err != nil {
+		// TODO: Handle error.
+	}
+}
+
+func ExampleTopic_ResumePublish() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+
+	topic := client.Topic("topicName")
+	defer topic.Stop()
+	topic.EnableMessageOrdering = true
+	r := topic.Publish(ctx, &pubsub.Message{
+		Data:        []byte("hello world"),
+		OrderingKey: "key",
+	})
+	if _, err := r.Get(ctx); err != nil {
+		var perr pubsub.ErrPublishingPaused
+		if errors.As(err, &perr) {
+			fmt.Printf("An earlier message with key %q failed to be published\n", perr.OrderingKey)
+		}
+		// TODO: Handle error, for example by publishing the failed messages
+		// again, in order, after resuming publishing for the key.
+		topic.ResumePublish("key")
+	}
+}
+
 func ExampleTopic_Subscriptions() {
 	ctx := context.Background()
 	client, err := pubsub.NewClient(ctx, "project-id")
@@ -209,6 +381,48 @@ func ExampleTopic_Subscriptions() {
 	}
 }
 
+// This example shows how to get the configs of the production subscriptions of
+// a project.
+func ExampleClient_ListSubscriptions() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	it := client.ListSubscriptions(ctx, &pubsub.ListOptions{
+		PageSize: 1000,
+		Labels:   map[string]string{"env": "prod"},
+	})
+	for {
+		cfg, err := it.NextConfig()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		_ = cfg // TODO: use the config.
+	}
+}
+
+func ExampleClient_SubscriptionConfigs() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	subs := []*pubsub.Subscription{client.Subscription("sub1"), client.Subscription("sub2")}
+	cfgs, err := client.SubscriptionConfigs(ctx, subs, 20)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	for i, cfg := range cfgs {
+		if cfg == nil {
+			fmt.Printf("%s does not exist\n", subs[i])
+		}
+	}
+}
+
 func ExampleTopic_Update() {
 	ctx := context.Background()
 	client, err := pubsub.NewClient(ctx, "project-id")
@@ -308,6 +522,35 @@ func ExampleSubscription_Receive() {
 	}
 }
 
+func ExampleSubscription_ReceiveWithDrain() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	// Leave time to finish processing messages before the process is killed.
+	sub.ReceiveSettings.DrainTimeout = 20 * time.Second
+
+	// Drain when the process is asked to terminate, as when a new version of
+	// it is rolled out.
+	stop, cancel := context.WithCancel(ctx)
+	go func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGTERM)
+		<-c
+		cancel()
+	}()
+	stats, err := sub.ReceiveWithDrain(ctx, stop, func(ctx context.Context, m *pubsub.Message) {
+		// TODO: Handle message.
+		m.Ack()
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	fmt.Printf("%d messages left to expire\n", stats.Expired)
+}
+
 // This example shows how to configure keepalive so that unacknoweldged messages
 // expire quickly, allowing other subscribers to take them.
 func ExampleSubscription_Receive_maxExtension() {
@@ -350,6 +593,144 @@ func ExampleSubscription_Receive_maxOutstanding() {
 	}
 }
 
+// This example shows how to monitor Subscription.Receive, to help tune its
+// ReceiveSettings.
+func ExampleSubscription_ReceiveStatus() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	go func() {
+		var last pubsub.ReceiveStatus
+		for range time.Tick(time.Minute) {
+			st := sub.ReceiveStatus()
+			log.Printf("outstanding: %d messages, %d bytes; callbacks: %d; acks/min: %d",
+				st.OutstandingMessages, st.OutstandingBytes, st.ActiveCallbacks, st.Acks-last.Acks)
+			last = st
+		}
+	}()
+	err = sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		// TODO: Handle message.
+		m.Ack()
+	})
+	if err != context.Canceled {
+		// TODO: Handle error.
+	}
+}
+
+// This example shows how to learn whether the acknowledgement of a message
+// succeeded, on a subscription with exactly-once delivery enabled.
+func ExampleSubscription_PullN() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	msgs, err := sub.PullN(ctx, 100, &pubsub.PullOptions{AckDeadline: time.Minute})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	for _, m := range msgs {
+		fmt.Printf("Got message: %q\n", string(m.Data))
+		m.Ack()
+	}
+}
+
+func ExampleAckWithResult() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	err = sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		// TODO: Handle message.
+		status, err := pubsub.AckWithResult(m).Get(ctx)
+		if err != nil {
+			// TODO: Handle error. If status is
+			// pubsub.AcknowledgeStatusInvalidAckID, the message will be
+			// redelivered.
+		}
+		_ = status
+	})
+	if err != context.Canceled {
+		// TODO: Handle error.
+	}
+}
+
+// This example shows how to manage the lease of messages whose processing
+// time is known in advance, rather than relying on automatic extension.
+func ExampleModifyAckDeadline() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	err = sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		// The job described by the message takes up to 5 minutes.
+		if err := pubsub.ModifyAckDeadline(ctx, m, 5*time.Minute); err != nil {
+			// TODO: Handle error.
+		}
+		// TODO: Handle message.
+		m.Ack()
+	})
+	if err != context.Canceled {
+		// TODO: Handle error.
+	}
+}
+
+func ExampleSubscription_EnableDeadLettering() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	cfg, err := sub.EnableDeadLettering(ctx, pubsub.DeadLetteringConfig{
+		DeadLetterTopic:     client.Topic("dead-letter-topic"),
+		MaxDeliveryAttempts: 10,
+		ProjectNumber:       123456789012, // The number of project-id.
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = cfg // TODO: Use SubscriptionConfig.
+
+	err = sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		if m.DeliveryAttempt != nil {
+			fmt.Printf("Delivery attempt %d of message %s\n", *m.DeliveryAttempt, m.ID)
+		}
+		m.Ack()
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+}
+
+func ExampleSubscription_SwitchToPush() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	pc, err := pubsub.OIDCPushConfig("https://example.com/push", "push@project-id.iam.gserviceaccount.com", "")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// Deliver the data of messages as the bodies of the push requests.
+	pc.Wrapper = &pubsub.NoWrapper{WriteMetadata: true}
+	subConfig, err := sub.SwitchToPush(ctx, *pc)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = subConfig // TODO: Use SubscriptionConfig.
+}
+
 func ExampleSubscription_Update() {
 	ctx := context.Background()
 	client, err := pubsub.NewClient(ctx, "project-id")
@@ -429,6 +810,31 @@ func ExampleSubscription_SeekToTime() {
 	}
 }
 
+func ExampleSubscription_ReplaySince() {
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project-id")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	sub := client.Subscription("subName")
+	go func() {
+		err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+			// TODO: Handle message.
+			m.Ack()
+		})
+		if err != nil {
+			// TODO: Handle error.
+		}
+	}()
+
+	// Later, to reprocess the messages of the last hour, for example after
+	// fixing a bug in their handling. The running Receive is paused while
+	// seeking, and then receives the messages again.
+	if err := sub.ReplaySince(ctx, time.Now().Add(-time.Hour)); err != nil {
+		// TODO: Handle error.
+	}
+}
+
 func ExampleSnapshot_Delete() {
 	ctx := context.Background()
 	client, err := pubsub.NewClient(ctx, "project-id")
@@ -0,0 +1,435 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"time"
+
+	"cloud.google.com/go/pubsub/internal/wirefield"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SubscriptionState denotes the possible states of a subscription.
+type SubscriptionState int
+
+const (
+	// SubscriptionStateUnspecified is the default value. This value is unused.
+	SubscriptionStateUnspecified SubscriptionState = iota
+
+	// SubscriptionStateActive means the subscription can actively send messages to the target.
+	SubscriptionStateActive
+
+	// SubscriptionStateResourceError means the subscription cannot receive messages because of an
+	// error with the resource to which it pushes messages.
+	// See the more detailed error state in the corresponding configuration.
+	SubscriptionStateResourceError
+)
+
+// BigQueryConfigState denotes the possible states of a BigQuery subscription.
+type BigQueryConfigState int
+
+const (
+	// BigQueryConfigStateUnspecified is the default value. This value is unused.
+	BigQueryConfigStateUnspecified BigQueryConfigState = iota
+
+	// BigQueryConfigActive means the subscription can actively send messages to BigQuery.
+	BigQueryConfigActive
+
+	// BigQueryConfigPermissionDenied means the subscription cannot write to the BigQuery
+	// table because of permission denied errors.
+	BigQueryConfigPermissionDenied
+
+	// BigQueryConfigNotFound means the subscription cannot write to the BigQuery table
+	// because it does not exist.
+	BigQueryConfigNotFound
+
+	// BigQueryConfigSchemaMismatch means the subscription cannot write to the BigQuery
+	// table due to a schema mismatch.
+	BigQueryConfigSchemaMismatch
+
+	// BigQueryConfigInTransitLocationRestriction means the subscription cannot write to
+	// the BigQuery table because the table is not in an allowed region of the
+	// topic's message storage policy.
+	BigQueryConfigInTransitLocationRestriction
+)
+
+// BigQueryConfig configures the subscription to deliver to a BigQuery table.
+type BigQueryConfig struct {
+	// The name of the table to which to write data, of the form
+	// {projectId}:{datasetId}.{tableId}
+	Table string
+
+	// When true, use the topic's schema as the columns to write to in BigQuery,
+	// if it exists. UseTopicSchema and UseTableSchema cannot both be true.
+	UseTopicSchema bool
+
+	// When true, use the BigQuery table's schema as the columns to write to in
+	// BigQuery. Messages must be published in JSON format.
+	UseTableSchema bool
+
+	// When true, write the subscription name, message_id, publish_time,
+	// attributes, and ordering_key to additional columns in the table. The
+	// subscription name, message_id, and publish_time fields are put in their own
+	// columns while all other message properties (other than data) are written to
+	// a JSON object in the attributes column.
+	WriteMetadata bool
+
+	// When true and use_topic_schema is true, any fields that are a part of the
+	// topic schema that are not part of the BigQuery table schema are dropped when
+	// writing to BigQuery. Otherwise, the schemas must be kept in sync and any
+	// messages with extra fields are not written and remain in the subscription's
+	// backlog.
+	DropUnknownFields bool
+
+	// This is an output-only field that indicates whether or not the subscription can
+	// receive messages. This field is set only in responses from the server;
+	// it is ignored if it is set in any requests.
+	State BigQueryConfigState
+}
+
+// CloudStorageConfigState denotes the possible states of a Cloud Storage
+// subscription.
+type CloudStorageConfigState int
+
+const (
+	// CloudStorageConfigStateUnspecified is the default value. This value is unused.
+	CloudStorageConfigStateUnspecified CloudStorageConfigState = iota
+
+	// CloudStorageConfigActive means the subscription can actively send messages to
+	// Cloud Storage.
+	CloudStorageConfigActive
+
+	// CloudStorageConfigPermissionDenied means the subscription cannot write to the
+	// Cloud Storage bucket because of permission denied errors.
+	CloudStorageConfigPermissionDenied
+
+	// CloudStorageConfigNotFound means the subscription cannot write to the Cloud
+	// Storage bucket because it does not exist.
+	CloudStorageConfigNotFound
+
+	// CloudStorageConfigInTransitLocationRestriction means the subscription cannot
+	// write to the Cloud Storage bucket because the bucket is not in an allowed
+	// region of the topic's message storage policy.
+	CloudStorageConfigInTransitLocationRestriction
+
+	// CloudStorageConfigSchemaMismatch means the subscription cannot write to the
+	// Cloud Storage bucket due to a schema mismatch.
+	CloudStorageConfigSchemaMismatch
+)
+
+// CloudStorageOutputFormat is the format of the files that a Cloud Storage
+// subscription writes: a *CloudStorageOutputFormatTextConfig or a
+// *CloudStorageOutputFormatAvroConfig.
+type CloudStorageOutputFormat interface {
+	isCloudStorageOutputFormat()
+}
+
+// CloudStorageOutputFormatTextConfig writes the data of each message to the
+// files as raw text, separated by a newline.
+type CloudStorageOutputFormatTextConfig struct{}
+
+// CloudStorageOutputFormatAvroConfig writes messages to the files in Avro
+// format.
+type CloudStorageOutputFormatAvroConfig struct {
+	// When true, write the subscription name, message_id, publish_time,
+	// attributes, and ordering_key as additional fields in the output.
+	WriteMetadata bool
+
+	// When true, the output Cloud Storage file will be serialized using the
+	// topic schema, if it exists.
+	UseTopicSchema bool
+}
+
+func (*CloudStorageOutputFormatTextConfig) isCloudStorageOutputFormat() {}
+func (*CloudStorageOutputFormatAvroConfig) isCloudStorageOutputFormat() {}
+
+// CloudStorageConfig configures the subscription to deliver to Cloud Storage.
+type CloudStorageConfig struct {
+	// User-provided name for the Cloud Storage bucket. The bucket must be
+	// created by the user. The bucket name must be without any prefix like
+	// "gs://".
+	Bucket string
+
+	// User-provided prefix for Cloud Storage filename.
+	FilenamePrefix string
+
+	// User-provided suffix for Cloud Storage filename. Must not end in "/".
+	FilenameSuffix string
+
+	// User-provided format string specifying how to represent datetimes in
+	// Cloud Storage filenames, such as "YYYY-MM-DD/hh_mm_ssZ".
+	FilenameDatetimeFormat string
+
+	// OutputFormat is the format of the files. If nil, the files are written
+	// as text.
+	OutputFormat CloudStorageOutputFormat
+
+	// The maximum duration that can elapse before a new Cloud Storage file is
+	// created. Min 1 minute, max 10 minutes, default 5 minutes. May not exceed
+	// the subscription's acknowledgement deadline.
+	MaxDuration time.Duration
+
+	// The maximum bytes that can be written to a Cloud Storage file before a new
+	// file is created. Min 1 KB, max 10 GiB. The max_bytes limit may be exceeded
+	// in cases where messages are larger than the limit.
+	MaxBytes int64
+
+	// The maximum number of messages that can be written to a Cloud Storage file
+	// before a new file is created. Min 1000 messages.
+	MaxMessages int64
+
+	// This is an output-only field that indicates whether or not the subscription can
+	// receive messages. This field is set only in responses from the server;
+	// it is ignored if it is set in any requests.
+	State CloudStorageConfigState
+}
+
+// The generated protos of the API version this package uses do not have the
+// fields of export subscriptions, so they are encoded and decoded here, and
+// carried as unknown fields of pb.Subscription. These are the field numbers
+// of google.pubsub.v1.Subscription and of its BigQueryConfig and
+// CloudStorageConfig messages.
+const (
+	subscriptionBigQueryConfigField     = 18
+	subscriptionStateField              = 19
+	subscriptionCloudStorageConfigField = 22
+
+	bqTableField             = 1
+	bqUseTopicSchemaField    = 2
+	bqWriteMetadataField     = 3
+	bqDropUnknownFieldsField = 4
+	bqStateField             = 5
+	bqUseTableSchemaField    = 6
+
+	csBucketField                 = 1
+	csFilenamePrefixField         = 2
+	csFilenameSuffixField         = 3
+	csTextConfigField             = 4
+	csAvroConfigField             = 5
+	csMaxDurationField            = 6
+	csMaxBytesField               = 7
+	csMaxMessagesField            = 8
+	csStateField                  = 9
+	csFilenameDatetimeFormatField = 10
+
+	avroWriteMetadataField  = 1
+	avroUseTopicSchemaField = 2
+
+	durationSecondsField = 1
+	durationNanosField   = 2
+)
+
+// setBigQueryConfig sets the BigQuery configuration of psub. A nil or empty
+// configuration clears it.
+func setBigQueryConfig(psub *pb.Subscription, bc *BigQueryConfig) {
+	m := psub.ProtoReflect()
+	if bc == nil || bc.Table == "" {
+		wirefield.Clear(m, subscriptionBigQueryConfigField)
+		return
+	}
+	wirefield.SetBytes(m, subscriptionBigQueryConfigField, bc.encode())
+}
+
+// setCloudStorageConfig sets the Cloud Storage configuration of psub. A nil
+// or empty configuration clears it.
+func setCloudStorageConfig(psub *pb.Subscription, cc *CloudStorageConfig) {
+	m := psub.ProtoReflect()
+	if cc == nil || cc.Bucket == "" {
+		wirefield.Clear(m, subscriptionCloudStorageConfigField)
+		return
+	}
+	wirefield.SetBytes(m, subscriptionCloudStorageConfigField, cc.encode())
+}
+
+// exportConfigs returns the BigQuery and Cloud Storage configurations and the
+// state of psub.
+func exportConfigs(psub *pb.Subscription) (BigQueryConfig, CloudStorageConfig, SubscriptionState) {
+	var bc BigQueryConfig
+	var cc CloudStorageConfig
+	m := psub.ProtoReflect()
+	if b, ok := wirefield.Bytes(m, subscriptionBigQueryConfigField); ok {
+		bc = decodeBigQueryConfig(b)
+	}
+	if b, ok := wirefield.Bytes(m, subscriptionCloudStorageConfigField); ok {
+		cc = decodeCloudStorageConfig(b)
+	}
+	state, _ := wirefield.Varint(m, subscriptionStateField)
+	return bc, cc, SubscriptionState(state)
+}
+
+func (bc *BigQueryConfig) encode() []byte {
+	var b []byte
+	b = appendString(b, bqTableField, bc.Table)
+	b = appendBool(b, bqUseTopicSchemaField, bc.UseTopicSchema)
+	b = appendBool(b, bqWriteMetadataField, bc.WriteMetadata)
+	b = appendBool(b, bqDropUnknownFieldsField, bc.DropUnknownFields)
+	b = appendBool(b, bqUseTableSchemaField, bc.UseTableSchema)
+	return b
+}
+
+func decodeBigQueryConfig(b []byte) BigQueryConfig {
+	var bc BigQueryConfig
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		switch {
+		case num == bqTableField && typ == protowire.BytesType:
+			bc.Table = string(v)
+		case typ != protowire.VarintType:
+		case num == bqUseTopicSchemaField:
+			bc.UseTopicSchema = varint(v) != 0
+		case num == bqWriteMetadataField:
+			bc.WriteMetadata = varint(v) != 0
+		case num == bqDropUnknownFieldsField:
+			bc.DropUnknownFields = varint(v) != 0
+		case num == bqUseTableSchemaField:
+			bc.UseTableSchema = varint(v) != 0
+		case num == bqStateField:
+			bc.State = BigQueryConfigState(varint(v))
+		}
+		return true
+	})
+	return bc
+}
+
+func (cc *CloudStorageConfig) encode() []byte {
+	var b []byte
+	b = appendString(b, csBucketField, cc.Bucket)
+	b = appendString(b, csFilenamePrefixField, cc.FilenamePrefix)
+	b = appendString(b, csFilenameSuffixField, cc.FilenameSuffix)
+	switch f := cc.OutputFormat.(type) {
+	case *CloudStorageOutputFormatTextConfig:
+		b = appendMessage(b, csTextConfigField, nil)
+	case *CloudStorageOutputFormatAvroConfig:
+		var ab []byte
+		ab = appendBool(ab, avroWriteMetadataField, f.WriteMetadata)
+		ab = appendBool(ab, avroUseTopicSchemaField, f.UseTopicSchema)
+		b = appendMessage(b, csAvroConfigField, ab)
+	}
+	if cc.MaxDuration != 0 {
+		var db []byte
+		db = appendVarint(db, durationSecondsField, uint64(cc.MaxDuration/time.Second))
+		db = appendVarint(db, durationNanosField, uint64(cc.MaxDuration%time.Second))
+		b = appendMessage(b, csMaxDurationField, db)
+	}
+	b = appendVarint(b, csMaxBytesField, uint64(cc.MaxBytes))
+	b = appendVarint(b, csMaxMessagesField, uint64(cc.MaxMessages))
+	b = appendString(b, csFilenameDatetimeFormatField, cc.FilenameDatetimeFormat)
+	return b
+}
+
+func decodeCloudStorageConfig(b []byte) CloudStorageConfig {
+	var cc CloudStorageConfig
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		if typ == protowire.BytesType {
+			switch num {
+			case csBucketField:
+				cc.Bucket = string(v)
+			case csFilenamePrefixField:
+				cc.FilenamePrefix = string(v)
+			case csFilenameSuffixField:
+				cc.FilenameSuffix = string(v)
+			case csFilenameDatetimeFormatField:
+				cc.FilenameDatetimeFormat = string(v)
+			case csTextConfigField:
+				cc.OutputFormat = &CloudStorageOutputFormatTextConfig{}
+			case csAvroConfigField:
+				cc.OutputFormat = decodeAvroConfig(v)
+			case csMaxDurationField:
+				cc.MaxDuration = decodeDuration(v)
+			}
+			return true
+		}
+		if typ == protowire.VarintType {
+			switch num {
+			case csMaxBytesField:
+				cc.MaxBytes = int64(varint(v))
+			case csMaxMessagesField:
+				cc.MaxMessages = int64(varint(v))
+			case csStateField:
+				cc.State = CloudStorageConfigState(varint(v))
+			}
+		}
+		return true
+	})
+	return cc
+}
+
+func decodeAvroConfig(b []byte) *CloudStorageOutputFormatAvroConfig {
+	ac := &CloudStorageOutputFormatAvroConfig{}
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		if typ == protowire.VarintType {
+			switch num {
+			case avroWriteMetadataField:
+				ac.WriteMetadata = varint(v) != 0
+			case avroUseTopicSchemaField:
+				ac.UseTopicSchema = varint(v) != 0
+			}
+		}
+		return true
+	})
+	return ac
+}
+
+func decodeDuration(b []byte) time.Duration {
+	var d time.Duration
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		if typ == protowire.VarintType {
+			switch num {
+			case durationSecondsField:
+				d += time.Duration(int64(varint(v))) * time.Second
+			case durationNanosField:
+				d += time.Duration(int32(varint(v)))
+			}
+		}
+		return true
+	})
+	return d
+}
+
+// The append functions omit fields with default values, as proto3 does.
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarint(b, num, 1)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// appendMessage appends a message field, even if the message is empty.
+func appendMessage(b []byte, num protowire.Number, m []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m)
+}
+
+func varint(v []byte) uint64 {
+	x, _ := protowire.ConsumeVarint(v)
+	return x
+}
@@ -0,0 +1,493 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxFilterLength is the maximum length in bytes of a subscription filter.
+const MaxFilterLength = 256
+
+// A Filter is a subscription filter expression, which selects the messages
+// delivered to a subscription by their attributes. Build filters with
+// AttributeExists, AttributeEquals, AttributeNotEquals and
+// AttributeHasPrefix, combine them with the And, Or and Not methods, and set
+// the result as SubscriptionConfig.Filter:
+//
+//	f := pubsub.AttributeEquals("region", "eu").And(pubsub.AttributeHasPrefix("type", "order."))
+//	cfg := pubsub.SubscriptionConfig{Topic: topic, Filter: f.String()}
+//
+// ParseFilter checks a filter written by hand. The zero Filter is not valid.
+//
+// See https://cloud.google.com/pubsub/docs/filtering for the filter syntax.
+type Filter struct {
+	op       filterOp
+	key      string
+	value    string
+	operands []Filter // of opNot, opAnd and opOr
+}
+
+type filterOp int
+
+const (
+	opInvalid filterOp = iota
+	opExists
+	opEquals
+	opNotEquals
+	opHasPrefix
+	opNot
+	opAnd
+	opOr
+)
+
+// AttributeExists returns a filter that matches messages that have the
+// attribute key.
+func AttributeExists(key string) Filter {
+	return Filter{op: opExists, key: key}
+}
+
+// AttributeEquals returns a filter that matches messages whose attribute key
+// is value.
+func AttributeEquals(key, value string) Filter {
+	return Filter{op: opEquals, key: key, value: value}
+}
+
+// AttributeNotEquals returns a filter that matches messages whose attribute
+// key is not value.
+func AttributeNotEquals(key, value string) Filter {
+	return Filter{op: opNotEquals, key: key, value: value}
+}
+
+// AttributeHasPrefix returns a filter that matches messages whose attribute
+// key starts with prefix.
+func AttributeHasPrefix(key, prefix string) Filter {
+	return Filter{op: opHasPrefix, key: key, value: prefix}
+}
+
+// And returns a filter that matches messages that f and all of others match.
+func (f Filter) And(others ...Filter) Filter {
+	return f.combine(opAnd, others)
+}
+
+// Or returns a filter that matches messages that f or any of others match.
+func (f Filter) Or(others ...Filter) Filter {
+	return f.combine(opOr, others)
+}
+
+func (f Filter) combine(op filterOp, others []Filter) Filter {
+	if len(others) == 0 {
+		return f
+	}
+	var operands []Filter
+	for _, g := range append([]Filter{f}, others...) {
+		if g.op == op {
+			operands = append(operands, g.operands...)
+		} else {
+			operands = append(operands, g)
+		}
+	}
+	return Filter{op: op, operands: operands}
+}
+
+// Not returns a filter that matches the messages that f does not match.
+func (f Filter) Not() Filter {
+	return Filter{op: opNot, operands: []Filter{f}}
+}
+
+// String returns the filter expression, for SubscriptionConfig.Filter.
+func (f Filter) String() string {
+	var b strings.Builder
+	f.write(&b)
+	return b.String()
+}
+
+func (f Filter) write(b *strings.Builder) {
+	switch f.op {
+	case opExists:
+		b.WriteString("attributes:")
+		writeFilterKey(b, f.key)
+	case opEquals, opNotEquals:
+		b.WriteString("attributes.")
+		writeFilterKey(b, f.key)
+		if f.op == opEquals {
+			b.WriteString(" = ")
+		} else {
+			b.WriteString(" != ")
+		}
+		b.WriteString(strconv.Quote(f.value))
+	case opHasPrefix:
+		b.WriteString("hasPrefix(attributes.")
+		writeFilterKey(b, f.key)
+		b.WriteString(", ")
+		b.WriteString(strconv.Quote(f.value))
+		b.WriteString(")")
+	case opNot:
+		b.WriteString("NOT ")
+		f.operands[0].writeOperand(b)
+	case opAnd, opOr:
+		sep := " AND "
+		if f.op == opOr {
+			sep = " OR "
+		}
+		for i, g := range f.operands {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			g.writeOperand(b)
+		}
+	}
+}
+
+// writeOperand writes f as an operand of NOT, AND or OR, in parentheses if
+// it is itself a conjunction or disjunction.
+func (f Filter) writeOperand(b *strings.Builder) {
+	if f.op == opAnd || f.op == opOr {
+		b.WriteString("(")
+		f.write(b)
+		b.WriteString(")")
+		return
+	}
+	f.write(b)
+}
+
+func writeFilterKey(b *strings.Builder, key string) {
+	if isFilterIdent(key) {
+		b.WriteString(key)
+		return
+	}
+	b.WriteString(strconv.Quote(key))
+}
+
+func isFilterIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate reports whether the service can accept f: whether it is built from
+// the filter functions, with non-empty attribute keys, and is at most
+// MaxFilterLength bytes long.
+func (f Filter) Validate() error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	if n := len(f.String()); n > MaxFilterLength {
+		return fmt.Errorf("pubsub: filter is %d bytes long, more than the maximum of %d", n, MaxFilterLength)
+	}
+	return nil
+}
+
+func (f Filter) validate() error {
+	switch f.op {
+	case opInvalid:
+		return errors.New("pubsub: invalid zero Filter")
+	case opExists, opEquals, opNotEquals, opHasPrefix:
+		if f.key == "" {
+			return errors.New("pubsub: filter has an empty attribute key")
+		}
+	default:
+		for _, g := range f.operands {
+			if err := g.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a message with the attributes attrs passes the
+// filter. It evaluates the filter locally, to preview its effect before
+// creating a subscription with it.
+func (f Filter) Matches(attrs map[string]string) bool {
+	v, ok := attrs[f.key]
+	switch f.op {
+	case opExists:
+		return ok
+	case opEquals:
+		return ok && v == f.value
+	case opNotEquals:
+		return ok && v != f.value
+	case opHasPrefix:
+		return ok && strings.HasPrefix(v, f.value)
+	case opNot:
+		return !f.operands[0].Matches(attrs)
+	case opAnd:
+		for _, g := range f.operands {
+			if !g.Matches(attrs) {
+				return false
+			}
+		}
+		return true
+	case opOr:
+		for _, g := range f.operands {
+			if g.Matches(attrs) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// Estimate returns the fraction of the sample messages that pass the filter,
+// as an estimate of the share of a topic's messages that a subscription with
+// the filter would receive. It returns 0 for an empty sample.
+func (f Filter) Estimate(sample []*Message) float64 {
+	if len(sample) == 0 {
+		return 0
+	}
+	var n int
+	for _, m := range sample {
+		if f.Matches(m.Attributes) {
+			n++
+		}
+	}
+	return float64(n) / float64(len(sample))
+}
+
+// ParseFilter parses a filter expression, such as
+//
+//	attributes.region = "eu" AND NOT hasPrefix(attributes.type, "test.")
+//
+// and returns an error describing the first syntax error in it, if any, or if
+// it is longer than MaxFilterLength. It catches locally the mistakes that
+// would otherwise fail the creation of a subscription.
+func ParseFilter(s string) (Filter, error) {
+	if len(s) > MaxFilterLength {
+		return Filter{}, fmt.Errorf("pubsub: filter is %d bytes long, more than the maximum of %d", len(s), MaxFilterLength)
+	}
+	p := &filterParser{s: s}
+	p.next()
+	f := p.parseExpr()
+	if p.err == nil && p.tok.kind != tokEOF {
+		p.fail("unexpected %s", p.tok)
+	}
+	if p.err != nil {
+		return Filter{}, p.err
+	}
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokPunct // one of ( ) : . , = != -
+)
+
+type filterToken struct {
+	kind tokenKind
+	text string // for strings, the unquoted value
+	pos  int
+}
+
+func (t filterToken) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type filterParser struct {
+	s   string
+	pos int
+	tok filterToken
+	err error
+}
+
+func (p *filterParser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("pubsub: invalid filter %q at offset %d: %s", p.s, p.tok.pos, fmt.Sprintf(format, args...))
+	}
+	p.tok = filterToken{kind: tokEOF, pos: len(p.s)}
+}
+
+// next reads the next token into p.tok.
+func (p *filterParser) next() {
+	if p.err != nil {
+		return
+	}
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	start := p.pos
+	p.tok = filterToken{pos: start}
+	if p.pos == len(p.s) {
+		return
+	}
+	switch c := p.s[p.pos]; {
+	case c == '"':
+		end := start + 1
+		for ; end < len(p.s) && p.s[end] != '"'; end++ {
+			if p.s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(p.s) {
+			p.fail("unterminated string")
+			return
+		}
+		v, err := strconv.Unquote(p.s[start : end+1])
+		if err != nil {
+			p.fail("invalid string %s", p.s[start:end+1])
+			return
+		}
+		p.pos = end + 1
+		p.tok.kind, p.tok.text = tokString, v
+	case c == '!':
+		if !strings.HasPrefix(p.s[p.pos:], "!=") {
+			p.fail("unexpected %q", "!")
+			return
+		}
+		p.pos += 2
+		p.tok.kind, p.tok.text = tokPunct, "!="
+	case strings.IndexByte("():.,=-", c) >= 0:
+		p.pos++
+		p.tok.kind, p.tok.text = tokPunct, string(c)
+	default:
+		end := start
+		for end < len(p.s) && isFilterIdent(p.s[start:end+1]) {
+			end++
+		}
+		if end == start {
+			p.fail("unexpected %q", c)
+			return
+		}
+		p.pos = end
+		p.tok.kind, p.tok.text = tokIdent, p.s[start:end]
+	}
+}
+
+func (p *filterParser) is(kind tokenKind, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+func (p *filterParser) expect(kind tokenKind, text string) {
+	if !p.is(kind, text) {
+		p.fail("got %s, want %q", p.tok, text)
+		return
+	}
+	p.next()
+}
+
+// parseExpr parses terms joined by AND or by OR, which cannot be mixed
+// without parentheses.
+func (p *filterParser) parseExpr() Filter {
+	f := p.parseTerm()
+	var op string
+	for p.is(tokIdent, "AND") || p.is(tokIdent, "OR") {
+		if op == "" {
+			op = p.tok.text
+		} else if p.tok.text != op {
+			p.fail("AND and OR must be separated by parentheses")
+			return Filter{}
+		}
+		p.next()
+		g := p.parseTerm()
+		if op == "AND" {
+			f = f.And(g)
+		} else {
+			f = f.Or(g)
+		}
+	}
+	return f
+}
+
+// parseTerm parses a primary expression, possibly negated with NOT or "-".
+func (p *filterParser) parseTerm() Filter {
+	if p.is(tokIdent, "NOT") || p.is(tokPunct, "-") {
+		p.next()
+		return p.parseTerm().Not()
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() Filter {
+	switch {
+	case p.is(tokPunct, "("):
+		p.next()
+		f := p.parseExpr()
+		p.expect(tokPunct, ")")
+		return f
+	case p.is(tokIdent, "attributes"):
+		p.next()
+		if p.is(tokPunct, ":") {
+			p.next()
+			return AttributeExists(p.parseKey())
+		}
+		p.expect(tokPunct, ".")
+		key := p.parseKey()
+		var op filterOp
+		switch {
+		case p.is(tokPunct, "="):
+			op = opEquals
+		case p.is(tokPunct, "!="):
+			op = opNotEquals
+		default:
+			p.fail("got %s, want \"=\" or \"!=\"", p.tok)
+			return Filter{}
+		}
+		p.next()
+		return Filter{op: op, key: key, value: p.parseString()}
+	case p.is(tokIdent, "hasPrefix"):
+		p.next()
+		p.expect(tokPunct, "(")
+		p.expect(tokIdent, "attributes")
+		p.expect(tokPunct, ".")
+		key := p.parseKey()
+		p.expect(tokPunct, ",")
+		prefix := p.parseString()
+		p.expect(tokPunct, ")")
+		return AttributeHasPrefix(key, prefix)
+	}
+	p.fail("got %s, want an attribute condition", p.tok)
+	return Filter{}
+}
+
+// parseKey parses an attribute key, an identifier or a string.
+func (p *filterParser) parseKey() string {
+	if p.tok.kind != tokIdent && p.tok.kind != tokString || p.tok.text == "" {
+		p.fail("got %s, want an attribute key", p.tok)
+		return ""
+	}
+	key := p.tok.text
+	p.next()
+	return key
+}
+
+func (p *filterParser) parseString() string {
+	if p.tok.kind != tokString {
+		p.fail("got %s, want a string", p.tok)
+		return ""
+	}
+	v := p.tok.text
+	p.next()
+	return v
+}
@@ -0,0 +1,148 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterString(t *testing.T) {
+	for _, test := range []struct {
+		f    Filter
+		want string
+	}{
+		{AttributeExists("region"), `attributes:region`},
+		{AttributeEquals("region", "eu"), `attributes.region = "eu"`},
+		{AttributeNotEquals("my-key", `a "b"`), `attributes."my-key" != "a \"b\""`},
+		{AttributeHasPrefix("type", "order."), `hasPrefix(attributes.type, "order.")`},
+		{AttributeExists("a").Not(), `NOT attributes:a`},
+		{
+			AttributeExists("a").And(AttributeExists("b"), AttributeExists("c").And(AttributeExists("d"))),
+			`attributes:a AND attributes:b AND attributes:c AND attributes:d`,
+		},
+		{
+			AttributeExists("a").Or(AttributeExists("b")).And(AttributeExists("c").Or(AttributeExists("d")).Not()),
+			`(attributes:a OR attributes:b) AND NOT (attributes:c OR attributes:d)`,
+		},
+	} {
+		got := test.f.String()
+		if got != test.want {
+			t.Errorf("got %s, want %s", got, test.want)
+		}
+		if err := test.f.Validate(); err != nil {
+			t.Errorf("%s: %v", got, err)
+		}
+		// The expression parses back to the same filter.
+		f, err := ParseFilter(got)
+		if err != nil {
+			t.Errorf("%s: %v", got, err)
+		} else if f.String() != got {
+			t.Errorf("%s: parsed as %s", got, f)
+		}
+	}
+
+	for _, f := range []Filter{
+		{},
+		AttributeExists(""),
+		AttributeExists("a").And(Filter{}),
+		AttributeEquals("a", strings.Repeat("x", MaxFilterLength)),
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("%#v: got nil, want error", f)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{`attributes.x="y"`, `attributes.x = "y"`},
+		{` -attributes:x `, `NOT attributes:x`},
+		{`NOT NOT attributes:x`, `NOT NOT attributes:x`},
+		{`(attributes:a)`, `attributes:a`},
+		{`attributes:a AND (attributes:b OR hasPrefix( attributes.c , "d" ))`, `attributes:a AND (attributes:b OR hasPrefix(attributes.c, "d"))`},
+		{`attributes."a.b" = "é"`, `attributes."a.b" = "é"`},
+	} {
+		f, err := ParseFilter(test.in)
+		if err != nil {
+			t.Errorf("%s: %v", test.in, err)
+			continue
+		}
+		if got := f.String(); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.in, got, test.want)
+		}
+	}
+
+	for _, in := range []string{
+		``,
+		`attributes`,
+		`attributes.x`,
+		`attributes.x = y`,
+		`attributes.x == "y"`,
+		`attributes:""`,
+		`attributes:x AND attributes:y OR attributes:z`,
+		`attributes:x AND`,
+		`(attributes:x`,
+		`attributes:x)`,
+		`hasPrefix(attributes.x)`,
+		`attributes.x = "y`,
+		`attributes.x ! "y"`,
+		`labels.x = "y"`,
+		`attributes:x and attributes:y`,
+		`attributes.x = "` + strings.Repeat("y", MaxFilterLength) + `"`,
+	} {
+		if _, err := ParseFilter(in); err == nil {
+			t.Errorf("%s: got nil, want error", in)
+		}
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	f, err := ParseFilter(`attributes.region = "eu" AND NOT hasPrefix(attributes.type, "test.") AND (attributes:id OR attributes.v != "1")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		attrs map[string]string
+		want  bool
+	}{
+		{nil, false},
+		{map[string]string{"region": "eu", "id": ""}, true},
+		{map[string]string{"region": "us", "id": ""}, false},
+		{map[string]string{"region": "eu", "type": "test.x", "id": ""}, false},
+		{map[string]string{"region": "eu", "type": "order", "v": "2"}, true},
+		{map[string]string{"region": "eu", "v": "1"}, false},
+		{map[string]string{"region": "eu"}, false},
+	} {
+		if got := f.Matches(test.attrs); got != test.want {
+			t.Errorf("%v: got %t, want %t", test.attrs, got, test.want)
+		}
+	}
+
+	sample := []*Message{
+		{Attributes: map[string]string{"region": "eu", "id": "1"}},
+		{Attributes: map[string]string{"region": "us", "id": "2"}},
+		{},
+		{Attributes: map[string]string{"region": "eu", "v": "2"}},
+	}
+	if got, want := f.Estimate(sample), 0.5; got != want {
+		t.Errorf("Estimate: got %v, want %v", got, want)
+	}
+	if got := f.Estimate(nil); got != 0 {
+		t.Errorf("Estimate of no messages: got %v, want 0", got)
+	}
+}
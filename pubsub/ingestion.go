@@ -0,0 +1,371 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"time"
+
+	"cloud.google.com/go/pubsub/internal/wirefield"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// TopicState denotes the possible states of a topic.
+type TopicState int
+
+const (
+	// TopicStateUnspecified is the default value. This value is unused.
+	TopicStateUnspecified TopicState = iota
+
+	// TopicStateActive means the topic does not have any persistent errors.
+	TopicStateActive
+
+	// TopicStateIngestionResourceError means ingestion from the data source
+	// has encountered a permanent error.
+	// See the more detailed error state in the corresponding ingestion
+	// source configuration.
+	TopicStateIngestionResourceError
+)
+
+// IngestionDataSourceSettings enables ingestion from a data source into a
+// topic, which makes it an import topic.
+type IngestionDataSourceSettings struct {
+	// Source is the data source: an *IngestionDataSourceAWSKinesis or an
+	// *IngestionDataSourceCloudStorage. If nil, the topic does not ingest
+	// messages.
+	Source IngestionDataSource
+}
+
+// IngestionDataSource is a source of messages for an import topic.
+type IngestionDataSource interface {
+	isIngestionDataSource()
+}
+
+// AWSKinesisState denotes the possible states of ingestion from Amazon
+// Kinesis Data Streams.
+type AWSKinesisState int
+
+const (
+	// AWSKinesisStateUnspecified is the default value. This value is unused.
+	AWSKinesisStateUnspecified AWSKinesisState = iota
+
+	// AWSKinesisStateActive means ingestion is active.
+	AWSKinesisStateActive
+
+	// AWSKinesisStatePermissionDenied means permission denied was encountered
+	// while consuming data from Kinesis. This can happen if the AWS role
+	// cannot be assumed, or it does not have the permissions to read from the
+	// stream or to use the consumer.
+	AWSKinesisStatePermissionDenied
+
+	// AWSKinesisStatePublishPermissionDenied means permission denied was
+	// encountered while publishing to the topic. This can happen if the
+	// Pub/Sub service agent has not been granted the
+	// roles/iam.serviceAccountTokenCreator role on GCPServiceAccount.
+	AWSKinesisStatePublishPermissionDenied
+
+	// AWSKinesisStateStreamNotFound means the Kinesis stream does not exist.
+	AWSKinesisStateStreamNotFound
+
+	// AWSKinesisStateConsumerNotFound means the Kinesis consumer does not
+	// exist.
+	AWSKinesisStateConsumerNotFound
+)
+
+// IngestionDataSourceAWSKinesis ingests messages from Amazon Kinesis Data
+// Streams.
+type IngestionDataSourceAWSKinesis struct {
+	// The Amazon Resource Name of the Kinesis stream to ingest from.
+	StreamARN string
+
+	// The Amazon Resource Name of the consumer, registered with the stream,
+	// that reads from it with enhanced fan-out.
+	ConsumerARN string
+
+	// The Amazon Resource Name of the AWS role that Pub/Sub assumes, with
+	// federated identity, to read from the stream.
+	AWSRoleARN string
+
+	// The email of the GCP service account that Pub/Sub uses to federate
+	// with AWS, and to publish to the topic.
+	GCPServiceAccount string
+
+	// This is an output-only field that indicates the state of ingestion.
+	// This field is set only in responses from the server; it is ignored if
+	// it is set in any requests.
+	State AWSKinesisState
+}
+
+// CloudStorageIngestionState denotes the possible states of ingestion from
+// Cloud Storage.
+type CloudStorageIngestionState int
+
+const (
+	// CloudStorageIngestionStateUnspecified is the default value. This value
+	// is unused.
+	CloudStorageIngestionStateUnspecified CloudStorageIngestionState = iota
+
+	// CloudStorageIngestionStateActive means ingestion is active.
+	CloudStorageIngestionStateActive
+
+	// CloudStorageIngestionPermissionDenied means permission denied was
+	// encountered while reading from the bucket.
+	CloudStorageIngestionPermissionDenied
+
+	// CloudStorageIngestionPublishPermissionDenied means permission denied
+	// was encountered while publishing to the topic.
+	CloudStorageIngestionPublishPermissionDenied
+
+	// CloudStorageIngestionBucketNotFound means the bucket does not exist.
+	CloudStorageIngestionBucketNotFound
+
+	// CloudStorageIngestionTooManyObjects means the bucket has too many
+	// objects to ingest, so ingestion stopped.
+	CloudStorageIngestionTooManyObjects
+)
+
+// CloudStorageIngestionInputFormat is the format of the objects that a
+// topic ingests from Cloud Storage: an
+// *IngestionDataSourceCloudStorageTextFormat, an
+// *IngestionDataSourceCloudStorageAvroFormat or an
+// *IngestionDataSourceCloudStoragePubSubAvroFormat.
+type CloudStorageIngestionInputFormat interface {
+	isCloudStorageIngestionInputFormat()
+}
+
+// IngestionDataSourceCloudStorageTextFormat reads objects as text, each
+// line of which is published as a message.
+type IngestionDataSourceCloudStorageTextFormat struct {
+	// Delimiter separates the messages in an object. If it is empty, the
+	// newline character "\n" is used. It must be a single character.
+	Delimiter string
+}
+
+// IngestionDataSourceCloudStorageAvroFormat reads objects in Avro format,
+// each record of which is published as a message.
+type IngestionDataSourceCloudStorageAvroFormat struct{}
+
+// IngestionDataSourceCloudStoragePubSubAvroFormat reads objects in the Avro
+// format that Cloud Storage subscriptions write, with the
+// CloudStorageOutputFormatAvroConfig, and publishes the messages they hold.
+type IngestionDataSourceCloudStoragePubSubAvroFormat struct{}
+
+func (*IngestionDataSourceCloudStorageTextFormat) isCloudStorageIngestionInputFormat()       {}
+func (*IngestionDataSourceCloudStorageAvroFormat) isCloudStorageIngestionInputFormat()       {}
+func (*IngestionDataSourceCloudStoragePubSubAvroFormat) isCloudStorageIngestionInputFormat() {}
+
+// IngestionDataSourceCloudStorage ingests the objects of a Cloud Storage
+// bucket.
+type IngestionDataSourceCloudStorage struct {
+	// The name of the bucket, without any prefix like "gs://".
+	Bucket string
+
+	// InputFormat is the format of the objects. It is required.
+	InputFormat CloudStorageIngestionInputFormat
+
+	// If set, only objects created at or after this time are ingested.
+	MinimumObjectCreateTime time.Time
+
+	// If set, only objects whose names match this glob pattern are
+	// ingested, such as "**.txt".
+	MatchGlob string
+
+	// This is an output-only field that indicates the state of ingestion.
+	// This field is set only in responses from the server; it is ignored if
+	// it is set in any requests.
+	State CloudStorageIngestionState
+}
+
+func (*IngestionDataSourceAWSKinesis) isIngestionDataSource()   {}
+func (*IngestionDataSourceCloudStorage) isIngestionDataSource() {}
+
+// The generated protos of the API version this package uses do not have the
+// ingestion fields of topics, so they are carried as unknown fields of
+// pb.Topic, like the export configurations of subscriptions. These are the
+// field numbers of google.pubsub.v1.Topic and of its
+// IngestionDataSourceSettings message.
+const (
+	topicStateField                       = 9
+	topicIngestionDataSourceSettingsField = 10
+
+	ingestionAWSKinesisField   = 1
+	ingestionCloudStorageField = 5
+
+	kinesisStateField             = 1
+	kinesisStreamARNField         = 2
+	kinesisConsumerARNField       = 3
+	kinesisAWSRoleARNField        = 4
+	kinesisGCPServiceAccountField = 5
+
+	gcsStateField                   = 1
+	gcsBucketField                  = 2
+	gcsTextFormatField              = 3
+	gcsAvroFormatField              = 4
+	gcsPubSubAvroFormatField        = 5
+	gcsMinimumObjectCreateTimeField = 6
+	gcsMatchGlobField               = 9
+
+	textFormatDelimiterField = 1
+
+	timestampSecondsField = 1
+	timestampNanosField   = 2
+)
+
+// setIngestionDataSourceSettings sets the ingestion settings of pbt. Nil
+// settings, or settings without a source, clear them.
+func setIngestionDataSourceSettings(pbt *pb.Topic, s *IngestionDataSourceSettings) {
+	m := pbt.ProtoReflect()
+	if s == nil || s.Source == nil {
+		wirefield.Clear(m, topicIngestionDataSourceSettingsField)
+		return
+	}
+	wirefield.SetBytes(m, topicIngestionDataSourceSettingsField, s.encode())
+}
+
+// ingestionConfig returns the ingestion settings and the state of pbt. The
+// settings are nil if the topic does not ingest messages.
+func ingestionConfig(pbt *pb.Topic) (*IngestionDataSourceSettings, TopicState) {
+	var s *IngestionDataSourceSettings
+	m := pbt.ProtoReflect()
+	if b, ok := wirefield.Bytes(m, topicIngestionDataSourceSettingsField); ok {
+		s = decodeIngestionDataSourceSettings(b)
+	}
+	state, _ := wirefield.Varint(m, topicStateField)
+	return s, TopicState(state)
+}
+
+func (s *IngestionDataSourceSettings) encode() []byte {
+	var b []byte
+	switch src := s.Source.(type) {
+	case *IngestionDataSourceAWSKinesis:
+		var kb []byte
+		kb = appendString(kb, kinesisStreamARNField, src.StreamARN)
+		kb = appendString(kb, kinesisConsumerARNField, src.ConsumerARN)
+		kb = appendString(kb, kinesisAWSRoleARNField, src.AWSRoleARN)
+		kb = appendString(kb, kinesisGCPServiceAccountField, src.GCPServiceAccount)
+		b = appendMessage(b, ingestionAWSKinesisField, kb)
+	case *IngestionDataSourceCloudStorage:
+		b = appendMessage(b, ingestionCloudStorageField, src.encode())
+	}
+	return b
+}
+
+func (cs *IngestionDataSourceCloudStorage) encode() []byte {
+	var b []byte
+	b = appendString(b, gcsBucketField, cs.Bucket)
+	switch f := cs.InputFormat.(type) {
+	case *IngestionDataSourceCloudStorageTextFormat:
+		b = appendMessage(b, gcsTextFormatField, appendString(nil, textFormatDelimiterField, f.Delimiter))
+	case *IngestionDataSourceCloudStorageAvroFormat:
+		b = appendMessage(b, gcsAvroFormatField, nil)
+	case *IngestionDataSourceCloudStoragePubSubAvroFormat:
+		b = appendMessage(b, gcsPubSubAvroFormatField, nil)
+	}
+	if !cs.MinimumObjectCreateTime.IsZero() {
+		var tb []byte
+		tb = appendVarint(tb, timestampSecondsField, uint64(cs.MinimumObjectCreateTime.Unix()))
+		tb = appendVarint(tb, timestampNanosField, uint64(cs.MinimumObjectCreateTime.Nanosecond()))
+		b = appendMessage(b, gcsMinimumObjectCreateTimeField, tb)
+	}
+	b = appendString(b, gcsMatchGlobField, cs.MatchGlob)
+	return b
+}
+
+func decodeIngestionDataSourceSettings(b []byte) *IngestionDataSourceSettings {
+	s := &IngestionDataSourceSettings{}
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		if typ != protowire.BytesType {
+			return true
+		}
+		switch num {
+		case ingestionAWSKinesisField:
+			s.Source = decodeAWSKinesis(v)
+		case ingestionCloudStorageField:
+			s.Source = decodeIngestionCloudStorage(v)
+		}
+		return true
+	})
+	return s
+}
+
+func decodeAWSKinesis(b []byte) *IngestionDataSourceAWSKinesis {
+	k := &IngestionDataSourceAWSKinesis{}
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		switch {
+		case num == kinesisStateField && typ == protowire.VarintType:
+			k.State = AWSKinesisState(varint(v))
+		case typ != protowire.BytesType:
+		case num == kinesisStreamARNField:
+			k.StreamARN = string(v)
+		case num == kinesisConsumerARNField:
+			k.ConsumerARN = string(v)
+		case num == kinesisAWSRoleARNField:
+			k.AWSRoleARN = string(v)
+		case num == kinesisGCPServiceAccountField:
+			k.GCPServiceAccount = string(v)
+		}
+		return true
+	})
+	return k
+}
+
+func decodeIngestionCloudStorage(b []byte) *IngestionDataSourceCloudStorage {
+	cs := &IngestionDataSourceCloudStorage{}
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		switch {
+		case num == gcsStateField && typ == protowire.VarintType:
+			cs.State = CloudStorageIngestionState(varint(v))
+		case typ != protowire.BytesType:
+		case num == gcsBucketField:
+			cs.Bucket = string(v)
+		case num == gcsTextFormatField:
+			f := &IngestionDataSourceCloudStorageTextFormat{}
+			wirefield.Range(v, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+				if num == textFormatDelimiterField && typ == protowire.BytesType {
+					f.Delimiter = string(v)
+				}
+				return true
+			})
+			cs.InputFormat = f
+		case num == gcsAvroFormatField:
+			cs.InputFormat = &IngestionDataSourceCloudStorageAvroFormat{}
+		case num == gcsPubSubAvroFormatField:
+			cs.InputFormat = &IngestionDataSourceCloudStoragePubSubAvroFormat{}
+		case num == gcsMinimumObjectCreateTimeField:
+			cs.MinimumObjectCreateTime = decodeTimestamp(v)
+		case num == gcsMatchGlobField:
+			cs.MatchGlob = string(v)
+		}
+		return true
+	})
+	return cs
+}
+
+func decodeTimestamp(b []byte) time.Time {
+	var secs int64
+	var nanos int32
+	wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+		if typ == protowire.VarintType {
+			switch num {
+			case timestampSecondsField:
+				secs = int64(varint(v))
+			case timestampNanosField:
+				nanos = int32(varint(v))
+			}
+		}
+		return true
+	})
+	return time.Unix(secs, int64(nanos)).UTC()
+}
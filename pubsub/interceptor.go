@@ -0,0 +1,79 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+
+	ipubsub "cloud.google.com/go/internal/pubsub"
+)
+
+// A PublishHandler publishes a message. It is the rest of the chain of
+// publish interceptors, ending in publishing the message to the topic.
+type PublishHandler func(ctx context.Context, msg *Message) *PublishResult
+
+// A PublishInterceptor is called by Topic.Publish with each message, before
+// the message is validated, batched and sent. It may change the message, or
+// replace it with another, and passes it on by calling next. It returns the
+// result of next, or a result made with NewPublishResultWithError to fail the
+// message without publishing it.
+//
+// Interceptors are set with ClientConfig.PublishInterceptors.
+type PublishInterceptor func(ctx context.Context, msg *Message, next PublishHandler) *PublishResult
+
+// A ReceiveHandler handles a received message. It is the rest of the chain of
+// receive interceptors, ending in the function passed to Subscription.Receive.
+type ReceiveHandler func(ctx context.Context, msg *Message)
+
+// A ReceiveInterceptor is called with each message received by
+// Subscription.Receive or Subscription.PullN, before the message is handed to
+// the application. It may change the message, or replace it with another, and
+// passes it on by calling next. An interceptor that does not call next must
+// ack or nack the message itself; PullN does not return such messages.
+//
+// Interceptors are set with ClientConfig.ReceiveInterceptors.
+type ReceiveInterceptor func(ctx context.Context, msg *Message, next ReceiveHandler)
+
+// NewPublishResultWithError returns a PublishResult that is ready, with err
+// as its error. It is for publish interceptors that fail a message.
+func NewPublishResultWithError(err error) *PublishResult {
+	r := ipubsub.NewPublishResult()
+	ipubsub.SetPublishResult(r, "", err)
+	return r
+}
+
+// chainPublishInterceptors returns a handler that calls the interceptors in
+// order, the first one outermost, and then h.
+func chainPublishInterceptors(interceptors []PublishInterceptor, h PublishHandler) PublishHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], h
+		h = func(ctx context.Context, msg *Message) *PublishResult {
+			return ic(ctx, msg, next)
+		}
+	}
+	return h
+}
+
+// chainReceiveInterceptors returns a handler that calls the interceptors in
+// order, the first one outermost, and then h.
+func chainReceiveInterceptors(interceptors []ReceiveInterceptor, h ReceiveHandler) ReceiveHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], h
+		h = func(ctx context.Context, msg *Message) {
+			ic(ctx, msg, next)
+		}
+	}
+	return h
+}
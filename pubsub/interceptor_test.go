@@ -0,0 +1,156 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/internal/testutil"
+	"cloud.google.com/go/pubsub/pstest"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return r
+}
+
+func TestInterceptors(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, s)
+	}
+	errRejected := errors.New("rejected")
+
+	config := &ClientConfig{
+		PublishInterceptors: []PublishInterceptor{
+			func(ctx context.Context, msg *Message, next PublishHandler) *PublishResult {
+				record("publish 1")
+				if msg.Attributes["reject"] != "" {
+					return NewPublishResultWithError(errRejected)
+				}
+				return next(ctx, msg)
+			},
+			// "Encrypt" the data, without changing the caller's message.
+			func(ctx context.Context, msg *Message, next PublishHandler) *PublishResult {
+				record("publish 2")
+				attrs := map[string]string{"reversed": "true"}
+				for k, v := range msg.Attributes {
+					attrs[k] = v
+				}
+				return next(ctx, &Message{Data: reverse(msg.Data), Attributes: attrs})
+			},
+		},
+		ReceiveInterceptors: []ReceiveInterceptor{
+			func(ctx context.Context, msg *Message, next ReceiveHandler) {
+				record("receive 1")
+				if msg.Attributes["drop"] != "" {
+					msg.Ack()
+					return
+				}
+				next(ctx, msg)
+			},
+			func(ctx context.Context, msg *Message, next ReceiveHandler) {
+				record("receive 2")
+				if msg.Attributes["reversed"] != "" {
+					msg.Data = reverse(msg.Data)
+				}
+				next(ctx, msg)
+			},
+		},
+	}
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	defer srv.Close()
+	client, err := NewClientWithConfig(ctx, "P", config,
+		option.WithEndpoint(srv.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithInsecure()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &Message{Data: []byte("hello")}
+	if _, err := topic.Publish(ctx, msg).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Data) != "hello" {
+		t.Errorf("the caller's message was changed to %q", msg.Data)
+	}
+	if _, err := topic.Publish(ctx, &Message{Data: []byte("x"), Attributes: map[string]string{"reject": "1"}}).Get(ctx); err != errRejected {
+		t.Errorf("rejected message: got %v, want %v", err, errRejected)
+	}
+	if _, err := topic.Publish(ctx, &Message{Data: []byte("x"), Attributes: map[string]string{"drop": "1"}}).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	topic.Stop()
+	want := []string{"publish 1", "publish 2", "publish 1", "publish 1", "publish 2"}
+	if diff := testutil.Diff(calls, want); diff != "" {
+		t.Errorf("publish calls: got=-, want=+:\n%s", diff)
+	}
+	if pm := srv.Messages(); len(pm) != 2 || string(pm[0].Data) != "olleh" {
+		t.Fatalf("got published messages %v, want \"olleh\" and \"x\"", pm)
+	}
+
+	// The dropped message is acked by the interceptor, so only one is
+	// received.
+	calls = nil
+	msgs, err := sub.PullN(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || string(msgs[0].Data) != "hello" {
+		t.Fatalf("PullN: got %v, want one message \"hello\"", msgs)
+	}
+	msgs[0].Nack()
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var received []string
+	err = sub.Receive(cctx, func(ctx context.Context, m *Message) {
+		received = append(received, string(m.Data))
+		m.Ack()
+		cancel()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := testutil.Diff(received, []string{"hello"}); diff != "" {
+		t.Errorf("Receive: got=-, want=+:\n%s", diff)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) < 5 || calls[0] != "receive 1" || calls[len(calls)-1] != "receive 2" {
+		t.Errorf("got receive calls %v", calls)
+	}
+}
@@ -0,0 +1,559 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package avro validates data against Avro schemas, in the Avro binary and
+// JSON encodings. It does not decode data into Go values.
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Schema is a parsed Avro schema.
+type Schema struct {
+	root *node
+}
+
+type kind int
+
+const (
+	kindNull kind = iota
+	kindBoolean
+	kindInt
+	kindLong
+	kindFloat
+	kindDouble
+	kindBytes
+	kindString
+	kindRecord
+	kindEnum
+	kindArray
+	kindMap
+	kindFixed
+	kindUnion
+)
+
+var primitives = map[string]kind{
+	"null":    kindNull,
+	"boolean": kindBoolean,
+	"int":     kindInt,
+	"long":    kindLong,
+	"float":   kindFloat,
+	"double":  kindDouble,
+	"bytes":   kindBytes,
+	"string":  kindString,
+}
+
+type node struct {
+	kind kind
+	// name is the full name of a record, enum or fixed, or the name of a
+	// primitive type. It is the key of the type's branch in a JSON-encoded
+	// union.
+	name    string
+	fields  []*field // record
+	symbols []string // enum
+	size    int      // fixed
+	items   *node    // array items, map values
+	types   []*node  // union
+}
+
+type field struct {
+	name       string
+	typ        *node
+	hasDefault bool
+}
+
+// Parse parses an Avro schema in its JSON form.
+func Parse(definition string) (*Schema, error) {
+	dec := json.NewDecoder(strings.NewReader(definition))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("avro: invalid schema: %v", err)
+	}
+	p := &parser{named: map[string]*node{}}
+	root, err := p.parse(v, "")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+type parser struct {
+	named map[string]*node // by full name
+}
+
+func (p *parser) parse(v interface{}, namespace string) (*node, error) {
+	switch v := v.(type) {
+	case string:
+		return p.reference(v, namespace)
+	case []interface{}:
+		n := &node{kind: kindUnion}
+		for _, t := range v {
+			b, err := p.parse(t, namespace)
+			if err != nil {
+				return nil, err
+			}
+			if b.kind == kindUnion {
+				return nil, errors.New("avro: unions may not immediately contain other unions")
+			}
+			n.types = append(n.types, b)
+		}
+		return n, nil
+	case map[string]interface{}:
+		return p.parseComplex(v, namespace)
+	default:
+		return nil, fmt.Errorf("avro: invalid schema %v", v)
+	}
+}
+
+func (p *parser) reference(name, namespace string) (*node, error) {
+	if k, ok := primitives[name]; ok {
+		return &node{kind: k, name: name}, nil
+	}
+	if n, ok := p.named[fullName(name, namespace)]; ok {
+		return n, nil
+	}
+	if n, ok := p.named[name]; ok {
+		return n, nil
+	}
+	return nil, fmt.Errorf("avro: unknown type %q", name)
+}
+
+func (p *parser) parseComplex(m map[string]interface{}, namespace string) (*node, error) {
+	typ, _ := m["type"].(string)
+	switch typ {
+	case "record", "error", "enum", "fixed":
+		return p.parseNamed(typ, m, namespace)
+	case "array", "map":
+		key := "items"
+		k := kindArray
+		if typ == "map" {
+			key, k = "values", kindMap
+		}
+		t, ok := m[key]
+		if !ok {
+			return nil, fmt.Errorf("avro: %s has no %s", typ, key)
+		}
+		items, err := p.parse(t, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: k, items: items}, nil
+	default:
+		if t, ok := m["type"]; ok {
+			// A primitive type, possibly with a logical type, which does not
+			// change its encoding.
+			return p.parse(t, namespace)
+		}
+		return nil, errors.New("avro: schema object has no type")
+	}
+}
+
+func (p *parser) parseNamed(typ string, m map[string]interface{}, namespace string) (*node, error) {
+	name, _ := m["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("avro: %s has no name", typ)
+	}
+	if ns, ok := m["namespace"].(string); ok && !strings.Contains(name, ".") {
+		namespace = ns
+	}
+	full := fullName(name, namespace)
+	if _, ok := p.named[full]; ok {
+		return nil, fmt.Errorf("avro: type %q is defined twice", full)
+	}
+	if i := strings.LastIndex(full, "."); i >= 0 {
+		namespace = full[:i]
+	} else {
+		namespace = ""
+	}
+	n := &node{name: full}
+	// Register the type before parsing its fields, which may refer to it.
+	p.named[full] = n
+	switch typ {
+	case "record", "error":
+		n.kind = kindRecord
+		fields, ok := m["fields"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("avro: record %q has no fields", full)
+		}
+		for _, f := range fields {
+			fm, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("avro: invalid field %v of record %q", f, full)
+			}
+			fname, _ := fm["name"].(string)
+			if fname == "" {
+				return nil, fmt.Errorf("avro: field of record %q has no name", full)
+			}
+			t, ok := fm["type"]
+			if !ok {
+				return nil, fmt.Errorf("avro: field %q of record %q has no type", fname, full)
+			}
+			ft, err := p.parse(t, namespace)
+			if err != nil {
+				return nil, err
+			}
+			_, hasDefault := fm["default"]
+			n.fields = append(n.fields, &field{name: fname, typ: ft, hasDefault: hasDefault})
+		}
+	case "enum":
+		n.kind = kindEnum
+		symbols, ok := m["symbols"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("avro: enum %q has no symbols", full)
+		}
+		for _, s := range symbols {
+			str, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("avro: invalid symbol %v of enum %q", s, full)
+			}
+			n.symbols = append(n.symbols, str)
+		}
+	case "fixed":
+		n.kind = kindFixed
+		size, ok := m["size"].(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("avro: fixed %q has no size", full)
+		}
+		s, err := size.Int64()
+		if err != nil || s < 0 {
+			return nil, fmt.Errorf("avro: invalid size %v of fixed %q", size, full)
+		}
+		n.size = int(s)
+	}
+	return n, nil
+}
+
+func fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+// ValidateBinary reports whether data is a single value of the schema in the
+// Avro binary encoding.
+func (s *Schema) ValidateBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if err := validateBinary(s.root, r); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errors.New("unexpected end of data")
+		}
+		return fmt.Errorf("avro: %v", err)
+	}
+	if r.Len() > 0 {
+		return fmt.Errorf("avro: %d bytes after the end of the value", r.Len())
+	}
+	return nil
+}
+
+func validateBinary(n *node, r *bytes.Reader) error {
+	switch n.kind {
+	case kindNull:
+		return nil
+	case kindBoolean:
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b > 1 {
+			return fmt.Errorf("invalid boolean %d", b)
+		}
+		return nil
+	case kindInt:
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return fmt.Errorf("int %d out of range", v)
+		}
+		return nil
+	case kindLong:
+		_, err := binary.ReadVarint(r)
+		return err
+	case kindFloat:
+		return skip(r, 4)
+	case kindDouble:
+		return skip(r, 8)
+	case kindBytes, kindString:
+		l, err := readLength(r)
+		if err != nil {
+			return err
+		}
+		if int64(r.Len()) < l {
+			return io.ErrUnexpectedEOF
+		}
+		b := make([]byte, l)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+		if n.kind == kindString && !utf8.Valid(b) {
+			return errors.New("string is not valid UTF-8")
+		}
+		return nil
+	case kindRecord:
+		for _, f := range n.fields {
+			if err := validateBinary(f.typ, r); err != nil {
+				return fmt.Errorf("%s.%s: %v", n.name, f.name, err)
+			}
+		}
+		return nil
+	case kindEnum:
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if i < 0 || i >= int64(len(n.symbols)) {
+			return fmt.Errorf("enum %s has no symbol %d", n.name, i)
+		}
+		return nil
+	case kindArray, kindMap:
+		for {
+			count, err := binary.ReadVarint(r)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return nil
+			}
+			if count < 0 {
+				// The count is followed by the size of the block in bytes.
+				count = -count
+				if _, err := readLength(r); err != nil {
+					return err
+				}
+			}
+			for i := int64(0); i < count; i++ {
+				if n.kind == kindMap {
+					if err := validateBinary(&node{kind: kindString}, r); err != nil {
+						return err
+					}
+				}
+				if err := validateBinary(n.items, r); err != nil {
+					return err
+				}
+			}
+		}
+	case kindFixed:
+		return skip(r, n.size)
+	case kindUnion:
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		if i < 0 || i >= int64(len(n.types)) {
+			return fmt.Errorf("union has no branch %d", i)
+		}
+		return validateBinary(n.types[i], r)
+	}
+	return fmt.Errorf("unknown type %v", n.kind)
+}
+
+func readLength(r *bytes.Reader) (int64, error) {
+	l, err := binary.ReadVarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if l < 0 {
+		return 0, fmt.Errorf("negative length %d", l)
+	}
+	return l, nil
+}
+
+func skip(r *bytes.Reader, n int) error {
+	if r.Len() < n {
+		return io.ErrUnexpectedEOF
+	}
+	_, err := r.Seek(int64(n), io.SeekCurrent)
+	return err
+}
+
+// ValidateJSON reports whether data is a single value of the schema in the
+// Avro JSON encoding, in which a non-null value of a union is an object whose
+// only key is the name of the value's type.
+func (s *Schema) ValidateJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("avro: invalid JSON: %v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("avro: data after the end of the value")
+	}
+	if err := validateJSON(s.root, v); err != nil {
+		return fmt.Errorf("avro: %v", err)
+	}
+	return nil
+}
+
+func validateJSON(n *node, v interface{}) error {
+	switch n.kind {
+	case kindNull:
+		if v != nil {
+			return fmt.Errorf("got %v, want null", v)
+		}
+	case kindBoolean:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("got %v, want a boolean", v)
+		}
+	case kindInt, kindLong:
+		num, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("got %v, want an integer", v)
+		}
+		i, err := num.Int64()
+		if err != nil {
+			return fmt.Errorf("got %v, want an integer", v)
+		}
+		if n.kind == kindInt && (i < math.MinInt32 || i > math.MaxInt32) {
+			return fmt.Errorf("int %d out of range", i)
+		}
+	case kindFloat, kindDouble:
+		if _, ok := v.(json.Number); !ok {
+			return fmt.Errorf("got %v, want a number", v)
+		}
+	case kindString:
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("got %v, want a string", v)
+		}
+	case kindBytes, kindFixed:
+		// Bytes are encoded as a string of the code points 0 to 255.
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("got %v, want a string of bytes", v)
+		}
+		l := 0
+		for _, r := range s {
+			if r > 255 {
+				return fmt.Errorf("invalid byte %U", r)
+			}
+			l++
+		}
+		if n.kind == kindFixed && l != n.size {
+			return fmt.Errorf("got %d bytes, want %d for fixed %s", l, n.size, n.name)
+		}
+	case kindRecord:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("got %v, want an object for record %s", v, n.name)
+		}
+		seen := 0
+		for _, f := range n.fields {
+			fv, ok := m[f.name]
+			if !ok {
+				if f.hasDefault {
+					continue
+				}
+				return fmt.Errorf("%s: missing field %q", n.name, f.name)
+			}
+			seen++
+			if err := validateJSON(f.typ, fv); err != nil {
+				return fmt.Errorf("%s.%s: %v", n.name, f.name, err)
+			}
+		}
+		if seen != len(m) {
+			for k := range m {
+				if !n.hasField(k) {
+					return fmt.Errorf("%s: unknown field %q", n.name, k)
+				}
+			}
+		}
+	case kindEnum:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("got %v, want a symbol of enum %s", v, n.name)
+		}
+		for _, sym := range n.symbols {
+			if s == sym {
+				return nil
+			}
+		}
+		return fmt.Errorf("enum %s has no symbol %q", n.name, s)
+	case kindArray:
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("got %v, want an array", v)
+		}
+		for i, e := range a {
+			if err := validateJSON(n.items, e); err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+		}
+	case kindMap:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("got %v, want an object for a map", v)
+		}
+		for k, e := range m {
+			if err := validateJSON(n.items, e); err != nil {
+				return fmt.Errorf("[%q]: %v", k, err)
+			}
+		}
+	case kindUnion:
+		if v == nil {
+			for _, t := range n.types {
+				if t.kind == kindNull {
+					return nil
+				}
+			}
+			return errors.New("got null for a union without null")
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok || len(m) != 1 {
+			return fmt.Errorf("got %v, want an object with the type of a union's value as its only key", v)
+		}
+		for name, bv := range m {
+			for _, t := range n.types {
+				if t.unionName() == name {
+					return validateJSON(t, bv)
+				}
+			}
+			return fmt.Errorf("union has no branch %q", name)
+		}
+	default:
+		return fmt.Errorf("unknown type %v", n.kind)
+	}
+	return nil
+}
+
+func (n *node) hasField(name string) bool {
+	for _, f := range n.fields {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unionName returns the key of the node's branch in a JSON-encoded union.
+func (n *node) unionName() string {
+	switch n.kind {
+	case kindArray:
+		return "array"
+	case kindMap:
+		return "map"
+	}
+	return n.name
+}
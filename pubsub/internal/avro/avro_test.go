@@ -0,0 +1,126 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avro
+
+import (
+	"testing"
+)
+
+const stateSchema = `{
+	"type": "record",
+	"name": "State",
+	"namespace": "utilities",
+	"fields": [
+		{"name": "name", "type": "string"},
+		{"name": "post_abbr", "type": "string"},
+		{"name": "population", "type": ["null", "long"], "default": null},
+		{"name": "kind", "type": {"type": "enum", "name": "Kind", "symbols": ["STATE", "TERRITORY"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "codes", "type": {"type": "map", "values": "int"}},
+		{"name": "capital", "type": ["null", "State"], "default": null},
+		{"name": "id", "type": {"type": "fixed", "name": "ID", "size": 2}},
+		{"name": "founded", "type": {"type": "long", "logicalType": "timestamp-millis"}}
+	]
+}`
+
+func mustParse(t *testing.T, def string) *Schema {
+	t.Helper()
+	s, err := Parse(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestValidateJSON(t *testing.T) {
+	s := mustParse(t, stateSchema)
+	for _, data := range []string{
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "STATE", "tags": [], "codes": {}, "id": "AK", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "population": {"long": 733391}, "kind": "STATE", "tags": ["cold"],
+		  "codes": {"fips": 2}, "capital": {"utilities.State": {"name": "Juneau", "post_abbr": "", "kind": "TERRITORY",
+		  "tags": [], "codes": {}, "id": "ÿ\u0000", "founded": 2}}, "id": "AK", "founded": -1}`,
+	} {
+		if err := s.ValidateJSON([]byte(data)); err != nil {
+			t.Errorf("%s: %v", data, err)
+		}
+	}
+	for _, data := range []string{
+		``,
+		`{}`,
+		`[]`,
+		`{"name": 1, "post_abbr": "AK", "kind": "STATE", "tags": [], "codes": {}, "id": "AK", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "STATE", "tags": [], "codes": {}, "id": "AK", "founded": 1, "extra": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "population": 733391, "kind": "STATE", "tags": [], "codes": {}, "id": "AK", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "population": {"int": 1}, "kind": "STATE", "tags": [], "codes": {}, "id": "AK", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "PROVINCE", "tags": [], "codes": {}, "id": "AK", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "STATE", "tags": [1], "codes": {}, "id": "AK", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "STATE", "tags": [], "codes": {"fips": 1e99}, "id": "AK", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "STATE", "tags": [], "codes": {}, "id": "AKA", "founded": 1}`,
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "STATE", "tags": [], "codes": {}, "id": "AK", "founded": 1.5}`,
+		`{"name": "Alaska", "post_abbr": "AK", "kind": "STATE", "tags": [], "codes": {}, "id": "AK", "founded": 1} {}`,
+	} {
+		if err := s.ValidateJSON([]byte(data)); err == nil {
+			t.Errorf("%s: got nil, want error", data)
+		}
+	}
+}
+
+func TestValidateBinary(t *testing.T) {
+	s := mustParse(t, stateSchema)
+	valid := []byte{
+		0x0c, 'A', 'l', 'a', 's', 'k', 'a', // name
+		0x04, 'A', 'K', // post_abbr
+		0x02, 0x02, // population: branch 1, 1
+		0x02,                        // kind: TERRITORY
+		0x01, 0x04, 0x02, 'x', 0x00, // tags: a block of 1 item in 4 bytes, then the end
+		0x02, 0x02, 'a', 0x54, 0x00, // codes: a block of 1 item, "a": 42, then the end
+		0x00,     // capital: branch 0, null
+		'I', 'D', // id
+		0x80, 0x01, // founded: 64
+	}
+	if err := s.ValidateBinary(valid); err != nil {
+		t.Fatalf("valid: %v", err)
+	}
+	for desc, data := range map[string][]byte{
+		"empty":     nil,
+		"truncated": valid[:len(valid)-1],
+		"extra":     append(append([]byte{}, valid...), 0),
+		"bad enum":  append(append(append([]byte{}, valid[:12]...), 0x04), valid[13:]...),
+		"bad union": append(append(append([]byte{}, valid[:10]...), 0x04), valid[11:]...),
+	} {
+		if err := s.ValidateBinary(data); err == nil {
+			t.Errorf("%s: got nil, want error", desc)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, def := range []string{
+		``,
+		`"decimal"`,
+		`{"type": "record", "fields": []}`,
+		`{"type": "record", "name": "R"}`,
+		`{"type": "record", "name": "R", "fields": [{"name": "f", "type": "Unknown"}]}`,
+		`{"type": "enum", "name": "E"}`,
+		`{"type": "fixed", "name": "F"}`,
+		`{"type": "array"}`,
+		`["null", ["int"]]`,
+		`[{"type": "fixed", "name": "F", "size": 1}, {"type": "fixed", "name": "F", "size": 2}]`,
+	} {
+		if _, err := Parse(def); err == nil {
+			t.Errorf("%s: got nil, want error", def)
+		}
+	}
+}
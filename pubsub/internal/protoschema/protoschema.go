@@ -0,0 +1,711 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protoschema parses the Protocol Buffer definitions of Pub/Sub
+// schemas, so that messages can be validated against them.
+//
+// Like Pub/Sub, it supports a single .proto file without imports or services,
+// in the proto2 or proto3 syntax. The schema's message is the first top-level
+// message of the file.
+package protoschema
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Parse parses a .proto definition and returns the descriptor of its first
+// top-level message.
+func Parse(definition string) (protoreflect.MessageDescriptor, error) {
+	p := &parser{toks: tokenize(definition)}
+	fdp, err := p.file()
+	if err != nil {
+		return nil, fmt.Errorf("protoschema: %v", err)
+	}
+	if len(fdp.MessageType) == 0 {
+		return nil, fmt.Errorf("protoschema: definition has no message")
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		return nil, fmt.Errorf("protoschema: %v", err)
+	}
+	return fd.Messages().Get(0), nil
+}
+
+type token struct {
+	text string
+	line int
+	str  bool // whether text is the value of a string literal
+}
+
+// tokenize splits a .proto definition into identifiers, numbers, string
+// literals and symbols, dropping comments. Malformed input results in tokens
+// that the parser rejects.
+func tokenize(s string) []token {
+	var toks []token
+	line := 1
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "//"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				end = len(s) - i - 2
+			}
+			line += strings.Count(s[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(s) && s[j] != c && s[j] != '\n' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) || s[j] != c {
+				toks = append(toks, token{text: s[i:j], line: line})
+				i = j
+				continue
+			}
+			v := s[i+1 : j]
+			if c == '"' {
+				if u, err := strconv.Unquote(s[i : j+1]); err == nil {
+					v = u
+				}
+			}
+			toks = append(toks, token{text: v, line: line, str: true})
+			i = j + 1
+		case isIdentChar(rune(c)) || c == '.' && i+1 < len(s) && isIdentChar(rune(s[i+1])):
+			j := i
+			for j < len(s) && (isIdentChar(rune(s[j])) || s[j] == '.' ||
+				(s[j] == '-' || s[j] == '+') && (s[j-1] == 'e' || s[j-1] == 'E') && unicode.IsDigit(rune(s[i]))) {
+				j++
+			}
+			toks = append(toks, token{text: s[i:j], line: line})
+			i = j
+		default:
+			toks = append(toks, token{text: s[i : i+1], line: line})
+			i++
+		}
+	}
+	return toks
+}
+
+func isIdentChar(r rune) bool {
+	return r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+type parser struct {
+	toks   []token
+	pos    int
+	syntax string
+}
+
+func (p *parser) peek() string {
+	if p.pos >= len(p.toks) {
+		return ""
+	}
+	return p.toks[p.pos].text
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.toks) {
+		return token{}, fmt.Errorf("unexpected end of definition")
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos > 0 && p.pos <= len(p.toks) {
+		line = p.toks[p.pos-1].line
+	} else if len(p.toks) > 0 {
+		line = p.toks[len(p.toks)-1].line
+	}
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.str || t.text != text {
+		return p.errorf("got %q, want %q", t.text, text)
+	}
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	text := strings.TrimPrefix(t.text, ".") // fully-qualified type names start with "."
+	if t.str || text == "" || !isIdentChar(rune(text[0])) || unicode.IsDigit(rune(text[0])) {
+		return "", p.errorf("got %q, want an identifier", t.text)
+	}
+	return t.text, nil
+}
+
+func (p *parser) integer() (int64, error) {
+	t, err := p.next()
+	if err != nil {
+		return 0, err
+	}
+	text := t.text
+	neg := false
+	if text == "-" {
+		neg = true
+		if t, err = p.next(); err != nil {
+			return 0, err
+		}
+		text = t.text
+	}
+	n, err := strconv.ParseInt(text, 0, 64)
+	if t.str || err != nil {
+		return 0, p.errorf("got %q, want an integer", text)
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+// constant returns the text of a constant, such as a default value.
+func (p *parser) constant() (token, error) {
+	t, err := p.next()
+	if err != nil {
+		return t, err
+	}
+	if !t.str && (t.text == "-" || t.text == "+") {
+		n, err := p.next()
+		if err != nil {
+			return n, err
+		}
+		if t.text == "-" {
+			n.text = "-" + n.text
+		}
+		return n, nil
+	}
+	if !t.str && t.text == "{" {
+		return t, p.errorf("aggregate option values are not supported")
+	}
+	return t, nil
+}
+
+func (p *parser) file() (*descriptorpb.FileDescriptorProto, error) {
+	fdp := &descriptorpb.FileDescriptorProto{Name: proto.String("schema.proto")}
+	p.syntax = "proto2"
+	if p.peek() == "syntax" {
+		p.pos++
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		t, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !t.str || (t.text != "proto2" && t.text != "proto3") {
+			return nil, p.errorf("unsupported syntax %q", t.text)
+		}
+		p.syntax = t.text
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+	if p.syntax == "proto3" {
+		fdp.Syntax = proto.String("proto3")
+	}
+	for p.pos < len(p.toks) {
+		t, _ := p.next()
+		switch t.text {
+		case ";":
+		case "package":
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			fdp.Package = proto.String(name)
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case "option":
+			if err := p.skipOption(); err != nil {
+				return nil, err
+			}
+		case "message":
+			m, err := p.message()
+			if err != nil {
+				return nil, err
+			}
+			fdp.MessageType = append(fdp.MessageType, m)
+		case "enum":
+			e, err := p.enum()
+			if err != nil {
+				return nil, err
+			}
+			fdp.EnumType = append(fdp.EnumType, e)
+		case "import", "service", "extend":
+			return nil, p.errorf("%s is not supported in schemas", t.text)
+		default:
+			return nil, p.errorf("unexpected %q", t.text)
+		}
+	}
+	return fdp, nil
+}
+
+// skipOption skips the rest of an option statement, after "option".
+func (p *parser) skipOption() error {
+	for {
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		if t.str {
+			continue
+		}
+		switch t.text {
+		case ";":
+			return nil
+		case "{":
+			return p.errorf("aggregate option values are not supported")
+		}
+	}
+}
+
+func (p *parser) message() (*descriptorpb.DescriptorProto, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	m := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "}":
+			p.pos++
+			addSyntheticOneofs(m)
+			return m, nil
+		case ";":
+			p.pos++
+		case "option":
+			p.pos++
+			if err := p.skipOption(); err != nil {
+				return nil, err
+			}
+		case "message":
+			p.pos++
+			nm, err := p.message()
+			if err != nil {
+				return nil, err
+			}
+			m.NestedType = append(m.NestedType, nm)
+		case "enum":
+			p.pos++
+			e, err := p.enum()
+			if err != nil {
+				return nil, err
+			}
+			m.EnumType = append(m.EnumType, e)
+		case "reserved":
+			p.pos++
+			if err := p.reserved(m); err != nil {
+				return nil, err
+			}
+		case "oneof":
+			p.pos++
+			if err := p.oneof(m); err != nil {
+				return nil, err
+			}
+		case "extensions", "extend", "group":
+			return nil, p.errorf("%s is not supported in schemas", p.peek())
+		case "":
+			return nil, p.errorf("message %s is not closed", name)
+		default:
+			f, err := p.field(m)
+			if err != nil {
+				return nil, err
+			}
+			m.Field = append(m.Field, f)
+		}
+	}
+}
+
+var scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"double":   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"float":    descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"int64":    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint64":   descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	"int32":    descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"fixed64":  descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	"fixed32":  descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	"bool":     descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"string":   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":    descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	"uint32":   descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	"sfixed32": descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	"sfixed64": descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	"sint32":   descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64":   descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+}
+
+// field parses a field of message m. Map fields add their entry message to m.
+func (p *parser) field(m *descriptorpb.DescriptorProto) (*descriptorpb.FieldDescriptorProto, error) {
+	f := &descriptorpb.FieldDescriptorProto{}
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	switch p.peek() {
+	case "optional":
+		p.pos++
+		if p.syntax == "proto3" {
+			f.Proto3Optional = proto.Bool(true)
+		}
+	case "required":
+		if p.syntax == "proto3" {
+			return nil, p.errorf("required fields are not allowed in proto3")
+		}
+		p.pos++
+		label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
+	case "repeated":
+		p.pos++
+		label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	default:
+		if p.syntax == "proto2" && p.peek() != "map" {
+			return nil, p.errorf("field %q has no label", p.peek())
+		}
+	}
+	typ, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	var entry *descriptorpb.DescriptorProto
+	if typ == "map" && p.peek() == "<" {
+		p.pos++
+		key, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		value, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(">"); err != nil {
+			return nil, err
+		}
+		label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		entry = &descriptorpb.DescriptorProto{
+			Field: []*descriptorpb.FieldDescriptorProto{
+				typedField("key", 1, key),
+				typedField("value", 2, value),
+			},
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+	}
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("="); err != nil {
+		return nil, err
+	}
+	num, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+	f.Name = proto.String(name)
+	f.Number = proto.Int32(int32(num))
+	f.Label = label.Enum()
+	if entry != nil {
+		entry.Name = proto.String(mapEntryName(name))
+		m.NestedType = append(m.NestedType, entry)
+		f.TypeName = proto.String(entry.GetName())
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	} else {
+		setType(f, typ)
+	}
+	if p.peek() == "[" {
+		p.pos++
+		if err := p.fieldOptions(f); err != nil {
+			return nil, err
+		}
+	}
+	return f, p.expect(";")
+}
+
+func typedField(name string, num int32, typ string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(num),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	setType(f, typ)
+	return f
+}
+
+// setType sets the type of f. The types of fields of message or enum types are
+// resolved by protodesc.
+func setType(f *descriptorpb.FieldDescriptorProto, typ string) {
+	if t, ok := scalarTypes[typ]; ok {
+		f.Type = t.Enum()
+	} else {
+		f.TypeName = proto.String(typ)
+	}
+}
+
+// mapEntryName returns the name of the entry message of a map field, as protoc
+// names it: "FooBarEntry" for "foo_bar".
+func mapEntryName(field string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range field {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String() + "Entry"
+}
+
+// fieldOptions parses field options, after "[". Unknown options are ignored.
+func (p *parser) fieldOptions(f *descriptorpb.FieldDescriptorProto) error {
+	for {
+		var name string
+		if p.peek() == "(" {
+			p.pos++
+			n, err := p.ident()
+			if err != nil {
+				return err
+			}
+			if err := p.expect(")"); err != nil {
+				return err
+			}
+			name = "(" + n + ")"
+		} else {
+			n, err := p.ident()
+			if err != nil {
+				return err
+			}
+			name = n
+		}
+		if err := p.expect("="); err != nil {
+			return err
+		}
+		v, err := p.constant()
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "default":
+			f.DefaultValue = proto.String(v.text)
+		case "json_name":
+			f.JsonName = proto.String(v.text)
+		case "packed":
+			if f.Options == nil {
+				f.Options = &descriptorpb.FieldOptions{}
+			}
+			f.Options.Packed = proto.Bool(v.text == "true")
+		}
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch t.text {
+		case "]":
+			return nil
+		case ",":
+		default:
+			return p.errorf("got %q, want \",\" or \"]\"", t.text)
+		}
+	}
+}
+
+func (p *parser) oneof(m *descriptorpb.DescriptorProto) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	index := int32(len(m.OneofDecl))
+	m.OneofDecl = append(m.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String(name)})
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for {
+		switch p.peek() {
+		case "}":
+			p.pos++
+			return nil
+		case ";":
+			p.pos++
+		case "option":
+			p.pos++
+			if err := p.skipOption(); err != nil {
+				return err
+			}
+		case "optional", "required", "repeated", "map":
+			return p.errorf("oneof fields may not be %s", p.peek())
+		default:
+			// Oneof fields have no label, even in proto2.
+			syntax := p.syntax
+			p.syntax = "proto3"
+			f, err := p.field(m)
+			p.syntax = syntax
+			if err != nil {
+				return err
+			}
+			f.OneofIndex = proto.Int32(index)
+			m.Field = append(m.Field, f)
+		}
+	}
+}
+
+// addSyntheticOneofs adds a oneof for each proto3 optional field of m, after
+// its other oneofs, as protoc does.
+func addSyntheticOneofs(m *descriptorpb.DescriptorProto) {
+	for _, f := range m.Field {
+		if !f.GetProto3Optional() {
+			continue
+		}
+		f.OneofIndex = proto.Int32(int32(len(m.OneofDecl)))
+		m.OneofDecl = append(m.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + f.GetName())})
+	}
+}
+
+func (p *parser) reserved(m *descriptorpb.DescriptorProto) error {
+	for {
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		if t.str {
+			m.ReservedName = append(m.ReservedName, t.text)
+		} else {
+			p.pos--
+			start, err := p.integer()
+			if err != nil {
+				return err
+			}
+			end := start
+			if p.peek() == "to" {
+				p.pos++
+				if p.peek() == "max" {
+					p.pos++
+					end = 536870911
+				} else if end, err = p.integer(); err != nil {
+					return err
+				}
+			}
+			m.ReservedRange = append(m.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+				Start: proto.Int32(int32(start)),
+				End:   proto.Int32(int32(end + 1)), // exclusive
+			})
+		}
+		t, err = p.next()
+		if err != nil {
+			return err
+		}
+		switch t.text {
+		case ";":
+			return nil
+		case ",":
+		default:
+			return p.errorf("got %q, want \",\" or \";\"", t.text)
+		}
+	}
+}
+
+func (p *parser) enum() (*descriptorpb.EnumDescriptorProto, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	e := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "}":
+			p.pos++
+			return e, nil
+		case ";":
+			p.pos++
+		case "option":
+			p.pos++
+			if err := p.skipOption(); err != nil {
+				return nil, err
+			}
+		case "reserved":
+			return nil, p.errorf("reserved enum values are not supported")
+		case "":
+			return nil, p.errorf("enum %s is not closed", name)
+		default:
+			vname, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			num, err := p.integer()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() == "[" {
+				// Enum value options, such as deprecated, do not affect
+				// validation.
+				for p.peek() != "]" && p.peek() != "" {
+					p.pos++
+				}
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+			e.Value = append(e.Value, &descriptorpb.EnumValueDescriptorProto{
+				Name:   proto.String(vname),
+				Number: proto.Int32(int32(num)),
+			})
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoschema
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const stateDefinition = `
+syntax = "proto3";
+
+package utilities;
+
+option go_package = "example.com/utilities";
+
+/* A state of a country. */
+message StateProto {
+  string name = 1;
+  string post_abbr = 2; // The postal abbreviation.
+  optional int64 population = 3;
+  Kind kind = 4;
+  repeated string tags = 5 [packed = false];
+  map<string, int32> codes = 6;
+  City capital = 7;
+  oneof area {
+    double square_miles = 8;
+    .utilities.StateProto.Area square_km = 9 [json_name = "km2"];
+  }
+  reserved 10, 12 to 14;
+  reserved "old";
+
+  message City {
+    string name = 1;
+  }
+  message Area {
+    double value = 1;
+  }
+}
+
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+  STATE = 1;
+  TERRITORY = 2 [deprecated = true];
+}
+
+message Unused {}
+`
+
+func TestParse(t *testing.T) {
+	md, err := Parse(stateDefinition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := md.FullName(), protoreflect.FullName("utilities.StateProto"); got != want {
+		t.Errorf("got message %s, want %s", got, want)
+	}
+	if f := md.Fields().ByName("population"); f == nil || !f.HasPresence() || f.ContainingOneof() == nil {
+		t.Errorf("population: got %v, want a proto3 optional field", f)
+	}
+	if f := md.Fields().ByName("codes"); f == nil || !f.IsMap() {
+		t.Errorf("codes: got %v, want a map field", f)
+	}
+	if f := md.Fields().ByName("square_km"); f == nil || f.JSONName() != "km2" || f.ContainingOneof().Name() != "area" {
+		t.Errorf("square_km: got %v", f)
+	}
+
+	m := dynamicpb.NewMessage(md)
+	data := `{"name": "Alaska", "postAbbr": "AK", "population": "733391", "kind": "STATE", "tags": ["cold"],
+		"codes": {"fips": 2}, "capital": {"name": "Juneau"}, "km2": {"value": 1.7e6}}`
+	if err := protojson.Unmarshal([]byte(data), m); err != nil {
+		t.Fatal(err)
+	}
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2 := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, m2); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(m, m2) {
+		t.Errorf("got %v, want %v", m2, m)
+	}
+	if err := protojson.Unmarshal([]byte(`{"unknown": 1}`), dynamicpb.NewMessage(md)); err == nil {
+		t.Error("unknown field: got nil, want error")
+	}
+}
+
+func TestParseProto2(t *testing.T) {
+	md, err := Parse(`
+		message Order {
+			required string id = 1;
+			optional int32 count = 2 [default = -1];
+			repeated Item items = 3;
+			message Item { optional string sku = 1; }
+			oneof payment { string card = 4; string cash = 5; }
+		}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := md.Fields().ByName("count"); f == nil || f.Default().Int() != -1 {
+		t.Errorf("count: got %v, want default -1", f)
+	}
+	// A message without its required field is invalid.
+	if err := proto.Unmarshal([]byte{0x10, 0x01}, dynamicpb.NewMessage(md)); err == nil {
+		t.Error("missing required field: got nil, want error")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, def := range []string{
+		``,
+		`syntax = "proto4"; message M {}`,
+		`syntax = "proto3"; import "other.proto"; message M {}`,
+		`syntax = "proto3"; message M { Unknown u = 1; }`,
+		`syntax = "proto3"; message M { string s = 1 }`,
+		`syntax = "proto3"; message M { string s = 1;`,
+		`syntax = "proto3"; message M { required string s = 1; }`,
+		`syntax = "proto3"; message M { string s = 1; int32 i = 1; }`,
+		`syntax = "proto3"; enum E { A = 1; } message M { E e = 1; }`,
+		`syntax = "proto3"; enum E { A = 0; }`,
+		`syntax = "proto3"; service S {} message M {}`,
+		`message M { string s = 1; }`,
+	} {
+		if _, err := Parse(def); err == nil {
+			t.Errorf("%s: got nil, want error", def)
+		}
+	}
+}
@@ -17,6 +17,7 @@ package scheduler
 import (
 	"errors"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -199,6 +200,18 @@ func (s *PublishScheduler) Pause(orderingKey string) {
 	}
 }
 
+// PausedKeys returns the paused ordering keys, in sorted order.
+func (s *PublishScheduler) PausedKeys() []string {
+	s.keysMu.RLock()
+	defer s.keysMu.RUnlock()
+	keys := make([]string, 0, len(s.keysWithErrors))
+	for k := range s.keysWithErrors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Resume resumes accepting message with the provided ordering key.
 func (s *PublishScheduler) Resume(orderingKey string) {
 	s.keysMu.Lock()
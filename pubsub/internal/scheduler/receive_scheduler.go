@@ -24,6 +24,16 @@ import (
 // handled in random order. Items added to any other key are handled
 // sequentially.
 type ReceiveScheduler struct {
+	// MaxKeys is the maximum number of non-empty keys whose items are
+	// handled concurrently. If it is 0, it is limited only by the number of
+	// workers.
+	MaxKeys int
+
+	// MaxItemsPerKey is the maximum number of items queued for a non-empty
+	// key, not counting the item being handled. Add blocks while the queue of
+	// the key is full. If it is 0, the queues are unbounded.
+	MaxItemsPerKey int
+
 	// workers is a channel that represents workers. Rather than a pool, where
 	// worker are "removed" until the pool is empty, the channel is more like a
 	// set of work desks, where workers are "added" until all the desks are full.
@@ -38,7 +48,30 @@ type ReceiveScheduler struct {
 	done    chan struct{}
 
 	mu sync.Mutex
-	m  map[string][]func()
+	// changed is signaled when a queue shrinks or a key stops being handled.
+	changed    *sync.Cond
+	m          map[string][]func()
+	activeKeys int   // keys that have a worker
+	waits      int64 // calls to Add that blocked on MaxKeys or MaxItemsPerKey
+}
+
+// ReceiveSchedulerStats describes the queues of the non-empty keys of a
+// ReceiveScheduler.
+type ReceiveSchedulerStats struct {
+	// Keys is the number of keys that have items queued or being handled.
+	Keys int
+
+	// QueuedItems is the number of items queued for all keys.
+	QueuedItems int
+
+	// DeepestKey is the key with the most items queued, and DeepestKeyItems
+	// is the number of those items.
+	DeepestKey      string
+	DeepestKeyItems int
+
+	// Waits is the number of calls to Add that blocked because MaxKeys keys
+	// were being handled, or because the queue of their key was full.
+	Waits int64
 }
 
 // NewReceiveScheduler creates a new ReceiveScheduler.
@@ -53,11 +86,13 @@ func NewReceiveScheduler(workers int) *ReceiveScheduler {
 		workers = 1e9
 	}
 
-	return &ReceiveScheduler{
+	s := &ReceiveScheduler{
 		workers: make(chan struct{}, workers),
 		done:    make(chan struct{}),
 		m:       make(map[string][]func()),
 	}
+	s.changed = sync.NewCond(&s.mu)
+	return s
 }
 
 // Add adds the item to be handled. Add may block.
@@ -65,7 +100,8 @@ func NewReceiveScheduler(workers int) *ReceiveScheduler {
 // Buffering happens above the ReceiveScheduler in the form of a flow controller
 // that requests batches of messages to pull. A backed up ReceiveScheduler.Add
 // call causes pushback to the pubsub service (less Receive calls on the
-// long-lived stream), which keeps memory footprint stable.
+// long-lived stream), which keeps memory footprint stable. MaxItemsPerKey
+// bounds the share of that memory that a single key can hold.
 func (s *ReceiveScheduler) Add(key string, item interface{}, handle func(item interface{})) error {
 	if key == "" {
 		// Spawn a worker.
@@ -83,15 +119,29 @@ func (s *ReceiveScheduler) Add(key string, item interface{}, handle func(item in
 	// adding another item before this one gets queued.
 
 	s.mu.Lock()
+	if s.MaxItemsPerKey > 0 && len(s.m[key]) >= s.MaxItemsPerKey {
+		s.waits++
+		for len(s.m[key]) >= s.MaxItemsPerKey {
+			s.changed.Wait()
+		}
+	}
 	_, ok := s.m[key]
 	s.m[key] = append(s.m[key], func() {
 		handle(item)
 	})
-	s.mu.Unlock()
 	if ok {
 		// Someone is already working on this key.
+		s.mu.Unlock()
 		return nil
 	}
+	if s.MaxKeys > 0 && s.activeKeys >= s.MaxKeys {
+		s.waits++
+		for s.activeKeys >= s.MaxKeys {
+			s.changed.Wait()
+		}
+	}
+	s.activeKeys++
+	s.mu.Unlock()
 
 	// Spawn a worker.
 	s.workers <- struct{}{}
@@ -106,12 +156,15 @@ func (s *ReceiveScheduler) Add(key string, item interface{}, handle func(item in
 				// We're done processing items - the queue is empty. Delete
 				// the queue from the map and free up the worker.
 				delete(s.m, key)
+				s.activeKeys--
+				s.changed.Broadcast()
 				s.mu.Unlock()
 				return
 			}
 			// Pop an item from the queue.
 			next := s.m[key][0]
 			s.m[key] = s.m[key][1:]
+			s.changed.Broadcast()
 			s.mu.Unlock()
 
 			next() // Handle next in queue.
@@ -121,6 +174,20 @@ func (s *ReceiveScheduler) Add(key string, item interface{}, handle func(item in
 	return nil
 }
 
+// Stats returns statistics about the queues of the non-empty keys.
+func (s *ReceiveScheduler) Stats() ReceiveSchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := ReceiveSchedulerStats{Keys: len(s.m), Waits: s.waits}
+	for k, q := range s.m {
+		st.QueuedItems += len(q)
+		if len(q) > st.DeepestKeyItems || (len(q) == st.DeepestKeyItems && k < st.DeepestKey) {
+			st.DeepestKey, st.DeepestKeyItems = k, len(q)
+		}
+	}
+	return st
+}
+
 // Shutdown begins flushing messages and stops accepting new Add calls. Shutdown
 // does not block, or wait for all messages to be flushed.
 func (s *ReceiveScheduler) Shutdown() {
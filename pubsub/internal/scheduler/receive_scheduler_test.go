@@ -173,3 +173,74 @@ func TestReceiveScheduler_FlushAndStop(t *testing.T) {
 		})
 	}
 }
+
+// MaxItemsPerKey and MaxKeys make Add block, and Stats reports the queues.
+func TestReceiveScheduler_KeyLimits(t *testing.T) {
+	release := make(chan struct{})
+	handled := make(chan pair, 10)
+	handle := func(itemi interface{}) {
+		<-release
+		handled <- itemi.(pair)
+	}
+	s := scheduler.NewReceiveScheduler(10)
+	s.MaxKeys = 1
+	s.MaxItemsPerKey = 2
+	defer s.Shutdown()
+
+	waitStats := func(want scheduler.ReceiveSchedulerStats) {
+		t.Helper()
+		var got scheduler.ReceiveSchedulerStats
+		for i := 0; i < 100; i++ {
+			if got = s.Stats(); got == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// The first item of "a" is being handled, and two more are queued.
+	if err := s.Add("a", pair{"a", 0}, handle); err != nil {
+		t.Fatal(err)
+	}
+	waitStats(scheduler.ReceiveSchedulerStats{Keys: 1})
+	for i := 1; i < 3; i++ {
+		if err := s.Add("a", pair{"a", i}, handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+	added := make(chan string, 2)
+	go func() {
+		// Blocks until an item of "a" is handled.
+		s.Add("a", pair{"a", 3}, handle)
+		added <- "a"
+		// Blocks until "a" is done, as only one key is handled at a time.
+		s.Add("b", pair{"b", 0}, handle)
+		added <- "b"
+	}()
+	waitStats(scheduler.ReceiveSchedulerStats{Keys: 1, QueuedItems: 2, DeepestKey: "a", DeepestKeyItems: 2, Waits: 1})
+	select {
+	case k := <-added:
+		t.Fatalf("Add(%q) did not block", k)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release <- struct{}{}
+	if k := <-added; k != "a" {
+		t.Fatalf("got Add(%q) returned, want Add(\"a\")", k)
+	}
+	waitStats(scheduler.ReceiveSchedulerStats{Keys: 2, QueuedItems: 3, DeepestKey: "a", DeepestKeyItems: 2, Waits: 2})
+
+	close(release)
+	<-added
+	var got []pair
+	for i := 0; i < 5; i++ {
+		got = append(got, <-handled)
+	}
+	want := []pair{{"a", 0}, {"a", 1}, {"a", 2}, {"a", 3}, {"b", 0}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
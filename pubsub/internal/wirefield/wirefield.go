@@ -0,0 +1,145 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wirefield reads and writes fields of protocol buffer messages by
+// field number, in their encoded form. It is for fields that the service
+// supports but that the generated code used by this module does not have yet,
+// which the protobuf runtime keeps as unknown fields.
+package wirefield
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var errInvalid = errors.New("wirefield: invalid encoding")
+
+// Range calls f with the number, type and value of each field encoded in b, in
+// order, until f returns false. The value of a length-delimited field is its
+// contents, without the length prefix; the value of a varint field is its
+// varint encoding. Fixed-size values are as encoded.
+func Range(b []byte, f func(num protowire.Number, typ protowire.Type, value []byte) bool) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errInvalid
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return errInvalid
+		}
+		value := b[:n]
+		if typ == protowire.BytesType {
+			v, m := protowire.ConsumeBytes(value)
+			if m < 0 {
+				return errInvalid
+			}
+			value = v
+		}
+		b = b[n:]
+		if !f(num, typ, value) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// Bytes returns the contents of the length-delimited unknown fields numbered
+// num of m, concatenated, and whether there are any. A message-typed field may
+// be split across several occurrences, whose concatenation is the encoding of
+// the merged message.
+func Bytes(m protoreflect.Message, num protowire.Number) ([]byte, bool) {
+	var out []byte
+	var found bool
+	Range(m.GetUnknown(), func(n protowire.Number, typ protowire.Type, v []byte) bool {
+		if n == num && typ == protowire.BytesType {
+			out = append(out, v...)
+			found = true
+		}
+		return true
+	})
+	return out, found
+}
+
+// Varint returns the value of the last varint unknown field numbered num of m,
+// and whether there is one.
+func Varint(m protoreflect.Message, num protowire.Number) (uint64, bool) {
+	var out uint64
+	var found bool
+	Range(m.GetUnknown(), func(n protowire.Number, typ protowire.Type, v []byte) bool {
+		if n == num && typ == protowire.VarintType {
+			if x, k := protowire.ConsumeVarint(v); k > 0 {
+				out, found = x, true
+			}
+		}
+		return true
+	})
+	return out, found
+}
+
+// SetBytes replaces the unknown fields numbered num of m with a
+// length-delimited field with contents v.
+func SetBytes(m protoreflect.Message, num protowire.Number, v []byte) {
+	b := withoutField(m.GetUnknown(), num)
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	b = protowire.AppendBytes(b, v)
+	m.SetUnknown(b)
+}
+
+// Clear removes the unknown fields numbered num of m.
+func Clear(m protoreflect.Message, num protowire.Number) {
+	if b := m.GetUnknown(); len(b) > 0 {
+		m.SetUnknown(withoutField(b, num))
+	}
+}
+
+// Copy replaces the unknown fields numbered num of dst with those of src.
+func Copy(dst, src protoreflect.Message, num protowire.Number) {
+	b := withoutField(dst.GetUnknown(), num)
+	b = append(b, onlyField(src.GetUnknown(), num)...)
+	dst.SetUnknown(b)
+}
+
+// withoutField returns the fields encoded in b other than those numbered num.
+func withoutField(b []byte, num protowire.Number) protoreflect.RawFields {
+	return filter(b, func(n protowire.Number) bool { return n != num })
+}
+
+// onlyField returns the fields encoded in b that are numbered num.
+func onlyField(b []byte, num protowire.Number) protoreflect.RawFields {
+	return filter(b, func(n protowire.Number) bool { return n == num })
+}
+
+func filter(b []byte, keep func(protowire.Number) bool) protoreflect.RawFields {
+	var out []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			// Keep what cannot be parsed as it is.
+			return append(out, b...)
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return append(out, b...)
+		}
+		if keep(num) {
+			out = append(out, b[:n+m]...)
+		}
+		b = b[n+m:]
+	}
+	return out
+}
@@ -0,0 +1,99 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wirefield
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestFields(t *testing.T) {
+	// Unknown fields 3 and 4 of a Duration, which has fields 1 and 2.
+	var b []byte
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendString(b, "ab")
+	b = protowire.AppendTag(b, 4, protowire.VarintType)
+	b = protowire.AppendVarint(b, 300)
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendString(b, "c")
+	d := &durationpb.Duration{}
+	if err := proto.Unmarshal(append(b, 0x08, 0x01), d); err != nil {
+		t.Fatal(err)
+	}
+	m := d.ProtoReflect()
+
+	if got, ok := Bytes(m, 3); !ok || string(got) != "abc" {
+		t.Errorf("Bytes(3): got %q, %t, want \"abc\", true", got, ok)
+	}
+	if got, ok := Varint(m, 4); !ok || got != 300 {
+		t.Errorf("Varint(4): got %d, %t, want 300, true", got, ok)
+	}
+	if _, ok := Bytes(m, 4); ok {
+		t.Error("Bytes(4): got a value, want none")
+	}
+
+	SetBytes(m, 3, []byte("d"))
+	if got, _ := Bytes(m, 3); string(got) != "d" {
+		t.Errorf("Bytes(3) after SetBytes: got %q, want \"d\"", got)
+	}
+	if _, ok := Varint(m, 4); !ok {
+		t.Error("SetBytes removed field 4")
+	}
+
+	d2 := &durationpb.Duration{}
+	Copy(d2.ProtoReflect(), m, 3)
+	if got, _ := Bytes(d2.ProtoReflect(), 3); string(got) != "d" {
+		t.Errorf("Bytes(3) after Copy: got %q, want \"d\"", got)
+	}
+	if _, ok := Varint(d2.ProtoReflect(), 4); ok {
+		t.Error("Copy copied field 4")
+	}
+
+	Clear(m, 3)
+	Clear(m, 4)
+	if len(m.GetUnknown()) != 0 {
+		t.Errorf("after Clear: got unknown fields %v, want none", m.GetUnknown())
+	}
+	if d.Seconds != 1 {
+		t.Errorf("got seconds %d, want 1", d.Seconds)
+	}
+}
+
+func TestRange(t *testing.T) {
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	b = protowire.AppendString(b, "x")
+	b = protowire.AppendTag(b, 2, protowire.Fixed32Type)
+	b = protowire.AppendFixed32(b, 7)
+	var nums []protowire.Number
+	var values [][]byte
+	err := Range(b, func(num protowire.Number, _ protowire.Type, v []byte) bool {
+		nums = append(nums, num)
+		values = append(values, v)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nums) != 2 || nums[0] != 1 || nums[1] != 2 || !bytes.Equal(values[0], []byte("x")) || len(values[1]) != 4 {
+		t.Errorf("got fields %v with values %q", nums, values)
+	}
+	if err := Range(b[:len(b)-1], func(protowire.Number, protowire.Type, []byte) bool { return true }); err == nil {
+		t.Error("truncated: got nil, want error")
+	}
+}
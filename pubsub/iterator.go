@@ -16,15 +16,18 @@ package pubsub
 
 import (
 	"context"
+	"errors"
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	vkit "cloud.google.com/go/pubsub/apiv1"
 	"cloud.google.com/go/pubsub/internal/distribution"
 	gax "github.com/googleapis/gax-go/v2"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -37,7 +40,20 @@ import (
 // of the actual deadline.
 const gracePeriod = 5 * time.Second
 
+// With exactly-once delivery, a message whose ack deadline expires gets a new
+// ack ID, and acking it with the old one fails. To make that less likely, the
+// ack deadline is at least minExactlyOnceAckDeadline when exactly-once delivery
+// is enabled.
+const minExactlyOnceAckDeadline = 60 * time.Second
+
+// errAckAfterStop is the error of an AckResult for a message that was acked or
+// nacked after its iterator had stopped and sent its final acks.
+var errAckAfterStop = errors.New("pubsub: message was acked or nacked after its iterator stopped")
+
 type messageIterator struct {
+	// sent is first for the 64-bit alignment of its atomic counts.
+	sent ackCounts
+
 	ctx        context.Context
 	cancel     func() // the function that will cancel ctx; called in stop
 	po         *pullOptions
@@ -62,10 +78,20 @@ type messageIterator struct {
 	// to update ack deadlines (via modack), we'll consult this table and only include IDs
 	// that are not beyond their deadline.
 	keepAliveDeadlines map[string]time.Time
-	pendingAcks        map[string]bool
-	pendingNacks       map[string]bool
-	pendingModAcks     map[string]bool // ack IDs whose ack deadline is to be modified
-	err                error           // error from stream failure
+	// manualLeases holds the ack IDs of the messages whose ack deadlines
+	// are managed with ModifyAckDeadline, which are not extended
+	// automatically. Their keepAliveDeadlines are their ack deadlines.
+	manualLeases map[string]bool
+	// The pending maps hold the AckResult of each ack ID, or nil if it has
+	// none.
+	pendingAcks    map[string]*AckResult
+	pendingNacks   map[string]*AckResult
+	pendingModAcks map[string]*AckResult // ack IDs whose ack deadline is to be modified
+	sendingAcks    bool                  // acks or nacks taken from the pending maps are being sent
+	err            error                 // error from stream failure
+
+	eoMu                      sync.RWMutex
+	enableExactlyOnceDelivery bool
 }
 
 // newMessageIterator starts and returns a new messageIterator.
@@ -108,9 +134,12 @@ func newMessageIterator(subc *vkit.SubscriberClient, subName string, po *pullOpt
 		drained:            make(chan struct{}),
 		ackTimeDist:        distribution.New(int(maxAckDeadline/time.Second) + 1),
 		keepAliveDeadlines: map[string]time.Time{},
-		pendingAcks:        map[string]bool{},
-		pendingNacks:       map[string]bool{},
-		pendingModAcks:     map[string]bool{},
+		manualLeases:       map[string]bool{},
+		pendingAcks:        map[string]*AckResult{},
+		pendingNacks:       map[string]*AckResult{},
+		pendingModAcks:     map[string]*AckResult{},
+
+		enableExactlyOnceDelivery: po.exactlyOnceDelivery,
 	}
 	it.wg.Add(1)
 	go it.sender()
@@ -129,6 +158,60 @@ func (it *messageIterator) stop() {
 	it.wg.Wait()
 }
 
+// expire stops extending the ack deadlines of the outstanding messages, as if
+// they had expired, so that a stopped iterator does not wait for them.
+func (it *messageIterator) expire() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	it.keepAliveDeadlines = map[string]time.Time{}
+	it.manualLeases = map[string]bool{}
+	it.checkDrained()
+}
+
+// modifyAckDeadline sets the ack deadline of an outstanding message to d from
+// now, and stops extending it automatically.
+func (it *messageIterator) modifyAckDeadline(ctx context.Context, ackID string, d time.Duration) error {
+	it.mu.Lock()
+	if _, ok := it.keepAliveDeadlines[ackID]; !ok {
+		it.mu.Unlock()
+		return errLeaseExpired
+	}
+	it.keepAliveDeadlines[ackID] = time.Now().Add(d)
+	it.manualLeases[ackID] = true
+	it.mu.Unlock()
+
+	recordStat(it.ctx, ModAckCount, 1)
+	atomic.AddInt64(&it.sent.modAcks, 1)
+	return it.subc.ModifyAckDeadline(ctx, &pb.ModifyAckDeadlineRequest{
+		Subscription:       it.subName,
+		AckDeadlineSeconds: int32(d / time.Second),
+		AckIds:             []string{ackID},
+	})
+}
+
+// setMaxExtension changes the time until which an outstanding message's ack
+// deadline is extended automatically.
+func (it *messageIterator) setMaxExtension(ackID string, expiry time.Time) error {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	if _, ok := it.keepAliveDeadlines[ackID]; !ok {
+		return errLeaseExpired
+	}
+	if it.manualLeases[ackID] {
+		return errors.New("pubsub: the ack deadline of the message is managed with ModifyAckDeadline")
+	}
+	it.keepAliveDeadlines[ackID] = expiry
+	return nil
+}
+
+// acksSent reports whether the acks and nacks of all messages done so far have
+// been sent, or will not be.
+func (it *messageIterator) acksSent() bool {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.err != nil || (len(it.pendingAcks) == 0 && len(it.pendingNacks) == 0 && !it.sendingAcks)
+}
+
 // checkDrained closes the drained channel if the iterator has been stopped and all
 // pending messages have either been n/acked or expired.
 //
@@ -149,15 +232,29 @@ func (it *messageIterator) checkDrained() {
 }
 
 // Called when a message is acked/nacked.
-func (it *messageIterator) done(ackID string, ack bool, receiveTime time.Time) {
+func (it *messageIterator) done(ackID string, ack bool, r *AckResult, receiveTime time.Time) {
 	it.ackTimeDist.Record(int(time.Since(receiveTime) / time.Second))
 	it.mu.Lock()
 	defer it.mu.Unlock()
 	delete(it.keepAliveDeadlines, ackID)
+	delete(it.manualLeases, ackID)
+	if r != nil {
+		// Nothing will send the ack or nack, so report that now.
+		if it.err != nil {
+			r.set(AcknowledgeStatusOther, it.err)
+			return
+		}
+		select {
+		case <-it.drained:
+			r.set(AcknowledgeStatusOther, errAckAfterStop)
+			return
+		default:
+		}
+	}
 	if ack {
-		it.pendingAcks[ackID] = true
+		it.pendingAcks[ackID] = r
 	} else {
-		it.pendingNacks[ackID] = true
+		it.pendingNacks[ackID] = r
 	}
 	it.checkDrained()
 }
@@ -171,10 +268,27 @@ func (it *messageIterator) fail(err error) error {
 	if it.err == nil {
 		it.err = err
 		close(it.failed)
+		// The pending acks and nacks will not be sent.
+		for _, m := range []map[string]*AckResult{it.pendingAcks, it.pendingNacks} {
+			for id, r := range m {
+				if r != nil {
+					r.set(AcknowledgeStatusOther, err)
+					delete(m, id)
+				}
+			}
+		}
 	}
 	return it.err
 }
 
+// exactlyOnceDelivery reports whether the subscription has exactly-once
+// delivery enabled, as far as the iterator knows.
+func (it *messageIterator) exactlyOnceDelivery() bool {
+	it.eoMu.RLock()
+	defer it.eoMu.RUnlock()
+	return it.enableExactlyOnceDelivery
+}
+
 // receive makes a call to the stream's Recv method, or the Pull RPC, and returns
 // its messages.
 // maxToPull is the maximum number of messages for the Pull RPC.
@@ -211,19 +325,28 @@ func (it *messageIterator) receive(maxToPull int32) ([]*Message, error) {
 	if err != nil {
 		return nil, it.fail(err)
 	}
+	exactlyOnce := it.exactlyOnceDelivery()
 	// We received some messages. Remember them so we can keep them alive. Also,
 	// do a receipt mod-ack when streaming.
 	maxExt := time.Now().Add(it.po.maxExtension)
-	ackIDs := map[string]bool{}
+	ackIDs := map[string]*AckResult{}
 	it.mu.Lock()
 	for _, m := range msgs {
 		ackID := msgAckID(m)
+		if ackh, ok := msgAckHandler(m); ok {
+			ackh.exactlyOnceDelivery = exactlyOnce
+			ackh.lease = it
+		}
 		addRecv(m.ID, ackID, now)
 		it.keepAliveDeadlines[ackID] = maxExt
 		// Don't change the mod-ack if the message is going to be nacked. This is
 		// possible if there are retries.
-		if !it.pendingNacks[ackID] {
-			ackIDs[ackID] = true
+		if _, ok := it.pendingNacks[ackID]; !ok {
+			var r *AckResult
+			if exactlyOnce {
+				r = newAckResult()
+			}
+			ackIDs[ackID] = r
 		}
 	}
 	deadline := it.ackDeadline()
@@ -233,6 +356,18 @@ func (it *messageIterator) receive(maxToPull int32) ([]*Message, error) {
 			return nil, it.err
 		}
 	}
+	if exactlyOnce {
+		// With exactly-once delivery, a message whose receipt mod-ack failed
+		// because its ack ID is invalid cannot be acked, so don't deliver it.
+		delivered := msgs[:0]
+		for _, m := range msgs {
+			if r := ackIDs[msgAckID(m)]; r != nil && r.status == AcknowledgeStatusInvalidAckID {
+				continue
+			}
+			delivered = append(delivered, m)
+		}
+		msgs = delivered
+	}
 	return msgs, nil
 }
 
@@ -262,6 +397,11 @@ func (it *messageIterator) recvMessages() ([]*pb.ReceivedMessage, error) {
 	if err != nil {
 		return nil, err
 	}
+	if sp := res.GetSubscriptionProperties(); sp != nil {
+		it.eoMu.Lock()
+		it.enableExactlyOnceDelivery = sp.GetExactlyOnceDeliveryEnabled()
+		it.eoMu.Unlock()
+	}
 	return res.ReceivedMessages, nil
 }
 
@@ -327,19 +467,20 @@ func (it *messageIterator) sender() {
 			sendPing = !it.po.synchronous
 		}
 		// Lock is held here.
-		var acks, nacks, modAcks map[string]bool
+		var acks, nacks, modAcks map[string]*AckResult
 		if sendAcks {
 			acks = it.pendingAcks
-			it.pendingAcks = map[string]bool{}
+			it.pendingAcks = map[string]*AckResult{}
 		}
 		if sendNacks {
 			nacks = it.pendingNacks
-			it.pendingNacks = map[string]bool{}
+			it.pendingNacks = map[string]*AckResult{}
 		}
 		if sendModAcks {
 			modAcks = it.pendingModAcks
-			it.pendingModAcks = map[string]bool{}
+			it.pendingModAcks = map[string]*AckResult{}
 		}
+		it.sendingAcks = sendAcks || sendNacks
 		it.mu.Unlock()
 		// Make Ack and ModAck RPCs.
 		if sendAcks {
@@ -353,6 +494,11 @@ func (it *messageIterator) sender() {
 				return
 			}
 		}
+		if sendAcks || sendNacks {
+			it.mu.Lock()
+			it.sendingAcks = false
+			it.mu.Unlock()
+		}
 		if sendModAcks {
 			if !it.sendModAck(modAcks, dl) {
 				return
@@ -377,19 +523,22 @@ func (it *messageIterator) handleKeepAlives() {
 			// statements with range clause", note 3, and stated explicitly at
 			// https://groups.google.com/forum/#!msg/golang-nuts/UciASUb03Js/pzSq5iVFAQAJ.
 			delete(it.keepAliveDeadlines, id)
-		} else {
+			delete(it.manualLeases, id)
+		} else if !it.manualLeases[id] {
 			// This will not conflict with a nack, because nacking removes the ID from keepAliveDeadlines.
-			it.pendingModAcks[id] = true
+			it.pendingModAcks[id] = nil
 		}
 	}
 	it.checkDrained()
 }
 
-func (it *messageIterator) sendAck(m map[string]bool) bool {
+func (it *messageIterator) sendAck(m map[string]*AckResult) bool {
 	// Account for the Subscription field.
 	overhead := calcFieldSizeString(it.subName)
+	exactlyOnce := it.exactlyOnceDelivery()
 	return it.sendAckIDRPC(m, maxPayload-overhead, func(ids []string) error {
 		recordStat(it.ctx, AckCount, int64(len(ids)))
+		atomic.AddInt64(&it.sent.acks, int64(len(ids)))
 		addAcks(ids)
 		bo := gax.Backoff{
 			Initial:    100 * time.Millisecond,
@@ -407,6 +556,18 @@ func (it *messageIterator) sendAck(m map[string]bool) bool {
 				Subscription: it.subName,
 				AckIds:       ids,
 			})
+			if exactlyOnce {
+				// Set the results of the acks, and retry those that failed
+				// transiently until the outer context expires.
+				if ids = it.handleExactlyOnceResults(err, ids, m); len(ids) == 0 {
+					return nil
+				}
+				if err := gax.Sleep(cctx, bo.Pause()); err != nil {
+					setAckResults(ids, m, AcknowledgeStatusOther, err)
+					return nil
+				}
+				continue
+			}
 			// Retry DeadlineExceeded errors a few times before giving up and
 			// allowing the message to expire and be redelivered.
 			// The underlying library handles other retries, currently only
@@ -445,15 +606,18 @@ func (it *messageIterator) sendAck(m map[string]bool) bool {
 // on the time it takes to process messages. The percentile chosen is the 99%th
 // percentile in order to capture the highest amount of time necessary without
 // considering 1% outliers.
-func (it *messageIterator) sendModAck(m map[string]bool, deadline time.Duration) bool {
+func (it *messageIterator) sendModAck(m map[string]*AckResult, deadline time.Duration) bool {
 	deadlineSec := int32(deadline / time.Second)
 	// Account for the Subscription and AckDeadlineSeconds fields.
 	overhead := calcFieldSizeString(it.subName) + calcFieldSizeInt(int(deadlineSec))
+	exactlyOnce := it.exactlyOnceDelivery()
 	return it.sendAckIDRPC(m, maxPayload-overhead, func(ids []string) error {
 		if deadline == 0 {
 			recordStat(it.ctx, NackCount, int64(len(ids)))
+			atomic.AddInt64(&it.sent.nacks, int64(len(ids)))
 		} else {
 			recordStat(it.ctx, ModAckCount, int64(len(ids)))
+			atomic.AddInt64(&it.sent.modAcks, int64(len(ids)))
 		}
 		addModAcks(ids, deadlineSec)
 		// Retry this RPC on Unavailable for a short amount of time, then give up
@@ -474,6 +638,17 @@ func (it *messageIterator) sendModAck(m map[string]bool, deadline time.Duration)
 				AckDeadlineSeconds: deadlineSec,
 				AckIds:             ids,
 			})
+			if exactlyOnce {
+				if ids = it.handleExactlyOnceResults(err, ids, m); len(ids) == 0 {
+					return nil
+				}
+				if err := gax.Sleep(cctx, bo.Pause()); err != nil {
+					recordStat(it.ctx, ModAckTimeoutCount, 1)
+					setAckResults(ids, m, AcknowledgeStatusOther, err)
+					return nil
+				}
+				continue
+			}
 			switch status.Code(err) {
 			case codes.Unavailable:
 				if err := gax.Sleep(cctx, bo.Pause()); err == nil {
@@ -503,7 +678,7 @@ func (it *messageIterator) sendModAck(m map[string]bool, deadline time.Duration)
 	})
 }
 
-func (it *messageIterator) sendAckIDRPC(ackIDSet map[string]bool, maxSize int, call func([]string) error) bool {
+func (it *messageIterator) sendAckIDRPC(ackIDSet map[string]*AckResult, maxSize int, call func([]string) error) bool {
 	ackIDs := make([]string, 0, len(ackIDSet))
 	for k := range ackIDSet {
 		ackIDs = append(ackIDs, k)
@@ -514,13 +689,114 @@ func (it *messageIterator) sendAckIDRPC(ackIDSet map[string]bool, maxSize int, c
 		if err := call(toSend); err != nil {
 			// The underlying client handles retries, so any error is fatal to the
 			// iterator.
+			setAckResults(toSend, ackIDSet, AcknowledgeStatusOther, err)
+			setAckResults(ackIDs, ackIDSet, AcknowledgeStatusOther, err)
 			it.fail(err)
 			return false
 		}
+		// Set any results that call did not, which happens if exactly-once
+		// delivery was disabled after the messages were received. Without
+		// exactly-once delivery, acks succeed as soon as they are sent.
+		setAckResults(toSend, ackIDSet, AcknowledgeStatusSuccess, nil)
 	}
 	return true
 }
 
+// handleExactlyOnceResults sets the results of the ack IDs of an Acknowledge or
+// ModifyAckDeadline RPC that returned err, when exactly-once delivery is
+// enabled, and returns the ack IDs to retry. It stops extending the ack
+// deadlines of messages whose ack IDs are no longer valid.
+func (it *messageIterator) handleExactlyOnceResults(err error, ids []string, results map[string]*AckResult) []string {
+	retry, invalid := processResults(err, ids, results)
+	if len(invalid) > 0 {
+		it.mu.Lock()
+		for _, id := range invalid {
+			delete(it.keepAliveDeadlines, id)
+		}
+		it.checkDrained()
+		it.mu.Unlock()
+	}
+	return retry
+}
+
+// processResults sets the results of the ack IDs of an Acknowledge or
+// ModifyAckDeadline RPC that returned err, when exactly-once delivery is
+// enabled. It returns the ack IDs that failed transiently and should be retried,
+// whose results are not set, and those that are no longer valid.
+//
+// If the RPC failed for only some ack IDs, the service reports the reason of
+// each failure in the metadata of an ErrorInfo detail of the error, keyed by
+// ack ID.
+func processResults(err error, ids []string, results map[string]*AckResult) (retry, invalid []string) {
+	if err == nil {
+		setAckResults(ids, results, AcknowledgeStatusSuccess, nil)
+		return nil, nil
+	}
+	st, _ := status.FromError(err)
+	var failures map[string]string
+	for _, d := range st.Details() {
+		if ei, ok := d.(*errdetails.ErrorInfo); ok {
+			failures = ei.GetMetadata()
+			break
+		}
+	}
+	for _, id := range ids {
+		r := results[id]
+		reason, failed := failures[id]
+		switch {
+		case failed && strings.HasPrefix(reason, "TRANSIENT_"):
+			retry = append(retry, id)
+		case failed && reason == "PERMANENT_FAILURE_INVALID_ACK_ID":
+			invalid = append(invalid, id)
+			setAckResult(r, AcknowledgeStatusInvalidAckID, err)
+		case failed:
+			setAckResult(r, AcknowledgeStatusOther, err)
+		case failures != nil:
+			// The RPC failed only for the ack IDs in the metadata.
+			setAckResult(r, AcknowledgeStatusSuccess, nil)
+		case isRetryableAckError(err):
+			retry = append(retry, id)
+		case st.Code() == codes.PermissionDenied:
+			setAckResult(r, AcknowledgeStatusPermissionDenied, err)
+		case st.Code() == codes.FailedPrecondition:
+			setAckResult(r, AcknowledgeStatusFailedPrecondition, err)
+		default:
+			setAckResult(r, AcknowledgeStatusOther, err)
+		}
+	}
+	return retry, invalid
+}
+
+// isRetryableAckError reports whether an Acknowledge or ModifyAckDeadline RPC
+// that failed with err for all of its ack IDs should be retried.
+func isRetryableAckError(err error) bool {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Unavailable, codes.Internal, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	// See https://github.com/googleapis/google-cloud-go/issues/3060
+	return strings.Contains(err.Error(), "context deadline exceeded")
+}
+
+// setAckResults sets the results of the ack IDs that have one and that are not
+// already set.
+func setAckResults(ids []string, results map[string]*AckResult, as AcknowledgeStatus, err error) {
+	for _, id := range ids {
+		setAckResult(results[id], as, err)
+	}
+}
+
+func setAckResult(r *AckResult, as AcknowledgeStatus, err error) {
+	if r == nil {
+		return
+	}
+	select {
+	case <-r.ready:
+	default:
+		r.set(as, err)
+	}
+}
+
 // Send a message to the stream to keep it open. The stream will close if there's no
 // traffic on it for a while. By keeping it open, we delay the start of the
 // expiration timer on messages that are buffered by gRPC or elsewhere in the
@@ -575,9 +851,19 @@ func splitRequestIDs(ids []string, maxSize int) (prefix, remainder []string) {
 // times should be safe. The highest 1% may expire. This number was chosen
 // as a way to cover most users' usecases without losing the value of
 // expiration.
+//
+// With exactly-once delivery, the deadline is at least
+// minExactlyOnceAckDeadline, unless maxExtensionPeriod is shorter. It is also
+// at least minExtensionPeriod, if set, unless maxExtensionPeriod is shorter.
 func (it *messageIterator) ackDeadline() time.Duration {
 	pt := time.Duration(it.ackTimeDist.Percentile(.99)) * time.Second
+	if it.exactlyOnceDelivery() && pt < minExactlyOnceAckDeadline {
+		pt = minExactlyOnceAckDeadline
+	}
 
+	if it.po.minExtensionPeriod > 0 && pt < it.po.minExtensionPeriod {
+		pt = it.po.minExtensionPeriod
+	}
 	if it.po.maxExtensionPeriod > 0 && pt > it.po.maxExtensionPeriod {
 		return it.po.maxExtensionPeriod
 	}
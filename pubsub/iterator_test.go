@@ -131,6 +131,150 @@ func TestMaxExtensionPeriod(t *testing.T) {
 	}
 }
 
+func TestMinExtensionPeriod(t *testing.T) {
+	srv := pstest.NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv.Publish(fullyQualifiedTopicName, []byte("creating a topic"), nil)
+
+	_, client, err := initConn(ctx, srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		minExtensionPeriod time.Duration
+		maxExtensionPeriod time.Duration
+		want               time.Duration
+	}{
+		{0, 0, minAckDeadline},
+		{time.Minute, 0, time.Minute},
+		{time.Minute, 30 * time.Second, 30 * time.Second},
+	} {
+		iter := newMessageIterator(client.subc, fullyQualifiedTopicName, &pullOptions{
+			minExtensionPeriod: test.minExtensionPeriod,
+			maxExtensionPeriod: test.maxExtensionPeriod,
+		})
+		iter.ackTimeDist.Record(3)
+		if got := iter.ackDeadline(); got != test.want {
+			t.Errorf("%+v: deadline got = %v", test, got)
+		}
+		iter.stop()
+	}
+}
+
+func TestExactlyOnceAckDeadline(t *testing.T) {
+	srv := pstest.NewServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv.Publish(fullyQualifiedTopicName, []byte("creating a topic"), nil)
+
+	_, client, err := initConn(ctx, srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		maxExtensionPeriod time.Duration
+		want               time.Duration
+	}{
+		{0, minExactlyOnceAckDeadline},
+		{30 * time.Second, 30 * time.Second},
+	} {
+		iter := newMessageIterator(client.subc, fullyQualifiedTopicName, &pullOptions{
+			maxExtensionPeriod:  test.maxExtensionPeriod,
+			exactlyOnceDelivery: true,
+		})
+		iter.ackTimeDist.Record(3)
+		if got := iter.ackDeadline(); got != test.want {
+			t.Errorf("maxExtensionPeriod %v: deadline got = %v, want %v", test.maxExtensionPeriod, got, test.want)
+		}
+		iter.stop()
+	}
+}
+
+func TestProcessResults(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		err         error
+		wantStatus  map[string]AcknowledgeStatus // of the ack IDs with results
+		wantRetry   []string
+		wantInvalid []string
+	}{
+		{
+			desc:       "success",
+			wantStatus: map[string]AcknowledgeStatus{"a": AcknowledgeStatusSuccess, "b": AcknowledgeStatusSuccess, "c": AcknowledgeStatusSuccess},
+		},
+		{
+			desc: "some failed",
+			err: exactlyOnceError(codes.InvalidArgument, map[string]string{
+				"a": "PERMANENT_FAILURE_INVALID_ACK_ID",
+				"b": "TRANSIENT_FAILURE_UNORDERED_ACK_ID",
+			}),
+			wantStatus:  map[string]AcknowledgeStatus{"a": AcknowledgeStatusInvalidAckID, "c": AcknowledgeStatusSuccess},
+			wantRetry:   []string{"b"},
+			wantInvalid: []string{"a"},
+		},
+		{
+			desc:       "other permanent failure",
+			err:        exactlyOnceError(codes.InvalidArgument, map[string]string{"c": "PERMANENT_FAILURE_OTHER"}),
+			wantStatus: map[string]AcknowledgeStatus{"a": AcknowledgeStatusSuccess, "b": AcknowledgeStatusSuccess, "c": AcknowledgeStatusOther},
+		},
+		{
+			desc:      "unavailable",
+			err:       status.Error(codes.Unavailable, ""),
+			wantRetry: []string{"a", "b", "c"},
+		},
+		{
+			desc:       "permission denied",
+			err:        status.Error(codes.PermissionDenied, ""),
+			wantStatus: map[string]AcknowledgeStatus{"a": AcknowledgeStatusPermissionDenied, "b": AcknowledgeStatusPermissionDenied, "c": AcknowledgeStatusPermissionDenied},
+		},
+		{
+			desc:       "failed precondition",
+			err:        status.Error(codes.FailedPrecondition, ""),
+			wantStatus: map[string]AcknowledgeStatus{"a": AcknowledgeStatusFailedPrecondition, "b": AcknowledgeStatusFailedPrecondition, "c": AcknowledgeStatusFailedPrecondition},
+		},
+		{
+			desc:       "other error",
+			err:        errors.New("oops"),
+			wantStatus: map[string]AcknowledgeStatus{"a": AcknowledgeStatusOther, "b": AcknowledgeStatusOther, "c": AcknowledgeStatusOther},
+		},
+	} {
+		// Ack ID "c" has no result, like a keep-alive mod-ack.
+		ids := []string{"a", "b", "c"}
+		results := map[string]*AckResult{"a": newAckResult(), "b": newAckResult(), "c": nil}
+		if _, ok := test.wantStatus["c"]; ok {
+			results["c"] = newAckResult()
+		}
+		retry, invalid := processResults(test.err, ids, results)
+		if !testutil.Equal(retry, test.wantRetry) {
+			t.Errorf("%s: retry: got %v, want %v", test.desc, retry, test.wantRetry)
+		}
+		if !testutil.Equal(invalid, test.wantInvalid) {
+			t.Errorf("%s: invalid: got %v, want %v", test.desc, invalid, test.wantInvalid)
+		}
+		for id, r := range results {
+			if r == nil {
+				continue
+			}
+			want, ok := test.wantStatus[id]
+			select {
+			case <-r.Ready():
+				if !ok {
+					t.Errorf("%s: %s: got result %v, want none", test.desc, id, r.status)
+				} else if got, err := r.Get(context.Background()); got != want {
+					t.Errorf("%s: %s: got %v (%v), want %v", test.desc, id, got, err, want)
+				}
+			default:
+				if ok {
+					t.Errorf("%s: %s: no result, want %v", test.desc, id, want)
+				}
+			}
+		}
+	}
+}
+
 func TestAckDistribution(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
@@ -0,0 +1,236 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListOptions are options for listing topics, subscriptions and snapshots
+// with Client.ListTopics, Client.ListSubscriptions and Client.ListSnapshots.
+//
+// The Pub/Sub API does not filter the resources it lists, so NamePrefix and
+// Labels are applied by the client to each page of results: pages may have
+// fewer than PageSize results, and the listing takes as many RPCs as without
+// filters.
+type ListOptions struct {
+	// PageSize is the maximum number of resources returned by each RPC. If
+	// it is zero, the server chooses. It can also be set with the MaxSize of
+	// the PageInfo of the iterator.
+	PageSize int
+
+	// NamePrefix, if not empty, restricts the results to the resources
+	// whose IDs start with it.
+	NamePrefix string
+
+	// Labels, if not empty, restricts the results to the resources that
+	// have all of these labels, with these values. A label with an empty
+	// value matches any value.
+	Labels map[string]string
+}
+
+// match reports whether the resource with the given full name and labels
+// passes the filters of o.
+func (o *ListOptions) match(name string, labels map[string]string) bool {
+	if o == nil {
+		return true
+	}
+	if o.NamePrefix != "" && !strings.HasPrefix(name[strings.LastIndex(name, "/")+1:], o.NamePrefix) {
+		return false
+	}
+	for k, v := range o.Labels {
+		got, ok := labels[k]
+		if !ok || (v != "" && got != v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *ListOptions) pageSize() int32 {
+	if o == nil {
+		return 0
+	}
+	return trunc32(int64(o.PageSize))
+}
+
+// ListTopics is like Topics, but lists the topics of the client's project
+// according to opts, which may be nil.
+func (c *Client) ListTopics(ctx context.Context, opts *ListOptions) *TopicIterator {
+	it := c.pubc.ListTopics(ctx, &pb.ListTopicsRequest{
+		Project:  c.fullyQualifiedProjectName(),
+		PageSize: opts.pageSize(),
+	})
+	fetch := it.InternalFetch
+	it.InternalFetch = func(pageSize int, pageToken string) ([]*pb.Topic, string, error) {
+		topics, nextPageToken, err := fetch(pageSize, pageToken)
+		var res []*pb.Topic
+		for _, t := range topics {
+			if opts.match(t.Name, t.Labels) {
+				res = append(res, t)
+			}
+		}
+		return res, nextPageToken, err
+	}
+	return &TopicIterator{
+		c:  c,
+		it: it,
+		next: func() (string, error) {
+			topic, err := it.Next()
+			if err != nil {
+				return "", err
+			}
+			return topic.Name, nil
+		},
+	}
+}
+
+// ListSubscriptions is like Subscriptions, but lists the subscriptions of the
+// client's project according to opts, which may be nil.
+func (c *Client) ListSubscriptions(ctx context.Context, opts *ListOptions) *SubscriptionIterator {
+	it := c.subc.ListSubscriptions(ctx, &pb.ListSubscriptionsRequest{
+		Project:  c.fullyQualifiedProjectName(),
+		PageSize: opts.pageSize(),
+	})
+	fetch := it.InternalFetch
+	it.InternalFetch = func(pageSize int, pageToken string) ([]*pb.Subscription, string, error) {
+		subs, nextPageToken, err := fetch(pageSize, pageToken)
+		var res []*pb.Subscription
+		for _, s := range subs {
+			if opts.match(s.Name, s.Labels) {
+				res = append(res, s)
+			}
+		}
+		return res, nextPageToken, err
+	}
+	return &SubscriptionIterator{
+		c:        c,
+		it:       it,
+		pageInfo: it.PageInfo,
+		next: func() (string, error) {
+			sub, err := it.Next()
+			if err != nil {
+				return "", err
+			}
+			return sub.Name, nil
+		},
+	}
+}
+
+// ListSnapshots is like Snapshots, but lists the snapshots of the client's
+// project according to opts, which may be nil.
+func (c *Client) ListSnapshots(ctx context.Context, opts *ListOptions) *SnapshotConfigIterator {
+	it := c.subc.ListSnapshots(ctx, &pb.ListSnapshotsRequest{
+		Project:  c.fullyQualifiedProjectName(),
+		PageSize: opts.pageSize(),
+	})
+	fetch := it.InternalFetch
+	it.InternalFetch = func(pageSize int, pageToken string) ([]*pb.Snapshot, string, error) {
+		snaps, nextPageToken, err := fetch(pageSize, pageToken)
+		var res []*pb.Snapshot
+		for _, s := range snaps {
+			if opts.match(s.Name, s.Labels) {
+				res = append(res, s)
+			}
+		}
+		return res, nextPageToken, err
+	}
+	next := func() (*SnapshotConfig, error) {
+		snap, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		return toSnapshotConfig(snap, c)
+	}
+	return &SnapshotConfigIterator{next: next, pageInfo: it.PageInfo}
+}
+
+// TopicConfigs gets the configs of topics, calling GetTopic for at most
+// maxConcurrency topics at a time, or 10 if maxConcurrency is less than 1. The
+// config of topics[i] is the i-th element of the result, or nil if the topic
+// does not exist. If getting a config fails, TopicConfigs stops and returns
+// the first error.
+func (c *Client) TopicConfigs(ctx context.Context, topics []*Topic, maxConcurrency int) ([]*TopicConfig, error) {
+	cfgs := make([]*TopicConfig, len(topics))
+	err := forEachConcurrently(ctx, len(topics), maxConcurrency, func(ctx context.Context, i int) error {
+		cfg, err := topics[i].Config(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cfgs[i] = &cfg
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfgs, nil
+}
+
+// SubscriptionConfigs gets the configs of subscriptions, calling
+// GetSubscription for at most maxConcurrency subscriptions at a time, or 10 if
+// maxConcurrency is less than 1. The config of subs[i] is the i-th element of
+// the result, or nil if the subscription does not exist. If getting a config
+// fails, SubscriptionConfigs stops and returns the first error.
+func (c *Client) SubscriptionConfigs(ctx context.Context, subs []*Subscription, maxConcurrency int) ([]*SubscriptionConfig, error) {
+	cfgs := make([]*SubscriptionConfig, len(subs))
+	err := forEachConcurrently(ctx, len(subs), maxConcurrency, func(ctx context.Context, i int) error {
+		cfg, err := subs[i].Config(ctx)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cfgs[i] = &cfg
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cfgs, nil
+}
+
+// forEachConcurrently calls f for 0 through n-1, with at most maxConcurrency
+// calls (or 10, if it is less than 1) running at a time. It stops at the
+// first error, canceling the context of the running calls, and returns it.
+func forEachConcurrently(ctx context.Context, n, maxConcurrency int, f func(context.Context, int) error) error {
+	if maxConcurrency < 1 {
+		maxConcurrency = 10
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, maxConcurrency)
+	for i := 0; i < n; i++ {
+		i := i
+		select {
+		case sem <- struct{}{}:
+		case <-gctx.Done():
+			return g.Wait()
+		}
+		g.Go(func() error {
+			defer func() { <-sem }()
+			return f(gctx, i)
+		})
+	}
+	return g.Wait()
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+)
+
+func TestListWithOptions(t *testing.T) {
+	ctx := context.Background()
+	c, srv := newFake(t)
+	defer c.Close()
+	defer srv.Close()
+
+	for _, id := range []string{"a1", "a2", "a3", "b1"} {
+		topic := mustCreateTopicWithConfig(t, c, id, &TopicConfig{Labels: map[string]string{"env": id[:1]}})
+		if _, err := c.CreateSubscription(ctx, "s"+id, SubscriptionConfig{Topic: topic, Labels: map[string]string{"env": id[:1]}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	topics, err := slurpTopics(c.ListTopics(ctx, &ListOptions{NamePrefix: "a", PageSize: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, topic := range topics {
+		got = append(got, topic.ID())
+	}
+	if want := []string{"a1", "a2", "a3"}; !testutil.Equal(got, want) {
+		t.Errorf("topics: got %v, want %v", got, want)
+	}
+
+	subs, err := slurpSubs(c.ListSubscriptions(ctx, &ListOptions{Labels: map[string]string{"env": "b"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := getSubIDs(subs), []string{"sb1"}; !testutil.Equal(got, want) {
+		t.Errorf("subscriptions: got %v, want %v", got, want)
+	}
+	subs, err = slurpSubs(c.ListSubscriptions(ctx, &ListOptions{Labels: map[string]string{"env": "", "other": ""}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 0 {
+		t.Errorf("subscriptions with an absent label: got %v, want none", getSubIDs(subs))
+	}
+
+	// Listing resumes from a page token. Pages are filtered, so the second
+	// page, with only sb1, is empty.
+	it := c.ListSubscriptions(ctx, &ListOptions{NamePrefix: "sa"})
+	it.PageInfo().MaxSize = 3
+	var page []string
+	for {
+		sub, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		page = append(page, sub.ID())
+		if it.PageInfo().Remaining() == 0 {
+			break
+		}
+	}
+	if want := []string{"sa1", "sa2", "sa3"}; !testutil.Equal(page, want) {
+		t.Errorf("first page: got %v, want %v", page, want)
+	}
+	token := it.PageInfo().Token
+	if token == "" {
+		t.Fatal("got no next page token")
+	}
+	it = c.ListSubscriptions(ctx, &ListOptions{NamePrefix: "sa"})
+	it.PageInfo().Token = token
+	if subs, err := slurpSubs(it); err != nil || len(subs) != 0 {
+		t.Errorf("after the first page: got %v, %v; want no subscriptions", getSubIDs(subs), err)
+	}
+}
+
+func TestSubscriptionConfigs(t *testing.T) {
+	ctx := context.Background()
+	c, srv := newFake(t)
+	defer c.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, c, "t")
+	var subs []*Subscription
+	for i := 0; i < 5; i++ {
+		sub, err := c.CreateSubscription(ctx, fmt.Sprintf("s%d", i), SubscriptionConfig{Topic: topic})
+		if err != nil {
+			t.Fatal(err)
+		}
+		subs = append(subs, sub)
+	}
+	subs = append(subs, c.Subscription("missing"))
+	cfgs, err := c.SubscriptionConfigs(ctx, subs, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfgs) != len(subs) {
+		t.Fatalf("got %d configs, want %d", len(cfgs), len(subs))
+	}
+	for i, cfg := range cfgs[:5] {
+		if cfg == nil || cfg.ID() != subs[i].ID() || cfg.Topic.ID() != "t" {
+			t.Errorf("%d: got %+v", i, cfg)
+		}
+	}
+	if cfgs[5] != nil {
+		t.Errorf("missing subscription: got %+v, want nil", cfgs[5])
+	}
+
+	tcfgs, err := c.TopicConfigs(ctx, []*Topic{topic, c.Topic("missing")}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tcfgs) != 2 || tcfgs[0] == nil || tcfgs[0].ID() != "t" || tcfgs[1] != nil {
+		t.Errorf("got %+v, want the config of t and nil", tcfgs)
+	}
+}
+
+func TestForEachConcurrently(t *testing.T) {
+	var (
+		mu              sync.Mutex
+		running, maxRun int
+		calls           int
+	)
+	err := forEachConcurrently(context.Background(), 20, 3, func(ctx context.Context, i int) error {
+		mu.Lock()
+		running++
+		calls++
+		if running > maxRun {
+			maxRun = running
+		}
+		mu.Unlock()
+		defer func() { mu.Lock(); running--; mu.Unlock() }()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 20 || maxRun > 3 {
+		t.Errorf("got %d calls with at most %d running, want 20 with at most 3", calls, maxRun)
+	}
+
+	wantErr := errors.New("fail")
+	err = forEachConcurrently(context.Background(), 100, 1, func(ctx context.Context, i int) error {
+		if i == 3 {
+			return wantErr
+		}
+		return nil
+	})
+	if err != wantErr {
+		t.Errorf("got %v, want %v", err, wantErr)
+	}
+}
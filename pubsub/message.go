@@ -15,6 +15,8 @@
 package pubsub
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -49,8 +51,103 @@ func msgAckID(m *Message) string {
 	return ""
 }
 
-// The done method of the iterator that created a Message.
-type iterDoneFunc func(string, bool, time.Time)
+// AcknowledgeStatus is the status of an Ack or Nack with a result, see
+// AckWithResult.
+type AcknowledgeStatus int
+
+const (
+	// AcknowledgeStatusSuccess indicates that the Ack or Nack succeeded. If
+	// exactly-once delivery is enabled, an acknowledged message will not be
+	// redelivered.
+	AcknowledgeStatusSuccess AcknowledgeStatus = iota
+	// AcknowledgeStatusPermissionDenied indicates that the caller is not
+	// permitted to acknowledge messages of the subscription.
+	AcknowledgeStatusPermissionDenied
+	// AcknowledgeStatusFailedPrecondition indicates that the subscription is
+	// not in a state that allows acknowledgement, such as when it is detached.
+	AcknowledgeStatusFailedPrecondition
+	// AcknowledgeStatusInvalidAckID indicates that the ack ID of the message
+	// is no longer valid, usually because its ack deadline expired. The
+	// message will be redelivered.
+	AcknowledgeStatusInvalidAckID
+	// AcknowledgeStatusOther indicates any other failure.
+	AcknowledgeStatusOther
+)
+
+// An AckResult holds the result of an Ack or Nack with a result. Its status
+// is only meaningful for subscriptions with exactly-once delivery enabled; for
+// other subscriptions it is AcknowledgeStatusSuccess as soon as the Ack or
+// Nack is called, as for Message.Ack and Message.Nack.
+type AckResult struct {
+	ready  chan struct{}
+	status AcknowledgeStatus
+	err    error
+}
+
+func newAckResult() *AckResult {
+	return &AckResult{ready: make(chan struct{})}
+}
+
+// Ready returns a channel that is closed when the result is available.
+// When the Ready channel is closed, Get is guaranteed not to block.
+func (r *AckResult) Ready() <-chan struct{} { return r.ready }
+
+// Get returns the status and error of the Ack or Nack, blocking until the
+// result is available or ctx is done. If ctx is done first, Get returns
+// AcknowledgeStatusOther and the context's error.
+func (r *AckResult) Get(ctx context.Context) (AcknowledgeStatus, error) {
+	select {
+	case <-r.ready:
+		return r.status, r.err
+	case <-ctx.Done():
+		return AcknowledgeStatusOther, ctx.Err()
+	}
+}
+
+// set sets the result. It must be called only once.
+func (r *AckResult) set(status AcknowledgeStatus, err error) {
+	r.status = status
+	r.err = err
+	close(r.ready)
+}
+
+// AckWithResult acknowledges a message received in the callback passed to
+// Subscription.Receive, like m.Ack, and returns the result of the
+// acknowledgement.
+//
+// If the subscription has exactly-once delivery enabled, the result is
+// available once the service has confirmed or rejected the acknowledgement,
+// and a message whose acknowledgement succeeded will not be redelivered.
+// Transient failures are retried until the result is known. Otherwise, the
+// result is AcknowledgeStatusSuccess immediately.
+//
+// As with Ack, only the first call to Ack, Nack, AckWithResult or
+// NackWithResult has an effect. Later calls return that call's result if it
+// had one, and a failed result otherwise.
+func AckWithResult(m *Message) *AckResult {
+	return doneWithResult(m, true)
+}
+
+// NackWithResult negatively acknowledges a message received in the callback
+// passed to Subscription.Receive, like m.Nack, and returns the result of the
+// negative acknowledgement. See AckWithResult.
+func NackWithResult(m *Message) *AckResult {
+	return doneWithResult(m, false)
+}
+
+func doneWithResult(m *Message, ack bool) *AckResult {
+	ackh, ok := msgAckHandler(m)
+	if !ok {
+		r := newAckResult()
+		r.set(AcknowledgeStatusOther, errors.New("pubsub: message was not received from a subscription"))
+		return r
+	}
+	return ackh.doneWithResult(ack)
+}
+
+// The done method of the iterator that created a Message. The AckResult is nil
+// if the Ack or Nack has no result to report.
+type iterDoneFunc func(ackID string, ack bool, r *AckResult, receiveTime time.Time)
 
 func convertMessages(rms []*pb.ReceivedMessage, receiveTime time.Time, doneFunc iterDoneFunc) ([]*Message, error) {
 	msgs := make([]*Message, 0, len(rms))
@@ -102,6 +199,70 @@ type psAckHandler struct {
 
 	// The done method of the iterator that created this Message.
 	doneFunc iterDoneFunc
+
+	// exactlyOnceDelivery is whether the subscription had exactly-once
+	// delivery enabled when the message was received.
+	exactlyOnceDelivery bool
+
+	// ackResult is the result of a call to doneWithResult, if any.
+	ackResult *AckResult
+
+	// lease manages the ack deadline of the message; nil if the message was
+	// not received from a subscription.
+	lease leaseManager
+}
+
+// leaseManager is implemented by the receivers of messages, to manage their
+// ack deadlines.
+type leaseManager interface {
+	modifyAckDeadline(ctx context.Context, ackID string, d time.Duration) error
+	setMaxExtension(ackID string, expiry time.Time) error
+}
+
+var errLeaseExpired = errors.New("pubsub: the message is done or no longer extended")
+
+// ModifyAckDeadline sets the ack deadline of a message received with
+// Subscription.Receive or Subscription.PullN to d from now, for handlers that
+// manage the lease of their messages rather than relying on automatic
+// extension. Receive stops extending the ack deadline of the message; call
+// ModifyAckDeadline again before the deadline passes to keep the message, or
+// it is redelivered.
+//
+// d must be between 0 and 10 minutes. A deadline of 0 makes the message
+// available for redelivery immediately, as Nack does, but the message must
+// still be acked or nacked.
+func ModifyAckDeadline(ctx context.Context, m *Message, d time.Duration) error {
+	if d < 0 || d > maxAckDeadline {
+		return fmt.Errorf("pubsub: ModifyAckDeadline: deadline is %v, should be between 0 and %v", d, maxAckDeadline)
+	}
+	ackh, err := leasedMessage(m)
+	if err != nil {
+		return err
+	}
+	return ackh.lease.modifyAckDeadline(ctx, ackh.ackID, d)
+}
+
+// SetMaxExtension sets the maximum period, counted from when m was received,
+// for which Subscription.Receive extends the ack deadline of m automatically.
+// It overrides ReceiveSettings.MaxExtension for m, to give more or less time
+// to messages that are known to take long or short to process.
+func SetMaxExtension(m *Message, d time.Duration) error {
+	ackh, err := leasedMessage(m)
+	if err != nil {
+		return err
+	}
+	return ackh.lease.setMaxExtension(ackh.ackID, ackh.receiveTime.Add(d))
+}
+
+func leasedMessage(m *Message) (*psAckHandler, error) {
+	ackh, ok := msgAckHandler(m)
+	if !ok || ackh.lease == nil {
+		return nil, errors.New("pubsub: message was not received from a subscription")
+	}
+	if ackh.calledDone {
+		return nil, errors.New("pubsub: message was already acked or nacked")
+	}
+	return ackh, nil
 }
 
 func (ah *psAckHandler) OnAck() {
@@ -118,6 +279,31 @@ func (ah *psAckHandler) done(ack bool) {
 	}
 	ah.calledDone = true
 	if ah.doneFunc != nil {
-		ah.doneFunc(ah.ackID, ack, ah.receiveTime)
+		ah.doneFunc(ah.ackID, ack, nil, ah.receiveTime)
+	}
+}
+
+func (ah *psAckHandler) doneWithResult(ack bool) *AckResult {
+	if ah.calledDone {
+		if ah.ackResult != nil {
+			return ah.ackResult
+		}
+		r := newAckResult()
+		r.set(AcknowledgeStatusOther, errors.New("pubsub: message was already acked or nacked"))
+		return r
+	}
+	ah.calledDone = true
+	r := newAckResult()
+	ah.ackResult = r
+	switch {
+	case ah.doneFunc == nil:
+		r.set(AcknowledgeStatusOther, errors.New("pubsub: message was not received from a subscription"))
+	case !ah.exactlyOnceDelivery:
+		r.set(AcknowledgeStatusSuccess, nil)
+		ah.doneFunc(ah.ackID, ack, nil, ah.receiveTime)
+	default:
+		// The iterator sets the result once the service has replied.
+		ah.doneFunc(ah.ackID, ack, r, ah.receiveTime)
 	}
+	return r
 }
@@ -154,7 +154,15 @@ func (s *mockServer) StreamingPull(stream pb.Subscriber_StreamingPullServer) err
 			return err
 		default:
 		}
-		res := &pb.StreamingPullResponse{ReceivedMessages: pr.msgs}
+		s.mu.Lock()
+		eod := s.sub.EnableExactlyOnceDelivery
+		s.mu.Unlock()
+		res := &pb.StreamingPullResponse{
+			ReceivedMessages: pr.msgs,
+			SubscriptionProperties: &pb.StreamingPullResponse_SubscriptionProperties{
+				ExactlyOnceDeliveryEnabled: eod,
+			},
+		}
 		if err := stream.Send(res); err != nil {
 			return err
 		}
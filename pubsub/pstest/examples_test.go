@@ -16,6 +16,7 @@ package pstest_test
 
 import (
 	"context"
+	"testing"
 
 	"cloud.google.com/go/pubsub"
 	"cloud.google.com/go/pubsub/pstest"
@@ -62,3 +63,25 @@ func ExampleNewServerWithPort() {
 	defer client.Close()
 	_ = client // TODO: Use the client.
 }
+
+func ExampleNewTestServer() {
+	var t *testing.T // The *testing.T of a test.
+	ctx := context.Background()
+	srv := pstest.NewTestServer(t)
+	// The client connects to the server, and creates topics and
+	// subscriptions as they are used.
+	client, err := pubsub.NewClientWithConfig(ctx, "project", &pubsub.ClientConfig{
+		Emulator: &pubsub.EmulatorConfig{
+			AutoCreate:         true,
+			SubscriptionTopics: map[string]string{"sub": "topic"},
+		},
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	defer client.Close()
+	_ = client // TODO: Use the client.
+
+	// Delete the topics, subscriptions and messages before the next test.
+	srv.Reset()
+}
@@ -34,9 +34,14 @@ import (
 	"time"
 
 	"cloud.google.com/go/internal/testutil"
+	"cloud.google.com/go/pubsub/internal/wirefield"
+	iampb "google.golang.org/genproto/googleapis/iam/v1"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 	durpb "google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -92,6 +97,7 @@ type Server struct {
 type GServer struct {
 	pb.PublisherServer
 	pb.SubscriberServer
+	iampb.IAMPolicyServer
 
 	mu             sync.Mutex
 	topics         map[string]*topic
@@ -103,7 +109,10 @@ type GServer struct {
 	streamTimeout  time.Duration
 	timeNowFunc    func() time.Time
 	reactorOptions ReactorOptions
-	schemas        map[string]*pb.Schema
+	schemas        map[string][]*pb.Schema // revisions of schemas, oldest first
+	nextRevision   int
+	policies       map[string]*iampb.Policy // IAM policies of topics and subscriptions
+	nextEtag       int
 
 	// PublishResponses is a channel of responses to use for Publish.
 	publishResponses chan *publishResponse
@@ -120,7 +129,10 @@ func NewServer(opts ...ServerReactorOption) *Server {
 
 // NewServerWithPort creates a new fake server running in the current process at the specified port.
 func NewServerWithPort(port int, opts ...ServerReactorOption) *Server {
-	srv, err := testutil.NewServerWithPort(port)
+	var s *Server
+	srv, err := testutil.NewServerWithPort(port, grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		return s.GServer.handleUnknownMethod(srv, stream)
+	}))
 	if err != nil {
 		panic(fmt.Sprintf("pstest.NewServerWithPort: %v", err))
 	}
@@ -128,7 +140,7 @@ func NewServerWithPort(port int, opts ...ServerReactorOption) *Server {
 	for _, opt := range opts {
 		reactorOptions[opt.FuncName] = append(reactorOptions[opt.FuncName], opt.Reactor)
 	}
-	s := &Server{
+	s = &Server{
 		srv:  srv,
 		Addr: srv.Addr,
 		GServer: GServer{
@@ -139,12 +151,14 @@ func NewServerWithPort(port int, opts ...ServerReactorOption) *Server {
 			reactorOptions:      reactorOptions,
 			publishResponses:    make(chan *publishResponse, 100),
 			autoPublishResponse: true,
-			schemas:             map[string]*pb.Schema{},
+			schemas:             map[string][]*pb.Schema{},
+			policies:            map[string]*iampb.Policy{},
 		},
 	}
 	pb.RegisterPublisherServer(srv.Gsrv, &s.GServer)
 	pb.RegisterSubscriberServer(srv.Gsrv, &s.GServer)
 	pb.RegisterSchemaServiceServer(srv.Gsrv, &s.GServer)
+	iampb.RegisterIAMPolicyServer(srv.Gsrv, &s.GServer)
 	srv.Start()
 	return s
 }
@@ -297,6 +311,22 @@ func (s *Server) ClearMessages() {
 	s.GServer.mu.Unlock()
 }
 
+// Reset deletes all topics, subscriptions, schemas, IAM policies and messages,
+// to isolate the tests that share a server. Open streaming pulls are closed.
+func (s *Server) Reset() {
+	s.GServer.mu.Lock()
+	defer s.GServer.mu.Unlock()
+	for _, sub := range s.GServer.subs {
+		sub.stop()
+	}
+	s.GServer.topics = map[string]*topic{}
+	s.GServer.subs = map[string]*subscription{}
+	s.GServer.schemas = map[string][]*pb.Schema{}
+	s.GServer.policies = map[string]*iampb.Policy{}
+	s.GServer.msgs = nil
+	s.GServer.msgsByID = map[string]*Message{}
+}
+
 // Close shuts down the server and releases all resources.
 func (s *Server) Close() error {
 	s.srv.Close()
@@ -356,6 +386,10 @@ func (s *GServer) UpdateTopic(_ context.Context, req *pb.UpdateTopicRequest) (*p
 			t.proto.Labels = req.Topic.Labels
 		case "message_storage_policy":
 			t.proto.MessageStoragePolicy = req.Topic.MessageStoragePolicy
+		// The generated protos do not have the ingestion settings yet, so
+		// they are copied as unknown fields, by field number.
+		case "ingestion_data_source_settings":
+			wirefield.Copy(t.proto.ProtoReflect(), req.Topic.ProtoReflect(), 10)
 		default:
 			return nil, status.Errorf(codes.InvalidArgument, "unknown field name %q", path)
 		}
@@ -436,6 +470,7 @@ func (s *GServer) DeleteTopic(_ context.Context, req *pb.DeleteTopicRequest) (*e
 	}
 	t.stop()
 	delete(s.topics, req.Topic)
+	delete(s.policies, req.Topic)
 	return &emptypb.Empty{}, nil
 }
 
@@ -605,6 +640,17 @@ func (s *GServer) UpdateSubscription(_ context.Context, req *pb.UpdateSubscripti
 		case "filter":
 			sub.proto.Filter = req.Subscription.Filter
 
+		case "enable_exactly_once_delivery":
+			sub.proto.EnableExactlyOnceDelivery = req.Subscription.EnableExactlyOnceDelivery
+
+		// The generated protos do not have the export configurations yet, so
+		// they are copied as unknown fields, by field number.
+		case "bigquery_config":
+			wirefield.Copy(sub.proto.ProtoReflect(), req.Subscription.ProtoReflect(), 18)
+
+		case "cloud_storage_config":
+			wirefield.Copy(sub.proto.ProtoReflect(), req.Subscription.ProtoReflect(), 22)
+
 		default:
 			return nil, status.Errorf(codes.InvalidArgument, "unknown field name %q", path)
 		}
@@ -652,6 +698,7 @@ func (s *GServer) DeleteSubscription(_ context.Context, req *pb.DeleteSubscripti
 	}
 	sub.stop()
 	delete(s.subs, req.Subscription)
+	delete(s.policies, req.Subscription)
 	sub.topic.deleteSub(sub)
 	return &emptypb.Empty{}, nil
 }
@@ -950,8 +997,13 @@ func (s *GServer) Seek(ctx context.Context, req *pb.SeekRequest) (*pb.SeekRespon
 			publishTime: m.PublishTime,
 			proto: &pb.ReceivedMessage{
 				AckId: m.ID,
-				// This was not preserved!
-				//Message: pm,
+				Message: &pb.PubsubMessage{
+					Data:        m.Data,
+					Attributes:  m.Attributes,
+					MessageId:   m.ID,
+					PublishTime: timestamppb.New(m.PublishTime),
+					OrderingKey: m.OrderingKey,
+				},
 			},
 			deliveries:  &m.deliveries,
 			acks:        &m.acks,
@@ -1212,7 +1264,15 @@ func (st *stream) sendLoop() error {
 		case <-st.done:
 			return nil
 		case rm := <-st.msgc:
-			res := &pb.StreamingPullResponse{ReceivedMessages: []*pb.ReceivedMessage{rm}}
+			st.sub.mu.Lock()
+			eod := st.sub.proto.EnableExactlyOnceDelivery
+			st.sub.mu.Unlock()
+			res := &pb.StreamingPullResponse{
+				ReceivedMessages: []*pb.ReceivedMessage{rm},
+				SubscriptionProperties: &pb.StreamingPullResponse_SubscriptionProperties{
+					ExactlyOnceDeliveryEnabled: eod,
+				},
+			}
 			if err := st.gstream.Send(res); err != nil {
 				return err
 			}
@@ -1321,14 +1381,56 @@ func (s *GServer) CreateSchema(_ context.Context, req *pb.CreateSchemaRequest) (
 	}
 
 	name := fmt.Sprintf("%s/schemas/%s", req.Parent, req.SchemaId)
-	sc := &pb.Schema{
+	sc := s.newSchemaRevision(name, req.Schema)
+	s.schemas[name] = []*pb.Schema{sc}
+	return sc, nil
+}
+
+// newSchemaRevision returns a new revision of the named schema, with the type
+// and definition of sc.
+// Must be called with the lock held.
+func (s *GServer) newSchemaRevision(name string, sc *pb.Schema) *pb.Schema {
+	s.nextRevision++
+	rev := &pb.Schema{
 		Name:       name,
-		Type:       req.Schema.Type,
-		Definition: req.Schema.Definition,
+		Type:       sc.Type,
+		Definition: sc.Definition,
 	}
-	s.schemas[name] = sc
+	created, _ := proto.Marshal(timestamppb.New(s.timeNowFunc()))
+	m := rev.ProtoReflect()
+	wirefield.SetBytes(m, schemaRevisionIDField, []byte(fmt.Sprintf("%08x", s.nextRevision)))
+	wirefield.SetBytes(m, schemaRevisionCreateTimeField, created)
+	return rev
+}
 
-	return sc, nil
+// revisionID returns the revision ID of a schema revision.
+func revisionID(sc *pb.Schema) string {
+	b, _ := wirefield.Bytes(sc.ProtoReflect(), schemaRevisionIDField)
+	return string(b)
+}
+
+// schemaRevision returns the revisions of the schema named by name, which
+// may end in "@" followed by a revision ID, and the index of the revision
+// it refers to: the latest one if there is no revision ID.
+// Must be called with the lock held.
+func (s *GServer) schemaRevision(name string) ([]*pb.Schema, int, error) {
+	name, revID := name, ""
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		name, revID = name[:i], name[i+1:]
+	}
+	revs, ok := s.schemas[name]
+	if !ok {
+		return nil, 0, status.Errorf(codes.NotFound, "schema(%q) not found", name)
+	}
+	if revID == "" {
+		return revs, len(revs) - 1, nil
+	}
+	for i, sc := range revs {
+		if revisionID(sc) == revID {
+			return revs, i, nil
+		}
+	}
+	return nil, 0, status.Errorf(codes.NotFound, "schema(%q) revision %q not found", name, revID)
 }
 
 func (s *GServer) GetSchema(_ context.Context, req *pb.GetSchemaRequest) (*pb.Schema, error) {
@@ -1340,11 +1442,11 @@ func (s *GServer) GetSchema(_ context.Context, req *pb.GetSchemaRequest) (*pb.Sc
 		return ret.(*pb.Schema), err
 	}
 
-	sc, ok := s.schemas[req.Name]
-	if !ok {
-		return nil, status.Errorf(codes.NotFound, "schema(%q) not found", req.Name)
+	revs, i, err := s.schemaRevision(req.Name)
+	if err != nil {
+		return nil, err
 	}
-	return sc, nil
+	return revs[i], nil
 }
 
 func (s *GServer) ListSchemas(_ context.Context, req *pb.ListSchemasRequest) (*pb.ListSchemasResponse, error) {
@@ -1355,14 +1457,148 @@ func (s *GServer) ListSchemas(_ context.Context, req *pb.ListSchemasRequest) (*p
 		return ret.(*pb.ListSchemasResponse), err
 	}
 	ss := make([]*pb.Schema, 0)
-	for _, sc := range s.schemas {
-		ss = append(ss, sc)
+	for _, revs := range s.schemas {
+		ss = append(ss, revs[len(revs)-1])
 	}
 	return &pb.ListSchemasResponse{
 		Schemas: ss,
 	}, nil
 }
 
+// The generated protos do not have the schema revision methods of the
+// SchemaService, so the server handles them as unknown methods. Their
+// requests are decoded into empty messages, whose fields are read by number.
+const (
+	schemaServiceMethodPrefix = "/google.pubsub.v1.SchemaService/"
+
+	schemaRevisionIDField         = 4
+	schemaRevisionCreateTimeField = 6
+)
+
+// handleUnknownMethod serves the schema revision methods.
+func (s *GServer) handleUnknownMethod(_ interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	var handle func(*emptypb.Empty) (proto.Message, error)
+	switch strings.TrimPrefix(method, schemaServiceMethodPrefix) {
+	case "ListSchemaRevisions":
+		handle = s.listSchemaRevisions
+	case "CommitSchema":
+		handle = s.commitSchema
+	case "RollbackSchema":
+		handle = s.rollbackSchema
+	case "DeleteSchemaRevision":
+		handle = s.deleteSchemaRevision
+	default:
+		return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	req := &emptypb.Empty{}
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	resp, err := handle(req)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(resp)
+}
+
+// stringField returns the string field numbered num of an undecoded request.
+func stringField(req *emptypb.Empty, num protowire.Number) string {
+	b, _ := wirefield.Bytes(req.ProtoReflect(), num)
+	return string(b)
+}
+
+// listSchemaRevisions lists the revisions of a schema, newest first. The
+// response has the fields of a ListSchemasResponse.
+func (s *GServer) listSchemaRevisions(req *emptypb.Empty) (proto.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handled, ret, err := s.runReactor(req, "ListSchemaRevisions", &pb.ListSchemasResponse{}); handled || err != nil {
+		return ret.(*pb.ListSchemasResponse), err
+	}
+	name := stringField(req, 1)
+	revs, ok := s.schemas[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "schema(%q) not found", name)
+	}
+	ss := make([]*pb.Schema, 0, len(revs))
+	for i := len(revs) - 1; i >= 0; i-- {
+		ss = append(ss, revs[i])
+	}
+	return &pb.ListSchemasResponse{
+		Schemas: ss,
+	}, nil
+}
+
+func (s *GServer) commitSchema(req *emptypb.Empty) (proto.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handled, ret, err := s.runReactor(req, "CommitSchema", &pb.Schema{}); handled || err != nil {
+		return ret.(*pb.Schema), err
+	}
+	name := stringField(req, 1)
+	sc := &pb.Schema{}
+	if b, ok := wirefield.Bytes(req.ProtoReflect(), 2); ok {
+		if err := proto.Unmarshal(b, sc); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid schema: %v", err)
+		}
+	}
+	revs, ok := s.schemas[name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "schema(%q) not found", name)
+	}
+	if sc.Definition == "" {
+		return nil, status.Error(codes.InvalidArgument, "schema definition cannot be empty")
+	}
+	sc = s.newSchemaRevision(name, sc)
+	s.schemas[name] = append(revs, sc)
+	return sc, nil
+}
+
+// rollbackSchema creates a new revision of a schema that is a copy of a
+// previous one.
+func (s *GServer) rollbackSchema(req *emptypb.Empty) (proto.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handled, ret, err := s.runReactor(req, "RollbackSchema", &pb.Schema{}); handled || err != nil {
+		return ret.(*pb.Schema), err
+	}
+	name := stringField(req, 1)
+	revs, i, err := s.schemaRevision(name + "@" + stringField(req, 2))
+	if err != nil {
+		return nil, err
+	}
+	sc := s.newSchemaRevision(name, revs[i])
+	s.schemas[name] = append(revs, sc)
+	return sc, nil
+}
+
+func (s *GServer) deleteSchemaRevision(req *emptypb.Empty) (proto.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handled, ret, err := s.runReactor(req, "DeleteSchemaRevision", &pb.Schema{}); handled || err != nil {
+		return ret.(*pb.Schema), err
+	}
+	name := stringField(req, 1)
+	if !strings.Contains(name, "@") {
+		return nil, status.Errorf(codes.InvalidArgument, "schema revision name %q has no revision ID", name)
+	}
+	revs, i, err := s.schemaRevision(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot delete the last revision of schema %q", revs[i].Name)
+	}
+	sc := revs[i]
+	s.schemas[sc.Name] = append(revs[:i:i], revs[i+1:]...)
+	return sc, nil
+}
+
 func (s *GServer) DeleteSchema(_ context.Context, req *pb.DeleteSchemaRequest) (*emptypb.Empty, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -1371,8 +1607,7 @@ func (s *GServer) DeleteSchema(_ context.Context, req *pb.DeleteSchemaRequest) (
 		return ret.(*emptypb.Empty), err
 	}
 
-	schema := s.schemas[req.Name]
-	if schema == nil {
+	if _, ok := s.schemas[req.Name]; !ok {
 		return nil, status.Errorf(codes.NotFound, "schema %q", req.Name)
 	}
 
@@ -1407,11 +1642,11 @@ func (s *GServer) ValidateMessage(_ context.Context, req *pb.ValidateMessageRequ
 
 	spec := req.GetSchemaSpec()
 	if valReq, ok := spec.(*pb.ValidateMessageRequest_Name); ok {
-		sc, ok := s.schemas[valReq.Name]
-		if !ok {
-			return nil, status.Errorf(codes.NotFound, "schema(%q) not found", valReq.Name)
+		revs, i, err := s.schemaRevision(valReq.Name)
+		if err != nil {
+			return nil, err
 		}
-		if sc.Definition == "" {
+		if revs[i].Definition == "" {
 			return nil, status.Error(codes.InvalidArgument, "schema definition cannot be empty")
 		}
 	}
@@ -1423,3 +1658,58 @@ func (s *GServer) ValidateMessage(_ context.Context, req *pb.ValidateMessageRequ
 
 	return &pb.ValidateMessageResponse{}, nil
 }
+
+// iamResourceExists reports whether resource is a topic or subscription of the
+// server. It must be called with s.mu held.
+func (s *GServer) iamResourceExists(resource string) bool {
+	return s.topics[resource] != nil || s.subs[resource] != nil
+}
+
+// GetIamPolicy returns the IAM policy of a topic or subscription. It is empty
+// unless it has been set.
+func (s *GServer) GetIamPolicy(_ context.Context, req *iampb.GetIamPolicyRequest) (*iampb.Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handled, ret, err := s.runReactor(req, "GetIamPolicy", &iampb.Policy{}); handled || err != nil {
+		return ret.(*iampb.Policy), err
+	}
+
+	if !s.iamResourceExists(req.Resource) {
+		return nil, status.Errorf(codes.NotFound, "resource %q", req.Resource)
+	}
+	if p := s.policies[req.Resource]; p != nil {
+		return p, nil
+	}
+	return &iampb.Policy{}, nil
+}
+
+// SetIamPolicy sets the IAM policy of a topic or subscription. Like the
+// service, it fails with Aborted if the etag of the policy is set and does not
+// match the etag of the current policy.
+func (s *GServer) SetIamPolicy(_ context.Context, req *iampb.SetIamPolicyRequest) (*iampb.Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if handled, ret, err := s.runReactor(req, "SetIamPolicy", &iampb.Policy{}); handled || err != nil {
+		return ret.(*iampb.Policy), err
+	}
+
+	if !s.iamResourceExists(req.Resource) {
+		return nil, status.Errorf(codes.NotFound, "resource %q", req.Resource)
+	}
+	if req.Policy == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing policy")
+	}
+	if cur := s.policies[req.Resource]; len(req.Policy.Etag) > 0 && (cur == nil || string(cur.Etag) != string(req.Policy.Etag)) {
+		return nil, status.Errorf(codes.Aborted, "etag of policy for %q does not match", req.Resource)
+	}
+	s.nextEtag++
+	p := &iampb.Policy{
+		Version:  req.Policy.Version,
+		Bindings: req.Policy.Bindings,
+		Etag:     []byte(fmt.Sprint(s.nextEtag)),
+	}
+	s.policies[req.Resource] = p
+	return p, nil
+}
@@ -21,6 +21,7 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"os"
 	"reflect"
 	"strings"
 	"sync"
@@ -490,6 +491,47 @@ func TestClearMessages(t *testing.T) {
 	}
 }
 
+func TestReset(t *testing.T) {
+	ctx := context.Background()
+	pclient, sclient, server, cleanup := newFake(ctx, t)
+	defer cleanup()
+
+	top := mustCreateTopic(ctx, t, pclient, &pb.Topic{Name: "projects/P/topics/T"})
+	sub := mustCreateSubscription(ctx, t, sclient, &pb.Subscription{
+		Name:               "projects/P/subscriptions/S",
+		Topic:              top.Name,
+		AckDeadlineSeconds: 10,
+	})
+	server.Publish(top.Name, []byte("hello"), nil)
+	server.Reset()
+
+	if msgs := server.Messages(); len(msgs) != 0 {
+		t.Errorf("got %d messages, want 0", len(msgs))
+	}
+	if _, err := pclient.GetTopic(ctx, &pb.GetTopicRequest{Topic: top.Name}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetTopic: got %v, want NotFound", err)
+	}
+	if _, err := sclient.GetSubscription(ctx, &pb.GetSubscriptionRequest{Subscription: sub.Name}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetSubscription: got %v, want NotFound", err)
+	}
+	// The server can be used again.
+	mustCreateTopic(ctx, t, pclient, top)
+}
+
+func TestNewTestServer(t *testing.T) {
+	var addr string
+	t.Run("test", func(t *testing.T) {
+		srv := NewTestServer(t)
+		addr = srv.Addr
+		if got := os.Getenv("PUBSUB_EMULATOR_HOST"); got != addr {
+			t.Errorf("PUBSUB_EMULATOR_HOST: got %q, want %q", got, addr)
+		}
+	})
+	if got := os.Getenv("PUBSUB_EMULATOR_HOST"); got == addr {
+		t.Errorf("PUBSUB_EMULATOR_HOST: got %q after the test, want it restored", got)
+	}
+}
+
 // Note: this sets the fake's "now" time, so it is sensitive to concurrent changes to "now".
 func publish(t *testing.T, pclient pb.PublisherClient, topic *pb.Topic, messages []*pb.PubsubMessage) map[string]*pb.PubsubMessage {
 	pubTime := time.Now()
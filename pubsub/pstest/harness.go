@@ -0,0 +1,47 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pstest
+
+import (
+	"os"
+	"testing"
+)
+
+// NewTestServer starts a server for the test or benchmark tb, and closes it
+// when tb ends. Until then, it sets the PUBSUB_EMULATOR_HOST environment
+// variable to the address of the server, so that clients created with
+// pubsub.NewClient use it like the Pub/Sub emulator. Because the environment
+// is shared by the process, tests that use NewTestServer must not run in
+// parallel.
+//
+// To isolate tests that share a server, call Server.Reset between them.
+func NewTestServer(tb testing.TB, opts ...ServerReactorOption) *Server {
+	tb.Helper()
+	srv := NewServer(opts...)
+	old, hadOld := os.LookupEnv("PUBSUB_EMULATOR_HOST")
+	if err := os.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr); err != nil {
+		srv.Close()
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		if hadOld {
+			os.Setenv("PUBSUB_EMULATOR_HOST", old)
+		} else {
+			os.Unsetenv("PUBSUB_EMULATOR_HOST")
+		}
+		srv.Close()
+	})
+	return srv
+}
@@ -26,6 +26,7 @@ import (
 	"cloud.google.com/go/internal/version"
 	vkit "cloud.google.com/go/pubsub/apiv1"
 	gax "github.com/googleapis/gax-go/v2"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -51,12 +52,48 @@ type Client struct {
 	projectID string
 	pubc      *vkit.PublisherClient
 	subc      *vkit.SubscriberClient
+
+	tracer              trace.Tracer // nil unless tracing is enabled
+	publishInterceptors []PublishInterceptor
+	receiveInterceptors []ReceiveInterceptor
+	autoCreate          *autoCreator
 }
 
 // ClientConfig has configurations for the client.
 type ClientConfig struct {
 	PublisherCallOptions  *vkit.PublisherCallOptions
 	SubscriberCallOptions *vkit.SubscriberCallOptions
+
+	// OpenTelemetryTracerProvider enables the built-in OpenTelemetry tracing
+	// of publishing and receiving messages, and is used to create the spans.
+	// Publish starts a span for each message, with child spans for waiting
+	// for flow control, and adds its trace context to the attributes of the
+	// message, under the "googclient_traceparent" key. Each bundle of messages
+	// is sent in a span linked to the spans of its messages. A subscriber
+	// that also enables tracing receives each message in a span that is a
+	// child of the span of publishing it, until the message is acked or
+	// nacked.
+	//
+	// Tracing is disabled if it is nil. To export the spans to Cloud Trace,
+	// use a TracerProvider of the OpenTelemetry SDK with a Cloud Trace
+	// exporter.
+	OpenTelemetryTracerProvider trace.TracerProvider
+
+	// PublishInterceptors are called, in order, with each message published
+	// with the client, for concerns such as encryption, compression or
+	// metrics that apply to all messages. The first interceptor is the
+	// outermost: it sees the message as passed to Topic.Publish.
+	PublishInterceptors []PublishInterceptor
+
+	// ReceiveInterceptors are called, in order, with each message received
+	// with the client, before it is handed to the application. The first
+	// interceptor is the outermost: it sees the message as received from the
+	// service.
+	ReceiveInterceptors []ReceiveInterceptor
+
+	// Emulator configures conveniences for using the Pub/Sub emulator. It is
+	// ignored unless the PUBSUB_EMULATOR_HOST environment variable is set.
+	Emulator *EmulatorConfig
 }
 
 // mergePublisherCallOptions merges two PublisherCallOptions into one and the first argument has
@@ -125,7 +162,8 @@ func NewClientWithConfig(ctx context.Context, projectID string, config *ClientCo
 	var o []option.ClientOption
 	// Environment variables for gcloud emulator:
 	// https://cloud.google.com/sdk/gcloud/reference/beta/emulators/pubsub/
-	if addr := os.Getenv("PUBSUB_EMULATOR_HOST"); addr != "" {
+	addr := os.Getenv("PUBSUB_EMULATOR_HOST")
+	if addr != "" {
 		conn, err := grpc.Dial(addr, grpc.WithInsecure())
 		if err != nil {
 			return nil, fmt.Errorf("grpc.Dial: %v", err)
@@ -159,11 +197,20 @@ func NewClientWithConfig(ctx context.Context, projectID string, config *ClientCo
 		subc.CallOptions = mergeSubscriberCallOptions(subc.CallOptions, config.SubscriberCallOptions)
 	}
 	pubc.SetGoogleClientInfo("gccl", version.Repo)
-	return &Client{
+	c = &Client{
 		projectID: projectID,
 		pubc:      pubc,
 		subc:      subc,
-	}, nil
+	}
+	if config != nil {
+		c.tracer = newTracer(config.OpenTelemetryTracerProvider)
+		c.publishInterceptors = config.PublishInterceptors
+		c.receiveInterceptors = config.ReceiveInterceptors
+		if addr != "" {
+			c.autoCreate = newAutoCreator(c, config.Emulator)
+		}
+	}
+	return c, nil
 }
 
 // Close releases any resources held by the client,
@@ -18,6 +18,7 @@ import (
 	"context"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	gax "github.com/googleapis/gax-go/v2"
@@ -35,6 +36,8 @@ type pullStream struct {
 	mu  sync.Mutex
 	spc *pb.Subscriber_StreamingPullClient
 	err error // permanent error
+
+	opened int32 // 1 while a stream is open; atomic
 }
 
 // for testing
@@ -101,9 +104,18 @@ func (s *pullStream) get(spc *pb.Subscriber_StreamingPullClient) (*pb.Subscriber
 	// anything done anyway.
 	s.spc = new(pb.Subscriber_StreamingPullClient)
 	*s.spc, s.err = s.openWithRetry() // Any error from openWithRetry is permanent.
+	if s.err == nil {
+		atomic.StoreInt32(&s.opened, 1)
+	}
 	return s.spc, s.err
 }
 
+// isOpen reports whether the stream is open: it was opened and has not failed
+// or been closed.
+func (s *pullStream) isOpen() bool {
+	return atomic.LoadInt32(&s.opened) == 1
+}
+
 func (s *pullStream) openWithRetry() (pb.Subscriber_StreamingPullClient, error) {
 	r := defaultRetryer{}
 	for {
@@ -156,6 +168,7 @@ func (s *pullStream) call(f func(pb.Subscriber_StreamingPullClient) error, opts
 			s.mu.Lock()
 			s.err = err
 			s.mu.Unlock()
+			atomic.StoreInt32(&s.opened, 0)
 		}
 		return err
 	}
@@ -195,5 +208,6 @@ func (s *pullStream) CloseSend() error {
 	s.mu.Lock()
 	s.err = io.EOF // should not be retried
 	s.mu.Unlock()
+	atomic.StoreInt32(&s.opened, 0)
 	return err
 }
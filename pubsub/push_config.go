@@ -0,0 +1,170 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/pubsub/internal/wirefield"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	fmpb "google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Wrapper defines the format of the messages that a push subscription
+// delivers: *PubsubWrapper or *NoWrapper.
+type Wrapper interface {
+	isWrapper()
+}
+
+// PubsubWrapper delivers each message as the JSON representation of a
+// PubsubMessage, in the body of the push request. It is the default.
+type PubsubWrapper struct{}
+
+// NoWrapper delivers the data of each message as the raw body of the push
+// request, for endpoints that do not understand the Pub/Sub wrapper.
+type NoWrapper struct {
+	// WriteMetadata, if true, delivers the attributes of messages, and their
+	// metadata such as their IDs, as the headers of push requests.
+	WriteMetadata bool
+}
+
+func (*PubsubWrapper) isWrapper() {}
+func (*NoWrapper) isWrapper()     {}
+
+// The numbers of fields that the generated code does not have yet.
+const (
+	pushConfigPubsubWrapperField = 4
+	pushConfigNoWrapperField     = 5
+
+	noWrapperWriteMetadataField = 1
+)
+
+// setWrapper sets the wrapper of ppc.
+func setWrapper(ppc *pb.PushConfig, w Wrapper) {
+	m := ppc.ProtoReflect()
+	wirefield.Clear(m, pushConfigPubsubWrapperField)
+	wirefield.Clear(m, pushConfigNoWrapperField)
+	switch w := w.(type) {
+	case *PubsubWrapper:
+		wirefield.SetBytes(m, pushConfigPubsubWrapperField, nil)
+	case *NoWrapper:
+		wirefield.SetBytes(m, pushConfigNoWrapperField, appendBool(nil, noWrapperWriteMetadataField, w.WriteMetadata))
+	}
+}
+
+// wrapper returns the wrapper of ppc, or nil if it has none.
+func wrapper(ppc *pb.PushConfig) Wrapper {
+	m := ppc.ProtoReflect()
+	if b, ok := wirefield.Bytes(m, pushConfigNoWrapperField); ok {
+		w := &NoWrapper{}
+		wirefield.Range(b, func(num protowire.Number, typ protowire.Type, v []byte) bool {
+			if num == noWrapperWriteMetadataField && typ == protowire.VarintType {
+				w.WriteMetadata = varint(v) != 0
+			}
+			return true
+		})
+		return w
+	}
+	if _, ok := wirefield.Bytes(m, pushConfigPubsubWrapperField); ok {
+		return &PubsubWrapper{}
+	}
+	return nil
+}
+
+// OIDCPushConfig returns a configuration that pushes messages to endpoint,
+// authenticated with OpenID Connect tokens of the service account with the
+// given email, for audience. If audience is empty, the tokens are for the
+// endpoint URL. It returns an error if the configuration is invalid.
+func OIDCPushConfig(endpoint, serviceAccountEmail, audience string) (*PushConfig, error) {
+	pc := &PushConfig{
+		Endpoint: endpoint,
+		AuthenticationMethod: &OIDCToken{
+			ServiceAccountEmail: serviceAccountEmail,
+			Audience:            audience,
+		},
+	}
+	if err := pc.Validate(); err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
+
+// Validate reports whether pc is a valid push configuration: its endpoint is
+// an absolute HTTPS URL and its OIDC token, if any, has a service account. A
+// configuration without an endpoint is valid only if it has no other field
+// set, as it configures pull delivery.
+func (pc *PushConfig) Validate() error {
+	if pc.Endpoint == "" {
+		if len(pc.Attributes) != 0 || pc.AuthenticationMethod != nil || pc.Wrapper != nil {
+			return errors.New("pubsub: push config without an endpoint has other fields set")
+		}
+		return nil
+	}
+	u, err := url.Parse(pc.Endpoint)
+	if err != nil {
+		return fmt.Errorf("pubsub: push endpoint: %v", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("pubsub: push endpoint %q is not an absolute HTTPS URL", pc.Endpoint)
+	}
+	if tok, ok := pc.AuthenticationMethod.(*OIDCToken); ok && tok != nil {
+		if !strings.Contains(tok.ServiceAccountEmail, "@") {
+			return fmt.Errorf("pubsub: OIDC token service account %q is not an email address", tok.ServiceAccountEmail)
+		}
+	}
+	return nil
+}
+
+// deliveryPaths are the update mask paths of the fields that configure how a
+// subscription delivers messages. A subscription can have at most one of
+// them set.
+var deliveryPaths = []string{"push_config", "bigquery_config", "cloud_storage_config"}
+
+// SwitchToPush makes s a push subscription with the configuration pc, which
+// must be valid. It replaces all of the delivery configuration of s, so it
+// also stops writing messages to BigQuery or Cloud Storage. It returns the
+// new SubscriptionConfig.
+func (s *Subscription) SwitchToPush(ctx context.Context, pc PushConfig) (SubscriptionConfig, error) {
+	if pc.Endpoint == "" {
+		return SubscriptionConfig{}, errors.New("pubsub: SwitchToPush: push config has no endpoint")
+	}
+	if err := pc.Validate(); err != nil {
+		return SubscriptionConfig{}, err
+	}
+	return s.updateDelivery(ctx, pc.toProto())
+}
+
+// SwitchToPull makes s a pull subscription. It replaces all of the delivery
+// configuration of s, so it also stops pushing messages or writing them to
+// BigQuery or Cloud Storage. It returns the new SubscriptionConfig.
+func (s *Subscription) SwitchToPull(ctx context.Context) (SubscriptionConfig, error) {
+	return s.updateDelivery(ctx, &pb.PushConfig{})
+}
+
+func (s *Subscription) updateDelivery(ctx context.Context, ppc *pb.PushConfig) (SubscriptionConfig, error) {
+	psub, err := s.c.subc.UpdateSubscription(ctx, &pb.UpdateSubscriptionRequest{
+		Subscription: &pb.Subscription{Name: s.name, PushConfig: ppc},
+		UpdateMask:   &fmpb.FieldMask{Paths: deliveryPaths},
+	})
+	if err != nil {
+		return SubscriptionConfig{}, err
+	}
+	return protoToSubscriptionConfig(psub, s.c)
+}
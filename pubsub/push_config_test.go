@@ -0,0 +1,115 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/internal/testutil"
+)
+
+func TestPushConfigValidate(t *testing.T) {
+	oidc := &OIDCToken{ServiceAccountEmail: "sa@p.iam.gserviceaccount.com"}
+	for _, pc := range []PushConfig{
+		{},
+		{Endpoint: "https://example.com/push"},
+		{Endpoint: "https://example.com", AuthenticationMethod: oidc, Wrapper: &NoWrapper{WriteMetadata: true}},
+	} {
+		if err := pc.Validate(); err != nil {
+			t.Errorf("%+v: %v", pc, err)
+		}
+	}
+	for _, pc := range []PushConfig{
+		{AuthenticationMethod: oidc},
+		{Wrapper: &PubsubWrapper{}},
+		{Endpoint: "http://example.com/push"},
+		{Endpoint: "/push"},
+		{Endpoint: "https://"},
+		{Endpoint: "https://example.com/%"},
+		{Endpoint: "https://example.com", AuthenticationMethod: &OIDCToken{Audience: "a"}},
+	} {
+		if err := pc.Validate(); err == nil {
+			t.Errorf("%+v: got nil, want error", pc)
+		}
+	}
+
+	pc, err := OIDCPushConfig("https://example.com", "sa@p.iam.gserviceaccount.com", "aud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PushConfig{
+		Endpoint:             "https://example.com",
+		AuthenticationMethod: &OIDCToken{ServiceAccountEmail: "sa@p.iam.gserviceaccount.com", Audience: "aud"},
+	}
+	if diff := testutil.Diff(pc, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+	if _, err := OIDCPushConfig("https://example.com", "", ""); err == nil {
+		t.Error("OIDCPushConfig without a service account: got nil, want error")
+	}
+}
+
+func TestPushConfigWrapper(t *testing.T) {
+	for _, w := range []Wrapper{nil, &PubsubWrapper{}, &NoWrapper{}, &NoWrapper{WriteMetadata: true}} {
+		pc := &PushConfig{Endpoint: "https://example.com", Wrapper: w}
+		got := protoToPushConfig(pc.toProto())
+		if diff := testutil.Diff(got, pc); diff != "" {
+			t.Errorf("%#v: got=-, want=+:\n%s", w, diff)
+		}
+	}
+}
+
+func TestSwitchPushPull(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{
+		Topic:          topic,
+		BigQueryConfig: BigQueryConfig{Table: "p.d.t"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pc := PushConfig{Endpoint: "https://example.com", Wrapper: &NoWrapper{WriteMetadata: true}}
+	cfg, err := sub.SwitchToPush(ctx, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := testutil.Diff(cfg.PushConfig, pc); diff != "" {
+		t.Errorf("push config: got=-, want=+:\n%s", diff)
+	}
+	if cfg.BigQueryConfig.Table != "" {
+		t.Errorf("got BigQuery config %+v, want none", cfg.BigQueryConfig)
+	}
+
+	cfg, err = sub.SwitchToPull(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := testutil.Diff(cfg.PushConfig, PushConfig{}); diff != "" {
+		t.Errorf("push config after SwitchToPull: got=-, want=+:\n%s", diff)
+	}
+
+	for _, pc := range []PushConfig{{}, {Endpoint: "http://example.com"}} {
+		if _, err := sub.SwitchToPush(ctx, pc); err == nil {
+			t.Errorf("%+v: got nil, want error", pc)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"cloud.google.com/go/pubsub/internal/scheduler"
+)
+
+// ReceiveStatus reports the state of the receivers of Subscription.Receive,
+// to help tune ReceiveSettings.
+//
+// The counts of acks, nacks and modacks are cumulative since Receive started.
+// To compute their rates, call Subscription.ReceiveStatus periodically and
+// divide the differences between the counts by the time elapsed.
+type ReceiveStatus struct {
+	// Active reports whether Receive is running. If it is not, the other
+	// fields are zero.
+	Active bool
+
+	// OutstandingMessages is the number of messages that were received and
+	// are neither acked nor nacked. It is at most
+	// ReceiveSettings.MaxOutstandingMessages.
+	OutstandingMessages int
+
+	// OutstandingBytes is the total size of the data of the outstanding
+	// messages. It is at most ReceiveSettings.MaxOutstandingBytes, except
+	// that a single message larger than that may be outstanding.
+	OutstandingBytes int
+
+	// OpenStreams is the number of streaming pulls that are open. It is at
+	// most ReceiveSettings.NumGoroutines, and 0 with
+	// ReceiveSettings.Synchronous.
+	OpenStreams int
+
+	// ActiveCallbacks is the number of calls to the function passed to
+	// Receive that have not returned.
+	ActiveCallbacks int
+
+	// Acks, Nacks and ModAcks are the numbers of ack IDs sent to acknowledge
+	// messages, to nack them and to extend their ack deadlines.
+	Acks, Nacks, ModAcks int64
+
+	// OrderingKeys is the number of ordering keys that have messages being
+	// processed or waiting to be, when message ordering is enabled. It is at
+	// most ReceiveSettings.MaxOrderingKeys, plus the keys waiting for their
+	// turn.
+	OrderingKeys int
+
+	// QueuedOrderedMessages is the number of messages with ordering keys
+	// that wait for the previous messages of their keys to be processed.
+	QueuedOrderedMessages int
+
+	// HottestOrderingKey is the ordering key with the most messages waiting
+	// to be processed, and HottestOrderingKeyMessages is the number of those
+	// messages. A key that persistently has many messages waiting limits the
+	// throughput of Receive, as the messages of a key are processed one at a
+	// time.
+	HottestOrderingKey         string
+	HottestOrderingKeyMessages int
+
+	// OrderingKeyWaits is the number of times Receive waited to deliver a
+	// message because ReceiveSettings.MaxOrderingKeys keys were being
+	// processed, or because its key had
+	// ReceiveSettings.MaxMessagesPerOrderingKey messages waiting.
+	OrderingKeyWaits int64
+}
+
+// ReceiveStatus returns the status of the active call to Receive or
+// ReceiveWithDrain on s. It is safe to call concurrently with them.
+func (s *Subscription) ReceiveStatus() ReceiveStatus {
+	s.mu.Lock()
+	m := s.metrics
+	s.mu.Unlock()
+	if m == nil {
+		return ReceiveStatus{}
+	}
+	m.mu.Lock()
+	rs := ReceiveStatus{
+		Active:              true,
+		OutstandingMessages: m.messages,
+		OutstandingBytes:    m.bytes,
+		ActiveCallbacks:     m.callbacks,
+	}
+	m.mu.Unlock()
+	ss := m.sched.Stats()
+	rs.OrderingKeys = ss.Keys
+	rs.QueuedOrderedMessages = ss.QueuedItems
+	rs.HottestOrderingKey = ss.DeepestKey
+	rs.HottestOrderingKeyMessages = ss.DeepestKeyItems
+	rs.OrderingKeyWaits = ss.Waits
+	for _, it := range m.iters {
+		if it.ps != nil && it.ps.isOpen() {
+			rs.OpenStreams++
+		}
+		rs.Acks += atomic.LoadInt64(&it.sent.acks)
+		rs.Nacks += atomic.LoadInt64(&it.sent.nacks)
+		rs.ModAcks += atomic.LoadInt64(&it.sent.modAcks)
+	}
+	return rs
+}
+
+// receiveMetrics holds the state of an active Receive reported by
+// ReceiveStatus.
+type receiveMetrics struct {
+	iters []*messageIterator
+	sched *scheduler.ReceiveScheduler
+
+	mu        sync.Mutex
+	messages  int
+	bytes     int
+	callbacks int
+}
+
+// acquire records that a message of size bytes is outstanding.
+func (m *receiveMetrics) acquire(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages++
+	m.bytes += size
+}
+
+// release records that a message of size bytes is no longer outstanding.
+func (m *receiveMetrics) release(size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages--
+	m.bytes -= size
+}
+
+// callback records that a callback started, if n is 1, or returned, if n is
+// -1.
+func (m *receiveMetrics) callback(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.callbacks += n
+}
+
+// ackCounts counts the ack IDs sent by a messageIterator. Atomic.
+type ackCounts struct {
+	acks, nacks, modAcks int64
+}
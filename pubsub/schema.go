@@ -17,10 +17,16 @@ package pubsub
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"google.golang.org/api/option"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
 
 	vkit "cloud.google.com/go/pubsub/apiv1"
+	"cloud.google.com/go/pubsub/internal/avro"
+	"cloud.google.com/go/pubsub/internal/protoschema"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
 )
 
@@ -56,6 +62,14 @@ type SchemaConfig struct {
 	// the full definition of the schema that is a valid schema definition of
 	// the type specified in `type`.
 	Definition string
+
+	// The revision ID of the schema populated by the server. This field is
+	// read-only.
+	RevisionID string
+
+	// The time the revision was created, populated by the server. This field
+	// is read-only.
+	RevisionCreateTime time.Time
 }
 
 // SchemaType is the possible shcema definition types.
@@ -133,15 +147,18 @@ func (s *SchemaConfig) toProto() *pb.Schema {
 }
 
 func protoToSchemaConfig(pbs *pb.Schema) *SchemaConfig {
-	return &SchemaConfig{
+	sc := &SchemaConfig{
 		Name:       pbs.Name,
 		Type:       SchemaType(pbs.Type),
 		Definition: pbs.Definition,
 	}
+	sc.RevisionID, sc.RevisionCreateTime = schemaRevision(pbs)
+	return sc
 }
 
 // CreateSchema creates a new schema with the given schemaID
-// and config. Schemas cannot be updated after creation.
+// and config. To change the definition of the schema afterwards, commit
+// a new revision with CommitSchema.
 func (c *SchemaClient) CreateSchema(ctx context.Context, schemaID string, s SchemaConfig) (*SchemaConfig, error) {
 	req := &pb.CreateSchemaRequest{
 		Parent:   fmt.Sprintf("projects/%s", c.projectID),
@@ -172,17 +189,17 @@ func (c *SchemaClient) Schema(ctx context.Context, schemaID string, view SchemaV
 // Schemas returns an iterator which returns all of the schemas for the client's project.
 func (c *SchemaClient) Schemas(ctx context.Context, view SchemaView) *SchemaIterator {
 	return &SchemaIterator{
-		it: c.sc.ListSchemas(ctx, &pb.ListSchemasRequest{
+		next: c.sc.ListSchemas(ctx, &pb.ListSchemasRequest{
 			Parent: fmt.Sprintf("projects/%s", c.projectID),
 			View:   pb.SchemaView(view),
-		}),
+		}).Next,
 	}
 }
 
 // SchemaIterator is a struct used to iterate over schemas.
 type SchemaIterator struct {
-	it  *vkit.SchemaIterator
-	err error
+	next func() (*pb.Schema, error)
+	err  error
 }
 
 // Next returns the next schema. If there are no more schemas, iterator.Done will be returned.
@@ -190,7 +207,7 @@ func (s *SchemaIterator) Next() (*SchemaConfig, error) {
 	if s.err != nil {
 		return nil, s.err
 	}
-	pbs, err := s.it.Next()
+	pbs, err := s.next()
 	if err != nil {
 		return nil, err
 	}
@@ -261,3 +278,80 @@ func (s *SchemaClient) ValidateMessageWithID(ctx context.Context, msg []byte, en
 	}
 	return &ValidateMessageResult{}, nil
 }
+
+// A SchemaValidationError is the error of publishing a message whose data does
+// not match the schema of the topic, when the topic validates messages locally.
+// See Topic.EnableSchemaValidation.
+type SchemaValidationError struct {
+	// Schema is the name of the schema, in the format
+	// "projects/P/schemas/S".
+	Schema string
+
+	// Encoding is the encoding of messages for the topic.
+	Encoding SchemaEncoding
+
+	// Err describes how the data does not match the schema.
+	Err error
+}
+
+func (e *SchemaValidationError) Error() string {
+	return fmt.Sprintf("pubsub: message data is not valid for schema %s: %v", e.Schema, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SchemaValidationError) Unwrap() error { return e.Err }
+
+// schemaValidator validates the data of messages against a schema.
+type schemaValidator struct {
+	schema   string
+	encoding SchemaEncoding
+	validate func(data []byte) error
+}
+
+// newSchemaValidator returns a validator for the data of messages of the given
+// encoding. A full view of the schema is needed. Like the service, it treats
+// EncodingUnspecified as EncodingJSON.
+func newSchemaValidator(s *SchemaConfig, encoding SchemaEncoding) (*schemaValidator, error) {
+	if encoding == EncodingUnspecified {
+		encoding = EncodingJSON
+	}
+	if encoding != EncodingJSON && encoding != EncodingBinary {
+		return nil, fmt.Errorf("pubsub: unknown schema encoding %v", encoding)
+	}
+	v := &schemaValidator{schema: s.Name, encoding: encoding}
+	switch s.Type {
+	case SchemaAvro:
+		as, err := avro.Parse(s.Definition)
+		if err != nil {
+			return nil, err
+		}
+		v.validate = as.ValidateJSON
+		if encoding == EncodingBinary {
+			v.validate = as.ValidateBinary
+		}
+	case SchemaProtocolBuffer:
+		md, err := protoschema.Parse(s.Definition)
+		if err != nil {
+			return nil, err
+		}
+		v.validate = func(data []byte) error {
+			m := dynamicpb.NewMessage(md)
+			if encoding == EncodingBinary {
+				return proto.Unmarshal(data, m)
+			}
+			return protojson.Unmarshal(data, m)
+		}
+	default:
+		return nil, fmt.Errorf("pubsub: cannot validate messages against schema %s of type %v", s.Name, s.Type)
+	}
+	return v, nil
+}
+
+// validateMessage returns a *SchemaValidationError if data does not match the
+// schema.
+func (v *schemaValidator) validateMessage(data []byte) error {
+	if err := v.validate(data); err != nil {
+		return &SchemaValidationError{Schema: v.schema, Encoding: v.encoding, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/pubsub/internal/wirefield"
+	"google.golang.org/api/iterator"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// The generated protos of the API version this package uses do not have the
+// schema revision methods of google.pubsub.v1.SchemaService, nor the revision
+// fields of Schema. The requests of these methods are encoded by field number
+// and sent as the unknown fields of an empty message. Their responses are
+// decoded into pb.Schema, whose revision fields are kept as unknown fields,
+// and pb.ListSchemasResponse, which has the fields of
+// ListSchemaRevisionsResponse.
+const (
+	schemaServiceMethodPrefix = "/google.pubsub.v1.SchemaService/"
+
+	schemaRevisionIDField         = 4
+	schemaRevisionCreateTimeField = 6
+
+	// The fields of CommitSchemaRequest, RollbackSchemaRequest,
+	// ListSchemaRevisionsRequest and DeleteSchemaRevisionRequest.
+	revisionRequestNameField       = 1
+	revisionRequestSchemaField     = 2
+	revisionRequestRevisionIDField = 2
+	revisionRequestViewField       = 2
+	revisionRequestPageTokenField  = 4
+)
+
+// schemaRevision returns the revision ID and creation time of pbs.
+func schemaRevision(pbs *pb.Schema) (string, time.Time) {
+	m := pbs.ProtoReflect()
+	var id string
+	var created time.Time
+	if b, ok := wirefield.Bytes(m, schemaRevisionIDField); ok {
+		id = string(b)
+	}
+	if b, ok := wirefield.Bytes(m, schemaRevisionCreateTimeField); ok {
+		created = decodeTimestamp(b)
+	}
+	return id, created
+}
+
+// invokeRevisionMethod calls the named schema revision method on the schema
+// named name, with the encoded request req, and decodes the response into
+// resp.
+func (c *SchemaClient) invokeRevisionMethod(ctx context.Context, method, name string, req []byte, resp proto.Message) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-goog-request-params", "name="+url.QueryEscape(name))
+	in := &emptypb.Empty{}
+	in.ProtoReflect().SetUnknown(req)
+	return c.sc.Connection().Invoke(ctx, schemaServiceMethodPrefix+method, in, resp)
+}
+
+// ListSchemaRevisions returns an iterator which returns all of the revisions
+// of the schema with the given schemaID, newest first.
+func (c *SchemaClient) ListSchemaRevisions(ctx context.Context, schemaID string, view SchemaView) *SchemaIterator {
+	name := fmt.Sprintf("projects/%s/schemas/%s", c.projectID, schemaID)
+	var (
+		buf       []*pb.Schema
+		pageToken string
+		last      bool
+	)
+	next := func() (*pb.Schema, error) {
+		for len(buf) == 0 {
+			if last {
+				return nil, iterator.Done
+			}
+			var req []byte
+			req = appendString(req, revisionRequestNameField, name)
+			req = appendVarint(req, revisionRequestViewField, uint64(view))
+			req = appendString(req, revisionRequestPageTokenField, pageToken)
+			resp := &pb.ListSchemasResponse{}
+			if err := c.invokeRevisionMethod(ctx, "ListSchemaRevisions", name, req, resp); err != nil {
+				return nil, err
+			}
+			buf, pageToken = resp.Schemas, resp.NextPageToken
+			last = pageToken == ""
+		}
+		pbs := buf[0]
+		buf = buf[1:]
+		return pbs, nil
+	}
+	return &SchemaIterator{next: next}
+}
+
+// CommitSchema commits a new revision of the schema with the given schemaID.
+// The type and definition of s become the new revision, which is returned.
+func (c *SchemaClient) CommitSchema(ctx context.Context, schemaID string, s SchemaConfig) (*SchemaConfig, error) {
+	schemaPath := fmt.Sprintf("projects/%s/schemas/%s", c.projectID, schemaID)
+	s.Name = schemaPath
+	schema, err := proto.Marshal(s.toProto())
+	if err != nil {
+		return nil, err
+	}
+	var req []byte
+	req = appendString(req, revisionRequestNameField, schemaPath)
+	req = appendMessage(req, revisionRequestSchemaField, schema)
+	pbs := &pb.Schema{}
+	if err := c.invokeRevisionMethod(ctx, "CommitSchema", schemaPath, req, pbs); err != nil {
+		return nil, err
+	}
+	return protoToSchemaConfig(pbs), nil
+}
+
+// RollbackSchema creates a new revision of the schema with the given
+// schemaID that is a copy of the revision with the given revisionID, and
+// returns it.
+func (c *SchemaClient) RollbackSchema(ctx context.Context, schemaID, revisionID string) (*SchemaConfig, error) {
+	schemaPath := fmt.Sprintf("projects/%s/schemas/%s", c.projectID, schemaID)
+	var req []byte
+	req = appendString(req, revisionRequestNameField, schemaPath)
+	req = appendString(req, revisionRequestRevisionIDField, revisionID)
+	pbs := &pb.Schema{}
+	if err := c.invokeRevisionMethod(ctx, "RollbackSchema", schemaPath, req, pbs); err != nil {
+		return nil, err
+	}
+	return protoToSchemaConfig(pbs), nil
+}
+
+// DeleteSchemaRevision deletes the revision with the given revisionID of the
+// schema with the given schemaID. The last revision of a schema cannot be
+// deleted; use DeleteSchema instead.
+func (c *SchemaClient) DeleteSchemaRevision(ctx context.Context, schemaID, revisionID string) (*SchemaConfig, error) {
+	revisionPath := fmt.Sprintf("projects/%s/schemas/%s@%s", c.projectID, schemaID, revisionID)
+	req := appendString(nil, revisionRequestNameField, revisionPath)
+	pbs := &pb.Schema{}
+	if err := c.invokeRevisionMethod(ctx, "DeleteSchemaRevision", revisionPath, req, pbs); err != nil {
+		return nil, err
+	}
+	return protoToSchemaConfig(pbs), nil
+}
@@ -15,11 +15,13 @@ package pubsub
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"cloud.google.com/go/internal/testutil"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc"
@@ -55,17 +57,21 @@ func TestSchemaBasicCreateGetDelete(t *testing.T) {
 	admin, _ := newSchemaFake(t)
 	defer admin.Close()
 
+	// The revision is set by the server.
+	ignoreRevision := cmpopts.IgnoreFields(SchemaConfig{}, "RevisionID", "RevisionCreateTime")
 	if gotConfig, err := admin.CreateSchema(ctx, schemaID, schemaConfig); err != nil {
 		t.Errorf("CreateSchema() got err: %v", err)
-	} else if diff := cmp.Diff(*gotConfig, schemaConfig); diff != "" {
+	} else if diff := cmp.Diff(*gotConfig, schemaConfig, ignoreRevision); diff != "" {
 		t.Errorf("CreateSchema() -want, +got: %v", diff)
+	} else if gotConfig.RevisionID == "" || gotConfig.RevisionCreateTime.IsZero() {
+		t.Errorf("CreateSchema() got no revision: %+v", gotConfig)
 	}
 
 	gotConfig, err := admin.Schema(ctx, schemaID, SchemaViewFull)
 	if err != nil {
 		t.Errorf("Schema() got err: %v", err)
 	}
-	if diff := testutil.Diff(*gotConfig, schemaConfig); diff != "" {
+	if diff := testutil.Diff(*gotConfig, schemaConfig, ignoreRevision); diff != "" {
 		t.Errorf("Schema() -got, +want:\n%v", diff)
 	}
 
@@ -119,6 +125,64 @@ func TestSchemaListSchemas(t *testing.T) {
 	}
 }
 
+func TestSchemaRevisions(t *testing.T) {
+	ctx := context.Background()
+	admin, _ := newSchemaFake(t)
+	defer admin.Close()
+
+	first := mustCreateSchema(t, admin, "s", SchemaConfig{Type: SchemaAvro, Definition: "def-1"})
+	second, err := admin.CommitSchema(ctx, "s", SchemaConfig{Type: SchemaAvro, Definition: "def-2"})
+	if err != nil {
+		t.Fatalf("CommitSchema() got err: %v", err)
+	}
+	if second.Name != first.Name || second.Definition != "def-2" || second.RevisionID == first.RevisionID {
+		t.Errorf("CommitSchema() got %+v, want a new revision of %+v", second, first)
+	}
+	if got, err := admin.Schema(ctx, "s", SchemaViewFull); err != nil {
+		t.Fatalf("Schema() got err: %v", err)
+	} else if got.RevisionID != second.RevisionID {
+		t.Errorf("Schema() got revision %q, want the latest, %q", got.RevisionID, second.RevisionID)
+	}
+
+	third, err := admin.RollbackSchema(ctx, "s", first.RevisionID)
+	if err != nil {
+		t.Fatalf("RollbackSchema() got err: %v", err)
+	}
+	if third.Definition != first.Definition || third.RevisionID == first.RevisionID {
+		t.Errorf("RollbackSchema() got %+v, want a new revision with the definition of %+v", third, first)
+	}
+	if _, err := admin.RollbackSchema(ctx, "s", "no-such-revision"); status.Code(err) != codes.NotFound {
+		t.Errorf("RollbackSchema() of unknown revision got err %v, want NotFound", err)
+	}
+
+	listRevisions := func() []string {
+		var ids []string
+		it := admin.ListSchemaRevisions(ctx, "s", SchemaViewBasic)
+		for {
+			sc, err := it.Next()
+			if err == iterator.Done {
+				return ids
+			}
+			if err != nil {
+				t.Fatalf("ListSchemaRevisions() got err: %v", err)
+			}
+			ids = append(ids, sc.RevisionID)
+		}
+	}
+	want := []string{third.RevisionID, second.RevisionID, first.RevisionID}
+	if diff := cmp.Diff(listRevisions(), want); diff != "" {
+		t.Errorf("ListSchemaRevisions() -got, +want:\n%v", diff)
+	}
+
+	if _, err := admin.DeleteSchemaRevision(ctx, "s", second.RevisionID); err != nil {
+		t.Fatalf("DeleteSchemaRevision() got err: %v", err)
+	}
+	want = []string{third.RevisionID, first.RevisionID}
+	if diff := cmp.Diff(listRevisions(), want); diff != "" {
+		t.Errorf("ListSchemaRevisions() after delete -got, +want:\n%v", diff)
+	}
+}
+
 func TestSchemaValidateSchema(t *testing.T) {
 	ctx := context.Background()
 	admin, _ := newSchemaFake(t)
@@ -173,3 +237,94 @@ func mustCreateSchema(t *testing.T, c *SchemaClient, id string, sc SchemaConfig)
 	}
 	return schema
 }
+
+func TestSchemaValidator(t *testing.T) {
+	const (
+		avroDef = `{"type": "record", "name": "State", "fields": [
+			{"name": "name", "type": "string"},
+			{"name": "population", "type": ["null", "long"], "default": null}]}`
+		protoDef = `syntax = "proto3"; message State { string name = 1; int64 population = 2; }`
+	)
+	for _, tc := range []struct {
+		desc     string
+		schema   SchemaConfig
+		encoding SchemaEncoding
+		data     []byte
+		wantErr  bool
+	}{
+		{"avro json", SchemaConfig{Type: SchemaAvro, Definition: avroDef}, EncodingJSON, []byte(`{"name": "Alaska"}`), false},
+		{"avro json invalid", SchemaConfig{Type: SchemaAvro, Definition: avroDef}, EncodingJSON, []byte(`{"name": 1}`), true},
+		{"avro unspecified encoding", SchemaConfig{Type: SchemaAvro, Definition: avroDef}, EncodingUnspecified, []byte(`{"name": "Alaska", "population": {"long": 1}}`), false},
+		{"avro binary", SchemaConfig{Type: SchemaAvro, Definition: avroDef}, EncodingBinary, []byte{0x02, 'A', 0x02, 0x04}, false},
+		{"avro binary invalid", SchemaConfig{Type: SchemaAvro, Definition: avroDef}, EncodingBinary, []byte{0x02, 'A', 0x04}, true},
+		{"proto json", SchemaConfig{Type: SchemaProtocolBuffer, Definition: protoDef}, EncodingJSON, []byte(`{"name": "Alaska", "population": "733391"}`), false},
+		{"proto json invalid", SchemaConfig{Type: SchemaProtocolBuffer, Definition: protoDef}, EncodingJSON, []byte(`{"capital": "Juneau"}`), true},
+		{"proto binary", SchemaConfig{Type: SchemaProtocolBuffer, Definition: protoDef}, EncodingBinary, []byte{0x0a, 0x01, 'A', 0x10, 0x02}, false},
+		{"proto binary invalid", SchemaConfig{Type: SchemaProtocolBuffer, Definition: protoDef}, EncodingBinary, []byte{0x0a, 0x05, 'A'}, true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.schema.Name = "projects/P/schemas/s"
+			v, err := newSchemaValidator(&tc.schema, tc.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = v.validateMessage(tc.data)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("got err %v, want error: %t", err, tc.wantErr)
+			}
+			var verr *SchemaValidationError
+			if tc.wantErr && (!errors.As(err, &verr) || verr.Schema != tc.schema.Name) {
+				t.Errorf("got %v, want a *SchemaValidationError for %s", err, tc.schema.Name)
+			}
+		})
+	}
+
+	if _, err := newSchemaValidator(&SchemaConfig{Type: SchemaAvro, Definition: "{name:some-avro-schema}"}, EncodingJSON); err == nil {
+		t.Error("invalid definition: got nil, want error")
+	}
+	if _, err := newSchemaValidator(&SchemaConfig{Type: SchemaTypeUnspecified, Definition: avroDef}, EncodingJSON); err == nil {
+		t.Error("unspecified type: got nil, want error")
+	}
+}
+
+func TestTopicEnableSchemaValidation(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+	admin, err := NewSchemaClient(ctx, "P", option.WithEndpoint(srv.Addr), option.WithoutAuthentication(), option.WithGRPCDialOption(grpc.WithInsecure()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+
+	schema := mustCreateSchema(t, admin, "s", SchemaConfig{
+		Type:       SchemaProtocolBuffer,
+		Definition: `syntax = "proto3"; message State { string name = 1; }`,
+	})
+	topic := mustCreateTopicWithConfig(t, client, "t", &TopicConfig{
+		SchemaSettings: &SchemaSettings{Schema: schema.Name, Encoding: EncodingJSON},
+	})
+	defer topic.Stop()
+	if err := topic.EnableSchemaValidation(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := topic.Publish(ctx, &Message{Data: []byte(`{"name": "Alaska"}`)}).Get(ctx); err != nil {
+		t.Errorf("valid message: %v", err)
+	}
+	_, err = topic.Publish(ctx, &Message{Data: []byte(`{"name": 1}`)}).Get(ctx)
+	var verr *SchemaValidationError
+	if !errors.As(err, &verr) || verr.Schema != schema.Name || verr.Encoding != EncodingJSON {
+		t.Errorf("invalid message: got %v, want a *SchemaValidationError", err)
+	}
+	if got := len(srv.Messages()); got != 1 {
+		t.Errorf("got %d published messages, want 1", got)
+	}
+
+	noSchema := mustCreateTopic(t, client, "no-schema")
+	defer noSchema.Stop()
+	if err := noSchema.EnableSchemaValidation(ctx, admin); err == nil {
+		t.Error("topic without schema: got nil, want error")
+	}
+}
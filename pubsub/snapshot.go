@@ -20,6 +20,7 @@ import (
 	"strings"
 	"time"
 
+	"google.golang.org/api/iterator"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -59,22 +60,13 @@ func (c *Client) Snapshot(id string) *Snapshot {
 
 // Snapshots returns an iterator which returns snapshots for this project.
 func (c *Client) Snapshots(ctx context.Context) *SnapshotConfigIterator {
-	it := c.subc.ListSnapshots(ctx, &pb.ListSnapshotsRequest{
-		Project: c.fullyQualifiedProjectName(),
-	})
-	next := func() (*SnapshotConfig, error) {
-		snap, err := it.Next()
-		if err != nil {
-			return nil, err
-		}
-		return toSnapshotConfig(snap, c)
-	}
-	return &SnapshotConfigIterator{next: next}
+	return c.ListSnapshots(ctx, nil)
 }
 
 // SnapshotConfigIterator is an iterator that returns a series of snapshots.
 type SnapshotConfigIterator struct {
-	next func() (*SnapshotConfig, error)
+	next     func() (*SnapshotConfig, error)
+	pageInfo func() *iterator.PageInfo
 }
 
 // Next returns the next SnapshotConfig. Its second return value is iterator.Done if there are no more results.
@@ -83,6 +75,13 @@ func (snaps *SnapshotConfigIterator) Next() (*SnapshotConfig, error) {
 	return snaps.next()
 }
 
+// PageInfo supports pagination: set its MaxSize before calling Next to choose
+// the page size, and its Token to resume listing from a page. The iterator
+// cannot be used with iterator.NewPager.
+func (snaps *SnapshotConfigIterator) PageInfo() *iterator.PageInfo {
+	return snaps.pageInfo()
+}
+
 // Delete deletes a snapshot.
 func (s *Snapshot) Delete(ctx context.Context) error {
 	return s.c.subc.DeleteSnapshot(ctx, &pb.DeleteSnapshotRequest{Snapshot: s.name})
@@ -108,6 +107,57 @@ func (s *Subscription) SeekToTime(ctx context.Context, t time.Time) error {
 	return err
 }
 
+// ReplaySince seeks the subscription to t, so that the messages published
+// since t are delivered again, whether or not they were acknowledged.
+//
+// Unlike SeekToTime, ReplaySince first checks that the subscription retains
+// the messages to replay: it returns an error if t is in the future, or
+// before the start of the retention window, which is the longer of the
+// subscription's RetentionDuration, if RetainAckedMessages is set, and the
+// topic's message retention duration.
+//
+// If Receive is active on s, ReplaySince pauses it while seeking: it stops
+// pulling messages, waits for the outstanding messages to be acked or nacked
+// and for the acks and nacks to be sent, so that they do not undo the seek,
+// seeks, and resumes pulling. Messages that arrive while Receive is paused are
+// nacked. Receive calls on other Subscription values, or in other processes,
+// are not paused.
+func (s *Subscription) ReplaySince(ctx context.Context, t time.Time) error {
+	cfg, err := s.Config(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if t.After(now) {
+		return fmt.Errorf("pubsub: ReplaySince: %v is in the future", t)
+	}
+	var window time.Duration
+	if cfg.RetainAckedMessages {
+		window = cfg.RetentionDuration
+	}
+	if cfg.TopicMessageRetentionDuration > window {
+		window = cfg.TopicMessageRetentionDuration
+	}
+	if window == 0 {
+		return fmt.Errorf("pubsub: ReplaySince: subscription %s does not retain acknowledged messages; set RetainAckedMessages in its config, or RetentionDuration in its topic's", s.name)
+	}
+	if start := now.Add(-window); t.Before(start) {
+		return fmt.Errorf("pubsub: ReplaySince: %v is before the start of the retention window of subscription %s, at %v", t, s.name, start)
+	}
+
+	s.mu.Lock()
+	rp := s.pause
+	s.mu.Unlock()
+	if rp != nil {
+		rp.pause()
+		defer rp.resume()
+		if err := rp.quiesce(ctx); err != nil {
+			return err
+		}
+	}
+	return s.SeekToTime(ctx, t)
+}
+
 // CreateSnapshot creates a new snapshot from this subscription.
 // The snapshot will be for the topic this subscription is subscribed to.
 // If the name is empty string, a unique name is assigned.
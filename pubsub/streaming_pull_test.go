@@ -32,6 +32,7 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/api/option"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -97,7 +98,7 @@ func testStreamingPullIteration(t *testing.T, client *Client, server *mockServer
 			t.Errorf("%d: no message for ackID %q", i, wantAckh.ackID)
 			continue
 		}
-		if !testutil.Equal(got, want, cmp.AllowUnexported(Message{}, psAckHandler{}), cmpopts.IgnoreTypes(time.Time{}, func(string, bool, time.Time) {})) {
+		if !testutil.Equal(got, want, cmp.AllowUnexported(Message{}, psAckHandler{}), cmpopts.IgnoreTypes(time.Time{}, func(string, bool, *AckResult, time.Time) {}), cmpopts.IgnoreInterfaces(struct{ leaseManager }{})) {
 			t.Errorf("%d: got\n%#v\nwant\n%#v", i, got, want)
 		}
 	}
@@ -426,6 +427,93 @@ func TestStreamingPull_ReconnectsAfterServerDies(t *testing.T) {
 	}
 }
 
+func TestStreamingPullExactlyOnce(t *testing.T) {
+	invalid := exactlyOnceError(codes.InvalidArgument, map[string]string{"0": "PERMANENT_FAILURE_INVALID_ACK_ID"})
+	transient := exactlyOnceError(codes.InvalidArgument, map[string]string{"0": "TRANSIENT_FAILURE_UNORDERED_ACK_ID"})
+	for _, test := range []struct {
+		desc          string
+		eod           bool
+		modAckErr     error // of the receipt mod-ack
+		ackErr        error
+		wantDelivered bool
+		wantStatus    AcknowledgeStatus
+		wantAcked     bool
+	}{
+		{"disabled", false, nil, nil, true, AcknowledgeStatusSuccess, true},
+		{"success", true, nil, nil, true, AcknowledgeStatusSuccess, true},
+		{"transient ack failure", true, nil, transient, true, AcknowledgeStatusSuccess, true},
+		{"invalid ack ID", true, nil, invalid, true, AcknowledgeStatusInvalidAckID, false},
+		{"permission denied", true, nil, status.Error(codes.PermissionDenied, ""), true, AcknowledgeStatusPermissionDenied, false},
+		{"invalid receipt ack ID", true, invalid, nil, false, 0, false},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			client, server := newMock(t)
+			defer server.srv.Close()
+			defer client.Close()
+			server.sub.EnableExactlyOnceDelivery = test.eod
+			if test.modAckErr != nil {
+				server.addModAckResponse(test.modAckErr)
+			}
+			if test.ackErr != nil {
+				server.addAckResponse(test.ackErr)
+			}
+			server.addStreamingPullMessages(testMessages[:1])
+			sub := client.Subscription("S")
+			sub.ReceiveSettings.NumGoroutines = 1
+
+			var (
+				delivered bool
+				gotStatus AcknowledgeStatus
+				gotErr    error
+			)
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			err := sub.Receive(ctx, func(ctx context.Context, m *Message) {
+				delivered = true
+				gotStatus, gotErr = AckWithResult(m).Get(ctx)
+				cancel()
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if delivered != test.wantDelivered {
+				t.Fatalf("delivered: got %t, want %t", delivered, test.wantDelivered)
+			}
+			if !delivered {
+				return
+			}
+			if gotStatus != test.wantStatus {
+				t.Errorf("status: got %v (%v), want %v", gotStatus, gotErr, test.wantStatus)
+			}
+			if (gotErr == nil) != (test.wantStatus == AcknowledgeStatusSuccess) {
+				t.Errorf("got error %v with status %v", gotErr, gotStatus)
+			}
+			server.wait()
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if got := server.Acked["0"]; got != test.wantAcked {
+				t.Errorf("acked: got %t, want %t", got, test.wantAcked)
+			}
+			if test.eod && server.Deadlines["0"] < int32(minExactlyOnceAckDeadline/time.Second) {
+				t.Errorf("got ack deadline %ds, want at least %v", server.Deadlines["0"], minExactlyOnceAckDeadline)
+			}
+		})
+	}
+}
+
+// exactlyOnceError returns an error of an Acknowledge or ModifyAckDeadline RPC
+// that failed for the ack IDs of failures, with exactly-once delivery.
+func exactlyOnceError(code codes.Code, failures map[string]string) error {
+	st, err := status.New(code, "some ack IDs failed").WithDetails(&errdetails.ErrorInfo{
+		Reason:   "EXACTLY_ONCE_ACKID_FAILURE",
+		Metadata: failures,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return st.Err()
+}
+
 func newMock(t *testing.T) (*Client, *mockServer) {
 	srv, err := newMockServer(0)
 	if err != nil {
@@ -27,9 +27,12 @@ import (
 	"cloud.google.com/go/internal/optional"
 	"cloud.google.com/go/pubsub/internal/scheduler"
 	gax "github.com/googleapis/gax-go/v2"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/iterator"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
 	fmpb "google.golang.org/genproto/protobuf/field_mask"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	durpb "google.golang.org/protobuf/types/known/durationpb"
@@ -49,8 +52,11 @@ type Subscription struct {
 
 	mu            sync.Mutex
 	receiveActive bool
+	pause         *receivePause   // of the active Receive, for ReplaySince
+	metrics       *receiveMetrics // of the active Receive, for ReceiveStatus
 
-	enableOrdering bool
+	enableOrdering            bool
+	enableExactlyOnceDelivery bool
 }
 
 // Subscription creates a reference to a subscription.
@@ -83,27 +89,15 @@ func (s *Subscription) ID() string {
 
 // Subscriptions returns an iterator which returns all of the subscriptions for the client's project.
 func (c *Client) Subscriptions(ctx context.Context) *SubscriptionIterator {
-	it := c.subc.ListSubscriptions(ctx, &pb.ListSubscriptionsRequest{
-		Project: c.fullyQualifiedProjectName(),
-	})
-	return &SubscriptionIterator{
-		c:  c,
-		it: it,
-		next: func() (string, error) {
-			sub, err := it.Next()
-			if err != nil {
-				return "", err
-			}
-			return sub.Name, nil
-		},
-	}
+	return c.ListSubscriptions(ctx, nil)
 }
 
 // SubscriptionIterator is an iterator that returns a series of subscriptions.
 type SubscriptionIterator struct {
-	c    *Client
-	it   *vkit.SubscriptionIterator
-	next func() (string, error)
+	c        *Client
+	it       *vkit.SubscriptionIterator
+	next     func() (string, error)
+	pageInfo func() *iterator.PageInfo
 }
 
 // Next returns the next subscription. If there are no more subscriptions, iterator.Done will be returned.
@@ -131,6 +125,13 @@ func (subs *SubscriptionIterator) NextConfig() (*SubscriptionConfig, error) {
 	return &cfg, nil
 }
 
+// PageInfo supports pagination: set its MaxSize before calling Next to choose
+// the page size, and its Token to resume listing from a page. The iterator
+// cannot be used with iterator.NewPager.
+func (subs *SubscriptionIterator) PageInfo() *iterator.PageInfo {
+	return subs.pageInfo()
+}
+
 // PushConfig contains configuration for subscriptions that operate in push mode.
 type PushConfig struct {
 	// A URL locating the endpoint to which messages should be pushed.
@@ -146,6 +147,10 @@ type PushConfig struct {
 	// This field is optional and should be set only by users interested in
 	// authenticated push.
 	AuthenticationMethod AuthenticationMethod
+
+	// Wrapper is the format of the delivered messages. If it is nil, the
+	// service uses a *PubsubWrapper.
+	Wrapper Wrapper
 }
 
 func (pc *PushConfig) toProto() *pb.PushConfig {
@@ -163,6 +168,7 @@ func (pc *PushConfig) toProto() *pb.PushConfig {
 		default: // TODO: add others here when GAIC adds more definitions.
 		}
 	}
+	setWrapper(pbCfg, pc.Wrapper)
 	return pbCfg
 }
 
@@ -280,6 +286,19 @@ type SubscriptionConfig struct {
 	// the endpoint will not be made.
 	Detached bool
 
+	// EnableExactlyOnceDelivery enables exactly-once delivery on this
+	// subscription. When enabled, a message that has been successfully
+	// acknowledged, as reported by the result of AckWithResult, is not
+	// redelivered, and Subscription.Receive keeps each message's ack deadline
+	// at least 60 seconds long, unless ReceiveSettings.MaxExtensionPeriod is
+	// shorter. Messages whose ack deadline expires are
+	// redelivered with a new ack ID, so acking them afterwards fails with
+	// AcknowledgeStatusInvalidAckID.
+	//
+	// As with EnableMessageOrdering, the client checks this value with a call
+	// to Subscription.Config() in Subscription.Receive.
+	EnableExactlyOnceDelivery bool
+
 	// TopicMessageRetentionDuration indicates the minimum duration for which a message is
 	// retained after it is published to the subscription's topic. If this field is
 	// set, messages published to the subscription's topic in the last
@@ -290,6 +309,25 @@ type SubscriptionConfig struct {
 	// This is an output only field, meaning it will only appear in responses from the backend
 	// and will be ignored if sent in a request.
 	TopicMessageRetentionDuration time.Duration
+
+	// BigQueryConfig configures the subscription to write messages to a
+	// BigQuery table instead of delivering them to subscribers. A
+	// subscription can have at most one of PushConfig, BigQueryConfig and
+	// CloudStorageConfig set.
+	BigQueryConfig BigQueryConfig
+
+	// CloudStorageConfig configures the subscription to write messages to
+	// files in a Cloud Storage bucket instead of delivering them to
+	// subscribers.
+	CloudStorageConfig CloudStorageConfig
+
+	// State indicates whether or not the subscription can receive messages.
+	// If it is SubscriptionStateResourceError, the State of BigQueryConfig or
+	// CloudStorageConfig has the details.
+	//
+	// This is an output only field, meaning it will only appear in responses from the backend
+	// and will be ignored if sent in a request.
+	State SubscriptionState
 }
 
 // String returns the globally unique printable name of the subscription config.
@@ -314,7 +352,7 @@ func (s *SubscriptionConfig) ID() string {
 
 func (cfg *SubscriptionConfig) toProto(name string) *pb.Subscription {
 	var pbPushConfig *pb.PushConfig
-	if cfg.PushConfig.Endpoint != "" || len(cfg.PushConfig.Attributes) != 0 || cfg.PushConfig.AuthenticationMethod != nil {
+	if cfg.PushConfig.Endpoint != "" || len(cfg.PushConfig.Attributes) != 0 || cfg.PushConfig.AuthenticationMethod != nil || cfg.PushConfig.Wrapper != nil {
 		pbPushConfig = cfg.PushConfig.toProto()
 	}
 	var retentionDuration *durpb.Duration
@@ -329,21 +367,25 @@ func (cfg *SubscriptionConfig) toProto(name string) *pb.Subscription {
 	if cfg.RetryPolicy != nil {
 		pbRetryPolicy = cfg.RetryPolicy.toProto()
 	}
-	return &pb.Subscription{
-		Name:                     name,
-		Topic:                    cfg.Topic.name,
-		PushConfig:               pbPushConfig,
-		AckDeadlineSeconds:       trunc32(int64(cfg.AckDeadline.Seconds())),
-		RetainAckedMessages:      cfg.RetainAckedMessages,
-		MessageRetentionDuration: retentionDuration,
-		Labels:                   cfg.Labels,
-		ExpirationPolicy:         expirationPolicyToProto(cfg.ExpirationPolicy),
-		EnableMessageOrdering:    cfg.EnableMessageOrdering,
-		DeadLetterPolicy:         pbDeadLetter,
-		Filter:                   cfg.Filter,
-		RetryPolicy:              pbRetryPolicy,
-		Detached:                 cfg.Detached,
-	}
+	psub := &pb.Subscription{
+		Name:                      name,
+		Topic:                     cfg.Topic.name,
+		PushConfig:                pbPushConfig,
+		AckDeadlineSeconds:        trunc32(int64(cfg.AckDeadline.Seconds())),
+		RetainAckedMessages:       cfg.RetainAckedMessages,
+		MessageRetentionDuration:  retentionDuration,
+		Labels:                    cfg.Labels,
+		ExpirationPolicy:          expirationPolicyToProto(cfg.ExpirationPolicy),
+		EnableMessageOrdering:     cfg.EnableMessageOrdering,
+		DeadLetterPolicy:          pbDeadLetter,
+		Filter:                    cfg.Filter,
+		RetryPolicy:               pbRetryPolicy,
+		Detached:                  cfg.Detached,
+		EnableExactlyOnceDelivery: cfg.EnableExactlyOnceDelivery,
+	}
+	setBigQueryConfig(psub, &cfg.BigQueryConfig)
+	setCloudStorageConfig(psub, &cfg.CloudStorageConfig)
+	return psub
 }
 
 func protoToSubscriptionConfig(pbSub *pb.Subscription, c *Client) (SubscriptionConfig, error) {
@@ -371,7 +413,9 @@ func protoToSubscriptionConfig(pbSub *pb.Subscription, c *Client) (SubscriptionC
 		RetryPolicy:                   rp,
 		Detached:                      pbSub.Detached,
 		TopicMessageRetentionDuration: pbSub.TopicMessageRetentionDuration.AsDuration(),
+		EnableExactlyOnceDelivery:     pbSub.EnableExactlyOnceDelivery,
 	}
+	subC.BigQueryConfig, subC.CloudStorageConfig, subC.State = exportConfigs(pbSub)
 	pc := protoToPushConfig(pbSub.PushConfig)
 	if pc != nil {
 		subC.PushConfig = *pc
@@ -395,6 +439,7 @@ func protoToPushConfig(pbPc *pb.PushConfig) *PushConfig {
 			}
 		}
 	}
+	pc.Wrapper = wrapper(pbPc)
 	return pc
 }
 
@@ -424,6 +469,104 @@ func protoToDLP(pbDLP *pb.DeadLetterPolicy) *DeadLetterPolicy {
 	}
 }
 
+// DeadLetteringConfig configures Subscription.EnableDeadLettering.
+type DeadLetteringConfig struct {
+	// DeadLetterTopic is the topic to which messages that cannot be delivered
+	// are forwarded. It is created if it does not exist. It must not be the
+	// topic of the subscription.
+	DeadLetterTopic *Topic
+
+	// MaxDeliveryAttempts is the maximum number of delivery attempts for a
+	// message before it is forwarded to the dead-letter topic. It must be
+	// between 5 and 100. If zero, the service uses 5.
+	MaxDeliveryAttempts int
+
+	// ProjectNumber is the number of the project of the subscription. The
+	// Pub/Sub service account of the project, which forwards messages to the
+	// dead-letter topic, is named after it.
+	ProjectNumber int64
+}
+
+// EnableDeadLettering sets up dead lettering for the subscription: it creates
+// the dead-letter topic if needed, grants the Pub/Sub service account of the
+// project the roles it needs to forward messages to the topic, and sets the
+// DeadLetterPolicy of the subscription. It returns the updated configuration
+// of the subscription.
+//
+// The service account is granted the Pub/Sub Publisher role on the dead-letter
+// topic and the Pub/Sub Subscriber role on the subscription, so the caller
+// needs permission to set the IAM policies of both. Roles already granted are
+// left as they are, so EnableDeadLettering may be called again, for example to
+// change MaxDeliveryAttempts.
+//
+// Once dead lettering is enabled, the DeliveryAttempt field of received
+// messages counts their delivery attempts.
+func (s *Subscription) EnableDeadLettering(ctx context.Context, cfg DeadLetteringConfig) (SubscriptionConfig, error) {
+	dlt := cfg.DeadLetterTopic
+	if dlt == nil {
+		return SubscriptionConfig{}, errors.New("pubsub: EnableDeadLettering: missing DeadLetterTopic")
+	}
+	if cfg.MaxDeliveryAttempts != 0 && (cfg.MaxDeliveryAttempts < 5 || cfg.MaxDeliveryAttempts > 100) {
+		return SubscriptionConfig{}, fmt.Errorf("pubsub: EnableDeadLettering: MaxDeliveryAttempts is %d, should be between 5 and 100", cfg.MaxDeliveryAttempts)
+	}
+	if cfg.ProjectNumber <= 0 {
+		return SubscriptionConfig{}, errors.New("pubsub: EnableDeadLettering: missing ProjectNumber")
+	}
+	subCfg, err := s.Config(ctx)
+	if err != nil {
+		return SubscriptionConfig{}, err
+	}
+	if subCfg.Topic != nil && subCfg.Topic.name == dlt.name {
+		return SubscriptionConfig{}, fmt.Errorf("pubsub: EnableDeadLettering: %s is the topic of the subscription", dlt.name)
+	}
+
+	ok, err := dlt.Exists(ctx)
+	if err != nil {
+		return SubscriptionConfig{}, err
+	}
+	if !ok {
+		_, err := dlt.c.pubc.CreateTopic(ctx, &pb.Topic{Name: dlt.name})
+		if err != nil && status.Code(err) != codes.AlreadyExists {
+			return SubscriptionConfig{}, err
+		}
+	}
+
+	member := fmt.Sprintf("serviceAccount:service-%d@gcp-sa-pubsub.iam.gserviceaccount.com", cfg.ProjectNumber)
+	if err := grantRole(ctx, dlt.IAM(), member, "roles/pubsub.publisher"); err != nil {
+		return SubscriptionConfig{}, err
+	}
+	if err := grantRole(ctx, s.IAM(), member, "roles/pubsub.subscriber"); err != nil {
+		return SubscriptionConfig{}, err
+	}
+
+	return s.Update(ctx, SubscriptionConfigToUpdate{
+		DeadLetterPolicy: &DeadLetterPolicy{
+			DeadLetterTopic:     dlt.name,
+			MaxDeliveryAttempts: cfg.MaxDeliveryAttempts,
+		},
+	})
+}
+
+// grantRole adds member to the role in the IAM policy of h's resource, unless
+// it has the role already. It retries if the policy is changed concurrently.
+func grantRole(ctx context.Context, h *iam.Handle, member string, role iam.RoleName) error {
+	const maxAttempts = 3
+	for attempt := 1; ; attempt++ {
+		p, err := h.Policy(ctx)
+		if err != nil {
+			return err
+		}
+		if p.HasRole(member, role) {
+			return nil
+		}
+		p.Add(member, role)
+		err = h.SetPolicy(ctx, p)
+		if status.Code(err) != codes.Aborted || attempt == maxAttempts {
+			return err
+		}
+	}
+}
+
 // RetryPolicy specifies how Cloud Pub/Sub retries message delivery.
 //
 // Retry delay will be exponential based on provided minimum and maximum
@@ -520,6 +663,17 @@ type ReceiveSettings struct {
 	// duration less than (or equal to) 0.
 	MaxExtensionPeriod time.Duration
 
+	// MinExtensionPeriod is the minimum duration by which to extend the ack
+	// deadline at a time. By default, the extensions follow the 99th
+	// percentile of the time taken to ack messages; setting
+	// MinExtensionPeriod avoids frequent extensions of messages whose
+	// processing takes long. It is at most 10 minutes. If MaxExtensionPeriod
+	// is also set, it takes precedence.
+	//
+	// MinExtensionPeriod configuration can be disabled by specifying a
+	// duration less than (or equal to) 0.
+	MinExtensionPeriod time.Duration
+
 	// MaxOutstandingMessages is the maximum number of unprocessed messages
 	// (unacknowledged but not yet expired). If MaxOutstandingMessages is 0, it
 	// will be treated as if it were DefaultReceiveSettings.MaxOutstandingMessages.
@@ -560,6 +714,30 @@ type ReceiveSettings struct {
 	// processed, rather than in memory. NumGoroutines is ignored.
 	// The default is false.
 	Synchronous bool
+
+	// DrainTimeout is the maximum time ReceiveWithDrain waits, once it stops
+	// pulling messages, for the callbacks to ack or nack the outstanding
+	// messages. If DrainTimeout is 0, it will be treated as if it were
+	// DefaultReceiveSettings.DrainTimeout. If it is negative, ReceiveWithDrain
+	// waits as long as Receive does. Receive does not use DrainTimeout.
+	DrainTimeout time.Duration
+
+	// MaxOrderingKeys is the maximum number of ordering keys whose messages
+	// are processed concurrently, when message ordering is enabled. The
+	// messages of other keys wait until a key has no more messages to
+	// process. If it is 0, the number of keys is limited only by
+	// MaxOutstandingMessages.
+	MaxOrderingKeys int
+
+	// MaxMessagesPerOrderingKey is the maximum number of messages of an
+	// ordering key that wait to be processed, when message ordering is
+	// enabled. Once a key has that many messages waiting, Receive stops
+	// pulling until one of them is processed, so that a single key with a
+	// high rate of messages cannot take up all of MaxOutstandingMessages
+	// and MaxOutstandingBytes. If it is 0, there is no limit per key.
+	//
+	// ReceiveStatus reports the key with the most messages waiting.
+	MaxMessagesPerOrderingKey int
 }
 
 // For synchronous receive, the time to wait if we are already processing
@@ -591,6 +769,7 @@ var DefaultReceiveSettings = ReceiveSettings{
 	MaxOutstandingMessages: 1000,
 	MaxOutstandingBytes:    1e9, // 1G
 	NumGoroutines:          10,
+	DrainTimeout:           20 * time.Second,
 }
 
 // Delete deletes the subscription.
@@ -654,6 +833,17 @@ type SubscriptionConfigToUpdate struct {
 	// (to redeliver messages as soon as possible) use a pointer to the zero value
 	// for this struct.
 	RetryPolicy *RetryPolicy
+
+	// If set, EnableExactlyOnceDelivery is changed.
+	EnableExactlyOnceDelivery optional.Bool
+
+	// If non-nil, BigQueryConfig is changed. To stop writing to BigQuery,
+	// use a pointer to the zero value for this struct.
+	BigQueryConfig *BigQueryConfig
+
+	// If non-nil, CloudStorageConfig is changed. To stop writing to Cloud
+	// Storage, use a pointer to the zero value for this struct.
+	CloudStorageConfig *CloudStorageConfig
 }
 
 // Update changes an existing subscription according to the fields set in cfg.
@@ -710,6 +900,18 @@ func (s *Subscription) updateRequest(cfg *SubscriptionConfigToUpdate) *pb.Update
 		psub.RetryPolicy = cfg.RetryPolicy.toProto()
 		paths = append(paths, "retry_policy")
 	}
+	if cfg.EnableExactlyOnceDelivery != nil {
+		psub.EnableExactlyOnceDelivery = optional.ToBool(cfg.EnableExactlyOnceDelivery)
+		paths = append(paths, "enable_exactly_once_delivery")
+	}
+	if cfg.BigQueryConfig != nil {
+		setBigQueryConfig(psub, cfg.BigQueryConfig)
+		paths = append(paths, "bigquery_config")
+	}
+	if cfg.CloudStorageConfig != nil {
+		setCloudStorageConfig(psub, cfg.CloudStorageConfig)
+		paths = append(paths, "cloud_storage_config")
+	}
 	return &pb.UpdateSubscriptionRequest{
 		Subscription: psub,
 		UpdateMask:   &fmpb.FieldMask{Paths: paths},
@@ -829,18 +1031,75 @@ var errReceiveInProgress = errors.New("pubsub: Receive already in progress for t
 // automatically extend the ack deadline of all fetched Messages up to the
 // period specified by s.ReceiveSettings.MaxExtension.
 //
+// If the subscription has exactly-once delivery enabled, Receive does not
+// deliver messages whose receipt extension fails because their ack ID is no
+// longer valid, and stops extending the ack deadline of messages whose
+// extension fails that way, as the service has already made them available
+// for redelivery.
+//
+// If the client has receive interceptors, Receive calls them with each
+// message first, and calls f with the message they pass on.
+//
 // Each Subscription may have only one invocation of Receive active at a time.
 func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Message)) error {
+	_, err := s.receive(ctx, nil, f)
+	return err
+}
+
+// DrainStats counts the messages that were outstanding when ReceiveWithDrain
+// stopped pulling messages, and those it received afterwards, by how they were
+// done.
+type DrainStats struct {
+	// Acked is the number of messages acked.
+	Acked int
+
+	// Nacked is the number of messages nacked, by the callbacks or, for
+	// messages that arrived after ReceiveWithDrain stopped pulling, by
+	// ReceiveWithDrain itself.
+	Nacked int
+
+	// Expired is the number of messages that were neither acked nor nacked
+	// within ReceiveSettings.DrainTimeout. ReceiveWithDrain stops extending
+	// their ack deadlines, so they are redelivered once the deadlines pass.
+	Expired int
+}
+
+// ReceiveWithDrain is like Receive, but it drains the subscriber when stop is
+// done, for example when the process is asked to terminate: it stops pulling
+// messages, without canceling the context passed to f, and returns once the
+// outstanding calls to f have returned and their acks and nacks have been
+// sent. It waits for the outstanding messages for at most
+// s.ReceiveSettings.DrainTimeout; then it cancels the context passed to f and
+// leaves the messages that are still outstanding to expire.
+//
+// Canceling ctx stops ReceiveWithDrain as it stops Receive, except that the
+// wait for outstanding messages is also bounded by DrainTimeout.
+//
+// The returned DrainStats count how the messages outstanding when
+// ReceiveWithDrain stopped pulling were done.
+func (s *Subscription) ReceiveWithDrain(ctx, stop context.Context, f func(context.Context, *Message)) (DrainStats, error) {
+	return s.receive(ctx, stop, f)
+}
+
+// receive implements Receive and, if stop is not nil, ReceiveWithDrain.
+func (s *Subscription) receive(ctx, stop context.Context, f func(context.Context, *Message)) (DrainStats, error) {
 	s.mu.Lock()
 	if s.receiveActive {
 		s.mu.Unlock()
-		return errReceiveInProgress
+		return DrainStats{}, errReceiveInProgress
 	}
 	s.receiveActive = true
 	s.mu.Unlock()
 	defer func() { s.mu.Lock(); s.receiveActive = false; s.mu.Unlock() }()
 
-	s.checkOrdering(ctx)
+	if len(s.c.receiveInterceptors) > 0 {
+		f = chainReceiveInterceptors(s.c.receiveInterceptors, f)
+	}
+
+	if err := s.c.autoCreate.subscription(ctx, s.name); err != nil {
+		return DrainStats{}, err
+	}
+	s.checkConfig(ctx)
 
 	maxCount := s.ReceiveSettings.MaxOutstandingMessages
 	if maxCount == 0 {
@@ -861,6 +1120,14 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 	if maxExtPeriod < 0 {
 		maxExtPeriod = 0
 	}
+	minExtPeriod := s.ReceiveSettings.MinExtensionPeriod
+	if minExtPeriod < 0 {
+		minExtPeriod = 0
+	}
+	drainTimeout := s.ReceiveSettings.DrainTimeout
+	if drainTimeout == 0 {
+		drainTimeout = DefaultReceiveSettings.DrainTimeout
+	}
 
 	var numGoroutines int
 	switch {
@@ -875,11 +1142,13 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 	po := &pullOptions{
 		maxExtension:           maxExt,
 		maxExtensionPeriod:     maxExtPeriod,
+		minExtensionPeriod:     minExtPeriod,
 		maxPrefetch:            trunc32(int64(maxCount)),
 		synchronous:            s.ReceiveSettings.Synchronous,
 		maxOutstandingMessages: maxCount,
 		maxOutstandingBytes:    maxBytes,
 		useLegacyFlowControl:   s.ReceiveSettings.UseLegacyFlowControl,
+		exactlyOnceDelivery:    s.enableExactlyOnceDelivery,
 	}
 	fc := newFlowController(FlowControlSettings{
 		MaxOutstandingMessages: maxCount,
@@ -888,6 +1157,30 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 	})
 
 	sched := scheduler.NewReceiveScheduler(maxCount)
+	if s.ReceiveSettings.MaxOrderingKeys > 0 {
+		sched.MaxKeys = s.ReceiveSettings.MaxOrderingKeys
+	}
+	if s.ReceiveSettings.MaxMessagesPerOrderingKey > 0 {
+		sched.MaxItemsPerKey = s.ReceiveSettings.MaxMessagesPerOrderingKey
+	}
+
+	// pullCtx is done when Receive should stop pulling messages: when ctx is
+	// done or, when draining, when stop is.
+	pullCtx := ctx
+	var dc *drainCounter
+	if stop != nil {
+		var cancelPull context.CancelFunc
+		pullCtx, cancelPull = context.WithCancel(ctx)
+		defer cancelPull()
+		go func() {
+			select {
+			case <-stop.Done():
+				cancelPull()
+			case <-pullCtx.Done():
+			}
+		}()
+		dc = &drainCounter{}
+	}
 
 	// Wait for all goroutines started by Receive to return, so instead of an
 	// obscure goroutine leak we have an obvious blocked call to Receive.
@@ -899,6 +1192,8 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 	}
 
 	var pairs []closeablePair
+	rp := &receivePause{fc: &fc}
+	rm := &receiveMetrics{sched: sched}
 
 	// Cancel a sub-context which, when we finish a single receiver, will kick
 	// off the context-aware callbacks and the goroutine below (which stops
@@ -906,11 +1201,30 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 	ctx2, cancel2 := context.WithCancel(gctx)
 	defer cancel2()
 
+	// cbCtx is the context passed to f. When draining, it is not canceled
+	// when the receivers stop, so that the callbacks can finish, but only when
+	// the drain times out.
+	cbCtx, cancelCb := ctx2, cancel2
+	if dc != nil {
+		cbCtx, cancelCb = context.WithCancel(gctx)
+		defer cancelCb()
+		// Stop the receivers, which may be blocked waiting for messages.
+		go func() {
+			select {
+			case <-pullCtx.Done():
+				cancel2()
+			case <-ctx2.Done():
+			}
+		}()
+	}
+
 	for i := 0; i < numGoroutines; i++ {
 		// The iterator does not use the context passed to Receive. If it did,
 		// canceling that context would immediately stop the iterator without
 		// waiting for unacked messages.
 		iter := newMessageIterator(s.c.subc, s.name, po)
+		rp.iters = append(rp.iters, iter)
+		rm.iters = append(rm.iters, iter)
 
 		// We cannot use errgroup from Receive here. Receive might already be
 		// calling group.Wait, and group.Wait cannot be called concurrently with
@@ -941,7 +1255,7 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 						maxToPull = po.maxPrefetch - int32(fc.count())
 						if maxToPull <= 0 {
 							// Wait for some callbacks to finish.
-							if err := gax.Sleep(ctx, synchronousWaitTime); err != nil {
+							if err := gax.Sleep(pullCtx, synchronousWaitTime); err != nil {
 								// Return nil if the context is done, not err.
 								return nil
 							}
@@ -949,9 +1263,13 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 						}
 					}
 				}
+				// While ReplaySince seeks, don't pull messages.
+				if err := rp.wait(pullCtx); err != nil {
+					return nil
+				}
 				// If the context is done, don't pull more messages.
 				select {
-				case <-ctx.Done():
+				case <-pullCtx.Done():
 					return nil
 				default:
 				}
@@ -962,33 +1280,53 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 				if err != nil {
 					return err
 				}
+				// Messages pulled before a seek may be redelivered after it,
+				// so nack them rather than have their acks undo the seek.
+				if rp.paused() {
+					for _, m := range msgs {
+						m.Nack()
+					}
+					continue
+				}
 				// If context is done and messages have been pulled,
 				// nack them.
 				select {
-				case <-ctx.Done():
+				case <-pullCtx.Done():
 					for _, m := range msgs {
 						m.Nack()
 					}
+					dc.nacked(len(msgs))
 					return nil
 				default:
 				}
 				for i, msg := range msgs {
 					msg := msg
 					// TODO(jba): call acquire closer to when the message is allocated.
-					if err := fc.acquire(ctx, len(msg.Data)); err != nil {
+					if err := fc.acquire(pullCtx, len(msg.Data)); err != nil {
 						// TODO(jba): test that these "orphaned" messages are nacked immediately when ctx is done.
 						for _, m := range msgs[i:] {
 							m.Nack()
 						}
+						dc.nacked(len(msgs) - i)
 						// Return nil if the context is done, not err.
 						return nil
 					}
 					ackh, _ := msgAckHandler(msg)
 					old := ackh.doneFunc
 					msgLen := len(msg.Data)
-					ackh.doneFunc = func(ackID string, ack bool, receiveTime time.Time) {
+					msgCtx := cbCtx
+					var span trace.Span
+					if s.c.tracer != nil {
+						msgCtx, span = startReceiveSpan(cbCtx, s.c.tracer, s.name, msg)
+					}
+					dc.add()
+					rm.acquire(msgLen)
+					ackh.doneFunc = func(ackID string, ack bool, r *AckResult, receiveTime time.Time) {
 						defer fc.release(ctx, msgLen)
-						old(ackID, ack, receiveTime)
+						defer rm.release(msgLen)
+						dc.done(ack, pullCtx.Err() != nil)
+						endReceiveSpan(span, ack, receiveTime)
+						old(ackID, ack, r, receiveTime)
 					}
 					wg.Add(1)
 					// Make sure the subscription has ordering enabled before adding to scheduler.
@@ -1000,7 +1338,9 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 					// constructor level?
 					if err := sched.Add(key, msg, func(msg interface{}) {
 						defer wg.Done()
-						f(ctx2, msg.(*Message))
+						rm.callback(1)
+						defer rm.callback(-1)
+						f(msgCtx, msg.(*Message))
 					}); err != nil {
 						wg.Done()
 						return err
@@ -1010,9 +1350,26 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 		})
 	}
 
+	s.mu.Lock()
+	s.pause = rp
+	s.metrics = rm
+	s.mu.Unlock()
+	defer func() { s.mu.Lock(); s.pause, s.metrics = nil, nil; s.mu.Unlock() }()
+
 	go func() {
 		<-ctx2.Done()
 
+		if dc != nil && drainTimeout > 0 {
+			t := time.AfterFunc(drainTimeout, func() {
+				dc.expire()
+				for _, p := range pairs {
+					p.iter.expire()
+				}
+				cancelCb()
+			})
+			defer t.Stop()
+		}
+
 		// Wait for all iterators to stop.
 		for _, p := range pairs {
 			p.iter.stop()
@@ -1025,26 +1382,329 @@ func (s *Subscription) Receive(ctx context.Context, f func(context.Context, *Mes
 		sched.Shutdown()
 	}()
 
-	return group.Wait()
+	err := group.Wait()
+	return dc.stats(), err
+}
+
+// receivePause pauses the receivers of a Receive while ReplaySince seeks the
+// subscription.
+type receivePause struct {
+	fc    *flowController
+	iters []*messageIterator
+
+	mu      sync.Mutex
+	resumed chan struct{} // while paused, closed on resume; nil otherwise
+}
+
+// pause stops the receivers from pulling messages, until resume is called.
+func (p *receivePause) pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed == nil {
+		p.resumed = make(chan struct{})
+	}
+}
+
+func (p *receivePause) resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+}
+
+func (p *receivePause) paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resumed != nil
+}
+
+// wait blocks while the receivers are paused, or until ctx is done.
+func (p *receivePause) wait(ctx context.Context) error {
+	p.mu.Lock()
+	resumed := p.resumed
+	p.mu.Unlock()
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// quiesce waits until all messages delivered to the callbacks are done and
+// their acks and nacks sent, or until ctx is done.
+func (p *receivePause) quiesce(ctx context.Context) error {
+	for {
+		idle := p.fc.count() == 0
+		for _, it := range p.iters {
+			idle = idle && it.acksSent()
+		}
+		if idle {
+			return nil
+		}
+		if err := gax.Sleep(ctx, synchronousWaitTime); err != nil {
+			return err
+		}
+	}
+}
+
+// drainCounter counts how the messages of ReceiveWithDrain are done once it
+// stops pulling. The methods of a nil *drainCounter do nothing.
+type drainCounter struct {
+	mu          sync.Mutex
+	outstanding int
+	expired     bool
+	s           DrainStats
+}
+
+// add records that a message is outstanding.
+func (d *drainCounter) add() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.outstanding++
+}
+
+// done records that an outstanding message was acked or nacked, and counts it
+// if Receive was draining.
+func (d *drainCounter) done(ack, draining bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.outstanding--
+	if d.expired || !draining {
+		return
+	}
+	if ack {
+		d.s.Acked++
+	} else {
+		d.s.Nacked++
+	}
+}
+
+// nacked counts n messages that Receive nacked without delivering them.
+func (d *drainCounter) nacked(n int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.expired {
+		d.s.Nacked += n
+	}
+}
+
+// expire counts the outstanding messages as expired. Messages done later are
+// not counted.
+func (d *drainCounter) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.expired {
+		d.expired = true
+		d.s.Expired = d.outstanding
+	}
+}
+
+func (d *drainCounter) stats() DrainStats {
+	if d == nil {
+		return DrainStats{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.s
+}
+
+// PullOptions configure PullN. A nil *PullOptions is equivalent to a zero
+// PullOptions.
+type PullOptions struct {
+	// AckDeadline, if positive, is the ack deadline that PullN sets for the
+	// messages it returns, instead of the ack deadline of the subscription. It
+	// must be between 10 seconds and 10 minutes.
+	AckDeadline time.Duration
+}
+
+// PullN pulls up to n messages from the subscription with a single Pull RPC,
+// without starting the goroutines of Receive. It is meant for batch workers,
+// scheduled jobs and command-line tools that process messages in a loop of
+// their own.
+//
+// PullN blocks until at least one message is available or the service returns
+// no messages after waiting for some time, so it may return fewer than n
+// messages, or none. It returns when ctx is done.
+//
+// The caller must call Ack or Nack on each message returned. Each call sends
+// its own Acknowledge or ModifyAckDeadline RPC in the background; use
+// AckWithResult or NackWithResult to wait for it. PullN does not extend the ack
+// deadline of the messages, so messages that are not acked before it expires
+// are redelivered. PullN does not use ReceiveSettings.
+//
+// If the client has receive interceptors, PullN calls them with each message,
+// and returns the messages they pass on.
+func (s *Subscription) PullN(ctx context.Context, n int, opts *PullOptions) ([]*Message, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("pubsub: PullN: n is %d, should be positive", n)
+	}
+	var ackDeadline time.Duration
+	if opts != nil {
+		ackDeadline = opts.AckDeadline
+	}
+	if ackDeadline > 0 && (ackDeadline < 10*time.Second || ackDeadline > maxAckDeadline) {
+		return nil, fmt.Errorf("pubsub: PullN: AckDeadline is %v, should be between 10s and %v", ackDeadline, maxAckDeadline)
+	}
+	if err := s.c.autoCreate.subscription(ctx, s.name); err != nil {
+		return nil, err
+	}
+	ctx = withSubscriptionKey(ctx, s.name)
+	res, err := s.c.subc.Pull(ctx, &pb.PullRequest{
+		Subscription: s.name,
+		MaxMessages:  trunc32(int64(n)),
+	}, gax.WithGRPCOptions(grpc.MaxCallRecvMsgSize(maxSendRecvBytes)))
+	if err != nil {
+		return nil, err
+	}
+	recordStat(ctx, PullCount, int64(len(res.ReceivedMessages)))
+	msgs, err := convertMessages(res.ReceivedMessages, time.Now(), s.pulledMessageDone)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range msgs {
+		// Set the results of acks and nacks once the service has replied,
+		// whether or not the subscription has exactly-once delivery enabled.
+		if ah, ok := msgAckHandler(m); ok {
+			ah.exactlyOnceDelivery = true
+			ah.lease = pulledLease{s}
+			if s.c.tracer != nil {
+				_, span := startReceiveSpan(ctx, s.c.tracer, s.name, m)
+				ah.doneFunc = func(ackID string, ack bool, r *AckResult, receiveTime time.Time) {
+					endReceiveSpan(span, ack, receiveTime)
+					s.pulledMessageDone(ackID, ack, r, receiveTime)
+				}
+			}
+		}
+	}
+	if ackDeadline > 0 && len(msgs) > 0 {
+		ackIDs := make([]string, len(msgs))
+		for i, m := range msgs {
+			ackIDs[i] = msgAckID(m)
+		}
+		recordStat(ctx, ModAckCount, int64(len(ackIDs)))
+		if err := s.c.subc.ModifyAckDeadline(ctx, &pb.ModifyAckDeadlineRequest{
+			Subscription:       s.name,
+			AckDeadlineSeconds: int32(ackDeadline / time.Second),
+			AckIds:             ackIDs,
+		}); err != nil {
+			// The messages will be redelivered when their ack deadline expires.
+			return nil, err
+		}
+	}
+	if len(s.c.receiveInterceptors) > 0 {
+		var intercepted []*Message
+		h := chainReceiveInterceptors(s.c.receiveInterceptors, func(_ context.Context, m *Message) {
+			intercepted = append(intercepted, m)
+		})
+		for _, m := range msgs {
+			h(ctx, m)
+		}
+		msgs = intercepted
+	}
+	return msgs, nil
+}
+
+// pulledLease manages the lease of the messages returned by PullN, which are
+// not extended automatically.
+type pulledLease struct {
+	s *Subscription
+}
+
+func (l pulledLease) modifyAckDeadline(ctx context.Context, ackID string, d time.Duration) error {
+	ctx = withSubscriptionKey(ctx, l.s.name)
+	recordStat(ctx, ModAckCount, 1)
+	return l.s.c.subc.ModifyAckDeadline(ctx, &pb.ModifyAckDeadlineRequest{
+		Subscription:       l.s.name,
+		AckDeadlineSeconds: int32(d / time.Second),
+		AckIds:             []string{ackID},
+	})
+}
+
+func (pulledLease) setMaxExtension(string, time.Time) error {
+	return errors.New("pubsub: the ack deadlines of messages from PullN are not extended automatically")
+}
+
+// pulledMessageDone is the done function of the messages returned by PullN. It
+// acks or nacks a message in a goroutine and sets r, if not nil, once the
+// service has replied.
+func (s *Subscription) pulledMessageDone(ackID string, ack bool, r *AckResult, _ time.Time) {
+	go func() {
+		// Use context.Background() as the call's context, not the context
+		// passed to PullN, which may be done by the time the message is acked.
+		ctx, cancel := context.WithTimeout(withSubscriptionKey(context.Background(), s.name), time.Minute)
+		defer cancel()
+		bo := gax.Backoff{
+			Initial:    100 * time.Millisecond,
+			Max:        time.Second,
+			Multiplier: 2,
+		}
+		ids := []string{ackID}
+		results := map[string]*AckResult{ackID: r}
+		for {
+			var err error
+			if ack {
+				recordStat(ctx, AckCount, 1)
+				err = s.c.subc.Acknowledge(ctx, &pb.AcknowledgeRequest{
+					Subscription: s.name,
+					AckIds:       ids,
+				})
+			} else {
+				recordStat(ctx, NackCount, 1)
+				err = s.c.subc.ModifyAckDeadline(ctx, &pb.ModifyAckDeadlineRequest{
+					Subscription:       s.name,
+					AckDeadlineSeconds: 0,
+					AckIds:             ids,
+				})
+			}
+			if ids, _ = processResults(err, ids, results); len(ids) == 0 {
+				return
+			}
+			if err := gax.Sleep(ctx, bo.Pause()); err != nil {
+				setAckResults(ids, results, AcknowledgeStatusOther, err)
+				return
+			}
+		}
+	}()
 }
 
-// checkOrdering calls Config to check theEnableMessageOrdering field.
+// checkConfig calls Config to check the EnableMessageOrdering and
+// EnableExactlyOnceDelivery fields.
 // If this call fails (e.g. because the service account doesn't have
 // the roles/viewer or roles/pubsub.viewer role) we will assume
 // EnableMessageOrdering to be true.
 // See: https://github.com/googleapis/google-cloud-go/issues/3884
-func (s *Subscription) checkOrdering(ctx context.Context) {
+// We will also assume EnableExactlyOnceDelivery to be false; streaming pulls
+// learn the actual value from the service.
+func (s *Subscription) checkConfig(ctx context.Context) {
 	cfg, err := s.Config(ctx)
 	if err != nil {
 		s.enableOrdering = true
+		s.enableExactlyOnceDelivery = false
 	} else {
 		s.enableOrdering = cfg.EnableMessageOrdering
+		s.enableExactlyOnceDelivery = cfg.EnableExactlyOnceDelivery
 	}
 }
 
 type pullOptions struct {
 	maxExtension       time.Duration // the maximum time to extend a message's ack deadline in total
 	maxExtensionPeriod time.Duration // the maximum time to extend a message's ack deadline per modack rpc
+	minExtensionPeriod time.Duration // the minimum time to extend a message's ack deadline per modack rpc
 	maxPrefetch        int32         // the max number of outstanding messages, used to calculate maxToPull
 	// If true, use unary Pull instead of StreamingPull, and never pull more
 	// than maxPrefetch messages.
@@ -1052,4 +1712,7 @@ type pullOptions struct {
 	maxOutstandingMessages int
 	maxOutstandingBytes    int
 	useLegacyFlowControl   bool
+	// The initial exactly-once delivery setting of the subscription. Streaming
+	// pulls update it from the service's responses.
+	exactlyOnceDelivery bool
 }
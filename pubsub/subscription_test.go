@@ -15,12 +15,17 @@
 package pubsub
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/iam"
 	"cloud.google.com/go/internal/testutil"
+	"cloud.google.com/go/pubsub/internal/wirefield"
 	"cloud.google.com/go/pubsub/pstest"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/api/iterator"
@@ -29,6 +34,8 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -197,10 +204,11 @@ func TestUpdateSubscription(t *testing.T) {
 	}
 
 	got, err := sub.Update(ctx, SubscriptionConfigToUpdate{
-		AckDeadline:         20 * time.Second,
-		RetainAckedMessages: true,
-		Labels:              map[string]string{"label": "value"},
-		ExpirationPolicy:    72 * time.Hour,
+		AckDeadline:               20 * time.Second,
+		RetainAckedMessages:       true,
+		Labels:                    map[string]string{"label": "value"},
+		ExpirationPolicy:          72 * time.Hour,
+		EnableExactlyOnceDelivery: true,
 		PushConfig: &PushConfig{
 			Endpoint: "https://example.com/push",
 			AuthenticationMethod: &OIDCToken{
@@ -213,12 +221,13 @@ func TestUpdateSubscription(t *testing.T) {
 		t.Fatal(err)
 	}
 	want = SubscriptionConfig{
-		Topic:               topic,
-		AckDeadline:         20 * time.Second,
-		RetainAckedMessages: true,
-		RetentionDuration:   defaultRetentionDuration,
-		Labels:              map[string]string{"label": "value"},
-		ExpirationPolicy:    72 * time.Hour,
+		Topic:                     topic,
+		AckDeadline:               20 * time.Second,
+		RetainAckedMessages:       true,
+		RetentionDuration:         defaultRetentionDuration,
+		Labels:                    map[string]string{"label": "value"},
+		ExpirationPolicy:          72 * time.Hour,
+		EnableExactlyOnceDelivery: true,
 		PushConfig: PushConfig{
 			Endpoint: "https://example.com/push",
 			AuthenticationMethod: &OIDCToken{
@@ -435,3 +444,735 @@ func TestOrdering_CreateSubscription(t *testing.T) {
 		msg.Ack()
 	})
 }
+
+func TestExactlyOnceDelivery_Receive(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{
+		Topic:                     topic,
+		EnableExactlyOnceDelivery: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.EnableExactlyOnceDelivery {
+		t.Fatalf("Expected EnableExactlyOnceDelivery to be true in %s", sub.String())
+	}
+	srv.Publish(topic.name, []byte("hello"), nil)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var (
+		ackStatus AcknowledgeStatus
+		ackErr    error
+	)
+	err = sub.Receive(ctx, func(ctx context.Context, msg *Message) {
+		ackStatus, ackErr = AckWithResult(msg).Get(ctx)
+		cancel()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ackStatus != AcknowledgeStatusSuccess || ackErr != nil {
+		t.Errorf("got %v, %v; want success", ackStatus, ackErr)
+	}
+	if msgs := srv.Messages(); len(msgs) != 1 || msgs[0].Acks != 1 {
+		t.Errorf("got %+v, want one message acked once", msgs)
+	}
+}
+
+func TestPullN(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		srv.Publish(topic.name, []byte{byte(i)}, nil)
+	}
+
+	msgs, err := sub.PullN(ctx, 2, &PullOptions{AckDeadline: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	for _, m := range msgs {
+		if st, err := AckWithResult(m).Get(ctx); st != AcknowledgeStatusSuccess || err != nil {
+			t.Errorf("ack: got %v, %v; want success", st, err)
+		}
+	}
+
+	// A nacked message is redelivered.
+	msgs, err = sub.PullN(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	data := msgs[0].Data
+	if st, err := NackWithResult(msgs[0]).Get(ctx); st != AcknowledgeStatusSuccess || err != nil {
+		t.Errorf("nack: got %v, %v; want success", st, err)
+	}
+	msgs, err = sub.PullN(ctx, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || !bytes.Equal(msgs[0].Data, data) {
+		t.Fatalf("got %v, want the nacked message", msgs)
+	}
+	if _, err := AckWithResult(msgs[0]).Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var acks int
+	for _, m := range srv.Messages() {
+		acks += m.Acks
+	}
+	if acks != 3 {
+		t.Errorf("got %d acks, want 3", acks)
+	}
+	var deadlines []int32
+	for _, m := range srv.Messages() {
+		for _, ma := range m.Modacks {
+			deadlines = append(deadlines, ma.AckDeadline)
+		}
+	}
+	sort.Slice(deadlines, func(i, j int) bool { return deadlines[i] < deadlines[j] })
+	if diff := testutil.Diff(deadlines, []int32{0, 30, 30}); diff != "" {
+		t.Errorf("modack deadlines: got=-, want=+:\n%s", diff)
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err := sub.PullN(ctx, n, nil); err == nil {
+			t.Errorf("n = %d: got nil, want error", n)
+		}
+	}
+	if _, err := sub.PullN(ctx, 1, &PullOptions{AckDeadline: time.Second}); err == nil {
+		t.Error("AckDeadline of 1s: got nil, want error")
+	}
+}
+
+func TestModifyAckDeadline(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Publish(topic.name, []byte("hello"), nil)
+
+	rctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var errs []error
+	err = sub.Receive(rctx, func(ctx context.Context, m *Message) {
+		defer cancel()
+		errs = append(errs,
+			SetMaxExtension(m, time.Minute),
+			ModifyAckDeadline(ctx, m, 45*time.Second))
+		if err := SetMaxExtension(m, time.Minute); err == nil {
+			t.Error("SetMaxExtension of a manually leased message: got nil, want error")
+		}
+		if err := ModifyAckDeadline(ctx, m, 11*time.Minute); err == nil {
+			t.Error("ModifyAckDeadline of 11m: got nil, want error")
+		}
+		m.Ack()
+		if err := ModifyAckDeadline(ctx, m, time.Minute); err == nil {
+			t.Error("ModifyAckDeadline of an acked message: got nil, want error")
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	msgs := srv.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	var found bool
+	for _, ma := range msgs[0].Modacks {
+		found = found || ma.AckDeadline == 45
+	}
+	if !found {
+		t.Errorf("got modacks %+v, want one of 45s", msgs[0].Modacks)
+	}
+
+	// Messages from PullN can be leased manually, but are not extended.
+	srv.Publish(topic.name, []byte("hello"), nil)
+	pulled, err := sub.PullN(ctx, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ModifyAckDeadline(ctx, pulled[0], time.Minute); err != nil {
+		t.Error(err)
+	}
+	if err := SetMaxExtension(pulled[0], time.Minute); err == nil {
+		t.Error("SetMaxExtension of a pulled message: got nil, want error")
+	}
+	pulled[0].Ack()
+
+	if err := ModifyAckDeadline(ctx, &Message{}, time.Minute); err == nil {
+		t.Error("ModifyAckDeadline of a published message: got nil, want error")
+	}
+}
+
+func TestReceiveStatus(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sub.ReceiveStatus(); got != (ReceiveStatus{}) {
+		t.Errorf("before Receive: got %+v, want zero", got)
+	}
+	for i := 0; i < 3; i++ {
+		srv.Publish(topic.name, []byte("hello"), nil)
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	sub.ReceiveSettings.NumGoroutines = 1
+	received := make(chan *Message)
+	release := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sub.Receive(rctx, func(ctx context.Context, m *Message) {
+			// Don't block forever if the test fails before receiving or
+			// releasing the message.
+			select {
+			case received <- m:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+		})
+	}()
+	var msgs []*Message
+	for len(msgs) < 3 {
+		select {
+		case m := <-received:
+			msgs = append(msgs, m)
+		case <-rctx.Done():
+			t.Fatal("timed out waiting for messages")
+		}
+	}
+	got := sub.ReceiveStatus()
+	want := ReceiveStatus{Active: true, OutstandingMessages: 3, OutstandingBytes: 15, OpenStreams: 1, ActiveCallbacks: 3}
+	// The initial modacks of the messages may or may not have been sent.
+	got.ModAcks = 0
+	if got != want {
+		t.Errorf("while processing: got %+v, want %+v", got, want)
+	}
+
+	close(release)
+	msgs[0].Nack()
+	for _, m := range msgs[1:] {
+		m.Ack()
+	}
+	for {
+		got = sub.ReceiveStatus()
+		if got.Acks == 2 && got.Nacks == 1 {
+			break
+		}
+		if rctx.Err() != nil {
+			t.Fatalf("got %+v, want 2 acks and 1 nack", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got.OutstandingMessages != 0 || got.OutstandingBytes != 0 || got.ModAcks < 3 {
+		t.Errorf("after acking: got %+v", got)
+	}
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Receive to return")
+	}
+	if got := sub.ReceiveStatus(); got != (ReceiveStatus{}) {
+		t.Errorf("after Receive: got %+v, want zero", got)
+	}
+}
+
+func TestReceiveStatus_orderingKeys(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic, EnableMessageOrdering: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	sub.ReceiveSettings.NumGoroutines = 1
+	sub.ReceiveSettings.MaxOrderingKeys = 1
+	release := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		errc <- sub.Receive(rctx, func(ctx context.Context, m *Message) {
+			select {
+			case <-release:
+				m.Ack()
+			case <-ctx.Done():
+			}
+		})
+	}()
+	waitStatus := func(want ReceiveStatus) {
+		t.Helper()
+		for {
+			rs := sub.ReceiveStatus()
+			got := ReceiveStatus{
+				OrderingKeys:               rs.OrderingKeys,
+				QueuedOrderedMessages:      rs.QueuedOrderedMessages,
+				HottestOrderingKey:         rs.HottestOrderingKey,
+				HottestOrderingKeyMessages: rs.HottestOrderingKeyMessages,
+				OrderingKeyWaits:           rs.OrderingKeyWaits,
+			}
+			if got == want {
+				return
+			}
+			if rctx.Err() != nil {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// The fake delivers messages in random order, so publish the messages
+	// of "cold" once those of "hot" are being processed.
+	for i := 0; i < 3; i++ {
+		srv.PublishOrdered(topic.name, []byte("hello"), nil, "hot")
+	}
+	waitStatus(ReceiveStatus{
+		OrderingKeys:               1,
+		QueuedOrderedMessages:      2,
+		HottestOrderingKey:         "hot",
+		HottestOrderingKeyMessages: 2,
+	})
+	// "cold" waits for its turn.
+	srv.PublishOrdered(topic.name, []byte("hello"), nil, "cold")
+	waitStatus(ReceiveStatus{
+		OrderingKeys:               2,
+		QueuedOrderedMessages:      3,
+		HottestOrderingKey:         "hot",
+		HottestOrderingKeyMessages: 2,
+		OrderingKeyWaits:           1,
+	})
+
+	close(release)
+	var got ReceiveStatus
+	for {
+		got = sub.ReceiveStatus()
+		if got.Acks == 4 && got.OrderingKeys == 0 && got.QueuedOrderedMessages == 0 {
+			break
+		}
+		if rctx.Err() != nil {
+			t.Fatalf("got %+v, want 4 acks and no ordering keys", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Receive to return")
+	}
+}
+
+func TestEnableDeadLettering(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dlt := client.Topic("dead-letter")
+	const member = "serviceAccount:service-123@gcp-sa-pubsub.iam.gserviceaccount.com"
+
+	for i := 0; i < 2; i++ {
+		cfg, err := sub.EnableDeadLettering(ctx, DeadLetteringConfig{
+			DeadLetterTopic:     dlt,
+			MaxDeliveryAttempts: 5 + i,
+			ProjectNumber:       123,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := &DeadLetterPolicy{DeadLetterTopic: dlt.name, MaxDeliveryAttempts: 5 + i}
+		if diff := testutil.Diff(cfg.DeadLetterPolicy, want); diff != "" {
+			t.Errorf("call %d: DeadLetterPolicy: got=-, want=+:\n%s", i, diff)
+		}
+		for _, tc := range []struct {
+			h    *iam.Handle
+			role iam.RoleName
+		}{
+			{dlt.IAM(), "roles/pubsub.publisher"},
+			{sub.IAM(), "roles/pubsub.subscriber"},
+		} {
+			p, err := tc.h.Policy(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := testutil.Diff(p.Members(tc.role), []string{member}); diff != "" {
+				t.Errorf("call %d: members of %s: got=-, want=+:\n%s", i, tc.role, diff)
+			}
+		}
+	}
+	if ok, err := dlt.Exists(ctx); err != nil || !ok {
+		t.Errorf("dead-letter topic exists: got %t, %v; want true, nil", ok, err)
+	}
+
+	for _, cfg := range []DeadLetteringConfig{
+		{MaxDeliveryAttempts: 5, ProjectNumber: 123},
+		{DeadLetterTopic: dlt, MaxDeliveryAttempts: 4, ProjectNumber: 123},
+		{DeadLetterTopic: dlt, MaxDeliveryAttempts: 101, ProjectNumber: 123},
+		{DeadLetterTopic: dlt},
+		{DeadLetterTopic: topic, ProjectNumber: 123},
+	} {
+		if _, err := sub.EnableDeadLettering(ctx, cfg); err == nil {
+			t.Errorf("%+v: got nil, want error", cfg)
+		}
+	}
+}
+
+func TestExportSubscriptionConfigs(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	bq := BigQueryConfig{
+		Table:             "project:dataset.table",
+		UseTopicSchema:    true,
+		WriteMetadata:     true,
+		DropUnknownFields: true,
+	}
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{
+		Topic:          topic,
+		BigQueryConfig: bq,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := testutil.Diff(cfg.BigQueryConfig, bq); diff != "" {
+		t.Errorf("BigQueryConfig: got=-, want=+:\n%s", diff)
+	}
+	if cfg.CloudStorageConfig != (CloudStorageConfig{}) {
+		t.Errorf("CloudStorageConfig: got %+v, want zero", cfg.CloudStorageConfig)
+	}
+
+	cs := CloudStorageConfig{
+		Bucket:                 "bucket",
+		FilenamePrefix:         "log_events_",
+		FilenameSuffix:         ".avro",
+		FilenameDatetimeFormat: "YYYY-MM-DD/hh_mm_ssZ",
+		OutputFormat:           &CloudStorageOutputFormatAvroConfig{WriteMetadata: true},
+		MaxDuration:            2*time.Minute + 500*time.Millisecond,
+		MaxBytes:               10e6,
+		MaxMessages:            1000,
+	}
+	got, err := sub.Update(ctx, SubscriptionConfigToUpdate{
+		BigQueryConfig:     &BigQueryConfig{},
+		CloudStorageConfig: &cs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.BigQueryConfig != (BigQueryConfig{}) {
+		t.Errorf("BigQueryConfig: got %+v, want zero", got.BigQueryConfig)
+	}
+	if diff := testutil.Diff(got.CloudStorageConfig, cs); diff != "" {
+		t.Errorf("CloudStorageConfig: got=-, want=+:\n%s", diff)
+	}
+
+	cs.OutputFormat = &CloudStorageOutputFormatTextConfig{}
+	got, err = sub.Update(ctx, SubscriptionConfigToUpdate{CloudStorageConfig: &cs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := testutil.Diff(got.CloudStorageConfig, cs); diff != "" {
+		t.Errorf("CloudStorageConfig: got=-, want=+:\n%s", diff)
+	}
+}
+
+func TestExportSubscriptionStates(t *testing.T) {
+	// A response with the states set, as the service encodes it.
+	b := protowire.AppendTag(nil, 18, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte{0x0a, 0x01, 't', 0x28, 0x04}) // table: "t", state: SCHEMA_MISMATCH
+	b = protowire.AppendTag(b, 19, protowire.VarintType)
+	b = protowire.AppendVarint(b, 2) // state: RESOURCE_ERROR
+	b = protowire.AppendTag(b, 22, protowire.BytesType)
+	b = protowire.AppendBytes(b, []byte{0x0a, 0x01, 'b', 0x48, 0x02}) // bucket: "b", state: PERMISSION_DENIED
+	psub := &pb.Subscription{Name: "projects/p/subscriptions/s"}
+	if err := proto.Unmarshal(b, psub); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := protoToSubscriptionConfig(psub, &Client{projectID: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.State, SubscriptionStateResourceError; got != want {
+		t.Errorf("State: got %v, want %v", got, want)
+	}
+	if got, want := cfg.BigQueryConfig, (BigQueryConfig{Table: "t", State: BigQueryConfigSchemaMismatch}); got != want {
+		t.Errorf("BigQueryConfig: got %+v, want %+v", got, want)
+	}
+	if got, want := cfg.CloudStorageConfig, (CloudStorageConfig{Bucket: "b", State: CloudStorageConfigPermissionDenied}); got != want {
+		t.Errorf("CloudStorageConfig: got %+v, want %+v", got, want)
+	}
+
+	// Output-only states are not sent back.
+	p := cfg.toProto(psub.Name)
+	if _, ok := wirefield.Varint(p.ProtoReflect(), 19); ok {
+		t.Error("toProto: got subscription state, want none")
+	}
+	bc, cc, _ := exportConfigs(p)
+	if bc.State != BigQueryConfigStateUnspecified || cc.State != CloudStorageConfigStateUnspecified {
+		t.Errorf("toProto: got states %v and %v, want none", bc.State, cc.State)
+	}
+}
+
+func TestReceiveWithDrain(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		srv.Publish(topic.name, []byte{byte('a' + i)}, nil)
+	}
+
+	// Stop once all messages are outstanding. The callbacks finish after
+	// that, with their context not canceled: two ack and one nacks.
+	// Pull synchronously, at most three messages at a time, so that no pull
+	// is in flight while draining: the fake redelivers the nacked message at
+	// once, and the drain would nack it again.
+	sub.ReceiveSettings.Synchronous = true
+	sub.ReceiveSettings.MaxOutstandingMessages = 3
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	stop, stopNow := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() { wg.Wait(); stopNow() }()
+	var mu sync.Mutex
+	var ctxErrs []error
+	stats, err := sub.ReceiveWithDrain(ctx, stop, func(ctx context.Context, m *Message) {
+		wg.Done()
+		<-stop.Done()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		ctxErrs = append(ctxErrs, ctx.Err())
+		mu.Unlock()
+		if m.Data[0] == 'c' {
+			m.Nack()
+		} else {
+			m.Ack()
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (DrainStats{Acked: 2, Nacked: 1}); stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+	for _, err := range ctxErrs {
+		if err != nil {
+			t.Errorf("callback context done while draining: %v", err)
+		}
+	}
+	acks := 0
+	for _, m := range srv.Messages() {
+		acks += m.Acks
+	}
+	if acks != 2 {
+		t.Errorf("got %d acks sent, want 2", acks)
+	}
+
+	// Callbacks that outlast DrainTimeout have their context canceled and
+	// their messages expire. Use a new subscription, with a single message.
+	sub2, err := client.CreateSubscription(ctx, "s2", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Publish(topic.name, []byte("d"), nil)
+	sub2.ReceiveSettings.DrainTimeout = 100 * time.Millisecond
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stop, stopNow = context.WithCancel(ctx)
+	expired := make(chan struct{})
+	start := time.Now()
+	stats, err = sub2.ReceiveWithDrain(ctx, stop, func(ctx context.Context, m *Message) {
+		stopNow()
+		<-ctx.Done()
+		close(expired)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-expired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback context not canceled")
+	}
+	if want := (DrainStats{Expired: 1}); stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("ReceiveWithDrain took %v", d)
+	}
+}
+
+func TestReplaySince(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	noRetention, err := client.CreateSubscription(ctx, "s1", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := noRetention.ReplaySince(ctx, time.Now().Add(-time.Minute)); err == nil {
+		t.Error("no retention: got nil, want error")
+	}
+	sub, err := client.CreateSubscription(ctx, "s2", SubscriptionConfig{
+		Topic:               topic,
+		RetainAckedMessages: true,
+		RetentionDuration:   time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tm := range []time.Time{time.Now().Add(time.Hour), time.Now().Add(-2 * time.Hour)} {
+		if err := sub.ReplaySince(ctx, tm); err == nil {
+			t.Errorf("%v: got nil, want error", tm)
+		}
+	}
+
+	start := time.Now()
+	srv.Publish(topic.name, []byte("a"), nil)
+	srv.Publish(topic.name, []byte("b"), nil)
+	rctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var mu sync.Mutex
+	var received []string
+	var ackedC time.Time
+	var startedC sync.Once
+	cStarted := make(chan struct{})
+	receiveDone := make(chan error, 1)
+	go func() {
+		receiveDone <- sub.Receive(rctx, func(_ context.Context, m *Message) {
+			if string(m.Data) == "c" {
+				// Still outstanding when ReplaySince is called.
+				startedC.Do(func() { close(cStarted) })
+				time.Sleep(200 * time.Millisecond)
+			}
+			m.Ack()
+			mu.Lock()
+			defer mu.Unlock()
+			if string(m.Data) == "c" && ackedC.IsZero() {
+				ackedC = time.Now()
+			}
+			received = append(received, string(m.Data))
+			if len(received) == 6 {
+				cancel()
+			}
+		})
+	}()
+	waitFor := func(n int) {
+		t.Helper()
+		for {
+			mu.Lock()
+			got := len(received)
+			mu.Unlock()
+			if got >= n {
+				return
+			}
+			if rctx.Err() != nil {
+				t.Fatalf("got %d messages, want %d", got, n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(2)
+	time.Sleep(200 * time.Millisecond) // for the acks to be sent
+
+	srv.Publish(topic.name, []byte("c"), nil)
+	select {
+	case <-cStarted:
+	case <-rctx.Done():
+		t.Fatal("message c not received")
+	}
+	if err := sub.ReplaySince(ctx, start); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if ackedC.IsZero() {
+		t.Error("ReplaySince returned before the outstanding message was acked")
+	}
+	mu.Unlock()
+	if err := <-receiveDone; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(received[:2])
+	sort.Strings(received[3:])
+	if diff := testutil.Diff(received, []string{"a", "b", "c", "a", "b", "c"}); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
@@ -32,11 +32,14 @@ import (
 	gax "github.com/googleapis/gax-go/v2"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/support/bundler"
 	pb "google.golang.org/genproto/googleapis/pubsub/v1"
 	fmpb "google.golang.org/genproto/protobuf/field_mask"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -75,6 +78,9 @@ type Topic struct {
 
 	// EnableMessageOrdering enables delivery of ordered keys.
 	EnableMessageOrdering bool
+
+	// validator, if not nil, validates messages before they are published.
+	validator *schemaValidator
 }
 
 // PublishSettings control the bundling of published messages.
@@ -108,8 +114,30 @@ type PublishSettings struct {
 	// Deprecated: Set `topic.PublishSettings.FlowControlSettings.MaxOutstandingBytes` instead.
 	BufferedByteLimit int
 
-	// FlowControlSettings defines publisher flow control settings.
+	// FlowControlSettings defines publisher flow control settings, which limit
+	// the messages that have been passed to Publish but not yet sent or failed
+	// to be sent. With FlowControlBlock, Publish blocks until the messages are
+	// within the limits or its context is done. With FlowControlSignalError,
+	// the result of Publish fails with ErrFlowControllerMaxOutstandingMessages
+	// or ErrFlowControllerMaxOutstandingBytes.
+	//
+	// Defaults to DefaultPublishSettings.FlowControlSettings, which disable
+	// flow control.
 	FlowControlSettings FlowControlSettings
+
+	// EnableCompression enables gzip compression of the Publish requests of
+	// batches whose messages total at least CompressionBytesThreshold bytes,
+	// which reduces egress for large, compressible payloads such as JSON.
+	// The requests are compressed by gRPC and decompressed by the service, so
+	// subscribers receive the messages as published.
+	EnableCompression bool
+
+	// CompressionBytesThreshold is the size of a batch in bytes above which
+	// it is compressed, if EnableCompression is set. Compressing small
+	// batches costs more than it saves.
+	//
+	// Defaults to DefaultPublishSettings.CompressionBytesThreshold.
+	CompressionBytesThreshold int
 }
 
 // DefaultPublishSettings holds the default values for topics' PublishSettings.
@@ -127,6 +155,7 @@ var DefaultPublishSettings = PublishSettings{
 		MaxOutstandingBytes:    -1,
 		LimitExceededBehavior:  FlowControlIgnore,
 	},
+	CompressionBytesThreshold: 240,
 }
 
 // CreateTopic creates a new topic.
@@ -226,6 +255,16 @@ type TopicConfig struct {
 	//
 	// For more information, see https://cloud.google.com/pubsub/docs/replay-overview#topic_message_retention.
 	RetentionDuration optional.Duration
+
+	// IngestionDataSourceSettings configures the topic to ingest messages
+	// from a data source, such as Amazon Kinesis Data Streams or Cloud
+	// Storage. If nil, the topic does not ingest messages.
+	IngestionDataSourceSettings *IngestionDataSourceSettings
+
+	// This is an output-only field that indicates the state of the topic.
+	// If it is TopicStateIngestionResourceError, the State of the source of
+	// IngestionDataSourceSettings has the details.
+	State TopicState
 }
 
 // String returns the printable globally unique name for the topic config.
@@ -260,6 +299,7 @@ func (tc *TopicConfig) toProto() *pb.Topic {
 		SchemaSettings:           schemaSettingsToProto(tc.SchemaSettings),
 		MessageRetentionDuration: retDur,
 	}
+	setIngestionDataSourceSettings(pbt, tc.IngestionDataSourceSettings)
 	return pbt
 }
 
@@ -285,6 +325,10 @@ type TopicConfigToUpdate struct {
 	// If set to a negative value, this clears RetentionDuration from the topic.
 	// If nil, the retention duration remains unchanged.
 	RetentionDuration optional.Duration
+
+	// If non-nil, IngestionDataSourceSettings is changed. To stop ingesting
+	// messages, set it to &IngestionDataSourceSettings{}.
+	IngestionDataSourceSettings *IngestionDataSourceSettings
 }
 
 func protoToTopicConfig(pbt *pb.Topic) TopicConfig {
@@ -298,6 +342,7 @@ func protoToTopicConfig(pbt *pb.Topic) TopicConfig {
 	if pbt.GetMessageRetentionDuration() != nil {
 		tc.RetentionDuration = pbt.GetMessageRetentionDuration().AsDuration()
 	}
+	tc.IngestionDataSourceSettings, tc.State = ingestionConfig(pbt)
 	return tc
 }
 
@@ -362,6 +407,41 @@ func (t *Topic) Config(ctx context.Context) (TopicConfig, error) {
 	return protoToTopicConfig(pbt), nil
 }
 
+// EnableSchemaValidation makes Publish validate the data of each message
+// against the schema of the topic, which it reads with sc, before sending it.
+// The result of publishing a message whose data does not match the schema
+// fails with a *SchemaValidationError, and the message is not sent. Without
+// local validation, the service rejects the whole batch of messages that
+// contains an invalid message.
+//
+// The schema is read once, so if the schema settings of the topic change,
+// call EnableSchemaValidation again. It returns an error if the topic has no
+// schema.
+func (t *Topic) EnableSchemaValidation(ctx context.Context, sc *SchemaClient) error {
+	cfg, err := t.Config(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.SchemaSettings == nil || cfg.SchemaSettings.Schema == "" {
+		return fmt.Errorf("pubsub: topic %s has no schema", t.name)
+	}
+	pbs, err := sc.sc.GetSchema(ctx, &pb.GetSchemaRequest{
+		Name: cfg.SchemaSettings.Schema,
+		View: pb.SchemaView_FULL,
+	})
+	if err != nil {
+		return err
+	}
+	v, err := newSchemaValidator(protoToSchemaConfig(pbs), cfg.SchemaSettings.Encoding)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.validator = v
+	t.mu.Unlock()
+	return nil
+}
+
 // Update changes an existing topic according to the fields set in cfg. It returns
 // the new TopicConfig.
 func (t *Topic) Update(ctx context.Context, cfg TopicConfigToUpdate) (TopicConfig, error) {
@@ -396,6 +476,10 @@ func (t *Topic) updateRequest(cfg TopicConfigToUpdate) *pb.UpdateTopicRequest {
 		}
 		paths = append(paths, "message_retention_duration")
 	}
+	if cfg.IngestionDataSourceSettings != nil {
+		setIngestionDataSourceSettings(pt, cfg.IngestionDataSourceSettings)
+		paths = append(paths, "ingestion_data_source_settings")
+	}
 	return &pb.UpdateTopicRequest{
 		Topic:      pt,
 		UpdateMask: &fmpb.FieldMask{Paths: paths},
@@ -404,18 +488,7 @@ func (t *Topic) updateRequest(cfg TopicConfigToUpdate) *pb.UpdateTopicRequest {
 
 // Topics returns an iterator which returns all of the topics for the client's project.
 func (c *Client) Topics(ctx context.Context) *TopicIterator {
-	it := c.pubc.ListTopics(ctx, &pb.ListTopicsRequest{Project: c.fullyQualifiedProjectName()})
-	return &TopicIterator{
-		c:  c,
-		it: it,
-		next: func() (string, error) {
-			topic, err := it.Next()
-			if err != nil {
-				return "", err
-			}
-			return topic.Name, nil
-		},
-	}
+	return c.ListTopics(ctx, nil)
 }
 
 // TopicIterator is an iterator that returns a series of topics.
@@ -447,6 +520,13 @@ func (t *TopicIterator) NextConfig() (*TopicConfig, error) {
 	return &cfg, nil
 }
 
+// PageInfo supports pagination: set its MaxSize before calling Next to choose
+// the page size, and its Token to resume listing from a page. The iterator
+// cannot be used with iterator.NewPager.
+func (t *TopicIterator) PageInfo() *iterator.PageInfo {
+	return t.it.PageInfo()
+}
+
 // ID returns the unique identifier of the topic within its project.
 func (t *Topic) ID() string {
 	slash := strings.LastIndex(t.name, "/")
@@ -495,13 +575,26 @@ func (t *Topic) Subscriptions(ctx context.Context) *SubscriptionIterator {
 		Topic: t.name,
 	})
 	return &SubscriptionIterator{
-		c:    t.c,
-		next: it.Next,
+		c:        t.c,
+		next:     it.Next,
+		pageInfo: it.PageInfo,
 	}
 }
 
 var errTopicStopped = errors.New("pubsub: Stop has been called for this topic")
 
+// ErrPublishingPaused is the error of publishing a message with an ordering key
+// for which publishing is paused, because an earlier message with the key
+// failed to be published. Call Topic.ResumePublish to publish messages with the
+// key again.
+type ErrPublishingPaused struct {
+	OrderingKey string
+}
+
+func (e ErrPublishingPaused) Error() string {
+	return fmt.Sprintf("pubsub: Publishing for ordering key, %s, paused due to previous error. Call topic.ResumePublish(orderingKey) before resuming publishing", e.OrderingKey)
+}
+
 // A PublishResult holds the result from a call to Publish.
 //
 // Call Get to obtain the result of the Publish call. Example:
@@ -513,7 +606,9 @@ var errTopicStopped = errors.New("pubsub: Stop has been called for this topic")
 type PublishResult = ipubsub.PublishResult
 
 // Publish publishes msg to the topic asynchronously. Messages are batched and
-// sent according to the topic's PublishSettings. Publish never blocks.
+// sent according to the topic's PublishSettings. Publish does not block,
+// unless PublishSettings.FlowControlSettings.LimitExceededBehavior is
+// FlowControlBlock and the flow control limits are reached.
 //
 // Publish returns a non-nil PublishResult which will be ready when the
 // message has been sent (or has failed to be sent) to the server.
@@ -521,13 +616,32 @@ type PublishResult = ipubsub.PublishResult
 // Publish creates goroutines for batching and sending messages. These goroutines
 // need to be stopped by calling t.Stop(). Once stopped, future calls to Publish
 // will immediately return a PublishResult with an error.
+//
+// If the client has publish interceptors, Publish calls them with msg first,
+// and publishes the message they pass on.
 func (t *Topic) Publish(ctx context.Context, msg *Message) *PublishResult {
+	if len(t.c.publishInterceptors) == 0 {
+		return t.publish(ctx, msg)
+	}
+	r := chainPublishInterceptors(t.c.publishInterceptors, t.publish)(ctx, msg)
+	if r == nil {
+		r = NewPublishResultWithError(errors.New("pubsub: publish interceptor returned a nil PublishResult"))
+	}
+	return r
+}
+
+func (t *Topic) publish(ctx context.Context, msg *Message) *PublishResult {
 	r := ipubsub.NewPublishResult()
 	if !t.EnableMessageOrdering && msg.OrderingKey != "" {
 		ipubsub.SetPublishResult(r, "", errors.New("Topic.EnableMessageOrdering=false, but an OrderingKey was set in Message. Please remove the OrderingKey or turn on Topic.EnableMessageOrdering"))
 		return r
 	}
 
+	var span trace.Span
+	if t.c.tracer != nil {
+		ctx, span = startPublishSpan(ctx, t.c.tracer, t.name, msg)
+	}
+
 	// Calculate the size of the encoded proto message by accounting
 	// for the length of an individual PubSubMessage and Data/Attributes field.
 	msgSize := proto.Size(&pb.PubsubMessage{
@@ -542,18 +656,45 @@ func (t *Topic) Publish(ctx context.Context, msg *Message) *PublishResult {
 	// TODO(aboulhosn) [from bcmills] consider changing the semantics of bundler to perform this logic so we don't have to do it here
 	if t.stopped {
 		ipubsub.SetPublishResult(r, "", errTopicStopped)
+		endPublishSpan(span, "", errTopicStopped)
 		return r
 	}
+	if msg.OrderingKey != "" && t.scheduler.IsPaused(msg.OrderingKey) {
+		err := ErrPublishingPaused{OrderingKey: msg.OrderingKey}
+		ipubsub.SetPublishResult(r, "", err)
+		endPublishSpan(span, "", err)
+		return r
+	}
+	if t.validator != nil {
+		if err := t.validator.validateMessage(msg.Data); err != nil {
+			ipubsub.SetPublishResult(r, "", err)
+			endPublishSpan(span, "", err)
+			return r
+		}
+	}
 
-	if err := t.flowController.acquire(ctx, msgSize); err != nil {
+	var fcSpan trace.Span
+	if span != nil {
+		_, fcSpan = t.c.tracer.Start(ctx, "publisher flow control")
+	}
+	err := t.flowController.acquire(ctx, msgSize)
+	if fcSpan != nil {
+		if err != nil {
+			setSpanStatus(fcSpan, err)
+		}
+		fcSpan.End()
+	}
+	if err != nil {
 		t.scheduler.Pause(msg.OrderingKey)
 		ipubsub.SetPublishResult(r, "", err)
+		endPublishSpan(span, "", err)
 		return r
 	}
-	err := t.scheduler.Add(msg.OrderingKey, &bundledMessage{msg, r, msgSize}, msgSize)
+	err = t.scheduler.Add(msg.OrderingKey, &bundledMessage{msg, r, msgSize, span}, msgSize)
 	if err != nil {
 		t.scheduler.Pause(msg.OrderingKey)
 		ipubsub.SetPublishResult(r, "", err)
+		endPublishSpan(span, "", err)
 	}
 	return r
 }
@@ -584,6 +725,7 @@ type bundledMessage struct {
 	msg  *Message
 	res  *PublishResult
 	size int
+	span trace.Span // nil unless tracing is enabled
 }
 
 func (t *Topic) initBundler() {
@@ -628,17 +770,20 @@ func (t *Topic) initBundler() {
 	t.scheduler.BundleByteThreshold = t.PublishSettings.ByteThreshold
 
 	fcs := DefaultPublishSettings.FlowControlSettings
-	if t.PublishSettings.FlowControlSettings.LimitExceededBehavior != FlowControlBlock {
-		fcs.LimitExceededBehavior = t.PublishSettings.FlowControlSettings.LimitExceededBehavior
-	}
-	if t.PublishSettings.FlowControlSettings.MaxOutstandingBytes > 0 {
-		b := t.PublishSettings.FlowControlSettings.MaxOutstandingBytes
-		fcs.MaxOutstandingBytes = b
+	ufcs := t.PublishSettings.FlowControlSettings
+	// FlowControlBlock is the zero value of LimitExceededBehavior, so it
+	// takes effect only together with a limit. Otherwise, zero
+	// FlowControlSettings disable flow control, as they always have.
+	if ufcs.LimitExceededBehavior != FlowControlBlock || ufcs.MaxOutstandingMessages > 0 || ufcs.MaxOutstandingBytes > 0 {
+		fcs.LimitExceededBehavior = ufcs.LimitExceededBehavior
+	}
+	if ufcs.MaxOutstandingBytes > 0 {
+		fcs.MaxOutstandingBytes = ufcs.MaxOutstandingBytes
 		// If MaxOutstandingBytes is set, override BufferedByteLimit.
-		t.PublishSettings.BufferedByteLimit = b
+		t.PublishSettings.BufferedByteLimit = ufcs.MaxOutstandingBytes
 	}
-	if t.PublishSettings.FlowControlSettings.MaxOutstandingMessages > 0 {
-		fcs.MaxOutstandingMessages = t.PublishSettings.FlowControlSettings.MaxOutstandingMessages
+	if ufcs.MaxOutstandingMessages > 0 {
+		fcs.MaxOutstandingMessages = ufcs.MaxOutstandingMessages
 	}
 
 	t.flowController = newFlowController(fcs)
@@ -654,6 +799,13 @@ func (t *Topic) initBundler() {
 	t.scheduler.BundleByteLimit = MaxPublishRequestBytes - calcFieldSizeString(t.name) - 5
 }
 
+func (t *Topic) compressionThreshold() int {
+	if t.PublishSettings.CompressionBytesThreshold > 0 {
+		return t.PublishSettings.CompressionBytesThreshold
+	}
+	return DefaultPublishSettings.CompressionBytesThreshold
+}
+
 func (t *Topic) publishMessageBundle(ctx context.Context, bms []*bundledMessage) {
 	ctx, err := tag.New(ctx, tag.Insert(keyStatus, "OK"), tag.Upsert(keyTopic, t.name))
 	if err != nil {
@@ -661,7 +813,9 @@ func (t *Topic) publishMessageBundle(ctx context.Context, bms []*bundledMessage)
 	}
 	pbMsgs := make([]*pb.PubsubMessage, len(bms))
 	var orderingKey string
+	var batchSize int
 	for i, bm := range bms {
+		batchSize += bm.size
 		orderingKey = bm.msg.OrderingKey
 		pbMsgs[i] = &pb.PubsubMessage{
 			Data:        bm.msg.Data,
@@ -670,15 +824,37 @@ func (t *Topic) publishMessageBundle(ctx context.Context, bms []*bundledMessage)
 		}
 		bm.msg = nil // release bm.msg for GC
 	}
+	var span trace.Span
+	if t.c.tracer != nil {
+		links := make([]trace.Link, len(bms))
+		for i, bm := range bms {
+			links[i] = trace.Link{SpanContext: bm.span.SpanContext()}
+		}
+		ctx, span = t.c.tracer.Start(ctx, t.name+" publish",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attrMessagingSystem.String("pubsub"),
+				attrMessagingDestination.String(t.name),
+				attrBatchSize.Int(len(bms)),
+			),
+			trace.WithLinks(links...))
+	}
 	var res *pb.PublishResponse
 	start := time.Now()
 	if orderingKey != "" && t.scheduler.IsPaused(orderingKey) {
-		err = fmt.Errorf("pubsub: Publishing for ordering key, %s, paused due to previous error. Call topic.ResumePublish(orderingKey) before resuming publishing", orderingKey)
+		err = ErrPublishingPaused{OrderingKey: orderingKey}
 	} else {
-		res, err = t.c.pubc.Publish(ctx, &pb.PublishRequest{
-			Topic:    t.name,
-			Messages: pbMsgs,
-		}, gax.WithGRPCOptions(grpc.MaxCallSendMsgSize(maxSendRecvBytes)))
+		opts := []grpc.CallOption{grpc.MaxCallSendMsgSize(maxSendRecvBytes)}
+		if t.PublishSettings.EnableCompression && batchSize >= t.compressionThreshold() {
+			opts = append(opts, grpc.UseCompressor(gzip.Name))
+		}
+		err = t.c.autoCreate.topic(ctx, t.name)
+		if err == nil {
+			res, err = t.c.pubc.Publish(ctx, &pb.PublishRequest{
+				Topic:    t.name,
+				Messages: pbMsgs,
+			}, gax.WithGRPCOptions(opts...))
+		}
 	}
 	end := time.Now()
 	if err != nil {
@@ -691,12 +867,20 @@ func (t *Topic) publishMessageBundle(ctx context.Context, bms []*bundledMessage)
 	stats.Record(ctx,
 		PublishLatency.M(float64(end.Sub(start)/time.Millisecond)),
 		PublishedMessages.M(int64(len(bms))))
+	if span != nil {
+		if err != nil {
+			setSpanStatus(span, err)
+		}
+		span.End()
+	}
 	for i, bm := range bms {
 		t.flowController.release(ctx, bm.size)
 		if err != nil {
 			ipubsub.SetPublishResult(bm.res, "", err)
+			endPublishSpan(bm.span, "", err)
 		} else {
 			ipubsub.SetPublishResult(bm.res, res.MessageIds[i], nil)
+			endPublishSpan(bm.span, res.MessageIds[i], nil)
 		}
 	}
 }
@@ -704,7 +888,12 @@ func (t *Topic) publishMessageBundle(ctx context.Context, bms []*bundledMessage)
 // ResumePublish resumes accepting messages for the provided ordering key.
 // Publishing using an ordering key might be paused if an error is
 // encountered while publishing, to prevent messages from being published
-// out of order.
+// out of order. While it is paused, the results of publishing messages with
+// the key fail with ErrPublishingPaused.
+//
+// Messages with the key that were published after the failed message, and
+// failed with ErrPublishingPaused, are not retried. To keep messages in order,
+// publish them again after calling ResumePublish.
 func (t *Topic) ResumePublish(orderingKey string) {
 	t.mu.RLock()
 	noop := t.scheduler == nil
@@ -715,3 +904,15 @@ func (t *Topic) ResumePublish(orderingKey string) {
 
 	t.scheduler.Resume(orderingKey)
 }
+
+// PausedOrderingKeys returns the ordering keys for which publishing is paused
+// because of an error, in sorted order. See ResumePublish.
+func (t *Topic) PausedOrderingKeys() []string {
+	t.mu.RLock()
+	noop := t.scheduler == nil
+	t.mu.RUnlock()
+	if noop {
+		return nil
+	}
+	return t.scheduler.PausedKeys()
+}
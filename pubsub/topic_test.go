@@ -17,12 +17,14 @@ package pubsub
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/internal/testutil"
+	"cloud.google.com/go/pubsub/internal/wirefield"
 	"cloud.google.com/go/pubsub/pstest"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/api/iterator"
@@ -303,6 +305,84 @@ func TestUpdateTopic_MessageStoragePolicy(t *testing.T) {
 	}
 }
 
+func TestUpdateTopic_IngestionDataSourceSettings(t *testing.T) {
+	ctx := context.Background()
+	client, srv := newFake(t)
+	defer client.Close()
+	defer srv.Close()
+
+	kinesis := &IngestionDataSourceSettings{
+		Source: &IngestionDataSourceAWSKinesis{
+			StreamARN:         "arn:aws:kinesis:us-west-2:111111111111:stream/s",
+			ConsumerARN:       "arn:aws:kinesis:us-west-2:111111111111:stream/s/consumer/c:1",
+			AWSRoleARN:        "arn:aws:iam::111111111111:role/r",
+			GCPServiceAccount: "sa@p.iam.gserviceaccount.com",
+		},
+	}
+	topic, err := client.CreateTopicWithConfig(ctx, "T", &TopicConfig{IngestionDataSourceSettings: kinesis})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := topic.Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := cmpopts.IgnoreUnexported(TopicConfig{})
+	want := TopicConfig{IngestionDataSourceSettings: kinesis}
+	if diff := testutil.Diff(config, want, opt); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+
+	gcs := &IngestionDataSourceSettings{
+		Source: &IngestionDataSourceCloudStorage{
+			Bucket:                  "bucket",
+			InputFormat:             &IngestionDataSourceCloudStorageTextFormat{Delimiter: ","},
+			MinimumObjectCreateTime: time.Date(2022, 3, 1, 12, 0, 0, 500, time.UTC),
+			MatchGlob:               "**.txt",
+		},
+	}
+	config, err = topic.Update(ctx, TopicConfigToUpdate{IngestionDataSourceSettings: gcs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.IngestionDataSourceSettings = gcs
+	if diff := testutil.Diff(config, want, opt); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+
+	// Clear the settings.
+	config, err = topic.Update(ctx, TopicConfigToUpdate{IngestionDataSourceSettings: &IngestionDataSourceSettings{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.IngestionDataSourceSettings = nil
+	if diff := testutil.Diff(config, want, opt); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
+
+func TestIngestionState(t *testing.T) {
+	// The states are set only by the service.
+	var ib []byte
+	ib = appendVarint(ib, kinesisStateField, uint64(AWSKinesisStatePermissionDenied))
+	ib = appendString(ib, kinesisStreamARNField, "arn")
+	pbt := &pb.Topic{}
+	m := pbt.ProtoReflect()
+	wirefield.SetBytes(m, topicIngestionDataSourceSettingsField, appendMessage(nil, ingestionAWSKinesisField, ib))
+	m.SetUnknown(appendVarint(m.GetUnknown(), topicStateField, uint64(TopicStateIngestionResourceError)))
+
+	tc := protoToTopicConfig(pbt)
+	want := TopicConfig{
+		IngestionDataSourceSettings: &IngestionDataSourceSettings{
+			Source: &IngestionDataSourceAWSKinesis{StreamARN: "arn", State: AWSKinesisStatePermissionDenied},
+		},
+		State: TopicStateIngestionResourceError,
+	}
+	if diff := testutil.Diff(tc, want, cmpopts.IgnoreUnexported(TopicConfig{})); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
+
 type alwaysFailPublish struct {
 	pubsubpb.PublisherServer
 }
@@ -515,6 +595,50 @@ func TestPublishFlowControl_SignalErrorOrderingKey(t *testing.T) {
 	}
 }
 
+func TestPublishPausedOrderingKey(t *testing.T) {
+	ctx := context.Background()
+	c, srv := newFake(t)
+	defer c.Close()
+	defer srv.Close()
+
+	topic := mustCreateTopic(t, c, "some-topic")
+	defer topic.Stop()
+	topic.EnableMessageOrdering = true
+	topic.PublishSettings.CountThreshold = 1
+	if got := topic.PausedOrderingKeys(); len(got) != 0 {
+		t.Errorf("PausedOrderingKeys() before publishing: got %v, want none", got)
+	}
+
+	srv.SetAutoPublishResponse(false)
+	srv.AddPublishResponse(nil, status.Error(codes.InvalidArgument, "bad message"))
+	if _, err := publishSingleMessageWithKey(ctx, topic, "A", "a").Get(ctx); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("r1.Get() got %v, want InvalidArgument", err)
+	}
+	_, err := publishSingleMessageWithKey(ctx, topic, "B", "a").Get(ctx)
+	var perr ErrPublishingPaused
+	if !errors.As(err, &perr) || perr.OrderingKey != "a" {
+		t.Errorf("r2.Get() got %v, want ErrPublishingPaused for key a", err)
+	}
+	if diff := testutil.Diff(topic.PausedOrderingKeys(), []string{"a"}); diff != "" {
+		t.Errorf("PausedOrderingKeys(): got=-, want=+:\n%s", diff)
+	}
+
+	// Messages with other keys are published.
+	addSingleResponse(srv, "1")
+	if _, err := publishSingleMessageWithKey(ctx, topic, "C", "b").Get(ctx); err != nil {
+		t.Errorf("r3.Get() got %v, want nil", err)
+	}
+
+	topic.ResumePublish("a")
+	if got := topic.PausedOrderingKeys(); len(got) != 0 {
+		t.Errorf("PausedOrderingKeys() after ResumePublish: got %v, want none", got)
+	}
+	addSingleResponse(srv, "2")
+	if id, err := publishSingleMessageWithKey(ctx, topic, "B", "a").Get(ctx); err != nil || id != "2" {
+		t.Errorf("r4.Get() got %q, %v; want \"2\", nil", id, err)
+	}
+}
+
 func TestPublishFlowControl_Block(t *testing.T) {
 	ctx := context.Background()
 	c, srv := newFake(t)
@@ -585,6 +709,57 @@ func TestPublishFlowControl_Block(t *testing.T) {
 }
 
 // publishSingleMessage publishes a single message to a topic.
+func TestPublishFlowControl_BlockUntilPublished(t *testing.T) {
+	ctx := context.Background()
+	c, srv := newFake(t)
+	defer c.Close()
+	defer srv.Close()
+
+	// Do not stop the topic if the test fails: Stop would wait for the
+	// outstanding messages.
+	topic := mustCreateTopic(t, c, "some-topic")
+	topic.PublishSettings.FlowControlSettings = FlowControlSettings{
+		MaxOutstandingMessages: 1,
+		LimitExceededBehavior:  FlowControlBlock,
+	}
+	topic.PublishSettings.CountThreshold = 1
+	srv.SetAutoPublishResponse(false)
+
+	r1 := publishSingleMessage(ctx, topic, "A")
+	published := make(chan *PublishResult)
+	go func() { published <- publishSingleMessage(ctx, topic, "B") }()
+	select {
+	case <-published:
+		t.Fatal("second Publish returned while the first message was outstanding")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	addSingleResponse(srv, "1")
+	if id, err := r1.Get(ctx); err != nil || id != "1" {
+		t.Fatalf("r1.Get() got %q, %v; want \"1\", nil", id, err)
+	}
+	var r2 *PublishResult
+	select {
+	case r2 = <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second Publish still blocked after the first message was published")
+	}
+	addSingleResponse(srv, "2")
+	if id, err := r2.Get(ctx); err != nil || id != "2" {
+		t.Fatalf("r2.Get() got %q, %v; want \"2\", nil", id, err)
+	}
+
+	// Publish returns when its context is done while it is blocked.
+	publishSingleMessage(ctx, topic, "C")
+	cctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := publishSingleMessage(cctx, topic, "D").Get(ctx); err != context.DeadlineExceeded {
+		t.Errorf("blocked Publish with done context: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	addSingleResponse(srv, "3")
+	topic.Stop()
+}
+
 func publishSingleMessage(ctx context.Context, t *Topic, data string) *PublishResult {
 	return t.Publish(ctx, &Message{
 		Data: []byte(data),
@@ -605,3 +780,51 @@ func addSingleResponse(srv *pstest.Server, id string) {
 		MessageIds: []string{id},
 	}, nil)
 }
+
+func TestPublishCompression(t *testing.T) {
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	defer srv.Close()
+	var mu sync.Mutex
+	var compressed []bool
+	recordCompression := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if method == "/google.pubsub.v1.Publisher/Publish" {
+			gz := false
+			for _, o := range opts {
+				if c, ok := o.(grpc.CompressorCallOption); ok && c.CompressorType == "gzip" {
+					gz = true
+				}
+			}
+			mu.Lock()
+			compressed = append(compressed, gz)
+			mu.Unlock()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	c, err := NewClient(ctx, "P",
+		option.WithEndpoint(srv.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithInsecure()),
+		option.WithGRPCDialOption(grpc.WithUnaryInterceptor(recordCompression)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	topic := mustCreateTopic(t, c, "t")
+	topic.PublishSettings.EnableCompression = true
+	topic.PublishSettings.CompressionBytesThreshold = 100
+	large := bytes.Repeat([]byte(`{"key": "value"}`), 100)
+	for _, data := range [][]byte{[]byte("small"), large} {
+		if _, err := topic.Publish(ctx, &Message{Data: data}).Get(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	topic.Stop()
+	if diff := testutil.Diff(compressed, []bool{false, true}); diff != "" {
+		t.Errorf("compressed requests: got=-, want=+:\n%s", diff)
+	}
+	if msgs := srv.Messages(); len(msgs) != 2 || !bytes.Equal(msgs[1].Data, large) {
+		t.Errorf("got messages %v, want the published data", msgs)
+	}
+}
@@ -17,11 +17,18 @@ package pubsub
 import (
 	"context"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
+	"cloud.google.com/go/internal/version"
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // The following keys are used to tag requests with a specific topic/subscription ID.
@@ -236,3 +243,141 @@ func withSubscriptionKey(ctx context.Context, subName string) context.Context {
 func recordStat(ctx context.Context, m *stats.Int64Measure, n int64) {
 	stats.Record(ctx, m.M(n))
 }
+
+// The following are used for the trace spans of publishing and receiving
+// messages, when ClientConfig.OpenTelemetryTracerProvider is set.
+
+// OpenTelemetryTracerName is the name of the OpenTelemetry tracer of clients.
+const OpenTelemetryTracerName = "cloud.google.com/go/pubsub"
+
+// newTracer returns the tracer of a client with the given TracerProvider. It
+// returns nil, which disables tracing, if tp is nil.
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		return nil
+	}
+	return tp.Tracer(OpenTelemetryTracerName, trace.WithInstrumentationVersion(version.Repo))
+}
+
+// traceAttributePrefix is the prefix of the message attributes that carry the
+// trace context of a published message to its subscribers, in the format of
+// W3C Trace Context: the traceparent header is the "googclient_traceparent"
+// attribute.
+const traceAttributePrefix = "googclient_"
+
+// tracePropagator propagates the trace context of messages in their
+// attributes.
+var tracePropagator = propagation.TraceContext{}
+
+// messageCarrier is a propagation.TextMapCarrier over the attributes of a
+// message.
+type messageCarrier map[string]string
+
+func (c messageCarrier) Get(key string) string { return c[traceAttributePrefix+key] }
+
+func (c messageCarrier) Set(key, value string) { c[traceAttributePrefix+key] = value }
+
+func (c messageCarrier) Keys() []string {
+	var keys []string
+	for k := range c {
+		if strings.HasPrefix(k, traceAttributePrefix) {
+			keys = append(keys, strings.TrimPrefix(k, traceAttributePrefix))
+		}
+	}
+	return keys
+}
+
+// The following are the attributes of spans, named after the OpenTelemetry
+// semantic conventions for messaging systems.
+const (
+	attrMessagingSystem      = attribute.Key("messaging.system")
+	attrMessagingDestination = attribute.Key("messaging.destination")
+	attrMessageID            = attribute.Key("messaging.message_id")
+	attrMessageSize          = attribute.Key("messaging.message_payload_size_bytes")
+	attrBatchSize            = attribute.Key("messaging.batch.message_count")
+	attrOrderingKey          = attribute.Key("messaging.pubsub.ordering_key")
+	attrDeliveryAttempt      = attribute.Key("messaging.pubsub.delivery_attempt")
+	attrAckLatency           = attribute.Key("messaging.pubsub.ack_latency_ms")
+)
+
+// startPublishSpan starts the span of a message passed to Topic.Publish, which
+// ends when the message has been sent or has failed to be sent. It adds the
+// trace context of the span to a copy of the attributes of msg.
+func startPublishSpan(ctx context.Context, tracer trace.Tracer, topicName string, msg *Message) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attrMessagingSystem.String("pubsub"),
+		attrMessagingDestination.String(topicName),
+		attrMessageSize.Int(len(msg.Data)),
+	}
+	if msg.OrderingKey != "" {
+		attrs = append(attrs, attrOrderingKey.String(msg.OrderingKey))
+	}
+	ctx, span := tracer.Start(ctx, topicName+" create",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...))
+	msgAttrs := make(map[string]string, len(msg.Attributes)+1)
+	for k, v := range msg.Attributes {
+		msgAttrs[k] = v
+	}
+	tracePropagator.Inject(ctx, messageCarrier(msgAttrs))
+	msg.Attributes = msgAttrs
+	return ctx, span
+}
+
+// endPublishSpan ends the span of a published message, with the message ID
+// assigned by the service or the error of publishing it. It does nothing if
+// span is nil.
+func endPublishSpan(span trace.Span, id string, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		setSpanStatus(span, err)
+	} else {
+		span.SetAttributes(attrMessageID.String(id))
+	}
+	span.End()
+}
+
+// startReceiveSpan starts the span of a received message, which ends when the
+// message is acked or nacked. If the publisher traced the message, the span is
+// a child of the span of publishing it.
+func startReceiveSpan(ctx context.Context, tracer trace.Tracer, subName string, msg *Message) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attrMessagingSystem.String("pubsub"),
+		attrMessagingDestination.String(subName),
+		attrMessageID.String(msg.ID),
+		attrMessageSize.Int(len(msg.Data)),
+	}
+	if msg.OrderingKey != "" {
+		attrs = append(attrs, attrOrderingKey.String(msg.OrderingKey))
+	}
+	if msg.DeliveryAttempt != nil {
+		attrs = append(attrs, attrDeliveryAttempt.Int(*msg.DeliveryAttempt))
+	}
+	ctx = tracePropagator.Extract(ctx, messageCarrier(msg.Attributes))
+	return tracer.Start(ctx, subName+" receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...))
+}
+
+// endReceiveSpan ends the span of a received message when it is acked or
+// nacked. It does nothing if span is nil.
+func endReceiveSpan(span trace.Span, ack bool, receiveTime time.Time) {
+	if span == nil {
+		return
+	}
+	event := "nack"
+	if ack {
+		event = "ack"
+	}
+	span.AddEvent(event, trace.WithAttributes(
+		attrAckLatency.Int64(int64(time.Since(receiveTime)/time.Millisecond)),
+	))
+	span.End()
+}
+
+func setSpanStatus(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, err.Error())
+}
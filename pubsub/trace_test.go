@@ -0,0 +1,168 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/pstest"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+func TestMessageCarrier(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	attrs := map[string]string{"k": "v"}
+	tracePropagator.Inject(trace.ContextWithSpanContext(context.Background(), sc), messageCarrier(attrs))
+	const h = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	if got := attrs["googclient_traceparent"]; got != h {
+		t.Errorf("traceparent attribute: got %q, want %q", got, h)
+	}
+	if got := messageCarrier(attrs).Keys(); len(got) != 1 || got[0] != "traceparent" {
+		t.Errorf("Keys: got %q, want [traceparent]", got)
+	}
+	got := trace.SpanContextFromContext(tracePropagator.Extract(context.Background(), messageCarrier(attrs)))
+	if !got.Equal(sc.WithRemote(true)) {
+		t.Errorf("Extract: got %v, want %v", got, sc)
+	}
+	for _, in := range []string{"", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		ctx := tracePropagator.Extract(context.Background(), messageCarrier{"googclient_traceparent": in})
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			t.Errorf("Extract(%q): got %v, want an invalid span context", in, sc)
+		}
+	}
+}
+
+// spansByName returns the ended spans of rec with the given name.
+func spansByName(rec *tracetest.SpanRecorder, name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func spanAttribute(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+
+	ctx := context.Background()
+	srv := pstest.NewServer()
+	defer srv.Close()
+	client, err := NewClientWithConfig(ctx, "P", &ClientConfig{OpenTelemetryTracerProvider: tp},
+		option.WithEndpoint(srv.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithInsecure()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	topic := mustCreateTopic(t, client, "t")
+	sub, err := client.CreateSubscription(ctx, "s", SubscriptionConfig{Topic: topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := map[string]string{"k": "v"}
+	msg := &Message{Data: []byte("hello"), Attributes: attrs}
+	id, err := topic.Publish(ctx, msg).Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic.Stop()
+	if len(attrs) != 1 {
+		t.Errorf("Publish modified the attributes of the caller: %v", attrs)
+	}
+
+	createSpans := spansByName(rec, topic.name+" create")
+	if len(createSpans) != 1 {
+		t.Fatalf("got %d create spans, want 1", len(createSpans))
+	}
+	create := createSpans[0]
+	if got := spanAttribute(create, attrMessageID).AsString(); got != id {
+		t.Errorf("create span message ID: got %v, want %s", got, id)
+	}
+	if create.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("create span kind: got %v, want producer", create.SpanKind())
+	}
+	if fc := spansByName(rec, "publisher flow control"); len(fc) != 1 || fc[0].Parent().SpanID() != create.SpanContext().SpanID() {
+		t.Errorf("got flow control spans %v, want one child of the create span", fc)
+	}
+	publishSpans := spansByName(rec, topic.name+" publish")
+	if len(publishSpans) != 1 {
+		t.Fatalf("got %d publish spans, want 1", len(publishSpans))
+	}
+	if links := publishSpans[0].Links(); len(links) != 1 || !links[0].SpanContext.Equal(create.SpanContext()) {
+		t.Errorf("got publish span links %v, want a link to the create span", links)
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var received *Message
+	var receiveParent trace.SpanContext
+	err = sub.Receive(cctx, func(ctx context.Context, m *Message) {
+		received = m
+		receiveParent = trace.SpanContextFromContext(ctx)
+		m.Ack()
+		cancel()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received == nil {
+		t.Fatal("no message received")
+	}
+	sc := create.SpanContext()
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if got := received.Attributes["googclient_traceparent"]; got != want {
+		t.Errorf("traceparent attribute: got %q, want %q", got, want)
+	}
+	receiveSpans := spansByName(rec, sub.name+" receive")
+	if len(receiveSpans) != 1 {
+		t.Fatalf("got %d receive spans, want 1", len(receiveSpans))
+	}
+	rs := receiveSpans[0]
+	if rs.SpanContext().TraceID() != sc.TraceID() || rs.Parent().SpanID() != sc.SpanID() || !rs.Parent().IsRemote() {
+		t.Errorf("receive span %v is not a child of create span %v", rs.SpanContext(), sc)
+	}
+	if !receiveParent.Equal(rs.SpanContext()) {
+		t.Error("the context of the callback does not have the receive span")
+	}
+	if events := rs.Events(); len(events) != 1 || events[0].Name != "ack" {
+		t.Errorf("got receive span events %v, want an ack", events)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/googleapis/gax-go/v2"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+var validRolePattern = regexp.MustCompile("^[A-Za-z][A-Za-z0-9_]{0,127}$")
+
+// A Grant describes privileges on a table that are granted to database roles
+// with fine-grained access control.
+type Grant struct {
+	// Privileges are the privileges that are granted: SELECT, INSERT, UPDATE
+	// and/or DELETE.
+	Privileges []string
+
+	// Columns restricts the SELECT, INSERT and UPDATE privileges to the given
+	// columns of the table. If it is empty, the privileges apply to all
+	// columns.
+	Columns []string
+
+	// Table is the table that the privileges apply to.
+	Table string
+
+	// Roles are the database roles that the privileges are granted to.
+	Roles []string
+}
+
+// CreateDatabaseRoles creates the given database roles in a single DDL
+// batch. The roles have no privileges until they are granted some with
+// GrantPrivileges.
+//
+// databasePath must have the form
+// projects/<project>/instances/<instance>/databases/<database>.
+func (c *DatabaseAdminClient) CreateDatabaseRoles(ctx context.Context, databasePath string, roles []string, opts ...gax.CallOption) (*UpdateDatabaseDdlOperation, error) {
+	var stmts []string
+	for _, role := range roles {
+		if err := validateRole(role); err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, "CREATE ROLE "+role)
+	}
+	return c.updateDDL(ctx, databasePath, stmts, opts...)
+}
+
+// GrantPrivileges grants the privileges described by grants in a single DDL
+// batch.
+//
+// databasePath must have the form
+// projects/<project>/instances/<instance>/databases/<database>.
+func (c *DatabaseAdminClient) GrantPrivileges(ctx context.Context, databasePath string, grants []Grant, opts ...gax.CallOption) (*UpdateDatabaseDdlOperation, error) {
+	var stmts []string
+	for _, g := range grants {
+		stmt, err := g.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+	return c.updateDDL(ctx, databasePath, stmts, opts...)
+}
+
+func (c *DatabaseAdminClient) updateDDL(ctx context.Context, databasePath string, stmts []string, opts ...gax.CallOption) (*UpdateDatabaseDdlOperation, error) {
+	if !validDBPattern.MatchString(databasePath) {
+		return nil, fmt.Errorf("database name %q should conform to pattern %q",
+			databasePath, validDBPattern)
+	}
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("no DDL statements to execute")
+	}
+	return c.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   databasePath,
+		Statements: stmts,
+	}, opts...)
+}
+
+// statement returns the GRANT statement of g.
+func (g Grant) statement() (string, error) {
+	if len(g.Privileges) == 0 {
+		return "", fmt.Errorf("grant on table %q has no privileges", g.Table)
+	}
+	if len(g.Roles) == 0 {
+		return "", fmt.Errorf("grant on table %q has no roles", g.Table)
+	}
+	if g.Table == "" {
+		return "", fmt.Errorf("grant has no table")
+	}
+	for _, role := range g.Roles {
+		if err := validateRole(role); err != nil {
+			return "", err
+		}
+	}
+	var privs []string
+	for _, p := range g.Privileges {
+		p = strings.ToUpper(p)
+		switch p {
+		case "SELECT", "INSERT", "UPDATE":
+			if len(g.Columns) > 0 {
+				p += "(" + strings.Join(g.Columns, ", ") + ")"
+			}
+		case "DELETE":
+		default:
+			return "", fmt.Errorf("unknown privilege %q", p)
+		}
+		privs = append(privs, p)
+	}
+	return fmt.Sprintf("GRANT %s ON TABLE %s TO ROLE %s",
+		strings.Join(privs, ", "), g.Table, strings.Join(g.Roles, ", ")), nil
+}
+
+// validateRole returns an error if role is not a valid name for a database
+// role that can be created or granted privileges.
+func validateRole(role string) error {
+	if !validRolePattern.MatchString(role) {
+		return fmt.Errorf("database role %q should conform to pattern %q", role, validRolePattern)
+	}
+	if strings.EqualFold(role, "public") || strings.HasPrefix(strings.ToLower(role), "spanner_") {
+		return fmt.Errorf("database role %q is a system role", role)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	emptypb "github.com/golang/protobuf/ptypes/empty"
+	longrunningpb "google.golang.org/genproto/googleapis/longrunning"
+	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+func TestDatabaseAdminClient_CreateDatabaseRolesAndGrantPrivileges(t *testing.T) {
+	databasePath := "projects/some-project/instances/some-instance/databases/some-database"
+	mockDatabaseAdmin.err = nil
+	mockDatabaseAdmin.reqs = nil
+	any, err := ptypes.MarshalAny(&emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockDatabaseAdmin.resps = append(mockDatabaseAdmin.resps[:0], &longrunningpb.Operation{
+		Name:   "longrunning-test",
+		Done:   true,
+		Result: &longrunningpb.Operation_Response{Response: any},
+	})
+
+	ctx := context.Background()
+	c, err := NewDatabaseAdminClient(ctx, clientOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, err := c.CreateDatabaseRoles(ctx, databasePath, []string{"reader", "writer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := op.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	op, err = c.GrantPrivileges(ctx, databasePath, []Grant{
+		{Privileges: []string{"SELECT"}, Table: "Singers", Roles: []string{"reader", "writer"}},
+		{Privileges: []string{"insert", "UPDATE", "DELETE"}, Columns: []string{"SingerId", "Name"}, Table: "Singers", Roles: []string{"writer"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := op.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []proto.Message{
+		&databasepb.UpdateDatabaseDdlRequest{
+			Database:   databasePath,
+			Statements: []string{"CREATE ROLE reader", "CREATE ROLE writer"},
+		},
+		&databasepb.UpdateDatabaseDdlRequest{
+			Database: databasePath,
+			Statements: []string{
+				"GRANT SELECT ON TABLE Singers TO ROLE reader, writer",
+				"GRANT INSERT(SingerId, Name), UPDATE(SingerId, Name), DELETE ON TABLE Singers TO ROLE writer",
+			},
+		},
+	}
+	if got := len(mockDatabaseAdmin.reqs); got != len(want) {
+		t.Fatalf("got %d requests, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if got := mockDatabaseAdmin.reqs[i]; !proto.Equal(w, got) {
+			t.Errorf("request %d: got %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestDatabaseAdminClient_DatabaseRolesErrors(t *testing.T) {
+	databasePath := "projects/some-project/instances/some-instance/databases/some-database"
+	mockDatabaseAdmin.err = nil
+	mockDatabaseAdmin.reqs = nil
+
+	ctx := context.Background()
+	c, err := NewDatabaseAdminClient(ctx, clientOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, roles := range [][]string{nil, {"1role"}, {"public"}, {"spanner_info_reader"}, {"bad-name"}} {
+		if _, err := c.CreateDatabaseRoles(ctx, databasePath, roles); err == nil {
+			t.Errorf("CreateDatabaseRoles(%q): got nil error, want error", roles)
+		}
+	}
+	if _, err := c.CreateDatabaseRoles(ctx, "some-database", []string{"reader"}); err == nil {
+		t.Error("CreateDatabaseRoles with invalid database path: got nil error, want error")
+	}
+	for _, g := range []Grant{
+		{Table: "Singers", Roles: []string{"reader"}},
+		{Privileges: []string{"SELECT"}, Table: "Singers"},
+		{Privileges: []string{"SELECT"}, Roles: []string{"reader"}},
+		{Privileges: []string{"ALTER"}, Table: "Singers", Roles: []string{"reader"}},
+	} {
+		if _, err := c.GrantPrivileges(ctx, databasePath, []Grant{g}); err == nil {
+			t.Errorf("GrantPrivileges(%+v): got nil error, want error", g)
+		}
+	}
+	if len(mockDatabaseAdmin.reqs) != 0 {
+		t.Errorf("got %d requests for invalid arguments, want 0", len(mockDatabaseAdmin.reqs))
+	}
+}
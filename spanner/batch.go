@@ -132,6 +132,11 @@ func (t *BatchReadOnlyTransaction) PartitionReadUsingIndexWithOptions(ctx contex
 	if err != nil {
 		return nil, err
 	}
+	readOptions = t.ro.merge(readOptions)
+	dro, err := t.directedReadOptions(readOptions.DirectedReadOptions)
+	if err != nil {
+		return nil, err
+	}
 	var md metadata.MD
 	resp, err = client.PartitionRead(contextWithOutgoingMetadata(ctx, sh.getMetadata()), &sppb.PartitionReadRequest{
 		Session:          sid,
@@ -158,6 +163,7 @@ func (t *BatchReadOnlyTransaction) PartitionReadUsingIndexWithOptions(ctx contex
 		KeySet:         kset,
 		RequestOptions: createRequestOptions(readOptions.Priority, readOptions.RequestTag, ""),
 	}
+	setDirectedReadOptions(req, readRequestDirectedReadOptions, dro)
 	// Generate partitions.
 	for _, p := range resp.GetPartitions() {
 		partitions = append(partitions, &Partition{
@@ -191,6 +197,10 @@ func (t *BatchReadOnlyTransaction) partitionQuery(ctx context.Context, statement
 	if err != nil {
 		return nil, err
 	}
+	dro, err := t.directedReadOptions(qOpts.DirectedReadOptions)
+	if err != nil {
+		return nil, err
+	}
 	var md metadata.MD
 
 	// request Partitions
@@ -220,6 +230,7 @@ func (t *BatchReadOnlyTransaction) partitionQuery(ctx context.Context, statement
 		QueryOptions:   qOpts.Options,
 		RequestOptions: createRequestOptions(qOpts.Priority, qOpts.RequestTag, ""),
 	}
+	setDirectedReadOptions(r, executeSQLRequestDirectedReadOptions, dro)
 
 	// generate Partitions
 	var partitions []*Partition
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/internal/trace"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultMaxBatchUpdateStatements is the default maximum number of
+	// statements in each BatchUpdate RPC of ChunkedBatchUpdate.
+	DefaultMaxBatchUpdateStatements = 1000
+
+	// DefaultMaxBatchUpdateBytes is the default maximum size in bytes of the
+	// statements in each BatchUpdate RPC of ChunkedBatchUpdate. It keeps the
+	// requests well below the maximum request size of Cloud Spanner.
+	DefaultMaxBatchUpdateBytes = 8 << 20
+)
+
+// ChunkedBatchUpdateOptions provides options for
+// ReadWriteTransaction.ChunkedBatchUpdate.
+type ChunkedBatchUpdateOptions struct {
+	// QueryOptions are the options of each BatchUpdate RPC. Only the request
+	// tag and the priority are used.
+	QueryOptions QueryOptions
+
+	// MaxStatements is the maximum number of statements in each BatchUpdate
+	// RPC. If it is zero, DefaultMaxBatchUpdateStatements is used.
+	MaxStatements int
+
+	// MaxBytes is the maximum size in bytes of the encoded statements in
+	// each BatchUpdate RPC. A statement that is larger than MaxBytes is sent
+	// in an RPC of its own. If it is zero, DefaultMaxBatchUpdateBytes is
+	// used.
+	MaxBytes int
+}
+
+// ChunkedBatchUpdate executes a large number of DML statements in the
+// transaction. The statements are split into chunks that respect the limits
+// in opts, and each chunk is executed with one BatchUpdate RPC. The chunks
+// are executed in order, and the statements are executed in the order of
+// stmts.
+//
+// A slice of counts is returned, where each count represents the number of
+// affected rows for the statement at the same index in stmts. If a statement
+// fails, the counts of the statements before it are returned together with
+// an error that mentions the index of the statement in stmts. That index is
+// equal to the length of the returned counts. The statements after the
+// failed statement are not executed.
+//
+// All chunks are executed in the same transaction, so the mutation limit of
+// a transaction applies to all statements together.
+func (t *ReadWriteTransaction) ChunkedBatchUpdate(ctx context.Context, stmts []Statement, opts ChunkedBatchUpdateOptions) (counts []int64, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/spanner.ChunkedBatchUpdate")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if len(stmts) == 0 {
+		return nil, spannerErrorf(codes.InvalidArgument, "no statements to execute")
+	}
+	if opts.MaxStatements < 0 || opts.MaxBytes < 0 {
+		return nil, spannerErrorf(codes.InvalidArgument, "MaxStatements and MaxBytes must not be negative")
+	}
+	sppbStmts, err := batchDmlStatements(stmts)
+	if err != nil {
+		return nil, err
+	}
+	qo := t.qo.merge(opts.QueryOptions)
+	for _, chunk := range chunkBatchDmlStatements(sppbStmts, opts.MaxStatements, opts.MaxBytes) {
+		c, err := t.executeBatchDml(ctx, chunk, qo)
+		counts = append(counts, c...)
+		if err != nil {
+			return counts, errChunkedBatchUpdate(err, len(counts))
+		}
+		trace.TracePrintf(ctx, map[string]interface{}{"statements": len(chunk)}, "Executed BatchUpdate chunk")
+	}
+	return counts, nil
+}
+
+// chunkBatchDmlStatements splits stmts into chunks of at most maxStatements
+// statements and at most maxBytes bytes. Zero values select the defaults.
+func chunkBatchDmlStatements(stmts []*sppb.ExecuteBatchDmlRequest_Statement, maxStatements, maxBytes int) [][]*sppb.ExecuteBatchDmlRequest_Statement {
+	if maxStatements == 0 {
+		maxStatements = DefaultMaxBatchUpdateStatements
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxBatchUpdateBytes
+	}
+	var (
+		chunks [][]*sppb.ExecuteBatchDmlRequest_Statement
+		start  int
+		size   int
+	)
+	for i, st := range stmts {
+		n := proto.Size(st)
+		if i > start && (i-start == maxStatements || size+n > maxBytes) {
+			chunks = append(chunks, stmts[start:i])
+			start, size = i, 0
+		}
+		size += n
+	}
+	return append(chunks, stmts[start:])
+}
+
+// errChunkedBatchUpdate returns err, which was returned for the statement at
+// the given index of a ChunkedBatchUpdate, decorated with the index.
+func errChunkedBatchUpdate(err error, index int) error {
+	var se *Error
+	if !errorAs(err, &se) {
+		return err
+	}
+	se.decorate(fmt.Sprintf("statement %d of ChunkedBatchUpdate failed", index))
+	return se
+}
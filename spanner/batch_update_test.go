@@ -0,0 +1,157 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestChunkBatchDmlStatements(t *testing.T) {
+	stmt := func(n int) *sppb.ExecuteBatchDmlRequest_Statement {
+		return &sppb.ExecuteBatchDmlRequest_Statement{Sql: strings.Repeat("x", n)}
+	}
+	// Each statement of 8 characters is 10 bytes.
+	var stmts []*sppb.ExecuteBatchDmlRequest_Statement
+	for i := 0; i < 5; i++ {
+		stmts = append(stmts, stmt(8))
+	}
+	if got := proto.Size(stmts[0]); got != 10 {
+		t.Fatalf("got statement size %d, want 10", got)
+	}
+	for _, test := range []struct {
+		maxStatements, maxBytes int
+		want                    []int
+	}{
+		{0, 0, []int{5}},
+		{2, 0, []int{2, 2, 1}},
+		{5, 0, []int{5}},
+		{0, 25, []int{2, 2, 1}},
+		{0, 30, []int{3, 2}},
+		{2, 30, []int{2, 2, 1}},
+		// A statement that is larger than maxBytes is sent on its own.
+		{0, 5, []int{1, 1, 1, 1, 1}},
+	} {
+		var got []int
+		for _, c := range chunkBatchDmlStatements(stmts, test.maxStatements, test.maxBytes) {
+			got = append(got, len(c))
+		}
+		if !testEqual(got, test.want) {
+			t.Errorf("maxStatements=%d, maxBytes=%d: got chunks %v, want %v", test.maxStatements, test.maxBytes, got, test.want)
+		}
+	}
+}
+
+func TestClient_ReadWriteTransaction_ChunkedBatchUpdate(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	var stmts []Statement
+	var want []int64
+	for i := 0; i < 5; i++ {
+		sql := fmt.Sprintf("UPDATE Singers SET Name='%d' WHERE SingerId=%d", i, i)
+		server.TestSpanner.PutStatementResult(sql, &StatementResult{Type: StatementResultUpdateCount, UpdateCount: int64(i)})
+		stmts = append(stmts, NewStatement(sql))
+		want = append(want, int64(i))
+	}
+	var got []int64
+	if _, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		var err error
+		got, err = tx.ChunkedBatchUpdate(ctx, stmts, ChunkedBatchUpdateOptions{
+			QueryOptions:  QueryOptions{RequestTag: "chunked"},
+			MaxStatements: 2,
+		})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !testEqual(got, want) {
+		t.Errorf("got counts %v, want %v", got, want)
+	}
+
+	var sizes []int
+	var seqnos []int64
+	for _, req := range drainRequestsFromServer(server.TestSpanner) {
+		if req, ok := req.(*sppb.ExecuteBatchDmlRequest); ok {
+			sizes = append(sizes, len(req.Statements))
+			seqnos = append(seqnos, req.Seqno)
+			if got, want := req.RequestOptions.RequestTag, "chunked"; got != want {
+				t.Errorf("got request tag %q, want %q", got, want)
+			}
+		}
+	}
+	if want := []int{2, 2, 1}; !testEqual(sizes, want) {
+		t.Errorf("got chunks %v, want %v", sizes, want)
+	}
+	if len(seqnos) == 3 && !(seqnos[0] < seqnos[1] && seqnos[1] < seqnos[2]) {
+		t.Errorf("got sequence numbers %v, want increasing sequence numbers", seqnos)
+	}
+}
+
+func TestClient_ReadWriteTransaction_ChunkedBatchUpdateError(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	failing := "UPDATE Singers SET Name='fail' WHERE SingerId=1"
+	server.TestSpanner.PutStatementResult(failing, &StatementResult{
+		Type: StatementResultError,
+		Err:  status.Error(codes.AlreadyExists, "duplicate"),
+	})
+	stmts := []Statement{
+		NewStatement(UpdateBarSetFoo),
+		NewStatement(UpdateBarSetFoo),
+		NewStatement(UpdateBarSetFoo),
+		NewStatement(failing),
+		NewStatement(UpdateBarSetFoo),
+	}
+	var got []int64
+	_, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		var err error
+		got, err = tx.ChunkedBatchUpdate(ctx, stmts, ChunkedBatchUpdateOptions{MaxStatements: 2})
+		return err
+	})
+	if ErrCode(err) != codes.AlreadyExists {
+		t.Fatalf("got error %v, want code %v", err, codes.AlreadyExists)
+	}
+	if !strings.Contains(err.Error(), "statement 3 of ChunkedBatchUpdate failed") {
+		t.Errorf("error %q does not mention the index of the failed statement", err)
+	}
+	if want := []int64{UpdateBarSetFooRowCount, UpdateBarSetFooRowCount, UpdateBarSetFooRowCount}; !testEqual(got, want) {
+		t.Errorf("got counts %v, want %v", got, want)
+	}
+	var chunks int
+	for _, req := range drainRequestsFromServer(server.TestSpanner) {
+		if _, ok := req.(*sppb.ExecuteBatchDmlRequest); ok {
+			chunks++
+		}
+	}
+	if chunks != 2 {
+		t.Errorf("got %d BatchUpdate requests, want 2", chunks)
+	}
+}
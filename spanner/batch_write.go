@@ -0,0 +1,360 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	"cloud.google.com/go/internal/version"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/iterator"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// batchWriteMethod is the full name of the BatchWrite RPC. The RPC is not part
+// of the generated Spanner client, so its messages are encoded by hand; see
+// encodeBatchWriteRequest and decodeBatchWriteResponse.
+const batchWriteMethod = "/google.spanner.v1.Spanner/BatchWrite"
+
+// batchWriteClientInfo is the x-goog-api-client header that the generated
+// client would attach to the BatchWrite RPC.
+var batchWriteClientInfo = metadata.Pairs("x-goog-api-client",
+	gax.XGoogHeader("gl-go", version.Go(), "gccl", version.Repo, "gax", gax.Version, "grpc", grpc.Version))
+
+// Field numbers of google.spanner.v1.BatchWriteRequest,
+// BatchWriteRequest.MutationGroup and BatchWriteResponse.
+const (
+	batchWriteRequestSession        = 1
+	batchWriteRequestRequestOptions = 3
+	batchWriteRequestMutationGroups = 4
+	mutationGroupMutations          = 1
+	batchWriteResponseIndexes       = 1
+	batchWriteResponseStatus        = 2
+	batchWriteResponseCommitTs      = 3
+)
+
+// A MutationGroup is a list of Mutations that is applied atomically by
+// BatchWrite. Mutation groups of the same batch are applied independently of
+// each other.
+type MutationGroup struct {
+	// Mutations is the list of mutations of the group.
+	Mutations []*Mutation
+}
+
+// BatchWriteOptions provides options for a BatchWrite request.
+type BatchWriteOptions struct {
+	// Priority is the RPC priority to use for the request.
+	Priority sppb.RequestOptions_Priority
+
+	// TransactionTag is the tag of the transactions that apply the mutation
+	// groups.
+	TransactionTag string
+}
+
+// merge combines two BatchWriteOptions that the input parameter will have
+// higher order of precedence.
+func (bwo BatchWriteOptions) merge(opts BatchWriteOptions) BatchWriteOptions {
+	merged := BatchWriteOptions{
+		Priority:       bwo.Priority,
+		TransactionTag: bwo.TransactionTag,
+	}
+	if opts.Priority != sppb.RequestOptions_PRIORITY_UNSPECIFIED {
+		merged.Priority = opts.Priority
+	}
+	if opts.TransactionTag != "" {
+		merged.TransactionTag = opts.TransactionTag
+	}
+	return merged
+}
+
+// BatchWriteResponse is the result of applying one or more mutation groups of
+// a BatchWrite request.
+type BatchWriteResponse struct {
+	// Indexes are the indexes of the mutation groups in the request that
+	// this response applies to.
+	Indexes []int32
+
+	// Err is nil if the mutation groups were applied, and the reason why they
+	// were not applied otherwise.
+	Err error
+
+	// CommitTimestamp is the commit timestamp of the transaction that applied
+	// the mutation groups. It is zero if Err is not nil.
+	CommitTimestamp time.Time
+}
+
+// BatchWriteResponseIterator is an iterator over the results of a BatchWrite
+// request.
+type BatchWriteResponseIterator struct {
+	ctx    context.Context
+	stream grpc.ClientStream
+	cancel func()
+	// release returns the session of the request to the pool.
+	release func(error)
+	err     error
+}
+
+// BatchWrite applies the mutation groups in a batch. The mutation groups are
+// not applied atomically: each mutation group is applied atomically in a
+// transaction of its own, but the batch may be only partially applied, and
+// mutation groups may be applied in any order. The results of the mutation
+// groups are streamed back and are returned by the iterator.
+//
+// BatchWrite is not idempotent: a mutation group may be applied more than
+// once if the request is retried. Use Apply for writes that must not be
+// repeated.
+func (c *Client) BatchWrite(ctx context.Context, mgs []*MutationGroup) *BatchWriteResponseIterator {
+	return c.BatchWriteWithOptions(ctx, mgs, BatchWriteOptions{})
+}
+
+// BatchWriteWithOptions is the same as BatchWrite, but with options.
+func (c *Client) BatchWriteWithOptions(ctx context.Context, mgs []*MutationGroup, opts BatchWriteOptions) *BatchWriteResponseIterator {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/spanner.BatchWrite")
+	iter := &BatchWriteResponseIterator{ctx: ctx}
+	opts = c.bwo.merge(opts)
+	if len(mgs) == 0 {
+		iter.err = spannerErrorf(codes.InvalidArgument, "no mutation groups to write")
+		return iter
+	}
+	groups := make([][]*sppb.Mutation, len(mgs))
+	for i, mg := range mgs {
+		mPb, err := mutationsProto(mg.Mutations)
+		if err != nil {
+			iter.err = err
+			return iter
+		}
+		groups[i] = mPb
+	}
+
+	sh, err := c.idleSessions.take(ctx)
+	if err != nil {
+		iter.err = err
+		return iter
+	}
+	iter.release = func(err error) {
+		if isSessionNotFoundError(err) {
+			sh.destroy()
+		}
+		sh.recycle()
+	}
+	req, err := encodeBatchWriteRequest(sh.getID(), createRequestOptions(opts.Priority, "", opts.TransactionTag), groups)
+	if err != nil {
+		iter.err = err
+		return iter
+	}
+	sctx, cancel := context.WithCancel(contextWithOutgoingMetadata(ctx, withRouteToLeader(metadata.Join(sh.getMetadata(), batchWriteClientInfo), !c.disableRouteToLeader)))
+	iter.cancel = cancel
+	iter.stream, iter.err = sh.getClient().Connection().NewStream(sctx, &grpc.StreamDesc{ServerStreams: true}, batchWriteMethod)
+	if iter.err == nil {
+		iter.err = iter.stream.SendMsg(req)
+	}
+	if iter.err == nil {
+		iter.err = iter.stream.CloseSend()
+	}
+	if iter.err != nil {
+		iter.err = ToSpannerError(iter.err)
+	}
+	return iter
+}
+
+// Next returns the next result. Its second return value is iterator.Done if
+// there are no more results. Once Next returns Done, all subsequent calls will
+// return Done.
+func (r *BatchWriteResponseIterator) Next() (*BatchWriteResponse, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	msg := &emptypb.Empty{}
+	if err := r.stream.RecvMsg(msg); err != nil {
+		if err == io.EOF {
+			r.err = iterator.Done
+		} else {
+			r.err = ToSpannerError(err)
+		}
+		return nil, r.err
+	}
+	resp, err := decodeBatchWriteResponse(msg.ProtoReflect().GetUnknown())
+	if err != nil {
+		r.err = err
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Do calls the provided function once in sequence for each result of the
+// request. It stops iterating and returns the error if f returns an error, or
+// if the request fails.
+//
+// Do always calls Stop on the iterator.
+func (r *BatchWriteResponseIterator) Do(f func(r *BatchWriteResponse) error) error {
+	defer r.Stop()
+	for {
+		resp, err := r.Next()
+		switch err {
+		case iterator.Done:
+			return nil
+		case nil:
+			if err = f(resp); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// Stop terminates the iteration. It should be called after you finish using
+// the iterator.
+func (r *BatchWriteResponseIterator) Stop() {
+	if r.ctx != nil {
+		if r.err != nil && r.err != iterator.Done {
+			trace.EndSpan(r.ctx, r.err)
+		} else {
+			trace.EndSpan(r.ctx, nil)
+		}
+		r.ctx = nil
+	}
+	if r.cancel != nil {
+		r.cancel()
+	}
+	if r.release != nil {
+		r.release(r.err)
+		r.release = nil
+	}
+	if r.err == nil {
+		r.err = spannerErrorf(codes.FailedPrecondition, "Next called after Stop")
+	}
+}
+
+// encodeBatchWriteRequest returns a message whose wire format is the
+// BatchWriteRequest for the given session, request options and mutation
+// groups.
+func encodeBatchWriteRequest(session string, ro *sppb.RequestOptions, groups [][]*sppb.Mutation) (*emptypb.Empty, error) {
+	var b []byte
+	b = protowire.AppendTag(b, batchWriteRequestSession, protowire.BytesType)
+	b = protowire.AppendString(b, session)
+	rob, err := proto.Marshal(ro)
+	if err != nil {
+		return nil, err
+	}
+	b = protowire.AppendTag(b, batchWriteRequestRequestOptions, protowire.BytesType)
+	b = protowire.AppendBytes(b, rob)
+	for _, g := range groups {
+		var gb []byte
+		for _, m := range g {
+			mb, err := proto.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+			gb = protowire.AppendTag(gb, mutationGroupMutations, protowire.BytesType)
+			gb = protowire.AppendBytes(gb, mb)
+		}
+		b = protowire.AppendTag(b, batchWriteRequestMutationGroups, protowire.BytesType)
+		b = protowire.AppendBytes(b, gb)
+	}
+	msg := &emptypb.Empty{}
+	msg.ProtoReflect().SetUnknown(b)
+	return msg, nil
+}
+
+// decodeBatchWriteResponse decodes the wire format of a BatchWriteResponse.
+func decodeBatchWriteResponse(b []byte) (*BatchWriteResponse, error) {
+	resp := &BatchWriteResponse{}
+	var st *statuspb.Status
+	var ts *timestamppb.Timestamp
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, errMalformedBatchWriteResponse(protowire.ParseError(n))
+		}
+		b = b[n:]
+		switch {
+		case num == batchWriteResponseIndexes && typ == protowire.BytesType:
+			// Packed repeated int32.
+			v, m := protowire.ConsumeBytes(b)
+			if m < 0 {
+				return nil, errMalformedBatchWriteResponse(protowire.ParseError(m))
+			}
+			for len(v) > 0 {
+				x, k := protowire.ConsumeVarint(v)
+				if k < 0 {
+					return nil, errMalformedBatchWriteResponse(protowire.ParseError(k))
+				}
+				resp.Indexes = append(resp.Indexes, int32(x))
+				v = v[k:]
+			}
+			n = m
+		case num == batchWriteResponseIndexes && typ == protowire.VarintType:
+			x, m := protowire.ConsumeVarint(b)
+			if m < 0 {
+				return nil, errMalformedBatchWriteResponse(protowire.ParseError(m))
+			}
+			resp.Indexes = append(resp.Indexes, int32(x))
+			n = m
+		case num == batchWriteResponseStatus && typ == protowire.BytesType:
+			v, m := protowire.ConsumeBytes(b)
+			if m < 0 {
+				return nil, errMalformedBatchWriteResponse(protowire.ParseError(m))
+			}
+			st = &statuspb.Status{}
+			if err := proto.Unmarshal(v, st); err != nil {
+				return nil, errMalformedBatchWriteResponse(err)
+			}
+			n = m
+		case num == batchWriteResponseCommitTs && typ == protowire.BytesType:
+			v, m := protowire.ConsumeBytes(b)
+			if m < 0 {
+				return nil, errMalformedBatchWriteResponse(protowire.ParseError(m))
+			}
+			ts = &timestamppb.Timestamp{}
+			if err := proto.Unmarshal(v, ts); err != nil {
+				return nil, errMalformedBatchWriteResponse(err)
+			}
+			n = m
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, errMalformedBatchWriteResponse(protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	if st != nil && codes.Code(st.Code) != codes.OK {
+		resp.Err = ToSpannerError(status.ErrorProto(st))
+	} else if ts != nil {
+		resp.CommitTimestamp = ts.AsTime()
+	}
+	return resp, nil
+}
+
+// errMalformedBatchWriteResponse returns error for a BatchWriteResponse that
+// could not be decoded.
+func errMalformedBatchWriteResponse(err error) error {
+	return spannerErrorf(codes.Internal, "malformed BatchWriteResponse: %v", err)
+}
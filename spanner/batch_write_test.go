@@ -0,0 +1,173 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestClient_BatchWrite(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	mgs := []*MutationGroup{
+		{Mutations: []*Mutation{
+			Insert("Singers", []string{"SingerId", "Name"}, []interface{}{int64(1), "One"}),
+			Insert("Albums", []string{"SingerId", "AlbumId"}, []interface{}{int64(1), int64(1)}),
+		}},
+		{Mutations: []*Mutation{
+			Insert("Singers", []string{"SingerId", "Name"}, []interface{}{int64(2), "Two"}),
+		}},
+	}
+	iter := client.BatchWriteWithOptions(context.Background(), mgs, BatchWriteOptions{
+		Priority:       sppb.RequestOptions_PRIORITY_LOW,
+		TransactionTag: "ingest",
+	})
+	var got [][]int32
+	err := iter.Do(func(r *BatchWriteResponse) error {
+		if r.Err != nil {
+			t.Errorf("group %v: unexpected error: %v", r.Indexes, r.Err)
+		}
+		if r.CommitTimestamp.IsZero() {
+			t.Errorf("group %v: missing commit timestamp", r.Indexes)
+		}
+		got = append(got, r.Indexes)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int32{{0}, {1}}; !testEqual(got, want) {
+		t.Errorf("got indexes %v, want %v", got, want)
+	}
+
+	var req *BatchWriteRequest
+	for _, r := range drainRequestsFromServer(server.TestSpanner) {
+		if bw, ok := r.(*BatchWriteRequest); ok {
+			req = bw
+		}
+	}
+	if req == nil {
+		t.Fatal("no BatchWrite request was sent")
+	}
+	if req.Session == "" {
+		t.Error("missing session in request")
+	}
+	if got, want := len(req.MutationGroups), 2; got != want {
+		t.Fatalf("got %d mutation groups, want %d", got, want)
+	}
+	if got, want := len(req.MutationGroups[0]), 2; got != want {
+		t.Errorf("got %d mutations in group 0, want %d", got, want)
+	}
+	if got, want := req.MutationGroups[1][0].GetInsert().GetTable(), "Singers"; got != want {
+		t.Errorf("got table %q, want %q", got, want)
+	}
+	wantOpts := &sppb.RequestOptions{Priority: sppb.RequestOptions_PRIORITY_LOW, TransactionTag: "ingest"}
+	if !testEqual(req.RequestOptions, wantOpts) {
+		t.Errorf("got request options %v, want %v", req.RequestOptions, wantOpts)
+	}
+}
+
+func TestClient_BatchWrite_error(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	server.TestSpanner.PutExecutionTime(MethodBatchWrite, SimulatedExecutionTime{
+		Errors: []error{status.Error(codes.PermissionDenied, "not allowed")},
+	})
+	mgs := []*MutationGroup{{Mutations: []*Mutation{Delete("Singers", AllKeys())}}}
+	iter := client.BatchWrite(context.Background(), mgs)
+	defer iter.Stop()
+	if _, err := iter.Next(); ErrCode(err) != codes.PermissionDenied {
+		t.Fatalf("got error %v, want code %v", err, codes.PermissionDenied)
+	}
+}
+
+func TestClient_BatchWrite_noGroups(t *testing.T) {
+	t.Parallel()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	err := client.BatchWrite(context.Background(), nil).Do(func(*BatchWriteResponse) error { return nil })
+	if ErrCode(err) != codes.InvalidArgument {
+		t.Fatalf("got error %v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestDecodeBatchWriteResponse(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	tsb, err := proto.Marshal(timestamppb.New(ts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stb, err := proto.Marshal(&statuspb.Status{Code: int32(codes.FailedPrecondition), Message: "duplicate key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes := protowire.AppendVarint(protowire.AppendVarint(nil, 2), 5)
+
+	var applied []byte
+	applied = protowire.AppendTag(applied, batchWriteResponseIndexes, protowire.BytesType)
+	applied = protowire.AppendBytes(applied, indexes)
+	applied = protowire.AppendTag(applied, batchWriteResponseStatus, protowire.BytesType)
+	applied = protowire.AppendBytes(applied, nil)
+	applied = protowire.AppendTag(applied, batchWriteResponseCommitTs, protowire.BytesType)
+	applied = protowire.AppendBytes(applied, tsb)
+	got, err := decodeBatchWriteResponse(applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&BatchWriteResponse{Indexes: []int32{2, 5}, CommitTimestamp: ts}); !testEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	var failed []byte
+	failed = protowire.AppendTag(failed, batchWriteResponseIndexes, protowire.VarintType)
+	failed = protowire.AppendVarint(failed, 3)
+	failed = protowire.AppendTag(failed, batchWriteResponseStatus, protowire.BytesType)
+	failed = protowire.AppendBytes(failed, stb)
+	got, err = decodeBatchWriteResponse(failed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !testEqual(got.Indexes, []int32{3}) {
+		t.Errorf("got indexes %v, want [3]", got.Indexes)
+	}
+	if ErrCode(got.Err) != codes.FailedPrecondition || !got.CommitTimestamp.IsZero() {
+		t.Errorf("got (%v, %v), want FailedPrecondition error and zero commit timestamp", got.Err, got.CommitTimestamp)
+	}
+
+	if _, err := decodeBatchWriteResponse([]byte{0x0a, 0x05}); ErrCode(err) != codes.Internal {
+		t.Errorf("got error %v for truncated response, want code %v", err, codes.Internal)
+	}
+}
@@ -0,0 +1,670 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	proto3 "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/api/iterator"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultChangeStreamHeartbeatInterval is the interval at which a
+// ChangeStreamReader asks for heartbeat records by default.
+const DefaultChangeStreamHeartbeatInterval = 10 * time.Second
+
+// ChangeStreamReaderOptions configures a ChangeStreamReader.
+type ChangeStreamReaderOptions struct {
+	// StartTime is the commit time of the first changes to read. If it is
+	// zero, the reader starts at the time of the first call to Read. It is
+	// ignored if Checkpoint is set.
+	StartTime time.Time
+
+	// EndTime is the commit time after which changes are not read. If it is
+	// zero, Read reads changes until its context is done.
+	EndTime time.Time
+
+	// HeartbeatInterval is the interval at which the partitions that have no
+	// changes report their progress. It bounds how long the watermark of a
+	// quiet partition lags behind. If it is zero,
+	// DefaultChangeStreamHeartbeatInterval is used.
+	HeartbeatInterval time.Duration
+
+	// MaxConcurrentPartitions is the maximum number of partitions read
+	// concurrently. Each partition holds a session of the client while it is
+	// read. If it is zero, there is no limit.
+	MaxConcurrentPartitions int
+
+	// Checkpoint, if not nil, resumes reading where a previous reader of the
+	// same change stream stopped, as recorded by its Checkpoint method.
+	Checkpoint *ChangeStreamCheckpoint
+}
+
+// ChangeStreamCheckpoint records the progress of a ChangeStreamReader, so
+// that reading can be resumed from it. It can be encoded as JSON to be
+// persisted.
+type ChangeStreamCheckpoint struct {
+	// Partitions are the partitions of the change stream that were not read
+	// to their end.
+	Partitions []ChangeStreamPartition
+}
+
+// ChangeStreamPartition is a partition of a change stream, and the time from
+// which it is to be read.
+type ChangeStreamPartition struct {
+	// Token identifies the partition. It is empty for the initial query of
+	// a change stream, which returns the first partitions.
+	Token string
+
+	// ParentTokens are the tokens of the partitions that the partition
+	// replaces. The partition is read after its parents have been read to
+	// their end, so that the changes of each key are delivered in order.
+	ParentTokens []string
+
+	// StartTime is the commit time from which the partition is to be read.
+	StartTime time.Time
+}
+
+// Watermark returns the earliest StartTime of the partitions of cp: all the
+// changes committed before it have been read. It returns the zero time if cp
+// has no partitions.
+func (cp *ChangeStreamCheckpoint) Watermark() time.Time {
+	var w time.Time
+	for _, p := range cp.Partitions {
+		if w.IsZero() || p.StartTime.Before(w) {
+			w = p.StartTime
+		}
+	}
+	return w
+}
+
+// ModType is the type of change of a DataChangeRecord.
+type ModType string
+
+// The types of changes.
+const (
+	ModTypeInsert ModType = "INSERT"
+	ModTypeUpdate ModType = "UPDATE"
+	ModTypeDelete ModType = "DELETE"
+)
+
+// DataChangeRecord holds the changes made by a transaction to a table, in a
+// partition of a change stream.
+type DataChangeRecord struct {
+	// PartitionToken is the token of the partition the record was read from.
+	PartitionToken string
+
+	// CommitTimestamp is the commit time of the transaction.
+	CommitTimestamp time.Time
+
+	// RecordSequence orders the records of the transaction in the partition.
+	RecordSequence string
+
+	// ServerTransactionID identifies the transaction. It is unique among the
+	// transactions of the database, and the same in all partitions.
+	ServerTransactionID string
+
+	// IsLastRecordInTransactionInPartition reports whether this is the last
+	// record of the transaction in the partition.
+	IsLastRecordInTransactionInPartition bool
+
+	// TableName is the name of the changed table.
+	TableName string
+
+	// ColumnTypes are the columns of the table that the record reports on.
+	ColumnTypes []*ChangeStreamColumnType
+
+	// Mods are the changes, one per changed row.
+	Mods []*Mod
+
+	// ModType is the type of the changes.
+	ModType ModType
+
+	// ValueCaptureType is the value capture type of the change stream, such
+	// as "OLD_AND_NEW_VALUES".
+	ValueCaptureType string
+
+	// NumberOfRecordsInTransaction and NumberOfPartitionsInTransaction are
+	// the numbers of records and partitions that hold the changes of the
+	// transaction.
+	NumberOfRecordsInTransaction    int64
+	NumberOfPartitionsInTransaction int64
+
+	// TransactionTag is the tag of the transaction.
+	TransactionTag string
+
+	// IsSystemTransaction reports whether the transaction was run by Spanner,
+	// such as to delete rows expired by a row deletion policy.
+	IsSystemTransaction bool
+}
+
+// ChangeStreamColumnType describes a column reported on by a
+// DataChangeRecord.
+type ChangeStreamColumnType struct {
+	Name            string
+	Type            *sppb.Type
+	IsPrimaryKey    bool
+	OrdinalPosition int64
+}
+
+// Mod is the change of a row. The values are decoded from JSON, in which
+// INT64 values are strings, to preserve their precision.
+type Mod struct {
+	// Keys are the values of the primary key columns of the row.
+	Keys map[string]interface{}
+
+	// NewValues and OldValues are the values of the changed columns after
+	// and before the change, as selected by the value capture type of the
+	// change stream.
+	NewValues map[string]interface{}
+	OldValues map[string]interface{}
+}
+
+// ChangeStreamReader reads the changes recorded by a change stream. It reads
+// the partitions of the change stream concurrently, following their splits
+// and merges, and keeps track of how far each has been read.
+type ChangeStreamReader struct {
+	c     *Client
+	name  string
+	opts  ChangeStreamReaderOptions
+	query func(context.Context, Statement) rowIterator
+
+	mu sync.Mutex
+	// parts are the partitions that were not read to their end. It is nil
+	// until Read is first called.
+	parts map[string]*csPartition
+	// done are the tokens of the partitions that were read to their end.
+	done map[string]bool
+}
+
+// rowIterator is the part of *RowIterator that ChangeStreamReader uses.
+type rowIterator interface {
+	Next() (*Row, error)
+	Stop()
+}
+
+type csPartition struct {
+	ChangeStreamPartition
+	running bool
+}
+
+// ChangeStreamReader returns a reader of the change stream with the given
+// name.
+func (c *Client) ChangeStreamReader(name string, opts ChangeStreamReaderOptions) *ChangeStreamReader {
+	if opts.HeartbeatInterval <= 0 {
+		opts.HeartbeatInterval = DefaultChangeStreamHeartbeatInterval
+	}
+	return &ChangeStreamReader{
+		c:    c,
+		name: name,
+		opts: opts,
+		query: func(ctx context.Context, stmt Statement) rowIterator {
+			return c.Single().Query(ctx, stmt)
+		},
+	}
+}
+
+// Read calls f with the data change records of the change stream.
+//
+// Read calls f concurrently for records of different partitions. The records
+// of a partition are delivered in order, one at a time, and a partition is
+// read once the partitions it replaces have been read to their end, so the
+// changes of each key are delivered in commit order. Heartbeat and child
+// partition records are handled by Read.
+//
+// A record is recorded as read once f returns nil for it. If f returns an
+// error, Read stops and returns it. The checkpoint of a reader resumes at
+// the commit time of the last record read of each partition, so some records
+// may be delivered again when reading resumes.
+//
+// Read blocks until ctx is done, in which case it returns nil, until all
+// partitions have been read up to EndTime, or until a query fails. A reader
+// may be read again after Read returns, to continue where it stopped, but
+// only by one call to Read at a time.
+func (r *ChangeStreamReader) Read(ctx context.Context, f func(context.Context, *DataChangeRecord) error) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/spanner.ChangeStreamReader.Read")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if r.name == "" {
+		return spannerErrorf(codes.InvalidArgument, "change stream name is empty")
+	}
+	rd := &csRead{r: r, f: f}
+	rd.ctx, rd.cancel = context.WithCancel(ctx)
+	defer rd.cancel()
+
+	r.mu.Lock()
+	if r.parts == nil {
+		r.parts = map[string]*csPartition{}
+		r.done = map[string]bool{}
+		if cp := r.opts.Checkpoint; cp != nil {
+			for _, p := range cp.Partitions {
+				r.parts[p.Token] = &csPartition{ChangeStreamPartition: p}
+			}
+		} else {
+			start := r.opts.StartTime
+			if start.IsZero() {
+				start = time.Now()
+			}
+			r.parts[""] = &csPartition{ChangeStreamPartition: ChangeStreamPartition{StartTime: start}}
+		}
+	}
+	rd.scheduleLocked()
+	r.mu.Unlock()
+
+	rd.wg.Wait()
+	return rd.err
+}
+
+// Checkpoint returns the progress of the reader, to resume reading from with
+// ChangeStreamReaderOptions.Checkpoint. It is safe to call while Read runs.
+func (r *ChangeStreamReader) Checkpoint() ChangeStreamCheckpoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.parts == nil {
+		if r.opts.Checkpoint != nil {
+			return *r.opts.Checkpoint
+		}
+		return ChangeStreamCheckpoint{}
+	}
+	var cp ChangeStreamCheckpoint
+	for _, p := range r.parts {
+		cp.Partitions = append(cp.Partitions, p.ChangeStreamPartition)
+	}
+	return cp
+}
+
+// Watermark returns the time before which all the changes of the change
+// stream have been read. It returns the zero time if no partitions remain
+// to be read.
+func (r *ChangeStreamReader) Watermark() time.Time {
+	cp := r.Checkpoint()
+	return cp.Watermark()
+}
+
+// csRead is the state of a call to ChangeStreamReader.Read.
+type csRead struct {
+	r      *ChangeStreamReader
+	f      func(context.Context, *DataChangeRecord) error
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// Guarded by r.mu.
+	running int
+	err     error
+}
+
+// scheduleLocked starts reading the partitions whose parents have been read,
+// up to MaxConcurrentPartitions. A parent that the reader does not know of,
+// as when resuming from a checkpoint, is taken to have been read.
+//
+// Must be called with r.mu held.
+func (rd *csRead) scheduleLocked() {
+	r := rd.r
+	if rd.ctx.Err() != nil {
+		return
+	}
+	for _, p := range r.parts {
+		if max := r.opts.MaxConcurrentPartitions; max > 0 && rd.running >= max {
+			return
+		}
+		if p.running || !rd.parentsDoneLocked(p) {
+			continue
+		}
+		p.running = true
+		rd.running++
+		rd.wg.Add(1)
+		go func(p *csPartition) {
+			defer rd.wg.Done()
+			err := rd.readPartition(p)
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			p.running = false
+			rd.running--
+			switch {
+			case err == nil:
+				delete(r.parts, p.Token)
+				r.done[p.Token] = true
+			case rd.ctx.Err() != nil:
+				// Read is stopping; the partition is resumed by the next one.
+				return
+			default:
+				if rd.err == nil {
+					rd.err = err
+				}
+				rd.cancel()
+				return
+			}
+			rd.scheduleLocked()
+		}(p)
+	}
+}
+
+func (rd *csRead) parentsDoneLocked(p *csPartition) bool {
+	for _, t := range p.ParentTokens {
+		if _, ok := rd.r.parts[t]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// readPartition reads p to its end. It returns nil once the query of p has
+// no more records.
+func (rd *csRead) readPartition(p *csPartition) error {
+	r := rd.r
+	r.mu.Lock()
+	start := p.StartTime
+	r.mu.Unlock()
+	iter := r.query(rd.ctx, r.statement(p.Token, start))
+	defer iter.Stop()
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		recs, err := decodeChangeRecords(row)
+		if err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			if err := rd.handle(p, rec); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (rd *csRead) handle(p *csPartition, rec *csRecord) error {
+	r := rd.r
+	switch {
+	case rec.data != nil:
+		rec.data.PartitionToken = p.Token
+		if err := rd.f(rd.ctx, rec.data); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		p.StartTime = rec.data.CommitTimestamp
+		r.mu.Unlock()
+	case !rec.heartbeat.IsZero():
+		r.mu.Lock()
+		p.StartTime = rec.heartbeat
+		r.mu.Unlock()
+	default:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for _, c := range rec.children {
+			// A partition that results from a merge is reported by each of
+			// its parents; read it once.
+			if _, ok := r.parts[c.Token]; ok || r.done[c.Token] {
+				continue
+			}
+			r.parts[c.Token] = &csPartition{ChangeStreamPartition: c}
+		}
+		if rec.childrenStart.After(p.StartTime) {
+			p.StartTime = rec.childrenStart
+		}
+		rd.scheduleLocked()
+	}
+	return nil
+}
+
+// statement returns the query that reads the partition with the given token
+// from start.
+func (r *ChangeStreamReader) statement(token string, start time.Time) Statement {
+	return Statement{
+		SQL: "SELECT ChangeRecord FROM READ_" + r.name + "(" +
+			"start_timestamp => @start_timestamp, " +
+			"end_timestamp => @end_timestamp, " +
+			"partition_token => @partition_token, " +
+			"heartbeat_milliseconds => @heartbeat_milliseconds)",
+		Params: map[string]interface{}{
+			"start_timestamp":        start,
+			"end_timestamp":          NullTime{Time: r.opts.EndTime, Valid: !r.opts.EndTime.IsZero()},
+			"partition_token":        NullString{StringVal: token, Valid: token != ""},
+			"heartbeat_milliseconds": int64(r.opts.HeartbeatInterval / time.Millisecond),
+		},
+	}
+}
+
+// csRecord is one of the records of a ChangeRecord: a data change record, a
+// heartbeat or the child partitions that replace a partition.
+type csRecord struct {
+	data          *DataChangeRecord
+	heartbeat     time.Time
+	children      []ChangeStreamPartition
+	childrenStart time.Time
+}
+
+// decodeChangeRecords decodes the ChangeRecord column of a row of a change
+// stream query. The fields of the records are looked up by name, so that
+// fields added to the records by newer versions of Spanner are ignored.
+func decodeChangeRecords(row *Row) ([]*csRecord, error) {
+	if len(row.fields) == 0 {
+		return nil, spannerErrorf(codes.FailedPrecondition, "change stream row has no columns")
+	}
+	crs, err := csStructs(row.vals[0], row.fields[0].Type)
+	if err != nil {
+		return nil, err
+	}
+	var recs []*csRecord
+	for _, cr := range crs {
+		dcrs, err := cr.structs("data_change_record")
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range dcrs {
+			d, err := s.dataChangeRecord()
+			if err != nil {
+				return nil, err
+			}
+			recs = append(recs, &csRecord{data: d})
+		}
+		hbs, err := cr.structs("heartbeat_record")
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range hbs {
+			rec := &csRecord{}
+			if err := s.decode("timestamp", &rec.heartbeat); err != nil {
+				return nil, err
+			}
+			recs = append(recs, rec)
+		}
+		cprs, err := cr.structs("child_partitions_record")
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range cprs {
+			rec := &csRecord{}
+			if err := s.decode("start_timestamp", &rec.childrenStart); err != nil {
+				return nil, err
+			}
+			cps, err := s.structs("child_partitions")
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range cps {
+				p := ChangeStreamPartition{StartTime: rec.childrenStart}
+				if err := c.decode("token", &p.Token); err != nil {
+					return nil, err
+				}
+				if err := c.decode("parent_partition_tokens", &p.ParentTokens); err != nil {
+					return nil, err
+				}
+				rec.children = append(rec.children, p)
+			}
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
+
+func (s csStruct) dataChangeRecord() (*DataChangeRecord, error) {
+	d := &DataChangeRecord{}
+	var modType string
+	for name, ptr := range map[string]interface{}{
+		"commit_timestamp":                           &d.CommitTimestamp,
+		"record_sequence":                            &d.RecordSequence,
+		"server_transaction_id":                      &d.ServerTransactionID,
+		"is_last_record_in_transaction_in_partition": &d.IsLastRecordInTransactionInPartition,
+		"table_name":                                 &d.TableName,
+		"mod_type":                                   &modType,
+		"value_capture_type":                         &d.ValueCaptureType,
+		"number_of_records_in_transaction":           &d.NumberOfRecordsInTransaction,
+		"number_of_partitions_in_transaction":        &d.NumberOfPartitionsInTransaction,
+		"transaction_tag":                            &d.TransactionTag,
+		"is_system_transaction":                      &d.IsSystemTransaction,
+	} {
+		if err := s.decode(name, ptr); err != nil {
+			return nil, err
+		}
+	}
+	d.ModType = ModType(modType)
+	cts, err := s.structs("column_types")
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cts {
+		ct := &ChangeStreamColumnType{}
+		if err := c.decode("name", &ct.Name); err != nil {
+			return nil, err
+		}
+		if err := c.decode("is_primary_key", &ct.IsPrimaryKey); err != nil {
+			return nil, err
+		}
+		if err := c.decode("ordinal_position", &ct.OrdinalPosition); err != nil {
+			return nil, err
+		}
+		if t := c.json("type"); t != "" {
+			ct.Type = &sppb.Type{}
+			if err := protojson.Unmarshal([]byte(t), ct.Type); err != nil {
+				return nil, spannerErrorf(codes.FailedPrecondition, "decoding type of column %s: %v", ct.Name, err)
+			}
+		}
+		d.ColumnTypes = append(d.ColumnTypes, ct)
+	}
+	mods, err := s.structs("mods")
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mods {
+		mod := &Mod{}
+		for name, ptr := range map[string]*map[string]interface{}{
+			"keys":       &mod.Keys,
+			"new_values": &mod.NewValues,
+			"old_values": &mod.OldValues,
+		} {
+			v := m.json(name)
+			if v == "" {
+				continue
+			}
+			if err := json.Unmarshal([]byte(v), ptr); err != nil {
+				return nil, spannerErrorf(codes.FailedPrecondition, "decoding %s of mod: %v", name, err)
+			}
+		}
+		d.Mods = append(d.Mods, mod)
+	}
+	return d, nil
+}
+
+// csStruct is a STRUCT value of a change stream record.
+type csStruct struct {
+	ty   *sppb.StructType
+	vals []*proto3.Value
+}
+
+// csStructs returns the elements of the ARRAY<STRUCT> value v of type t.
+func csStructs(v *proto3.Value, t *sppb.Type) ([]csStruct, error) {
+	if _, ok := v.GetKind().(*proto3.Value_NullValue); ok {
+		return nil, nil
+	}
+	if t.GetCode() != sppb.TypeCode_ARRAY || t.GetArrayElementType().GetCode() != sppb.TypeCode_STRUCT {
+		return nil, errTypeMismatch(t.GetCode(), t.GetArrayElementType().GetCode(), &[]csStruct{})
+	}
+	lv, err := getListValue(v)
+	if err != nil {
+		return nil, err
+	}
+	st := t.GetArrayElementType().GetStructType()
+	var out []csStruct
+	for _, ev := range lv.Values {
+		if _, ok := ev.GetKind().(*proto3.Value_NullValue); ok {
+			continue
+		}
+		elv, err := getListValue(ev)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, csStruct{ty: st, vals: elv.Values})
+	}
+	return out, nil
+}
+
+// field returns the value and type of the field with the given name, or nil
+// if s has no such field.
+func (s csStruct) field(name string) (*proto3.Value, *sppb.Type) {
+	for i, f := range s.ty.GetFields() {
+		if f.Name == name && i < len(s.vals) {
+			return s.vals[i], f.Type
+		}
+	}
+	return nil, nil
+}
+
+// decode decodes the field with the given name into ptr. It leaves ptr
+// unchanged if s has no such field, or if the field is NULL.
+func (s csStruct) decode(name string, ptr interface{}) error {
+	v, t := s.field(name)
+	if v == nil {
+		return nil
+	}
+	if _, ok := v.GetKind().(*proto3.Value_NullValue); ok {
+		return nil
+	}
+	if err := decodeValue(v, t, ptr); err != nil {
+		return spannerErrorf(codes.FailedPrecondition, "decoding change record field %s: %v", name, err)
+	}
+	return nil
+}
+
+// structs returns the elements of the ARRAY<STRUCT> field with the given
+// name.
+func (s csStruct) structs(name string) ([]csStruct, error) {
+	v, t := s.field(name)
+	if v == nil {
+		return nil, nil
+	}
+	return csStructs(v, t)
+}
+
+// json returns the JSON text of the field with the given name, which is of
+// type JSON or STRING depending on the dialect and version of the database.
+func (s csStruct) json(name string) string {
+	v, _ := s.field(name)
+	return v.GetStringValue()
+}
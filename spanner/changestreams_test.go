@@ -0,0 +1,333 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/internal/testutil"
+	proto3 "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/api/iterator"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+)
+
+// The types of the records of a change stream, to encode test rows.
+type (
+	testChangeRecord struct {
+		DataChangeRecord      []*testDataChangeRecord      `spanner:"data_change_record"`
+		HeartbeatRecord       []*testHeartbeatRecord       `spanner:"heartbeat_record"`
+		ChildPartitionsRecord []*testChildPartitionsRecord `spanner:"child_partitions_record"`
+	}
+	testDataChangeRecord struct {
+		CommitTimestamp     time.Time         `spanner:"commit_timestamp"`
+		RecordSequence      string            `spanner:"record_sequence"`
+		ServerTransactionID string            `spanner:"server_transaction_id"`
+		TableName           string            `spanner:"table_name"`
+		ColumnTypes         []*testColumnType `spanner:"column_types"`
+		Mods                []*testMod        `spanner:"mods"`
+		ModType             string            `spanner:"mod_type"`
+		// A field that the reader does not know of.
+		Unknown int64 `spanner:"unknown"`
+	}
+	testColumnType struct {
+		Name         string   `spanner:"name"`
+		Type         NullJSON `spanner:"type"`
+		IsPrimaryKey bool     `spanner:"is_primary_key"`
+	}
+	testMod struct {
+		Keys      NullJSON `spanner:"keys"`
+		NewValues NullJSON `spanner:"new_values"`
+		OldValues NullJSON `spanner:"old_values"`
+	}
+	testHeartbeatRecord struct {
+		Timestamp time.Time `spanner:"timestamp"`
+	}
+	testChildPartitionsRecord struct {
+		StartTimestamp  time.Time             `spanner:"start_timestamp"`
+		ChildPartitions []*testChildPartition `spanner:"child_partitions"`
+	}
+	testChildPartition struct {
+		Token                 string   `spanner:"token"`
+		ParentPartitionTokens []string `spanner:"parent_partition_tokens"`
+	}
+)
+
+func changeStreamRow(t *testing.T, crs ...*testChangeRecord) *Row {
+	t.Helper()
+	v, ty, err := encodeValue(crs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Row{
+		fields: []*sppb.StructType_Field{{Name: "ChangeRecord", Type: ty}},
+		vals:   []*proto3.Value{v},
+	}
+}
+
+func dataRow(t *testing.T, ts time.Time, table string) *Row {
+	return changeStreamRow(t, &testChangeRecord{DataChangeRecord: []*testDataChangeRecord{{
+		CommitTimestamp: ts,
+		TableName:       table,
+		ModType:         "INSERT",
+	}}})
+}
+
+func heartbeatRow(t *testing.T, ts time.Time) *Row {
+	return changeStreamRow(t, &testChangeRecord{HeartbeatRecord: []*testHeartbeatRecord{{Timestamp: ts}}})
+}
+
+func childrenRow(t *testing.T, ts time.Time, children ...*testChildPartition) *Row {
+	return changeStreamRow(t, &testChangeRecord{ChildPartitionsRecord: []*testChildPartitionsRecord{{
+		StartTimestamp:  ts,
+		ChildPartitions: children,
+	}}})
+}
+
+type fakeRowIterator struct {
+	rows []*Row
+	err  error
+}
+
+func (it *fakeRowIterator) Next() (*Row, error) {
+	if len(it.rows) == 0 {
+		if it.err != nil {
+			return nil, it.err
+		}
+		return nil, iterator.Done
+	}
+	r := it.rows[0]
+	it.rows = it.rows[1:]
+	return r, nil
+}
+
+func (it *fakeRowIterator) Stop() {}
+
+// fakeChangeStream returns a ChangeStreamReader whose queries return the
+// rows of partitions, by token, and records the queries.
+func fakeChangeStream(opts ChangeStreamReaderOptions, partitions map[string][]*Row) (*ChangeStreamReader, func() []string) {
+	r := (&Client{}).ChangeStreamReader("Stream", opts)
+	var mu sync.Mutex
+	var queried []string
+	r.query = func(_ context.Context, stmt Statement) rowIterator {
+		token := stmt.Params["partition_token"].(NullString).StringVal
+		mu.Lock()
+		queried = append(queried, token)
+		mu.Unlock()
+		return &fakeRowIterator{rows: partitions[token]}
+	}
+	return r, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), queried...)
+	}
+}
+
+func TestDecodeChangeRecords(t *testing.T) {
+	ts := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	row := changeStreamRow(t, &testChangeRecord{DataChangeRecord: []*testDataChangeRecord{{
+		CommitTimestamp:     ts,
+		RecordSequence:      "00000001",
+		ServerTransactionID: "tx",
+		TableName:           "Singers",
+		ColumnTypes: []*testColumnType{
+			{Name: "SingerId", Type: NullJSON{Value: map[string]string{"code": "INT64"}, Valid: true}, IsPrimaryKey: true},
+		},
+		Mods: []*testMod{{
+			Keys:      NullJSON{Value: map[string]string{"SingerId": "1"}, Valid: true},
+			NewValues: NullJSON{Value: map[string]string{"Name": "Alice"}, Valid: true},
+		}},
+		ModType: "INSERT",
+		Unknown: 42,
+	}}})
+	recs, err := decodeChangeRecords(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &DataChangeRecord{
+		CommitTimestamp:     ts,
+		RecordSequence:      "00000001",
+		ServerTransactionID: "tx",
+		TableName:           "Singers",
+		ColumnTypes: []*ChangeStreamColumnType{
+			{Name: "SingerId", Type: &sppb.Type{Code: sppb.TypeCode_INT64}, IsPrimaryKey: true},
+		},
+		Mods: []*Mod{{
+			Keys:      map[string]interface{}{"SingerId": "1"},
+			NewValues: map[string]interface{}{"Name": "Alice"},
+		}},
+		ModType: ModTypeInsert,
+	}
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	if diff := testutil.Diff(recs[0].data, want); diff != "" {
+		t.Errorf("got=-, want=+:\n%s", diff)
+	}
+}
+
+func TestChangeStreamReader(t *testing.T) {
+	t0 := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	// The initial partitions a and b merge into c.
+	r, queried := fakeChangeStream(ChangeStreamReaderOptions{StartTime: t0, EndTime: at(10)}, map[string][]*Row{
+		"": {childrenRow(t, t0,
+			&testChildPartition{Token: "a"},
+			&testChildPartition{Token: "b"})},
+		"a": {
+			dataRow(t, at(1), "A"),
+			childrenRow(t, at(3), &testChildPartition{Token: "c", ParentPartitionTokens: []string{"a", "b"}}),
+		},
+		"b": {
+			heartbeatRow(t, at(2)),
+			childrenRow(t, at(3), &testChildPartition{Token: "c", ParentPartitionTokens: []string{"a", "b"}}),
+		},
+		"c": {dataRow(t, at(4), "C")},
+	})
+	var mu sync.Mutex
+	var got []string
+	err := r.Read(context.Background(), func(_ context.Context, d *DataChangeRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, d.PartitionToken+":"+d.TableName)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a:A", "c:C"}; !testEqual(got, want) {
+		t.Errorf("got records %v, want %v", got, want)
+	}
+	q := queried()
+	if q[0] != "" || q[len(q)-1] != "c" || len(q) != 4 {
+		t.Errorf("got queries for %q, want the initial query, a and b, then c", q)
+	}
+	if cp := r.Checkpoint(); len(cp.Partitions) != 0 {
+		t.Errorf("got checkpoint %+v, want no partitions", cp)
+	}
+}
+
+func TestChangeStreamReaderResume(t *testing.T) {
+	t0 := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	partitions := map[string][]*Row{
+		"": {childrenRow(t, t0, &testChildPartition{Token: "a"})},
+		"a": {
+			dataRow(t, at(1), "A1"),
+			dataRow(t, at(2), "A2"),
+		},
+	}
+	r, _ := fakeChangeStream(ChangeStreamReaderOptions{StartTime: t0}, partitions)
+	errStop := errors.New("stop")
+	err := r.Read(context.Background(), func(_ context.Context, d *DataChangeRecord) error {
+		if d.TableName == "A2" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Fatalf("got %v, want %v", err, errStop)
+	}
+	cp := r.Checkpoint()
+	want := ChangeStreamCheckpoint{Partitions: []ChangeStreamPartition{{Token: "a", StartTime: at(1)}}}
+	if diff := testutil.Diff(cp, want); diff != "" {
+		t.Fatalf("checkpoint: got=-, want=+:\n%s", diff)
+	}
+	if got := r.Watermark(); !got.Equal(at(1)) {
+		t.Errorf("got watermark %v, want %v", got, at(1))
+	}
+
+	// A new reader resumes from the checkpoint, and delivers the record at
+	// the checkpoint again.
+	r, queried := fakeChangeStream(ChangeStreamReaderOptions{Checkpoint: &cp}, partitions)
+	var got []string
+	if err := r.Read(context.Background(), func(_ context.Context, d *DataChangeRecord) error {
+		got = append(got, d.TableName)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A1", "A2"}; !testEqual(got, want) {
+		t.Errorf("got records %v, want %v", got, want)
+	}
+	if q := queried(); !testEqual(q, []string{"a"}) {
+		t.Errorf("got queries for %q, want a", q)
+	}
+}
+
+func TestChangeStreamReaderMaxConcurrentPartitions(t *testing.T) {
+	t0 := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	partitions := map[string][]*Row{
+		"": {childrenRow(t, t0,
+			&testChildPartition{Token: "a"},
+			&testChildPartition{Token: "b"},
+			&testChildPartition{Token: "c"})},
+	}
+	for _, tok := range []string{"a", "b", "c"} {
+		partitions[tok] = []*Row{dataRow(t, t0.Add(time.Second), tok)}
+	}
+	r, queried := fakeChangeStream(ChangeStreamReaderOptions{StartTime: t0, MaxConcurrentPartitions: 1}, partitions)
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	err := r.Read(context.Background(), func(context.Context, *DataChangeRecord) error {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxActive != 1 {
+		t.Errorf("got %d partitions read concurrently, want 1", maxActive)
+	}
+	q := queried()
+	sort.Strings(q)
+	if want := []string{"", "a", "b", "c"}; !testEqual(q, want) {
+		t.Errorf("got queries for %q, want %q", q, want)
+	}
+}
+
+func TestChangeStreamStatement(t *testing.T) {
+	t0 := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := (&Client{}).ChangeStreamReader("Stream", ChangeStreamReaderOptions{})
+	got := r.statement("", t0)
+	want := Statement{
+		SQL: "SELECT ChangeRecord FROM READ_Stream(start_timestamp => @start_timestamp, end_timestamp => @end_timestamp, " +
+			"partition_token => @partition_token, heartbeat_milliseconds => @heartbeat_milliseconds)",
+		Params: map[string]interface{}{
+			"start_timestamp":        t0,
+			"end_timestamp":          NullTime{},
+			"partition_token":        NullString{},
+			"heartbeat_milliseconds": int64(10000),
+		},
+	}
+	if !testEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -22,13 +22,16 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/internal/trace"
 	vkit "cloud.google.com/go/spanner/apiv1"
+	"go.opentelemetry.io/otel/metric"
 	"google.golang.org/api/option"
 	"google.golang.org/api/option/internaloption"
 	gtransport "google.golang.org/api/transport/grpc"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
 	sppb "google.golang.org/genproto/googleapis/spanner/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -80,7 +83,21 @@ type Client struct {
 	idleSessions *sessionPool
 	logger       *log.Logger
 	qo           QueryOptions
+	ro           ReadOptions
+	to           TransactionOptions
+	bwo          BatchWriteOptions
+	dro          *DirectedReadOptions
 	ct           *commonTags
+	otm          *openTelemetryMetrics
+
+	// disableRouteToLeader is true if requests are not routed to the leader
+	// region unless a transaction enables it with LeaderRouting.
+	disableRouteToLeader bool
+
+	// dialectMu guards dialect, which caches the dialect of the database
+	// once it has been detected.
+	dialectMu sync.Mutex
+	dialect   adminpb.DatabaseDialect
 }
 
 // DatabaseName returns the full name of a database, e.g.,
@@ -108,13 +125,58 @@ type ClientConfig struct {
 	// for more info.
 	SessionLabels map[string]string
 
+	// DatabaseRole is the database role that the sessions of the client are
+	// created with. The privileges of the role, which are managed with
+	// fine-grained access control, determine which data the client can
+	// access. If it is empty, the client has the privileges of the IAM
+	// identity that it authenticates as.
+	DatabaseRole string
+
 	// QueryOptions is the configuration for executing a sql query.
 	QueryOptions QueryOptions
 
+	// ReadOptions is the configuration for reading rows from a database.
+	// Options that are set on a read take precedence.
+	ReadOptions ReadOptions
+
+	// TransactionOptions is the configuration for read/write transactions,
+	// including the transactions of Apply. Options that are set on a
+	// transaction take precedence.
+	TransactionOptions TransactionOptions
+
+	// BatchWriteOptions is the configuration for BatchWrite requests.
+	// Options that are set on a request take precedence.
+	BatchWriteOptions BatchWriteOptions
+
+	// DirectedReadOptions directs the reads and queries of read-only
+	// transactions and single-use reads to specific replicas by default.
+	// It is not used for read/write transactions. DirectedReadOptions that
+	// are set on a read or query take precedence.
+	DirectedReadOptions *DirectedReadOptions
+
+	// DisableRouteToLeader disables leader-aware routing. By default, the
+	// requests of read/write transactions, Apply, BatchWrite, partitioned
+	// DML and the creation of sessions are routed to the leader region of
+	// the database, which reduces their latency in multi-region instances.
+	// Set it to route these requests in the same way as read-only requests,
+	// as in previous versions of the client. Transactions can override the
+	// setting with TransactionOptions.LeaderRouting.
+	DisableRouteToLeader bool
+
 	// CallOptions is the configuration for providing custom retry settings that
 	// override the default values.
 	CallOptions *vkit.CallOptions
 
+	// OpenTelemetryMeterProvider enables the built-in OpenTelemetry metrics
+	// of the client and is used to create their instruments. The metrics
+	// include the state of the session pool, the session checkout latency,
+	// the number of get session timeouts, the number of aborted and retried
+	// read/write transactions and the latency of each RPC.
+	//
+	// OpenTelemetry metrics are disabled if it is nil. The OpenCensus views
+	// in this package are recorded regardless of this setting.
+	OpenTelemetryMeterProvider metric.MeterProvider
+
 	// logger is the logger to use for this client. If it is nil, all logging
 	// will be directed to the standard logger.
 	logger *log.Logger
@@ -162,6 +224,20 @@ func NewClientWithConfig(ctx context.Context, database string, config ClientConf
 	if config.NumChannels == 0 {
 		config.NumChannels = numChannels
 	}
+	if config.DirectedReadOptions != nil {
+		if err := config.DirectedReadOptions.validate(); err != nil {
+			return nil, err
+		}
+	}
+	otm, err := newOpenTelemetryMetrics(config.OpenTelemetryMeterProvider)
+	if err != nil {
+		return nil, err
+	}
+	if otm != nil {
+		for _, opt := range otm.dialOptions() {
+			opts = append(opts, option.WithGRPCDialOption(opt))
+		}
+	}
 	// gRPC options.
 	allOpts := allClientOpts(config.NumChannels, opts...)
 	pool, err := gtransport.DialPool(ctx, allOpts...)
@@ -193,19 +269,36 @@ func NewClientWithConfig(ctx context.Context, database string, config ClientConf
 	}
 	// Create a session client.
 	sc := newSessionClient(pool, database, sessionLabels, metadata.Pairs(resourcePrefixHeader, database), config.logger, config.CallOptions)
+	sc.databaseRole = config.DatabaseRole
+	sc.routeToLeader = !config.DisableRouteToLeader
+	ct := getCommonTags(sc)
+	otm.setCommonAttributes(ct)
 	// Create a session pool.
 	config.SessionPoolConfig.sessionLabels = sessionLabels
+	config.SessionPoolConfig.otm = otm
 	sp, err := newSessionPool(sc, config.SessionPoolConfig)
 	if err != nil {
 		sc.close()
 		return nil, err
 	}
+	if err := otm.observeSessionPool(sp); err != nil {
+		sp.close(ctx)
+		sc.close()
+		return nil, err
+	}
 	c = &Client{
 		sc:           sc,
 		idleSessions: sp,
 		logger:       config.logger,
 		qo:           getQueryOptions(config.QueryOptions),
-		ct:           getCommonTags(sc),
+		ro:           config.ReadOptions,
+		to:           config.TransactionOptions,
+		bwo:          config.BatchWriteOptions,
+		dro:          config.DirectedReadOptions,
+		ct:           ct,
+		otm:          otm,
+
+		disableRouteToLeader: config.DisableRouteToLeader,
 	}
 	return c, nil
 }
@@ -250,6 +343,7 @@ func (c *Client) Close() {
 		defer cancel()
 		c.idleSessions.close(ctx)
 	}
+	c.otm.close()
 	c.sc.close()
 }
 
@@ -267,6 +361,8 @@ func (c *Client) Single() *ReadOnlyTransaction {
 	t.txReadOnly.sp = c.idleSessions
 	t.txReadOnly.txReadEnv = t
 	t.txReadOnly.qo = c.qo
+	t.txReadOnly.ro = c.ro
+	t.txReadOnly.dro = c.dro
 	t.txReadOnly.replaceSessionFunc = func(ctx context.Context) error {
 		if t.sh == nil {
 			return spannerErrorf(codes.InvalidArgument, "missing session handle on transaction")
@@ -303,6 +399,8 @@ func (c *Client) ReadOnlyTransaction() *ReadOnlyTransaction {
 	t.txReadOnly.sp = c.idleSessions
 	t.txReadOnly.txReadEnv = t
 	t.txReadOnly.qo = c.qo
+	t.txReadOnly.ro = c.ro
+	t.txReadOnly.dro = c.dro
 	t.ct = c.ct
 	return t
 }
@@ -372,6 +470,8 @@ func (c *Client) BatchReadOnlyTransaction(ctx context.Context, tb TimestampBound
 	t.txReadOnly.sh = sh
 	t.txReadOnly.txReadEnv = t
 	t.txReadOnly.qo = c.qo
+	t.txReadOnly.ro = c.ro
+	t.txReadOnly.dro = c.dro
 	t.ct = c.ct
 	return t, nil
 }
@@ -400,6 +500,8 @@ func (c *Client) BatchReadOnlyTransactionFromID(tid BatchReadOnlyTransactionID)
 	t.txReadOnly.sh = sh
 	t.txReadOnly.txReadEnv = t
 	t.txReadOnly.qo = c.qo
+	t.txReadOnly.ro = c.ro
+	t.txReadOnly.dro = c.dro
 	t.ct = c.ct
 	return t
 }
@@ -464,11 +566,17 @@ func (c *Client) rwTransaction(ctx context.Context, f func(context.Context, *Rea
 			sh.recycle()
 		}
 	}()
-	err = runWithRetryOnAbortedOrSessionNotFound(ctx, func(ctx context.Context) error {
+	var attempts int
+	txOpts := c.to.merge(options)
+	err = runWithRetryOnAbortedOrSessionNotFound(ctx, txOpts.RetryBackoff, txOpts.OnRetry, func(ctx context.Context) error {
 		var (
 			err error
 			t   *ReadWriteTransaction
 		)
+		if attempts > 0 {
+			c.otm.recordTransactionRetry(ctx)
+		}
+		attempts++
 		if sh == nil || sh.getID() == "" || sh.getClient() == nil {
 			// Session handle hasn't been allocated or has been destroyed.
 			sh, err = c.idleSessions.takeWriteSession(ctx)
@@ -485,7 +593,9 @@ func (c *Client) rwTransaction(ctx context.Context, f func(context.Context, *Rea
 		t.txReadOnly.sh = sh
 		t.txReadOnly.txReadEnv = t
 		t.txReadOnly.qo = c.qo
-		t.txOpts = options
+		t.txReadOnly.ro = c.ro
+		t.txOpts = txOpts
+		t.routeToLeader = t.txOpts.LeaderRouting.routeToLeader(c.disableRouteToLeader)
 		t.ct = c.ct
 
 		trace.TracePrintf(ctx, map[string]interface{}{"transactionID": string(sh.getTransactionID())},
@@ -494,6 +604,9 @@ func (c *Client) rwTransaction(ctx context.Context, f func(context.Context, *Rea
 			return err
 		}
 		resp, err = t.runInTransaction(ctx, f)
+		if ErrCode(err) == codes.Aborted {
+			c.otm.recordTransactionAborted(ctx)
+		}
 		return err
 	})
 	return resp, err
@@ -508,6 +621,8 @@ type applyOption struct {
 	transactionTag string
 	// priority is the RPC priority that is used for the commit operation.
 	priority sppb.RequestOptions_Priority
+	// commitOptions are the options that are used for the commit operation.
+	commitOptions CommitOptions
 }
 
 // An ApplyOption is an optional argument to Apply.
@@ -546,9 +661,18 @@ func Priority(priority sppb.RequestOptions_Priority) ApplyOption {
 	}
 }
 
+// ApplyCommitOptions returns an ApplyOption that sets the commit options to
+// use for the commit operation, such as its MaxCommitDelay. They take
+// precedence over the CommitOptions of ClientConfig.TransactionOptions.
+func ApplyCommitOptions(co CommitOptions) ApplyOption {
+	return func(ao *applyOption) {
+		ao.commitOptions = co
+	}
+}
+
 // Apply applies a list of mutations atomically to the database.
 func (c *Client) Apply(ctx context.Context, ms []*Mutation, opts ...ApplyOption) (commitTimestamp time.Time, err error) {
-	ao := &applyOption{}
+	ao := &applyOption{priority: c.to.CommitPriority, transactionTag: c.to.TransactionTag}
 	for _, opt := range opts {
 		opt(ao)
 	}
@@ -559,10 +683,16 @@ func (c *Client) Apply(ctx context.Context, ms []*Mutation, opts ...ApplyOption)
 	if !ao.atLeastOnce {
 		resp, err := c.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, t *ReadWriteTransaction) error {
 			return t.BufferWrite(ms)
-		}, TransactionOptions{CommitPriority: ao.priority, TransactionTag: ao.transactionTag})
+		}, TransactionOptions{CommitPriority: ao.priority, TransactionTag: ao.transactionTag, CommitOptions: ao.commitOptions})
 		return resp.CommitTs, err
 	}
-	t := &writeOnlyTransaction{sp: c.idleSessions, commitPriority: ao.priority, transactionTag: ao.transactionTag}
+	t := &writeOnlyTransaction{
+		sp:             c.idleSessions,
+		commitPriority: ao.priority,
+		transactionTag: ao.transactionTag,
+		routeToLeader:  c.to.LeaderRouting.routeToLeader(c.disableRouteToLeader),
+		maxCommitDelay: c.to.merge(TransactionOptions{CommitOptions: ao.commitOptions}).CommitOptions.MaxCommitDelay,
+	}
 	return t.applyAtLeastOnce(ctx, ms...)
 }
 
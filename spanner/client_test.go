@@ -2714,6 +2714,48 @@ func TestClient_Apply_Tagging(t *testing.T) {
 	checkCommitForExpectedRequestOptions(t, server.TestSpanner, sppb.RequestOptions{TransactionTag: "tx-tag"})
 }
 
+func TestClient_ReadOptions_ClientDefaults(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{
+		ReadOptions: ReadOptions{Priority: sppb.RequestOptions_PRIORITY_LOW, RequestTag: "default-tag"},
+	})
+	defer teardown()
+
+	iter := client.Single().Read(context.Background(), "FOO", AllKeys(), []string{"BAR"})
+	iter.Next()
+	iter.Stop()
+	checkRequestsForExpectedRequestOptions(t, server.TestSpanner, 1, sppb.RequestOptions{Priority: sppb.RequestOptions_PRIORITY_LOW, RequestTag: "default-tag"})
+
+	iter = client.Single().ReadWithOptions(context.Background(), "FOO", AllKeys(), []string{"BAR"}, &ReadOptions{Priority: sppb.RequestOptions_PRIORITY_HIGH})
+	iter.Next()
+	iter.Stop()
+	checkRequestsForExpectedRequestOptions(t, server.TestSpanner, 1, sppb.RequestOptions{Priority: sppb.RequestOptions_PRIORITY_HIGH, RequestTag: "default-tag"})
+}
+
+func TestClient_TransactionOptions_ClientDefaults(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{
+		TransactionOptions: TransactionOptions{CommitPriority: sppb.RequestOptions_PRIORITY_LOW, TransactionTag: "default-tx"},
+	})
+	defer teardown()
+
+	ms := []*Mutation{Insert("foo", []string{"col1"}, []interface{}{"val1"})}
+	client.Apply(context.Background(), ms)
+	checkCommitForExpectedRequestOptions(t, server.TestSpanner, sppb.RequestOptions{Priority: sppb.RequestOptions_PRIORITY_LOW, TransactionTag: "default-tx"})
+
+	client.Apply(context.Background(), ms, ApplyAtLeastOnce(), TransactionTag("tx-tag"))
+	checkCommitForExpectedRequestOptions(t, server.TestSpanner, sppb.RequestOptions{Priority: sppb.RequestOptions_PRIORITY_LOW, TransactionTag: "tx-tag"})
+
+	client.ReadWriteTransactionWithOptions(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		tx.UpdateWithOptions(ctx, NewStatement(UpdateBarSetFoo), QueryOptions{RequestTag: "update"})
+		checkRequestsForExpectedRequestOptions(t, server.TestSpanner, 1, sppb.RequestOptions{RequestTag: "update", TransactionTag: "default-tx"})
+		return nil
+	}, TransactionOptions{CommitPriority: sppb.RequestOptions_PRIORITY_HIGH})
+	checkCommitForExpectedRequestOptions(t, server.TestSpanner, sppb.RequestOptions{Priority: sppb.RequestOptions_PRIORITY_HIGH, TransactionTag: "default-tx"})
+}
+
 func TestClient_PartitionQuery_RequestOptions(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+
+	"cloud.google.com/go/internal/trace"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	"google.golang.org/grpc/codes"
+)
+
+// dialectSQL selects the dialect of a database. The query is valid in both
+// the GoogleSQL and the PostgreSQL dialect.
+const dialectSQL = "SELECT option_value FROM information_schema.database_options WHERE option_name = 'database_dialect'"
+
+// DatabaseDialect returns the SQL dialect of the database of the client. The
+// dialect is detected by querying the information schema the first time
+// DatabaseDialect is called, and cached for the lifetime of the client.
+//
+// Databases that do not report a dialect use the GoogleSQL dialect.
+func (c *Client) DatabaseDialect(ctx context.Context) (dialect adminpb.DatabaseDialect, err error) {
+	c.dialectMu.Lock()
+	defer c.dialectMu.Unlock()
+	if c.dialect != adminpb.DatabaseDialect_DATABASE_DIALECT_UNSPECIFIED {
+		return c.dialect, nil
+	}
+
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/spanner.DatabaseDialect")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	dialect = adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL
+	iter := c.Single().Query(ctx, NewStatement(dialectSQL))
+	err = iter.Do(func(r *Row) error {
+		var name string
+		if err := r.Column(0, &name); err != nil {
+			return err
+		}
+		v, ok := adminpb.DatabaseDialect_value[name]
+		if !ok {
+			return spannerErrorf(codes.FailedPrecondition, "unknown database dialect: %q", name)
+		}
+		dialect = adminpb.DatabaseDialect(v)
+		return nil
+	})
+	if err != nil {
+		return adminpb.DatabaseDialect_DATABASE_DIALECT_UNSPECIFIED, err
+	}
+	c.dialect = dialect
+	return dialect, nil
+}
+
+// ColumnInfo describes a column of a table as reported by the information
+// schema of the database.
+type ColumnInfo struct {
+	// Name is the name of the column.
+	Name string
+	// SpannerType is the data type of the column in the dialect of the
+	// database, e.g. "STRING(MAX)" or "character varying".
+	SpannerType string
+	// IsNullable is true if the column accepts NULL values.
+	IsNullable bool
+	// OrdinalPosition is the 1-based position of the column in the table.
+	OrdinalPosition int64
+}
+
+// Information schema queries, keyed by dialect. User tables live in the
+// unnamed default schema in GoogleSQL databases, and in the "public" schema
+// in PostgreSQL databases. Both dialects bind the table name as p1.
+var (
+	listTablesSQL = map[adminpb.DatabaseDialect]string{
+		adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL: "SELECT table_name FROM information_schema.tables WHERE table_catalog = '' AND table_schema = '' ORDER BY table_name",
+		adminpb.DatabaseDialect_POSTGRESQL:          "SELECT table_name FROM information_schema.tables WHERE table_schema = 'public' ORDER BY table_name",
+	}
+	listColumnsSQL = map[adminpb.DatabaseDialect]string{
+		adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL: "SELECT column_name, spanner_type, is_nullable, ordinal_position FROM information_schema.columns WHERE table_schema = '' AND table_name = @p1 ORDER BY ordinal_position",
+		adminpb.DatabaseDialect_POSTGRESQL:          "SELECT column_name, spanner_type, is_nullable, ordinal_position FROM information_schema.columns WHERE table_schema = 'public' AND table_name = $1 ORDER BY ordinal_position",
+	}
+)
+
+// ListTables returns the names of the user tables of the database in
+// alphabetical order. The information schema query that is used depends on
+// the dialect of the database.
+func (c *Client) ListTables(ctx context.Context) ([]string, error) {
+	dialect, err := c.DatabaseDialect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	iter := c.Single().Query(ctx, NewStatement(listTablesSQL[dialect]))
+	err = iter.Do(func(r *Row) error {
+		var name string
+		if err := r.Column(0, &name); err != nil {
+			return err
+		}
+		tables = append(tables, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// ListColumns returns the columns of the given user table in the order in
+// which they are defined. The information schema query that is used depends
+// on the dialect of the database.
+func (c *Client) ListColumns(ctx context.Context, table string) ([]ColumnInfo, error) {
+	dialect, err := c.DatabaseDialect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var cols []ColumnInfo
+	iter := c.Single().Query(ctx, NewPGStatement(listColumnsSQL[dialect], table))
+	err = iter.Do(func(r *Row) error {
+		var (
+			col        ColumnInfo
+			isNullable string
+		)
+		if err := r.Columns(&col.Name, &col.SpannerType, &isNullable, &col.OrdinalPosition); err != nil {
+			return err
+		}
+		col.IsNullable = isNullable == "YES"
+		cols = append(cols, col)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
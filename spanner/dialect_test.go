@@ -0,0 +1,144 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"testing"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	proto3 "github.com/golang/protobuf/ptypes/struct"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+)
+
+func putStringRows(server *MockedSpannerInMemTestServer, sql string, fields []*sppb.StructType_Field, rows ...[]*proto3.Value) {
+	rs := &sppb.ResultSet{Metadata: &sppb.ResultSetMetadata{RowType: &sppb.StructType{Fields: fields}}}
+	for _, r := range rows {
+		rs.Rows = append(rs.Rows, &proto3.ListValue{Values: r})
+	}
+	server.TestSpanner.PutStatementResult(sql, &StatementResult{Type: StatementResultResultSet, ResultSet: rs})
+}
+
+func putDialect(server *MockedSpannerInMemTestServer, dialect string) {
+	fields := []*sppb.StructType_Field{{Name: "option_value", Type: stringType()}}
+	if dialect == "" {
+		putStringRows(server, dialectSQL, fields)
+		return
+	}
+	putStringRows(server, dialectSQL, fields, []*proto3.Value{stringProto(dialect)})
+}
+
+func TestClient_DatabaseDialect(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		reported string
+		want     adminpb.DatabaseDialect
+	}{
+		{"", adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL},
+		{"GOOGLE_STANDARD_SQL", adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL},
+		{"POSTGRESQL", adminpb.DatabaseDialect_POSTGRESQL},
+	} {
+		server, client, teardown := setupMockedTestServer(t)
+		putDialect(server, test.reported)
+		ctx := context.Background()
+		got, err := client.DatabaseDialect(ctx)
+		if err != nil {
+			t.Fatalf("%q: %v", test.reported, err)
+		}
+		if got != test.want {
+			t.Errorf("%q: got %v, want %v", test.reported, got, test.want)
+		}
+		// The dialect is cached.
+		server.TestSpanner.RemoveStatementResult(dialectSQL)
+		if got, err := client.DatabaseDialect(ctx); err != nil || got != test.want {
+			t.Errorf("%q: cached: got (%v, %v), want (%v, nil)", test.reported, got, err, test.want)
+		}
+		teardown()
+	}
+}
+
+func TestClient_DatabaseDialect_unknown(t *testing.T) {
+	t.Parallel()
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	putDialect(server, "COBOL")
+	if _, err := client.DatabaseDialect(context.Background()); err == nil {
+		t.Fatal("got nil error, want error for unknown dialect")
+	}
+}
+
+func TestClient_ListTablesAndColumns(t *testing.T) {
+	t.Parallel()
+
+	for _, dialect := range []adminpb.DatabaseDialect{
+		adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL,
+		adminpb.DatabaseDialect_POSTGRESQL,
+	} {
+		server, client, teardown := setupMockedTestServer(t)
+		putDialect(server, dialect.String())
+		putStringRows(server, listTablesSQL[dialect],
+			[]*sppb.StructType_Field{{Name: "table_name", Type: stringType()}},
+			[]*proto3.Value{stringProto("Albums")},
+			[]*proto3.Value{stringProto("Singers")})
+		putStringRows(server, listColumnsSQL[dialect],
+			[]*sppb.StructType_Field{
+				{Name: "column_name", Type: stringType()},
+				{Name: "spanner_type", Type: stringType()},
+				{Name: "is_nullable", Type: stringType()},
+				{Name: "ordinal_position", Type: intType()},
+			},
+			[]*proto3.Value{stringProto("SingerId"), stringProto("INT64"), stringProto("NO"), intProto(1)},
+			[]*proto3.Value{stringProto("Name"), stringProto("STRING(MAX)"), stringProto("YES"), intProto(2)})
+
+		ctx := context.Background()
+		tables, err := client.ListTables(ctx)
+		if err != nil {
+			t.Fatalf("%v: %v", dialect, err)
+		}
+		if want := []string{"Albums", "Singers"}; !testEqual(tables, want) {
+			t.Errorf("%v: got tables %v, want %v", dialect, tables, want)
+		}
+		cols, err := client.ListColumns(ctx, "Singers")
+		if err != nil {
+			t.Fatalf("%v: %v", dialect, err)
+		}
+		want := []ColumnInfo{
+			{Name: "SingerId", SpannerType: "INT64", IsNullable: false, OrdinalPosition: 1},
+			{Name: "Name", SpannerType: "STRING(MAX)", IsNullable: true, OrdinalPosition: 2},
+		}
+		if !testEqual(cols, want) {
+			t.Errorf("%v: got columns %v, want %v", dialect, cols, want)
+		}
+
+		var params *proto3.Struct
+		for _, req := range drainRequestsFromServer(server.TestSpanner) {
+			if sqlReq, ok := req.(*sppb.ExecuteSqlRequest); ok && sqlReq.Sql == listColumnsSQL[dialect] {
+				params = sqlReq.Params
+			}
+		}
+		if params == nil {
+			t.Fatalf("%v: no ListColumns request was sent", dialect)
+		}
+		if got := params.Fields["p1"].GetStringValue(); got != "Singers" {
+			t.Errorf("%v: got table parameter %q, want %q", dialect, got, "Singers")
+		}
+		teardown()
+	}
+}
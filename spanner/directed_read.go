@@ -0,0 +1,174 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Field numbers of google.spanner.v1.DirectedReadOptions and of the
+// directed_read_options fields of the requests, which the generated types
+// do not have.
+const (
+	readRequestDirectedReadOptions       = 14
+	executeSQLRequestDirectedReadOptions = 15
+
+	directedReadOptionsIncludeReplicas = 1
+	directedReadOptionsExcludeReplicas = 2
+
+	includeReplicasReplicaSelections    = 1
+	includeReplicasAutoFailoverDisabled = 2
+	excludeReplicasReplicaSelections    = 1
+
+	replicaSelectionLocation = 1
+	replicaSelectionType     = 2
+)
+
+// maxReplicaSelections is the maximum number of replica selections that
+// Cloud Spanner accepts in DirectedReadOptions.
+const maxReplicaSelections = 10
+
+// ReplicaType is the type of a replica that reads can be directed to.
+type ReplicaType int32
+
+const (
+	// ReplicaTypeUnspecified matches replicas of any type.
+	ReplicaTypeUnspecified ReplicaType = 0
+	// ReplicaTypeReadWrite matches read/write replicas.
+	ReplicaTypeReadWrite ReplicaType = 1
+	// ReplicaTypeReadOnly matches read-only replicas.
+	ReplicaTypeReadOnly ReplicaType = 2
+)
+
+// ReplicaSelection selects the replicas that match a location and/or a type.
+// Replicas match if they match all fields that are set.
+type ReplicaSelection struct {
+	// Location is the name of the location of the replicas, e.g.
+	// "us-east1".
+	Location string
+	// Type is the type of the replicas.
+	Type ReplicaType
+}
+
+// IncludeReplicas is a list of replica selections that reads are directed
+// to, in order of preference.
+type IncludeReplicas struct {
+	// ReplicaSelections are the replicas that reads should be served by.
+	ReplicaSelections []ReplicaSelection
+	// AutoFailoverDisabled disables the failover to the nearest available
+	// replica if none of the selected replicas are available or healthy. If
+	// it is set, reads fail instead.
+	AutoFailoverDisabled bool
+}
+
+// ExcludeReplicas is a list of replica selections that reads must not be
+// served by.
+type ExcludeReplicas struct {
+	// ReplicaSelections are the replicas that reads should not be served by.
+	ReplicaSelections []ReplicaSelection
+}
+
+// DirectedReadOptions directs the reads and queries of read-only
+// transactions and single-use reads to specific replicas, e.g. to keep
+// analytics traffic on read-only replicas away from the leader. At most one
+// of IncludeReplicas and ExcludeReplicas can be set.
+//
+// DirectedReadOptions cannot be used in read/write transactions.
+type DirectedReadOptions struct {
+	// IncludeReplicas are the replicas that reads are directed to.
+	IncludeReplicas *IncludeReplicas
+	// ExcludeReplicas are the replicas that reads are not directed to.
+	ExcludeReplicas *ExcludeReplicas
+}
+
+// errDirectedReadOptionsInReadWriteTransaction is returned when
+// DirectedReadOptions are set on a read or query in a read/write
+// transaction.
+func errDirectedReadOptionsInReadWriteTransaction() error {
+	return spannerErrorf(codes.InvalidArgument, "DirectedReadOptions cannot be used in a read/write transaction")
+}
+
+// validate returns an error if Cloud Spanner would not accept o.
+func (o *DirectedReadOptions) validate() error {
+	if o.IncludeReplicas != nil && o.ExcludeReplicas != nil {
+		return spannerErrorf(codes.InvalidArgument, "DirectedReadOptions cannot have both IncludeReplicas and ExcludeReplicas")
+	}
+	var n int
+	if o.IncludeReplicas != nil {
+		n = len(o.IncludeReplicas.ReplicaSelections)
+	} else if o.ExcludeReplicas != nil {
+		n = len(o.ExcludeReplicas.ReplicaSelections)
+	}
+	if n > maxReplicaSelections {
+		return spannerErrorf(codes.InvalidArgument, "DirectedReadOptions can have at most %d replica selections, got %d", maxReplicaSelections, n)
+	}
+	return nil
+}
+
+// setDirectedReadOptions sets o as field num of req, which is the
+// directed_read_options field of a ReadRequest or an ExecuteSqlRequest. It
+// does nothing if o is nil.
+func setDirectedReadOptions(req proto.Message, num protowire.Number, o *DirectedReadOptions) {
+	if o == nil {
+		return
+	}
+	m := req.ProtoReflect()
+	b := protowire.AppendTag(m.GetUnknown(), num, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(b, o.marshal()))
+}
+
+// marshal returns the wire format of o as a
+// google.spanner.v1.DirectedReadOptions.
+func (o *DirectedReadOptions) marshal() []byte {
+	var b []byte
+	if o.IncludeReplicas != nil {
+		var ib []byte
+		ib = appendReplicaSelections(ib, includeReplicasReplicaSelections, o.IncludeReplicas.ReplicaSelections)
+		if o.IncludeReplicas.AutoFailoverDisabled {
+			ib = protowire.AppendTag(ib, includeReplicasAutoFailoverDisabled, protowire.VarintType)
+			ib = protowire.AppendVarint(ib, 1)
+		}
+		b = protowire.AppendTag(b, directedReadOptionsIncludeReplicas, protowire.BytesType)
+		b = protowire.AppendBytes(b, ib)
+	}
+	if o.ExcludeReplicas != nil {
+		var eb []byte
+		eb = appendReplicaSelections(eb, excludeReplicasReplicaSelections, o.ExcludeReplicas.ReplicaSelections)
+		b = protowire.AppendTag(b, directedReadOptionsExcludeReplicas, protowire.BytesType)
+		b = protowire.AppendBytes(b, eb)
+	}
+	return b
+}
+
+func appendReplicaSelections(b []byte, num protowire.Number, rs []ReplicaSelection) []byte {
+	for _, r := range rs {
+		var rb []byte
+		if r.Location != "" {
+			rb = protowire.AppendTag(rb, replicaSelectionLocation, protowire.BytesType)
+			rb = protowire.AppendString(rb, r.Location)
+		}
+		if r.Type != ReplicaTypeUnspecified {
+			rb = protowire.AppendTag(rb, replicaSelectionType, protowire.VarintType)
+			rb = protowire.AppendVarint(rb, uint64(r.Type))
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, rb)
+	}
+	return b
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// directedReadOptionsOf returns the raw directed_read_options field num of
+// req, or nil if it is not set.
+func directedReadOptionsOf(t *testing.T, req proto.Message, num protowire.Number) []byte {
+	t.Helper()
+	b := req.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		fn, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		if fn == num && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				t.Fatal(protowire.ParseError(n))
+			}
+			return v
+		}
+		n = protowire.ConsumeFieldValue(fn, typ, b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func TestDirectedReadOptions_marshal(t *testing.T) {
+	for _, test := range []struct {
+		opts *DirectedReadOptions
+		want []byte
+	}{
+		{
+			opts: &DirectedReadOptions{IncludeReplicas: &IncludeReplicas{
+				ReplicaSelections:    []ReplicaSelection{{Location: "us-east1", Type: ReplicaTypeReadOnly}},
+				AutoFailoverDisabled: true,
+			}},
+			want: append(append([]byte{0x0a, 0x10, 0x0a, 0x0c, 0x0a, 0x08}, "us-east1"...), 0x10, 0x02, 0x10, 0x01),
+		},
+		{
+			opts: &DirectedReadOptions{ExcludeReplicas: &ExcludeReplicas{
+				ReplicaSelections: []ReplicaSelection{{Type: ReplicaTypeReadWrite}, {Location: "eu"}},
+			}},
+			want: append([]byte{0x12, 0x0a, 0x0a, 0x02, 0x10, 0x01, 0x0a, 0x04, 0x0a, 0x02}, "eu"...),
+		},
+		{
+			opts: &DirectedReadOptions{},
+			want: nil,
+		},
+	} {
+		if got := test.opts.marshal(); !bytes.Equal(got, test.want) {
+			t.Errorf("%+v: got %x, want %x", test.opts, got, test.want)
+		}
+	}
+}
+
+func TestClient_DirectedReadOptions(t *testing.T) {
+	t.Parallel()
+
+	clientOpts := &DirectedReadOptions{IncludeReplicas: &IncludeReplicas{
+		ReplicaSelections: []ReplicaSelection{{Type: ReplicaTypeReadOnly}},
+	}}
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{DirectedReadOptions: clientOpts})
+	defer teardown()
+	ctx := context.Background()
+	columns := []string{"SingerId", "AlbumId", "AlbumTitle"}
+
+	// Single-use reads use the client-wide options.
+	if err := client.Single().Read(ctx, "Albums", AllKeys(), columns).Do(func(*Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	// Options that are set on a query take precedence.
+	queryOpts := &DirectedReadOptions{ExcludeReplicas: &ExcludeReplicas{
+		ReplicaSelections: []ReplicaSelection{{Location: "us-east1"}},
+	}}
+	tx := client.ReadOnlyTransaction()
+	iter := tx.QueryWithOptions(ctx, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums), QueryOptions{DirectedReadOptions: queryOpts})
+	if err := iter.Do(func(*Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	tx.Close()
+	// Read/write transactions do not use the client-wide options.
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return tx.Read(ctx, "Albums", AllKeys(), columns).Do(func(*Row) error { return nil })
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]byte
+	for _, req := range drainRequestsFromServer(server.TestSpanner) {
+		switch req := req.(type) {
+		case *sppb.ReadRequest:
+			got = append(got, directedReadOptionsOf(t, req, readRequestDirectedReadOptions))
+		case *sppb.ExecuteSqlRequest:
+			got = append(got, directedReadOptionsOf(t, req, executeSQLRequestDirectedReadOptions))
+		}
+	}
+	want := [][]byte{clientOpts.marshal(), queryOpts.marshal(), nil}
+	if !testEqual(got, want) {
+		t.Errorf("got directed read options %x, want %x", got, want)
+	}
+}
+
+func TestClient_DirectedReadOptions_errors(t *testing.T) {
+	t.Parallel()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	ctx := context.Background()
+	opts := &DirectedReadOptions{IncludeReplicas: &IncludeReplicas{
+		ReplicaSelections: []ReplicaSelection{{Location: "us-east1"}},
+	}}
+
+	_, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		iter := tx.QueryWithOptions(ctx, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums), QueryOptions{DirectedReadOptions: opts})
+		return iter.Do(func(*Row) error { return nil })
+	})
+	if ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("query in read/write transaction: got error %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	invalid := &DirectedReadOptions{
+		IncludeReplicas: &IncludeReplicas{},
+		ExcludeReplicas: &ExcludeReplicas{},
+	}
+	iter := client.Single().ReadWithOptions(ctx, "Albums", AllKeys(), []string{"SingerId"}, &ReadOptions{DirectedReadOptions: invalid})
+	if err := iter.Do(func(*Row) error { return nil }); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("invalid options: got error %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	tooMany := &DirectedReadOptions{ExcludeReplicas: &ExcludeReplicas{
+		ReplicaSelections: make([]ReplicaSelection, maxReplicaSelections+1),
+	}}
+	if _, err := NewClientWithConfig(ctx, client.DatabaseName(), ClientConfig{DirectedReadOptions: tooMany}); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("too many replica selections: got error %v, want code %v", err, codes.InvalidArgument)
+	}
+}
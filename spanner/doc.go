@@ -369,6 +369,21 @@ This client has been instrumented to use OpenCensus tracing
 (http://opencensus.io). To enable tracing, see "Enabling Tracing for a Program"
 at https://godoc.org/go.opencensus.io/trace. OpenCensus tracing requires Go 1.8
 or higher.
+
+
+Metrics
+
+The client records metrics about its session pool as OpenCensus measures, see
+the views in this package. The client can also export built-in OpenTelemetry
+metrics for the session pool, the session checkout latency, get session
+timeouts, aborted and retried read/write transactions and the latency of each
+RPC. These metrics are opt-in; set ClientConfig.OpenTelemetryMeterProvider to
+enable them:
+
+	client, err := spanner.NewClientWithConfig(ctx, db, spanner.ClientConfig{
+		SessionPoolConfig:          spanner.DefaultSessionPoolConfig,
+		OpenTelemetryMeterProvider: meterProvider,
+	})
 */
 package spanner // import "cloud.google.com/go/spanner"
 
@@ -20,14 +20,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/spanner"
+	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/iterator"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
 	sppb "google.golang.org/genproto/googleapis/spanner/v1"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 func ExampleNewClient() {
@@ -55,6 +60,21 @@ func ExampleNewClientWithConfig() {
 	client.Close() // Close client when done.
 }
 
+func ExampleNewClientWithConfig_databaseRole() {
+	ctx := context.Background()
+	// The sessions of the client are created with the database role
+	// "reader", and only have access to the data that the role was granted.
+	client, err := spanner.NewClientWithConfig(ctx, myDB, spanner.ClientConfig{
+		SessionPoolConfig: spanner.DefaultSessionPoolConfig,
+		DatabaseRole:      "reader",
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = client     // TODO: Use client.
+	client.Close() // Close client when done.
+}
+
 func ExampleClient_Single() {
 	ctx := context.Background()
 	client, err := spanner.NewClient(ctx, myDB)
@@ -65,6 +85,110 @@ func ExampleClient_Single() {
 	_ = iter // TODO: iterate using Next or Do.
 }
 
+func ExampleDirectedReadOptions() {
+	ctx := context.Background()
+	client, err := spanner.NewClient(ctx, myDB)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// Serve the query from read-only replicas in us-east1, and fall back to
+	// the nearest available replica if none of them is available.
+	iter := client.Single().QueryWithOptions(ctx, spanner.NewStatement("SELECT FirstName FROM Singers"), spanner.QueryOptions{
+		DirectedReadOptions: &spanner.DirectedReadOptions{
+			IncludeReplicas: &spanner.IncludeReplicas{
+				ReplicaSelections: []spanner.ReplicaSelection{
+					{Location: "us-east1", Type: spanner.ReplicaTypeReadOnly},
+				},
+			},
+		},
+	})
+	_ = iter // TODO: iterate using Next or Do.
+}
+
+func ExampleClient_DefaultLeader() {
+	ctx := context.Background()
+	// Do not route read/write transactions to the leader region by default.
+	client, err := spanner.NewClientWithConfig(ctx, myDB, spanner.ClientConfig{DisableRouteToLeader: true})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	leader, err := client.DefaultLeader(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	fmt.Printf("The default leader is %q\n", leader)
+	// Route a latency sensitive transaction to the leader region anyway.
+	_, err = client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, txn *spanner.ReadWriteTransaction) error {
+		_, err := txn.Update(ctx, spanner.NewStatement("UPDATE Singers SET FirstName = 'Alice' WHERE SingerId = 1"))
+		return err
+	}, spanner.TransactionOptions{LeaderRouting: spanner.LeaderRoutingEnabled})
+	if err != nil {
+		// TODO: Handle error.
+	}
+}
+
+func ExampleRegisterProtoDescriptors() {
+	ctx := context.Background()
+	// descriptors.pb was generated with
+	// protoc --include_imports --descriptor_set_out=descriptors.pb singer.proto
+	b, err := ioutil.ReadFile("descriptors.pb")
+	if err != nil {
+		// TODO: Handle error.
+	}
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &fds); err != nil {
+		// TODO: Handle error.
+	}
+	if err := spanner.RegisterProtoDescriptors(&fds); err != nil {
+		// TODO: Handle error.
+	}
+	client, err := spanner.NewClient(ctx, myDB)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	iter := client.Single().Query(ctx, spanner.NewStatement("SELECT SingerId, SingerInfo, SingerGenre FROM Singers"))
+	defer iter.Stop()
+	for {
+		row, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		// The PROTO and ENUM columns are encoded with their registered types.
+		b, err := row.MarshalJSON()
+		if err != nil {
+			// TODO: Handle error.
+		}
+		fmt.Println(string(b))
+	}
+}
+
+func ExampleTransactionOptions_retry() {
+	ctx := context.Background()
+	client, err := spanner.NewClientWithConfig(ctx, myDB, spanner.ClientConfig{
+		TransactionOptions: spanner.TransactionOptions{
+			// Back off more aggressively than DefaultRetryBackoff.
+			RetryBackoff: gax.Backoff{
+				Initial:    100 * time.Millisecond,
+				Max:        10 * time.Second,
+				Multiplier: 2,
+			},
+			// Report transactions that are aborted repeatedly.
+			OnRetry: func(info spanner.TransactionRetryInfo) {
+				if info.Attempt >= 5 {
+					fmt.Printf("transaction aborted %d times, retrying in %v: %v\n", info.Attempt, info.Delay, info.Err)
+				}
+			},
+		},
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	_ = client // TODO: Use client.
+}
+
 func ExampleClient_ReadOnlyTransaction() {
 	ctx := context.Background()
 	client, err := spanner.NewClient(ctx, myDB)
@@ -207,6 +331,32 @@ func ExampleClient_Apply() {
 	}
 }
 
+func ExampleClient_BatchWrite() {
+	ctx := context.Background()
+	client, err := spanner.NewClient(ctx, myDB)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	mgs := []*spanner.MutationGroup{
+		{Mutations: []*spanner.Mutation{
+			spanner.InsertOrUpdate("Users", []string{"name", "email"}, []interface{}{"alice", "a@example.com"}),
+		}},
+		{Mutations: []*spanner.Mutation{
+			spanner.InsertOrUpdate("Users", []string{"name", "email"}, []interface{}{"bob", "b@example.com"}),
+		}},
+	}
+	iter := client.BatchWrite(ctx, mgs)
+	err = iter.Do(func(r *spanner.BatchWriteResponse) error {
+		if r.Err != nil {
+			fmt.Printf("mutation groups %v were not applied: %v\n", r.Indexes, r.Err)
+		}
+		return nil
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+}
+
 func ExampleInsert() {
 	m := spanner.Insert("Users", []string{"name", "email"}, []interface{}{"alice", "a@example.com"})
 	_ = m // TODO: use with Client.Apply or in a ReadWriteTransaction.
@@ -499,6 +649,33 @@ func ExampleNewStatement() {
 	// TODO: Use stmt in Query.
 }
 
+func ExampleNewPGStatement() {
+	stmt := spanner.NewPGStatement("SELECT FirstName, LastName FROM Singers WHERE LastName >= $1", "Dylan")
+	_ = stmt // TODO: Use stmt in Query.
+}
+
+func ExampleClient_DatabaseDialect() {
+	ctx := context.Background()
+	client, err := spanner.NewClient(ctx, myDB)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	dialect, err := client.DatabaseDialect(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	var stmt spanner.Statement
+	if dialect == adminpb.DatabaseDialect_POSTGRESQL {
+		stmt = spanner.NewPGStatement("SELECT SingerId FROM Singers WHERE LastName = $1", "Dylan")
+	} else {
+		stmt = spanner.Statement{
+			SQL:    "SELECT SingerId FROM Singers WHERE LastName = @lastName",
+			Params: map[string]interface{}{"lastName": "Dylan"},
+		}
+	}
+	_ = stmt // TODO: Use stmt in Query.
+}
+
 func ExampleNewStatement_structLiteral() {
 	stmt := spanner.Statement{
 		SQL: `SELECT FirstName, LastName FROM SINGERS WHERE LastName = ("Lea", "Martin")`,
@@ -660,6 +837,29 @@ func ExampleClient_BatchReadOnlyTransaction() {
 	wg.Wait()
 }
 
+func ExampleChangeStreamReader_Read() {
+	ctx := context.Background()
+	client, err := spanner.NewClient(ctx, myDB)
+	if err != nil {
+		// TODO: Handle error.
+	}
+	r := client.ChangeStreamReader("SingersStream", spanner.ChangeStreamReaderOptions{
+		StartTime: time.Now().Add(-time.Hour),
+	})
+	err = r.Read(ctx, func(ctx context.Context, rec *spanner.DataChangeRecord) error {
+		for _, mod := range rec.Mods {
+			fmt.Println(rec.CommitTimestamp, rec.TableName, rec.ModType, mod.Keys, mod.NewValues)
+		}
+		return nil
+	})
+	if err != nil {
+		// TODO: Handle error.
+	}
+	// TODO: Persist the checkpoint, to resume with
+	// ChangeStreamReaderOptions.Checkpoint.
+	_ = r.Checkpoint()
+}
+
 func ExampleCommitTimestamp() {
 	ctx := context.Background()
 	client, err := spanner.NewClient(ctx, myDB)
@@ -36,6 +36,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	gstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
 )
 
 var (
@@ -87,6 +89,7 @@ const (
 	MethodExecuteStreamingSql string = "EXECUTE_STREAMING_SQL"
 	MethodExecuteBatchDml     string = "EXECUTE_BATCH_DML"
 	MethodStreamingRead       string = "EXECUTE_STREAMING_READ"
+	MethodBatchWrite          string = "BATCH_WRITE"
 )
 
 // StatementResult represents a mocked result on the test server. The result is
@@ -244,6 +247,10 @@ type InMemSpannerServer interface {
 
 	// Puts a simulated execution time for one of the Spanner methods.
 	PutExecutionTime(method string, executionTime SimulatedExecutionTime)
+
+	// BatchWrite handles a BatchWrite stream. The RPC is not part of
+	// spannerpb.SpannerServer, so the server must dispatch it explicitly.
+	BatchWrite(stream grpc.ServerStream) error
 	// Freeze stalls all requests.
 	Freeze()
 	// Unfreeze restores processing requests.
@@ -1095,3 +1102,112 @@ func DecodeResumeToken(t []byte) (uint64, error) {
 	}
 	return s, nil
 }
+
+// BatchWriteRequest is a decoded BatchWriteRequest, as received by the
+// BatchWrite method of the server.
+type BatchWriteRequest struct {
+	Session        string
+	RequestOptions *spannerpb.RequestOptions
+	MutationGroups [][]*spannerpb.Mutation
+}
+
+func (s *inMemSpannerServer) BatchWrite(stream grpc.ServerStream) error {
+	msg := &emptypb.Empty{}
+	if err := stream.RecvMsg(msg); err != nil {
+		return err
+	}
+	req, err := decodeBatchWriteRequest(msg.ProtoReflect().GetUnknown())
+	if err != nil {
+		return gstatus.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.simulateExecutionTime(MethodBatchWrite, req); err != nil {
+		return err
+	}
+	if req.Session == "" {
+		return gstatus.Error(codes.InvalidArgument, "Missing session name")
+	}
+	session, err := s.findSession(req.Session)
+	if err != nil {
+		return err
+	}
+	s.updateSessionLastUseTime(session.Name)
+	for i := range req.MutationGroups {
+		// Every mutation group is applied in a transaction of its own.
+		ts, err := proto.Marshal(getCurrentTimestamp())
+		if err != nil {
+			return err
+		}
+		st, err := proto.Marshal(&status.Status{})
+		if err != nil {
+			return err
+		}
+		var b []byte
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, protowire.AppendVarint(nil, uint64(i)))
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendBytes(b, st)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+		resp := &emptypb.Empty{}
+		resp.ProtoReflect().SetUnknown(b)
+		if err := stream.SendMsg(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeBatchWriteRequest decodes the wire format of a BatchWriteRequest.
+func decodeBatchWriteRequest(b []byte) (*BatchWriteRequest, error) {
+	req := &BatchWriteRequest{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch num {
+		case 1:
+			req.Session = string(v)
+		case 3:
+			req.RequestOptions = &spannerpb.RequestOptions{}
+			if err := proto.Unmarshal(v, req.RequestOptions); err != nil {
+				return nil, err
+			}
+		case 4:
+			var group []*spannerpb.Mutation
+			for len(v) > 0 {
+				_, _, n := protowire.ConsumeTag(v)
+				if n < 0 {
+					return nil, protowire.ParseError(n)
+				}
+				v = v[n:]
+				mb, n := protowire.ConsumeBytes(v)
+				if n < 0 {
+					return nil, protowire.ParseError(n)
+				}
+				v = v[n:]
+				m := &spannerpb.Mutation{}
+				if err := proto.Unmarshal(mb, m); err != nil {
+					return nil, err
+				}
+				group = append(group, m)
+			}
+			req.MutationGroups = append(req.MutationGroups, group)
+		}
+	}
+	return req, nil
+}
@@ -25,6 +25,8 @@ import (
 	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
 	spannerpb "google.golang.org/genproto/googleapis/spanner/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	gstatus "google.golang.org/grpc/status"
 )
 
 // SelectFooFromBar is a SELECT statement that is added to the mocked test
@@ -89,7 +91,7 @@ func (s *MockedSpannerInMemTestServer) setupMockedServerWithAddr(t *testing.T, a
 	s.TestInstanceAdmin = NewInMemInstanceAdminServer()
 	s.setupFooResults()
 	s.setupSingersResults()
-	s.server = grpc.NewServer()
+	s.server = grpc.NewServer(grpc.UnknownServiceHandler(s.handleUnknownMethod))
 	spannerpb.RegisterSpannerServer(s.server, s.TestSpanner)
 	instancepb.RegisterInstanceAdminServer(s.server, s.TestInstanceAdmin)
 
@@ -108,6 +110,16 @@ func (s *MockedSpannerInMemTestServer) setupMockedServerWithAddr(t *testing.T, a
 	return opts
 }
 
+// handleUnknownMethod dispatches the Spanner RPCs that are not part of the
+// generated SpannerServer interface.
+func (s *MockedSpannerInMemTestServer) handleUnknownMethod(srv interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	if method == "/google.spanner.v1.Spanner/BatchWrite" {
+		return s.TestSpanner.BatchWrite(stream)
+	}
+	return gstatus.Errorf(codes.Unimplemented, "unknown method %v", method)
+}
+
 func (s *MockedSpannerInMemTestServer) setupFooResults() {
 	fields := make([]*spannerpb.StructType_Field, selectFooFromBarColCount)
 	fields[0] = &spannerpb.StructType_Field{
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+
+	"cloud.google.com/go/internal/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// routeToLeaderHeader is the header that asks Cloud Spanner to route a
+// request to the leader region of the database.
+const routeToLeaderHeader = "x-goog-spanner-route-to-leader"
+
+// defaultLeaderSQL selects the default leader of a database. The query is
+// valid in both the GoogleSQL and the PostgreSQL dialect.
+const defaultLeaderSQL = "SELECT option_value FROM information_schema.database_options WHERE option_name = 'default_leader'"
+
+// LeaderRouting specifies whether the requests of a read/write transaction
+// are routed to the leader region of the database.
+type LeaderRouting int
+
+const (
+	// LeaderRoutingDefault routes the requests to the leader region, unless
+	// ClientConfig.DisableRouteToLeader is set.
+	LeaderRoutingDefault LeaderRouting = iota
+	// LeaderRoutingEnabled routes the requests to the leader region,
+	// regardless of ClientConfig.DisableRouteToLeader.
+	LeaderRoutingEnabled
+	// LeaderRoutingDisabled lets Cloud Spanner route the requests in the
+	// same way as requests that are not routed to the leader region.
+	LeaderRoutingDisabled
+)
+
+// routeToLeader returns whether requests with this LeaderRouting are routed
+// to the leader region if the client disables routing to the leader with
+// clientDisabled.
+func (lr LeaderRouting) routeToLeader(clientDisabled bool) bool {
+	switch lr {
+	case LeaderRoutingEnabled:
+		return true
+	case LeaderRoutingDisabled:
+		return false
+	default:
+		return !clientDisabled
+	}
+}
+
+// withRouteToLeader returns md with the route-to-leader header added if
+// routeToLeader is true.
+func withRouteToLeader(md metadata.MD, routeToLeader bool) metadata.MD {
+	if !routeToLeader {
+		return md
+	}
+	return metadata.Join(md, metadata.Pairs(routeToLeaderHeader, "true"))
+}
+
+// DefaultLeader returns the default leader region of the database of the
+// client, e.g. "us-central1". It returns an empty string if the database
+// does not have a default leader, which is the case for databases in
+// regional instances.
+//
+// The default leader is read from the information schema each time
+// DefaultLeader is called, as it can be changed with ALTER DATABASE.
+func (c *Client) DefaultLeader(ctx context.Context) (leader string, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/spanner.DefaultLeader")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	iter := c.Single().Query(ctx, NewStatement(defaultLeaderSQL))
+	err = iter.Do(func(r *Row) error {
+		return r.Column(0, &leader)
+	})
+	if err != nil {
+		return "", err
+	}
+	return leader, nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	proto3 "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/api/option"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// routeToLeaderRecorder records for each RPC whether it was sent with the
+// route-to-leader header.
+type routeToLeaderRecorder struct {
+	mu     sync.Mutex
+	routed map[string][]bool
+}
+
+func (r *routeToLeaderRecorder) record(ctx context.Context, method string) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	routed := len(md.Get(routeToLeaderHeader)) > 0
+	method = method[strings.LastIndex(method, "/")+1:]
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routed[method] = append(r.routed[method], routed)
+}
+
+func (r *routeToLeaderRecorder) clientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			r.record(ctx, method)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			r.record(ctx, method)
+			return streamer(ctx, desc, cc, method, opts...)
+		})),
+	}
+}
+
+// take returns the recorded RPCs and clears the recorder.
+func (r *routeToLeaderRecorder) take() map[string][]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	routed := r.routed
+	r.routed = make(map[string][]bool)
+	return routed
+}
+
+func TestClient_RouteToLeader(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		desc          string
+		disabled      bool
+		leaderRouting LeaderRouting
+		want          bool
+	}{
+		{"default", false, LeaderRoutingDefault, true},
+		{"disabled", true, LeaderRoutingDefault, false},
+		{"disabled for transaction", false, LeaderRoutingDisabled, false},
+		{"enabled for transaction", true, LeaderRoutingEnabled, true},
+	} {
+		rec := &routeToLeaderRecorder{routed: make(map[string][]bool)}
+		_, client, teardown := setupMockedTestServerWithConfigAndClientOptions(t, ClientConfig{
+			SessionPoolConfig:    SessionPoolConfig{MinOpened: 1},
+			DisableRouteToLeader: test.disabled,
+		}, rec.clientOptions())
+
+		// Read-only transactions are never routed to the leader.
+		ctx := context.Background()
+		if err := client.Single().Query(ctx, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums)).Do(func(r *Row) error { return nil }); err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		if got := rec.take()["ExecuteStreamingSql"]; !testEqual(got, []bool{false}) {
+			t.Errorf("%s: single-use query: got routed %v, want [false]", test.desc, got)
+		}
+
+		_, err := client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+			if err := tx.Query(ctx, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums)).Do(func(r *Row) error { return nil }); err != nil {
+				return err
+			}
+			if _, err := tx.Update(ctx, NewStatement(UpdateBarSetFoo)); err != nil {
+				return err
+			}
+			_, err := tx.BatchUpdate(ctx, []Statement{NewStatement(UpdateBarSetFoo)})
+			return err
+		}, TransactionOptions{LeaderRouting: test.leaderRouting})
+		if err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		routed := rec.take()
+		methods := []string{"ExecuteStreamingSql", "ExecuteSql", "ExecuteBatchDml", "Commit"}
+		if test.leaderRouting == LeaderRoutingDefault {
+			// The transaction may have been started when the session was
+			// prepared for write in the session pool.
+			methods = append(methods, "BeginTransaction")
+		}
+		for _, method := range methods {
+			if got := routed[method]; len(got) == 0 || got[len(got)-1] != test.want {
+				t.Errorf("%s: %s: got routed %v, want %v", test.desc, method, got, test.want)
+			}
+		}
+		teardown()
+	}
+}
+
+func TestClient_RouteToLeader_partitionedUpdate(t *testing.T) {
+	t.Parallel()
+
+	for _, disabled := range []bool{false, true} {
+		rec := &routeToLeaderRecorder{routed: make(map[string][]bool)}
+		_, client, teardown := setupMockedTestServerWithConfigAndClientOptions(t, ClientConfig{
+			SessionPoolConfig:    SessionPoolConfig{MinOpened: 1},
+			DisableRouteToLeader: disabled,
+		}, rec.clientOptions())
+		if _, err := client.PartitionedUpdate(context.Background(), NewStatement(UpdateBarSetFoo)); err != nil {
+			t.Fatal(err)
+		}
+		routed := rec.take()
+		for _, method := range []string{"BatchCreateSessions", "BeginTransaction", "ExecuteSql"} {
+			if got := routed[method]; len(got) == 0 || got[0] == disabled {
+				t.Errorf("disabled=%v: %s: got routed %v, want %v", disabled, method, got, !disabled)
+			}
+		}
+		teardown()
+	}
+}
+
+func TestLeaderRouting_merge(t *testing.T) {
+	for _, test := range []struct {
+		client, txn, want LeaderRouting
+	}{
+		{LeaderRoutingDefault, LeaderRoutingDefault, LeaderRoutingDefault},
+		{LeaderRoutingDisabled, LeaderRoutingDefault, LeaderRoutingDisabled},
+		{LeaderRoutingDisabled, LeaderRoutingEnabled, LeaderRoutingEnabled},
+		{LeaderRoutingDefault, LeaderRoutingDisabled, LeaderRoutingDisabled},
+	} {
+		got := TransactionOptions{LeaderRouting: test.client}.merge(TransactionOptions{LeaderRouting: test.txn}).LeaderRouting
+		if got != test.want {
+			t.Errorf("merge(%v, %v): got %v, want %v", test.client, test.txn, got, test.want)
+		}
+	}
+}
+
+func TestClient_DefaultLeader(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	fields := []*sppb.StructType_Field{{Name: "option_value", Type: stringType()}}
+	ctx := context.Background()
+	putStringRows(server, defaultLeaderSQL, fields)
+	if got, err := client.DefaultLeader(ctx); err != nil || got != "" {
+		t.Errorf("no default leader: got (%q, %v), want (\"\", nil)", got, err)
+	}
+	putStringRows(server, defaultLeaderSQL, fields, []*proto3.Value{stringProto("us-central1")})
+	if got, err := client.DefaultLeader(ctx); err != nil || got != "us-central1" {
+		t.Errorf("got (%q, %v), want (\"us-central1\", nil)", got, err)
+	}
+}
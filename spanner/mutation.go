@@ -121,6 +121,10 @@ const (
 //     []Date, []*Date, []NullDate - DATE ARRAY
 //     big.Rat, *big.Rat, NullNumeric - NUMERIC
 //     []big.Rat, []*big.Rat, []NullNumeric - NUMERIC ARRAY
+//     proto.Message, NullProtoMessage - PROTO
+//     []*some_proto_message - PROTO ARRAY
+//     protoreflect.Enum, NullProtoEnum - ENUM
+//     []some_proto_enum - ENUM ARRAY
 //
 // To compare two Mutations for testing purposes, use reflect.DeepEqual.
 type Mutation struct {
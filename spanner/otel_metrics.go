@@ -0,0 +1,298 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/internal/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// OpenTelemetryMeterName is the name of the meter that the client uses to
+// create its OpenTelemetry instruments.
+const OpenTelemetryMeterName = "cloud.google.com/go/spanner"
+
+// Attribute keys of the OpenTelemetry metrics.
+const (
+	otelAttrClientID   = attribute.Key("client_id")
+	otelAttrDatabase   = attribute.Key("database")
+	otelAttrInstance   = attribute.Key("instance_id")
+	otelAttrLibVersion = attribute.Key("library_version")
+	otelAttrType       = attribute.Key("type")
+	otelAttrMethod     = attribute.Key("grpc_client_method")
+	otelAttrStatus     = attribute.Key("grpc_client_status")
+)
+
+// openTelemetryMetrics contains the OpenTelemetry instruments of a client.
+// All methods can be called on a nil *openTelemetryMetrics, in which case
+// they do nothing. A client only has a non-nil openTelemetryMetrics if
+// ClientConfig.OpenTelemetryMeterProvider has been set.
+type openTelemetryMetrics struct {
+	// attrs are the attributes that are added to all measurements. They are
+	// set once the session client has been created, before any RPC is sent.
+	attrs []attribute.KeyValue
+	meter metric.Meter
+
+	openSessions        metric.Int64ObservableGauge
+	maxAllowedSessions  metric.Int64ObservableGauge
+	sessions            metric.Int64ObservableGauge
+	checkoutLatency     metric.Float64Histogram
+	getSessionTimeouts  metric.Int64Counter
+	abortedTransactions metric.Int64Counter
+	transactionRetries  metric.Int64Counter
+	rpcLatency          metric.Float64Histogram
+	sessionPoolCallback metric.Registration
+}
+
+// newOpenTelemetryMetrics creates the instruments of a client with the given
+// MeterProvider. It returns nil if mp is nil.
+func newOpenTelemetryMetrics(mp metric.MeterProvider) (*openTelemetryMetrics, error) {
+	if mp == nil {
+		return nil, nil
+	}
+	m := mp.Meter(OpenTelemetryMeterName, metric.WithInstrumentationVersion(version.Repo))
+	otm := &openTelemetryMetrics{meter: m}
+	var err error
+	if otm.openSessions, err = m.Int64ObservableGauge(
+		"spanner/open_session_count",
+		metric.WithDescription("Number of sessions currently opened"),
+		metric.WithUnit("1"),
+	); err != nil {
+		return nil, err
+	}
+	if otm.maxAllowedSessions, err = m.Int64ObservableGauge(
+		"spanner/max_allowed_sessions",
+		metric.WithDescription("The maximum number of sessions allowed. Configurable by the user."),
+		metric.WithUnit("1"),
+	); err != nil {
+		return nil, err
+	}
+	if otm.sessions, err = m.Int64ObservableGauge(
+		"spanner/num_sessions_in_pool",
+		metric.WithDescription("The number of sessions currently in use or idle in the session pool, by type"),
+		metric.WithUnit("1"),
+	); err != nil {
+		return nil, err
+	}
+	if otm.checkoutLatency, err = m.Float64Histogram(
+		"spanner/session_checkout_latency",
+		metric.WithDescription("The time it takes to check out a session from the session pool"),
+		metric.WithUnit("ms"),
+	); err != nil {
+		return nil, err
+	}
+	if otm.getSessionTimeouts, err = m.Int64Counter(
+		"spanner/get_session_timeouts",
+		metric.WithDescription("The number of get sessions timeouts due to pool exhaustion, which often indicates a session leak"),
+		metric.WithUnit("1"),
+	); err != nil {
+		return nil, err
+	}
+	if otm.abortedTransactions, err = m.Int64Counter(
+		"spanner/aborted_transactions",
+		metric.WithDescription("The number of read/write transaction attempts that were aborted by Cloud Spanner"),
+		metric.WithUnit("1"),
+	); err != nil {
+		return nil, err
+	}
+	if otm.transactionRetries, err = m.Int64Counter(
+		"spanner/transaction_retries",
+		metric.WithDescription("The number of times a read/write transaction was retried"),
+		metric.WithUnit("1"),
+	); err != nil {
+		return nil, err
+	}
+	if otm.rpcLatency, err = m.Float64Histogram(
+		"spanner/rpc_latency",
+		metric.WithDescription("The latency of completed RPCs to Cloud Spanner, by method and status"),
+		metric.WithUnit("ms"),
+	); err != nil {
+		return nil, err
+	}
+	return otm, nil
+}
+
+// setCommonAttributes sets the attributes that are added to all measurements
+// from the common tags of the client.
+func (otm *openTelemetryMetrics) setCommonAttributes(ct *commonTags) {
+	if otm == nil || ct == nil {
+		return
+	}
+	otm.attrs = []attribute.KeyValue{
+		otelAttrClientID.String(ct.clientID),
+		otelAttrDatabase.String(ct.database),
+		otelAttrInstance.String(ct.instance),
+		otelAttrLibVersion.String(ct.libVersion),
+	}
+}
+
+// attributes returns the common attributes with the given attributes
+// appended.
+func (otm *openTelemetryMetrics) attributes(attrs ...attribute.KeyValue) metric.MeasurementOption {
+	all := make([]attribute.KeyValue, 0, len(otm.attrs)+len(attrs))
+	all = append(all, otm.attrs...)
+	all = append(all, attrs...)
+	return metric.WithAttributes(all...)
+}
+
+// observeSessionPool registers a callback that reports the state of the
+// given session pool when the metrics are collected.
+func (otm *openTelemetryMetrics) observeSessionPool(p *sessionPool) error {
+	if otm == nil {
+		return nil
+	}
+	reg, err := otm.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		p.mu.Lock()
+		numOpened := int64(p.numOpened)
+		maxOpened := int64(p.MaxOpened)
+		numInUse := int64(p.numInUse)
+		numIdle := int64(p.idleList.Len() + p.idleWriteList.Len())
+		p.mu.Unlock()
+
+		o.ObserveInt64(otm.openSessions, numOpened, otm.attributes())
+		o.ObserveInt64(otm.maxAllowedSessions, maxOpened, otm.attributes())
+		o.ObserveInt64(otm.sessions, numInUse, otm.attributes(otelAttrType.String("num_in_use_sessions")))
+		o.ObserveInt64(otm.sessions, numIdle, otm.attributes(otelAttrType.String("num_idle_sessions")))
+		return nil
+	}, otm.openSessions, otm.maxAllowedSessions, otm.sessions)
+	if err != nil {
+		return err
+	}
+	otm.sessionPoolCallback = reg
+	return nil
+}
+
+// close unregisters the session pool callback.
+func (otm *openTelemetryMetrics) close() {
+	if otm == nil || otm.sessionPoolCallback == nil {
+		return
+	}
+	otm.sessionPoolCallback.Unregister()
+}
+
+// recordCheckoutLatency records the time it took to check out a read-only or
+// read/write session that was requested at start.
+func (otm *openTelemetryMetrics) recordCheckoutLatency(ctx context.Context, start time.Time, write bool) {
+	if otm == nil {
+		return
+	}
+	typ := "read_only"
+	if write {
+		typ = "read_write"
+	}
+	otm.checkoutLatency.Record(ctx, millisSince(start), otm.attributes(otelAttrType.String(typ)))
+}
+
+// recordGetSessionTimeout records that a session could not be checked out
+// before the context was done.
+func (otm *openTelemetryMetrics) recordGetSessionTimeout(ctx context.Context) {
+	if otm == nil {
+		return
+	}
+	otm.getSessionTimeouts.Add(ctx, 1, otm.attributes())
+}
+
+// recordTransactionAborted records that an attempt of a read/write
+// transaction was aborted.
+func (otm *openTelemetryMetrics) recordTransactionAborted(ctx context.Context) {
+	if otm == nil {
+		return
+	}
+	otm.abortedTransactions.Add(ctx, 1, otm.attributes())
+}
+
+// recordTransactionRetry records that a read/write transaction is retried.
+func (otm *openTelemetryMetrics) recordTransactionRetry(ctx context.Context) {
+	if otm == nil {
+		return
+	}
+	otm.transactionRetries.Add(ctx, 1, otm.attributes())
+}
+
+// recordRPCLatency records the latency of an RPC that was started at start
+// and finished with err.
+func (otm *openTelemetryMetrics) recordRPCLatency(ctx context.Context, fullMethod string, start time.Time, err error) {
+	if otm == nil {
+		return
+	}
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	otm.rpcLatency.Record(ctx, millisSince(start), otm.attributes(
+		otelAttrMethod.String(method),
+		otelAttrStatus.String(status.Code(err).String()),
+	))
+}
+
+// dialOptions returns the gRPC dial options that record the latency of the
+// RPCs of the client.
+func (otm *openTelemetryMetrics) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(otm.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(otm.streamInterceptor),
+	}
+}
+
+func (otm *openTelemetryMetrics) unaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	otm.recordRPCLatency(ctx, method, start, err)
+	return err
+}
+
+func (otm *openTelemetryMetrics) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		otm.recordRPCLatency(ctx, method, start, err)
+		return nil, err
+	}
+	return &latencyRecordingStream{ClientStream: s, otm: otm, ctx: ctx, method: method, start: start}, nil
+}
+
+// latencyRecordingStream records the latency of a streaming RPC when the
+// stream has been received completely or has failed.
+type latencyRecordingStream struct {
+	grpc.ClientStream
+	otm    *openTelemetryMetrics
+	ctx    context.Context
+	method string
+	start  time.Time
+	done   bool
+}
+
+func (s *latencyRecordingStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil && !s.done {
+		s.done = true
+		if err == io.EOF {
+			s.otm.recordRPCLatency(s.ctx, s.method, s.start, nil)
+		} else {
+			s.otm.recordRPCLatency(s.ctx, s.method, s.start, err)
+		}
+	}
+	return err
+}
+
+func millisSince(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
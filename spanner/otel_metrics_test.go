@@ -0,0 +1,199 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// otelDataPoint is a data point of a collected OpenTelemetry metric. Gauges
+// and counters have a Value, histograms have a Count.
+type otelDataPoint struct {
+	Attributes map[string]interface{}
+	Value      float64
+	Count      uint64
+}
+
+// collectOpenTelemetryMetrics collects the metrics of reader and returns the
+// data points of each metric by name. The metrics are converted through
+// their JSON representation so that the test does not depend on the
+// aggregation types of the SDK.
+func collectOpenTelemetryMetrics(t *testing.T, reader sdkmetric.Reader) map[string][]otelDataPoint {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(rm.ScopeMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scopes []struct {
+		Metrics []struct {
+			Name string
+			Data struct {
+				DataPoints []struct {
+					Attributes []struct {
+						Key   string
+						Value struct{ Value interface{} }
+					}
+					Value float64
+					Count uint64
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &scopes); err != nil {
+		t.Fatal(err)
+	}
+	res := make(map[string][]otelDataPoint)
+	for _, s := range scopes {
+		for _, m := range s.Metrics {
+			for _, dp := range m.Data.DataPoints {
+				p := otelDataPoint{Attributes: make(map[string]interface{}), Value: dp.Value, Count: dp.Count}
+				for _, a := range dp.Attributes {
+					p.Attributes[a.Key] = a.Value.Value
+				}
+				res[m.Name] = append(res[m.Name], p)
+			}
+		}
+	}
+	return res
+}
+
+func TestOpenTelemetryMetrics(t *testing.T) {
+	t.Parallel()
+
+	reader := sdkmetric.NewManualReader()
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{
+		SessionPoolConfig:          SessionPoolConfig{MinOpened: 2, MaxOpened: 10},
+		OpenTelemetryMeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	defer teardown()
+	waitFor(t, func() error {
+		client.idleSessions.mu.Lock()
+		defer client.idleSessions.mu.Unlock()
+		if got := client.idleSessions.idleList.Len(); got < 2 {
+			return status.Errorf(codes.FailedPrecondition, "got %d idle sessions, want 2", got)
+		}
+		return nil
+	})
+
+	server.TestSpanner.PutExecutionTime(MethodCommitTransaction, SimulatedExecutionTime{
+		Errors: []error{status.Error(codes.Aborted, "Transaction aborted")},
+	})
+	ctx := context.Background()
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		tx.BufferWrite([]*Mutation{Insert("Singers", []string{"SingerId"}, []interface{}{int64(1)})})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	iter := client.Single().Query(ctx, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums))
+	if err := iter.Do(func(*Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	got := collectOpenTelemetryMetrics(t, reader)
+	for _, name := range []string{"spanner/open_session_count", "spanner/max_allowed_sessions"} {
+		if len(got[name]) != 1 {
+			t.Fatalf("%s: got %d data points, want 1", name, len(got[name]))
+		}
+	}
+	if g, w := got["spanner/max_allowed_sessions"][0].Value, 10.0; g != w {
+		t.Errorf("max allowed sessions: got %v, want %v", g, w)
+	}
+	if g, w := got["spanner/open_session_count"][0].Value, 2.0; g < w {
+		t.Errorf("open sessions: got %v, want at least %v", g, w)
+	}
+	p := got["spanner/open_session_count"][0]
+	for k, w := range map[string]interface{}{
+		"client_id":       client.sc.id,
+		"database":        "[DATABASE]",
+		"instance_id":     "[INSTANCE]",
+		"library_version": client.ct.libVersion,
+	} {
+		if g := p.Attributes[k]; g != w {
+			t.Errorf("attribute %s: got %v, want %v", k, g, w)
+		}
+	}
+	sessions := make(map[interface{}]float64)
+	for _, p := range got["spanner/num_sessions_in_pool"] {
+		sessions[p.Attributes["type"]] = p.Value
+	}
+	if g, w := sessions["num_in_use_sessions"], 0.0; g != w {
+		t.Errorf("in use sessions: got %v, want %v", g, w)
+	}
+	if _, ok := sessions["num_idle_sessions"]; !ok {
+		t.Error("missing idle sessions")
+	}
+
+	checkouts := make(map[interface{}]uint64)
+	for _, p := range got["spanner/session_checkout_latency"] {
+		checkouts[p.Attributes["type"]] = p.Count
+	}
+	if g, w := checkouts["read_write"], uint64(1); g != w {
+		t.Errorf("read/write session checkouts: got %v, want %v", g, w)
+	}
+	if g, w := checkouts["read_only"], uint64(1); g != w {
+		t.Errorf("read-only session checkouts: got %v, want %v", g, w)
+	}
+	for _, name := range []string{"spanner/aborted_transactions", "spanner/transaction_retries"} {
+		if len(got[name]) != 1 || got[name][0].Value != 1 {
+			t.Errorf("%s: got %v, want a single data point with value 1", name, got[name])
+		}
+	}
+
+	rpcs := make(map[[2]interface{}]uint64)
+	for _, p := range got["spanner/rpc_latency"] {
+		rpcs[[2]interface{}{p.Attributes["grpc_client_method"], p.Attributes["grpc_client_status"]}] = p.Count
+	}
+	for k, w := range map[[2]interface{}]uint64{
+		{"Commit", "Aborted"}:         1,
+		{"Commit", "OK"}:              1,
+		{"ExecuteStreamingSql", "OK"}: 1,
+	} {
+		if g := rpcs[k]; g != w {
+			t.Errorf("RPC latency %v: got %d data points, want %d", k, g, w)
+		}
+	}
+	if rpcs[[2]interface{}{"BatchCreateSessions", "OK"}] == 0 {
+		t.Error("missing RPC latency of BatchCreateSessions")
+	}
+}
+
+func TestOpenTelemetryMetrics_disabled(t *testing.T) {
+	t.Parallel()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+	if client.otm != nil || client.idleSessions.otm != nil {
+		t.Fatal("OpenTelemetry metrics are enabled without a MeterProvider")
+	}
+	iter := client.Single().Query(context.Background(), NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums))
+	if err := iter.Do(func(*Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
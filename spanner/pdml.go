@@ -52,6 +52,8 @@ func (c *Client) partitionedUpdate(ctx context.Context, statement Statement, opt
 		return 0, err
 	}
 
+	ctx, cancel := contextWithStatementTimeout(ctx, options.Timeout)
+	defer cancel()
 	sh, err := c.idleSessions.take(ctx)
 	if err != nil {
 		return 0, ToSpannerError(err)
@@ -80,7 +82,7 @@ func (c *Client) partitionedUpdate(ctx context.Context, statement Statement, opt
 	// Execute the PDML and retry if the transaction is aborted.
 	executePdmlWithRetry := func(ctx context.Context) (int64, error) {
 		for {
-			count, err := executePdml(ctx, sh, req)
+			count, err := executePdml(ctx, sh, req, !c.disableRouteToLeader)
 			if err == nil {
 				return count, nil
 			}
@@ -101,11 +103,13 @@ func (c *Client) partitionedUpdate(ctx context.Context, statement Statement, opt
 // 2. Add the ID of the PDML transaction to the SQL request.
 // 3. Execute the update statement on the PDML transaction
 //
-// Note that PDML transactions cannot be committed or rolled back.
-func executePdml(ctx context.Context, sh *sessionHandle, req *sppb.ExecuteSqlRequest) (count int64, err error) {
+// Note that PDML transactions cannot be committed or rolled back. The requests
+// are routed to the leader region if routeToLeader is true.
+func executePdml(ctx context.Context, sh *sessionHandle, req *sppb.ExecuteSqlRequest, routeToLeader bool) (count int64, err error) {
 	var md metadata.MD
+	ctx = contextWithOutgoingMetadata(ctx, withRouteToLeader(sh.getMetadata(), routeToLeader))
 	// Begin transaction.
-	res, err := sh.getClient().BeginTransaction(contextWithOutgoingMetadata(ctx, sh.getMetadata()), &sppb.BeginTransactionRequest{
+	res, err := sh.getClient().BeginTransaction(ctx, &sppb.BeginTransactionRequest{
 		Session: sh.getID(),
 		Options: &sppb.TransactionOptions{
 			Mode: &sppb.TransactionOptions_PartitionedDml_{PartitionedDml: &sppb.TransactionOptions_PartitionedDml{}},
@@ -118,7 +122,7 @@ func executePdml(ctx context.Context, sh *sessionHandle, req *sppb.ExecuteSqlReq
 	req.Transaction = &sppb.TransactionSelector{
 		Selector: &sppb.TransactionSelector_Id{Id: res.Id},
 	}
-	resultSet, err := sh.getClient().ExecuteSql(contextWithOutgoingMetadata(ctx, sh.getMetadata()), req, gax.WithGRPCOptions(grpc.Header(&md)))
+	resultSet, err := sh.getClient().ExecuteSql(ctx, req, gax.WithGRPCOptions(grpc.Header(&md)))
 	if getGFELatencyMetricsFlag() && md != nil && sh.session.pool != nil {
 		err := captureGFELatencyStats(tag.NewContext(ctx, sh.session.pool.tagMap), md, "executePdml_ExecuteSql")
 		if err != nil {
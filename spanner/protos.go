@@ -0,0 +1,584 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	proto3 "github.com/golang/protobuf/ptypes/struct"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// typeCodeProto and typeCodeEnum are the type codes of PROTO and ENUM
+	// columns, which the generated sppb.TypeCode does not have.
+	typeCodeProto sppb.TypeCode = 13
+	typeCodeEnum  sppb.TypeCode = 14
+
+	// typeProtoTypeFqn is the field number of the proto_type_fqn field of
+	// google.spanner.v1.Type, which holds the fully qualified name of the
+	// proto message or enum of a PROTO or ENUM column.
+	typeProtoTypeFqn = 5
+)
+
+var (
+	protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+	protoEnumType    = reflect.TypeOf((*protoreflect.Enum)(nil)).Elem()
+
+	// dynamicMessageType is the type of messages that are created from
+	// descriptors. Unlike generated messages, a nil *dynamicpb.Message has no
+	// descriptor.
+	dynamicMessageType = reflect.TypeOf((*dynamicpb.Message)(nil))
+)
+
+// protoType returns the Cloud Spanner type of a PROTO or ENUM column of the
+// proto type with the given fully qualified name.
+func protoType(code sppb.TypeCode, fqn protoreflect.FullName) *sppb.Type {
+	t := &sppb.Type{Code: code}
+	m := t.ProtoReflect()
+	b := protowire.AppendTag(m.GetUnknown(), typeProtoTypeFqn, protowire.BytesType)
+	m.SetUnknown(protowire.AppendString(b, string(fqn)))
+	return t
+}
+
+// protoTypeFqn returns the fully qualified name of the proto type of t, or
+// an empty string if t does not have one.
+func protoTypeFqn(t *sppb.Type) protoreflect.FullName {
+	b := t.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return ""
+		}
+		b = b[n:]
+		if num == typeProtoTypeFqn && typ == protowire.BytesType {
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return ""
+			}
+			return protoreflect.FullName(v)
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return ""
+		}
+		b = b[n:]
+	}
+	return ""
+}
+
+// protoTypes holds the proto types registered with RegisterProtoDescriptors.
+var protoTypes struct {
+	mu       sync.RWMutex
+	messages map[protoreflect.FullName]protoreflect.MessageType
+	enums    map[protoreflect.FullName]protoreflect.EnumType
+}
+
+// RegisterProtoDescriptors registers the proto messages and enums of a
+// descriptor set, such as the one produced by
+//
+//	protoc --include_imports --descriptor_set_out=descriptors.pb
+//
+// for the proto files of the PROTO and ENUM columns of a database.
+//
+// The registered types are used to decode PROTO and ENUM values when the
+// destination does not determine the proto type, such as in Row.ToMap and
+// Row.MarshalJSON. Types that are linked into the binary as generated Go code
+// do not need to be registered. Registering a type again replaces the
+// previously registered one.
+func RegisterProtoDescriptors(fds *descriptorpb.FileDescriptorSet) error {
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return spannerErrorf(codes.InvalidArgument, "invalid proto descriptors: %v", err)
+	}
+	messages := make(map[protoreflect.FullName]protoreflect.MessageType)
+	enums := make(map[protoreflect.FullName]protoreflect.EnumType)
+	var register func(ms protoreflect.MessageDescriptors, es protoreflect.EnumDescriptors)
+	register = func(ms protoreflect.MessageDescriptors, es protoreflect.EnumDescriptors) {
+		for i := 0; i < es.Len(); i++ {
+			enums[es.Get(i).FullName()] = dynamicpb.NewEnumType(es.Get(i))
+		}
+		for i := 0; i < ms.Len(); i++ {
+			md := ms.Get(i)
+			if !md.IsMapEntry() {
+				messages[md.FullName()] = dynamicpb.NewMessageType(md)
+			}
+			register(md.Messages(), md.Enums())
+		}
+	}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		register(fd.Messages(), fd.Enums())
+		return true
+	})
+
+	protoTypes.mu.Lock()
+	defer protoTypes.mu.Unlock()
+	if protoTypes.messages == nil {
+		protoTypes.messages = make(map[protoreflect.FullName]protoreflect.MessageType)
+		protoTypes.enums = make(map[protoreflect.FullName]protoreflect.EnumType)
+	}
+	for name, mt := range messages {
+		protoTypes.messages[name] = mt
+	}
+	for name, et := range enums {
+		protoTypes.enums[name] = et
+	}
+	return nil
+}
+
+// findProtoMessageType returns the message type with the given name, or nil
+// if it is neither linked into the binary nor registered with
+// RegisterProtoDescriptors.
+func findProtoMessageType(name protoreflect.FullName) protoreflect.MessageType {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(name); err == nil {
+		return mt
+	}
+	protoTypes.mu.RLock()
+	defer protoTypes.mu.RUnlock()
+	return protoTypes.messages[name]
+}
+
+// findProtoEnumType returns the enum type with the given name, or nil if it
+// is neither linked into the binary nor registered with
+// RegisterProtoDescriptors.
+func findProtoEnumType(name protoreflect.FullName) protoreflect.EnumType {
+	if et, err := protoregistry.GlobalTypes.FindEnumByName(name); err == nil {
+		return et
+	}
+	protoTypes.mu.RLock()
+	defer protoTypes.mu.RUnlock()
+	return protoTypes.enums[name]
+}
+
+// NullProtoMessage represents a Cloud Spanner PROTO that may be NULL.
+//
+// ProtoMessageVal determines the proto type of the column, and must therefore
+// be set to a message of that type, such as a typed nil pointer, also when the
+// value is NULL.
+type NullProtoMessage struct {
+	ProtoMessageVal proto.Message // ProtoMessageVal contains the value when Valid is true.
+	Valid           bool          // Valid is true if ProtoMessageVal is not NULL.
+}
+
+// IsNull implements NullableValue.IsNull for NullProtoMessage.
+func (n NullProtoMessage) IsNull() bool {
+	return !n.Valid
+}
+
+// String implements Stringer.String for NullProtoMessage.
+func (n NullProtoMessage) String() string {
+	if !n.Valid {
+		return nullString
+	}
+	return protojson.Format(n.ProtoMessageVal)
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON for NullProtoMessage.
+func (n NullProtoMessage) MarshalJSON() ([]byte, error) {
+	if n.Valid {
+		return protojson.Marshal(n.ProtoMessageVal)
+	}
+	return jsonNullBytes, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON for
+// NullProtoMessage. ProtoMessageVal must be set to a message of the type to
+// unmarshal.
+func (n *NullProtoMessage) UnmarshalJSON(payload []byte) error {
+	if payload == nil {
+		return fmt.Errorf("payload should not be nil")
+	}
+	if bytes.Equal(payload, jsonNullBytes) {
+		n.Valid = false
+		return nil
+	}
+	if n.ProtoMessageVal == nil {
+		return fmt.Errorf("ProtoMessageVal must be set to unmarshal a proto message")
+	}
+	m := n.ProtoMessageVal.ProtoReflect().Type().New().Interface()
+	if err := protojson.Unmarshal(payload, m); err != nil {
+		return fmt.Errorf("payload cannot be converted to a proto message: got %v, err: %s", string(payload), err)
+	}
+	n.ProtoMessageVal = m
+	n.Valid = true
+	return nil
+}
+
+// NullProtoEnum represents a Cloud Spanner ENUM that may be NULL.
+//
+// ProtoEnumVal determines the proto type of the column, and must therefore be
+// set to a value of that type, such as its zero value, also when the value is
+// NULL.
+type NullProtoEnum struct {
+	ProtoEnumVal protoreflect.Enum // ProtoEnumVal contains the value when Valid is true.
+	Valid        bool              // Valid is true if ProtoEnumVal is not NULL.
+}
+
+// IsNull implements NullableValue.IsNull for NullProtoEnum.
+func (n NullProtoEnum) IsNull() bool {
+	return !n.Valid
+}
+
+// String implements Stringer.String for NullProtoEnum.
+func (n NullProtoEnum) String() string {
+	if !n.Valid {
+		return nullString
+	}
+	return protoEnumName(n.ProtoEnumVal)
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON for NullProtoEnum.
+func (n NullProtoEnum) MarshalJSON() ([]byte, error) {
+	if n.Valid {
+		return []byte(strconv.Quote(protoEnumName(n.ProtoEnumVal))), nil
+	}
+	return jsonNullBytes, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON for NullProtoEnum.
+// ProtoEnumVal must be set to a value of the type to unmarshal. The payload
+// can either be the name or the number of the enum value.
+func (n *NullProtoEnum) UnmarshalJSON(payload []byte) error {
+	if payload == nil {
+		return fmt.Errorf("payload should not be nil")
+	}
+	if bytes.Equal(payload, jsonNullBytes) {
+		n.Valid = false
+		return nil
+	}
+	if n.ProtoEnumVal == nil {
+		return fmt.Errorf("ProtoEnumVal must be set to unmarshal a proto enum")
+	}
+	et := n.ProtoEnumVal.Type()
+	if name, err := strconv.Unquote(string(payload)); err == nil {
+		v := et.Descriptor().Values().ByName(protoreflect.Name(name))
+		if v == nil {
+			return fmt.Errorf("payload cannot be converted to enum %v: got %v", et.Descriptor().FullName(), string(payload))
+		}
+		n.ProtoEnumVal = et.New(v.Number())
+		n.Valid = true
+		return nil
+	}
+	x, err := strconv.ParseInt(string(payload), 10, 32)
+	if err != nil {
+		return fmt.Errorf("payload cannot be converted to enum %v: got %v, err: %s", et.Descriptor().FullName(), string(payload), err)
+	}
+	n.ProtoEnumVal = et.New(protoreflect.EnumNumber(x))
+	n.Valid = true
+	return nil
+}
+
+// protoEnumName returns the name of the value of e, or its number if the
+// value is not defined by the enum.
+func protoEnumName(e protoreflect.Enum) string {
+	if v := e.Descriptor().Values().ByNumber(e.Number()); v != nil {
+		return string(v.Name())
+	}
+	return strconv.FormatInt(int64(e.Number()), 10)
+}
+
+// isProtoValue returns true if v is a proto message or enum, a nullable
+// proto message or enum, or a slice of proto messages or enums.
+func isProtoValue(v interface{}) bool {
+	switch v.(type) {
+	case proto.Message, protoreflect.Enum, NullProtoMessage, NullProtoEnum:
+		return true
+	}
+	typ := reflect.TypeOf(v)
+	if typ == nil || typ.Kind() != reflect.Slice {
+		return false
+	}
+	return typ.Elem().Implements(protoMessageType) || typ.Elem().Implements(protoEnumType)
+}
+
+// errProtoTypeUnknown returns error for a PROTO or ENUM value whose proto
+// type cannot be determined.
+func errProtoTypeUnknown(v interface{}) error {
+	return spannerErrorf(codes.InvalidArgument, "cannot determine the proto type of %T value %v", v, v)
+}
+
+// encodeProtoValue encodes a value for which isProtoValue returns true.
+func encodeProtoValue(v interface{}) (*proto3.Value, *sppb.Type, error) {
+	null := &proto3.Value{Kind: &proto3.Value_NullValue{NullValue: proto3.NullValue_NULL_VALUE}}
+	switch v := v.(type) {
+	case NullProtoMessage:
+		if v.ProtoMessageVal == nil {
+			return nil, nil, errProtoTypeUnknown(v)
+		}
+		if v.Valid {
+			return encodeProtoValue(v.ProtoMessageVal)
+		}
+		return null, protoType(typeCodeProto, v.ProtoMessageVal.ProtoReflect().Descriptor().FullName()), nil
+	case NullProtoEnum:
+		if v.ProtoEnumVal == nil {
+			return nil, nil, errProtoTypeUnknown(v)
+		}
+		if v.Valid {
+			return encodeProtoValue(v.ProtoEnumVal)
+		}
+		return null, protoType(typeCodeEnum, v.ProtoEnumVal.Descriptor().FullName()), nil
+	case proto.Message:
+		m := v.ProtoReflect()
+		pt := protoType(typeCodeProto, m.Descriptor().FullName())
+		if !m.IsValid() {
+			return null, pt, nil
+		}
+		b, err := proto.Marshal(v)
+		if err != nil {
+			return nil, nil, ToSpannerError(err)
+		}
+		return &proto3.Value{Kind: stringKind(base64.StdEncoding.EncodeToString(b))}, pt, nil
+	case protoreflect.Enum:
+		pt := protoType(typeCodeEnum, v.Descriptor().FullName())
+		return &proto3.Value{Kind: stringKind(strconv.FormatInt(int64(v.Number()), 10))}, pt, nil
+	}
+
+	// v is a slice of proto messages or enums. The type of its elements is
+	// taken from a non-nil element, or from the zero value of the element
+	// type if that is a generated message or enum.
+	vs := reflect.ValueOf(v)
+	var et *sppb.Type
+	for i := 0; i < vs.Len() && et == nil; i++ {
+		if e := vs.Index(i); !(e.Kind() == reflect.Interface || e.Kind() == reflect.Ptr) || !e.IsNil() {
+			_, et, _ = encodeProtoValue(e.Interface())
+		}
+	}
+	if et == nil {
+		zero := reflect.Zero(vs.Type().Elem())
+		if zero.Kind() == reflect.Interface || zero.Type() == dynamicMessageType {
+			return nil, nil, errProtoTypeUnknown(v)
+		}
+		_, et, _ = encodeProtoValue(zero.Interface())
+	}
+	pt := listType(et)
+	if vs.IsNil() {
+		return null, pt, nil
+	}
+	pb, err := encodeArray(vs.Len(), func(i int) interface{} { return vs.Index(i).Interface() })
+	if err != nil {
+		return nil, nil, err
+	}
+	return pb, pt, nil
+}
+
+// errProtoTypeMismatch returns error for decoding a PROTO or ENUM value into
+// a proto type other than the one of the column.
+func errProtoTypeMismatch(t *sppb.Type, name protoreflect.FullName, dst interface{}) error {
+	return spannerErrorf(codes.InvalidArgument, "type %v of the Cloud Spanner value cannot be decoded into %T of proto type %v", protoTypeFqn(t), dst, name)
+}
+
+// checkProtoType checks that t is a PROTO or ENUM type with the given code
+// that can be decoded into a proto type with the given name.
+func checkProtoType(t *sppb.Type, code sppb.TypeCode, name protoreflect.FullName, dst interface{}) error {
+	if t.Code != code {
+		return errTypeMismatch(t.Code, t.GetArrayElementType().GetCode(), dst)
+	}
+	if fqn := protoTypeFqn(t); fqn != "" && fqn != name {
+		return errProtoTypeMismatch(t, name, dst)
+	}
+	return nil
+}
+
+// decodeProtoValue decodes a PROTO or ENUM value, or an ARRAY of them, into
+// ptr if ptr is a destination for proto messages or enums. It returns false
+// if ptr is not such a destination.
+//
+// The supported destinations are proto.Message, *NullProtoMessage,
+// *NullProtoEnum, pointers to generated enums, and pointers to pointers to
+// generated messages and enums, and pointers to slices of those.
+func decodeProtoValue(v *proto3.Value, t *sppb.Type, ptr interface{}) (bool, error) {
+	_, isNull := v.Kind.(*proto3.Value_NullValue)
+	switch p := ptr.(type) {
+	case *NullProtoMessage:
+		if p == nil {
+			return true, errNilDst(p)
+		}
+		if p.ProtoMessageVal == nil {
+			return true, errProtoTypeUnknown(*p)
+		}
+		m := p.ProtoMessageVal.ProtoReflect().Type().New().Interface()
+		if err := checkProtoType(t, typeCodeProto, m.ProtoReflect().Descriptor().FullName(), ptr); err != nil {
+			return true, err
+		}
+		if isNull {
+			p.Valid = false
+			return true, nil
+		}
+		if err := unmarshalProtoValue(v, m); err != nil {
+			return true, err
+		}
+		*p = NullProtoMessage{m, true}
+		return true, nil
+	case *NullProtoEnum:
+		if p == nil {
+			return true, errNilDst(p)
+		}
+		if p.ProtoEnumVal == nil {
+			return true, errProtoTypeUnknown(*p)
+		}
+		et := p.ProtoEnumVal.Type()
+		if err := checkProtoType(t, typeCodeEnum, et.Descriptor().FullName(), ptr); err != nil {
+			return true, err
+		}
+		if isNull {
+			p.Valid = false
+			return true, nil
+		}
+		var x int64
+		if err := decodeValue(v, t, &x); err != nil {
+			return true, err
+		}
+		*p = NullProtoEnum{et.New(protoreflect.EnumNumber(x)), true}
+		return true, nil
+	case proto.Message:
+		if reflect.ValueOf(p).IsNil() {
+			return true, errNilDst(p)
+		}
+		if err := checkProtoType(t, typeCodeProto, p.ProtoReflect().Descriptor().FullName(), ptr); err != nil {
+			return true, err
+		}
+		if isNull {
+			return true, errDstNotForNull(ptr)
+		}
+		proto.Reset(p)
+		return true, unmarshalProtoValue(v, p)
+	}
+
+	vp := reflect.ValueOf(ptr)
+	if vp.Kind() != reflect.Ptr {
+		return false, nil
+	}
+	typ := vp.Type().Elem()
+	switch {
+	case typ.Kind() == reflect.Ptr && typ.Implements(protoMessageType) && typ != dynamicMessageType:
+		// A pointer to a generated message pointer.
+		if vp.IsNil() {
+			return true, errNilDst(ptr)
+		}
+		m := reflect.New(typ.Elem()).Interface().(proto.Message)
+		if err := checkProtoType(t, typeCodeProto, m.ProtoReflect().Descriptor().FullName(), ptr); err != nil {
+			return true, err
+		}
+		if isNull {
+			vp.Elem().Set(reflect.Zero(typ))
+			return true, nil
+		}
+		if err := unmarshalProtoValue(v, m); err != nil {
+			return true, err
+		}
+		vp.Elem().Set(reflect.ValueOf(m))
+	case typ.Kind() == reflect.Int32 && typ.Implements(protoEnumType):
+		// A pointer to a generated enum.
+		if vp.IsNil() {
+			return true, errNilDst(ptr)
+		}
+		e := reflect.Zero(typ).Interface().(protoreflect.Enum)
+		if err := checkProtoType(t, typeCodeEnum, e.Descriptor().FullName(), ptr); err != nil {
+			return true, err
+		}
+		if isNull {
+			return true, errDstNotForNull(ptr)
+		}
+		var x int64
+		if err := decodeValue(v, t, &x); err != nil {
+			return true, err
+		}
+		vp.Elem().SetInt(x)
+	case typ.Kind() == reflect.Ptr && typ.Elem().Kind() == reflect.Int32 && typ.Elem().Implements(protoEnumType):
+		// A pointer to a generated enum pointer.
+		if vp.IsNil() {
+			return true, errNilDst(ptr)
+		}
+		e := reflect.New(typ.Elem())
+		if isNull {
+			if err := checkProtoType(t, typeCodeEnum, e.Elem().Interface().(protoreflect.Enum).Descriptor().FullName(), ptr); err != nil {
+				return true, err
+			}
+			vp.Elem().Set(reflect.Zero(typ))
+			return true, nil
+		}
+		if err := decodeValue(v, t, e.Interface()); err != nil {
+			return true, err
+		}
+		vp.Elem().Set(e)
+	case typ.Kind() == reflect.Slice:
+		// A pointer to a slice of any of the above.
+		elem := typ.Elem()
+		if !(elem.Kind() == reflect.Ptr && elem.Implements(protoMessageType) && elem != dynamicMessageType) &&
+			!(elem.Kind() == reflect.Int32 && elem.Implements(protoEnumType)) &&
+			!(elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Int32 && elem.Elem().Implements(protoEnumType)) {
+			return false, nil
+		}
+		if vp.IsNil() {
+			return true, errNilDst(ptr)
+		}
+		if t.Code != sppb.TypeCode_ARRAY {
+			return true, errTypeMismatch(t.Code, sppb.TypeCode_TYPE_CODE_UNSPECIFIED, ptr)
+		}
+		if t.ArrayElementType == nil {
+			return true, errNilArrElemType(t)
+		}
+		if isNull {
+			vp.Elem().Set(reflect.Zero(typ))
+			return true, nil
+		}
+		x, err := getListValue(v)
+		if err != nil {
+			return true, err
+		}
+		s := reflect.MakeSlice(typ, len(x.Values), len(x.Values))
+		for i, e := range x.Values {
+			if err := decodeValue(e, t.ArrayElementType, s.Index(i).Addr().Interface()); err != nil {
+				return true, errDecodeArrayElement(i, e, t.ArrayElementType.Code.String(), err)
+			}
+		}
+		vp.Elem().Set(s)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// unmarshalProtoValue unmarshals the base64 encoded PROTO value v into m.
+func unmarshalProtoValue(v *proto3.Value, m proto.Message) error {
+	x, err := getStringValue(v)
+	if err != nil {
+		return err
+	}
+	b, err := base64.StdEncoding.DecodeString(x)
+	if err != nil {
+		return errBadEncoding(v, err)
+	}
+	if err := proto.Unmarshal(b, m); err != nil {
+		return errBadEncoding(v, err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	proto3 "github.com/golang/protobuf/ptypes/struct"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func durationValueProto(t *testing.T, d time.Duration) *proto3.Value {
+	b, err := proto.Marshal(durationpb.New(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stringProto(base64.StdEncoding.EncodeToString(b))
+}
+
+func TestEncodeProtoValue(t *testing.T) {
+	durationType := protoType(typeCodeProto, "google.protobuf.Duration")
+	typeCodeType := protoType(typeCodeEnum, "google.spanner.v1.TypeCode")
+	for _, test := range []struct {
+		in       interface{}
+		wantVal  *proto3.Value
+		wantType *sppb.Type
+	}{
+		{durationpb.New(time.Second), durationValueProto(t, time.Second), durationType},
+		{(*durationpb.Duration)(nil), nullProto(), durationType},
+		{NullProtoMessage{durationpb.New(time.Second), true}, durationValueProto(t, time.Second), durationType},
+		{NullProtoMessage{(*durationpb.Duration)(nil), false}, nullProto(), durationType},
+		{[]*durationpb.Duration{durationpb.New(time.Second), nil}, listProto(durationValueProto(t, time.Second), nullProto()), listType(durationType)},
+		{[]*durationpb.Duration(nil), nullProto(), listType(durationType)},
+		{sppb.TypeCode_STRING, stringProto("6"), typeCodeType},
+		{NullProtoEnum{sppb.TypeCode_STRING, true}, stringProto("6"), typeCodeType},
+		{NullProtoEnum{sppb.TypeCode(0), false}, nullProto(), typeCodeType},
+		{[]sppb.TypeCode{sppb.TypeCode_BOOL, sppb.TypeCode_INT64}, listProto(stringProto("1"), stringProto("2")), listType(typeCodeType)},
+	} {
+		gotVal, gotType, err := encodeValue(test.in)
+		if err != nil {
+			t.Errorf("encodeValue(%v): %v", test.in, err)
+			continue
+		}
+		if !testEqual(gotVal, test.wantVal) {
+			t.Errorf("encodeValue(%v): got value %v, want %v", test.in, gotVal, test.wantVal)
+		}
+		if !proto.Equal(gotType, test.wantType) {
+			t.Errorf("encodeValue(%v): got type %v, want %v", test.in, gotType, test.wantType)
+		}
+		if !isSupportedMutationType(test.in) {
+			t.Errorf("isSupportedMutationType(%v): got false, want true", test.in)
+		}
+	}
+
+	for _, in := range []interface{}{NullProtoMessage{}, NullProtoEnum{}, []proto.Message{}} {
+		if _, _, err := encodeValue(in); ErrCode(err) != codes.InvalidArgument {
+			t.Errorf("encodeValue(%#v): got error %v, want code %v", in, err, codes.InvalidArgument)
+		}
+	}
+}
+
+func TestDecodeProtoValue(t *testing.T) {
+	r, err := NewRow(
+		[]string{"Message", "NullMessage", "Messages", "Enum", "NullEnum", "Enums"},
+		[]interface{}{
+			durationpb.New(time.Second),
+			(*durationpb.Duration)(nil),
+			[]*durationpb.Duration{durationpb.New(time.Minute), nil},
+			sppb.TypeCode_JSON,
+			NullProtoEnum{sppb.TypeCode(0), false},
+			[]sppb.TypeCode{sppb.TypeCode_BOOL, sppb.TypeCode_DATE},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m durationpb.Duration
+	var mp, nmp *durationpb.Duration
+	var nm = NullProtoMessage{ProtoMessageVal: (*durationpb.Duration)(nil)}
+	var ms []*durationpb.Duration
+	var b []byte
+	if err := r.Columns(&m, &nmp, &ms, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Column(0, &mp); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Column(0, &nm); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Column(0, &b); err != nil {
+		t.Fatal(err)
+	}
+	if m.AsDuration() != time.Second || mp.AsDuration() != time.Second || nmp != nil {
+		t.Errorf("got messages %v, %v and %v, want 1s, 1s and nil", &m, mp, nmp)
+	}
+	if !nm.Valid || !proto.Equal(nm.ProtoMessageVal, durationpb.New(time.Second)) {
+		t.Errorf("got %v, want 1s", nm)
+	}
+	if len(ms) != 2 || ms[0].AsDuration() != time.Minute || ms[1] != nil {
+		t.Errorf("got messages %v, want [1m nil]", ms)
+	}
+	var got durationpb.Duration
+	if err := proto.Unmarshal(b, &got); err != nil || got.AsDuration() != time.Second {
+		t.Errorf("got bytes %v (%v), want the encoding of 1s", b, err)
+	}
+	if err := r.Column(1, &nm); err != nil || nm.Valid {
+		t.Errorf("got %v (%v), want NULL", nm, err)
+	}
+	if err := r.Column(1, &m); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("decoding NULL into a message: got error %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	var e sppb.TypeCode
+	var ep, nep *sppb.TypeCode
+	var ne = NullProtoEnum{ProtoEnumVal: sppb.TypeCode(0)}
+	var es []sppb.TypeCode
+	var n int64
+	if err := r.Columns(nil, nil, nil, &e, &nep, &es); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Column(3, &ep); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Column(3, &ne); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Column(3, &n); err != nil {
+		t.Fatal(err)
+	}
+	if e != sppb.TypeCode_JSON || ep == nil || *ep != sppb.TypeCode_JSON || nep != nil || n != int64(sppb.TypeCode_JSON) {
+		t.Errorf("got enums %v, %v, %v and %v, want JSON, JSON, nil and 11", e, ep, nep, n)
+	}
+	if !ne.Valid || ne.ProtoEnumVal.Number() != protoreflect.EnumNumber(sppb.TypeCode_JSON) {
+		t.Errorf("got %v, want JSON", ne)
+	}
+	if !testEqual(es, []sppb.TypeCode{sppb.TypeCode_BOOL, sppb.TypeCode_DATE}) {
+		t.Errorf("got enums %v, want [BOOL DATE]", es)
+	}
+	if err := r.Column(4, &ne); err != nil || ne.Valid {
+		t.Errorf("got %v (%v), want NULL", ne, err)
+	}
+
+	// The proto type of the destination must match the one of the column.
+	for _, test := range []struct {
+		col int
+		ptr interface{}
+	}{
+		{0, &timestamppb.Timestamp{}},
+		{0, &e},
+		{0, &ne},
+		{3, &m},
+		{3, &NullProtoEnum{ProtoEnumVal: sppb.TypeAnnotationCode(0)}},
+	} {
+		if err := r.Column(test.col, test.ptr); ErrCode(err) != codes.InvalidArgument {
+			t.Errorf("decoding column %d into %T: got error %v, want code %v", test.col, test.ptr, err, codes.InvalidArgument)
+		}
+	}
+}
+
+func TestRegisterProtoDescriptors(t *testing.T) {
+	fds := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("singer.proto"),
+		Package: proto.String("examples.spanner.music"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("SingerInfo"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:     proto.String("nationality"),
+				JsonName: proto.String("nationality"),
+				Number:   proto.Int32(1),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Genre"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("POP"), Number: proto.Int32(0)},
+				{Name: proto.String("JAZZ"), Number: proto.Int32(1)},
+			},
+		}},
+	}}}
+	if err := RegisterProtoDescriptors(fds); err != nil {
+		t.Fatal(err)
+	}
+	mt := findProtoMessageType("examples.spanner.music.SingerInfo")
+	et := findProtoEnumType("examples.spanner.music.Genre")
+	if mt == nil || et == nil {
+		t.Fatalf("got types %v and %v, want registered types", mt, et)
+	}
+
+	info := dynamicpb.NewMessage(mt.Descriptor())
+	info.Set(mt.Descriptor().Fields().ByName("nationality"), protoreflect.ValueOfString("Swedish"))
+	r, err := NewRow([]string{"SingerInfo", "SingerGenre", "Unknown"}, []interface{}{
+		info,
+		et.New(1),
+		GenericColumnValue{Type: protoType(typeCodeEnum, "examples.spanner.music.Unknown"), Value: stringProto("3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := r.ToMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m["SingerInfo"].(proto.Message); !ok || !proto.Equal(got, info) {
+		t.Errorf("got SingerInfo %v, want %v", m["SingerInfo"], info)
+	}
+	if got, ok := m["SingerGenre"].(protoreflect.Enum); !ok || got.Number() != 1 {
+		t.Errorf("got SingerGenre %v, want JAZZ", m["SingerGenre"])
+	}
+	if got := m["Unknown"]; got != int64(3) {
+		t.Errorf("got Unknown %v, want 3", got)
+	}
+	b, err := r.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"SingerInfo":{"nationality":"Swedish"},"SingerGenre":"JAZZ","Unknown":3}`; got != want {
+		t.Errorf("got JSON %s, want %s", got, want)
+	}
+
+	if err := RegisterProtoDescriptors(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:       proto.String("invalid.proto"),
+		Dependency: []string{"missing.proto"},
+	}}}); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("got error %v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestClient_ProtoParams(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	stmt := NewStatement("UPDATE Singers SET SingerInfo = @info, SingerGenre = @genre WHERE TRUE")
+	stmt.Params["info"] = durationpb.New(time.Second)
+	stmt.Params["genre"] = sppb.TypeCode_STRING
+	server.TestSpanner.PutStatementResult(stmt.SQL, &StatementResult{Type: StatementResultUpdateCount, UpdateCount: 1})
+	if _, err := client.ReadWriteTransaction(context.Background(), func(ctx context.Context, tx *ReadWriteTransaction) error {
+		_, err := tx.Update(ctx, stmt)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range drainRequestsFromServer(server.TestSpanner) {
+		if req, ok := req.(*sppb.ExecuteSqlRequest); ok {
+			if got := protoTypeFqn(req.ParamTypes["info"]); req.ParamTypes["info"].Code != typeCodeProto || got != "google.protobuf.Duration" {
+				t.Errorf("got info type %v with proto type %q, want PROTO google.protobuf.Duration", req.ParamTypes["info"].Code, got)
+			}
+			if got := protoTypeFqn(req.ParamTypes["genre"]); req.ParamTypes["genre"].Code != typeCodeEnum || got != "google.spanner.v1.TypeCode" {
+				t.Errorf("got genre type %v with proto type %q, want ENUM google.spanner.v1.TypeCode", req.ParamTypes["genre"].Code, got)
+			}
+			return
+		}
+	}
+	t.Fatal("no ExecuteSqlRequest found")
+}
@@ -77,6 +77,25 @@ func jsonType() *sppb.Type {
 	return &sppb.Type{Code: sppb.TypeCode_JSON}
 }
 
+// typeAnnotationPGJsonB and typeAnnotationPGOid are the type annotation codes
+// of the PostgreSQL JSONB and OID types.
+const (
+	typeAnnotationPGJsonB = sppb.TypeAnnotationCode(3)
+	typeAnnotationPGOid   = sppb.TypeAnnotationCode(4)
+)
+
+func pgNumericType() *sppb.Type {
+	return &sppb.Type{Code: sppb.TypeCode_NUMERIC, TypeAnnotation: sppb.TypeAnnotationCode_PG_NUMERIC}
+}
+
+func pgJsonbType() *sppb.Type {
+	return &sppb.Type{Code: sppb.TypeCode_JSON, TypeAnnotation: typeAnnotationPGJsonB}
+}
+
+func pgOidType() *sppb.Type {
+	return &sppb.Type{Code: sppb.TypeCode_INT64, TypeAnnotation: typeAnnotationPGOid}
+}
+
 func bytesProto(b []byte) *proto3.Value {
 	return &proto3.Value{Kind: &proto3.Value_StringValue{StringValue: base64.StdEncoding.EncodeToString(b)}}
 }
@@ -79,16 +79,33 @@ func (r *spannerRetryer) Retry(err error) (time.Duration, bool) {
 	return delay, true
 }
 
+// TransactionRetryInfo describes a read/write transaction attempt that was
+// aborted by Cloud Spanner and that will be retried.
+type TransactionRetryInfo struct {
+	// Attempt is the number of the aborted attempt. The first attempt is 1.
+	Attempt int
+	// Err is the Aborted error that was returned for the attempt. It
+	// contains the reason that Cloud Spanner gave for aborting the
+	// transaction.
+	Err error
+	// Delay is the time that the client waits before the next attempt.
+	Delay time.Duration
+}
+
 // runWithRetryOnAbortedOrSessionNotFound executes the given function and
 // retries it if it returns an Aborted or Session not found error. The retry
 // is delayed if the error was Aborted. The delay between retries is the delay
-// returned by Cloud Spanner, or if none is returned, the calculated delay with
-// a minimum of 10ms and maximum of 32s. There is no delay before the retry if
-// the error was Session not found.
-func runWithRetryOnAbortedOrSessionNotFound(ctx context.Context, f func(context.Context) error) error {
-	retryer := onCodes(DefaultRetryBackoff, codes.Aborted)
+// returned by Cloud Spanner, or if none is returned, the delay calculated with
+// bo, or with DefaultRetryBackoff if bo is the zero value. There is no delay
+// before the retry if the error was Session not found. onRetry is called, if
+// it is not nil, before each delay after an Aborted error.
+func runWithRetryOnAbortedOrSessionNotFound(ctx context.Context, bo gax.Backoff, onRetry func(TransactionRetryInfo), f func(context.Context) error) error {
+	if bo == (gax.Backoff{}) {
+		bo = DefaultRetryBackoff
+	}
+	retryer := onCodes(bo, codes.Aborted)
 	funcWithRetry := func(ctx context.Context) error {
-		for {
+		for attempt := 1; ; attempt++ {
 			err := f(ctx)
 			if err == nil {
 				return nil
@@ -119,6 +136,9 @@ func runWithRetryOnAbortedOrSessionNotFound(ctx context.Context, f func(context.
 			if !shouldRetry {
 				return err
 			}
+			if onRetry != nil {
+				onRetry(TransactionRetryInfo{Attempt: attempt, Err: err, Delay: delay})
+			}
 			trace.TracePrintf(ctx, nil, "Backing off after ABORTED for %s, then retrying", delay)
 			if err := gax.Sleep(ctx, delay); err != nil {
 				return err
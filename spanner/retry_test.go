@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	. "cloud.google.com/go/spanner/internal/testutil"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/googleapis/gax-go/v2"
 	edpb "google.golang.org/genproto/googleapis/rpc/errdetails"
@@ -88,3 +89,94 @@ func TestRetryerRespectsServerDelay(t *testing.T) {
 		t.Fatalf("Retry delay mismatch:\ngot: %v\nwant: %v", maxSeenDelay, serverDelay)
 	}
 }
+
+func TestRunWithRetryOnAbortedOrSessionNotFound_onRetry(t *testing.T) {
+	t.Parallel()
+	serverDelay := 5 * time.Millisecond
+	s, err := status.New(codes.Aborted, "transaction was aborted").WithDetails(&edpb.RetryInfo{
+		RetryDelay: ptypes.DurationProto(serverDelay),
+	})
+	if err != nil {
+		t.Fatalf("Error setting retry details: %v", err)
+	}
+	errs := []error{
+		ToSpannerError(status.Error(codes.Aborted, "transaction was aborted")),
+		newSessionNotFoundError("projects/p/instances/i/databases/d/sessions/s"),
+		ToSpannerError(s.Err()),
+	}
+	var calls int
+	var infos []TransactionRetryInfo
+	bo := gax.Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond, Multiplier: 1}
+	err = runWithRetryOnAbortedOrSessionNotFound(context.Background(), bo, func(info TransactionRetryInfo) {
+		infos = append(infos, info)
+	}, func(ctx context.Context) error {
+		calls++
+		if calls > len(errs) {
+			return nil
+		}
+		return errs[calls-1]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("got %d attempts, want 4", calls)
+	}
+	// Session not found errors are retried without calling onRetry.
+	if len(infos) != 2 {
+		t.Fatalf("got %d calls of onRetry, want 2", len(infos))
+	}
+	if infos[0].Attempt != 1 || ErrCode(infos[0].Err) != codes.Aborted || infos[0].Delay > bo.Max {
+		t.Errorf("got first retry %+v, want attempt 1 with Aborted error and delay at most %v", infos[0], bo.Max)
+	}
+	// The delay that is returned by Cloud Spanner takes precedence.
+	if infos[1].Attempt != 3 || ErrCode(infos[1].Err) != codes.Aborted || infos[1].Delay != serverDelay {
+		t.Errorf("got second retry %+v, want attempt 3 with Aborted error and delay %v", infos[1], serverDelay)
+	}
+}
+
+func TestClient_ReadWriteTransaction_OnRetry(t *testing.T) {
+	t.Parallel()
+
+	var clientRetries, txRetries []TransactionRetryInfo
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{
+		TransactionOptions: TransactionOptions{
+			RetryBackoff: gax.Backoff{Initial: time.Millisecond, Max: time.Millisecond},
+			OnRetry:      func(info TransactionRetryInfo) { clientRetries = append(clientRetries, info) },
+		},
+	})
+	defer teardown()
+
+	aborted := SimulatedExecutionTime{
+		Errors: []error{status.Error(codes.Aborted, "Aborted"), status.Error(codes.Aborted, "Aborted")},
+	}
+	ctx := context.Background()
+	server.TestSpanner.PutExecutionTime(MethodCommitTransaction, aborted)
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(clientRetries) != 2 || clientRetries[0].Attempt != 1 || clientRetries[1].Attempt != 2 {
+		t.Errorf("got retries %+v, want 2 retries", clientRetries)
+	}
+
+	// The options of a transaction take precedence.
+	clientRetries = nil
+	server.TestSpanner.PutExecutionTime(MethodCommitTransaction, aborted)
+	if _, err := client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return nil
+	}, TransactionOptions{
+		OnRetry: func(info TransactionRetryInfo) { txRetries = append(txRetries, info) },
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(clientRetries) != 0 || len(txRetries) != 2 {
+		t.Errorf("got %d client retries and %d transaction retries, want 0 and 2", len(clientRetries), len(txRetries))
+	}
+	for _, info := range txRetries {
+		if info.Delay > time.Millisecond {
+			t.Errorf("got delay %v, want at most %v from the backoff of the client", info.Delay, time.Millisecond)
+		}
+	}
+}
@@ -17,12 +17,21 @@ limitations under the License.
 package spanner
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"time"
 
+	"cloud.google.com/go/civil"
 	proto3 "github.com/golang/protobuf/ptypes/struct"
 	sppb "google.golang.org/genproto/googleapis/spanner/v1"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // A Row is a view of a row of data returned by a Cloud Spanner read.
@@ -67,6 +76,10 @@ import (
 //	*[]*some_go_struct, *[]NullRow - STRUCT ARRAY
 //	*NullJSON - JSON
 //	*[]NullJSON - JSON ARRAY
+//	proto.Message(not NULL), **some_proto_message, *NullProtoMessage - PROTO
+//	*[]*some_proto_message - PROTO ARRAY
+//	*some_proto_enum(not NULL), **some_proto_enum, *NullProtoEnum - ENUM
+//	*[]some_proto_enum, *[]*some_proto_enum - ENUM ARRAY
 //	*GenericColumnValue - any Cloud Spanner type
 //
 // For TIMESTAMP columns, the returned time.Time object will be in UTC.
@@ -362,3 +375,268 @@ func (r *Row) ToStructLenient(p interface{}) error {
 		true,
 	)
 }
+
+// ToMap returns the columns of the row as a map from column name to value.
+// It can be used to decode rows whose columns are not known in advance. The
+// values have the following Go types, depending on the type of the column:
+//
+//	BOOL: bool
+//	INT64: int64
+//	FLOAT64: float64
+//	NUMERIC: *big.Rat, or PGNumeric for PostgreSQL NUMERIC
+//	STRING: string
+//	BYTES: []byte
+//	JSON: the decoded JSON value, as produced by json.Unmarshal into an
+//	      interface{}
+//	DATE: civil.Date
+//	TIMESTAMP: time.Time
+//	PROTO: proto.Message, or []byte if the proto type is not known
+//	ENUM: protoreflect.Enum, or int64 if the proto type is not known
+//	ARRAY: []interface{}, with elements of the types above
+//	STRUCT: map[string]interface{}, with values of the types above
+//
+// NULL values are nil. ToMap returns an error if a column or a field of a
+// STRUCT is unnamed, or if a name is used by more than one column or by more
+// than one field of the same STRUCT.
+func (r *Row) ToMap() (map[string]interface{}, error) {
+	if len(r.vals) != len(r.fields) {
+		return nil, errFieldsMismatchVals(r)
+	}
+	v, err := rowValue(&proto3.Value{Kind: &proto3.Value_ListValue{ListValue: &proto3.ListValue{Values: r.vals}}},
+		&sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: r.fields}}, false)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// MarshalJSON implements json.Marshaler. It encodes the row as a JSON object
+// with a member for each column, in the order of the columns. The values are
+// encoded as the JSON encoding of the values of ToMap, with the following
+// exceptions that preserve the values exactly:
+//
+//	NUMERIC: a JSON number with all the digits of the value, or the
+//	         string "NaN"
+//	FLOAT64: a JSON number, or one of the strings "NaN", "Infinity" and
+//	         "-Infinity"
+//	JSON: the JSON value itself, not a string that contains it
+//	STRUCT: a JSON object with the fields in the order of the STRUCT type
+//
+// BYTES values are encoded as base64 strings, DATE values as strings of the
+// form "YYYY-MM-DD", and TIMESTAMP values as RFC 3339 strings. MarshalJSON
+// returns an error in the same cases as ToMap.
+func (r *Row) MarshalJSON() ([]byte, error) {
+	if len(r.vals) != len(r.fields) {
+		return nil, errFieldsMismatchVals(r)
+	}
+	v, err := rowValue(&proto3.Value{Kind: &proto3.Value_ListValue{ListValue: &proto3.ListValue{Values: r.vals}}},
+		&sppb.Type{Code: sppb.TypeCode_STRUCT, StructType: &sppb.StructType{Fields: r.fields}}, true)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// errUnsupportedRowValueType returns error for a value of a Cloud Spanner type
+// that ToMap and MarshalJSON cannot decode.
+func errUnsupportedRowValueType(t *sppb.Type) error {
+	return spannerErrorf(codes.FailedPrecondition, "unsupported Cloud Spanner type %v", t)
+}
+
+// rowValue decodes v of type t into the Go value that is returned by ToMap, or
+// into a value with the JSON encoding of MarshalJSON if forJSON is true.
+func rowValue(v *proto3.Value, t *sppb.Type, forJSON bool) (interface{}, error) {
+	if v == nil {
+		return nil, errNilSrc()
+	}
+	if t == nil {
+		return nil, errNilSpannerType()
+	}
+	if _, isNull := v.Kind.(*proto3.Value_NullValue); isNull {
+		return nil, nil
+	}
+	switch t.Code {
+	case sppb.TypeCode_BOOL:
+		return getBoolValue(v)
+	case sppb.TypeCode_INT64:
+		var x int64
+		err := decodeValue(v, t, &x)
+		return x, err
+	case sppb.TypeCode_FLOAT64:
+		x, err := getFloat64Value(v)
+		if err != nil {
+			return nil, err
+		}
+		if forJSON && (math.IsNaN(x) || math.IsInf(x, 0)) {
+			return v.GetStringValue(), nil
+		}
+		return x, nil
+	case sppb.TypeCode_NUMERIC:
+		if t.TypeAnnotation == sppb.TypeAnnotationCode_PG_NUMERIC {
+			x, err := getStringValue(v)
+			if err != nil {
+				return nil, err
+			}
+			if !forJSON {
+				return PGNumeric{x, true}, nil
+			}
+			if x == "NaN" {
+				return x, nil
+			}
+			return json.Number(x), nil
+		}
+		var x big.Rat
+		if err := decodeValue(v, t, &x); err != nil {
+			return nil, err
+		}
+		if forJSON {
+			return json.Number(v.GetStringValue()), nil
+		}
+		return &x, nil
+	case sppb.TypeCode_STRING:
+		return getStringValue(v)
+	case sppb.TypeCode_BYTES:
+		var x []byte
+		err := decodeValue(v, t, &x)
+		return x, err
+	case sppb.TypeCode_JSON:
+		if forJSON {
+			x, err := getStringValue(v)
+			if err != nil {
+				return nil, err
+			}
+			if !json.Valid([]byte(x)) {
+				return nil, spannerErrorf(codes.FailedPrecondition, "invalid JSON value %q", x)
+			}
+			return json.RawMessage(x), nil
+		}
+		var x NullJSON
+		err := decodeValue(v, t, &x)
+		return x.Value, err
+	case typeCodeProto:
+		var x []byte
+		if err := decodeValue(v, t, &x); err != nil {
+			return nil, err
+		}
+		mt := findProtoMessageType(protoTypeFqn(t))
+		if mt == nil {
+			// The raw bytes are returned for messages of unknown types.
+			return x, nil
+		}
+		m := mt.New().Interface()
+		if err := proto.Unmarshal(x, m); err != nil {
+			return nil, errBadEncoding(v, err)
+		}
+		if forJSON {
+			b, err := protojson.Marshal(m)
+			if err != nil {
+				return nil, ToSpannerError(err)
+			}
+			return json.RawMessage(b), nil
+		}
+		return m, nil
+	case typeCodeEnum:
+		var x int64
+		if err := decodeValue(v, t, &x); err != nil {
+			return nil, err
+		}
+		et := findProtoEnumType(protoTypeFqn(t))
+		if et == nil {
+			// The number is returned for enums of unknown types.
+			return x, nil
+		}
+		e := et.New(protoreflect.EnumNumber(x))
+		if forJSON {
+			return protoEnumName(e), nil
+		}
+		return e, nil
+	case sppb.TypeCode_DATE:
+		var x civil.Date
+		err := decodeValue(v, t, &x)
+		return x, err
+	case sppb.TypeCode_TIMESTAMP:
+		var x time.Time
+		err := decodeValue(v, t, &x)
+		return x, err
+	case sppb.TypeCode_ARRAY:
+		if t.ArrayElementType == nil {
+			return nil, errNilArrElemType(t)
+		}
+		x, err := getListValue(v)
+		if err != nil {
+			return nil, err
+		}
+		a := make([]interface{}, len(x.Values))
+		for i, e := range x.Values {
+			if a[i], err = rowValue(e, t.ArrayElementType, forJSON); err != nil {
+				return nil, errDecodeArrayElement(i, e, t.ArrayElementType.Code.String(), err)
+			}
+		}
+		return a, nil
+	case sppb.TypeCode_STRUCT:
+		if t.StructType == nil {
+			return nil, errNilSpannerStructType()
+		}
+		x, err := getListValue(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(x.Values) != len(t.StructType.Fields) {
+			return nil, spannerErrorf(codes.FailedPrecondition, "%d values for the %d fields of Cloud Spanner STRUCT %+v", len(x.Values), len(t.StructType.Fields), t.StructType)
+		}
+		o := jsonObject{names: make([]string, len(x.Values)), values: make([]interface{}, len(x.Values))}
+		seen := make(map[string]bool)
+		for i, f := range t.StructType.Fields {
+			if f.Name == "" {
+				return nil, errUnnamedField(t.StructType, i)
+			}
+			if seen[f.Name] {
+				return nil, errDupSpannerField(f.Name, t.StructType)
+			}
+			seen[f.Name] = true
+			o.names[i] = f.Name
+			if o.values[i], err = rowValue(x.Values[i], f.Type, forJSON); err != nil {
+				return nil, errDecodeStructField(t.StructType, f.Name, err)
+			}
+		}
+		if forJSON {
+			return o, nil
+		}
+		m := make(map[string]interface{}, len(o.names))
+		for i, name := range o.names {
+			m[name] = o.values[i]
+		}
+		return m, nil
+	}
+	return nil, errUnsupportedRowValueType(t)
+}
+
+// jsonObject is a JSON object whose members are encoded in order.
+type jsonObject struct {
+	names  []string
+	values []interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, name := range o.names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
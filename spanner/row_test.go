@@ -18,7 +18,10 @@ package spanner
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -1967,3 +1970,105 @@ func BenchmarkColumn(b *testing.B) {
 		}
 	}
 }
+
+func TestToMapAndMarshalJSON(t *testing.T) {
+	ts := time.Date(2022, 3, 4, 5, 6, 7, 8, time.UTC)
+	d := civil.Date{Year: 2022, Month: 3, Day: 4}
+	r := Row{
+		[]*sppb.StructType_Field{
+			{Name: "Bool", Type: boolType()},
+			{Name: "Int", Type: intType()},
+			{Name: "Float", Type: floatType()},
+			{Name: "NaN", Type: floatType()},
+			{Name: "Numeric", Type: numericType()},
+			{Name: "PGNumeric", Type: pgNumericType()},
+			{Name: "String", Type: stringType()},
+			{Name: "Bytes", Type: bytesType()},
+			{Name: "JSON", Type: jsonType()},
+			{Name: "Date", Type: dateType()},
+			{Name: "Timestamp", Type: timeType()},
+			{Name: "Null", Type: stringType()},
+			{Name: "Array", Type: listType(intType())},
+			{Name: "Struct", Type: structType(mkField("B", stringType()), mkField("A", listType(floatType())))},
+		},
+		[]*proto3.Value{
+			boolProto(true),
+			intProto(9007199254740993),
+			floatProto(1.5),
+			stringProto("NaN"),
+			stringProto("123456789012345678901234567890.123456789"),
+			stringProto("NaN"),
+			stringProto("<value>"),
+			bytesProto([]byte("bytes")),
+			stringProto(`{"a":[1,2],"b":null}`),
+			dateProto(d),
+			timeProto(ts),
+			nullProto(),
+			listProto(intProto(1), nullProto(), intProto(3)),
+			listProto(stringProto("b"), listProto(floatProto(2), stringProto("Infinity"))),
+		},
+	}
+
+	m, err := r.ToMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	numeric, _ := (&big.Rat{}).SetString("123456789012345678901234567890.123456789")
+	want := map[string]interface{}{
+		"Bool":      true,
+		"Int":       int64(9007199254740993),
+		"Float":     1.5,
+		"Numeric":   numeric,
+		"PGNumeric": PGNumeric{"NaN", true},
+		"String":    "<value>",
+		"Bytes":     []byte("bytes"),
+		"JSON":      map[string]interface{}{"a": []interface{}{float64(1), float64(2)}, "b": nil},
+		"Date":      d,
+		"Timestamp": ts,
+		"Null":      nil,
+		"Array":     []interface{}{int64(1), nil, int64(3)},
+		"Struct":    map[string]interface{}{"B": "b", "A": []interface{}{float64(2), math.Inf(1)}},
+	}
+	if nan, ok := m["NaN"].(float64); !ok || !math.IsNaN(nan) {
+		t.Errorf("got NaN column %v, want NaN", m["NaN"])
+	}
+	delete(m, "NaN")
+	if !testEqual(m, want) {
+		t.Errorf("ToMap: got %v, want %v", m, want)
+	}
+
+	b, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"Bool":true,"Int":9007199254740993,"Float":1.5,"NaN":"NaN",` +
+		`"Numeric":123456789012345678901234567890.123456789,"PGNumeric":"NaN",` +
+		`"String":"\u003cvalue\u003e","Bytes":"Ynl0ZXM=","JSON":{"a":[1,2],"b":null},` +
+		`"Date":"2022-03-04","Timestamp":"2022-03-04T05:06:07.000000008Z","Null":null,` +
+		`"Array":[1,null,3],"Struct":{"B":"b","A":[2,"Infinity"]}}`
+	if string(b) != wantJSON {
+		t.Errorf("MarshalJSON:\ngot  %s\nwant %s", b, wantJSON)
+	}
+}
+
+func TestToMapAndMarshalJSON_errors(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		fields []*sppb.StructType_Field
+		vals   []*proto3.Value
+	}{
+		{"unnamed column", []*sppb.StructType_Field{{Name: "", Type: intType()}}, []*proto3.Value{intProto(1)}},
+		{"duplicate column", []*sppb.StructType_Field{{Name: "A", Type: intType()}, {Name: "A", Type: intType()}}, []*proto3.Value{intProto(1), intProto(2)}},
+		{"unnamed struct field", []*sppb.StructType_Field{{Name: "S", Type: structType(mkField("", intType()))}}, []*proto3.Value{listProto(intProto(1))}},
+		{"invalid value", []*sppb.StructType_Field{{Name: "A", Type: intType()}}, []*proto3.Value{stringProto("one")}},
+		{"mismatched values", []*sppb.StructType_Field{{Name: "A", Type: intType()}}, nil},
+	} {
+		r := Row{test.fields, test.vals}
+		if _, err := r.ToMap(); err == nil {
+			t.Errorf("%s: ToMap: got nil error, want error", test.desc)
+		}
+		if _, err := r.MarshalJSON(); err == nil {
+			t.Errorf("%s: MarshalJSON: got nil error, want error", test.desc)
+		}
+	}
+}
@@ -362,7 +362,11 @@ func (s *session) prepareForWrite(ctx context.Context) error {
 	if s.isWritePrepared() {
 		return nil
 	}
-	tx, err := beginTransaction(contextWithOutgoingMetadata(ctx, s.md), s.getID(), s.client)
+	md := s.md
+	if s.pool != nil {
+		md = withRouteToLeader(md, s.pool.sc.routeToLeader)
+	}
+	tx, err := beginTransaction(contextWithOutgoingMetadata(ctx, md), s.getID(), s.client)
 	// Session not found should cause the session to be removed from the pool.
 	if isSessionNotFoundError(err) {
 		s.pool.remove(s, false)
@@ -461,6 +465,10 @@ type SessionPoolConfig struct {
 
 	// sessionLabels for the sessions created in the session pool.
 	sessionLabels map[string]string
+
+	// otm records the OpenTelemetry metrics of the session pool. It is nil
+	// if OpenTelemetry metrics have not been enabled for the client.
+	otm *openTelemetryMetrics
 }
 
 // DefaultSessionPoolConfig is the default configuration for the session pool
@@ -902,6 +910,7 @@ func (p *sessionPool) isHealthy(s *session) bool {
 // for read operations.
 func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 	trace.TracePrintf(ctx, nil, "Acquiring a read-only session")
+	start := time.Now()
 	for {
 		var s *session
 
@@ -937,6 +946,7 @@ func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 				continue
 			}
 			p.incNumInUse(ctx)
+			p.otm.recordCheckoutLatency(ctx, start, false)
 			return p.newSessionHandle(s), nil
 		}
 
@@ -958,6 +968,7 @@ func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 		case <-ctx.Done():
 			trace.TracePrintf(ctx, nil, "Context done waiting for session")
 			p.recordStat(ctx, GetSessionTimeoutsCount, 1)
+			p.otm.recordGetSessionTimeout(ctx)
 			p.mu.Lock()
 			p.numReadWaiters--
 			p.mu.Unlock()
@@ -981,6 +992,7 @@ func (p *sessionPool) take(ctx context.Context) (*sessionHandle, error) {
 // returned should be used for read write transactions.
 func (p *sessionPool) takeWriteSession(ctx context.Context) (*sessionHandle, error) {
 	trace.TracePrintf(ctx, nil, "Acquiring a read-write session")
+	start := time.Now()
 	for {
 		var (
 			s   *session
@@ -1035,6 +1047,7 @@ func (p *sessionPool) takeWriteSession(ctx context.Context) (*sessionHandle, err
 			case <-ctx.Done():
 				trace.TracePrintf(ctx, nil, "Context done waiting for session")
 				p.recordStat(ctx, GetSessionTimeoutsCount, 1)
+				p.otm.recordGetSessionTimeout(ctx)
 				p.mu.Lock()
 				p.numWriteWaiters--
 				p.mu.Unlock()
@@ -1069,6 +1082,7 @@ func (p *sessionPool) takeWriteSession(ctx context.Context) (*sessionHandle, err
 			}
 		}
 		p.incNumInUse(ctx)
+		p.otm.recordCheckoutLatency(ctx, start, true)
 		return p.newSessionHandle(s), nil
 	}
 }
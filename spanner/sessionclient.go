@@ -91,6 +91,8 @@ type sessionClient struct {
 	database      string
 	id            string
 	sessionLabels map[string]string
+	databaseRole  string
+	routeToLeader bool
 	md            metadata.MD
 	batchTimeout  time.Duration
 	logger        *log.Logger
@@ -111,6 +113,13 @@ func newSessionClient(connPool gtransport.ConnPool, database string, sessionLabe
 	}
 }
 
+// sessionTemplate returns the template for the sessions that are created by
+// the session client, with the given labels and the database role of the
+// client.
+func (sc *sessionClient) sessionTemplate(labels map[string]string) *sppb.Session {
+	return &sppb.Session{Labels: labels, CreatorRole: sc.databaseRole}
+}
+
 func (sc *sessionClient) close() error {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
@@ -131,11 +140,11 @@ func (sc *sessionClient) createSession(ctx context.Context) (*session, error) {
 	if err != nil {
 		return nil, err
 	}
-	ctx = contextWithOutgoingMetadata(ctx, sc.md)
+	ctx = contextWithOutgoingMetadata(ctx, withRouteToLeader(sc.md, sc.routeToLeader))
 	var md metadata.MD
 	sid, err := client.CreateSession(ctx, &sppb.CreateSessionRequest{
 		Database: sc.database,
-		Session:  &sppb.Session{Labels: sc.sessionLabels},
+		Session:  sc.sessionTemplate(sc.sessionLabels),
 	}, gax.WithGRPCOptions(grpc.Header(&md)))
 
 	if getGFELatencyMetricsFlag() && md != nil {
@@ -232,7 +241,7 @@ func (sc *sessionClient) batchCreateSessions(createSessionCount int32, distribut
 func (sc *sessionClient) executeBatchCreateSessions(client *vkit.Client, createCount int32, labels map[string]string, md metadata.MD, consumer sessionConsumer) {
 	ctx, cancel := context.WithTimeout(context.Background(), sc.batchTimeout)
 	defer cancel()
-	ctx = contextWithOutgoingMetadata(ctx, sc.md)
+	ctx = contextWithOutgoingMetadata(ctx, withRouteToLeader(sc.md, sc.routeToLeader))
 
 	ctx = trace.StartSpan(ctx, "cloud.google.com/go/spanner.BatchCreateSessions")
 	defer func() { trace.EndSpan(ctx, nil) }()
@@ -257,7 +266,7 @@ func (sc *sessionClient) executeBatchCreateSessions(client *vkit.Client, createC
 		response, err := client.BatchCreateSessions(ctx, &sppb.BatchCreateSessionsRequest{
 			SessionCount:    remainingCreateCount,
 			Database:        sc.database,
-			SessionTemplate: &sppb.Session{Labels: labels},
+			SessionTemplate: sc.sessionTemplate(labels),
 		}, gax.WithGRPCOptions(grpc.Header(&mdForGFELatency)))
 
 		if getGFELatencyMetricsFlag() && mdForGFELatency != nil {
@@ -26,6 +26,7 @@ import (
 	vkit "cloud.google.com/go/spanner/apiv1"
 	. "cloud.google.com/go/spanner/internal/testutil"
 	gax "github.com/googleapis/gax-go/v2"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -148,6 +149,44 @@ func TestCreateAndCloseSession(t *testing.T) {
 	}
 }
 
+func TestCreateSessions_DatabaseRole(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{
+		SessionPoolConfig: SessionPoolConfig{
+			MinOpened: 0,
+			MaxOpened: 100,
+		},
+		DatabaseRole: "reader",
+	})
+	defer teardown()
+
+	s, err := client.sc.createSession(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.delete(context.Background())
+	consumer := newTestConsumer(1)
+	client.sc.batchCreateSessions(1, true, consumer)
+	<-consumer.receivedAll
+	for _, s := range consumer.sessions {
+		s.delete(context.Background())
+	}
+
+	var roles []string
+	for _, req := range drainRequestsFromServer(server.TestSpanner) {
+		switch req := req.(type) {
+		case *sppb.CreateSessionRequest:
+			roles = append(roles, req.Session.GetCreatorRole())
+		case *sppb.BatchCreateSessionsRequest:
+			roles = append(roles, req.SessionTemplate.GetCreatorRole())
+		}
+	}
+	if want := []string{"reader", "reader"}; !testEqual(roles, want) {
+		t.Fatalf("creator roles mismatch\ngot: %v\nwant: %v", roles, want)
+	}
+}
+
 func TestBatchCreateAndCloseSession(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,429 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package spanneremulator manages the lifecycle of the Cloud Spanner emulator
+for integration tests.
+
+Unlike the in-memory fake in cloud.google.com/go/spanner/spannertest, the
+emulator is the official emulator of Cloud Spanner, which supports the full
+SQL dialects. See https://github.com/GoogleCloudPlatform/cloud-spanner-emulator.
+
+Start starts an emulator, creates an instance and a database with the given
+DDL statements, and returns an Emulator with a client that is connected to
+the database:
+
+	emu, err := spanneremulator.Start(ctx, spanneremulator.Config{
+		DDL: []string{"CREATE TABLE Singers (SingerId INT64, Name STRING(MAX)) PRIMARY KEY (SingerId)"},
+	})
+	...
+	defer emu.Stop(ctx)
+	_, err = emu.Client.Apply(ctx, ...)
+
+In tests, Setup does the same and registers the cleanup with the test. It
+skips the test if no emulator is available:
+
+	func TestSingers(t *testing.T) {
+		client := spanneremulator.Setup(t, spanneremulator.Config{DDL: ddl}).Client
+		...
+	}
+
+The emulator is started in one of the following ways, in order of
+preference:
+
+ 1. If SPANNER_EMULATOR_HOST is set and Config.Binary and Config.Image are
+    empty, the emulator that runs at that address is used. Each Emulator
+    creates an instance with a unique ID, so that tests can share the
+    emulator.
+ 2. If Config.Binary is set or the emulator_main binary of the emulator is
+    found in the PATH, the binary is started.
+ 3. If docker is found in the PATH, the container image in Config.Image,
+    or DefaultImage, is started.
+*/
+package spanneremulator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"google.golang.org/api/option"
+	"google.golang.org/api/option/internaloption"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+	instancepb "google.golang.org/genproto/googleapis/spanner/admin/instance/v1"
+	"google.golang.org/grpc"
+)
+
+const (
+	// DefaultImage is the container image of the emulator that is started if
+	// Config.Image is empty.
+	DefaultImage = "gcr.io/cloud-spanner-emulator/emulator"
+
+	// DefaultStartTimeout is the time that Start waits for the emulator to
+	// accept requests if Config.StartTimeout is zero.
+	DefaultStartTimeout = time.Minute
+
+	// binaryName is the name of the gRPC server binary of the emulator.
+	binaryName = "emulator_main"
+	// containerPort is the gRPC port of the emulator in its container.
+	containerPort = 9010
+	// instanceConfig is the only instance configuration of the emulator.
+	instanceConfig = "emulator-config"
+)
+
+// ErrNoEmulator is returned by Start if SPANNER_EMULATOR_HOST is not set and
+// neither the emulator binary nor docker is found.
+var ErrNoEmulator = errors.New("spanneremulator: no emulator found; set SPANNER_EMULATOR_HOST, or install the emulator_main binary or docker")
+
+// Config configures the emulator, and the instance and database that are
+// created in it.
+type Config struct {
+	// Binary is the path of the emulator_main binary of the emulator. If it is
+	// empty, the binary is looked up in the PATH.
+	Binary string
+
+	// Image is the container image of the emulator that is run with docker
+	// if no binary is found. If it is empty, DefaultImage is used.
+	Image string
+
+	// ProjectID, InstanceID and DatabaseID are the IDs of the project,
+	// instance and database. Unique IDs are generated for the instance and
+	// the database if they are empty. "test-project" is used if ProjectID is
+	// empty.
+	ProjectID  string
+	InstanceID string
+	DatabaseID string
+
+	// DatabaseDialect is the SQL dialect of the database. The GoogleSQL
+	// dialect is used if it is unspecified.
+	DatabaseDialect adminpb.DatabaseDialect
+
+	// DDL are the statements that create the schema of the database.
+	DDL []string
+
+	// ClientConfig is the configuration of Emulator.Client.
+	ClientConfig spanner.ClientConfig
+
+	// StartTimeout is the time that Start waits for the emulator to accept
+	// requests. If it is zero, DefaultStartTimeout is used.
+	StartTimeout time.Duration
+
+	// Output receives the standard output and error of the emulator process
+	// or container, if it is not nil.
+	Output io.Writer
+}
+
+// Emulator is a running emulator with an instance and a database.
+type Emulator struct {
+	// Addr is the address of the gRPC endpoint of the emulator.
+	Addr string
+
+	// DatabaseName is the full name of the database, e.g.
+	// "projects/test-project/instances/test-instance/databases/test-db".
+	DatabaseName string
+
+	// Client is a client that is connected to the database.
+	Client *spanner.Client
+
+	// InstanceAdmin and DatabaseAdmin are admin clients that are connected
+	// to the emulator.
+	InstanceAdmin *instance.InstanceAdminClient
+	DatabaseAdmin *database.DatabaseAdminClient
+
+	instanceName string
+	// cmd is the emulator process if a binary was started.
+	cmd *exec.Cmd
+	// containerID is the ID of the emulator container if one was started.
+	containerID string
+}
+
+// Start starts an emulator, creates an instance and a database in it, and
+// connects a client to the database. Stop must be called to stop the
+// emulator and to release the resources of the Emulator.
+func Start(ctx context.Context, cfg Config) (e *Emulator, err error) {
+	if cfg.ProjectID == "" {
+		cfg.ProjectID = "test-project"
+	}
+	if cfg.InstanceID == "" {
+		cfg.InstanceID = "test-instance-" + randomSuffix()
+	}
+	if cfg.DatabaseID == "" {
+		cfg.DatabaseID = "test-db-" + randomSuffix()
+	}
+	if cfg.StartTimeout == 0 {
+		cfg.StartTimeout = DefaultStartTimeout
+	}
+
+	e = &Emulator{instanceName: fmt.Sprintf("projects/%s/instances/%s", cfg.ProjectID, cfg.InstanceID)}
+	e.DatabaseName = fmt.Sprintf("%s/databases/%s", e.instanceName, cfg.DatabaseID)
+	if err := e.start(cfg); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			e.Stop(ctx)
+		}
+	}()
+
+	opts := e.ClientOptions()
+	if e.InstanceAdmin, err = instance.NewInstanceAdminClient(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if e.DatabaseAdmin, err = database.NewDatabaseAdminClient(ctx, opts...); err != nil {
+		return nil, err
+	}
+	if err := e.createInstance(ctx, cfg); err != nil {
+		return nil, err
+	}
+	if err := e.createDatabase(ctx, cfg); err != nil {
+		return nil, err
+	}
+	if e.Client, err = spanner.NewClientWithConfig(ctx, e.DatabaseName, cfg.ClientConfig, opts...); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Setup starts an emulator like Start and registers a cleanup function with
+// tb that stops it. It skips the test if no emulator is available, and fails
+// the test if the emulator cannot be started.
+func Setup(tb testing.TB, cfg Config) *Emulator {
+	tb.Helper()
+	ctx := context.Background()
+	e, err := Start(ctx, cfg)
+	if err == ErrNoEmulator {
+		tb.Skip(err)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		if err := e.Stop(ctx); err != nil {
+			tb.Errorf("stopping the emulator: %v", err)
+		}
+	})
+	return e
+}
+
+// ClientOptions returns the options that connect a client to the emulator,
+// e.g. to create additional clients.
+func (e *Emulator) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(e.Addr),
+		option.WithGRPCDialOption(grpc.WithInsecure()),
+		option.WithoutAuthentication(),
+		internaloption.SkipDialSettingsValidation(),
+	}
+}
+
+// Stop closes the clients of the Emulator and stops the emulator. If the
+// emulator was not started by the Emulator, the database and the instance
+// are deleted instead.
+func (e *Emulator) Stop(ctx context.Context) error {
+	var errs []string
+	record := func(err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if e.Client != nil {
+		e.Client.Close()
+		e.Client = nil
+	}
+	if e.cmd == nil && e.containerID == "" && e.InstanceAdmin != nil {
+		// Deleting the instance deletes its databases.
+		record(e.InstanceAdmin.DeleteInstance(ctx, &instancepb.DeleteInstanceRequest{Name: e.instanceName}))
+	}
+	if e.DatabaseAdmin != nil {
+		record(e.DatabaseAdmin.Close())
+		e.DatabaseAdmin = nil
+	}
+	if e.InstanceAdmin != nil {
+		record(e.InstanceAdmin.Close())
+		e.InstanceAdmin = nil
+	}
+	if e.cmd != nil {
+		// The emulator shuts down gracefully on an interrupt.
+		if err := e.cmd.Process.Signal(os.Interrupt); err != nil {
+			e.cmd.Process.Kill()
+		}
+		e.cmd.Wait()
+		e.cmd = nil
+	}
+	if e.containerID != "" {
+		record(exec.Command("docker", "stop", e.containerID).Run())
+		e.containerID = ""
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("spanneremulator: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// start starts the emulator that is selected by cfg and sets e.Addr.
+func (e *Emulator) start(cfg Config) error {
+	if addr := os.Getenv("SPANNER_EMULATOR_HOST"); addr != "" && cfg.Binary == "" && cfg.Image == "" {
+		e.Addr = addr
+		return nil
+	}
+	binary := cfg.Binary
+	if binary == "" && cfg.Image == "" {
+		binary, _ = exec.LookPath(binaryName)
+	}
+	if binary == "" {
+		if _, err := exec.LookPath("docker"); err != nil {
+			return ErrNoEmulator
+		}
+	}
+	port, err := freePort()
+	if err != nil {
+		return err
+	}
+	e.Addr = fmt.Sprintf("localhost:%d", port)
+	if binary != "" {
+		e.cmd = exec.Command(binary, "--host_port", e.Addr)
+		e.cmd.Stdout = cfg.Output
+		e.cmd.Stderr = cfg.Output
+		if err := e.cmd.Start(); err != nil {
+			e.cmd = nil
+			return fmt.Errorf("spanneremulator: starting %s: %v", binary, err)
+		}
+		return nil
+	}
+
+	image := cfg.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	cmd := exec.Command("docker", "run", "--rm", "--detach", "--publish", fmt.Sprintf("127.0.0.1:%d:%d", port, containerPort), image)
+	cmd.Stderr = cfg.Output
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("spanneremulator: starting container %s: %v", image, err)
+	}
+	e.containerID = strings.TrimSpace(string(out))
+	if cfg.Output != nil {
+		logs := exec.Command("docker", "logs", "--follow", e.containerID)
+		logs.Stdout = cfg.Output
+		logs.Stderr = cfg.Output
+		logs.Start()
+	}
+	return nil
+}
+
+// createInstance creates the instance of the Emulator. It retries until the
+// emulator accepts requests or cfg.StartTimeout has elapsed.
+func (e *Emulator) createInstance(ctx context.Context, cfg Config) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.StartTimeout)
+	defer cancel()
+	for {
+		err := e.tryCreateInstance(ctx, cfg)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("spanneremulator: creating instance %s: %v", e.instanceName, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func (e *Emulator) tryCreateInstance(ctx context.Context, cfg Config) error {
+	op, err := e.InstanceAdmin.CreateInstance(ctx, &instancepb.CreateInstanceRequest{
+		Parent:     "projects/" + cfg.ProjectID,
+		InstanceId: cfg.InstanceID,
+		Instance: &instancepb.Instance{
+			Config:      fmt.Sprintf("projects/%s/instanceConfigs/%s", cfg.ProjectID, instanceConfig),
+			DisplayName: cfg.InstanceID,
+			NodeCount:   1,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = op.Wait(ctx)
+	return err
+}
+
+// createDatabase creates the database of the Emulator with the DDL in cfg.
+func (e *Emulator) createDatabase(ctx context.Context, cfg Config) error {
+	req := &adminpb.CreateDatabaseRequest{
+		Parent:          e.instanceName,
+		CreateStatement: "CREATE DATABASE `" + cfg.DatabaseID + "`",
+		DatabaseDialect: cfg.DatabaseDialect,
+		ExtraStatements: cfg.DDL,
+	}
+	postgres := cfg.DatabaseDialect == adminpb.DatabaseDialect_POSTGRESQL
+	if postgres {
+		// PostgreSQL databases must be created without extra statements.
+		req.CreateStatement = `CREATE DATABASE "` + cfg.DatabaseID + `"`
+		req.ExtraStatements = nil
+	}
+	op, err := e.DatabaseAdmin.CreateDatabase(ctx, req)
+	if err == nil {
+		_, err = op.Wait(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("spanneremulator: creating database %s: %v", e.DatabaseName, err)
+	}
+	if !postgres || len(cfg.DDL) == 0 {
+		return nil
+	}
+	ddl, err := e.DatabaseAdmin.UpdateDatabaseDdl(ctx, &adminpb.UpdateDatabaseDdlRequest{
+		Database:   e.DatabaseName,
+		Statements: cfg.DDL,
+	})
+	if err == nil {
+		err = ddl.Wait(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("spanneremulator: updating the schema of database %s: %v", e.DatabaseName, err)
+	}
+	return nil
+}
+
+// freePort returns a TCP port on localhost that is not in use.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// randomSuffix returns a random suffix for the IDs of instances and
+// databases.
+func randomSuffix() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprint(time.Now().UnixNano() % 1e8)
+	}
+	return hex.EncodeToString(b)
+}
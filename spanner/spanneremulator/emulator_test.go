@@ -0,0 +1,77 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanneremulator
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	adminpb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+func TestStart_noEmulator(t *testing.T) {
+	for _, env := range []string{"SPANNER_EMULATOR_HOST", "PATH"} {
+		old, ok := os.LookupEnv(env)
+		os.Unsetenv(env)
+		if ok {
+			defer os.Setenv(env, old)
+		}
+	}
+	if _, err := Start(context.Background(), Config{}); err != ErrNoEmulator {
+		t.Fatalf("got error %v, want %v", err, ErrNoEmulator)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	for _, test := range []struct {
+		dialect adminpb.DatabaseDialect
+		ddl     string
+		query   string
+	}{
+		{
+			adminpb.DatabaseDialect_GOOGLE_STANDARD_SQL,
+			"CREATE TABLE Singers (SingerId INT64, Name STRING(MAX)) PRIMARY KEY (SingerId)",
+			"SELECT Name FROM Singers WHERE SingerId = 1",
+		},
+		{
+			adminpb.DatabaseDialect_POSTGRESQL,
+			"CREATE TABLE Singers (SingerId bigint PRIMARY KEY, Name varchar)",
+			"SELECT name FROM singers WHERE singerid = 1",
+		},
+	} {
+		e := Setup(t, Config{DatabaseDialect: test.dialect, DDL: []string{test.ddl}})
+		ctx := context.Background()
+		if _, err := e.Client.Apply(ctx, []*spanner.Mutation{
+			spanner.Insert("Singers", []string{"SingerId", "Name"}, []interface{}{1, "Marc"}),
+		}); err != nil {
+			t.Fatalf("%v: %v", test.dialect, err)
+		}
+		row, err := e.Client.Single().Query(ctx, spanner.NewStatement(test.query)).Next()
+		if err != nil {
+			t.Fatalf("%v: %v", test.dialect, err)
+		}
+		var name string
+		if err := row.Column(0, &name); err != nil {
+			t.Fatalf("%v: %v", test.dialect, err)
+		}
+		if name != "Marc" {
+			t.Errorf("%v: got name %q, want %q", test.dialect, name, "Marc")
+		}
+	}
+}
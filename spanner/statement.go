@@ -35,6 +35,11 @@ import (
 // statement with unbound parameters. On the other hand, it is allowable to
 // bind parameter names that are not used.
 //
+// Statements for PostgreSQL-dialect databases use positional parameter
+// placeholders instead: '$' followed by the 1-based position of the
+// parameter. The value of parameter $n is bound with the name "pn". See
+// NewPGStatement.
+//
 // See the documentation of the Row type for how Go types are mapped to Cloud
 // Spanner types.
 type Statement struct {
@@ -47,6 +52,23 @@ func NewStatement(sql string) Statement {
 	return Statement{SQL: sql, Params: map[string]interface{}{}}
 }
 
+// NewPGStatement returns a Statement for a PostgreSQL-dialect database with
+// the given SQL, binding args to the positional parameters $1, $2, ... in
+// order.
+func NewPGStatement(sql string, args ...interface{}) Statement {
+	stmt := NewStatement(sql)
+	for i, arg := range args {
+		stmt.Params[pgParamName(i+1)] = arg
+	}
+	return stmt
+}
+
+// pgParamName returns the name of the parameter that is bound to the
+// positional parameter $pos in a PostgreSQL-dialect statement.
+func pgParamName(pos int) string {
+	return fmt.Sprintf("p%d", pos)
+}
+
 // convertParams converts a statement's parameters into proto Param and
 // ParamTypes.
 func (s *Statement) convertParams() (*structpb.Struct, map[string]*sppb.Type, error) {
@@ -193,3 +193,14 @@ func TestNewStatement(t *testing.T) {
 		t.Errorf("got %q, want %q", got, want)
 	}
 }
+
+func TestNewPGStatement(t *testing.T) {
+	s := NewPGStatement("SELECT * FROM t WHERE a = $1 AND b = $2", int64(1), "two")
+	if got, want := s.SQL, "SELECT * FROM t WHERE a = $1 AND b = $2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want := map[string]interface{}{"p1": int64(1), "p2": "two"}
+	if !testEqual(s.Params, want) {
+		t.Errorf("got %v, want %v", s.Params, want)
+	}
+}
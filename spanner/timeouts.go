@@ -0,0 +1,75 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"time"
+
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// commitRequestMaxCommitDelay is the field number of the max_commit_delay
+// field of google.spanner.v1.CommitRequest, which the generated type does
+// not have.
+const commitRequestMaxCommitDelay = 8
+
+// MaxCommitDelayLimit is the largest CommitOptions.MaxCommitDelay that Cloud
+// Spanner accepts.
+const MaxCommitDelayLimit = 500 * time.Millisecond
+
+// errInvalidMaxCommitDelay returns error for a MaxCommitDelay outside of the
+// range that Cloud Spanner accepts.
+func errInvalidMaxCommitDelay(d time.Duration) error {
+	return spannerErrorf(codes.InvalidArgument, "MaxCommitDelay must be between 0 and %v, got %v", MaxCommitDelayLimit, d)
+}
+
+// setMaxCommitDelay sets the max_commit_delay field of req to d, if d is not
+// nil.
+func setMaxCommitDelay(req *sppb.CommitRequest, d *time.Duration) error {
+	if d == nil {
+		return nil
+	}
+	if *d < 0 || *d > MaxCommitDelayLimit {
+		return errInvalidMaxCommitDelay(*d)
+	}
+	b, err := proto.Marshal(durationpb.New(*d))
+	if err != nil {
+		return ToSpannerError(err)
+	}
+	m := req.ProtoReflect()
+	u := protowire.AppendTag(m.GetUnknown(), commitRequestMaxCommitDelay, protowire.BytesType)
+	m.SetUnknown(protowire.AppendBytes(u, b))
+	return nil
+}
+
+// contextWithStatementTimeout returns a context that is canceled when the
+// timeout of a statement elapses. Cloud Spanner has no statement timeout
+// setting of its own: gRPC sends the deadline of the context to Cloud
+// Spanner with each RPC of the statement, in the grpc-timeout header, and
+// Cloud Spanner cancels the RPC when it elapses. A timeout that is not
+// positive returns ctx unchanged.
+func contextWithStatementTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2022 Google LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spanner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	"google.golang.org/api/option"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// maxCommitDelayFromRequest returns the max_commit_delay of req, or nil if it
+// is not set.
+func maxCommitDelayFromRequest(t *testing.T, req *sppb.CommitRequest) *time.Duration {
+	b := req.ProtoReflect().GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("invalid unknown fields: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if num == commitRequestMaxCommitDelay && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				t.Fatalf("invalid max_commit_delay: %v", protowire.ParseError(n))
+			}
+			var pb durationpb.Duration
+			if err := proto.Unmarshal(v, &pb); err != nil {
+				t.Fatal(err)
+			}
+			d := pb.AsDuration()
+			return &d
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			t.Fatalf("invalid unknown fields: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func TestClient_MaxCommitDelay(t *testing.T) {
+	t.Parallel()
+
+	clientDelay := 10 * time.Millisecond
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{
+		TransactionOptions: TransactionOptions{CommitOptions: CommitOptions{MaxCommitDelay: &clientDelay}},
+	})
+	defer teardown()
+
+	ctx := context.Background()
+	txDelay := 100 * time.Millisecond
+	for _, test := range []struct {
+		desc  string
+		apply func() error
+		want  time.Duration
+	}{
+		{"transaction", func() error {
+			_, err := client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+				return tx.BufferWrite([]*Mutation{Insert("Singers", []string{"SingerId"}, []interface{}{1})})
+			}, TransactionOptions{CommitOptions: CommitOptions{MaxCommitDelay: &txDelay}})
+			return err
+		}, txDelay},
+		{"Apply", func() error {
+			_, err := client.Apply(ctx, []*Mutation{Insert("Singers", []string{"SingerId"}, []interface{}{1})})
+			return err
+		}, clientDelay},
+		{"ApplyAtLeastOnce", func() error {
+			_, err := client.Apply(ctx, []*Mutation{Insert("Singers", []string{"SingerId"}, []interface{}{1})}, ApplyAtLeastOnce())
+			return err
+		}, clientDelay},
+		{"Apply with commit options", func() error {
+			_, err := client.Apply(ctx, []*Mutation{Insert("Singers", []string{"SingerId"}, []interface{}{1})}, ApplyCommitOptions(CommitOptions{MaxCommitDelay: &txDelay}))
+			return err
+		}, txDelay},
+		{"ApplyAtLeastOnce with commit options", func() error {
+			_, err := client.Apply(ctx, []*Mutation{Insert("Singers", []string{"SingerId"}, []interface{}{1})}, ApplyAtLeastOnce(), ApplyCommitOptions(CommitOptions{MaxCommitDelay: &txDelay}))
+			return err
+		}, txDelay},
+	} {
+		if err := test.apply(); err != nil {
+			t.Fatalf("%s: %v", test.desc, err)
+		}
+		var got *time.Duration
+		for _, req := range drainRequestsFromServer(server.TestSpanner) {
+			if req, ok := req.(*sppb.CommitRequest); ok {
+				got = maxCommitDelayFromRequest(t, req)
+			}
+		}
+		if got == nil || *got != test.want {
+			t.Errorf("%s: got max commit delay %v, want %v", test.desc, got, test.want)
+		}
+	}
+
+	invalid := time.Second
+	_, err := client.ReadWriteTransactionWithOptions(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		return nil
+	}, TransactionOptions{CommitOptions: CommitOptions{MaxCommitDelay: &invalid}})
+	if ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("got error %v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestClient_StatementTimeout(t *testing.T) {
+	t.Parallel()
+
+	server, client, teardown := setupMockedTestServerWithConfig(t, ClientConfig{
+		QueryOptions: QueryOptions{Timeout: time.Minute},
+	})
+	defer teardown()
+
+	ctx := context.Background()
+	slow := SimulatedExecutionTime{MinimumExecutionTime: 500 * time.Millisecond}
+	server.TestSpanner.PutExecutionTime(MethodExecuteStreamingSql, slow)
+	iter := client.Single().QueryWithOptions(ctx, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums), QueryOptions{Timeout: 10 * time.Millisecond})
+	err := iter.Do(func(r *Row) error { return nil })
+	if ErrCode(err) != codes.DeadlineExceeded {
+		t.Errorf("query: got error %v, want code %v", err, codes.DeadlineExceeded)
+	}
+
+	server.TestSpanner.PutExecutionTime(MethodExecuteSql, slow)
+	_, err = client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		_, err := tx.UpdateWithOptions(ctx, NewStatement(UpdateBarSetFoo), QueryOptions{Timeout: 10 * time.Millisecond})
+		return err
+	})
+	if ErrCode(err) != codes.DeadlineExceeded {
+		t.Errorf("update: got error %v, want code %v", err, codes.DeadlineExceeded)
+	}
+
+	// The timeout of the client is used if a statement does not set one.
+	if _, err := client.PartitionedUpdate(ctx, NewStatement(UpdateBarSetFoo)); err != nil {
+		t.Errorf("partitioned update: %v", err)
+	}
+}
+
+func TestClient_StatementTimeoutDeadline(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	deadlines := make(map[string]time.Duration)
+	record := func(ctx context.Context, method string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if d, ok := ctx.Deadline(); ok {
+			deadlines[method] = time.Until(d)
+		}
+	}
+	_, client, teardown := setupMockedTestServerWithConfigAndClientOptions(t, ClientConfig{}, []option.ClientOption{
+		option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			record(ctx, method)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})),
+		option.WithGRPCDialOption(grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			record(ctx, method)
+			return streamer(ctx, desc, cc, method, opts...)
+		})),
+	})
+	defer teardown()
+
+	// The timeout is the deadline of the RPC of the statement, which gRPC
+	// sends to Cloud Spanner, even if the context has no deadline.
+	ctx := context.Background()
+	timeout := 10 * time.Second
+	iter := client.Single().QueryWithOptions(ctx, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums), QueryOptions{Timeout: timeout})
+	if err := iter.Do(func(r *Row) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *ReadWriteTransaction) error {
+		_, err := tx.UpdateWithOptions(ctx, NewStatement(UpdateBarSetFoo), QueryOptions{Timeout: timeout})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, method := range []string{"/google.spanner.v1.Spanner/ExecuteStreamingSql", "/google.spanner.v1.Spanner/ExecuteSql"} {
+		if d, ok := deadlines[method]; !ok || d <= 0 || d > timeout {
+			t.Errorf("%s: got deadline in %v, want a deadline within %v", method, d, timeout)
+		}
+	}
+}
+
+func TestQueryOptions_mergeTimeout(t *testing.T) {
+	for _, test := range []struct {
+		client, statement, want time.Duration
+	}{
+		{0, 0, 0},
+		{time.Second, 0, time.Second},
+		{time.Second, time.Minute, time.Minute},
+	} {
+		got := QueryOptions{Timeout: test.client}.merge(QueryOptions{Timeout: test.statement}).Timeout
+		if got != test.want {
+			t.Errorf("merge(%v, %v): got %v, want %v", test.client, test.statement, got, test.want)
+		}
+	}
+}
@@ -76,9 +76,20 @@ type txReadOnly struct {
 	// qo provides options for executing a sql query.
 	qo QueryOptions
 
+	// ro provides options for reading rows from a database.
+	ro ReadOptions
+
+	// dro are the client-wide DirectedReadOptions. They are only set for
+	// read-only transactions.
+	dro *DirectedReadOptions
+
 	// txOpts provides options for a transaction.
 	txOpts TransactionOptions
 
+	// routeToLeader is true if the requests of the transaction are routed to
+	// the leader region. It is only set for read/write transactions.
+	routeToLeader bool
+
 	// commonTags for opencensus metrics
 	ct *commonTags
 }
@@ -95,6 +106,59 @@ type TransactionOptions struct {
 	// CommitPriority is the priority to use for the Commit RPC for the
 	// transaction.
 	CommitPriority sppb.RequestOptions_Priority
+
+	// RetryBackoff is the backoff policy that is used to calculate the delay
+	// before a read/write transaction is retried after it was aborted, if
+	// Cloud Spanner does not return a delay. DefaultRetryBackoff is used if
+	// it is the zero value. It is not used by ReadWriteStmtBasedTransaction,
+	// which is not retried automatically.
+	RetryBackoff gax.Backoff
+
+	// OnRetry is called each time a read/write transaction was aborted and
+	// will be retried, before the client waits for the delay of the retry.
+	// It can be used to log or alert on transactions that are aborted
+	// repeatedly because of high contention. OnRetry must not block.
+	OnRetry func(info TransactionRetryInfo)
+
+	// LeaderRouting overrides ClientConfig.DisableRouteToLeader for the
+	// transaction. Sessions that the session pool prepares for write in the
+	// background begin their transaction with the setting of the client.
+	LeaderRouting LeaderRouting
+}
+
+// merge combines two TransactionOptions that the input parameter will have
+// higher order of precedence.
+func (to TransactionOptions) merge(opts TransactionOptions) TransactionOptions {
+	merged := TransactionOptions{
+		CommitOptions:  to.CommitOptions,
+		TransactionTag: to.TransactionTag,
+		CommitPriority: to.CommitPriority,
+		RetryBackoff:   to.RetryBackoff,
+		OnRetry:        to.OnRetry,
+		LeaderRouting:  to.LeaderRouting,
+	}
+	if opts.CommitOptions.ReturnCommitStats {
+		merged.CommitOptions.ReturnCommitStats = opts.CommitOptions.ReturnCommitStats
+	}
+	if opts.CommitOptions.MaxCommitDelay != nil {
+		merged.CommitOptions.MaxCommitDelay = opts.CommitOptions.MaxCommitDelay
+	}
+	if opts.TransactionTag != "" {
+		merged.TransactionTag = opts.TransactionTag
+	}
+	if opts.CommitPriority != sppb.RequestOptions_PRIORITY_UNSPECIFIED {
+		merged.CommitPriority = opts.CommitPriority
+	}
+	if opts.RetryBackoff != (gax.Backoff{}) {
+		merged.RetryBackoff = opts.RetryBackoff
+	}
+	if opts.OnRetry != nil {
+		merged.OnRetry = opts.OnRetry
+	}
+	if opts.LeaderRouting != LeaderRoutingDefault {
+		merged.LeaderRouting = opts.LeaderRouting
+	}
+	return merged
 }
 
 func (to *TransactionOptions) requestPriority() sppb.RequestOptions_Priority {
@@ -137,6 +201,39 @@ type ReadOptions struct {
 
 	// The request tag to use for this request.
 	RequestTag string
+
+	// DirectedReadOptions directs the read to specific replicas. It can only
+	// be used in read-only transactions and single-use reads, and takes
+	// precedence over ClientConfig.DirectedReadOptions.
+	DirectedReadOptions *DirectedReadOptions
+}
+
+// merge combines two ReadOptions that the input parameter will have higher
+// order of precedence.
+func (ro ReadOptions) merge(opts ReadOptions) ReadOptions {
+	merged := ReadOptions{
+		Index:               ro.Index,
+		Limit:               ro.Limit,
+		Priority:            ro.Priority,
+		RequestTag:          ro.RequestTag,
+		DirectedReadOptions: ro.DirectedReadOptions,
+	}
+	if opts.Index != "" {
+		merged.Index = opts.Index
+	}
+	if opts.Limit > 0 {
+		merged.Limit = opts.Limit
+	}
+	if opts.Priority != sppb.RequestOptions_PRIORITY_UNSPECIFIED {
+		merged.Priority = opts.Priority
+	}
+	if opts.RequestTag != "" {
+		merged.RequestTag = opts.RequestTag
+	}
+	if opts.DirectedReadOptions != nil {
+		merged.DirectedReadOptions = opts.DirectedReadOptions
+	}
+	return merged
 }
 
 // ReadWithOptions returns a RowIterator for reading multiple rows from the
@@ -162,34 +259,35 @@ func (t *txReadOnly) ReadWithOptions(ctx context.Context, table string, keys Key
 		// Might happen if transaction is closed in the middle of a API call.
 		return &RowIterator{err: errSessionClosed(sh)}
 	}
-	index := ""
-	limit := 0
-	prio := sppb.RequestOptions_PRIORITY_UNSPECIFIED
-	requestTag := ""
+	ro := t.ro
 	if opts != nil {
-		index = opts.Index
-		if opts.Limit > 0 {
-			limit = opts.Limit
-		}
-		prio = opts.Priority
-		requestTag = opts.RequestTag
+		ro = ro.merge(*opts)
+	}
+	index := ro.Index
+	limit := ro.Limit
+	prio := ro.Priority
+	requestTag := ro.RequestTag
+	dro, err := t.directedReadOptions(ro.DirectedReadOptions)
+	if err != nil {
+		return &RowIterator{err: err}
 	}
 	return streamWithReplaceSessionFunc(
-		contextWithOutgoingMetadata(ctx, sh.getMetadata()),
+		contextWithOutgoingMetadata(ctx, withRouteToLeader(sh.getMetadata(), t.routeToLeader)),
 		sh.session.logger,
 		func(ctx context.Context, resumeToken []byte) (streamingReceiver, error) {
-			client, err := client.StreamingRead(ctx,
-				&sppb.ReadRequest{
-					Session:        t.sh.getID(),
-					Transaction:    ts,
-					Table:          table,
-					Index:          index,
-					Columns:        columns,
-					KeySet:         kset,
-					ResumeToken:    resumeToken,
-					Limit:          int64(limit),
-					RequestOptions: createRequestOptions(prio, requestTag, t.txOpts.TransactionTag),
-				})
+			req := &sppb.ReadRequest{
+				Session:        t.sh.getID(),
+				Transaction:    ts,
+				Table:          table,
+				Index:          index,
+				Columns:        columns,
+				KeySet:         kset,
+				ResumeToken:    resumeToken,
+				Limit:          int64(limit),
+				RequestOptions: createRequestOptions(prio, requestTag, t.txOpts.TransactionTag),
+			}
+			setDirectedReadOptions(req, readRequestDirectedReadOptions, dro)
+			client, err := client.StreamingRead(ctx, req)
 			if err != nil {
 				return client, err
 			}
@@ -289,16 +387,34 @@ type QueryOptions struct {
 
 	// The request tag to use for this request.
 	RequestTag string
+
+	// DirectedReadOptions directs the query to specific replicas. It can only
+	// be used in read-only transactions and single-use reads, and takes
+	// precedence over ClientConfig.DirectedReadOptions.
+	DirectedReadOptions *DirectedReadOptions
+
+	// Timeout bounds the execution of the statement, including the
+	// streaming of the results of a query and any retries, and the statement
+	// fails with codes.DeadlineExceeded when it elapses. It is not a separate
+	// setting of Cloud Spanner: it is the deadline of the RPCs of the
+	// statement, which gRPC sends to Cloud Spanner so that it stops executing
+	// the statement as well. Unlike the deadline of the context, which also
+	// applies, it bounds only this statement and not the rest of the
+	// transaction. For BatchUpdate, it bounds each BatchUpdate RPC. There is
+	// no timeout if it is zero.
+	Timeout time.Duration
 }
 
 // merge combines two QueryOptions that the input parameter will have higher
 // order of precedence.
 func (qo QueryOptions) merge(opts QueryOptions) QueryOptions {
 	merged := QueryOptions{
-		Mode:       qo.Mode,
-		Options:    &sppb.ExecuteSqlRequest_QueryOptions{},
-		RequestTag: qo.RequestTag,
-		Priority:   qo.Priority,
+		Mode:                qo.Mode,
+		Options:             &sppb.ExecuteSqlRequest_QueryOptions{},
+		RequestTag:          qo.RequestTag,
+		Priority:            qo.Priority,
+		DirectedReadOptions: qo.DirectedReadOptions,
+		Timeout:             qo.Timeout,
 	}
 	if opts.Mode != nil {
 		merged.Mode = opts.Mode
@@ -309,6 +425,12 @@ func (qo QueryOptions) merge(opts QueryOptions) QueryOptions {
 	if opts.Priority != sppb.RequestOptions_PRIORITY_UNSPECIFIED {
 		merged.Priority = opts.Priority
 	}
+	if opts.DirectedReadOptions != nil {
+		merged.DirectedReadOptions = opts.DirectedReadOptions
+	}
+	if opts.Timeout != 0 {
+		merged.Timeout = opts.Timeout
+	}
 	proto.Merge(merged.Options, qo.Options)
 	proto.Merge(merged.Options, opts.Options)
 	return merged
@@ -391,8 +513,9 @@ func (t *txReadOnly) query(ctx context.Context, statement Statement, options Que
 		return &RowIterator{err: err}
 	}
 	client := sh.getClient()
-	return streamWithReplaceSessionFunc(
-		contextWithOutgoingMetadata(ctx, sh.getMetadata()),
+	sctx, cancel := contextWithStatementTimeout(ctx, options.Timeout)
+	iter := streamWithReplaceSessionFunc(
+		contextWithOutgoingMetadata(sctx, withRouteToLeader(sh.getMetadata(), t.routeToLeader)),
 		sh.session.logger,
 		func(ctx context.Context, resumeToken []byte) (streamingReceiver, error) {
 			req.ResumeToken = resumeToken
@@ -412,6 +535,12 @@ func (t *txReadOnly) query(ctx context.Context, statement Statement, options Que
 		t.replaceSessionFunc,
 		t.setTimestamp,
 		t.release)
+	streamCancel := iter.cancel
+	iter.cancel = func() {
+		streamCancel()
+		cancel()
+	}
+	return iter
 }
 
 func (t *txReadOnly) prepareExecuteSQL(ctx context.Context, stmt Statement, options QueryOptions) (*sppb.ExecuteSqlRequest, *sessionHandle, error) {
@@ -444,9 +573,35 @@ func (t *txReadOnly) prepareExecuteSQL(ctx context.Context, stmt Statement, opti
 		QueryOptions:   options.Options,
 		RequestOptions: createRequestOptions(options.Priority, options.RequestTag, t.txOpts.TransactionTag),
 	}
+	dro, err := t.directedReadOptions(options.DirectedReadOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	setDirectedReadOptions(req, executeSQLRequestDirectedReadOptions, dro)
 	return req, sh, nil
 }
 
+// directedReadOptions returns the DirectedReadOptions to use for a read or
+// query with the given options. It returns an error if the options are set
+// for a read/write transaction or are invalid.
+func (t *txReadOnly) directedReadOptions(opts *DirectedReadOptions) (*DirectedReadOptions, error) {
+	if opts == nil {
+		opts = t.dro
+	} else {
+		switch t.txReadEnv.(type) {
+		case *ReadWriteTransaction, *ReadWriteStmtBasedTransaction:
+			return nil, errDirectedReadOptionsInReadWriteTransaction()
+		}
+	}
+	if opts == nil {
+		return nil, nil
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
 // txState is the status of a transaction.
 type txState int
 
@@ -929,7 +1084,9 @@ func (t *ReadWriteTransaction) update(ctx context.Context, stmt Statement, opts
 		return 0, err
 	}
 	var md metadata.MD
-	resultSet, err := sh.getClient().ExecuteSql(contextWithOutgoingMetadata(ctx, sh.getMetadata()), req, gax.WithGRPCOptions(grpc.Header(&md)))
+	sctx, cancel := contextWithStatementTimeout(ctx, opts.Timeout)
+	defer cancel()
+	resultSet, err := sh.getClient().ExecuteSql(contextWithOutgoingMetadata(sctx, withRouteToLeader(sh.getMetadata(), t.routeToLeader)), req, gax.WithGRPCOptions(grpc.Header(&md)))
 
 	if getGFELatencyMetricsFlag() && md != nil && t.ct != nil {
 		if err := createContextAndCaptureGFELatencyMetrics(ctx, t.ct, md, "update"); err != nil {
@@ -973,17 +1130,16 @@ func (t *ReadWriteTransaction) batchUpdateWithOptions(ctx context.Context, stmts
 	ctx = trace.StartSpan(ctx, "cloud.google.com/go/spanner.BatchUpdate")
 	defer func() { trace.EndSpan(ctx, err) }()
 
-	sh, ts, err := t.acquire(ctx)
+	sppbStmts, err := batchDmlStatements(stmts)
 	if err != nil {
 		return nil, err
 	}
-	// Cloud Spanner will return "Session not found" on bad sessions.
-	sid := sh.getID()
-	if sid == "" {
-		// Might happen if transaction is closed in the middle of a API call.
-		return nil, errSessionClosed(sh)
-	}
+	return t.executeBatchDml(ctx, sppbStmts, opts)
+}
 
+// batchDmlStatements converts stmts to the statements of an
+// ExecuteBatchDmlRequest.
+func batchDmlStatements(stmts []Statement) ([]*sppb.ExecuteBatchDmlRequest_Statement, error) {
 	var sppbStmts []*sppb.ExecuteBatchDmlRequest_Statement
 	for _, st := range stmts {
 		params, paramTypes, err := st.convertParams()
@@ -996,9 +1152,27 @@ func (t *ReadWriteTransaction) batchUpdateWithOptions(ctx context.Context, stmts
 			ParamTypes: paramTypes,
 		})
 	}
+	return sppbStmts, nil
+}
+
+// executeBatchDml executes the given statements in a single ExecuteBatchDml
+// RPC.
+func (t *ReadWriteTransaction) executeBatchDml(ctx context.Context, sppbStmts []*sppb.ExecuteBatchDmlRequest_Statement, opts QueryOptions) ([]int64, error) {
+	sh, ts, err := t.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Cloud Spanner will return "Session not found" on bad sessions.
+	sid := sh.getID()
+	if sid == "" {
+		// Might happen if transaction is closed in the middle of a API call.
+		return nil, errSessionClosed(sh)
+	}
 
 	var md metadata.MD
-	resp, err := sh.getClient().ExecuteBatchDml(contextWithOutgoingMetadata(ctx, sh.getMetadata()), &sppb.ExecuteBatchDmlRequest{
+	sctx, cancel := contextWithStatementTimeout(ctx, opts.Timeout)
+	defer cancel()
+	resp, err := sh.getClient().ExecuteBatchDml(contextWithOutgoingMetadata(sctx, withRouteToLeader(sh.getMetadata(), t.routeToLeader)), &sppb.ExecuteBatchDmlRequest{
 		Session:        sh.getID(),
 		Transaction:    ts,
 		Statements:     sppbStmts,
@@ -1082,7 +1256,7 @@ func (t *ReadWriteTransaction) begin(ctx context.Context) error {
 		t.state = txActive
 		return nil
 	}
-	tx, err := beginTransaction(contextWithOutgoingMetadata(ctx, t.sh.getMetadata()), t.sh.getID(), t.sh.getClient())
+	tx, err := beginTransaction(contextWithOutgoingMetadata(ctx, withRouteToLeader(t.sh.getMetadata(), t.routeToLeader)), t.sh.getID(), t.sh.getClient())
 	if err == nil {
 		t.tx = tx
 		t.state = txActive
@@ -1105,6 +1279,13 @@ type CommitResponse struct {
 // CommitOptions provides options for commiting a transaction in a database.
 type CommitOptions struct {
 	ReturnCommitStats bool
+
+	// MaxCommitDelay is the amount of time that Cloud Spanner may delay the
+	// commit of the transaction to batch it with other transactions, which
+	// can increase the write throughput at the cost of commit latency. It
+	// must be between 0 and MaxCommitDelayLimit. Cloud Spanner chooses the
+	// delay if it is nil.
+	MaxCommitDelay *time.Duration
 }
 
 // commit tries to commit a readwrite transaction to Cloud Spanner. It also
@@ -1126,7 +1307,7 @@ func (t *ReadWriteTransaction) commit(ctx context.Context, options CommitOptions
 		return resp, errSessionClosed(t.sh)
 	}
 
-	res, e := client.Commit(contextWithOutgoingMetadata(ctx, t.sh.getMetadata()), &sppb.CommitRequest{
+	req := &sppb.CommitRequest{
 		Session: sid,
 		Transaction: &sppb.CommitRequest_TransactionId{
 			TransactionId: t.tx,
@@ -1134,7 +1315,11 @@ func (t *ReadWriteTransaction) commit(ctx context.Context, options CommitOptions
 		RequestOptions:    createRequestOptions(t.txOpts.CommitPriority, "", t.txOpts.TransactionTag),
 		Mutations:         mPb,
 		ReturnCommitStats: options.ReturnCommitStats,
-	})
+	}
+	if err := setMaxCommitDelay(req, options.MaxCommitDelay); err != nil {
+		return resp, err
+	}
+	res, e := client.Commit(contextWithOutgoingMetadata(ctx, withRouteToLeader(t.sh.getMetadata(), t.routeToLeader)), req)
 	if e != nil {
 		return resp, toSpannerErrorWithCommitInfo(e, true)
 	}
@@ -1163,7 +1348,7 @@ func (t *ReadWriteTransaction) rollback(ctx context.Context) {
 	if sid == "" || client == nil {
 		return
 	}
-	err := client.Rollback(contextWithOutgoingMetadata(ctx, t.sh.getMetadata()), &sppb.RollbackRequest{
+	err := client.Rollback(contextWithOutgoingMetadata(ctx, withRouteToLeader(t.sh.getMetadata(), t.routeToLeader)), &sppb.RollbackRequest{
 		Session:       sid,
 		TransactionId: t.tx,
 	})
@@ -1265,7 +1450,9 @@ func NewReadWriteStmtBasedTransactionWithOptions(ctx context.Context, c *Client,
 	t.txReadOnly.sh = sh
 	t.txReadOnly.txReadEnv = t
 	t.txReadOnly.qo = c.qo
-	t.txOpts = options
+	t.txReadOnly.ro = c.ro
+	t.txOpts = c.to.merge(options)
+	t.routeToLeader = t.txOpts.LeaderRouting.routeToLeader(c.disableRouteToLeader)
 	t.ct = c.ct
 
 	if err = t.begin(ctx); err != nil {
@@ -1318,6 +1505,10 @@ type writeOnlyTransaction struct {
 	transactionTag string
 	// commitPriority is the RPC priority to use for the commit operation.
 	commitPriority sppb.RequestOptions_Priority
+	// routeToLeader is true if the commit is routed to the leader region.
+	routeToLeader bool
+	// maxCommitDelay is the max_commit_delay of the CommitRequest.
+	maxCommitDelay *time.Duration
 }
 
 // applyAtLeastOnce commits a list of mutations to Cloud Spanner at least once,
@@ -1350,7 +1541,7 @@ func (t *writeOnlyTransaction) applyAtLeastOnce(ctx context.Context, ms ...*Muta
 			}
 			defer sh.recycle()
 		}
-		res, err := sh.getClient().Commit(contextWithOutgoingMetadata(ctx, sh.getMetadata()), &sppb.CommitRequest{
+		req := &sppb.CommitRequest{
 			Session: sh.getID(),
 			Transaction: &sppb.CommitRequest_SingleUseTransaction{
 				SingleUseTransaction: &sppb.TransactionOptions{
@@ -1361,7 +1552,11 @@ func (t *writeOnlyTransaction) applyAtLeastOnce(ctx context.Context, ms ...*Muta
 			},
 			Mutations:      mPb,
 			RequestOptions: createRequestOptions(t.commitPriority, "", t.transactionTag),
-		})
+		}
+		if err := setMaxCommitDelay(req, t.maxCommitDelay); err != nil {
+			return ts, err
+		}
+		res, err := sh.getClient().Commit(contextWithOutgoingMetadata(ctx, withRouteToLeader(sh.getMetadata(), t.routeToLeader)), req)
 		if err != nil && !isAbortedErr(err) {
 			if isSessionNotFoundError(err) {
 				// Discard the bad session.
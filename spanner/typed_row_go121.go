@@ -0,0 +1,199 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package spanner
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+
+	"google.golang.org/api/iterator"
+	sppb "google.golang.org/genproto/googleapis/spanner/v1"
+)
+
+// decodePlan maps the columns of a row to the fields of a struct type. A plan
+// is computed once per struct type and set of columns, so that decoding a
+// row does not need to match column names to struct fields again.
+type decodePlan struct {
+	// index contains the index of the struct field of each column.
+	index [][]int
+}
+
+// decodePlanKey identifies the plan of a struct type and the names of the
+// columns of a row.
+type decodePlanKey struct {
+	t       reflect.Type
+	columns string
+}
+
+// decodePlanCache caches the decodePlans by decodePlanKey.
+var decodePlanCache sync.Map
+
+// getDecodePlan returns the plan for decoding a row with the given fields
+// into a struct of type t. It follows the rules of Row.ToStruct.
+func getDecodePlan(t reflect.Type, fields []*sppb.StructType_Field) (*decodePlan, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, errToStructArgType(reflect.New(t).Interface())
+	}
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	key := decodePlanKey{t: t, columns: strings.Join(names, "\x00")}
+	if p, ok := decodePlanCache.Load(key); ok {
+		return p.(*decodePlan), nil
+	}
+
+	fs, err := fieldCache.Fields(t)
+	if err != nil {
+		return nil, ToSpannerError(err)
+	}
+	p := &decodePlan{index: make([][]int, len(fields))}
+	seen := make(map[string]bool)
+	for i, f := range fields {
+		if f.Name == "" {
+			return nil, errUnnamedField(&sppb.StructType{Fields: fields}, i)
+		}
+		sf := fs.Match(f.Name)
+		if sf == nil {
+			return nil, errNoOrDupGoField(reflect.New(t).Interface(), f.Name)
+		}
+		if seen[f.Name] {
+			return nil, errDupSpannerField(f.Name, &sppb.StructType{Fields: fields})
+		}
+		seen[f.Name] = true
+		p.index[i] = sf.Index
+	}
+	decodePlanCache.Store(key, p)
+	return p, nil
+}
+
+// decode decodes r into the struct that v points to according to plan p.
+func (p *decodePlan) decode(r *Row, v reflect.Value) error {
+	if len(r.vals) != len(r.fields) {
+		return errFieldsMismatchVals(r)
+	}
+	for i, f := range r.fields {
+		if err := decodeValue(r.vals[i], f.Type, v.FieldByIndex(p.index[i]).Addr().Interface()); err != nil {
+			return errDecodeStructField(&sppb.StructType{Fields: r.fields}, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// typeOf returns the type T. Unlike reflect.TypeOf of a zero T, it also
+// returns the type if T is an interface type.
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Decode decodes the columns of row into a new value of the struct type T.
+// The columns are mapped to the fields of T with the rules of Row.ToStruct:
+// a column is decoded into the field with a matching `spanner:"name"` tag or
+// with a matching name, and every column must have a field. Pointer, slice
+// and NullXXX fields can hold NULL values.
+//
+// The mapping of columns to fields is computed once for each struct type and
+// set of columns, which makes Decode cheaper than Row.ToStruct when many rows
+// are decoded.
+func Decode[T any](row *Row) (T, error) {
+	var v T
+	p, err := getDecodePlan(typeOf[T](), row.fields)
+	if err != nil {
+		return v, err
+	}
+	err = p.decode(row, reflect.ValueOf(&v).Elem())
+	return v, err
+}
+
+// TypedRowIterator is an iterator over the rows of a RowIterator that are
+// decoded into values of the struct type T with the rules of Decode.
+type TypedRowIterator[T any] struct {
+	iter *RowIterator
+	plan *decodePlan
+}
+
+// Rows returns an iterator that decodes the rows of iter into values of the
+// struct type T.
+func Rows[T any](iter *RowIterator) *TypedRowIterator[T] {
+	return &TypedRowIterator[T]{iter: iter}
+}
+
+// Query executes a query in a single-use read-only transaction of client and
+// returns an iterator that decodes the resulting rows into values of the
+// struct type T.
+func Query[T any](ctx context.Context, client *Client, statement Statement) *TypedRowIterator[T] {
+	return Rows[T](client.Single().Query(ctx, statement))
+}
+
+// Next returns the next row decoded into a T. Its second return value is
+// iterator.Done if there are no more rows. Once Next returns Done, all
+// subsequent calls will return Done.
+func (it *TypedRowIterator[T]) Next() (T, error) {
+	var v T
+	row, err := it.iter.Next()
+	if err != nil {
+		return v, err
+	}
+	if it.plan == nil {
+		// All rows of a result set have the same columns.
+		if it.plan, err = getDecodePlan(typeOf[T](), row.fields); err != nil {
+			return v, err
+		}
+	}
+	err = it.plan.decode(row, reflect.ValueOf(&v).Elem())
+	return v, err
+}
+
+// Do calls the provided function once in sequence for each row in the
+// iteration. If the function returns a non-nil error, Do immediately returns
+// that error.
+//
+// If there are no rows in the iterator, Do will return nil without calling
+// the provided function.
+//
+// Do always calls Stop on the iterator.
+func (it *TypedRowIterator[T]) Do(f func(v T) error) error {
+	defer it.Stop()
+	for {
+		v, err := it.Next()
+		switch err {
+		case iterator.Done:
+			return nil
+		case nil:
+			if err = f(v); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// Stop terminates the iteration. It should be called after you finish using
+// the iterator.
+func (it *TypedRowIterator[T]) Stop() {
+	it.iter.Stop()
+}
+
+// RowIterator returns the underlying RowIterator, e.g. to get the query
+// statistics after the iteration has finished.
+func (it *TypedRowIterator[T]) RowIterator() *RowIterator {
+	return it.iter
+}
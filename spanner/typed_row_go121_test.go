@@ -0,0 +1,175 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package spanner
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	. "cloud.google.com/go/spanner/internal/testutil"
+	"google.golang.org/grpc/codes"
+)
+
+func TestDecode(t *testing.T) {
+	type singer struct {
+		ID        int64 `spanner:"SingerId"`
+		FirstName NullString
+		LastName  *string
+		Ignored   string `spanner:"-"`
+	}
+	last := "Richards"
+	for _, test := range []struct {
+		cols []string
+		vals []interface{}
+		want singer
+	}{
+		{
+			cols: []string{"SingerId", "FirstName", "LastName"},
+			vals: []interface{}{int64(1), "Marc", "Richards"},
+			want: singer{ID: 1, FirstName: NullString{"Marc", true}, LastName: &last},
+		},
+		{
+			// Columns are matched by name, and can be NULL.
+			cols: []string{"lastname", "SingerId", "FirstName"},
+			vals: []interface{}{NullString{}, int64(2), NullString{}},
+			want: singer{ID: 2},
+		},
+	} {
+		row, err := NewRow(test.cols, test.vals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Decode[singer](row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !testEqual(got, test.want) {
+			t.Errorf("Decode(%v): got %+v, want %+v", test.cols, got, test.want)
+		}
+		var want singer
+		if err := row.ToStruct(&want); err != nil {
+			t.Fatal(err)
+		}
+		if !testEqual(got, want) {
+			t.Errorf("Decode(%v): got %+v, ToStruct got %+v", test.cols, got, want)
+		}
+	}
+}
+
+func TestDecode_errors(t *testing.T) {
+	type singer struct {
+		ID int64 `spanner:"SingerId"`
+	}
+	for _, test := range []struct {
+		desc string
+		cols []string
+		vals []interface{}
+	}{
+		{"no field for column", []string{"SingerId", "Name"}, []interface{}{int64(1), "Marc"}},
+		{"duplicate column", []string{"SingerId", "SingerId"}, []interface{}{int64(1), int64(2)}},
+		{"wrong type", []string{"SingerId"}, []interface{}{"one"}},
+	} {
+		row, err := NewRow(test.cols, test.vals)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode[singer](row); ErrCode(err) != codes.InvalidArgument && ErrCode(err) != codes.FailedPrecondition {
+			t.Errorf("%s: got error %v, want InvalidArgument or FailedPrecondition", test.desc, err)
+		}
+	}
+	row, err := NewRow([]string{"SingerId"}, []interface{}{int64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode[int64](row); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("non-struct type: got error %v, want code %v", err, codes.InvalidArgument)
+	}
+	if _, err := Decode[interface{}](row); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("interface type: got error %v, want code %v", err, codes.InvalidArgument)
+	}
+	if _, err := Decode[fmt.Stringer](row); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("non-empty interface type: got error %v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+func TestQuery_typed(t *testing.T) {
+	t.Parallel()
+
+	_, client, teardown := setupMockedTestServer(t)
+	defer teardown()
+
+	type album struct {
+		SingerID int64  `spanner:"SingerId"`
+		AlbumID  int64  `spanner:"AlbumId"`
+		Title    string `spanner:"AlbumTitle"`
+	}
+	var got []album
+	err := Query[album](context.Background(), client, NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums)).Do(func(a album) error {
+		got = append(got, a)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []album
+	for i := int64(0); i < SelectSingerIDAlbumIDAlbumTitleFromAlbumsRowCount; i++ {
+		want = append(want, album{SingerID: i + 1, AlbumID: i*10 + i, Title: fmt.Sprintf("Album title %d", i)})
+	}
+	if !testEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// A struct without a field for each column fails on the first row.
+	type noTitle struct {
+		SingerID int64 `spanner:"SingerId"`
+		AlbumID  int64 `spanner:"AlbumId"`
+	}
+	it := Rows[noTitle](client.Single().Query(context.Background(), NewStatement(SelectSingerIDAlbumIDAlbumTitleFromAlbums)))
+	defer it.Stop()
+	if _, err := it.Next(); ErrCode(err) != codes.InvalidArgument {
+		t.Errorf("got error %v, want code %v", err, codes.InvalidArgument)
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	type singer struct {
+		ID        int64 `spanner:"SingerId"`
+		FirstName NullString
+		LastName  string
+	}
+	row, err := NewRow([]string{"SingerId", "FirstName", "LastName"}, []interface{}{int64(1), "Marc", "Richards"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("Decode", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Decode[singer](row); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ToStruct", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var s singer
+			if err := row.ToStruct(&s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
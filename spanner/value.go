@@ -830,6 +830,118 @@ func (n NullJSON) GormDataType() string {
 	return "JSON"
 }
 
+// PGNumeric represents a Cloud Spanner PG Numeric that may be NULL.
+//
+// PG Numeric values are stored as strings, as they can contain values, such as
+// NaN, that cannot be represented by a big.Rat. This type must be used when
+// encoding values to a numeric column in a PostgreSQL-dialect database.
+type PGNumeric struct {
+	Numeric string // Numeric contains the value when it is non-NULL, and an empty string when NULL.
+	Valid   bool   // Valid is true if Numeric is not NULL.
+}
+
+// IsNull implements NullableValue.IsNull for PGNumeric.
+func (n PGNumeric) IsNull() bool {
+	return !n.Valid
+}
+
+// String implements Stringer.String for PGNumeric.
+func (n PGNumeric) String() string {
+	if !n.Valid {
+		return nullString
+	}
+	return n.Numeric
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON for PGNumeric.
+func (n PGNumeric) MarshalJSON() ([]byte, error) {
+	if n.Valid {
+		return []byte(fmt.Sprintf("%q", n.Numeric)), nil
+	}
+	return jsonNullBytes, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON for PGNumeric.
+func (n *PGNumeric) UnmarshalJSON(payload []byte) error {
+	if payload == nil {
+		return fmt.Errorf("payload should not be nil")
+	}
+	if bytes.Equal(payload, jsonNullBytes) {
+		n.Numeric = ""
+		n.Valid = false
+		return nil
+	}
+	payload, err := trimDoubleQuotes(payload)
+	if err != nil {
+		return err
+	}
+	n.Numeric = string(payload)
+	n.Valid = true
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (n PGNumeric) Value() (driver.Value, error) {
+	if n.IsNull() {
+		return nil, nil
+	}
+	return n.Numeric, nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (n *PGNumeric) Scan(value interface{}) error {
+	if value == nil {
+		n.Numeric, n.Valid = "", false
+		return nil
+	}
+	n.Valid = true
+	switch p := value.(type) {
+	default:
+		return spannerErrorf(codes.InvalidArgument, "invalid type for PGNumeric: %v", p)
+	case string:
+		n.Numeric = p
+	case *string:
+		n.Numeric = *p
+	case *PGNumeric:
+		n.Numeric = p.Numeric
+		n.Valid = p.Valid
+	case PGNumeric:
+		n.Numeric = p.Numeric
+		n.Valid = p.Valid
+	}
+	return nil
+}
+
+// PGJsonB represents a Cloud Spanner PG JSONB that may be NULL.
+//
+// This type must always be used when encoding values to a JSONB column in a
+// PostgreSQL-dialect database. Like NullJSON, it does not implement the
+// driver.Valuer and sql.Scanner interfaces.
+type PGJsonB struct {
+	Value interface{} // Value contains the value when it is non-NULL, and nil when NULL.
+	Valid bool        // Valid is true if Value is not NULL.
+}
+
+// IsNull implements NullableValue.IsNull for PGJsonB.
+func (n PGJsonB) IsNull() bool {
+	return !n.Valid
+}
+
+// String implements Stringer.String for PGJsonB.
+func (n PGJsonB) String() string {
+	return NullJSON(n).String()
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON for PGJsonB.
+func (n PGJsonB) MarshalJSON() ([]byte, error) {
+	return NullJSON(n).MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON for PGJsonB.
+func (n *PGJsonB) UnmarshalJSON(payload []byte) error {
+	return (*NullJSON)(n).UnmarshalJSON(payload)
+}
+
 // NullRow represents a Cloud Spanner STRUCT that may be NULL.
 // See also the document for Row.
 // Note that NullRow is not a valid Cloud Spanner column Type.
@@ -1071,7 +1183,7 @@ func decodeValue(v *proto3.Value, t *sppb.Type, ptr interface{}) error {
 		if p == nil {
 			return errNilDst(p)
 		}
-		if code != sppb.TypeCode_BYTES {
+		if code != sppb.TypeCode_BYTES && code != typeCodeProto {
 			return errTypeMismatch(code, acode, ptr)
 		}
 		if isNull {
@@ -1111,7 +1223,7 @@ func decodeValue(v *proto3.Value, t *sppb.Type, ptr interface{}) error {
 		if p == nil {
 			return errNilDst(p)
 		}
-		if code != sppb.TypeCode_INT64 {
+		if code != sppb.TypeCode_INT64 && code != typeCodeEnum {
 			return errTypeMismatch(code, acode, ptr)
 		}
 		if isNull {
@@ -1130,7 +1242,7 @@ func decodeValue(v *proto3.Value, t *sppb.Type, ptr interface{}) error {
 		if p == nil {
 			return errNilDst(p)
 		}
-		if code != sppb.TypeCode_INT64 {
+		if code != sppb.TypeCode_INT64 && code != typeCodeEnum {
 			return errTypeMismatch(code, acode, ptr)
 		}
 		if isNull {
@@ -1469,6 +1581,76 @@ func decodeValue(v *proto3.Value, t *sppb.Type, ptr interface{}) error {
 			return err
 		}
 		*p = y
+	case *PGJsonB:
+		if p == nil {
+			return errNilDst(p)
+		}
+		if code != sppb.TypeCode_JSON {
+			return errTypeMismatch(code, acode, ptr)
+		}
+		if isNull {
+			*p = PGJsonB{}
+			break
+		}
+		x := v.GetStringValue()
+		var y interface{}
+		err := json.Unmarshal([]byte(x), &y)
+		if err != nil {
+			return err
+		}
+		*p = PGJsonB{y, true}
+	case *[]PGJsonB:
+		if p == nil {
+			return errNilDst(p)
+		}
+		if acode != sppb.TypeCode_JSON {
+			return errTypeMismatch(code, acode, ptr)
+		}
+		if isNull {
+			*p = nil
+			break
+		}
+		x, err := getListValue(v)
+		if err != nil {
+			return err
+		}
+		y, err := decodePGJsonBArray(x)
+		if err != nil {
+			return err
+		}
+		*p = y
+	case *PGNumeric:
+		if p == nil {
+			return errNilDst(p)
+		}
+		if code != sppb.TypeCode_NUMERIC {
+			return errTypeMismatch(code, acode, ptr)
+		}
+		if isNull {
+			*p = PGNumeric{}
+			break
+		}
+		*p = PGNumeric{v.GetStringValue(), true}
+	case *[]PGNumeric:
+		if p == nil {
+			return errNilDst(p)
+		}
+		if acode != sppb.TypeCode_NUMERIC {
+			return errTypeMismatch(code, acode, ptr)
+		}
+		if isNull {
+			*p = nil
+			break
+		}
+		x, err := getListValue(v)
+		if err != nil {
+			return err
+		}
+		y, err := decodePGNumericArray(x)
+		if err != nil {
+			return err
+		}
+		*p = y
 	case *NullNumeric:
 		if p == nil {
 			return errNilDst(p)
@@ -1774,6 +1956,11 @@ func decodeValue(v *proto3.Value, t *sppb.Type, ptr interface{}) error {
 			return decodedVal.DecodeSpanner(x)
 		}
 
+		// Check if the pointer is to a protocol buffer message or enum.
+		if ok, err := decodeProtoValue(v, t, ptr); ok {
+			return err
+		}
+
 		// Check if the pointer is a variant of a base type.
 		decodableType := getDecodableSpannerType(ptr, true)
 		if decodableType != spannerTypeUnknown {
@@ -2689,6 +2876,34 @@ func decodeNullJSONArray(pb *proto3.ListValue) ([]NullJSON, error) {
 	return a, nil
 }
 
+// decodePGNumericArray decodes proto3.ListValue pb into a PGNumeric slice.
+func decodePGNumericArray(pb *proto3.ListValue) ([]PGNumeric, error) {
+	if pb == nil {
+		return nil, errNilListValue("PG NUMERIC")
+	}
+	a := make([]PGNumeric, len(pb.Values))
+	for i, v := range pb.Values {
+		if err := decodeValue(v, pgNumericType(), &a[i]); err != nil {
+			return nil, errDecodeArrayElement(i, v, "PG NUMERIC", err)
+		}
+	}
+	return a, nil
+}
+
+// decodePGJsonBArray decodes proto3.ListValue pb into a PGJsonB slice.
+func decodePGJsonBArray(pb *proto3.ListValue) ([]PGJsonB, error) {
+	if pb == nil {
+		return nil, errNilListValue("PG JSONB")
+	}
+	a := make([]PGJsonB, len(pb.Values))
+	for i, v := range pb.Values {
+		if err := decodeValue(v, pgJsonbType(), &a[i]); err != nil {
+			return nil, errDecodeArrayElement(i, v, "PG JSONB", err)
+		}
+	}
+	return a, nil
+}
+
 // decodeNullJSONArray decodes proto3.ListValue pb into a NullJSON pointer.
 func decodeNullJSONArrayToNullJSON(pb *proto3.ListValue) (*NullJSON, error) {
 	if pb == nil {
@@ -3290,6 +3505,36 @@ func encodeValue(v interface{}) (*proto3.Value, *sppb.Type, error) {
 			}
 		}
 		pt = listType(jsonType())
+	case PGNumeric:
+		if v.Valid {
+			pb.Kind = stringKind(v.Numeric)
+		}
+		return pb, pgNumericType(), nil
+	case []PGNumeric:
+		if v != nil {
+			pb, err = encodeArray(len(v), func(i int) interface{} { return v[i] })
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		pt = listType(pgNumericType())
+	case PGJsonB:
+		if v.Valid {
+			b, err := json.Marshal(v.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+			pb.Kind = stringKind(string(b))
+		}
+		return pb, pgJsonbType(), nil
+	case []PGJsonB:
+		if v != nil {
+			pb, err = encodeArray(len(v), func(i int) interface{} { return v[i] })
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		pt = listType(pgJsonbType())
 	case *big.Rat:
 		switch LossOfPrecisionHandling {
 		case NumericError:
@@ -3408,6 +3653,11 @@ func encodeValue(v interface{}) (*proto3.Value, *sppb.Type, error) {
 			return encodeValue(nv)
 		}
 
+		// Check if the value is a protocol buffer message or enum.
+		if isProtoValue(v) {
+			return encodeProtoValue(v)
+		}
+
 		// Check if the value is a variant of a base type.
 		decodableType := getDecodableSpannerType(v, false)
 		if decodableType != spannerTypeUnknown && decodableType != spannerTypeInvalid {
@@ -3698,6 +3948,7 @@ func isSupportedMutationType(v interface{}) bool {
 		time.Time, *time.Time, []time.Time, []*time.Time, NullTime, []NullTime,
 		civil.Date, *civil.Date, []civil.Date, []*civil.Date, NullDate, []NullDate,
 		big.Rat, *big.Rat, []big.Rat, []*big.Rat, NullNumeric, []NullNumeric,
+		PGNumeric, []PGNumeric, PGJsonB, []PGJsonB,
 		GenericColumnValue:
 		return true
 	default:
@@ -3705,6 +3956,9 @@ func isSupportedMutationType(v interface{}) bool {
 		if _, ok := v.(Encoder); ok {
 			return true
 		}
+		if isProtoValue(v) {
+			return true
+		}
 
 		decodableType := getDecodableSpannerType(v, false)
 		return decodableType != spannerTypeUnknown && decodableType != spannerTypeInvalid
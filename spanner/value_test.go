@@ -331,6 +331,16 @@ func TestEncodeValue(t *testing.T) {
 		{[]NullJSON{{msg, true}, {msg, false}}, listProto(stringProto(jsonStr), nullProto()), listType(tJSON), "[]NullJSON"},
 		{NullJSON{[]Message{}, true}, stringProto(emptyArrayJSONStr), tJSON, "a json string with empty array to NullJSON"},
 		{NullJSON{ptrMsg, true}, stringProto(nullValueJSONStr), tJSON, "a json string with null value to NullJSON"},
+		// PG NUMERIC / PG JSONB
+		{PGNumeric{"123.456", true}, stringProto("123.456"), pgNumericType(), "PGNumeric with value"},
+		{PGNumeric{"NaN", true}, stringProto("NaN"), pgNumericType(), "PGNumeric with NaN"},
+		{PGNumeric{"", false}, nullProto(), pgNumericType(), "PGNumeric with null"},
+		{[]PGNumeric(nil), nullProto(), listType(pgNumericType()), "null []PGNumeric"},
+		{[]PGNumeric{{"123.456", true}, {"", false}}, listProto(stringProto("123.456"), nullProto()), listType(pgNumericType()), "[]PGNumeric"},
+		{PGJsonB{msg, true}, stringProto(jsonStr), pgJsonbType(), "PGJsonB with value"},
+		{PGJsonB{msg, false}, nullProto(), pgJsonbType(), "PGJsonB with null"},
+		{[]PGJsonB(nil), nullProto(), listType(pgJsonbType()), "null []PGJsonB"},
+		{[]PGJsonB{{msg, true}, {msg, false}}, listProto(stringProto(jsonStr), nullProto()), listType(pgJsonbType()), "[]PGJsonB"},
 		// TIMESTAMP / TIMESTAMP ARRAY
 		{t1, timeProto(t1), tTime, "time"},
 		{NullTime{t1, true}, timeProto(t1), tTime, "NullTime with value"},
@@ -1472,6 +1482,23 @@ func TestDecodeValue(t *testing.T) {
 		{desc: "decode ARRAY<JSON> to []NullJSON", proto: listProto(stringProto(jsonStr), stringProto(jsonStr), nullProto()), protoType: listType(jsonType()), want: []NullJSON{{unmarshalledJSONStruct, true}, {unmarshalledJSONStruct, true}, {}}},
 		{desc: "decode ARRAY<JSON> to NullJSON", proto: listProto(stringProto(jsonStr), nullProto(), stringProto("true")), protoType: listType(jsonType()), want: NullJSON{unmarshalledJSONArray, true}},
 		{desc: "decode NULL to []NullJSON", proto: nullProto(), protoType: listType(jsonType()), want: []NullJSON(nil)},
+		// PG NUMERIC
+		{desc: "decode PG NUMERIC to PGNumeric", proto: stringProto("123.456"), protoType: pgNumericType(), want: PGNumeric{"123.456", true}},
+		{desc: "decode PG NUMERIC NaN to PGNumeric", proto: stringProto("NaN"), protoType: pgNumericType(), want: PGNumeric{"NaN", true}},
+		{desc: "decode NULL to PGNumeric", proto: nullProto(), protoType: pgNumericType(), want: PGNumeric{}},
+		{desc: "decode ARRAY<PG NUMERIC> to []PGNumeric", proto: listProto(stringProto("123.456"), stringProto("NaN"), nullProto()), protoType: listType(pgNumericType()), want: []PGNumeric{{"123.456", true}, {"NaN", true}, {}}},
+		{desc: "decode NULL to []PGNumeric", proto: nullProto(), protoType: listType(pgNumericType()), want: []PGNumeric(nil)},
+		{desc: "decode STRING to PGNumeric", proto: stringProto("123.456"), protoType: stringType(), want: PGNumeric{}, wantErr: true},
+		// PG JSONB
+		{desc: "decode PG JSONB to PGJsonB", proto: stringProto(jsonStr), protoType: pgJsonbType(), want: PGJsonB{unmarshalledJSONStruct, true}},
+		{desc: "decode NULL to PGJsonB", proto: nullProto(), protoType: pgJsonbType(), want: PGJsonB{}},
+		{desc: "decode an invalid json string to PGJsonB", proto: stringProto(invalidJSONStr), protoType: pgJsonbType(), want: PGJsonB{}, wantErr: true},
+		{desc: "decode ARRAY<PG JSONB> to []PGJsonB", proto: listProto(stringProto(jsonStr), nullProto()), protoType: listType(pgJsonbType()), want: []PGJsonB{{unmarshalledJSONStruct, true}, {}}},
+		{desc: "decode NULL to []PGJsonB", proto: nullProto(), protoType: listType(pgJsonbType()), want: []PGJsonB(nil)},
+		// PG OID
+		{desc: "decode PG OID to int64", proto: intProto(705), protoType: pgOidType(), want: int64(705)},
+		{desc: "decode PG OID to NullInt64", proto: intProto(705), protoType: pgOidType(), want: NullInt64{705, true}},
+		{desc: "decode NULL PG OID to NullInt64", proto: nullProto(), protoType: pgOidType(), want: NullInt64{}},
 		// TIMESTAMP
 		{desc: "decode TIMESTAMP to time.Time", proto: timeProto(t1), protoType: timeType(), want: t1},
 		{desc: "decode TIMESTAMP to NullTime", proto: timeProto(t1), protoType: timeType(), want: NullTime{t1, true}},
@@ -0,0 +1,200 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+const defaultBatchConcurrency = 16
+
+// Batch returns a new Batch for operations on objects in the bucket.
+func (b *BucketHandle) Batch() *Batch {
+	return &Batch{b: b}
+}
+
+// A Batch performs many object operations in a bucket with bounded concurrency
+// and an optional rate limit, so that large bulk changes such as deleting every
+// object under a prefix don't need to be orchestrated by the caller.
+//
+// Operations are queued with Delete, Update, SetACL and DeleteACL, and run by
+// Run. Each operation is an individual request that is retried according to
+// the bucket's retry configuration; Batch does not use the JSON API batch
+// endpoint. A Batch must not be modified while Run is in progress.
+type Batch struct {
+	// Concurrency is the maximum number of operations in flight at the same
+	// time. The default is 16.
+	Concurrency int
+
+	// RateLimit is the maximum number of operations started per second. Zero
+	// means no limit.
+	RateLimit float64
+
+	b   *BucketHandle
+	ops []batchOp
+}
+
+type batchOp struct {
+	object string
+	op     BatchOp
+	run    func(ctx context.Context, o *ObjectHandle) error
+}
+
+// BatchOp identifies the kind of an operation in a Batch.
+type BatchOp string
+
+const (
+	// BatchDelete is an operation queued by Batch.Delete.
+	BatchDelete BatchOp = "delete"
+	// BatchUpdate is an operation queued by Batch.Update.
+	BatchUpdate BatchOp = "update"
+	// BatchSetACL is an operation queued by Batch.SetACL.
+	BatchSetACL BatchOp = "setACL"
+	// BatchDeleteACL is an operation queued by Batch.DeleteACL.
+	BatchDeleteACL BatchOp = "deleteACL"
+)
+
+// BatchResult is the outcome of one operation of a Batch.
+type BatchResult struct {
+	// Object is the name of the object the operation applied to.
+	Object string
+	// Op is the kind of operation.
+	Op BatchOp
+	// Err is the error returned by the operation, or nil if it succeeded.
+	Err error
+}
+
+// Len returns the number of queued operations.
+func (bt *Batch) Len() int {
+	return len(bt.ops)
+}
+
+// Delete queues the deletion of the named object.
+func (bt *Batch) Delete(object string) *Batch {
+	return bt.add(object, BatchDelete, func(ctx context.Context, o *ObjectHandle) error {
+		return o.Delete(ctx)
+	})
+}
+
+// Update queues an update of the named object's attributes, as by
+// ObjectHandle.Update.
+func (bt *Batch) Update(object string, uattrs ObjectAttrsToUpdate) *Batch {
+	return bt.add(object, BatchUpdate, func(ctx context.Context, o *ObjectHandle) error {
+		_, err := o.Update(ctx, uattrs)
+		return err
+	})
+}
+
+// SetACL queues setting the role for entity in the named object's ACL.
+func (bt *Batch) SetACL(object string, entity ACLEntity, role ACLRole) *Batch {
+	return bt.add(object, BatchSetACL, func(ctx context.Context, o *ObjectHandle) error {
+		return o.ACL().Set(ctx, entity, role)
+	})
+}
+
+// DeleteACL queues deleting entity from the named object's ACL.
+func (bt *Batch) DeleteACL(object string, entity ACLEntity) *Batch {
+	return bt.add(object, BatchDeleteACL, func(ctx context.Context, o *ObjectHandle) error {
+		return o.ACL().Delete(ctx, entity)
+	})
+}
+
+func (bt *Batch) add(object string, op BatchOp, run func(context.Context, *ObjectHandle) error) *Batch {
+	bt.ops = append(bt.ops, batchOp{object: object, op: op, run: run})
+	return bt
+}
+
+// Run performs the queued operations and returns their results, in the order
+// the operations were queued. If any operation failed, Run also returns an
+// error that summarizes the failures. If ctx is done before all operations
+// have started, the remaining operations fail with ctx.Err().
+func (bt *Batch) Run(ctx context.Context) (results []BatchResult, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Batch.Run")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	concurrency := bt.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	var tick <-chan time.Time
+	if bt.RateLimit > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / bt.RateLimit))
+		defer t.Stop()
+		tick = t.C
+	}
+
+	results = make([]BatchResult, len(bt.ops))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, op := range bt.ops {
+		results[i] = BatchResult{Object: op.object, Op: op.op}
+		if err := bt.wait(ctx, sem, tick, i == 0); err != nil {
+			results[i].Err = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int, op batchOp) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i].Err = op.run(ctx, bt.b.Object(op.object))
+		}(i, op)
+	}
+	wg.Wait()
+
+	var failed int
+	var first *BatchResult
+	for i := range results {
+		if results[i].Err != nil {
+			failed++
+			if first == nil {
+				first = &results[i]
+			}
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("storage: %d of %d batch operations failed; first error: %s %q: %v",
+			failed, len(results), first.Op, first.Object, first.Err)
+	}
+	return results, nil
+}
+
+// wait blocks until an operation may start: a concurrency slot is acquired
+// and, unless this is the first operation, the rate limiter has ticked.
+func (bt *Batch) wait(ctx context.Context, sem chan struct{}, tick <-chan time.Time, first bool) error {
+	if tick != nil && !first {
+		select {
+		case <-tick:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		<-sem
+		return err
+	}
+	return nil
+}
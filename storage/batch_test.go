@@ -0,0 +1,167 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/api/option"
+)
+
+// batchServer records object requests and fails those for objects whose
+// name starts with "missing".
+type batchServer struct {
+	mu       sync.Mutex
+	inFlight int
+	maxIn    int
+	requests []string
+}
+
+func (s *batchServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxIn {
+		s.maxIn = s.inFlight
+	}
+	s.requests = append(s.requests, r.Method+" "+strings.TrimPrefix(r.URL.Path, "/storage/v1/b/b/o/"))
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	if strings.HasPrefix(r.URL.Path, "/storage/v1/b/b/o/missing") {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case "DELETE":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func newBatchTestBucket(t *testing.T, s *batchServer) (*BucketHandle, func()) {
+	t.Helper()
+	hc, close := newTestServer(s.handle)
+	c, err := NewClient(context.Background(), option.WithHTTPClient(hc))
+	if err != nil {
+		close()
+		t.Fatal(err)
+	}
+	return c.Bucket("b"), close
+}
+
+func TestBatch(t *testing.T) {
+	s := &batchServer{}
+	b, close := newBatchTestBucket(t, s)
+	defer close()
+
+	bt := b.Batch()
+	bt.Concurrency = 2
+	for i := 0; i < 6; i++ {
+		bt.Delete(fmt.Sprintf("obj%d", i))
+	}
+	bt.Update("obj6", ObjectAttrsToUpdate{ContentType: "text/plain"}).
+		SetACL("obj7", AllUsers, RoleReader).
+		DeleteACL("obj8", AllUsers).
+		Delete("missing")
+	if got, want := bt.Len(), 10; got != want {
+		t.Fatalf("Len: got %d, want %d", got, want)
+	}
+
+	results, err := bt.Run(context.Background())
+	if err == nil {
+		t.Error("Run: got nil error, want non-nil")
+	}
+	var want []BatchResult
+	for i := 0; i < 6; i++ {
+		want = append(want, BatchResult{Object: fmt.Sprintf("obj%d", i), Op: BatchDelete})
+	}
+	want = append(want,
+		BatchResult{Object: "obj6", Op: BatchUpdate},
+		BatchResult{Object: "obj7", Op: BatchSetACL},
+		BatchResult{Object: "obj8", Op: BatchDeleteACL},
+		BatchResult{Object: "missing", Op: BatchDelete, Err: ErrObjectNotExist},
+	)
+	if diff := cmp.Diff(want, results, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("results mismatch (-want +got):\n%s", diff)
+	}
+	if s.maxIn > bt.Concurrency {
+		t.Errorf("got %d requests in flight, want at most %d", s.maxIn, bt.Concurrency)
+	}
+	wantReqs := []string{"PATCH obj6", "PUT obj7/acl/allUsers", "DELETE obj8/acl/allUsers"}
+	for _, req := range wantReqs {
+		var found bool
+		for _, got := range s.requests {
+			found = found || got == req
+		}
+		if !found {
+			t.Errorf("request %q not sent; got %v", req, s.requests)
+		}
+	}
+}
+
+func TestBatchRateLimit(t *testing.T) {
+	s := &batchServer{}
+	b, close := newBatchTestBucket(t, s)
+	defer close()
+
+	bt := b.Batch()
+	bt.RateLimit = 50
+	for i := 0; i < 5; i++ {
+		bt.Delete(fmt.Sprintf("obj%d", i))
+	}
+	start := time.Now()
+	if _, err := bt.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// Four waits of 20ms between the five operations.
+	if got, min := time.Since(start), 80*time.Millisecond; got < min {
+		t.Errorf("Run took %v, want at least %v", got, min)
+	}
+}
+
+func TestBatchCanceled(t *testing.T) {
+	s := &batchServer{}
+	b, close := newBatchTestBucket(t, s)
+	defer close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := b.Batch().Delete("a").Delete("b").Run(ctx)
+	if err == nil {
+		t.Error("Run: got nil error, want non-nil")
+	}
+	for _, r := range results {
+		if r.Err != context.Canceled {
+			t.Errorf("%s: got error %v, want %v", r.Object, r.Err, context.Canceled)
+		}
+	}
+	if len(s.requests) != 0 {
+		t.Errorf("got requests %v, want none", s.requests)
+	}
+}
@@ -60,17 +60,20 @@ func (c *Client) Bucket(name string) *BucketHandle {
 		c:    c,
 		name: name,
 		acl: ACLHandle{
-			c:      c,
-			bucket: name,
-			retry:  retry,
+			c:           c,
+			bucket:      name,
+			userProject: c.userProject,
+			retry:       retry,
 		},
 		defaultObjectACL: ACLHandle{
-			c:         c,
-			bucket:    name,
-			isDefault: true,
-			retry:     retry,
+			c:           c,
+			bucket:      name,
+			isDefault:   true,
+			userProject: c.userProject,
+			retry:       retry,
 		},
-		retry: retry,
+		userProject: c.userProject,
+		retry:       retry,
 	}
 }
 
@@ -82,6 +85,9 @@ func (b *BucketHandle) Create(ctx context.Context, projectID string, attrs *Buck
 
 	var bkt *raw.Bucket
 	if attrs != nil {
+		if attrs.Lifecycle.usesJSONOnlyFields() {
+			return errLifecycleJSONOnly
+		}
 		bkt = attrs.toRawBucket()
 	} else {
 		bkt = &raw.Bucket{}
@@ -94,13 +100,20 @@ func (b *BucketHandle) Create(ctx context.Context, projectID string, attrs *Buck
 	}
 	req := b.c.raw.Buckets.Insert(projectID, bkt)
 	setClientHeader(req.Header())
+	if b.userProject != "" {
+		req.UserProject(b.userProject)
+	}
 	if attrs != nil && attrs.PredefinedACL != "" {
 		req.PredefinedAcl(attrs.PredefinedACL)
 	}
 	if attrs != nil && attrs.PredefinedDefaultObjectACL != "" {
 		req.PredefinedDefaultObjectAcl(attrs.PredefinedDefaultObjectACL)
 	}
-	return run(ctx, func() error { _, err := req.Context(ctx).Do(); return err }, b.retry, true)
+	var opts []googleapi.CallOption
+	if attrs != nil && attrs.EnableObjectRetention {
+		opts = append(opts, googleapi.QueryParameter("enableObjectRetention", "true"))
+	}
+	return run(ctx, func() error { _, err := req.Context(ctx).Do(opts...); return err }, b.retry, true)
 }
 
 // Delete deletes the Bucket.
@@ -209,6 +222,9 @@ func (b *BucketHandle) Update(ctx context.Context, uattrs BucketAttrsToUpdate) (
 	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Bucket.Create")
 	defer func() { trace.EndSpan(ctx, err) }()
 
+	if uattrs.Lifecycle != nil && uattrs.Lifecycle.usesJSONOnlyFields() {
+		return nil, errLifecycleJSONOnly
+	}
 	req, err := b.newPatchCall(&uattrs)
 	if err != nil {
 		return nil, err
@@ -266,6 +282,7 @@ func (b *BucketHandle) newPatchCall(uattrs *BucketAttrsToUpdate) (*raw.BucketsPa
 // If you do not want these fields set for you, you may pass them in through opts or use
 // SignedURL(bucket, name string, opts *SignedURLOptions) instead.
 func (b *BucketHandle) SignedURL(object string, opts *SignedURLOptions) (string, error) {
+	opts = opts.forEmulator(b.c.emulator)
 	if opts.GoogleAccessID != "" && (opts.SignBytes != nil || len(opts.PrivateKey) > 0) {
 		return SignedURL(b.name, object, opts)
 	}
@@ -315,6 +332,7 @@ func (b *BucketHandle) SignedURL(object string, opts *SignedURLOptions) (string,
 // If you do not want these fields set for you, you may pass them in through opts or use
 // GenerateSignedPostPolicyV4(bucket, name string, opts *PostPolicyV4Options) instead.
 func (b *BucketHandle) GenerateSignedPostPolicyV4(object string, opts *PostPolicyV4Options) (*PostPolicyV4, error) {
+	opts = opts.forEmulator(b.c.emulator)
 	if opts.GoogleAccessID != "" && (opts.SignRawBytes != nil || opts.SignBytes != nil || len(opts.PrivateKey) > 0) {
 		return GenerateSignedPostPolicyV4(b.name, object, opts)
 	}
@@ -457,6 +475,13 @@ type BucketAttrs struct {
 	// for valid values.
 	PredefinedDefaultObjectACL string
 
+	// EnableObjectRetention enables retention configurations on individual
+	// objects in the bucket, see ObjectHandle.SetRetention. It should be set
+	// only when creating a bucket; object retention cannot be disabled once
+	// enabled. It is always false for BucketAttrs returned from the service;
+	// use BucketHandle.ObjectRetentionEnabled to check a bucket.
+	EnableObjectRetention bool
+
 	// Location is the location of the bucket. It defaults to "US".
 	Location string
 
@@ -530,6 +555,22 @@ type BucketAttrs struct {
 	// See https://cloud.google.com/storage/docs/managing-turbo-replication for
 	// more information.
 	RPO RPO
+
+	// CustomPlacementConfig specifies the regions that make up a
+	// configurable dual-region bucket, with Location set to the multi-region
+	// code (e.g. "US") that contains them. It can only be set when the bucket
+	// is created. See
+	// https://cloud.google.com/storage/docs/locations#configurable for more
+	// information.
+	CustomPlacementConfig *CustomPlacementConfig
+}
+
+// CustomPlacementConfig holds the placement of data in a configurable
+// dual-region bucket.
+type CustomPlacementConfig struct {
+	// DataLocations is the list of regions in which data is placed, for
+	// example "US-EAST1" and "US-WEST1".
+	DataLocations []string
 }
 
 // BucketPolicyOnly is an alias for UniformBucketLevelAccess.
@@ -642,6 +683,13 @@ const (
 	// SetStorageClassAction changes the storage class of live and/or archived
 	// objects.
 	SetStorageClassAction = "SetStorageClass"
+
+	// AbortIncompleteMPUAction is a lifecycle action that aborts an incomplete
+	// multipart upload when the multipart upload meets the conditions
+	// specified in the lifecycle rule. The AgeInDays condition is the age of
+	// the upload. Rules with this action must be set with
+	// BucketHandle.SetLifecycle.
+	AbortIncompleteMPUAction = "AbortIncompleteMultipartUpload"
 )
 
 // LifecycleRule is a lifecycle configuration rule.
@@ -662,9 +710,10 @@ type LifecycleRule struct {
 type LifecycleAction struct {
 	// Type is the type of action to take on matching objects.
 	//
-	// Acceptable values are "Delete" to delete matching objects and
+	// Acceptable values are "Delete" to delete matching objects,
 	// "SetStorageClass" to set the storage class defined in StorageClass on
-	// matching objects.
+	// matching objects, and "AbortIncompleteMultipartUpload" to abort matching
+	// multipart uploads.
 	Type string
 
 	// StorageClass is the storage class to set on matching objects if the Action
@@ -722,6 +771,14 @@ type LifecycleCondition struct {
 	// Values include "STANDARD", "NEARLINE", "COLDLINE" and "ARCHIVE".
 	MatchesStorageClasses []string
 
+	// MatchesPrefix is the condition matching objects whose names begin with
+	// any of the prefixes, and MatchesSuffix the condition matching objects
+	// whose names end with any of the suffixes. Rules with these conditions
+	// must be set with BucketHandle.SetLifecycle, and they are only returned
+	// by BucketHandle.Lifecycle.
+	MatchesPrefix []string
+	MatchesSuffix []string
+
 	// NoncurrentTimeBefore is the noncurrent timestamp of the object. This
 	// condition is satisfied when an object's noncurrent timestamp is before
 	// midnight of the specified date in UTC.
@@ -798,6 +855,7 @@ func newBucket(b *raw.Bucket) (*BucketAttrs, error) {
 		LocationType:             b.LocationType,
 		ProjectNumber:            b.ProjectNumber,
 		RPO:                      toRPO(b),
+		CustomPlacementConfig:    toCustomPlacementConfig(b.CustomPlacementConfig),
 	}, nil
 }
 
@@ -835,22 +893,23 @@ func (b *BucketAttrs) toRawBucket() *raw.Bucket {
 		}
 	}
 	return &raw.Bucket{
-		Name:             b.Name,
-		Location:         b.Location,
-		StorageClass:     b.StorageClass,
-		Acl:              toRawBucketACL(b.ACL),
-		DefaultObjectAcl: toRawObjectACL(b.DefaultObjectACL),
-		Versioning:       v,
-		Labels:           labels,
-		Billing:          bb,
-		Lifecycle:        toRawLifecycle(b.Lifecycle),
-		RetentionPolicy:  b.RetentionPolicy.toRawRetentionPolicy(),
-		Cors:             toRawCORS(b.CORS),
-		Encryption:       b.Encryption.toRawBucketEncryption(),
-		Logging:          b.Logging.toRawBucketLogging(),
-		Website:          b.Website.toRawBucketWebsite(),
-		IamConfiguration: bktIAM,
-		Rpo:              b.RPO.String(),
+		Name:                  b.Name,
+		Location:              b.Location,
+		StorageClass:          b.StorageClass,
+		Acl:                   toRawBucketACL(b.ACL),
+		DefaultObjectAcl:      toRawObjectACL(b.DefaultObjectACL),
+		Versioning:            v,
+		Labels:                labels,
+		Billing:               bb,
+		Lifecycle:             toRawLifecycle(b.Lifecycle),
+		RetentionPolicy:       b.RetentionPolicy.toRawRetentionPolicy(),
+		Cors:                  toRawCORS(b.CORS),
+		Encryption:            b.Encryption.toRawBucketEncryption(),
+		Logging:               b.Logging.toRawBucketLogging(),
+		Website:               b.Website.toRawBucketWebsite(),
+		IamConfiguration:      bktIAM,
+		Rpo:                   b.RPO.String(),
+		CustomPlacementConfig: b.CustomPlacementConfig.toRawCustomPlacement(),
 	}
 }
 
@@ -1381,6 +1440,24 @@ func toBucketWebsite(w *raw.BucketWebsite) *BucketWebsite {
 	}
 }
 
+func (p *CustomPlacementConfig) toRawCustomPlacement() *raw.BucketCustomPlacementConfig {
+	if p == nil {
+		return nil
+	}
+	return &raw.BucketCustomPlacementConfig{
+		DataLocations: p.DataLocations,
+	}
+}
+
+func toCustomPlacementConfig(p *raw.BucketCustomPlacementConfig) *CustomPlacementConfig {
+	if p == nil {
+		return nil
+	}
+	return &CustomPlacementConfig{
+		DataLocations: p.DataLocations,
+	}
+}
+
 func toBucketPolicyOnly(b *raw.BucketIamConfiguration) BucketPolicyOnly {
 	if b == nil || b.BucketPolicyOnly == nil || !b.BucketPolicyOnly.Enabled {
 		return BucketPolicyOnly{}
@@ -1474,8 +1551,9 @@ func (b *BucketHandle) Retryer(opts ...RetryOption) *BucketHandle {
 	b2 := *b
 	var retry *retryConfig
 	if b.retry != nil {
-		// merge the options with the existing retry
-		retry = b.retry
+		// merge the options with a copy of the existing retry, so that the
+		// handle this one was derived from is not affected.
+		retry = b.retry.clone()
 	} else {
 		retry = &retryConfig{}
 	}
@@ -1553,10 +1631,17 @@ func (it *ObjectIterator) fetch(pageSize int, pageToken string) (string, error)
 	if pageSize > 0 {
 		req.MaxResults(int64(pageSize))
 	}
+	var opts []googleapi.CallOption
+	if it.query.MatchGlob != "" {
+		opts = append(opts, googleapi.QueryParameter("matchGlob", it.query.MatchGlob))
+	}
+	if it.query.SoftDeleted {
+		opts = append(opts, googleapi.QueryParameter("softDeleted", "true"))
+	}
 	var resp *raw.Objects
 	var err error
 	err = run(it.ctx, func() error {
-		resp, err = req.Context(it.ctx).Do()
+		resp, err = req.Context(it.ctx).Do(opts...)
 		return err
 	}, it.bucket.retry, true)
 	if err != nil {
@@ -1635,6 +1720,9 @@ func (it *BucketIterator) fetch(pageSize int, pageToken string) (token string, e
 	req.Projection("full")
 	req.Prefix(it.Prefix)
 	req.PageToken(pageToken)
+	if it.client.userProject != "" {
+		req.UserProject(it.client.userProject)
+	}
 	if pageSize > 0 {
 		req.MaxResults(int64(pageSize))
 	}
@@ -46,6 +46,7 @@ func TestBucketAttrsToRawBucket(t *testing.T) {
 		PublicAccessPrevention:   PublicAccessPreventionEnforced,
 		VersioningEnabled:        false,
 		RPO:                      RPOAsyncTurbo,
+		CustomPlacementConfig:    &CustomPlacementConfig{DataLocations: []string{"US-EAST1", "US-WEST1"}},
 		// should be ignored:
 		MetaGeneration: 39,
 		Created:        time.Now(),
@@ -128,7 +129,10 @@ func TestBucketAttrsToRawBucket(t *testing.T) {
 		},
 		Versioning: nil, // ignore VersioningEnabled if false
 		Rpo:        rpoAsyncTurbo,
-		Labels:     map[string]string{"label": "value"},
+		CustomPlacementConfig: &raw.BucketCustomPlacementConfig{
+			DataLocations: []string{"US-EAST1", "US-WEST1"},
+		},
+		Labels: map[string]string{"label": "value"},
 		Cors: []*raw.BucketCors{
 			{
 				MaxAgeSeconds:  3600,
@@ -669,6 +673,10 @@ func TestNewBucket(t *testing.T) {
 		Logging:       &raw.BucketLogging{LogBucket: "lb", LogObjectPrefix: "p"},
 		Website:       &raw.BucketWebsite{MainPageSuffix: "mps", NotFoundPage: "404"},
 		ProjectNumber: 123231313,
+		Rpo:           rpoAsyncTurbo,
+		CustomPlacementConfig: &raw.BucketCustomPlacementConfig{
+			DataLocations: []string{"US-EAST1", "US-WEST1"},
+		},
 	}
 	want := &BucketAttrs{
 		Name:                  "name",
@@ -719,6 +727,10 @@ func TestNewBucket(t *testing.T) {
 		DefaultObjectACL: nil,
 		LocationType:     "dual-region",
 		ProjectNumber:    123231313,
+		RPO:              RPOAsyncTurbo,
+		CustomPlacementConfig: &CustomPlacementConfig{
+			DataLocations: []string{"US-EAST1", "US-WEST1"},
+		},
 	}
 	got, err := newBucket(rb)
 	if err != nil {
@@ -0,0 +1,423 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+const (
+	// maxComposeSources is the maximum number of source objects accepted by a
+	// single compose request.
+	maxComposeSources = 32
+
+	defaultPartSize          = 32 << 20
+	defaultUploadConcurrency = 8
+)
+
+// NewCompositeUploader returns a CompositeUploader that uploads the first size
+// bytes of r to o as a parallel composite upload. You can immediately call
+// Run on the returned CompositeUploader, or you can configure it first.
+func (o *ObjectHandle) NewCompositeUploader(r io.ReaderAt, size int64) *CompositeUploader {
+	return &CompositeUploader{dst: o, r: r, size: size}
+}
+
+// A CompositeUploader uploads a large source by splitting it into parts,
+// uploading the parts concurrently as temporary objects in the destination
+// bucket, and composing them into the destination object. The temporary
+// objects are deleted once the upload finishes, whether or not it succeeded.
+//
+// Each part is uploaded with its CRC32C so that the service rejects corrupted
+// parts, and the final compose is validated against the CRC32C of the whole
+// source.
+//
+// See https://cloud.google.com/storage/docs/parallel-composite-uploads for the
+// trade-offs of composite objects.
+type CompositeUploader struct {
+	// ObjectAttrs are optional attributes to set on the destination object.
+	// Any attributes must be initialized before calling Run. Nil or
+	// zero-valued attributes are ignored. The CRC32C field is ignored and
+	// computed from the source.
+	ObjectAttrs
+
+	// PartSize is the size in bytes of each part. If the source would be
+	// split into more parts than a single compose request accepts, the part
+	// size is increased accordingly. The default is 32 MiB.
+	PartSize int64
+
+	// Concurrency is the maximum number of parts uploaded at the same time.
+	// The default is 8.
+	Concurrency int
+
+	// TempPrefix is the prefix of the names of the temporary part objects.
+	// The default is derived from the destination object name and the time
+	// the upload started.
+	TempPrefix string
+
+	dst  *ObjectHandle
+	r    io.ReaderAt
+	size int64
+}
+
+type uploadedPart struct {
+	obj *ObjectHandle
+	crc uint32
+	len int64
+}
+
+// Run performs the upload.
+func (u *CompositeUploader) Run(ctx context.Context) (attrs *ObjectAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.CompositeUploader.Run")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if err := u.dst.validate(); err != nil {
+		return nil, err
+	}
+	if u.size < 0 {
+		return nil, fmt.Errorf("storage: invalid composite upload size %d", u.size)
+	}
+	if u.dst.encryptionKey != nil {
+		return nil, errors.New("storage: composite uploads do not support customer-supplied encryption keys")
+	}
+
+	partSize := u.PartSize
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+	if min := (u.size + maxComposeSources - 1) / maxComposeSources; partSize < min {
+		partSize = min
+	}
+	nParts := int((u.size + partSize - 1) / partSize)
+	if nParts == 0 {
+		// Compose needs at least one source, so upload an empty part.
+		nParts = 1
+	}
+	concurrency := u.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultUploadConcurrency
+	}
+	prefix := u.TempPrefix
+	if prefix == "" {
+		prefix = fmt.Sprintf("%s.parts-%x/", u.dst.object, time.Now().UnixNano())
+	}
+
+	parts := make([]*uploadedPart, nParts)
+	defer func() {
+		// Clean up with a fresh context, so parts are deleted even if the
+		// upload was canceled.
+		u.deleteParts(context.Background(), parts)
+	}()
+
+	// Once a part fails, no new parts are started, but the parts being
+	// uploaded are not canceled: a canceled upload can still create its
+	// object after the request was abandoned, which would leak it. Waiting
+	// for all of them before returning ensures that the deferred cleanup
+	// sees every part object that can exist.
+	var (
+		sem      = make(chan struct{}, concurrency)
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	for i := 0; i < nParts; i++ {
+		off := int64(i) * partSize
+		length := partSize
+		if off+length > u.size {
+			length = u.size - off
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil || failed() {
+			break
+		}
+		wg.Add(1)
+		go func(i int, off, length int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			// Record the part before uploading it, so that it is cleaned up
+			// even if the upload fails after the object was created.
+			parts[i] = u.newPart(fmt.Sprintf("%s%05d", prefix, i), length)
+			if err := u.uploadPart(ctx, parts[i], off); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i, off, length)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	srcs := make([]*ObjectHandle, nParts)
+	crc := parts[0].crc
+	for i, p := range parts {
+		srcs[i] = p.obj
+		if i > 0 {
+			crc = crc32cCombine(crc, p.crc, p.len)
+		}
+	}
+	c := u.dst.ComposerFrom(srcs...)
+	c.ObjectAttrs = u.ObjectAttrs
+	c.CRC32C = crc
+	c.SendCRC32C = true
+	return c.Run(ctx)
+}
+
+// newPart returns a part of the given length to be uploaded to a temporary
+// object with the given name.
+func (u *CompositeUploader) newPart(name string, length int64) *uploadedPart {
+	return &uploadedPart{obj: tempObject(u.dst, name), len: length}
+}
+
+// tempObject returns a handle for a temporary object with the given name in
+// dst's bucket, billed to the same user project and retried in the same way
+// as dst.
+func tempObject(dst *ObjectHandle, name string) *ObjectHandle {
+	b := dst.c.Bucket(dst.bucket)
+	if dst.userProject != "" {
+		b = b.UserProject(dst.userProject)
+	}
+	o := b.Object(name)
+	if dst.retry != nil {
+		o.retry = dst.retry
+	}
+	return o
+}
+
+// uploadPart uploads the bytes of the source starting at off to the part's
+// object. On success, the part's object is pinned to the uploaded generation.
+func (u *CompositeUploader) uploadPart(ctx context.Context, p *uploadedPart, off int64) error {
+	h := crc32.New(crc32cTable)
+	if _, err := io.Copy(h, io.NewSectionReader(u.r, off, p.len)); err != nil {
+		return err
+	}
+	// The part must not already exist, which also makes the upload safe to
+	// retry.
+	w := p.obj.If(Conditions{DoesNotExist: true}).NewWriter(ctx)
+	w.CRC32C = h.Sum32()
+	w.SendCRC32C = true
+	w.ContentType = u.ContentType
+	if _, err := io.Copy(w, io.NewSectionReader(u.r, off, p.len)); err != nil {
+		w.CloseWithError(err)
+		if w.opened {
+			// Wait for the upload to stop, so that it cannot create the
+			// part's object after the part has been cleaned up.
+			<-w.donec
+		}
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	p.obj = p.obj.Generation(w.Attrs().Generation)
+	p.crc = w.CRC32C
+	return nil
+}
+
+// deleteParts deletes the parts. Errors are ignored, since the parts are not
+// needed once the upload has finished and some may never have been created.
+func (u *CompositeUploader) deleteParts(ctx context.Context, parts []*uploadedPart) {
+	var objs []*ObjectHandle
+	for _, p := range parts {
+		if p != nil {
+			objs = append(objs, p.obj)
+		}
+	}
+	deleteTemps(ctx, objs)
+}
+
+// deleteTemps deletes temporary objects concurrently, ignoring errors.
+func deleteTemps(ctx context.Context, objs []*ObjectHandle) {
+	var wg sync.WaitGroup
+	for _, o := range objs {
+		wg.Add(1)
+		go func(o *ObjectHandle) {
+			defer wg.Done()
+			o.Delete(ctx)
+		}(o)
+	}
+	wg.Wait()
+}
+
+// MultiComposerFrom creates a MultiComposer that can compose any number of
+// srcs into dst. You can immediately call Run on the returned MultiComposer,
+// or you can configure it first.
+//
+// The sources must be in the same bucket as dst and must not have
+// customer-supplied encryption keys, as for ComposerFrom.
+func (dst *ObjectHandle) MultiComposerFrom(srcs ...*ObjectHandle) *MultiComposer {
+	return &MultiComposer{dst: dst, srcs: srcs}
+}
+
+// A MultiComposer composes source objects into a destination object, without
+// the limit of 32 sources of a single compose request. When there are more
+// sources than a compose request accepts, they are composed in groups into
+// temporary objects, which are composed in turn until a single request can
+// produce the destination. The temporary objects are deleted once Run
+// finishes, whether or not it succeeded.
+//
+// Each temporary object is created only if it does not already exist, and is
+// pinned to the generation that was created, so a concurrent writer cannot
+// change the data that ends up in the destination. Preconditions and
+// generations set on the source handles are applied to every compose that
+// reads them, and those set on the destination handle apply to the final
+// compose.
+type MultiComposer struct {
+	// ObjectAttrs are optional attributes to set on the destination object.
+	// Any attributes must be initialized before calling Run. Nil or
+	// zero-valued attributes are ignored.
+	ObjectAttrs
+
+	// SendCRC32C specifies whether to transmit a CRC32C field, as for
+	// Composer. The checksum is only validated by the final compose into the
+	// destination.
+	SendCRC32C bool
+
+	// Concurrency is the maximum number of compose requests in flight at the
+	// same time. The default is 8.
+	Concurrency int
+
+	// TempPrefix is the prefix of the names of the temporary objects. The
+	// default is derived from the destination object name and the time the
+	// compose started.
+	TempPrefix string
+
+	dst  *ObjectHandle
+	srcs []*ObjectHandle
+}
+
+// Run performs the compose operation.
+func (c *MultiComposer) Run(ctx context.Context) (attrs *ObjectAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.MultiComposer.Run")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if err := c.dst.validate(); err != nil {
+		return nil, err
+	}
+	if len(c.srcs) == 0 {
+		return nil, errors.New("storage: at least one source object must be specified")
+	}
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultUploadConcurrency
+	}
+	prefix := c.TempPrefix
+	if prefix == "" {
+		prefix = fmt.Sprintf("%s.compose-%x/", c.dst.object, time.Now().UnixNano())
+	}
+
+	var (
+		mu    sync.Mutex
+		temps []*ObjectHandle
+	)
+	defer func() {
+		// Clean up with a fresh context, so temporary objects are deleted
+		// even if the compose was canceled.
+		deleteTemps(context.Background(), temps)
+	}()
+
+	srcs := c.srcs
+	for level := 0; len(srcs) > maxComposeSources; level++ {
+		n := (len(srcs) + maxComposeSources - 1) / maxComposeSources
+		next := make([]*ObjectHandle, n)
+		levelCtx, cancel := context.WithCancel(ctx)
+		var (
+			sem      = make(chan struct{}, concurrency)
+			wg       sync.WaitGroup
+			errOnce  sync.Once
+			firstErr error
+		)
+		for i := 0; i < n; i++ {
+			group := srcs[i*maxComposeSources:]
+			if len(group) > maxComposeSources {
+				group = group[:maxComposeSources]
+			}
+			if len(group) == 1 {
+				// Nothing to compose; carry the source over to the next level.
+				next[i] = group[0]
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-levelCtx.Done():
+			}
+			if levelCtx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(i int, group []*ObjectHandle) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				// Record the temporary object before composing it, so that it
+				// is cleaned up even if the compose fails after the object was
+				// created.
+				tmp := tempObject(c.dst, fmt.Sprintf("%s%d-%05d", prefix, level, i))
+				mu.Lock()
+				temps = append(temps, tmp)
+				mu.Unlock()
+				comp := tmp.If(Conditions{DoesNotExist: true}).ComposerFrom(group...)
+				comp.ContentType = c.ContentType
+				attrs, err := comp.Run(levelCtx)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				next[i] = tmp.Generation(attrs.Generation)
+			}(i, group)
+		}
+		wg.Wait()
+		cancel()
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		srcs = next
+	}
+
+	comp := c.dst.ComposerFrom(srcs...)
+	comp.ObjectAttrs = c.ObjectAttrs
+	comp.SendCRC32C = c.SendCRC32C
+	return comp.Run(ctx)
+}
@@ -0,0 +1,317 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// fakeComposeServer implements the subset of the JSON API used by composite
+// uploads: multipart uploads, compose and delete.
+type fakeComposeServer struct {
+	mu      sync.Mutex
+	objects map[string]string
+	gen     int64
+	// composed is the number of source objects in each compose request.
+	composed []int
+	// failUpload makes uploads of part objects whose name has this suffix
+	// fail.
+	failUpload string
+}
+
+func newFakeComposeServer() *fakeComposeServer {
+	return &fakeComposeServer{objects: map[string]string{}}
+}
+
+func (f *fakeComposeServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	const objPrefix = "/storage/v1/b/b/o/"
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/upload/storage/v1/b/b/o":
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		var obj raw.Object
+		p, err := mr.NextPart()
+		if err == nil {
+			err = json.NewDecoder(p).Decode(&obj)
+		}
+		var media []byte
+		if err == nil {
+			p, err = mr.NextPart()
+		}
+		if err == nil {
+			media, err = ioutil.ReadAll(p)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		if f.failUpload != "" && strings.HasSuffix(name, f.failUpload) {
+			http.Error(w, "injected failure", http.StatusBadRequest)
+			return
+		}
+		if _, ok := f.objects[name]; ok && r.URL.Query().Get("ifGenerationMatch") == "0" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if want := encodeUint32(crc32.Checksum(media, crc32cTable)); obj.Crc32c != want {
+			http.Error(w, "bad crc32c", http.StatusBadRequest)
+			return
+		}
+		f.objects[name] = string(media)
+		f.writeObject(w, name)
+	case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/compose"):
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, objPrefix), "/compose")
+		var req raw.ComposeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.SourceObjects) > maxComposeSources {
+			http.Error(w, "too many sources", http.StatusBadRequest)
+			return
+		}
+		var sb strings.Builder
+		for _, src := range req.SourceObjects {
+			v, ok := f.objects[src.Name]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			sb.WriteString(v)
+		}
+		if req.Destination.Crc32c != "" && req.Destination.Crc32c != encodeUint32(crc32.Checksum([]byte(sb.String()), crc32cTable)) {
+			http.Error(w, "bad crc32c", http.StatusBadRequest)
+			return
+		}
+		f.composed = append(f.composed, len(req.SourceObjects))
+		f.objects[name] = sb.String()
+		f.writeObject(w, name)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, objPrefix):
+		name := strings.TrimPrefix(r.URL.Path, objPrefix)
+		if _, ok := f.objects[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, fmt.Sprintf("unexpected request %s %s", r.Method, r.URL), http.StatusBadRequest)
+	}
+}
+
+func (f *fakeComposeServer) writeObject(w http.ResponseWriter, name string) {
+	f.gen++
+	data := f.objects[name]
+	json.NewEncoder(w).Encode(&raw.Object{
+		Bucket:     "b",
+		Name:       name,
+		Generation: f.gen,
+		Size:       uint64(len(data)),
+		Crc32c:     encodeUint32(crc32.Checksum([]byte(data), crc32cTable)),
+	})
+}
+
+func (f *fakeComposeServer) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for n := range f.objects {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestCompositeUploader(t *testing.T) {
+	data := strings.Repeat("abcdefghij", 100)
+	for _, test := range []struct {
+		desc         string
+		partSize     int64
+		size         int64
+		wantComposed int
+	}{
+		{desc: "single part", size: int64(len(data)), wantComposed: 1},
+		{desc: "several parts", partSize: 300, size: int64(len(data)), wantComposed: 4},
+		{desc: "part size grows to the compose limit", partSize: 1, size: int64(len(data)), wantComposed: 32},
+		{desc: "empty source", size: 0, wantComposed: 1},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			f := newFakeComposeServer()
+			hc, close := newTestServer(f.handle)
+			defer close()
+			ctx := context.Background()
+			c, err := NewClient(ctx, option.WithHTTPClient(hc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			u := c.Bucket("b").Object("dst").NewCompositeUploader(strings.NewReader(data), test.size)
+			u.PartSize = test.partSize
+			u.Concurrency = 4
+			attrs, err := u.Run(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := attrs.Size, test.size; got != want {
+				t.Errorf("got size %d, want %d", got, want)
+			}
+			if got, want := f.objects["dst"], data[:test.size]; got != want {
+				t.Errorf("got content %q, want %q", got, want)
+			}
+			if diff := cmp.Diff([]int{test.wantComposed}, f.composed); diff != "" {
+				t.Errorf("compose requests mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"dst"}, f.names()); diff != "" {
+				t.Errorf("objects after upload mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompositeUploaderCleansUpOnError(t *testing.T) {
+	data := strings.Repeat("abcdefghij", 100)
+	f := newFakeComposeServer()
+	f.failUpload = "00002"
+	hc, close := newTestServer(f.handle)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := c.Bucket("b").Object("dst").NewCompositeUploader(strings.NewReader(data), int64(len(data)))
+	u.PartSize = 100
+	u.TempPrefix = "tmp/"
+	if _, err := u.Run(ctx); err == nil {
+		t.Fatal("got nil error, want non-nil")
+	}
+	if got := f.names(); len(got) != 0 {
+		t.Errorf("got objects %v after failed upload, want none", got)
+	}
+}
+
+func TestMultiComposer(t *testing.T) {
+	for _, test := range []struct {
+		desc         string
+		nSrcs        int
+		wantComposed []int
+	}{
+		{desc: "single request", nSrcs: 32, wantComposed: []int{32}},
+		{desc: "two levels", nSrcs: 100, wantComposed: []int{4, 4, 32, 32, 32}},
+		{desc: "single source carried over", nSrcs: 65, wantComposed: []int{3, 32, 32}},
+		{desc: "three levels", nSrcs: 1025, wantComposed: append(append([]int{2}, repeatInt(32, 32)...), 32)},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			f := newFakeComposeServer()
+			var srcNames []string
+			var want strings.Builder
+			for i := 0; i < test.nSrcs; i++ {
+				name := fmt.Sprintf("shard-%04d", i)
+				f.objects[name] = fmt.Sprintf("<%d>", i)
+				srcNames = append(srcNames, name)
+				want.WriteString(f.objects[name])
+			}
+			hc, close := newTestServer(f.handle)
+			defer close()
+			ctx := context.Background()
+			c, err := NewClient(ctx, option.WithHTTPClient(hc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := c.Bucket("b")
+			var srcs []*ObjectHandle
+			for _, name := range srcNames {
+				srcs = append(srcs, b.Object(name))
+			}
+			mc := b.Object("dst").MultiComposerFrom(srcs...)
+			mc.Concurrency = 4
+			if _, err := mc.Run(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if got := f.objects["dst"]; got != want.String() {
+				t.Errorf("got content %q, want %q", got, want.String())
+			}
+			sort.Ints(f.composed)
+			sort.Ints(test.wantComposed)
+			if diff := cmp.Diff(test.wantComposed, f.composed); diff != "" {
+				t.Errorf("compose requests mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(append([]string{"dst"}, srcNames...), f.names()); diff != "" {
+				t.Errorf("objects after compose mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMultiComposerCleansUpOnError(t *testing.T) {
+	f := newFakeComposeServer()
+	var srcs []*ObjectHandle
+	hc, close := newTestServer(f.handle)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("shard-%04d", i)
+		// Leave out a source in the last group, so the other groups are
+		// composed before the compose fails.
+		if i != 99 {
+			f.objects[name] = "x"
+		}
+		srcs = append(srcs, c.Bucket("b").Object(name))
+	}
+	mc := c.Bucket("b").Object("dst").MultiComposerFrom(srcs...)
+	mc.Concurrency = 1
+	mc.TempPrefix = "tmp/"
+	if _, err := mc.Run(ctx); err == nil {
+		t.Fatal("got nil error, want non-nil")
+	}
+	for _, name := range f.names() {
+		if !strings.HasPrefix(name, "shard-") {
+			t.Errorf("got object %q after failed compose, want only sources", name)
+		}
+	}
+}
+
+func repeatInt(v, n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = v
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/internal/trace"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	defaultCopyConcurrency = 16
+
+	// maxCopyResumes is the number of times the copy of one object is resumed
+	// from its last rewrite token after a transient error.
+	maxCopyResumes = 3
+)
+
+// CopyPrefixOptions configures Client.CopyPrefix.
+type CopyPrefixOptions struct {
+	// Concurrency is the maximum number of objects copied at the same time.
+	// The default is 16.
+	Concurrency int
+
+	// DestinationName maps the name of a source object to the name of its
+	// copy. If nil, copies have the same names as their sources.
+	DestinationName func(name string) string
+
+	// PreserveACL copies the ACL of each source object to its copy. By
+	// default copies get the default object ACL of the destination bucket.
+	// Object metadata such as Content-Type and custom metadata is always
+	// preserved.
+	PreserveACL bool
+
+	// ProgressFunc, if not nil, is called after each object has been copied
+	// or has failed. It may be called concurrently from multiple goroutines
+	// and should return quickly without blocking.
+	ProgressFunc func(CopyResult)
+}
+
+// CopyResult is the outcome of copying one object with Client.CopyPrefix.
+type CopyResult struct {
+	// Source is the name of the source object.
+	Source string
+	// Destination is the name of the copy.
+	Destination string
+	// Attrs are the attributes of the copy, if it was created.
+	Attrs *ObjectAttrs
+	// Err is the error copying the object, or nil if it succeeded.
+	Err error
+}
+
+// CopyPrefix copies every object in srcBucket whose name starts with prefix to
+// dstBucket, using server-side rewrites so no data passes through the client.
+// Opts may be nil.
+//
+// Each source object is pinned to the generation that was listed, and copies
+// that need several rewrite calls are resumed from their last rewrite token
+// after transient errors. Copies overwrite existing objects in dstBucket.
+//
+// CopyPrefix returns the results in listing order. If any copy failed,
+// CopyPrefix also returns an error that summarizes the failures. If listing
+// the source objects fails, CopyPrefix waits for the copies already started
+// and returns their results along with the listing error.
+func (c *Client) CopyPrefix(ctx context.Context, srcBucket, dstBucket, prefix string, opts *CopyPrefixOptions) (results []CopyResult, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Client.CopyPrefix")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if opts == nil {
+		opts = &CopyPrefixOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultCopyConcurrency
+	}
+	src := c.Bucket(srcBucket)
+	dst := c.Bucket(dstBucket)
+	q := &Query{Prefix: prefix, Projection: ProjectionNoACL}
+	if opts.PreserveACL {
+		q.Projection = ProjectionFull
+	}
+
+	// Results are appended only by this goroutine; each copy goroutine writes
+	// to its own element through a pointer, so the slice is never grown
+	// while a copy is running.
+	var pending []*CopyResult
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	it := src.Objects(ctx, q)
+	var listErr error
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			listErr = err
+			break
+		}
+		res := &CopyResult{Source: attrs.Name, Destination: attrs.Name}
+		if opts.DestinationName != nil {
+			res.Destination = opts.DestinationName(attrs.Name)
+		}
+		pending = append(pending, res)
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			res.Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(attrs *ObjectAttrs, res *CopyResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res.Attrs, res.Err = copyObject(ctx, src, dst, attrs, res.Destination, opts.PreserveACL)
+			if opts.ProgressFunc != nil {
+				opts.ProgressFunc(*res)
+			}
+		}(attrs, res)
+	}
+	wg.Wait()
+
+	results = make([]CopyResult, len(pending))
+	var failed int
+	var first *CopyResult
+	for i, res := range pending {
+		results[i] = *res
+		if res.Err != nil {
+			failed++
+			if first == nil {
+				first = res
+			}
+		}
+	}
+	if listErr != nil {
+		return results, fmt.Errorf("storage: listing objects with prefix %q in bucket %q: %v", prefix, srcBucket, listErr)
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("storage: %d of %d objects failed to copy; first error: %q: %v",
+			failed, len(results), first.Source, first.Err)
+	}
+	return results, nil
+}
+
+// copyObject copies the object described by attrs to the named object in dst,
+// resuming from the last rewrite token after transient errors.
+func copyObject(ctx context.Context, src, dst *BucketHandle, attrs *ObjectAttrs, name string, preserveACL bool) (*ObjectAttrs, error) {
+	cp := dst.Object(name).CopierFrom(src.Object(attrs.Name).Generation(attrs.Generation))
+	if preserveACL {
+		// Sending any destination attributes replaces those of the source,
+		// so the metadata is sent along with the ACL.
+		cp.ObjectAttrs = ObjectAttrs{
+			ContentType:        attrs.ContentType,
+			ContentLanguage:    attrs.ContentLanguage,
+			CacheControl:       attrs.CacheControl,
+			ContentEncoding:    attrs.ContentEncoding,
+			ContentDisposition: attrs.ContentDisposition,
+			Metadata:           attrs.Metadata,
+			CustomTime:         attrs.CustomTime,
+			ACL:                attrs.ACL,
+		}
+	}
+	for resumes := 0; ; resumes++ {
+		got, err := cp.Run(ctx)
+		if err == nil {
+			return got, nil
+		}
+		if cp.RewriteToken == "" || resumes == maxCopyResumes || !shouldRetry(err) || ctx.Err() != nil {
+			return nil, err
+		}
+	}
+}
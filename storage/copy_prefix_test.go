@@ -0,0 +1,178 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+)
+
+// copyServer lists the objects a/1, a/2, a/big and a/bad in bucket "src" and
+// serves rewrites of them. Rewriting a/big takes two calls, and the second
+// call fails once with a transient error; rewriting a/bad is forbidden.
+type copyServer struct {
+	mu       sync.Mutex
+	inFlight int
+	maxIn    int
+	failed   bool
+	rewrites []string
+	bodies   map[string]string
+	listings []string
+}
+
+func (s *copyServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	q := r.URL.Query()
+	if r.URL.Path == "/storage/v1/b/src/o" {
+		s.mu.Lock()
+		s.listings = append(s.listings, q.Get("prefix")+" "+q.Get("projection"))
+		s.mu.Unlock()
+		if q.Get("pageToken") == "" {
+			fmt.Fprint(w, `{"items": [{"name": "a/1", "generation": "11"}, {"name": "a/2", "generation": "12"}], "nextPageToken": "t"}`)
+			return
+		}
+		fmt.Fprint(w, `{"items": [{"name": "a/big", "generation": "13"}, {"name": "a/bad", "generation": "14",
+			"acl": [{"entity": "allUsers", "role": "READER"}]}]}`)
+		return
+	}
+
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.maxIn {
+		s.maxIn = s.inFlight
+	}
+	s.rewrites = append(s.rewrites, fmt.Sprintf("%s gen=%s token=%s", r.URL.Path, q.Get("sourceGeneration"), q.Get("rewriteToken")))
+	if len(body) > 0 {
+		s.bodies[r.URL.Path] = strings.TrimSpace(string(body))
+	}
+	fail := false
+	if strings.Contains(r.URL.Path, "/a/big/") && q.Get("rewriteToken") == "t1" && !s.failed {
+		s.failed, fail = true, true
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	dst := r.URL.Path[strings.Index(r.URL.Path, "/rewriteTo/b/dst/o/")+len("/rewriteTo/b/dst/o/"):]
+	switch {
+	case strings.Contains(r.URL.Path, "/a/bad/"):
+		http.Error(w, `{"error": {"code": 403, "message": "forbidden"}}`, http.StatusForbidden)
+	case fail:
+		http.Error(w, `{"error": {"code": 503, "message": "unavailable"}}`, http.StatusServiceUnavailable)
+	case strings.Contains(r.URL.Path, "/a/big/") && q.Get("rewriteToken") == "":
+		fmt.Fprint(w, `{"done": false, "rewriteToken": "t1", "totalBytesRewritten": "5", "objectSize": "10"}`)
+	default:
+		fmt.Fprintf(w, `{"done": true, "resource": {"bucket": "dst", "name": %q}}`, dst)
+	}
+}
+
+func TestCopyPrefix(t *testing.T) {
+	s := &copyServer{bodies: map[string]string{}}
+	hc, close := newTestServer(s.handle)
+	defer close()
+	c, err := NewClient(context.Background(), option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var progress []string
+	var mu sync.Mutex
+	results, err := c.CopyPrefix(context.Background(), "src", "dst", "a/", &CopyPrefixOptions{
+		Concurrency:     2,
+		DestinationName: func(name string) string { return "b/" + strings.TrimPrefix(name, "a/") },
+		ProgressFunc: func(r CopyResult) {
+			mu.Lock()
+			progress = append(progress, r.Source)
+			mu.Unlock()
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 objects failed") {
+		t.Errorf("got error %v, want a summary of one failure", err)
+	}
+
+	var got []string
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			got = append(got, fmt.Sprintf("%s -> %s: error", r.Source, r.Destination))
+		case r.Attrs == nil || r.Attrs.Name != r.Destination:
+			got = append(got, fmt.Sprintf("%s -> %s: bad attrs %+v", r.Source, r.Destination, r.Attrs))
+		default:
+			got = append(got, fmt.Sprintf("%s -> %s", r.Source, r.Destination))
+		}
+	}
+	want := []string{"a/1 -> b/1", "a/2 -> b/2", "a/big -> b/big", "a/bad -> b/bad: error"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("results mismatch (-want +got):\n%s", diff)
+	}
+	sort.Strings(progress)
+	if diff := cmp.Diff([]string{"a/1", "a/2", "a/bad", "a/big"}, progress); diff != "" {
+		t.Errorf("progress mismatch (-want +got):\n%s", diff)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxIn > 2 {
+		t.Errorf("got %d concurrent rewrites, want at most 2", s.maxIn)
+	}
+	if diff := cmp.Diff([]string{"a/ noAcl", "a/ noAcl"}, s.listings); diff != "" {
+		t.Errorf("listings mismatch (-want +got):\n%s", diff)
+	}
+	sort.Strings(s.rewrites)
+	wantRewrites := []string{
+		"/storage/v1/b/src/o/a/1/rewriteTo/b/dst/o/b/1 gen=11 token=",
+		"/storage/v1/b/src/o/a/2/rewriteTo/b/dst/o/b/2 gen=12 token=",
+		"/storage/v1/b/src/o/a/bad/rewriteTo/b/dst/o/b/bad gen=14 token=",
+		"/storage/v1/b/src/o/a/big/rewriteTo/b/dst/o/b/big gen=13 token=",
+		"/storage/v1/b/src/o/a/big/rewriteTo/b/dst/o/b/big gen=13 token=t1",
+		"/storage/v1/b/src/o/a/big/rewriteTo/b/dst/o/b/big gen=13 token=t1",
+	}
+	if diff := cmp.Diff(wantRewrites, s.rewrites); diff != "" {
+		t.Errorf("rewrites mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCopyPrefixPreserveACL(t *testing.T) {
+	s := &copyServer{bodies: map[string]string{}}
+	hc, close := newTestServer(s.handle)
+	defer close()
+	c, err := NewClient(context.Background(), option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.CopyPrefix(context.Background(), "src", "dst", "a/", &CopyPrefixOptions{PreserveACL: true})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if diff := cmp.Diff([]string{"a/ full", "a/ full"}, s.listings); diff != "" {
+		t.Errorf("listings mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := s.bodies["/storage/v1/b/src/o/a/bad/rewriteTo/b/dst/o/a/bad"], `{"acl":[{"entity":"allUsers","role":"READER"}]}`; got != want {
+		t.Errorf("got rewrite body %s, want %s", got, want)
+	}
+}
@@ -65,6 +65,16 @@ and use a client as usual:
         // TODO: Handle error.
     }
 
+To point a single client at an emulator without setting the environment
+variable, for example in hermetic tests that start their own emulator, use
+the WithEmulator option:
+
+    client, err := storage.NewClient(ctx, storage.WithEmulator("localhost:9000"))
+
+Signed URLs and POST policies use the host of the emulator, in path style,
+unless a Hostname is set in their options, and resumable uploads are sent to
+the emulator even if it returns upload session URIs for another address.
+
 Please note that there is no official emulator for Cloud Storage.
 
 Buckets
@@ -168,6 +178,13 @@ lexicographically, Query.StartOffset and/or Query.EndOffset can be used:
 
     // ... as before
 
+To list only objects whose names match a glob pattern, without fetching and
+filtering every object under a prefix, use Query.MatchGlob:
+
+    query := &storage.Query{MatchGlob: "logs/2022-??/*.json"}
+
+    // ... as before
+
 If only a subset of object attributes is needed when listing, specifying this
 subset using Query.SetAttrSelection may speed up the listing process:
 
@@ -238,6 +255,23 @@ as the documentation of GenerateSignedPostPolicyV4.
     }
     fmt.Printf("URL: %s\nFields; %v\n", pv4.URL, pv4.Fields)
 
+Client-side envelope encryption
+
+Objects can be encrypted before they leave your program, with a data key that
+is itself encrypted by Cloud KMS and stored in the object's metadata. Write
+such objects with NewEnvelopeWriter and read them with NewEnvelopeReader:
+
+    kw := client.KMSKeyWrapper("projects/P/locations/L/keyRings/R/cryptoKeys/K")
+    w := obj.NewEnvelopeWriter(ctx, kw)
+    // Write and Close as with a Writer.
+
+    r, err := obj.NewEnvelopeReader(ctx, kw)
+    if err != nil {
+        // TODO: Handle error.
+    }
+    defer r.Close()
+    // Read decrypted data from r.
+
 Errors
 
 Errors returned by this client are often of the type googleapi.Error.
@@ -251,11 +285,24 @@ with the richer googleapi.Error type. For example:
 
 See https://pkg.go.dev/google.golang.org/api/googleapi#Error for more information.
 
+Experimental gRPC API
+
+Object reads and writes can be served by the Cloud Storage gRPC API instead of
+the JSON and XML APIs by creating the client with NewGRPCClient. The rest of the
+Client API is unchanged. This feature is experimental and the gRPC API must be
+enabled for the project:
+
+	client, err := storage.NewGRPCClient(ctx)
+	if err != nil {
+		// TODO: Handle error.
+	}
+
 Retrying failed requests
 
 Methods in this package may retry calls that fail with transient errors.
 Retrying continues indefinitely unless the controlling context is canceled, the
-client is closed, or a non-transient error is received. To stop retries from
+client is closed, a non-transient error is received, or the maximum number of
+attempts configured with WithMaxAttempts is reached. To stop retries from
 continuing, use context timeouts or cancellation.
 
 The retry strategy in this library follows best practices for Cloud Storage. By
@@ -276,6 +323,9 @@ client (using Client.SetRetry). For example:
 		// Use WithPolicy to configure the idempotency policy. RetryAlways will
 		// retry the operation even if it is non-idempotent.
 		storage.WithPolicy(storage.RetryAlways),
+		// Use WithMaxAttempts to give up after a number of attempts rather
+		// than retrying until the context is done.
+		storage.WithMaxAttempts(5),
 	)
 
 	// Use a context timeout to set an overall deadline on the call, including all
@@ -287,5 +337,10 @@ client (using Client.SetRetry). For example:
 	if err := o.Delete(ctx); err != nil {
 		// Handle err.
 	}
+
+A Reader whose connection fails while it is reading an object reconnects and
+continues from the last byte it read, and still checks the whole content
+against the object's CRC32C. Use ObjectHandle.ReadResumes to change how many
+times in a row it reconnects without reading any data.
 */
 package storage // import "cloud.google.com/go/storage"
@@ -0,0 +1,440 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+const (
+	defaultSliceSize        = 64 << 20
+	defaultSliceConcurrency = 8
+	defaultSliceAttempts    = 3
+)
+
+// SlicedDownloadOptions configures ObjectHandle.DownloadSliced.
+type SlicedDownloadOptions struct {
+	// SliceSize is the number of bytes fetched by each ranged request.
+	// The default is 64 MiB.
+	SliceSize int64
+
+	// Concurrency is the maximum number of slices downloaded at the same
+	// time. The default is 8.
+	Concurrency int
+
+	// MaxSliceAttempts is the maximum number of times a single slice is
+	// downloaded before DownloadSliced gives up. Each attempt already retries
+	// transient errors according to the handle's retry configuration; a new
+	// attempt restarts the slice from its first byte. The default is 3.
+	MaxSliceAttempts int
+}
+
+func (opts *SlicedDownloadOptions) sliceSize() int64 {
+	if opts == nil || opts.SliceSize <= 0 {
+		return defaultSliceSize
+	}
+	return opts.SliceSize
+}
+
+func (opts *SlicedDownloadOptions) concurrency() int {
+	if opts == nil || opts.Concurrency <= 0 {
+		return defaultSliceConcurrency
+	}
+	return opts.Concurrency
+}
+
+func (opts *SlicedDownloadOptions) maxSliceAttempts() int {
+	if opts == nil || opts.MaxSliceAttempts <= 0 {
+		return defaultSliceAttempts
+	}
+	return opts.MaxSliceAttempts
+}
+
+// DownloadSliced downloads the object into w by splitting it into ranged
+// slices that are fetched concurrently. An *os.File can be used as w. It
+// returns the number of bytes written.
+//
+// The generation of the object is pinned when the download starts, so all
+// slices read the same content. Once all slices have been written, the CRC32C
+// of the content is checked against the object's and an error is returned if
+// they differ.
+//
+// Objects served with decompressive transcoding cannot be read in ranges, so
+// they are downloaded in a single stream and the integrity check is skipped.
+// See https://cloud.google.com/storage/docs/transcoding.
+//
+// A nil opts uses the defaults described in SlicedDownloadOptions.
+func (o *ObjectHandle) DownloadSliced(ctx context.Context, w io.WriterAt, opts *SlicedDownloadOptions) (n int64, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Object.DownloadSliced")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	oh := o.Generation(attrs.Generation)
+
+	if attrs.ContentEncoding == "gzip" && !o.readCompressed {
+		r, err := oh.NewReader(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer r.Close()
+		return io.Copy(&offsetWriter{w: w}, r)
+	}
+	if attrs.Size == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		size     = opts.sliceSize()
+		nSlices  = int((attrs.Size + size - 1) / size)
+		crcs     = make([]uint32, nSlices)
+		sem      = make(chan struct{}, opts.concurrency())
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i := 0; i < nSlices; i++ {
+		off := int64(i) * size
+		length := size
+		if off+length > attrs.Size {
+			length = attrs.Size - off
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, off, length int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			crc, err := oh.downloadSlice(ctx, w, off, length, opts.maxSliceAttempts())
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			crcs[i] = crc
+		}(i, off, length)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if attrs.CRC32C != 0 {
+		got := crcs[0]
+		for i := 1; i < nSlices; i++ {
+			length := size
+			if i == nSlices-1 {
+				length = attrs.Size - int64(i)*size
+			}
+			got = crc32cCombine(got, crcs[i], length)
+		}
+		if got != attrs.CRC32C {
+			return attrs.Size, fmt.Errorf("storage: bad CRC on sliced download: got %d, want %d", got, attrs.CRC32C)
+		}
+	}
+	return attrs.Size, nil
+}
+
+// FileDownloadOptions configures ObjectHandle.DownloadToFile.
+type FileDownloadOptions struct {
+	// Resume continues an interrupted download from the partial file left
+	// by a previous call with Resume set, instead of starting over. The
+	// partial file is only used if it is not longer than the object; it is
+	// checked along with the rest of the content once the download
+	// completes.
+	Resume bool
+
+	// Fsync flushes the file and its directory entry to stable storage
+	// before DownloadToFile returns.
+	Fsync bool
+}
+
+// partialSuffix is appended to the path of a file being downloaded.
+const partialSuffix = ".partial"
+
+// DownloadToFile downloads the object to the file at path, and returns the
+// attributes of the downloaded generation. The content is first written to
+// path with the suffix ".partial", which is renamed to path once the CRC32C
+// and, if the object has one, the MD5 hash of the content have been checked
+// against the object's. If the checksums differ, the partial file is removed
+// and an error is returned.
+//
+// If the download fails before completing, the partial file is removed
+// unless opts.Resume is set, in which case it is kept so that a later call
+// with opts.Resume can continue from its end with a ranged read.
+//
+// Objects served with decompressive transcoding cannot be read in ranges, so
+// they are downloaded in a single stream, without resuming, and the
+// integrity check is skipped. Use ReadCompressed(true) to download their
+// stored bytes instead. See https://cloud.google.com/storage/docs/transcoding.
+//
+// A nil opts uses the defaults described in FileDownloadOptions.
+func (o *ObjectHandle) DownloadToFile(ctx context.Context, path string, opts *FileDownloadOptions) (attrs *ObjectAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Object.DownloadToFile")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if opts == nil {
+		opts = &FileDownloadOptions{}
+	}
+	attrs, err = o.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oh := o.Generation(attrs.Generation)
+	transcoded := attrs.ContentEncoding == "gzip" && !o.readCompressed
+
+	partial := path + partialSuffix
+	flag := os.O_RDWR | os.O_CREATE
+	if !opts.Resume || transcoded {
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(partial, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+	keep := false
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+		if err != nil && !keep {
+			os.Remove(partial)
+		}
+	}()
+
+	crc := crc32.New(crc32cTable)
+	md := md5.New()
+	hashes := io.MultiWriter(crc, md)
+	// Hash the content of the partial file, and continue from its end.
+	off, err := io.Copy(hashes, f)
+	if err != nil {
+		return nil, err
+	}
+	if off > attrs.Size && !transcoded {
+		if err := restartFile(f); err != nil {
+			return nil, err
+		}
+		off = 0
+		crc.Reset()
+		md.Reset()
+	}
+
+	if off < attrs.Size || transcoded {
+		var r *Reader
+		if transcoded {
+			r, err = oh.NewReader(ctx)
+		} else {
+			r, err = oh.NewRangeReader(ctx, off, -1)
+		}
+		if err != nil {
+			keep = opts.Resume
+			return nil, err
+		}
+		_, err = io.Copy(io.MultiWriter(f, hashes), r)
+		r.Close()
+		if err != nil {
+			keep = opts.Resume
+			return nil, err
+		}
+	}
+
+	if !transcoded {
+		if err := checkDownload(attrs, crc, md); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Fsync {
+		if err := f.Sync(); err != nil {
+			return nil, err
+		}
+	}
+	err = f.Close()
+	f = nil
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return nil, err
+	}
+	if opts.Fsync {
+		if err := syncDir(filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+	}
+	return attrs, nil
+}
+
+// checkDownload compares the checksums of downloaded content with the
+// object's.
+func checkDownload(attrs *ObjectAttrs, crc hash.Hash32, md hash.Hash) error {
+	if attrs.CRC32C != 0 || attrs.Size == 0 {
+		if got := crc.Sum32(); got != attrs.CRC32C {
+			return fmt.Errorf("storage: bad CRC on download: got %d, want %d", got, attrs.CRC32C)
+		}
+	}
+	if len(attrs.MD5) > 0 {
+		if got := md.Sum(nil); !bytes.Equal(got, attrs.MD5) {
+			return fmt.Errorf("storage: bad MD5 on download: got %x, want %x", got, attrs.MD5)
+		}
+	}
+	return nil
+}
+
+// restartFile empties f and moves to its start.
+func restartFile(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.Seek(0, io.SeekStart)
+	return err
+}
+
+// syncDir flushes the directory entries of dir to stable storage.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// downloadSlice writes length bytes of the object starting at off into w at
+// the same offset, and returns the CRC32C of those bytes.
+func (o *ObjectHandle) downloadSlice(ctx context.Context, w io.WriterAt, off, length int64, attempts int) (uint32, error) {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, ctxErr
+		}
+		var crc uint32
+		crc, err = o.readSlice(ctx, w, off, length)
+		if err == nil {
+			return crc, nil
+		}
+		if err == ErrObjectNotExist {
+			return 0, err
+		}
+	}
+	return 0, err
+}
+
+func (o *ObjectHandle) readSlice(ctx context.Context, w io.WriterAt, off, length int64) (uint32, error) {
+	r, err := o.NewRangeReader(ctx, off, length)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	h := crc32.New(crc32cTable)
+	n, err := io.Copy(io.MultiWriter(&offsetWriter{w: w, off: off}, h), r)
+	if err != nil {
+		return 0, err
+	}
+	if n != length {
+		return 0, fmt.Errorf("storage: short read of slice at offset %d: got %d bytes, want %d", off, n, length)
+	}
+	return h.Sum32(), nil
+}
+
+// offsetWriter adapts an io.WriterAt to an io.Writer that writes sequentially
+// from off.
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.WriteAt(p, ow.off)
+	ow.off += int64(n)
+	return n, err
+}
+
+// castagnoliReversed is the bit-reversed Castagnoli polynomial.
+const castagnoliReversed = 0x82f63b78
+
+// x2nTable[k] is x^(2^k) modulo the Castagnoli polynomial.
+var x2nTable = func() (t [32]uint32) {
+	p := uint32(1) << 30 // x^1
+	t[0] = p
+	for k := 1; k < 32; k++ {
+		p = multModP(p, p)
+		t[k] = p
+	}
+	return t
+}()
+
+// multModP returns a(x) * b(x) modulo the Castagnoli polynomial, with the
+// polynomials in the reflected bit order used by CRC32C.
+func multModP(a, b uint32) uint32 {
+	m := uint32(1) << 31
+	var p uint32
+	for {
+		if a&m != 0 {
+			p ^= b
+			if a&(m-1) == 0 {
+				return p
+			}
+		}
+		m >>= 1
+		if b&1 != 0 {
+			b = b>>1 ^ castagnoliReversed
+		} else {
+			b >>= 1
+		}
+	}
+}
+
+// crc32cCombine returns the CRC32C of the concatenation of two byte
+// sequences, given the CRC32C of each and the length of the second. It is
+// the technique used by zlib's crc32_combine.
+func crc32cCombine(crc1, crc2 uint32, len2 int64) uint32 {
+	// Multiply crc1 by x^(8*len2).
+	p := uint32(1) << 31 // x^0
+	for k := 3; len2 != 0; k++ {
+		if len2&1 != 0 {
+			p = multModP(x2nTable[k&31], p)
+		}
+		len2 >>= 1
+	}
+	return multModP(p, crc1) ^ crc2
+}
@@ -0,0 +1,350 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+)
+
+func TestCRC32CCombine(t *testing.T) {
+	data := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog", 50))
+	for _, split := range []int{0, 1, 7, 64, 1000, len(data)} {
+		crc1 := crc32.Checksum(data[:split], crc32cTable)
+		crc2 := crc32.Checksum(data[split:], crc32cTable)
+		got := crc32cCombine(crc1, crc2, int64(len(data)-split))
+		if want := crc32.Checksum(data, crc32cTable); got != want {
+			t.Errorf("split at %d: got %d, want %d", split, got, want)
+		}
+	}
+}
+
+// slicedServer serves the metadata and ranged media of a single object.
+type slicedServer struct {
+	data string
+	// crc is the CRC32C reported in the object's metadata.
+	crc uint32
+	// shortRanges is the number of upcoming ranged reads that are
+	// truncated.
+	shortRanges int
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (s *slicedServer) handle(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/storage/v1/") {
+		fmt.Fprintf(w, `{"bucket": "b", "name": "o", "generation": "5", "size": "%d", "crc32c": %q}`,
+			len(s.data), encodeUint32(s.crc))
+		return
+	}
+	if got := r.URL.Query().Get("generation"); got != "5" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var from, to int
+	rh := r.Header.Get("Range")
+	if _, err := fmt.Sscanf(rh, "bytes=%d-%d", &from, &to); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.ranges = append(s.ranges, rh)
+	short := s.shortRanges > 0
+	if short {
+		s.shortRanges--
+	}
+	s.mu.Unlock()
+	body := s.data[from : to+1]
+	if short {
+		body = body[:len(body)/2]
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", from, to, len(s.data)))
+	w.WriteHeader(http.StatusPartialContent)
+	fmt.Fprint(w, body)
+}
+
+// bufWriterAt is an in-memory io.WriterAt.
+type bufWriterAt struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (b *bufWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if end := int(off) + len(p); end > len(b.buf) {
+		b.buf = append(b.buf, make([]byte, end-len(b.buf))...)
+	}
+	return copy(b.buf[off:], p), nil
+}
+
+func TestDownloadSliced(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+	crc := crc32.Checksum([]byte(data), crc32cTable)
+	for _, test := range []struct {
+		desc        string
+		crc         uint32
+		shortRanges int
+		opts        *SlicedDownloadOptions
+		wantSlices  int
+		wantErr     bool
+	}{
+		{
+			desc:       "default options",
+			crc:        crc,
+			wantSlices: 1,
+		},
+		{
+			desc:       "many slices",
+			crc:        crc,
+			opts:       &SlicedDownloadOptions{SliceSize: 64, Concurrency: 3},
+			wantSlices: 16,
+		},
+		{
+			desc:        "retries short slices",
+			crc:         crc,
+			shortRanges: 2,
+			opts:        &SlicedDownloadOptions{SliceSize: 300, Concurrency: 1},
+			wantSlices:  6,
+		},
+		{
+			desc:        "gives up after max attempts",
+			crc:         crc,
+			shortRanges: 2,
+			opts:        &SlicedDownloadOptions{SliceSize: 300, Concurrency: 1, MaxSliceAttempts: 2},
+			wantErr:     true,
+		},
+		{
+			desc:    "bad CRC",
+			crc:     crc + 1,
+			opts:    &SlicedDownloadOptions{SliceSize: 100},
+			wantErr: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			s := &slicedServer{data: data, crc: test.crc, shortRanges: test.shortRanges}
+			hc, close := newTestServer(s.handle)
+			defer close()
+			ctx := context.Background()
+			c, err := NewClient(ctx, option.WithHTTPClient(hc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var w bufWriterAt
+			n, err := c.Bucket("b").Object("o").DownloadSliced(ctx, &w, test.opts)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil error, want non-nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(len(data)) {
+				t.Errorf("got %d bytes, want %d", n, len(data))
+			}
+			if got := string(w.buf); got != data {
+				t.Errorf("got %q, want %q", got, data)
+			}
+			if got := len(s.ranges); got != test.wantSlices {
+				t.Errorf("got %d ranged reads, want %d", got, test.wantSlices)
+			}
+		})
+	}
+}
+
+// fileServer serves the metadata and media of a single object, which may be
+// read from an offset.
+type fileServer struct {
+	data string
+	// fail makes media requests fail.
+	fail bool
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (s *fileServer) handle(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/storage/v1/") {
+		sum := md5.Sum([]byte(s.data))
+		fmt.Fprintf(w, `{"bucket": "b", "name": "o", "generation": "5", "size": "%d", "crc32c": %q, "md5Hash": %q}`,
+			len(s.data), encodeUint32(crc32.Checksum([]byte(s.data), crc32cTable)), base64.StdEncoding.EncodeToString(sum[:]))
+		return
+	}
+	if got := r.URL.Query().Get("generation"); got != "5" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rh := r.Header.Get("Range")
+	s.mu.Lock()
+	s.ranges = append(s.ranges, rh)
+	s.mu.Unlock()
+	if s.fail {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if rh == "" {
+		fmt.Fprint(w, s.data)
+		return
+	}
+	var from int
+	if _, err := fmt.Sscanf(rh, "bytes=%d-", &from); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", from, len(s.data)-1, len(s.data)))
+	w.WriteHeader(http.StatusPartialContent)
+	fmt.Fprint(w, s.data[from:])
+}
+
+func TestDownloadToFile(t *testing.T) {
+	data := strings.Repeat("0123456789", 100)
+	for _, test := range []struct {
+		desc string
+		// partial is the content of the partial file before the download,
+		// if it exists.
+		partial     *string
+		opts        *FileDownloadOptions
+		fail        bool
+		wantRanges  []string
+		wantErr     bool
+		wantPartial bool
+	}{
+		{
+			desc:       "default options",
+			wantRanges: []string{""},
+		},
+		{
+			desc:       "fsync",
+			opts:       &FileDownloadOptions{Fsync: true},
+			wantRanges: []string{""},
+		},
+		{
+			desc:       "ignores partial file without resume",
+			partial:    strPtr(data[:300]),
+			wantRanges: []string{""},
+		},
+		{
+			desc:       "resumes partial file",
+			partial:    strPtr(data[:300]),
+			opts:       &FileDownloadOptions{Resume: true},
+			wantRanges: []string{"bytes=300-"},
+		},
+		{
+			desc:       "complete partial file",
+			partial:    strPtr(data),
+			opts:       &FileDownloadOptions{Resume: true},
+			wantRanges: nil,
+		},
+		{
+			desc:       "restarts partial file longer than object",
+			partial:    strPtr(data + "extra"),
+			opts:       &FileDownloadOptions{Resume: true},
+			wantRanges: []string{""},
+		},
+		{
+			desc:       "corrupt partial file",
+			partial:    strPtr("x" + data[1:300]),
+			opts:       &FileDownloadOptions{Resume: true},
+			wantRanges: []string{"bytes=300-"},
+			wantErr:    true,
+		},
+		{
+			desc:       "failure removes partial file",
+			fail:       true,
+			wantRanges: []string{""},
+			wantErr:    true,
+		},
+		{
+			desc:        "failure keeps partial file with resume",
+			partial:     strPtr(data[:300]),
+			opts:        &FileDownloadOptions{Resume: true},
+			fail:        true,
+			wantRanges:  []string{"bytes=300-"},
+			wantErr:     true,
+			wantPartial: true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			s := &fileServer{data: data, fail: test.fail}
+			hc, close := newTestServer(s.handle)
+			defer close()
+			ctx := context.Background()
+			c, err := NewClient(ctx, option.WithHTTPClient(hc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir, err := ioutil.TempDir("", "download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "o")
+			if test.partial != nil {
+				if err := ioutil.WriteFile(path+".partial", []byte(*test.partial), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			attrs, err := c.Bucket("b").Object("o").DownloadToFile(ctx, path, test.opts)
+			if diff := cmp.Diff(test.wantRanges, s.ranges); diff != "" {
+				t.Errorf("ranges mismatch (-want +got):\n%s", diff)
+			}
+			_, statErr := os.Stat(path + ".partial")
+			if gotPartial := statErr == nil; gotPartial != test.wantPartial {
+				t.Errorf("got partial file %t, want %t", gotPartial, test.wantPartial)
+			}
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil error, want non-nil")
+				}
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("got file after failed download, stat error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attrs.Generation != 5 {
+				t.Errorf("got generation %d, want 5", attrs.Generation)
+			}
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != data {
+				t.Errorf("got %q, want %q", got, data)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string { return &s }
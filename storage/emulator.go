@@ -0,0 +1,105 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// parseEmulatorHost parses the address of an emulator, as given to
+// WithEmulator or in STORAGE_EMULATOR_HOST. The scheme defaults to http.
+func parseEmulatorHost(host string) (*url.URL, error) {
+	if strings.Contains(host, "://") {
+		return url.Parse(host)
+	}
+	// Add scheme for user if not supplied in STORAGE_EMULATOR_HOST
+	// URL is only parsed correctly if it has a scheme, so we build it ourselves
+	return &url.URL{Scheme: "http", Host: host}, nil
+}
+
+// envEmulator returns the scheme and host of the emulator set in
+// STORAGE_EMULATOR_HOST, or nil if it is not set.
+func envEmulator() (*url.URL, error) {
+	host := os.Getenv("STORAGE_EMULATOR_HOST")
+	if host == "" {
+		return nil, nil
+	}
+	u, err := parseEmulatorHost(host)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+// newEmulatorHTTPClient returns a copy of hc that sends resumable upload
+// requests to the emulator. Emulators may return upload session URIs with
+// the address they were started with, such as one inside a container, rather
+// than the one the client uses.
+func newEmulatorHTTPClient(hc *http.Client, emulator *url.URL) *http.Client {
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	hc2 := *hc
+	hc2.Transport = &emulatorTransport{base: base, emulator: emulator}
+	return &hc2
+}
+
+// emulatorTransport redirects resumable upload requests to an emulator.
+type emulatorTransport struct {
+	base     http.RoundTripper
+	emulator *url.URL
+}
+
+func (t *emulatorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasPrefix(req.URL.Path, "/upload/storage/v1/") && req.URL.Host != t.emulator.Host {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = t.emulator.Scheme
+		req.URL.Host = t.emulator.Host
+		req.Host = ""
+	}
+	return t.base.RoundTrip(req)
+}
+
+// forEmulator returns opts with the host and scheme of the emulator, unless
+// the host is set by opts. The URL is path style, as the emulator does not
+// serve bucket subdomains of its host.
+func (opts *SignedURLOptions) forEmulator(emulator *url.URL) *SignedURLOptions {
+	if _, ok := opts.Style.(bucketBoundHostname); ok || emulator == nil || opts.Hostname != "" {
+		return opts
+	}
+	opts = opts.clone()
+	opts.Hostname = emulator.Host
+	opts.Insecure = emulator.Scheme == "http"
+	opts.Style = PathStyle()
+	return opts
+}
+
+// forEmulator returns opts with the host and scheme of the emulator, unless
+// the host is set by opts. The URL is path style, as the emulator does not
+// serve bucket subdomains of its host.
+func (opts *PostPolicyV4Options) forEmulator(emulator *url.URL) *PostPolicyV4Options {
+	if _, ok := opts.Style.(bucketBoundHostname); ok || emulator == nil || opts.Hostname != "" {
+		return opts
+	}
+	opts = opts.clone()
+	opts.Hostname = emulator.Host
+	opts.Insecure = emulator.Scheme == "http"
+	opts.Style = PathStyle()
+	return opts
+}
@@ -0,0 +1,247 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+)
+
+// fakeEmulator serves the requests of a client that uses an emulator, and
+// returns upload session URIs for another address, like an emulator in a
+// container.
+type fakeEmulator struct {
+	mu       sync.Mutex
+	requests []string
+	authed   bool
+}
+
+func (e *fakeEmulator) handle(w http.ResponseWriter, r *http.Request) {
+	ioutil.ReadAll(r.Body)
+	e.mu.Lock()
+	e.requests = append(e.requests, r.Method+" "+r.URL.Path)
+	if r.Header.Get("Authorization") != "" {
+		e.authed = true
+	}
+	e.mu.Unlock()
+	switch {
+	case r.URL.Query().Get("upload_id") == "u":
+		if rng := r.Header.Get("Content-Range"); strings.HasSuffix(rng, "/*") {
+			end := rng[strings.Index(rng, "-")+1 : strings.Index(rng, "/")]
+			w.Header().Set("Range", "bytes=0-"+end)
+			w.Header().Set("X-Http-Status-Code-Override", "308")
+			return
+		}
+		fmt.Fprint(w, `{"bucket": "b", "name": "o"}`)
+	case r.URL.Query().Get("uploadType") == "resumable":
+		w.Header().Set("Location", "http://0.0.0.0:4443/upload/storage/v1/b/b/o?uploadType=resumable&upload_id=u")
+	case strings.Contains(r.URL.Path, "/rewriteTo/"):
+		fmt.Fprint(w, `{"done": true, "resource": {"bucket": "b", "name": "o"}}`)
+	case r.URL.Path == "/b/o":
+		fmt.Fprint(w, "hello")
+	default:
+		fmt.Fprint(w, `{"bucket": "b", "name": "o"}`)
+	}
+}
+
+func TestWithEmulator(t *testing.T) {
+	e := &fakeEmulator{}
+	ts := httptest.NewServer(http.HandlerFunc(e.handle))
+	defer ts.Close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, WithEmulator(strings.TrimPrefix(ts.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := c.Bucket("b").Object("o")
+
+	if _, err := obj.Attrs(ctx); err != nil {
+		t.Fatalf("Attrs: %v", err)
+	}
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	r.Close()
+	// Write more than a chunk, to make a resumable upload.
+	data := make([]byte, 256*1024+5)
+	w := obj.NewWriter(ctx)
+	w.ChunkSize = 256 * 1024
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Writer.Close: %v", err)
+	}
+	w = obj.NewWriter(ctx)
+	w.ChunkSize = 256 * 1024
+	w.EnableFlush = true
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Writer.Close with EnableFlush: %v", err)
+	}
+	if _, err := obj.CopierFrom(c.Bucket("b").Object("src")).Run(ctx); err != nil {
+		t.Fatalf("Copier.Run: %v", err)
+	}
+	if _, err := obj.ComposerFrom(c.Bucket("b").Object("src")).Run(ctx); err != nil {
+		t.Fatalf("Composer.Run: %v", err)
+	}
+
+	want := []string{
+		"GET /storage/v1/b/b/o/o",
+		"GET /b/o",
+		"POST /upload/storage/v1/b/b/o",
+		"POST /upload/storage/v1/b/b/o",
+		"POST /upload/storage/v1/b/b/o",
+		"POST /upload/storage/v1/b/b/o",
+		"PUT /upload/storage/v1/b/b/o",
+		"PUT /upload/storage/v1/b/b/o",
+		"POST /storage/v1/b/b/o/src/rewriteTo/b/b/o/o",
+		"POST /storage/v1/b/b/o/o/compose",
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if diff := cmp.Diff(want, e.requests); diff != "" {
+		t.Errorf("emulator requests mismatch (-want +got):\n%s", diff)
+	}
+	if e.authed {
+		t.Error("got authenticated requests to the emulator")
+	}
+}
+
+func TestSignedURLEmulator(t *testing.T) {
+	orig := os.Getenv("STORAGE_EMULATOR_HOST")
+	defer os.Setenv("STORAGE_EMULATOR_HOST", orig)
+	opts := func() *SignedURLOptions {
+		return &SignedURLOptions{
+			GoogleAccessID: "xxx@clientid",
+			PrivateKey:     dummyKey("rsa"),
+			Method:         "GET",
+			Expires:        time.Now().Add(time.Hour),
+			Scheme:         SigningSchemeV4,
+		}
+	}
+	withHost := opts()
+	withHost.Hostname = "example.com"
+	v2 := opts()
+	v2.Scheme = SigningSchemeV2
+	virtualHosted := opts()
+	virtualHosted.Style = VirtualHostedStyle()
+
+	for _, test := range []struct {
+		desc    string
+		env     string
+		client  *Client
+		opts    *SignedURLOptions
+		wantURL string
+	}{
+		{
+			desc:    "no emulator",
+			opts:    opts(),
+			wantURL: "https://storage.googleapis.com/b/o",
+		},
+		{
+			desc:    "environment",
+			env:     "localhost:9000",
+			opts:    opts(),
+			wantURL: "http://localhost:9000/b/o",
+		},
+		{
+			desc:    "environment with scheme",
+			env:     "https://emu.example.com:8443",
+			opts:    v2,
+			wantURL: "https://emu.example.com:8443/b/o",
+		},
+		{
+			desc:    "virtual hosted style",
+			env:     "localhost:9000",
+			opts:    virtualHosted,
+			wantURL: "http://localhost:9000/b/o",
+		},
+		{
+			desc:    "hostname overrides emulator",
+			env:     "localhost:9000",
+			opts:    withHost,
+			wantURL: "https://example.com/b/o",
+		},
+		{
+			desc:    "client option",
+			client:  mustNewClient(t, WithEmulator("localhost:9001")),
+			opts:    opts(),
+			wantURL: "http://localhost:9001/b/o",
+		},
+		{
+			desc:    "client option overrides environment",
+			env:     "localhost:9000",
+			client:  mustNewClient(t, WithEmulator("localhost:9001")),
+			opts:    opts(),
+			wantURL: "http://localhost:9001/b/o",
+		},
+	} {
+		os.Setenv("STORAGE_EMULATOR_HOST", test.env)
+		var got string
+		var err error
+		if test.client != nil {
+			got, err = test.client.Bucket("b").SignedURL("o", test.opts)
+		} else {
+			got, err = SignedURL("b", "o", test.opts)
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.desc, err)
+			continue
+		}
+		u, err := url.Parse(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.RawQuery = ""
+		if u.String() != test.wantURL {
+			t.Errorf("%s: got URL %s, want %s", test.desc, u, test.wantURL)
+		}
+	}
+
+	os.Setenv("STORAGE_EMULATOR_HOST", "localhost:9000")
+	p, err := GenerateSignedPostPolicyV4("b", "o", &PostPolicyV4Options{
+		GoogleAccessID: "xxx@clientid",
+		PrivateKey:     dummyKey("rsa"),
+		Expires:        time.Now().Add(time.Hour),
+		Style:          VirtualHostedStyle(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://localhost:9000/b/"; p.URL != want {
+		t.Errorf("got POST policy URL %s, want %s", p.URL, want)
+	}
+}
+
+func mustNewClient(t *testing.T, opts ...option.ClientOption) *Client {
+	t.Helper()
+	c, err := NewClient(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
@@ -0,0 +1,420 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// Metadata keys set on objects written with envelope encryption. Metadata
+// keys starting with "envelope-" are reserved on such objects.
+const (
+	envelopeAlgorithmKey = "envelope-algorithm"
+	envelopeWrappedKey   = "envelope-wrapped-key"
+	envelopeNonceKey     = "envelope-nonce"
+
+	// envelopeAlgorithm identifies the format of the encrypted data: a
+	// sequence of segments of envelopeSegmentSize bytes of plaintext, each
+	// sealed with AES-256-GCM under a nonce made of a random prefix, the
+	// segment number and a flag marking the last segment, so that segments
+	// can be neither reordered nor dropped.
+	envelopeAlgorithm   = "AES256-GCM-64K"
+	envelopeSegmentSize = 64 << 10
+	envelopeNonceSize   = 7
+)
+
+// A KeyWrapper encrypts and decrypts the data encryption keys used for
+// envelope encryption. Use Client.KMSKeyWrapper to wrap keys with Cloud KMS,
+// or implement KeyWrapper to use another key management system.
+type KeyWrapper interface {
+	// WrapKey encrypts a data encryption key.
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	// UnwrapKey decrypts a data encryption key encrypted by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// KMSKeyWrapper returns a KeyWrapper that wraps data encryption keys with the
+// Cloud KMS key keyName, of the form
+// "projects/P/locations/L/keyRings/R/cryptoKeys/K". The requests to Cloud KMS
+// are made with the client's credentials, so the client must have been
+// created with a scope that allows them, such as
+// "https://www.googleapis.com/auth/cloudkms", and the caller needs the
+// cloudkms.cryptoKeyVersions.useToEncrypt and useToDecrypt permissions on
+// the key.
+func (c *Client) KMSKeyWrapper(keyName string) KeyWrapper {
+	return &kmsKeyWrapper{c: c, name: keyName}
+}
+
+type kmsKeyWrapper struct {
+	c    *Client
+	name string
+}
+
+func (k *kmsKeyWrapper) service(ctx context.Context) (*cloudkms.Service, error) {
+	// It's ok to recreate this service per call since we pass in the http client,
+	// circumventing the cost of recreating the auth/transport layer
+	svc, err := cloudkms.NewService(ctx, option.WithHTTPClient(k.c.hc))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cloudkms client: %v", err)
+	}
+	return svc, nil
+}
+
+func (k *kmsKeyWrapper) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	svc, err := k.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.Projects.Locations.KeyRings.CryptoKeys.Encrypt(k.name, &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(key),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to wrap key: %v", err)
+	}
+	return base64.StdEncoding.DecodeString(resp.Ciphertext)
+}
+
+func (k *kmsKeyWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	svc, err := k.service(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := svc.Projects.Locations.KeyRings.CryptoKeys.Decrypt(k.name, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(wrapped),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to unwrap key: %v", err)
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+// NewEnvelopeWriter returns a writer that encrypts the data written to it
+// before uploading it to the object, so that plaintext never reaches Cloud
+// Storage. Each object is encrypted with its own random AES-256 data key,
+// which is wrapped by kw and stored, with the other parameters needed for
+// decryption, in the object's metadata. Use NewEnvelopeReader to read the
+// object.
+//
+// The attributes of the object can be set through the returned writer's
+// Writer field before the first call to Write or Close. The checksums and
+// size of the object are those of the encrypted data.
+func (o *ObjectHandle) NewEnvelopeWriter(ctx context.Context, kw KeyWrapper) *EnvelopeWriter {
+	return &EnvelopeWriter{
+		Writer: o.NewWriter(ctx),
+		ctx:    ctx,
+		kw:     kw,
+	}
+}
+
+// An EnvelopeWriter writes an object with client-side envelope encryption.
+// It is created by ObjectHandle.NewEnvelopeWriter.
+type EnvelopeWriter struct {
+	// Writer is the writer of the encrypted object. Its ObjectAttrs and
+	// ChunkSize may be set before the first call to Write or Close, as for
+	// any Writer. Write and Close must be called on the EnvelopeWriter
+	// rather than on Writer. To abort the write, cancel the context passed
+	// to NewEnvelopeWriter.
+	Writer *Writer
+
+	ctx context.Context
+	kw  KeyWrapper
+	enc *envelopeEncrypter
+	err error
+}
+
+// Write encrypts p and writes it to the object.
+func (w *EnvelopeWriter) Write(p []byte) (int, error) {
+	if err := w.open(); err != nil {
+		return 0, err
+	}
+	return w.enc.Write(p)
+}
+
+// Close completes the write operation and flushes any buffered data, as
+// Writer.Close.
+func (w *EnvelopeWriter) Close() error {
+	if err := w.open(); err != nil {
+		w.Writer.CloseWithError(err)
+		return err
+	}
+	if err := w.enc.Close(); err != nil {
+		w.Writer.CloseWithError(err)
+		return err
+	}
+	return w.Writer.Close()
+}
+
+// Attrs returns metadata about a successfully-written object. It's only valid
+// to call it after Close returns nil.
+func (w *EnvelopeWriter) Attrs() *ObjectAttrs {
+	return w.Writer.Attrs()
+}
+
+// open generates and wraps the data key and records it in the object's
+// metadata, the first time it is called.
+func (w *EnvelopeWriter) open() error {
+	if w.enc != nil || w.err != nil {
+		return w.err
+	}
+	key := make([]byte, 32)
+	nonce := make([]byte, envelopeNonceSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		w.err = err
+		return err
+	}
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		w.err = err
+		return err
+	}
+	wrapped, err := w.kw.WrapKey(w.ctx, key)
+	if err != nil {
+		w.err = fmt.Errorf("storage: wrapping envelope key: %v", err)
+		return w.err
+	}
+	enc, err := newEnvelopeEncrypter(w.Writer, key, nonce)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	md := make(map[string]string, len(w.Writer.Metadata)+3)
+	for k, v := range w.Writer.Metadata {
+		md[k] = v
+	}
+	md[envelopeAlgorithmKey] = envelopeAlgorithm
+	md[envelopeWrappedKey] = base64.StdEncoding.EncodeToString(wrapped)
+	md[envelopeNonceKey] = base64.StdEncoding.EncodeToString(nonce)
+	w.Writer.Metadata = md
+	w.enc = enc
+	return nil
+}
+
+// NewEnvelopeReader creates a new EnvelopeReader to read and decrypt the
+// contents of an object written by an EnvelopeWriter. The data key is
+// unwrapped with kw, and the generation of the object is pinned so that the
+// data read matches the key.
+// ErrObjectNotExist will be returned if the object is not found.
+//
+// The caller must call Close on the returned EnvelopeReader when done
+// reading. Decrypted data is only returned once it has been authenticated;
+// if the object's data was modified or truncated, Read returns an error.
+func (o *ObjectHandle) NewEnvelopeReader(ctx context.Context, kw KeyWrapper) (*EnvelopeReader, error) {
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if alg := attrs.Metadata[envelopeAlgorithmKey]; alg != envelopeAlgorithm {
+		return nil, fmt.Errorf("storage: object %q is not envelope encrypted with a supported algorithm (got %q)", o.object, alg)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(attrs.Metadata[envelopeWrappedKey])
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid envelope key: %v", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(attrs.Metadata[envelopeNonceKey])
+	if err != nil || len(nonce) != envelopeNonceSize {
+		return nil, errors.New("storage: invalid envelope nonce")
+	}
+	key, err := kw.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("storage: unwrapping envelope key: %v", err)
+	}
+	r, err := o.Generation(attrs.Generation).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := newEnvelopeDecrypter(r, key, nonce)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &EnvelopeReader{r: r, dec: dec, attrs: attrs}, nil
+}
+
+// An EnvelopeReader reads and decrypts an object written by an
+// EnvelopeWriter.
+type EnvelopeReader struct {
+	r     *Reader
+	dec   *envelopeDecrypter
+	attrs *ObjectAttrs
+}
+
+// Read reads decrypted data from the object.
+func (r *EnvelopeReader) Read(p []byte) (int, error) {
+	return r.dec.Read(p)
+}
+
+// Close closes the EnvelopeReader. It must be called when done reading.
+func (r *EnvelopeReader) Close() error {
+	return r.r.Close()
+}
+
+// Attrs returns the attributes of the object being read. Its Size and
+// checksums are those of the encrypted data.
+func (r *EnvelopeReader) Attrs() *ObjectAttrs {
+	return r.attrs
+}
+
+func newEnvelopeAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid envelope key: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// envelopeSegmentNonce returns the nonce of segment seq: the random prefix,
+// the big-endian segment number and a byte that is 1 for the last segment.
+func envelopeSegmentNonce(prefix []byte, seq uint32, last bool) []byte {
+	nonce := make([]byte, envelopeNonceSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[envelopeNonceSize:], seq)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// envelopeEncrypter encrypts data written to it in segments and writes the
+// sealed segments to w.
+type envelopeEncrypter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	seq    uint32
+	buf    []byte
+}
+
+func newEnvelopeEncrypter(w io.Writer, key, prefix []byte) (*envelopeEncrypter, error) {
+	aead, err := newEnvelopeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeEncrypter{w: w, aead: aead, prefix: prefix}, nil
+}
+
+func (e *envelopeEncrypter) Write(p []byte) (int, error) {
+	n := len(p)
+	e.buf = append(e.buf, p...)
+	// Keep at least one byte buffered, so that the last segment is only
+	// sealed by Close.
+	for len(e.buf) > envelopeSegmentSize {
+		if err := e.seal(e.buf[:envelopeSegmentSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[envelopeSegmentSize:]
+	}
+	return n, nil
+}
+
+// Close seals and writes the last segment. It does not close w.
+func (e *envelopeEncrypter) Close() error {
+	err := e.seal(e.buf, true)
+	e.buf = nil
+	return err
+}
+
+func (e *envelopeEncrypter) seal(plain []byte, last bool) error {
+	if e.seq == ^uint32(0) {
+		return errors.New("storage: envelope encrypted data too large")
+	}
+	out := e.aead.Seal(nil, envelopeSegmentNonce(e.prefix, e.seq, last), plain, nil)
+	e.seq++
+	_, err := e.w.Write(out)
+	return err
+}
+
+// envelopeDecrypter reads sealed segments from r and returns their decrypted
+// contents.
+type envelopeDecrypter struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	seq    uint32
+	buf    []byte // sealed data read ahead from r.
+	plain  []byte // decrypted data not yet returned.
+	done   bool
+	err    error
+}
+
+func newEnvelopeDecrypter(r io.Reader, key, prefix []byte) (*envelopeDecrypter, error) {
+	aead, err := newEnvelopeAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeDecrypter{r: r, aead: aead, prefix: prefix}, nil
+}
+
+func (d *envelopeDecrypter) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.err = d.next()
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next reads and decrypts the next segment. A segment is the last one if r
+// ends before one more byte than a full segment could be read.
+func (d *envelopeDecrypter) next() error {
+	sealedSize := envelopeSegmentSize + d.aead.Overhead()
+	if cap(d.buf) < sealedSize+1 {
+		b := make([]byte, len(d.buf), sealedSize+1)
+		copy(b, d.buf)
+		d.buf = b
+	}
+	n, err := io.ReadFull(d.r, d.buf[len(d.buf):sealedSize+1])
+	d.buf = d.buf[:len(d.buf)+n]
+	last := false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+	sealed := d.buf
+	if !last {
+		sealed = d.buf[:sealedSize]
+	}
+	plain, err := d.aead.Open(nil, envelopeSegmentNonce(d.prefix, d.seq, last), sealed, nil)
+	if err != nil {
+		return errors.New("storage: envelope encrypted data is corrupt or truncated")
+	}
+	d.seq++
+	d.plain = plain
+	if last {
+		d.done = true
+		d.buf = d.buf[:0]
+	} else {
+		d.buf = append(d.buf[:0], d.buf[sealedSize:]...)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"google.golang.org/api/option"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// xorKeyWrapper is a KeyWrapper for tests that "wraps" keys by XORing them
+// with a byte.
+type xorKeyWrapper byte
+
+func (x xorKeyWrapper) xor(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[i] = b[i] ^ byte(x)
+	}
+	return out
+}
+
+func (x xorKeyWrapper) WrapKey(_ context.Context, key []byte) ([]byte, error) {
+	return x.xor(key), nil
+}
+
+func (x xorKeyWrapper) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	return x.xor(wrapped), nil
+}
+
+func encryptEnvelope(t *testing.T, key, prefix, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc, err := newEnvelopeEncrypter(&buf, key, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write in uneven pieces to exercise buffering across segments.
+	for len(data) > 0 {
+		n := 1000
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := enc.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decryptEnvelope(key, prefix, sealed []byte) ([]byte, error) {
+	dec, err := newEnvelopeDecrypter(iotest.HalfReader(bytes.NewReader(sealed)), key, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(dec)
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	prefix := []byte("nonce-7")
+	for _, size := range []int{0, 1, envelopeSegmentSize - 1, envelopeSegmentSize, envelopeSegmentSize + 1, 3*envelopeSegmentSize + 5} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			data := make([]byte, size)
+			for i := range data {
+				data[i] = byte(i)
+			}
+			sealed := encryptEnvelope(t, key, prefix, data)
+			nSegs := size/envelopeSegmentSize + 1
+			if size > 0 && size%envelopeSegmentSize == 0 {
+				nSegs--
+			}
+			if got, want := len(sealed), size+16*nSegs; got != want {
+				t.Errorf("got %d encrypted bytes, want %d", got, want)
+			}
+			got, err := decryptEnvelope(key, prefix, sealed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Error("decrypted data does not match")
+			}
+
+			tampered := append([]byte(nil), sealed...)
+			tampered[len(tampered)/2] ^= 1
+			if _, err := decryptEnvelope(key, prefix, tampered); err == nil {
+				t.Error("tampered data: got nil error, want non-nil")
+			}
+			if _, err := decryptEnvelope(key, prefix, sealed[:len(sealed)-1]); err == nil {
+				t.Error("truncated data: got nil error, want non-nil")
+			}
+			if nSegs > 1 {
+				// Drop the last segment entirely.
+				short := sealed[:(nSegs-1)*(envelopeSegmentSize+16)]
+				if _, err := decryptEnvelope(key, prefix, short); err == nil {
+					t.Error("missing last segment: got nil error, want non-nil")
+				}
+			}
+			if _, err := decryptEnvelope(bytes.Repeat([]byte{8}, 32), prefix, sealed); err == nil {
+				t.Error("wrong key: got nil error, want non-nil")
+			}
+		})
+	}
+}
+
+// envelopeServer stores a single object uploaded with a multipart upload and
+// serves its metadata and contents.
+type envelopeServer struct {
+	mu   sync.Mutex
+	obj  *raw.Object
+	data []byte
+}
+
+func (s *envelopeServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/upload/storage/v1/b/b/o":
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		var obj raw.Object
+		p, err := mr.NextPart()
+		if err == nil {
+			err = json.NewDecoder(p).Decode(&obj)
+		}
+		if err == nil {
+			p, err = mr.NextPart()
+		}
+		if err == nil {
+			s.data, err = ioutil.ReadAll(p)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		obj.Bucket, obj.Name, obj.Generation, obj.Size = "b", r.URL.Query().Get("name"), 3, uint64(len(s.data))
+		s.obj = &obj
+		json.NewEncoder(w).Encode(s.obj)
+	case s.obj == nil:
+		http.NotFound(w, r)
+	case r.URL.Path == "/storage/v1/b/b/o/obj":
+		json.NewEncoder(w).Encode(s.obj)
+	case r.URL.Path == "/b/obj" && r.URL.Query().Get("generation") == "3":
+		w.Write(s.data)
+	default:
+		http.Error(w, fmt.Sprintf("unexpected request %s %s", r.Method, r.URL), http.StatusBadRequest)
+	}
+}
+
+func TestEnvelopeWriterReader(t *testing.T) {
+	s := &envelopeServer{}
+	hc, close := newTestServer(s.handle)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := c.Bucket("b").Object("obj")
+	kw := xorKeyWrapper(0x5c)
+	data := strings.Repeat("secret data ", 10000)
+
+	w := obj.NewEnvelopeWriter(ctx, kw)
+	w.Writer.ContentType = "text/plain"
+	w.Writer.Metadata = map[string]string{"owner": "me"}
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(s.data, []byte("secret")) {
+		t.Error("uploaded data contains plaintext")
+	}
+	md := w.Attrs().Metadata
+	if got, want := md["owner"], "me"; got != want {
+		t.Errorf("got owner metadata %q, want %q", got, want)
+	}
+	if got, want := md[envelopeAlgorithmKey], envelopeAlgorithm; got != want {
+		t.Errorf("got algorithm %q, want %q", got, want)
+	}
+
+	r, err := obj.NewEnvelopeReader(ctx, kw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Error("read data does not match written data")
+	}
+
+	// A wrong key is only detected when the data is read.
+	r2, err := obj.NewEnvelopeReader(ctx, xorKeyWrapper(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	if _, err := ioutil.ReadAll(r2); err == nil {
+		t.Error("wrong key: got nil error, want non-nil")
+	}
+}
+
+func TestEnvelopeReaderNotEncrypted(t *testing.T) {
+	hc, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"bucket": "b", "name": "obj", "generation": "1"}`)
+	})
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Bucket("b").Object("obj").NewEnvelopeReader(ctx, xorKeyWrapper(1)); err == nil {
+		t.Error("got nil error, want non-nil")
+	}
+}
+
+func TestKMSKeyWrapper(t *testing.T) {
+	const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+	hc, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Plaintext  string `json:"plaintext"`
+			Ciphertext string `json:"ciphertext"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/" + keyName + ":encrypt":
+			b, _ := base64.StdEncoding.DecodeString(req.Plaintext)
+			fmt.Fprintf(w, `{"ciphertext": %q}`, base64.StdEncoding.EncodeToString(append([]byte("wrapped:"), b...)))
+		case "/v1/" + keyName + ":decrypt":
+			b, _ := base64.StdEncoding.DecodeString(req.Ciphertext)
+			fmt.Fprintf(w, `{"plaintext": %q}`, base64.StdEncoding.EncodeToString(bytes.TrimPrefix(b, []byte("wrapped:"))))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kw := c.KMSKeyWrapper(keyName)
+	wrapped, err := kw.WrapKey(ctx, []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(wrapped), "wrapped:key"; got != want {
+		t.Errorf("got wrapped key %q, want %q", got, want)
+	}
+	key, err := kw.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(key), "key"; got != want {
+		t.Errorf("got unwrapped key %q, want %q", got, want)
+	}
+	if _, err := c.KMSKeyWrapper("projects/p/locations/global/keyRings/r/cryptoKeys/other").WrapKey(ctx, []byte("key")); err == nil {
+		t.Error("unknown key: got nil error, want non-nil")
+	}
+}
@@ -182,6 +182,37 @@ func ExampleBucketHandle_Objects() {
 	_ = it // TODO: iterate using Next or iterator.Pager.
 }
 
+func ExampleBucketHandle_Batch() {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		// TODO: handle error.
+	}
+	b := client.Bucket("my-bucket")
+	// Delete every object under a prefix.
+	batch := b.Batch()
+	batch.RateLimit = 100
+	it := b.Objects(ctx, &storage.Query{Prefix: "logs/2021/"})
+	for {
+		objAttrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			// TODO: Handle error.
+		}
+		batch.Delete(objAttrs.Name)
+	}
+	results, err := batch.Run(ctx)
+	if err != nil {
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Printf("%s %s: %v\n", r.Op, r.Object, r.Err)
+			}
+		}
+	}
+}
+
 func ExampleBucketHandle_AddNotification() {
 	ctx := context.Background()
 	client, err := storage.NewClient(ctx)
@@ -235,6 +266,28 @@ func ExampleBucketHandle_Notifications() {
 	}
 }
 
+func ExampleBucketHandle_Watch() {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		// TODO: handle error.
+	}
+	w, err := client.Bucket("my-bucket").Watch(ctx, &storage.WatchOptions{
+		ProjectID:  "my-project",
+		EventTypes: []string{storage.ObjectFinalizeEvent, storage.ObjectDeleteEvent},
+	})
+	if err != nil {
+		// TODO: handle error.
+	}
+	defer w.Close()
+	for ev := range w.Events() {
+		fmt.Printf("%s: %s/%s#%d\n", ev.Type, ev.Bucket, ev.Object, ev.Generation)
+	}
+	if err := w.Err(); err != nil {
+		// TODO: handle error.
+	}
+}
+
 var notificationID string
 
 func ExampleBucketHandle_DeleteNotification() {
@@ -887,7 +940,7 @@ func ExampleObjectHandle_exists() {
 }
 
 func ExampleGenerateSignedPostPolicyV4() {
-	pv4, err := storage.GenerateSignedPostPolicyV4("my-bucket", "my-object.txt", &storage.PostPolicyV4Options{
+	pv4, err := storage.GenerateSignedPostPolicyV4("my-bucket", "uploads/my-object.txt", &storage.PostPolicyV4Options{
 		GoogleAccessID: "my-access-id",
 		PrivateKey:     []byte("my-private-key"),
 
@@ -905,6 +958,8 @@ func ExampleGenerateSignedPostPolicyV4() {
 		Conditions: []storage.PostPolicyV4Condition{
 			// Make the file a maximum of 10mB.
 			storage.ConditionContentLengthRange(0, 10<<20),
+			// Only allow keys under the "uploads/" prefix.
+			storage.ConditionStartsWith("$key", "uploads/"),
 		},
 	})
 	if err != nil {
@@ -946,3 +1001,57 @@ func ExampleGenerateSignedPostPolicyV4() {
 	}
 	_ = res
 }
+
+func ExampleNewLifecycle() {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		// TODO: handle error.
+	}
+	lifecycle, err := storage.NewLifecycle(
+		storage.DeleteRule().AgeInDays(30).MatchesPrefix("logs/"),
+		storage.SetStorageClassRule("COLDLINE").AgeInDays(90).MatchesStorageClasses("STANDARD"),
+		storage.AbortIncompleteMultipartUploadRule().AgeInDays(7),
+	)
+	if err != nil {
+		// TODO: handle error.
+	}
+	if _, err := client.Bucket("my-bucket").SetLifecycle(ctx, lifecycle); err != nil {
+		// TODO: handle error.
+	}
+}
+
+func ExampleObjectHandle_DownloadToFile() {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		// TODO: handle error.
+	}
+	obj := client.Bucket("my-bucket").Object("my-object")
+	// Calling DownloadToFile again after a failure continues from the
+	// partial file.
+	attrs, err := obj.DownloadToFile(ctx, "/tmp/my-object", &storage.FileDownloadOptions{Resume: true, Fsync: true})
+	if err != nil {
+		// TODO: handle error.
+	}
+	fmt.Printf("downloaded %d bytes\n", attrs.Size)
+}
+
+func ExampleClient_CopyPrefix() {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		// TODO: handle error.
+	}
+	// Copy everything under logs/2021/ to another bucket, keeping ACLs.
+	results, err := client.CopyPrefix(ctx, "my-bucket", "my-archive-bucket", "logs/2021/", &storage.CopyPrefixOptions{
+		PreserveACL: true,
+	})
+	if err != nil {
+		for _, r := range results {
+			if r.Err != nil {
+				fmt.Printf("%s: %v\n", r.Source, r.Err)
+			}
+		}
+	}
+}
@@ -0,0 +1,529 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	gax "github.com/googleapis/gax-go/v2"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrFolderNotExist indicates that the folder does not exist.
+var ErrFolderNotExist = errors.New("storage: folder doesn't exist")
+
+// FolderAttrs represents the metadata for a folder of a bucket with
+// hierarchical namespace enabled.
+// See https://cloud.google.com/storage/docs/hns-overview.
+type FolderAttrs struct {
+	// Bucket is the name of the bucket containing the folder.
+	// This field is read-only.
+	Bucket string
+
+	// Name is the name of the folder, which ends with a slash.
+	// This field is read-only.
+	Name string
+
+	// Metageneration is the version of the folder's metadata.
+	// This field is read-only.
+	Metageneration int64
+
+	// Created is the time the folder was created.
+	// This field is read-only.
+	Created time.Time
+
+	// Updated is the time the folder was last changed, for example by a
+	// rename.
+	// This field is read-only.
+	Updated time.Time
+
+	// PendingRenameOperation is the name of the rename operation in progress
+	// on the folder, if any. See BucketHandle.RenameFolderOperation.
+	// This field is read-only.
+	PendingRenameOperation string
+}
+
+type rawFolder struct {
+	Bucket            string `json:"bucket,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Metageneration    int64  `json:"metageneration,string,omitempty"`
+	CreateTime        string `json:"createTime,omitempty"`
+	UpdateTime        string `json:"updateTime,omitempty"`
+	PendingRenameInfo *struct {
+		OperationID string `json:"operationId,omitempty"`
+	} `json:"pendingRenameInfo,omitempty"`
+}
+
+func newFolder(f *rawFolder) *FolderAttrs {
+	attrs := &FolderAttrs{
+		Bucket:         f.Bucket,
+		Name:           f.Name,
+		Metageneration: f.Metageneration,
+		Created:        convertTime(f.CreateTime),
+		Updated:        convertTime(f.UpdateTime),
+	}
+	if f.PendingRenameInfo != nil {
+		attrs.PendingRenameOperation = f.PendingRenameInfo.OperationID
+	}
+	return attrs
+}
+
+// FolderConditions constrain folder and managed folder methods to act on
+// specific metagenerations.
+//
+// The zero value is an empty set of constraints.
+type FolderConditions struct {
+	// MetagenerationMatch specifies that the folder must have the given
+	// metageneration for the operation to occur.
+	// If MetagenerationMatch is zero, it has no effect.
+	MetagenerationMatch int64
+
+	// MetagenerationNotMatch specifies that the folder must not have the given
+	// metageneration for the operation to occur.
+	// If MetagenerationNotMatch is zero, it has no effect.
+	MetagenerationNotMatch int64
+}
+
+func (c *FolderConditions) validate(method string) error {
+	if *c == (FolderConditions{}) {
+		return fmt.Errorf("storage: %s: empty conditions", method)
+	}
+	if c.MetagenerationMatch != 0 && c.MetagenerationNotMatch != 0 {
+		return fmt.Errorf("storage: %s: multiple conditions specified for metageneration", method)
+	}
+	return nil
+}
+
+// applyFolderConds sets the query parameters for conds, which may be nil.
+func applyFolderConds(method string, conds *FolderConditions, query url.Values) error {
+	if conds == nil {
+		return nil
+	}
+	if err := conds.validate(method); err != nil {
+		return err
+	}
+	if conds.MetagenerationMatch != 0 {
+		query.Set("ifMetagenerationMatch", strconv.FormatInt(conds.MetagenerationMatch, 10))
+	}
+	if conds.MetagenerationNotMatch != 0 {
+		query.Set("ifMetagenerationNotMatch", strconv.FormatInt(conds.MetagenerationNotMatch, 10))
+	}
+	return nil
+}
+
+func folderNotExistErr(err error) error {
+	var e *googleapi.Error
+	if ok := xerrors.As(err, &e); ok && e.Code == http.StatusNotFound {
+		return ErrFolderNotExist
+	}
+	return err
+}
+
+// FolderHandle provides operations on a folder of a bucket with hierarchical
+// namespace enabled. Use BucketHandle.Folder to get a handle.
+type FolderHandle struct {
+	c           *Client
+	bucket      string
+	name        string
+	conds       *FolderConditions
+	userProject string
+	retry       *retryConfig
+}
+
+// Folder returns a FolderHandle, which provides operations on the named
+// folder. A slash is appended to name if it does not end with one. The bucket
+// must have hierarchical namespace enabled.
+// This call does not perform any network operations.
+func (b *BucketHandle) Folder(name string) *FolderHandle {
+	if !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+	return &FolderHandle{
+		c:           b.c,
+		bucket:      b.name,
+		name:        name,
+		userProject: b.userProject,
+		retry:       b.retry.clone(),
+	}
+}
+
+// Name returns the name of the folder, which ends with a slash.
+func (f *FolderHandle) Name() string {
+	return f.name
+}
+
+// If returns a new FolderHandle that applies a set of preconditions.
+// Preconditions already set on the FolderHandle are ignored. Attrs, Delete
+// and Rename take the conditions into account; for Rename, they apply to the
+// source folder.
+func (f *FolderHandle) If(conds FolderConditions) *FolderHandle {
+	f2 := *f
+	f2.conds = &conds
+	return &f2
+}
+
+func (f *FolderHandle) query() url.Values {
+	query := url.Values{}
+	if f.userProject != "" {
+		query.Set("userProject", f.userProject)
+	}
+	return query
+}
+
+func (f *FolderHandle) path() string {
+	return "b/" + url.PathEscape(f.bucket) + "/folders/" + url.PathEscape(f.name)
+}
+
+// CreateFolderOptions are options for FolderHandle.Create.
+type CreateFolderOptions struct {
+	// Recursive creates the parent folders of the folder that do not exist.
+	// Otherwise, creating a folder whose parent does not exist fails.
+	Recursive bool
+}
+
+// Create creates the folder, and returns its attributes. A nil opts uses the
+// defaults described in CreateFolderOptions.
+func (f *FolderHandle) Create(ctx context.Context, opts *CreateFolderOptions) (attrs *FolderAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Folder.Create")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := f.query()
+	if opts != nil && opts.Recursive {
+		query.Set("recursive", "true")
+	}
+	var resp rawFolder
+	// Creating a folder fails if it already exists, so it can be retried.
+	err = run(ctx, func() error {
+		return f.c.doJSON(ctx, "POST", "b/"+url.PathEscape(f.bucket)+"/folders", query, &rawFolder{Name: f.name}, &resp)
+	}, f.retry, true)
+	if err != nil {
+		return nil, bucketNotExistErr(err)
+	}
+	return newFolder(&resp), nil
+}
+
+// Attrs returns the metadata for the folder.
+// ErrFolderNotExist will be returned if the folder does not exist.
+func (f *FolderHandle) Attrs(ctx context.Context) (attrs *FolderAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Folder.Attrs")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := f.query()
+	if err := applyFolderConds("FolderHandle.Attrs", f.conds, query); err != nil {
+		return nil, err
+	}
+	var resp rawFolder
+	err = run(ctx, func() error {
+		return f.c.doJSON(ctx, "GET", f.path(), query, nil, &resp)
+	}, f.retry, true)
+	if err != nil {
+		return nil, folderNotExistErr(err)
+	}
+	return newFolder(&resp), nil
+}
+
+// Delete deletes the folder, which must be empty.
+// ErrFolderNotExist will be returned if the folder does not exist.
+func (f *FolderHandle) Delete(ctx context.Context) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Folder.Delete")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := f.query()
+	if err := applyFolderConds("FolderHandle.Delete", f.conds, query); err != nil {
+		return err
+	}
+	err = run(ctx, func() error {
+		return f.c.doJSON(ctx, "DELETE", f.path(), query, nil, nil)
+	}, f.retry, true)
+	return folderNotExistErr(err)
+}
+
+// Rename starts renaming the folder, with the folders and objects it
+// contains, to dst. A slash is appended to dst if it does not end with one.
+// Rename returns a long-running operation; use its Wait method to wait for
+// the rename to complete.
+func (f *FolderHandle) Rename(ctx context.Context, dst string) (op *RenameFolderOperation, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Folder.Rename")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if !strings.HasSuffix(dst, "/") {
+		dst += "/"
+	}
+	query := f.query()
+	if f.conds != nil {
+		if err := f.conds.validate("FolderHandle.Rename"); err != nil {
+			return nil, err
+		}
+		if f.conds.MetagenerationMatch != 0 {
+			query.Set("ifSourceMetagenerationMatch", strconv.FormatInt(f.conds.MetagenerationMatch, 10))
+		}
+		if f.conds.MetagenerationNotMatch != 0 {
+			query.Set("ifSourceMetagenerationNotMatch", strconv.FormatInt(f.conds.MetagenerationNotMatch, 10))
+		}
+	}
+	var resp rawOperation
+	isIdempotent := f.conds != nil && f.conds.MetagenerationMatch != 0
+	err = run(ctx, func() error {
+		return f.c.doJSON(ctx, "POST", f.path()+"/renameTo/folders/"+url.PathEscape(dst), query, nil, &resp)
+	}, f.retry, isIdempotent)
+	if err != nil {
+		return nil, folderNotExistErr(err)
+	}
+	op = newRenameFolderOperation(f.c, f.bucket, resp.Name, f.userProject, f.retry)
+	if err := op.update(&resp); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func newRenameFolderOperation(c *Client, bucket, name, userProject string, retry *retryConfig) *RenameFolderOperation {
+	return &RenameFolderOperation{
+		c:           c,
+		bucket:      bucket,
+		name:        name,
+		userProject: userProject,
+		retry:       retry,
+		backoff:     gax.Backoff{Initial: time.Second, Max: 30 * time.Second, Multiplier: 2},
+	}
+}
+
+// RenameFolderOperation returns the folder rename operation with the given
+// name, for example to wait for a rename started by another process. The name
+// can be found in FolderAttrs.PendingRenameOperation or from
+// RenameFolderOperation.Name.
+// This call does not perform any network operations.
+func (b *BucketHandle) RenameFolderOperation(name string) *RenameFolderOperation {
+	return newRenameFolderOperation(b.c, b.name, name, b.userProject, b.retry)
+}
+
+// RenameFolderOperation is a long-running folder rename, started by
+// FolderHandle.Rename.
+type RenameFolderOperation struct {
+	c           *Client
+	bucket      string
+	name        string
+	userProject string
+	retry       *retryConfig
+	backoff     gax.Backoff
+
+	done  bool
+	attrs *FolderAttrs
+	err   error
+}
+
+type rawOperation struct {
+	Name  string `json:"name,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+	Error *struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"error,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// Name returns the name of the operation.
+func (op *RenameFolderOperation) Name() string {
+	return op.name
+}
+
+// Done reports whether the rename has completed, as of the last call to
+// Poll or Wait.
+func (op *RenameFolderOperation) Done() bool {
+	return op.done
+}
+
+// Poll fetches the status of the operation. If the rename has completed, it
+// returns the attributes of the renamed folder or the error of the rename.
+// Otherwise, it returns nil, nil.
+func (op *RenameFolderOperation) Poll(ctx context.Context) (attrs *FolderAttrs, err error) {
+	if op.done {
+		return op.attrs, op.err
+	}
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.RenameFolderOperation.Poll")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	i := strings.LastIndex(op.name, "/operations/")
+	if i < 0 {
+		return nil, fmt.Errorf("storage: invalid operation name %q", op.name)
+	}
+	query := url.Values{}
+	if op.userProject != "" {
+		query.Set("userProject", op.userProject)
+	}
+	path := "b/" + url.PathEscape(op.bucket) + "/operations/" + url.PathEscape(op.name[i+len("/operations/"):])
+	var resp rawOperation
+	err = run(ctx, func() error {
+		return op.c.doJSON(ctx, "GET", path, query, nil, &resp)
+	}, op.retry, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := op.update(&resp); err != nil {
+		return nil, err
+	}
+	return op.attrs, op.err
+}
+
+// Wait polls the operation until the rename completes or ctx is done, and
+// returns the attributes of the renamed folder.
+func (op *RenameFolderOperation) Wait(ctx context.Context) (*FolderAttrs, error) {
+	for {
+		attrs, err := op.Poll(ctx)
+		if err != nil || op.done {
+			return attrs, err
+		}
+		if err := gax.Sleep(ctx, op.backoff.Pause()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// update records the state of a completed operation.
+func (op *RenameFolderOperation) update(resp *rawOperation) error {
+	if !resp.Done {
+		return nil
+	}
+	if resp.Error != nil {
+		op.done = true
+		op.err = status.Error(codes.Code(resp.Error.Code), resp.Error.Message)
+		return nil
+	}
+	var f rawFolder
+	if len(resp.Response) > 0 {
+		if err := json.Unmarshal(resp.Response, &f); err != nil {
+			return err
+		}
+	}
+	op.done = true
+	op.attrs = newFolder(&f)
+	return nil
+}
+
+// FolderQuery represents a query to filter folders from a bucket.
+type FolderQuery struct {
+	// Prefix is the prefix filter to query folders whose names begin with
+	// this prefix.
+	// Optional.
+	Prefix string
+
+	// Delimiter returns only the folders directly under Prefix when set to
+	// "/". Delimiter must be empty or "/".
+	// Optional.
+	Delimiter string
+
+	// StartOffset is used to filter results to folders whose names are
+	// lexicographically equal to or after StartOffset.
+	// Optional.
+	StartOffset string
+
+	// EndOffset is used to filter results to folders whose names are
+	// lexicographically before EndOffset.
+	// Optional.
+	EndOffset string
+}
+
+// Folders returns an iterator over the folders in the bucket that match the
+// Query q. If q is nil, no filtering is done.
+func (b *BucketHandle) Folders(ctx context.Context, q *FolderQuery) *FolderIterator {
+	it := &FolderIterator{
+		ctx:    ctx,
+		bucket: b,
+	}
+	if q != nil {
+		it.query = *q
+	}
+	it.pageInfo, it.nextFunc = iterator.NewPageInfo(
+		it.fetch,
+		func() int { return len(it.items) },
+		func() interface{} { b := it.items; it.items = nil; return b })
+	return it
+}
+
+// A FolderIterator is an iterator over FolderAttrs.
+//
+// Note: This iterator is not safe for concurrent operations without explicit synchronization.
+type FolderIterator struct {
+	ctx      context.Context
+	bucket   *BucketHandle
+	query    FolderQuery
+	pageInfo *iterator.PageInfo
+	nextFunc func() error
+	items    []*FolderAttrs
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+//
+// Note: This method is not safe for concurrent operations without explicit synchronization.
+func (it *FolderIterator) PageInfo() *iterator.PageInfo { return it.pageInfo }
+
+// Next returns the next result. Its second return value is iterator.Done if
+// there are no more results. Once Next returns iterator.Done, all subsequent
+// calls will return iterator.Done.
+//
+// Note: This method is not safe for concurrent operations without explicit synchronization.
+func (it *FolderIterator) Next() (*FolderAttrs, error) {
+	if err := it.nextFunc(); err != nil {
+		return nil, err
+	}
+	item := it.items[0]
+	it.items = it.items[1:]
+	return item, nil
+}
+
+func (it *FolderIterator) fetch(pageSize int, pageToken string) (string, error) {
+	query := url.Values{}
+	for k, v := range map[string]string{
+		"prefix":      it.query.Prefix,
+		"delimiter":   it.query.Delimiter,
+		"startOffset": it.query.StartOffset,
+		"endOffset":   it.query.EndOffset,
+		"pageToken":   pageToken,
+		"userProject": it.bucket.userProject,
+	} {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	if pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(pageSize))
+	}
+	var resp struct {
+		Items         []*rawFolder `json:"items"`
+		NextPageToken string       `json:"nextPageToken"`
+	}
+	err := run(it.ctx, func() error {
+		return it.bucket.c.doJSON(it.ctx, "GET", "b/"+url.PathEscape(it.bucket.name)+"/folders", query, nil, &resp)
+	}, it.bucket.retry, true)
+	if err != nil {
+		return "", bucketNotExistErr(err)
+	}
+	for _, f := range resp.Items {
+		it.items = append(it.items, newFolder(f))
+	}
+	return resp.NextPageToken, nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	gax "github.com/googleapis/gax-go/v2"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFolder(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /storage/v1/b/b/folders":
+			fmt.Fprint(w, `{"bucket": "b", "name": "a/b/", "metageneration": "1", "createTime": "2022-03-01T10:00:00Z", "updateTime": "2022-03-01T10:00:00Z"}`)
+		case "GET /storage/v1/b/b/folders/a/b/":
+			fmt.Fprint(w, `{"bucket": "b", "name": "a/b/", "metageneration": "2", "pendingRenameInfo": {"operationId": "projects/_/buckets/b/operations/op1"}}`)
+		case "DELETE /storage/v1/b/b/folders/a/b/":
+		case "GET /storage/v1/b/b/folders/missing/":
+			http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	b := c.Bucket("b").UserProject("p")
+
+	created, err := b.Folder("a/b").Create(ctx, &CreateFolderOptions{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	want := &FolderAttrs{Bucket: "b", Name: "a/b/", Metageneration: 1, Created: day, Updated: day}
+	if diff := cmp.Diff(want, created); diff != "" {
+		t.Errorf("Create mismatch (-want +got):\n%s", diff)
+	}
+	attrs, err := b.Folder("a/b/").If(FolderConditions{MetagenerationMatch: 2}).Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &FolderAttrs{Bucket: "b", Name: "a/b/", Metageneration: 2, PendingRenameOperation: "projects/_/buckets/b/operations/op1"}
+	if diff := cmp.Diff(want, attrs); diff != "" {
+		t.Errorf("Attrs mismatch (-want +got):\n%s", diff)
+	}
+	if err := b.Folder("a/b/").If(FolderConditions{MetagenerationNotMatch: 3}).Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Folder("missing").Attrs(ctx); err != ErrFolderNotExist {
+		t.Errorf("missing folder: got error %v, want %v", err, ErrFolderNotExist)
+	}
+	if err := b.Folder("a").If(FolderConditions{}).Delete(ctx); err == nil {
+		t.Error("empty conditions: got nil error, want non-nil")
+	}
+
+	wantReqs := []recordedRequest{
+		{"POST", "/storage/v1/b/b/folders", url.Values{"recursive": {"true"}, "userProject": {"p"}}, `{"name":"a/b/"}`},
+		{"GET", "/storage/v1/b/b/folders/a/b/", url.Values{"ifMetagenerationMatch": {"2"}, "userProject": {"p"}}, ""},
+		{"DELETE", "/storage/v1/b/b/folders/a/b/", url.Values{"ifMetagenerationNotMatch": {"3"}, "userProject": {"p"}}, ""},
+		{"GET", "/storage/v1/b/b/folders/missing/", url.Values{"userProject": {"p"}}, ""},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenameFolder(t *testing.T) {
+	var reqs []recordedRequest
+	polls := 0
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /storage/v1/b/b/folders/a//renameTo/folders/c/":
+			fmt.Fprint(w, `{"name": "projects/_/buckets/b/operations/op1", "done": false}`)
+		case "GET /storage/v1/b/b/operations/op1":
+			if polls++; polls < 2 {
+				fmt.Fprint(w, `{"name": "projects/_/buckets/b/operations/op1", "done": false}`)
+				return
+			}
+			fmt.Fprint(w, `{"name": "projects/_/buckets/b/operations/op1", "done": true,
+				"response": {"@type": "type.googleapis.com/google.storage.control.v2.Folder", "bucket": "b", "name": "c/", "metageneration": "1"}}`)
+		case "GET /storage/v1/b/b/operations/op2":
+			fmt.Fprint(w, `{"name": "projects/_/buckets/b/operations/op2", "done": true, "error": {"code": 9, "message": "not empty"}}`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	b := c.Bucket("b")
+
+	op, err := b.Folder("a/").If(FolderConditions{MetagenerationMatch: 4}).Rename(ctx, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Done() {
+		t.Error("operation done before polling")
+	}
+	if got, want := op.Name(), "projects/_/buckets/b/operations/op1"; got != want {
+		t.Errorf("got operation name %q, want %q", got, want)
+	}
+	op.backoff = gax.Backoff{Initial: time.Millisecond}
+	got, err := op.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&FolderAttrs{Bucket: "b", Name: "c/", Metageneration: 1}, got); diff != "" {
+		t.Errorf("renamed folder mismatch (-want +got):\n%s", diff)
+	}
+	if !op.Done() {
+		t.Error("operation not done after Wait")
+	}
+	// A completed operation is not polled again.
+	n := len(reqs)
+	if _, err := op.Poll(ctx); err != nil || len(reqs) != n {
+		t.Errorf("Poll after completion: got error %v and %d requests, want nil and none", err, len(reqs)-n)
+	}
+
+	failed := b.RenameFolderOperation("projects/_/buckets/b/operations/op2")
+	if _, err := failed.Poll(ctx); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("failed operation: got error %v, want code %v", err, codes.FailedPrecondition)
+	}
+
+	wantReqs := []recordedRequest{
+		{"POST", "/storage/v1/b/b/folders/a//renameTo/folders/c/", url.Values{"ifSourceMetagenerationMatch": {"4"}}, ""},
+		{"GET", "/storage/v1/b/b/operations/op1", url.Values{}, ""},
+		{"GET", "/storage/v1/b/b/operations/op1", url.Values{}, ""},
+		{"GET", "/storage/v1/b/b/operations/op2", url.Values{}, ""},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFolderIterator(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprint(w, `{"items": [{"name": "a/"}, {"name": "a/b/"}], "nextPageToken": "t"}`)
+		} else {
+			fmt.Fprint(w, `{"items": [{"name": "a/c/"}]}`)
+		}
+	})
+	defer close()
+	it := c.Bucket("b").Folders(context.Background(), &FolderQuery{Prefix: "a/", Delimiter: "/", StartOffset: "a/"})
+	var got []string
+	for {
+		f, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, f.Name)
+	}
+	if diff := cmp.Diff([]string{"a/", "a/b/", "a/c/"}, got); diff != "" {
+		t.Errorf("folders mismatch (-want +got):\n%s", diff)
+	}
+	wantReqs := []recordedRequest{
+		{"GET", "/storage/v1/b/b/folders", url.Values{"prefix": {"a/"}, "delimiter": {"/"}, "startOffset": {"a/"}}, ""},
+		{"GET", "/storage/v1/b/b/folders", url.Values{"prefix": {"a/"}, "delimiter": {"/"}, "startOffset": {"a/"}, "pageToken": {"t"}}, ""},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -87,10 +87,11 @@ type HMACKey struct {
 //
 // This type is EXPERIMENTAL and subject to change or removal without notice.
 type HMACKeyHandle struct {
-	projectID string
-	accessID  string
-	retry     *retryConfig
-	raw       *raw.ProjectsHmacKeysService
+	projectID   string
+	accessID    string
+	userProject string
+	retry       *retryConfig
+	raw         *raw.ProjectsHmacKeysService
 }
 
 // HMACKeyHandle creates a handle that will be used for HMACKey operations.
@@ -98,10 +99,11 @@ type HMACKeyHandle struct {
 // This method is EXPERIMENTAL and subject to change or removal without notice.
 func (c *Client) HMACKeyHandle(projectID, accessID string) *HMACKeyHandle {
 	return &HMACKeyHandle{
-		projectID: projectID,
-		accessID:  accessID,
-		retry:     c.retry,
-		raw:       raw.NewProjectsHmacKeysService(c.raw),
+		projectID:   projectID,
+		accessID:    accessID,
+		userProject: c.userProject,
+		retry:       c.retry,
+		raw:         raw.NewProjectsHmacKeysService(c.raw),
 	}
 }
 
@@ -115,7 +117,7 @@ func (c *Client) HMACKeyHandle(projectID, accessID string) *HMACKeyHandle {
 func (hkh *HMACKeyHandle) Get(ctx context.Context, opts ...HMACKeyOption) (*HMACKey, error) {
 	call := hkh.raw.Get(hkh.projectID, hkh.accessID)
 
-	desc := new(hmacKeyDesc)
+	desc := &hmacKeyDesc{userProjectID: hkh.userProject}
 	for _, opt := range opts {
 		opt.withHMACKeyDesc(desc)
 	}
@@ -148,7 +150,7 @@ func (hkh *HMACKeyHandle) Get(ctx context.Context, opts ...HMACKeyOption) (*HMAC
 // This method is EXPERIMENTAL and subject to change or removal without notice.
 func (hkh *HMACKeyHandle) Delete(ctx context.Context, opts ...HMACKeyOption) error {
 	delCall := hkh.raw.Delete(hkh.projectID, hkh.accessID)
-	desc := new(hmacKeyDesc)
+	desc := &hmacKeyDesc{userProjectID: hkh.userProject}
 	for _, opt := range opts {
 		opt.withHMACKeyDesc(desc)
 	}
@@ -205,7 +207,7 @@ func (c *Client) CreateHMACKey(ctx context.Context, projectID, serviceAccountEma
 
 	svc := raw.NewProjectsHmacKeysService(c.raw)
 	call := svc.Create(projectID, serviceAccountEmail)
-	desc := new(hmacKeyDesc)
+	desc := &hmacKeyDesc{userProjectID: c.userProject}
 	for _, opt := range opts {
 		opt.withHMACKeyDesc(desc)
 	}
@@ -252,7 +254,7 @@ func (h *HMACKeyHandle) Update(ctx context.Context, au HMACKeyAttrsToUpdate, opt
 		State: string(au.State),
 	})
 
-	desc := new(hmacKeyDesc)
+	desc := &hmacKeyDesc{userProjectID: h.userProject}
 	for _, opt := range opts {
 		opt.withHMACKeyDesc(desc)
 	}
@@ -306,6 +308,7 @@ func (c *Client) ListHMACKeys(ctx context.Context, projectID string, opts ...HMA
 		raw:       raw.NewProjectsHmacKeysService(c.raw),
 		projectID: projectID,
 		retry:     c.retry,
+		desc:      hmacKeyDesc{userProjectID: c.userProject},
 	}
 
 	for _, opt := range opts {
@@ -20,6 +20,7 @@ import (
 	"net"
 	"net/url"
 	"strings"
+	"sync"
 
 	"cloud.google.com/go/internal"
 	gax "github.com/googleapis/gax-go/v2"
@@ -51,12 +52,42 @@ func run(ctx context.Context, call func() error, retry *retryConfig, isIdempoten
 	if retry.shouldRetry != nil {
 		errorFunc = retry.shouldRetry
 	}
+	var attempts int
 	return internal.Retry(ctx, bo, func() (stop bool, err error) {
+		attempts++
 		err = call()
+		if retry.maxAttempts != nil && attempts >= *retry.maxAttempts {
+			return true, err
+		}
 		return !errorFunc(err), err
 	})
 }
 
+// uploadErrorFunc returns the error func to use for retries of a Writer's
+// upload, which is retried by the googleapi media upload machinery rather than
+// by run. It applies the configured maximum number of attempts across the
+// upload.
+func (r *retryConfig) uploadErrorFunc() func(err error) bool {
+	if r.maxAttempts == nil {
+		return r.shouldRetry
+	}
+	errorFunc := shouldRetry
+	if r.shouldRetry != nil {
+		errorFunc = r.shouldRetry
+	}
+	var mu sync.Mutex
+	attempts := 1
+	return func(err error) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts >= *r.maxAttempts || !errorFunc(err) {
+			return false
+		}
+		attempts++
+		return true
+	}
+}
+
 func shouldRetry(err error) bool {
 	if err == nil {
 		return false
@@ -20,6 +20,7 @@ import (
 	"io"
 	"net"
 	"net/url"
+	"reflect"
 	"testing"
 
 	"golang.org/x/xerrors"
@@ -161,6 +162,24 @@ func TestInvoke(t *testing.T) {
 			},
 			expectFinalErr: false,
 		},
+		{
+			desc:              "retriable error retried within max attempts",
+			count:             2,
+			initialErr:        &googleapi.Error{Code: 503},
+			finalErr:          nil,
+			isIdempotentValue: true,
+			retry:             &retryConfig{maxAttempts: intPtr(3)},
+			expectFinalErr:    true,
+		},
+		{
+			desc:              "retriable error returned after max attempts",
+			count:             3,
+			initialErr:        &googleapi.Error{Code: 503},
+			finalErr:          nil,
+			isIdempotentValue: true,
+			retry:             &retryConfig{maxAttempts: intPtr(3)},
+			expectFinalErr:    false,
+		},
 	} {
 		t.Run(test.desc, func(s *testing.T) {
 			counter := 0
@@ -181,6 +200,28 @@ func TestInvoke(t *testing.T) {
 	}
 }
 
+func intPtr(n int) *int { return &n }
+
+func TestUploadErrorFunc(t *testing.T) {
+	t.Parallel()
+	retryable := &googleapi.Error{Code: 503}
+	f := (&retryConfig{maxAttempts: intPtr(3)}).uploadErrorFunc()
+	var got []bool
+	for i := 0; i < 3; i++ {
+		got = append(got, f(retryable))
+	}
+	// Two retries after the first attempt.
+	if want := []bool{true, true, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if f := (&retryConfig{maxAttempts: intPtr(5)}).uploadErrorFunc(); f(errors.New("not retryable")) {
+		t.Error("non-retryable error was retried")
+	}
+	if f := (&retryConfig{}).uploadErrorFunc(); f != nil {
+		t.Error("got non-nil error func without max attempts, want the default")
+	}
+}
+
 func TestShouldRetry(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,385 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+// storageClasses are the storage classes that lifecycle rules accept.
+var storageClasses = map[string]bool{
+	"STANDARD":                     true,
+	"NEARLINE":                     true,
+	"COLDLINE":                     true,
+	"ARCHIVE":                      true,
+	"MULTI_REGIONAL":               true,
+	"REGIONAL":                     true,
+	"DURABLE_REDUCED_AVAILABILITY": true,
+}
+
+// LifecycleRuleBuilder builds a LifecycleRule, checking that its action and
+// conditions can be combined. Start with DeleteRule, SetStorageClassRule or
+// AbortIncompleteMultipartUploadRule, add conditions, and call Build, or
+// pass the builders to NewLifecycle. The first invalid condition is reported
+// by Build.
+type LifecycleRuleBuilder struct {
+	rule LifecycleRule
+	err  error
+}
+
+// DeleteRule starts building a rule that deletes matching objects.
+func DeleteRule() *LifecycleRuleBuilder {
+	return &LifecycleRuleBuilder{rule: LifecycleRule{Action: LifecycleAction{Type: DeleteAction}}}
+}
+
+// SetStorageClassRule starts building a rule that changes the storage class
+// of matching objects to storageClass, such as "COLDLINE".
+func SetStorageClassRule(storageClass string) *LifecycleRuleBuilder {
+	b := &LifecycleRuleBuilder{rule: LifecycleRule{Action: LifecycleAction{Type: SetStorageClassAction, StorageClass: storageClass}}}
+	if !storageClasses[storageClass] {
+		b.err = fmt.Errorf("storage: invalid storage class %q", storageClass)
+	}
+	return b
+}
+
+// AbortIncompleteMultipartUploadRule starts building a rule that aborts
+// matching incomplete XML API multipart uploads. Such rules accept only the
+// AgeInDays, MatchesPrefix and MatchesSuffix conditions.
+func AbortIncompleteMultipartUploadRule() *LifecycleRuleBuilder {
+	return &LifecycleRuleBuilder{rule: LifecycleRule{Action: LifecycleAction{Type: AbortIncompleteMPUAction}}}
+}
+
+func (b *LifecycleRuleBuilder) setErr(format string, args ...interface{}) *LifecycleRuleBuilder {
+	if b.err == nil {
+		b.err = fmt.Errorf("storage: "+format, args...)
+	}
+	return b
+}
+
+func (b *LifecycleRuleBuilder) days(name string, days int64, field *int64) *LifecycleRuleBuilder {
+	if days < 0 {
+		return b.setErr("%s must not be negative, got %d", name, days)
+	}
+	*field = days
+	return b
+}
+
+// AgeInDays matches objects at least days old.
+func (b *LifecycleRuleBuilder) AgeInDays(days int64) *LifecycleRuleBuilder {
+	return b.days("AgeInDays", days, &b.rule.Condition.AgeInDays)
+}
+
+// CreatedBefore matches objects created before midnight UTC of the date of t.
+func (b *LifecycleRuleBuilder) CreatedBefore(t time.Time) *LifecycleRuleBuilder {
+	b.rule.Condition.CreatedBefore = t
+	return b
+}
+
+// CustomTimeBefore matches objects whose CustomTime is before midnight UTC
+// of the date of t.
+func (b *LifecycleRuleBuilder) CustomTimeBefore(t time.Time) *LifecycleRuleBuilder {
+	b.rule.Condition.CustomTimeBefore = t
+	return b
+}
+
+// DaysSinceCustomTime matches objects whose CustomTime is at least days old.
+func (b *LifecycleRuleBuilder) DaysSinceCustomTime(days int64) *LifecycleRuleBuilder {
+	return b.days("DaysSinceCustomTime", days, &b.rule.Condition.DaysSinceCustomTime)
+}
+
+// DaysSinceNoncurrentTime matches archived objects that became noncurrent at
+// least days ago.
+func (b *LifecycleRuleBuilder) DaysSinceNoncurrentTime(days int64) *LifecycleRuleBuilder {
+	return b.days("DaysSinceNoncurrentTime", days, &b.rule.Condition.DaysSinceNoncurrentTime)
+}
+
+// NoncurrentTimeBefore matches archived objects that became noncurrent
+// before midnight UTC of the date of t.
+func (b *LifecycleRuleBuilder) NoncurrentTimeBefore(t time.Time) *LifecycleRuleBuilder {
+	b.rule.Condition.NoncurrentTimeBefore = t
+	return b
+}
+
+// NumNewerVersions matches objects with at least n newer versions.
+func (b *LifecycleRuleBuilder) NumNewerVersions(n int64) *LifecycleRuleBuilder {
+	return b.days("NumNewerVersions", n, &b.rule.Condition.NumNewerVersions)
+}
+
+// Liveness matches live or archived objects only.
+func (b *LifecycleRuleBuilder) Liveness(l Liveness) *LifecycleRuleBuilder {
+	if l < LiveAndArchived || l > Archived {
+		return b.setErr("invalid Liveness %d", l)
+	}
+	b.rule.Condition.Liveness = l
+	return b
+}
+
+// MatchesStorageClasses matches objects in any of the storage classes.
+func (b *LifecycleRuleBuilder) MatchesStorageClasses(classes ...string) *LifecycleRuleBuilder {
+	for _, c := range classes {
+		if !storageClasses[c] {
+			return b.setErr("invalid storage class %q", c)
+		}
+	}
+	b.rule.Condition.MatchesStorageClasses = append(b.rule.Condition.MatchesStorageClasses, classes...)
+	return b
+}
+
+// MatchesPrefix matches objects whose names start with any of the prefixes.
+func (b *LifecycleRuleBuilder) MatchesPrefix(prefixes ...string) *LifecycleRuleBuilder {
+	for _, p := range prefixes {
+		if p == "" {
+			return b.setErr("empty MatchesPrefix")
+		}
+	}
+	b.rule.Condition.MatchesPrefix = append(b.rule.Condition.MatchesPrefix, prefixes...)
+	return b
+}
+
+// MatchesSuffix matches objects whose names end with any of the suffixes.
+func (b *LifecycleRuleBuilder) MatchesSuffix(suffixes ...string) *LifecycleRuleBuilder {
+	for _, s := range suffixes {
+		if s == "" {
+			return b.setErr("empty MatchesSuffix")
+		}
+	}
+	b.rule.Condition.MatchesSuffix = append(b.rule.Condition.MatchesSuffix, suffixes...)
+	return b
+}
+
+// Build returns the rule, or the first error found in it.
+func (b *LifecycleRuleBuilder) Build() (LifecycleRule, error) {
+	if b.err != nil {
+		return LifecycleRule{}, b.err
+	}
+	if err := b.rule.validate(); err != nil {
+		return LifecycleRule{}, err
+	}
+	return b.rule, nil
+}
+
+func (r *LifecycleRule) validate() error {
+	c := &r.Condition
+	noCondition := c.AgeInDays == 0 && c.CreatedBefore.IsZero() &&
+		c.CustomTimeBefore.IsZero() && c.DaysSinceCustomTime == 0 &&
+		c.DaysSinceNoncurrentTime == 0 && c.NoncurrentTimeBefore.IsZero() &&
+		c.NumNewerVersions == 0 && c.Liveness == LiveAndArchived &&
+		len(c.MatchesStorageClasses) == 0 && len(c.MatchesPrefix) == 0 && len(c.MatchesSuffix) == 0
+	if noCondition {
+		return errors.New("storage: lifecycle rule has no condition")
+	}
+	if c.Liveness == Live && (c.DaysSinceNoncurrentTime != 0 || !c.NoncurrentTimeBefore.IsZero() || c.NumNewerVersions != 0) {
+		return errors.New("storage: noncurrent conditions never match live objects")
+	}
+	if r.Action.Type == AbortIncompleteMPUAction {
+		onlyUploads := c.CreatedBefore.IsZero() && c.CustomTimeBefore.IsZero() &&
+			c.DaysSinceCustomTime == 0 && c.DaysSinceNoncurrentTime == 0 &&
+			c.NoncurrentTimeBefore.IsZero() && c.NumNewerVersions == 0 &&
+			c.Liveness == LiveAndArchived && len(c.MatchesStorageClasses) == 0
+		if !onlyUploads {
+			return fmt.Errorf("storage: %s rules accept only AgeInDays, MatchesPrefix and MatchesSuffix conditions", AbortIncompleteMPUAction)
+		}
+	}
+	return nil
+}
+
+// NewLifecycle builds a lifecycle configuration from rule builders, and
+// returns the first error found in them.
+func NewLifecycle(rules ...*LifecycleRuleBuilder) (Lifecycle, error) {
+	var l Lifecycle
+	for i, b := range rules {
+		r, err := b.Build()
+		if err != nil {
+			return Lifecycle{}, fmt.Errorf("%v (rule %d)", err, i)
+		}
+		l.Rules = append(l.Rules, r)
+	}
+	return l, nil
+}
+
+// usesJSONOnlyFields reports whether the lifecycle configuration uses
+// features that can only be set with BucketHandle.SetLifecycle.
+func (l *Lifecycle) usesJSONOnlyFields() bool {
+	for _, r := range l.Rules {
+		if r.Action.Type == AbortIncompleteMPUAction || len(r.Condition.MatchesPrefix) > 0 || len(r.Condition.MatchesSuffix) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+var errLifecycleJSONOnly = fmt.Errorf("storage: lifecycle rules with MatchesPrefix, MatchesSuffix or the %s action must be set with BucketHandle.SetLifecycle", AbortIncompleteMPUAction)
+
+type rawLifecycleCondition struct {
+	Age                     int64    `json:"age,omitempty"`
+	CreatedBefore           string   `json:"createdBefore,omitempty"`
+	CustomTimeBefore        string   `json:"customTimeBefore,omitempty"`
+	DaysSinceCustomTime     int64    `json:"daysSinceCustomTime,omitempty"`
+	DaysSinceNoncurrentTime int64    `json:"daysSinceNoncurrentTime,omitempty"`
+	IsLive                  *bool    `json:"isLive,omitempty"`
+	MatchesStorageClass     []string `json:"matchesStorageClass,omitempty"`
+	MatchesPrefix           []string `json:"matchesPrefix,omitempty"`
+	MatchesSuffix           []string `json:"matchesSuffix,omitempty"`
+	NoncurrentTimeBefore    string   `json:"noncurrentTimeBefore,omitempty"`
+	NumNewerVersions        int64    `json:"numNewerVersions,omitempty"`
+}
+
+type rawLifecycleRule struct {
+	Action struct {
+		Type         string `json:"type"`
+		StorageClass string `json:"storageClass,omitempty"`
+	} `json:"action"`
+	Condition rawLifecycleCondition `json:"condition"`
+}
+
+type rawLifecycleBucket struct {
+	Lifecycle *struct {
+		Rule []*rawLifecycleRule `json:"rule"`
+	} `json:"lifecycle"`
+}
+
+func toRawLifecycleRules(l Lifecycle) []*rawLifecycleRule {
+	rules := []*rawLifecycleRule{}
+	for _, r := range l.Rules {
+		rr := &rawLifecycleRule{}
+		rr.Action.Type = r.Action.Type
+		rr.Action.StorageClass = r.Action.StorageClass
+		c := r.Condition
+		rr.Condition.Age = c.AgeInDays
+		rr.Condition.DaysSinceCustomTime = c.DaysSinceCustomTime
+		rr.Condition.DaysSinceNoncurrentTime = c.DaysSinceNoncurrentTime
+		rr.Condition.MatchesStorageClass = c.MatchesStorageClasses
+		rr.Condition.MatchesPrefix = c.MatchesPrefix
+		rr.Condition.MatchesSuffix = c.MatchesSuffix
+		rr.Condition.NumNewerVersions = c.NumNewerVersions
+		switch c.Liveness {
+		case Live:
+			rr.Condition.IsLive = new(bool)
+			*rr.Condition.IsLive = true
+		case Archived:
+			rr.Condition.IsLive = new(bool)
+		}
+		if !c.CreatedBefore.IsZero() {
+			rr.Condition.CreatedBefore = c.CreatedBefore.Format(rfc3339Date)
+		}
+		if !c.CustomTimeBefore.IsZero() {
+			rr.Condition.CustomTimeBefore = c.CustomTimeBefore.Format(rfc3339Date)
+		}
+		if !c.NoncurrentTimeBefore.IsZero() {
+			rr.Condition.NoncurrentTimeBefore = c.NoncurrentTimeBefore.Format(rfc3339Date)
+		}
+		rules = append(rules, rr)
+	}
+	return rules
+}
+
+func (rb *rawLifecycleBucket) toLifecycle() *Lifecycle {
+	l := &Lifecycle{}
+	if rb.Lifecycle == nil {
+		return l
+	}
+	for _, rr := range rb.Lifecycle.Rule {
+		r := LifecycleRule{
+			Action: LifecycleAction{
+				Type:         rr.Action.Type,
+				StorageClass: rr.Action.StorageClass,
+			},
+			Condition: LifecycleCondition{
+				AgeInDays:               rr.Condition.Age,
+				DaysSinceCustomTime:     rr.Condition.DaysSinceCustomTime,
+				DaysSinceNoncurrentTime: rr.Condition.DaysSinceNoncurrentTime,
+				MatchesStorageClasses:   rr.Condition.MatchesStorageClass,
+				MatchesPrefix:           rr.Condition.MatchesPrefix,
+				MatchesSuffix:           rr.Condition.MatchesSuffix,
+				NumNewerVersions:        rr.Condition.NumNewerVersions,
+			},
+		}
+		if rr.Condition.IsLive != nil {
+			if *rr.Condition.IsLive {
+				r.Condition.Liveness = Live
+			} else {
+				r.Condition.Liveness = Archived
+			}
+		}
+		if rr.Condition.CreatedBefore != "" {
+			r.Condition.CreatedBefore, _ = time.Parse(rfc3339Date, rr.Condition.CreatedBefore)
+		}
+		if rr.Condition.CustomTimeBefore != "" {
+			r.Condition.CustomTimeBefore, _ = time.Parse(rfc3339Date, rr.Condition.CustomTimeBefore)
+		}
+		if rr.Condition.NoncurrentTimeBefore != "" {
+			r.Condition.NoncurrentTimeBefore, _ = time.Parse(rfc3339Date, rr.Condition.NoncurrentTimeBefore)
+		}
+		l.Rules = append(l.Rules, r)
+	}
+	return l
+}
+
+// Lifecycle returns the lifecycle configuration of the bucket, including the
+// conditions and actions that BucketAttrs.Lifecycle omits.
+func (b *BucketHandle) Lifecycle(ctx context.Context) (l *Lifecycle, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Bucket.Lifecycle")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := url.Values{"fields": {"lifecycle"}}
+	if b.userProject != "" {
+		query.Set("userProject", b.userProject)
+	}
+	var resp rawLifecycleBucket
+	err = run(ctx, func() error {
+		return b.c.doJSON(ctx, "GET", "b/"+url.PathEscape(b.name), query, nil, &resp)
+	}, b.retry, true)
+	if err != nil {
+		return nil, bucketNotExistErr(err)
+	}
+	return resp.toLifecycle(), nil
+}
+
+// SetLifecycle replaces the lifecycle configuration of the bucket, and
+// returns the updated configuration. Unlike BucketHandle.Update, it supports
+// the MatchesPrefix and MatchesSuffix conditions and the
+// AbortIncompleteMultipartUpload action. An empty configuration removes all
+// rules.
+func (b *BucketHandle) SetLifecycle(ctx context.Context, l Lifecycle) (_ *Lifecycle, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Bucket.SetLifecycle")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := url.Values{"fields": {"lifecycle"}}
+	if err := applyBucketConds("SetLifecycle", b.conds, queryConds(query)); err != nil {
+		return nil, err
+	}
+	if b.userProject != "" {
+		query.Set("userProject", b.userProject)
+	}
+	req := &rawLifecycleBucket{}
+	req.Lifecycle = &struct {
+		Rule []*rawLifecycleRule `json:"rule"`
+	}{Rule: toRawLifecycleRules(l)}
+	var resp rawLifecycleBucket
+	// Setting the configuration to a fixed value is idempotent.
+	err = run(ctx, func() error {
+		return b.c.doJSON(ctx, "PATCH", "b/"+url.PathEscape(b.name), query, req, &resp)
+	}, b.retry, true)
+	if err != nil {
+		return nil, bucketNotExistErr(err)
+	}
+	return resp.toLifecycle(), nil
+}
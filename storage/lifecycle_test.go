@@ -0,0 +1,144 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLifecycleRuleBuilder(t *testing.T) {
+	day := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	got, err := NewLifecycle(
+		DeleteRule().AgeInDays(30).MatchesPrefix("logs/", "tmp/").MatchesSuffix(".log"),
+		SetStorageClassRule("COLDLINE").MatchesStorageClasses("STANDARD", "NEARLINE").CreatedBefore(day),
+		DeleteRule().Liveness(Archived).DaysSinceNoncurrentTime(7).NumNewerVersions(2),
+		AbortIncompleteMultipartUploadRule().AgeInDays(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Lifecycle{Rules: []LifecycleRule{
+		{
+			Action:    LifecycleAction{Type: DeleteAction},
+			Condition: LifecycleCondition{AgeInDays: 30, MatchesPrefix: []string{"logs/", "tmp/"}, MatchesSuffix: []string{".log"}},
+		},
+		{
+			Action:    LifecycleAction{Type: SetStorageClassAction, StorageClass: "COLDLINE"},
+			Condition: LifecycleCondition{MatchesStorageClasses: []string{"STANDARD", "NEARLINE"}, CreatedBefore: day},
+		},
+		{
+			Action:    LifecycleAction{Type: DeleteAction},
+			Condition: LifecycleCondition{Liveness: Archived, DaysSinceNoncurrentTime: 7, NumNewerVersions: 2},
+		},
+		{
+			Action:    LifecycleAction{Type: AbortIncompleteMPUAction},
+			Condition: LifecycleCondition{AgeInDays: 1},
+		},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("lifecycle mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLifecycleRuleBuilderErrors(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		b    *LifecycleRuleBuilder
+	}{
+		{"no condition", DeleteRule()},
+		{"negative age", DeleteRule().AgeInDays(-1)},
+		{"bad target class", SetStorageClassRule("COLD").AgeInDays(1)},
+		{"bad matched class", DeleteRule().MatchesStorageClasses("STANDARD", "HOT")},
+		{"empty prefix", DeleteRule().MatchesPrefix("")},
+		{"empty suffix", DeleteRule().AgeInDays(1).MatchesSuffix("")},
+		{"bad liveness", DeleteRule().Liveness(Liveness(7))},
+		{"noncurrent live", DeleteRule().Liveness(Live).NumNewerVersions(1)},
+		{"upload with live", AbortIncompleteMultipartUploadRule().AgeInDays(1).Liveness(Live)},
+		{"upload with class", AbortIncompleteMultipartUploadRule().MatchesStorageClasses("STANDARD")},
+	} {
+		if _, err := test.b.Build(); err == nil {
+			t.Errorf("%s: got nil error, want non-nil", test.desc)
+		}
+		if _, err := NewLifecycle(DeleteRule().AgeInDays(1), test.b); err == nil {
+			t.Errorf("%s: NewLifecycle: got nil error, want non-nil", test.desc)
+		}
+	}
+}
+
+func TestSetLifecycle(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"lifecycle": {"rule": [
+			{"action": {"type": "Delete"}, "condition": {"age": 30, "matchesPrefix": ["logs/"], "isLive": false}},
+			{"action": {"type": "AbortIncompleteMultipartUpload"}, "condition": {"age": 1, "matchesSuffix": [".tmp"]}}
+		]}}`))
+	})
+	defer close()
+	ctx := context.Background()
+	b := c.Bucket("b")
+	want, err := NewLifecycle(
+		DeleteRule().AgeInDays(30).MatchesPrefix("logs/").Liveness(Archived),
+		AbortIncompleteMultipartUploadRule().AgeInDays(1).MatchesSuffix(".tmp"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := b.If(BucketConditions{MetagenerationMatch: 4}).SetLifecycle(ctx, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&want, got); diff != "" {
+		t.Errorf("SetLifecycle mismatch (-want +got):\n%s", diff)
+	}
+	got, err = b.Lifecycle(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&want, got); diff != "" {
+		t.Errorf("Lifecycle mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := b.SetLifecycle(ctx, Lifecycle{}); err != nil {
+		t.Fatal(err)
+	}
+
+	wantReqs := []recordedRequest{
+		{"PATCH", "/storage/v1/b/b", url.Values{"fields": {"lifecycle"}, "ifMetagenerationMatch": {"4"}},
+			`{"lifecycle":{"rule":[{"action":{"type":"Delete"},"condition":{"age":30,"isLive":false,"matchesPrefix":["logs/"]}},` +
+				`{"action":{"type":"AbortIncompleteMultipartUpload"},"condition":{"age":1,"matchesSuffix":[".tmp"]}}]}}`},
+		{"GET", "/storage/v1/b/b", url.Values{"fields": {"lifecycle"}}, ""},
+		{"PATCH", "/storage/v1/b/b", url.Values{"fields": {"lifecycle"}}, `{"lifecycle":{"rule":[]}}`},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+
+	// The generated client cannot send these rules.
+	if _, err := b.Update(ctx, BucketAttrsToUpdate{Lifecycle: &want}); err != errLifecycleJSONOnly {
+		t.Errorf("Update: got error %v, want %v", err, errLifecycleJSONOnly)
+	}
+	if err := c.Bucket("new").Create(ctx, "p", &BucketAttrs{Lifecycle: want}); err != errLifecycleJSONOnly {
+		t.Errorf("Create: got error %v, want %v", err, errLifecycleJSONOnly)
+	}
+	if got, want := len(reqs), 3; got != want {
+		t.Errorf("got %d requests, want %d", got, want)
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/iam"
+	"cloud.google.com/go/internal/trace"
+	"google.golang.org/api/iterator"
+	raw "google.golang.org/api/storage/v1"
+	iampb "google.golang.org/genproto/googleapis/iam/v1"
+)
+
+// ManagedFolderAttrs represents the metadata for a managed folder. Managed
+// folders group objects by prefix so that IAM policies can be applied to
+// them, in buckets with uniform bucket-level access, with or without
+// hierarchical namespace.
+// See https://cloud.google.com/storage/docs/managed-folders.
+type ManagedFolderAttrs struct {
+	// Bucket is the name of the bucket containing the managed folder.
+	// This field is read-only.
+	Bucket string
+
+	// Name is the name of the managed folder, which ends with a slash.
+	// This field is read-only.
+	Name string
+
+	// Metageneration is the version of the managed folder's metadata.
+	// This field is read-only.
+	Metageneration int64
+
+	// Created is the time the managed folder was created.
+	// This field is read-only.
+	Created time.Time
+
+	// Updated is the time the managed folder's metadata was last changed.
+	// This field is read-only.
+	Updated time.Time
+}
+
+type rawManagedFolder struct {
+	Bucket         string `json:"bucket,omitempty"`
+	Name           string `json:"name,omitempty"`
+	Metageneration int64  `json:"metageneration,string,omitempty"`
+	CreateTime     string `json:"createTime,omitempty"`
+	UpdateTime     string `json:"updateTime,omitempty"`
+}
+
+func newManagedFolder(f *rawManagedFolder) *ManagedFolderAttrs {
+	return &ManagedFolderAttrs{
+		Bucket:         f.Bucket,
+		Name:           f.Name,
+		Metageneration: f.Metageneration,
+		Created:        convertTime(f.CreateTime),
+		Updated:        convertTime(f.UpdateTime),
+	}
+}
+
+// ManagedFolderHandle provides operations on a managed folder. Use
+// BucketHandle.ManagedFolder to get a handle.
+type ManagedFolderHandle struct {
+	c           *Client
+	bucket      string
+	name        string
+	conds       *FolderConditions
+	userProject string
+	retry       *retryConfig
+}
+
+// ManagedFolder returns a ManagedFolderHandle, which provides operations on
+// the named managed folder. A slash is appended to name if it does not end
+// with one.
+// This call does not perform any network operations.
+func (b *BucketHandle) ManagedFolder(name string) *ManagedFolderHandle {
+	if !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+	return &ManagedFolderHandle{
+		c:           b.c,
+		bucket:      b.name,
+		name:        name,
+		userProject: b.userProject,
+		retry:       b.retry.clone(),
+	}
+}
+
+// Name returns the name of the managed folder, which ends with a slash.
+func (f *ManagedFolderHandle) Name() string {
+	return f.name
+}
+
+// If returns a new ManagedFolderHandle that applies a set of preconditions.
+// Preconditions already set on the ManagedFolderHandle are ignored. Attrs
+// and Delete take the conditions into account.
+func (f *ManagedFolderHandle) If(conds FolderConditions) *ManagedFolderHandle {
+	f2 := *f
+	f2.conds = &conds
+	return &f2
+}
+
+func (f *ManagedFolderHandle) query() url.Values {
+	query := url.Values{}
+	if f.userProject != "" {
+		query.Set("userProject", f.userProject)
+	}
+	return query
+}
+
+func (f *ManagedFolderHandle) path() string {
+	return "b/" + url.PathEscape(f.bucket) + "/managedFolders/" + url.PathEscape(f.name)
+}
+
+// Create creates the managed folder, and returns its attributes.
+func (f *ManagedFolderHandle) Create(ctx context.Context) (attrs *ManagedFolderAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.ManagedFolder.Create")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	var resp rawManagedFolder
+	// Creating a managed folder fails if it already exists, so it can be
+	// retried.
+	err = run(ctx, func() error {
+		return f.c.doJSON(ctx, "POST", "b/"+url.PathEscape(f.bucket)+"/managedFolders", f.query(), &rawManagedFolder{Name: f.name}, &resp)
+	}, f.retry, true)
+	if err != nil {
+		return nil, bucketNotExistErr(err)
+	}
+	return newManagedFolder(&resp), nil
+}
+
+// Attrs returns the metadata for the managed folder.
+// ErrFolderNotExist will be returned if the managed folder does not exist.
+func (f *ManagedFolderHandle) Attrs(ctx context.Context) (attrs *ManagedFolderAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.ManagedFolder.Attrs")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := f.query()
+	if err := applyFolderConds("ManagedFolderHandle.Attrs", f.conds, query); err != nil {
+		return nil, err
+	}
+	var resp rawManagedFolder
+	err = run(ctx, func() error {
+		return f.c.doJSON(ctx, "GET", f.path(), query, nil, &resp)
+	}, f.retry, true)
+	if err != nil {
+		return nil, folderNotExistErr(err)
+	}
+	return newManagedFolder(&resp), nil
+}
+
+// DeleteManagedFolderOptions are options for ManagedFolderHandle.Delete.
+type DeleteManagedFolderOptions struct {
+	// AllowNonEmpty deletes the managed folder even if it contains objects.
+	// The objects are not deleted, but the managed folder's IAM policy no
+	// longer applies to them.
+	AllowNonEmpty bool
+}
+
+// Delete deletes the managed folder. A nil opts uses the defaults described
+// in DeleteManagedFolderOptions.
+// ErrFolderNotExist will be returned if the managed folder does not exist.
+func (f *ManagedFolderHandle) Delete(ctx context.Context, opts *DeleteManagedFolderOptions) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.ManagedFolder.Delete")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := f.query()
+	if err := applyFolderConds("ManagedFolderHandle.Delete", f.conds, query); err != nil {
+		return err
+	}
+	if opts != nil && opts.AllowNonEmpty {
+		query.Set("allowNonEmpty", "true")
+	}
+	err = run(ctx, func() error {
+		return f.c.doJSON(ctx, "DELETE", f.path(), query, nil, nil)
+	}, f.retry, true)
+	return folderNotExistErr(err)
+}
+
+// IAM provides access to IAM access control for the managed folder.
+func (f *ManagedFolderHandle) IAM() *iam.Handle {
+	return iam.InternalNewHandleClient(&managedFolderIAMClient{
+		c:           f.c,
+		bucket:      f.bucket,
+		userProject: f.userProject,
+		retry:       f.retry,
+	}, f.name)
+}
+
+// managedFolderIAMClient implements the iam.client interface for the managed
+// folders of a bucket, with the JSON API.
+type managedFolderIAMClient struct {
+	c           *Client
+	bucket      string
+	userProject string
+	retry       *retryConfig
+}
+
+func (c *managedFolderIAMClient) path(resource string) string {
+	return "b/" + url.PathEscape(c.bucket) + "/managedFolders/" + url.PathEscape(resource) + "/iam"
+}
+
+func (c *managedFolderIAMClient) query() url.Values {
+	query := url.Values{}
+	if c.userProject != "" {
+		query.Set("userProject", c.userProject)
+	}
+	return query
+}
+
+func (c *managedFolderIAMClient) Get(ctx context.Context, resource string) (p *iampb.Policy, err error) {
+	return c.GetWithVersion(ctx, resource, 1)
+}
+
+func (c *managedFolderIAMClient) GetWithVersion(ctx context.Context, resource string, requestedPolicyVersion int32) (p *iampb.Policy, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.ManagedFolder.IAM.Get")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := c.query()
+	query.Set("optionsRequestedPolicyVersion", strconv.Itoa(int(requestedPolicyVersion)))
+	var rp raw.Policy
+	err = run(ctx, func() error {
+		return c.c.doJSON(ctx, "GET", c.path(resource), query, nil, &rp)
+	}, c.retry, true)
+	if err != nil {
+		return nil, folderNotExistErr(err)
+	}
+	return iamFromStoragePolicy(&rp), nil
+}
+
+func (c *managedFolderIAMClient) Set(ctx context.Context, resource string, p *iampb.Policy) (err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.ManagedFolder.IAM.Set")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	rp := iamToStoragePolicy(p)
+	isIdempotent := len(p.Etag) > 0
+	err = run(ctx, func() error {
+		return c.c.doJSON(ctx, "PUT", c.path(resource), c.query(), rp, nil)
+	}, c.retry, isIdempotent)
+	return folderNotExistErr(err)
+}
+
+func (c *managedFolderIAMClient) Test(ctx context.Context, resource string, perms []string) (permissions []string, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.ManagedFolder.IAM.Test")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := c.query()
+	query["permissions"] = perms
+	var res raw.TestIamPermissionsResponse
+	err = run(ctx, func() error {
+		return c.c.doJSON(ctx, "GET", c.path(resource)+"/testPermissions", query, nil, &res)
+	}, c.retry, true)
+	if err != nil {
+		return nil, folderNotExistErr(err)
+	}
+	return res.Permissions, nil
+}
+
+// ManagedFolders returns an iterator over the managed folders in the bucket
+// whose names begin with prefix.
+func (b *BucketHandle) ManagedFolders(ctx context.Context, prefix string) *ManagedFolderIterator {
+	it := &ManagedFolderIterator{
+		ctx:    ctx,
+		bucket: b,
+		prefix: prefix,
+	}
+	it.pageInfo, it.nextFunc = iterator.NewPageInfo(
+		it.fetch,
+		func() int { return len(it.items) },
+		func() interface{} { b := it.items; it.items = nil; return b })
+	return it
+}
+
+// A ManagedFolderIterator is an iterator over ManagedFolderAttrs.
+//
+// Note: This iterator is not safe for concurrent operations without explicit synchronization.
+type ManagedFolderIterator struct {
+	ctx      context.Context
+	bucket   *BucketHandle
+	prefix   string
+	pageInfo *iterator.PageInfo
+	nextFunc func() error
+	items    []*ManagedFolderAttrs
+}
+
+// PageInfo supports pagination. See the google.golang.org/api/iterator package for details.
+//
+// Note: This method is not safe for concurrent operations without explicit synchronization.
+func (it *ManagedFolderIterator) PageInfo() *iterator.PageInfo { return it.pageInfo }
+
+// Next returns the next result. Its second return value is iterator.Done if
+// there are no more results. Once Next returns iterator.Done, all subsequent
+// calls will return iterator.Done.
+//
+// Note: This method is not safe for concurrent operations without explicit synchronization.
+func (it *ManagedFolderIterator) Next() (*ManagedFolderAttrs, error) {
+	if err := it.nextFunc(); err != nil {
+		return nil, err
+	}
+	item := it.items[0]
+	it.items = it.items[1:]
+	return item, nil
+}
+
+func (it *ManagedFolderIterator) fetch(pageSize int, pageToken string) (string, error) {
+	query := url.Values{}
+	if it.prefix != "" {
+		query.Set("prefix", it.prefix)
+	}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+	if pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(pageSize))
+	}
+	if it.bucket.userProject != "" {
+		query.Set("userProject", it.bucket.userProject)
+	}
+	var resp struct {
+		Items         []*rawManagedFolder `json:"items"`
+		NextPageToken string              `json:"nextPageToken"`
+	}
+	err := run(it.ctx, func() error {
+		return it.bucket.c.doJSON(it.ctx, "GET", "b/"+url.PathEscape(it.bucket.name)+"/managedFolders", query, nil, &resp)
+	}, it.bucket.retry, true)
+	if err != nil {
+		return "", bucketNotExistErr(err)
+	}
+	for _, f := range resp.Items {
+		it.items = append(it.items, newManagedFolder(f))
+	}
+	return resp.NextPageToken, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"cloud.google.com/go/iam"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/iterator"
+)
+
+func TestManagedFolder(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /storage/v1/b/b/managedFolders", "GET /storage/v1/b/b/managedFolders/m/":
+			fmt.Fprint(w, `{"bucket": "b", "name": "m/", "metageneration": "1"}`)
+		case "DELETE /storage/v1/b/b/managedFolders/m/":
+		case "GET /storage/v1/b/b/managedFolders":
+			if r.URL.Query().Get("pageToken") == "" {
+				fmt.Fprint(w, `{"items": [{"name": "m/"}], "nextPageToken": "t"}`)
+			} else {
+				fmt.Fprint(w, `{"items": [{"name": "m/n/"}]}`)
+			}
+		case "GET /storage/v1/b/b/managedFolders/missing/":
+			http.Error(w, `{"error": {"code": 404, "message": "not found"}}`, http.StatusNotFound)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	b := c.Bucket("b").UserProject("p")
+	want := &ManagedFolderAttrs{Bucket: "b", Name: "m/", Metageneration: 1}
+
+	got, err := b.ManagedFolder("m").Create(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Create mismatch (-want +got):\n%s", diff)
+	}
+	got, err = b.ManagedFolder("m/").If(FolderConditions{MetagenerationMatch: 1}).Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Attrs mismatch (-want +got):\n%s", diff)
+	}
+	if err := b.ManagedFolder("m/").Delete(ctx, &DeleteManagedFolderOptions{AllowNonEmpty: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ManagedFolder("missing").Attrs(ctx); err != ErrFolderNotExist {
+		t.Errorf("missing managed folder: got error %v, want %v", err, ErrFolderNotExist)
+	}
+	var names []string
+	it := b.ManagedFolders(ctx, "m")
+	for {
+		f, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, f.Name)
+	}
+	if diff := cmp.Diff([]string{"m/", "m/n/"}, names); diff != "" {
+		t.Errorf("managed folders mismatch (-want +got):\n%s", diff)
+	}
+
+	wantReqs := []recordedRequest{
+		{"POST", "/storage/v1/b/b/managedFolders", url.Values{"userProject": {"p"}}, `{"name":"m/"}`},
+		{"GET", "/storage/v1/b/b/managedFolders/m/", url.Values{"ifMetagenerationMatch": {"1"}, "userProject": {"p"}}, ""},
+		{"DELETE", "/storage/v1/b/b/managedFolders/m/", url.Values{"allowNonEmpty": {"true"}, "userProject": {"p"}}, ""},
+		{"GET", "/storage/v1/b/b/managedFolders/missing/", url.Values{"userProject": {"p"}}, ""},
+		{"GET", "/storage/v1/b/b/managedFolders", url.Values{"prefix": {"m"}, "userProject": {"p"}}, ""},
+		{"GET", "/storage/v1/b/b/managedFolders", url.Values{"prefix": {"m"}, "pageToken": {"t"}, "userProject": {"p"}}, ""},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestManagedFolderIAM(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /storage/v1/b/b/managedFolders/m//iam":
+			fmt.Fprint(w, `{"etag": "e1", "bindings": [{"role": "roles/storage.objectViewer", "members": ["user:a@example.com"]}]}`)
+		case "PUT /storage/v1/b/b/managedFolders/m//iam":
+			fmt.Fprint(w, `{}`)
+		case "GET /storage/v1/b/b/managedFolders/m//iam/testPermissions":
+			fmt.Fprint(w, `{"permissions": ["storage.objects.get"]}`)
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	h := c.Bucket("b").ManagedFolder("m").IAM()
+
+	p, err := h.Policy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Members(iam.Viewer); len(got) != 0 {
+		t.Errorf("got viewers %v, want none", got)
+	}
+	if got, want := p.Members("roles/storage.objectViewer"), []string{"user:a@example.com"}; !cmp.Equal(got, want) {
+		t.Errorf("got members %v, want %v", got, want)
+	}
+	p.Add("user:b@example.com", "roles/storage.objectViewer")
+	if err := h.SetPolicy(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	perms, err := h.TestPermissions(ctx, []string{"storage.objects.get", "storage.objects.delete"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"storage.objects.get"}, perms); diff != "" {
+		t.Errorf("permissions mismatch (-want +got):\n%s", diff)
+	}
+
+	wantReqs := []recordedRequest{
+		{"GET", "/storage/v1/b/b/managedFolders/m//iam", url.Values{"optionsRequestedPolicyVersion": {"1"}}, ""},
+		{"PUT", "/storage/v1/b/b/managedFolders/m//iam", url.Values{},
+			`{"bindings":[{"members":["user:a@example.com","user:b@example.com"],"role":"roles/storage.objectViewer"}],"etag":"e1"}`},
+		{"GET", "/storage/v1/b/b/managedFolders/m//iam/testPermissions",
+			url.Values{"permissions": {"storage.objects.get", "storage.objects.delete"}}, ""},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// WatchOptions configure BucketHandle.Watch.
+type WatchOptions struct {
+	// ProjectID is the project of the Pub/Sub topic and subscription.
+	// Required.
+	ProjectID string
+
+	// TopicID is the Pub/Sub topic that receives the bucket's notifications.
+	// If the bucket already has a notification configuration with a JSON
+	// payload for the topic, it is used. Otherwise the topic is created if it
+	// does not exist, the Cloud Storage service account of the bucket's
+	// project is allowed to publish to it, and a notification configuration
+	// is added to the bucket.
+	// Optional. The default is "storage-notifications-" followed by the
+	// bucket name.
+	TopicID string
+
+	// SubscriptionID is the Pub/Sub subscription from which notifications
+	// are pulled. It is created if it does not exist, and is kept when the
+	// watcher is closed.
+	// Optional. If empty, a subscription with a random ID is created, and is
+	// deleted when the watcher is closed.
+	SubscriptionID string
+
+	// EventTypes and ObjectNamePrefix are used when a notification
+	// configuration is added to the bucket. See Notification.
+	EventTypes       []string
+	ObjectNamePrefix string
+}
+
+// NotificationEvent is a change to an object reported by a bucket
+// notification.
+// See https://cloud.google.com/storage/docs/pubsub-notifications#attributes.
+type NotificationEvent struct {
+	// Type is the type of the event, such as ObjectFinalizeEvent.
+	Type string
+
+	// NotificationID is the ID of the notification configuration that
+	// reported the event.
+	NotificationID string
+
+	// Bucket and Object are the names of the bucket and of the object that
+	// changed, and Generation is the generation of the object.
+	Bucket     string
+	Object     string
+	Generation int64
+
+	// Time is the time of the event.
+	Time time.Time
+
+	// OverwroteGeneration is the generation of the object that was replaced
+	// by a finalize event, and OverwrittenByGeneration is the generation of
+	// the object that replaced the object of a delete or archive event. They
+	// are zero if no object was replaced.
+	OverwroteGeneration     int64
+	OverwrittenByGeneration int64
+
+	// Attrs are the attributes of the object at the time of the event. They
+	// are nil if the notification configuration has no JSON payload.
+	Attrs *ObjectAttrs
+}
+
+// NotificationWatcher delivers the notifications of a bucket. It is created
+// with BucketHandle.Watch.
+type NotificationWatcher struct {
+	events    chan *NotificationEvent
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+	svc       *pubsub.Service
+	sub       string
+	deleteSub bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Watch sets up the delivery of the bucket's notifications to a Pub/Sub
+// subscription, as described in WatchOptions, and returns a watcher that
+// pulls them. The notifications are received from the watcher's Events
+// channel. Each notification is acknowledged once it has been received from
+// the channel; notifications that are received more than a minute after they
+// were pulled may be delivered again.
+//
+// The client's credentials are used for the Pub/Sub requests, so the client
+// must have been created with a scope that allows them, such as
+// "https://www.googleapis.com/auth/cloud-platform".
+//
+// The watcher stops when ctx is done, when Close is called, or when pulling
+// notifications fails.
+func (b *BucketHandle) Watch(ctx context.Context, opts *WatchOptions) (w *NotificationWatcher, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Bucket.Watch")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if opts == nil || opts.ProjectID == "" {
+		return nil, errors.New("storage: Watch: missing ProjectID")
+	}
+	topicID := opts.TopicID
+	if topicID == "" {
+		topicID = "storage-notifications-" + b.name
+	}
+	// It's ok to recreate this service per watcher since we pass in the http
+	// client, circumventing the cost of recreating the auth/transport layer.
+	svc, err := pubsub.NewService(ctx, option.WithHTTPClient(b.c.hc))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create pubsub client: %v", err)
+	}
+	if err := b.ensureNotification(ctx, svc, opts, topicID); err != nil {
+		return nil, err
+	}
+
+	w = &NotificationWatcher{
+		events: make(chan *NotificationEvent),
+		done:   make(chan struct{}),
+		svc:    svc,
+	}
+	subID := opts.SubscriptionID
+	if subID == "" {
+		var id [8]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return nil, err
+		}
+		subID = "storage-watch-" + hex.EncodeToString(id[:])
+		w.deleteSub = true
+	}
+	w.sub = fmt.Sprintf("projects/%s/subscriptions/%s", opts.ProjectID, subID)
+	sub := &pubsub.Subscription{
+		Topic:              fmt.Sprintf("projects/%s/topics/%s", opts.ProjectID, topicID),
+		AckDeadlineSeconds: 60,
+	}
+	err = run(ctx, func() error {
+		_, err := svc.Projects.Subscriptions.Create(w.sub, sub).Context(ctx).Do()
+		return err
+	}, b.retry, true)
+	if err != nil && !(isStatus(err, http.StatusConflict) && !w.deleteSub) {
+		return nil, err
+	}
+
+	var pullCtx context.Context
+	pullCtx, w.cancel = context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.cancel()
+		case <-pullCtx.Done():
+		}
+	}()
+	go w.pull(pullCtx, b.name, b.retry)
+	return w, nil
+}
+
+// ensureNotification makes sure that the notifications of the bucket are
+// published to the topic.
+func (b *BucketHandle) ensureNotification(ctx context.Context, svc *pubsub.Service, opts *WatchOptions, topicID string) error {
+	ns, err := b.Notifications(ctx)
+	if err != nil {
+		return err
+	}
+	for _, n := range ns {
+		if n.TopicProjectID == opts.ProjectID && n.TopicID == topicID && n.PayloadFormat == JSONPayload {
+			return nil
+		}
+	}
+
+	topic := fmt.Sprintf("projects/%s/topics/%s", opts.ProjectID, topicID)
+	err = run(ctx, func() error {
+		_, err := svc.Projects.Topics.Create(topic, &pubsub.Topic{}).Context(ctx).Do()
+		return err
+	}, b.retry, true)
+	if err != nil && !isStatus(err, http.StatusConflict) {
+		return err
+	}
+
+	attrs, err := b.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+	email, err := b.c.ServiceAccount(ctx, strconv.FormatUint(attrs.ProjectNumber, 10))
+	if err != nil {
+		return err
+	}
+	if err := addTopicPublisher(ctx, svc, topic, "serviceAccount:"+email, b.retry); err != nil {
+		return err
+	}
+
+	_, err = b.AddNotification(ctx, &Notification{
+		TopicProjectID:   opts.ProjectID,
+		TopicID:          topicID,
+		EventTypes:       opts.EventTypes,
+		ObjectNamePrefix: opts.ObjectNamePrefix,
+		PayloadFormat:    JSONPayload,
+	})
+	return err
+}
+
+// addTopicPublisher grants the Pub/Sub publisher role on the topic to member,
+// if it does not have it already.
+func addTopicPublisher(ctx context.Context, svc *pubsub.Service, topic, member string, retry *retryConfig) error {
+	const role = "roles/pubsub.publisher"
+	var policy *pubsub.Policy
+	err := run(ctx, func() error {
+		var err error
+		policy, err = svc.Projects.Topics.GetIamPolicy(topic).Context(ctx).Do()
+		return err
+	}, retry, true)
+	if err != nil {
+		return err
+	}
+	var binding *pubsub.Binding
+	for _, b := range policy.Bindings {
+		if b.Role == role && b.Condition == nil {
+			binding = b
+			break
+		}
+	}
+	if binding == nil {
+		binding = &pubsub.Binding{Role: role}
+		policy.Bindings = append(policy.Bindings, binding)
+	}
+	for _, m := range binding.Members {
+		if m == member {
+			return nil
+		}
+	}
+	binding.Members = append(binding.Members, member)
+	// The policy's etag makes the update idempotent.
+	return run(ctx, func() error {
+		_, err := svc.Projects.Topics.SetIamPolicy(topic, &pubsub.SetIamPolicyRequest{Policy: policy}).Context(ctx).Do()
+		return err
+	}, retry, true)
+}
+
+// Events returns the channel on which notifications are delivered. It is
+// closed when the watcher stops.
+func (w *NotificationWatcher) Events() <-chan *NotificationEvent {
+	return w.events
+}
+
+// Err returns the error that stopped the watcher, if any. It should be
+// called after the Events channel is closed.
+func (w *NotificationWatcher) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the watcher, and deletes its subscription if it was created
+// by Watch without a SubscriptionID. The notification configuration and the
+// topic are kept.
+func (w *NotificationWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		w.cancel()
+		<-w.done
+		if w.deleteSub {
+			_, w.closeErr = w.svc.Projects.Subscriptions.Delete(w.sub).Context(context.Background()).Do()
+		}
+	})
+	return w.closeErr
+}
+
+func (w *NotificationWatcher) pull(ctx context.Context, bucket string, retry *retryConfig) {
+	defer close(w.done)
+	defer close(w.events)
+	for {
+		var res *pubsub.PullResponse
+		err := run(ctx, func() error {
+			var err error
+			res, err = w.svc.Projects.Subscriptions.Pull(w.sub, &pubsub.PullRequest{MaxMessages: 100}).Context(ctx).Do()
+			return err
+		}, retry, true)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.err = err
+			}
+			return
+		}
+		var ackIDs []string
+		stopped := false
+		for _, m := range res.ReceivedMessages {
+			ev, err := toNotificationEvent(m.Message)
+			// Messages that are not notifications for this bucket are
+			// acknowledged without being delivered.
+			if err == nil && ev.Bucket == bucket {
+				select {
+				case w.events <- ev:
+				case <-ctx.Done():
+					stopped = true
+				}
+			}
+			if stopped {
+				break
+			}
+			ackIDs = append(ackIDs, m.AckId)
+		}
+		if len(ackIDs) > 0 {
+			// Delivered notifications are acknowledged even if the watcher
+			// is stopping, so that they aren't delivered again.
+			ackCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			err = run(ackCtx, func() error {
+				_, err := w.svc.Projects.Subscriptions.Acknowledge(w.sub, &pubsub.AcknowledgeRequest{AckIds: ackIDs}).Context(ackCtx).Do()
+				return err
+			}, retry, true)
+			cancel()
+			if err != nil {
+				w.err = err
+				return
+			}
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+func toNotificationEvent(m *pubsub.PubsubMessage) (*NotificationEvent, error) {
+	if m == nil || m.Attributes["eventType"] == "" {
+		return nil, errors.New("storage: not a bucket notification")
+	}
+	a := m.Attributes
+	ev := &NotificationEvent{
+		Type:           a["eventType"],
+		NotificationID: a["notificationConfig"],
+		Bucket:         a["bucketId"],
+		Object:         a["objectId"],
+		Time:           convertTime(a["eventTime"]),
+	}
+	ev.Generation, _ = strconv.ParseInt(a["objectGeneration"], 10, 64)
+	ev.OverwroteGeneration, _ = strconv.ParseInt(a["overwroteGeneration"], 10, 64)
+	ev.OverwrittenByGeneration, _ = strconv.ParseInt(a["overwrittenByGeneration"], 10, 64)
+	if a["payloadFormat"] == JSONPayload && m.Data != "" {
+		data, err := base64.StdEncoding.DecodeString(m.Data)
+		if err != nil {
+			return nil, err
+		}
+		var obj raw.Object
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		ev.Attrs = newObject(&obj)
+	}
+	return ev, nil
+}
+
+func isStatus(err error, code int) bool {
+	var e *googleapi.Error
+	return xerrors.As(err, &e) && e.Code == code
+}
@@ -0,0 +1,267 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+	pubsub "google.golang.org/api/pubsub/v1"
+)
+
+// notificationServer fakes the Cloud Storage and Pub/Sub requests made by
+// BucketHandle.Watch.
+type notificationServer struct {
+	mu       sync.Mutex
+	requests []string
+	policy   string
+	notif    string
+	pulled   bool
+	acked    []string
+}
+
+func (s *notificationServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := r.URL.Path
+	// Replace the random subscription ID.
+	if strings.HasPrefix(path, "/v1/projects/p/subscriptions/storage-watch-") {
+		path = "/v1/projects/p/subscriptions/ID"
+		if i := strings.Index(r.URL.Path, ":"); i >= 0 {
+			path += r.URL.Path[i:]
+		}
+	}
+	s.requests = append(s.requests, r.Method+" "+path)
+	switch r.Method + " " + path {
+	case "GET /storage/v1/b/b/notificationConfigs":
+		fmt.Fprint(w, `{}`)
+	case "PUT /v1/projects/p/topics/storage-notifications-b":
+		http.Error(w, `{"error": {"code": 409, "message": "exists"}}`, http.StatusConflict)
+	case "GET /storage/v1/b/b":
+		fmt.Fprint(w, `{"name": "b", "projectNumber": "123"}`)
+	case "GET /storage/v1/projects/123/serviceAccount":
+		fmt.Fprint(w, `{"email_address": "sa@gs-project-accounts.iam.gserviceaccount.com"}`)
+	case "GET /v1/projects/p/topics/storage-notifications-b:getIamPolicy":
+		fmt.Fprint(w, `{"etag": "e1", "bindings": [{"role": "roles/pubsub.publisher", "members": ["user:a@example.com"]}]}`)
+	case "POST /v1/projects/p/topics/storage-notifications-b:setIamPolicy":
+		s.policy = string(body)
+		fmt.Fprint(w, `{}`)
+	case "POST /storage/v1/b/b/notificationConfigs":
+		s.notif = string(body)
+		fmt.Fprint(w, `{"id": "7", "topic": "//pubsub.googleapis.com/projects/p/topics/storage-notifications-b"}`)
+	case "PUT /v1/projects/p/subscriptions/ID":
+		fmt.Fprint(w, `{}`)
+	case "POST /v1/projects/p/subscriptions/ID:pull":
+		if s.pulled {
+			s.mu.Unlock()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(20 * time.Millisecond):
+			}
+			s.mu.Lock()
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		s.pulled = true
+		payload := base64.StdEncoding.EncodeToString([]byte(`{"bucket": "b", "name": "obj", "generation": "5", "size": "3"}`))
+		res := &pubsub.PullResponse{ReceivedMessages: []*pubsub.ReceivedMessage{
+			{AckId: "a1", Message: &pubsub.PubsubMessage{
+				Data: payload,
+				Attributes: map[string]string{
+					"eventType": ObjectFinalizeEvent, "notificationConfig": "projects/_/buckets/b/notificationConfigs/7",
+					"bucketId": "b", "objectId": "obj", "objectGeneration": "5", "overwroteGeneration": "4",
+					"eventTime": "2022-03-01T10:00:00Z", "payloadFormat": JSONPayload,
+				},
+			}},
+			{AckId: "a2", Message: &pubsub.PubsubMessage{
+				Attributes: map[string]string{"eventType": ObjectDeleteEvent, "bucketId": "other", "objectId": "x"},
+			}},
+			{AckId: "a3", Message: &pubsub.PubsubMessage{
+				Attributes: map[string]string{
+					"eventType": ObjectDeleteEvent, "bucketId": "b", "objectId": "old", "objectGeneration": "2",
+					"payloadFormat": NoPayload,
+				},
+			}},
+		}}
+		json.NewEncoder(w).Encode(res)
+	case "POST /v1/projects/p/subscriptions/ID:acknowledge":
+		var req pubsub.AcknowledgeRequest
+		json.Unmarshal(body, &req)
+		s.acked = append(s.acked, req.AckIds...)
+		fmt.Fprint(w, `{}`)
+	case "DELETE /v1/projects/p/subscriptions/ID":
+		fmt.Fprint(w, `{}`)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := &notificationServer{}
+	hc, close := newTestServer(s.handle)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := c.Bucket("b").Watch(ctx, &WatchOptions{ProjectID: "p", EventTypes: []string{ObjectFinalizeEvent, ObjectDeleteEvent}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*NotificationEvent
+	for ev := range w.Events() {
+		got = append(got, ev)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-w.Events(); ok {
+		t.Error("Events not closed after Close")
+	}
+
+	want := []*NotificationEvent{
+		{
+			Type:                ObjectFinalizeEvent,
+			NotificationID:      "projects/_/buckets/b/notificationConfigs/7",
+			Bucket:              "b",
+			Object:              "obj",
+			Generation:          5,
+			Time:                time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC),
+			OverwroteGeneration: 4,
+			Attrs:               &ObjectAttrs{Bucket: "b", Name: "obj", Generation: 5, Size: 3, MD5: []byte{}},
+		},
+		{Type: ObjectDeleteEvent, Bucket: "b", Object: "old", Generation: 2},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if diff := cmp.Diff([]string{"a1", "a2", "a3"}, s.acked); diff != "" {
+		t.Errorf("acknowledged messages mismatch (-want +got):\n%s", diff)
+	}
+	if want := `"members":["user:a@example.com","serviceAccount:sa@gs-project-accounts.iam.gserviceaccount.com"]`; !strings.Contains(s.policy, want) {
+		t.Errorf("got policy %s, want it to contain %s", s.policy, want)
+	}
+	if !strings.Contains(s.policy, `"etag":"e1"`) {
+		t.Errorf("got policy %s, want it to keep the etag", s.policy)
+	}
+	var n struct {
+		Topic         string
+		EventTypes    []string `json:"event_types"`
+		PayloadFormat string   `json:"payload_format"`
+	}
+	if err := json.Unmarshal([]byte(s.notif), &n); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n.Topic, "//pubsub.googleapis.com/projects/p/topics/storage-notifications-b"; got != want {
+		t.Errorf("got notification topic %q, want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{ObjectFinalizeEvent, ObjectDeleteEvent}, n.EventTypes); diff != "" {
+		t.Errorf("notification event types mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := n.PayloadFormat, JSONPayload; got != want {
+		t.Errorf("got payload format %q, want %q", got, want)
+	}
+	if last := s.requests[len(s.requests)-1]; last != "DELETE /v1/projects/p/subscriptions/ID" {
+		t.Errorf("got last request %q, want the subscription to be deleted", last)
+	}
+}
+
+func TestWatchExistingNotification(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/storage/v1/b/b/notificationConfigs":
+			fmt.Fprint(w, `{"items": [{"id": "1", "topic": "//pubsub.googleapis.com/projects/p/topics/t", "payload_format": "JSON_API_V1"}]}`)
+		case r.URL.Path == "/v1/projects/p/subscriptions/s":
+			http.Error(w, `{"error": {"code": 409, "message": "exists"}}`, http.StatusConflict)
+		case strings.HasSuffix(r.URL.Path, ":pull"):
+			<-r.Context().Done()
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	})
+	defer close()
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := c.Bucket("b").Watch(ctx, &WatchOptions{ProjectID: "p", TopicID: "t", SubscriptionID: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	for range w.Events() {
+		t.Error("got unexpected event")
+	}
+	if err := w.Err(); err != nil {
+		t.Errorf("got error %v after cancellation, want nil", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range reqs {
+		if r.method == "DELETE" || r.method == "PUT" && strings.Contains(r.path, "/topics/") {
+			t.Errorf("got unexpected request %s %s", r.method, r.path)
+		}
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/storage/v1/b/b/notificationConfigs":
+			fmt.Fprint(w, `{"items": [{"id": "1", "topic": "//pubsub.googleapis.com/projects/p/topics/t", "payload_format": "JSON_API_V1"}]}`)
+		case strings.HasSuffix(r.URL.Path, ":pull"):
+			http.Error(w, `{"error": {"code": 403, "message": "denied"}}`, http.StatusForbidden)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	if _, err := c.Bucket("b").Watch(ctx, &WatchOptions{}); err == nil {
+		t.Error("missing ProjectID: got nil error, want non-nil")
+	}
+	w, err := c.Bucket("b").Watch(ctx, &WatchOptions{ProjectID: "p", TopicID: "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for range w.Events() {
+		t.Error("got unexpected event")
+	}
+	if w.Err() == nil {
+		t.Error("pull failure: got nil error, want non-nil")
+	}
+}
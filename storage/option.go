@@ -0,0 +1,114 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// clientConfig holds the Client settings given by storage-specific client
+// options.
+type clientConfig struct {
+	userProject  string
+	emulatorHost string
+}
+
+// storageOption is a ClientOption that configures the Client itself rather
+// than its transport. It is removed from the options used to create the
+// transport.
+type storageOption interface {
+	option.ClientOption
+	applyStorage(*clientConfig)
+}
+
+// noopOption returns the ClientOption embedded by storage options, which
+// cannot implement option.ClientOption themselves. It does not change the
+// dial settings.
+func noopOption() option.ClientOption {
+	return option.WithGRPCDialOption(grpc.EmptyDialOption{})
+}
+
+// splitClientOptions separates the storage-specific options in opts from
+// the transport options.
+func splitClientOptions(opts []option.ClientOption) (storageOpts, transportOpts []option.ClientOption) {
+	for _, opt := range opts {
+		if _, ok := opt.(storageOption); ok {
+			storageOpts = append(storageOpts, opt)
+		} else {
+			transportOpts = append(transportOpts, opt)
+		}
+	}
+	return storageOpts, transportOpts
+}
+
+// newClientConfig applies the storage-specific options in opts, and returns
+// the remaining transport options.
+func newClientConfig(opts []option.ClientOption) (*clientConfig, []option.ClientOption) {
+	storageOpts, transportOpts := splitClientOptions(opts)
+	config := &clientConfig{}
+	for _, opt := range storageOpts {
+		opt.(storageOption).applyStorage(config)
+	}
+	return config, transportOpts
+}
+
+// WithUserProject returns a ClientOption that sets the project billed for the
+// requests made by the client, as if UserProject(projectID) were called on
+// every BucketHandle returned by Client.Bucket. It also applies to
+// Client.Buckets, Client.ServiceAccount and HMAC key operations. A user
+// project is required for all operations on Requester Pays buckets.
+//
+// A user project set on a bucket handle or with UserProjectForHMACKeys takes
+// precedence over this one.
+func WithUserProject(projectID string) option.ClientOption {
+	return &withUserProject{ClientOption: noopOption(), projectID: projectID}
+}
+
+type withUserProject struct {
+	option.ClientOption
+	projectID string
+}
+
+func (w *withUserProject) applyStorage(c *clientConfig) {
+	c.userProject = w.projectID
+}
+
+// WithEmulator returns a ClientOption that sends the Cloud Storage requests
+// of the client to an emulator, such as fake-gcs-server, at the given
+// address, without authentication. The address is a host and port, or
+// a URL with an http or https scheme; the scheme defaults to http.
+//
+// The JSON API, upload and XML API endpoints of the client all use the
+// emulator, as do resumable upload sessions, even if the emulator returns
+// session URIs for another address. URLs and POST policies signed with
+// BucketHandle.SignedURL and BucketHandle.GenerateSignedPostPolicyV4 target
+// the emulator unless a Hostname is set.
+//
+// WithEmulator takes precedence over the STORAGE_EMULATOR_HOST environment
+// variable, and is convenient for hermetic tests that start their own
+// emulator. It does not apply to the gRPC API.
+func WithEmulator(host string) option.ClientOption {
+	return &withEmulator{ClientOption: noopOption(), host: host}
+}
+
+type withEmulator struct {
+	option.ClientOption
+	host string
+}
+
+func (w *withEmulator) applyStorage(c *clientConfig) {
+	c.emulatorHost = w.host
+}
@@ -0,0 +1,39 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+
+	"google.golang.org/api/option"
+)
+
+func TestNewClientConfig(t *testing.T) {
+	transport := []option.ClientOption{option.WithoutAuthentication(), option.WithUserAgent("ua")}
+	config, got := newClientConfig([]option.ClientOption{
+		transport[0], WithUserProject("p"), transport[1], WithUserProject("q"),
+	})
+	if config.userProject != "q" {
+		t.Errorf("got user project %q, want %q", config.userProject, "q")
+	}
+	if len(got) != len(transport) {
+		t.Fatalf("got %d transport options, want %d", len(got), len(transport))
+	}
+	for i := range got {
+		if got[i] != transport[i] {
+			t.Errorf("transport option %d: got %v, want %v", i, got[i], transport[i])
+		}
+	}
+}
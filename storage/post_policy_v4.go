@@ -101,6 +101,18 @@ type PostPolicyV4Options struct {
 	// Optional.
 	Insecure bool
 
+	// Hostname replaces storage.googleapis.com in the generated URL, for
+	// example to target an emulator or a private endpoint. With
+	// VirtualHostedStyle, the bucket name is prepended to Hostname. It cannot
+	// be used with BucketBoundHostname, which already sets the host.
+	//
+	// If Hostname is empty and an emulator is in use, through
+	// STORAGE_EMULATOR_HOST or, for BucketHandle.GenerateSignedPostPolicyV4,
+	// WithEmulator, the host and scheme of the emulator are used, and Style
+	// is ignored in favor of PathStyle.
+	// Optional.
+	Hostname string
+
 	// Fields specifies the attributes of a PostPolicyV4 request.
 	// When Fields is non-nil, its attributes must match those that will
 	// passed into field Conditions.
@@ -125,6 +137,7 @@ func (opts *PostPolicyV4Options) clone() *PostPolicyV4Options {
 		Expires:             opts.Expires,
 		Style:               opts.Style,
 		Insecure:            opts.Insecure,
+		Hostname:            opts.Hostname,
 		Fields:              opts.Fields,
 		Conditions:          opts.Conditions,
 		shouldHashSignBytes: opts.shouldHashSignBytes,
@@ -250,6 +263,11 @@ func GenerateSignedPostPolicyV4(bucket, object string, opts *PostPolicyV4Options
 	if err := validatePostPolicyV4Options(opts, now); err != nil {
 		return nil, err
 	}
+	emulator, err := envEmulator()
+	if err != nil {
+		return nil, err
+	}
+	opts = opts.forEmulator(emulator)
 
 	var signingFn func(hashedBytes []byte) ([]byte, error)
 	switch {
@@ -368,7 +386,7 @@ func GenerateSignedPostPolicyV4(bucket, object string, opts *PostPolicyV4Options
 	u := &url.URL{
 		Path:    path,
 		RawPath: pathEncodeV4(path),
-		Host:    opts.Style.host(bucket),
+		Host:    signedURLHost(opts.Style, bucket, opts.Hostname),
 		Scheme:  scheme,
 	}
 
@@ -394,6 +412,7 @@ func GenerateSignedPostPolicyV4(bucket, object string, opts *PostPolicyV4Options
 // * either PrivateKey or SignRawBytes/SignBytes is set, but not both
 // * the deadline set in Expires is not in the past
 // * if Style is not set, it'll use PathStyle
+// * Hostname is not combined with BucketBoundHostname
 // * sets shouldHashSignBytes to true if opts.SignBytes should be used
 func validatePostPolicyV4Options(opts *PostPolicyV4Options, now time.Time) error {
 	if opts == nil || opts.GoogleAccessID == "" {
@@ -408,6 +427,9 @@ func validatePostPolicyV4Options(opts *PostPolicyV4Options, now time.Time) error
 	if opts.Style == nil {
 		opts.Style = PathStyle()
 	}
+	if _, ok := opts.Style.(bucketBoundHostname); ok && opts.Hostname != "" {
+		return errors.New("storage: Hostname cannot be used with BucketBoundHostname")
+	}
 	if opts.SignRawBytes == nil && opts.SignBytes != nil {
 		opts.shouldHashSignBytes = true
 	}
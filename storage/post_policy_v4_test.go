@@ -15,11 +15,18 @@
 package storage
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
 )
 
 func TestPostPolicyV4OptionsClone(t *testing.T) {
@@ -37,6 +44,7 @@ func TestPostPolicyV4OptionsClone(t *testing.T) {
 		Expires:             time.Now(),
 		Style:               VirtualHostedStyle(),
 		Insecure:            true,
+		Hostname:            "localhost:8080",
 		Fields:              &PolicyV4Fields{ACL: "test-acl"},
 		Conditions:          []PostPolicyV4Condition{},
 		shouldHashSignBytes: true,
@@ -68,3 +76,194 @@ func TestPostPolicyV4OptionsClone(t *testing.T) {
 		t.Errorf("clone does not match (original: -, cloned: +):\n%s", diff)
 	}
 }
+
+func TestGenerateSignedPostPolicyV4(t *testing.T) {
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	oldUTCNow := utcNow
+	defer func() {
+		utcNow = oldUTCNow
+	}()
+	utcNow = func() time.Time {
+		return now
+	}
+
+	for _, test := range []struct {
+		desc    string
+		style   URLStyle
+		host    string
+		wantURL string
+	}{
+		{
+			desc:    "default",
+			wantURL: "https://storage.googleapis.com/bucket-name/",
+		},
+		{
+			desc:    "virtual hosted style",
+			style:   VirtualHostedStyle(),
+			wantURL: "https://bucket-name.storage.googleapis.com/",
+		},
+		{
+			desc:    "custom hostname",
+			host:    "localhost:8080",
+			wantURL: "https://localhost:8080/bucket-name/",
+		},
+		{
+			desc:    "custom hostname with virtual hosted style",
+			style:   VirtualHostedStyle(),
+			host:    "example.com",
+			wantURL: "https://bucket-name.example.com/",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			pv4, err := GenerateSignedPostPolicyV4("bucket-name", "uploads/photo.jpg", &PostPolicyV4Options{
+				GoogleAccessID: "xxx@clientid",
+				PrivateKey:     dummyKey("rsa"),
+				Expires:        now.Add(time.Hour),
+				Style:          test.style,
+				Hostname:       test.host,
+				Fields:         &PolicyV4Fields{StatusCodeOnSuccess: 201},
+				Conditions: []PostPolicyV4Condition{
+					ConditionContentLengthRange(1, 10<<20),
+					ConditionStartsWith("$Content-Type", "image/"),
+					ConditionStartsWith("$key", "uploads/"),
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pv4.URL != test.wantURL {
+				t.Errorf("got URL %q, want %q", pv4.URL, test.wantURL)
+			}
+			for k, want := range map[string]string{
+				"key":                   "uploads/photo.jpg",
+				"x-goog-algorithm":      "GOOG4-RSA-SHA256",
+				"x-goog-credential":     "xxx@clientid/20220501/auto/storage/goog4_request",
+				"x-goog-date":           "20220501T120000Z",
+				"success_action_status": "201",
+			} {
+				if got := pv4.Fields[k]; got != want {
+					t.Errorf("field %q: got %q, want %q", k, got, want)
+				}
+			}
+			if pv4.Fields["x-goog-signature"] == "" {
+				t.Error("missing x-goog-signature field")
+			}
+
+			b, err := base64.StdEncoding.DecodeString(pv4.Fields["policy"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var policy struct {
+				Conditions []json.RawMessage
+				Expiration string
+			}
+			if err := json.Unmarshal(b, &policy); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := policy.Expiration, "2022-05-01T13:00:00Z"; got != want {
+				t.Errorf("got expiration %q, want %q", got, want)
+			}
+			var conds []string
+			for _, c := range policy.Conditions {
+				conds = append(conds, string(c))
+			}
+			for _, want := range []string{
+				`["content-length-range",1,10485760]`,
+				`["starts-with","$Content-Type","image/"]`,
+				`["starts-with","$key","uploads/"]`,
+				`{"success_action_status":"201"}`,
+				`{"bucket":"bucket-name"}`,
+				`{"key":"uploads/photo.jpg"}`,
+			} {
+				found := false
+				for _, got := range conds {
+					found = found || got == want
+				}
+				if !found {
+					t.Errorf("condition %s not in policy %v", want, conds)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateSignedPostPolicyV4Errors(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	for _, test := range []struct {
+		desc   string
+		opts   *PostPolicyV4Options
+		errMsg string
+	}{
+		{
+			desc:   "no access ID",
+			opts:   &PostPolicyV4Options{PrivateKey: dummyKey("rsa"), Expires: expires},
+			errMsg: "missing required GoogleAccessID",
+		},
+		{
+			desc:   "no signer",
+			opts:   &PostPolicyV4Options{GoogleAccessID: "xxx@clientid", Expires: expires},
+			errMsg: "exactly one of PrivateKey or SignRawBytes must be set",
+		},
+		{
+			desc:   "expired",
+			opts:   &PostPolicyV4Options{GoogleAccessID: "xxx@clientid", PrivateKey: dummyKey("rsa"), Expires: time.Now().Add(-time.Hour)},
+			errMsg: "expecting Expires to be in the future",
+		},
+		{
+			desc: "hostname with bucket bound hostname",
+			opts: &PostPolicyV4Options{
+				GoogleAccessID: "xxx@clientid",
+				PrivateKey:     dummyKey("rsa"),
+				Expires:        expires,
+				Style:          BucketBoundHostname("cdn.example.com"),
+				Hostname:       "localhost:8080",
+			},
+			errMsg: "cannot be used with BucketBoundHostname",
+		},
+	} {
+		_, err := GenerateSignedPostPolicyV4("bucket", "name", test.opts)
+		if err == nil || !strings.Contains(err.Error(), test.errMsg) {
+			t.Errorf("%s: got error %v, want it to contain %q", test.desc, err, test.errMsg)
+		}
+	}
+}
+
+func TestBucketGenerateSignedPostPolicyV4SignBlob(t *testing.T) {
+	var gotPath, gotPayload string
+	hc, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		var req struct {
+			Payload string `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		gotPayload = req.Payload
+		fmt.Fprintf(w, `{"signedBlob": %q}`, base64.StdEncoding.EncodeToString([]byte("signature")))
+	})
+	defer close()
+	c, err := NewClient(context.Background(), option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pv4, err := c.Bucket("bucket-name").GenerateSignedPostPolicyV4("object-name", &PostPolicyV4Options{
+		GoogleAccessID: "sa@project.iam.gserviceaccount.com",
+		Expires:        time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/v1/projects/-/serviceAccounts/sa@project.iam.gserviceaccount.com:signBlob"; gotPath != want {
+		t.Errorf("got signBlob path %q, want %q", gotPath, want)
+	}
+	// The signBlob payload is the base64-encoded policy, itself base64-encoded
+	// for transport.
+	if want := base64.StdEncoding.EncodeToString([]byte(pv4.Fields["policy"])); gotPayload != want {
+		t.Errorf("got payload %q, want %q", gotPayload, want)
+	}
+	if got, want := pv4.Fields["x-goog-signature"], fmt.Sprintf("%x", "signature"); got != want {
+		t.Errorf("got signature %q, want %q", got, want)
+	}
+}
@@ -70,6 +70,15 @@ type ReaderObjectAttrs struct {
 	// meaningful in the context of a particular generation of a
 	// particular object.
 	Metageneration int64
+
+	// Decompressed reports whether the content of a gzip-encoded object is
+	// being served decompressed, either by decompressive transcoding in the
+	// service or by the HTTP client. When it is true, Size is the size of
+	// the decompressed content if known and -1 otherwise, and the content is
+	// not checked against the object's CRC32C, which is computed on the
+	// stored bytes. Use ObjectHandle.ReadCompressed(true) to read the stored
+	// bytes instead.
+	Decompressed bool
 }
 
 // NewReader creates a new Reader to read the contents of the
@@ -90,7 +99,10 @@ func (o *ObjectHandle) NewReader(ctx context.Context) (*Reader, error) {
 // If the object's metadata property "Content-Encoding" is set to "gzip" or satisfies
 // decompressive transcoding per https://cloud.google.com/storage/docs/transcoding
 // that file will be served back whole, regardless of the requested range as
-// Google Cloud Storage dictates.
+// Google Cloud Storage dictates. The range is then applied to the
+// decompressed content as it is read, so the whole object is downloaded up to
+// the end of the range, and negative offsets are not supported. To read a
+// range of the stored, compressed bytes instead, use ReadCompressed(true).
 func (o *ObjectHandle) NewRangeReader(ctx context.Context, offset, length int64) (r *Reader, err error) {
 	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Object.NewRangeReader")
 	defer func() { trace.EndSpan(ctx, err) }()
@@ -195,9 +207,25 @@ func (o *ObjectHandle) NewRangeReader(ctx context.Context, offset, length int64)
 			// With "Content-Encoding": "gzip" aka decompressive transcoding, GCS serves
 			// back the whole file regardless of the range count passed in as per:
 			//      https://cloud.google.com/storage/docs/transcoding#range,
-			// thus we have to manually move the body forward by seen bytes.
-			if decompressiveTranscoding(res) && seen > 0 {
-				_, _ = io.CopyN(ioutil.Discard, res.Body, seen)
+			// thus we have to manually move the body forward to the start of the
+			// range, and stop at its end.
+			if decompressiveTranscoding(res) && res.StatusCode != http.StatusPartialContent && length != 0 {
+				if start < 0 {
+					res.Body.Close()
+					return errors.New("storage: negative offsets are not supported for objects served with decompressive transcoding; use ReadCompressed(true) to read the stored bytes")
+				}
+				if start > 0 {
+					if _, err := io.CopyN(ioutil.Discard, res.Body, start); err != nil {
+						res.Body.Close()
+						if err == io.EOF {
+							return fmt.Errorf("storage: offset %d is past the end of the decompressed object", start)
+						}
+						return err
+					}
+				}
+				if length > 0 {
+					res.Body = &limitedReadCloser{io.LimitReader(res.Body, offset+length-start), res.Body}
+				}
 			}
 
 			// If a generation hasn't been specified, and this is the first response we get, let's record the
@@ -263,6 +291,22 @@ func (o *ObjectHandle) NewRangeReader(ctx context.Context, offset, length int64)
 	}
 
 	remain := res.ContentLength
+	decompressed := res.Uncompressed || uncompressedByServer(res)
+	if decompressiveTranscoding(res) && res.StatusCode != http.StatusPartialContent && length != 0 && (offset > 0 || length > 0) {
+		// The range was applied to the decompressed content by reopen. The
+		// whole decompressed size is known only if the server sent it.
+		startOffset = offset
+		checkCRC = false
+		if remain >= 0 {
+			remain -= offset
+			if remain < 0 {
+				remain = 0
+			}
+			if length > 0 && length < remain {
+				remain = length
+			}
+		}
+	}
 	body := res.Body
 	if length == 0 {
 		remain = 0
@@ -294,15 +338,17 @@ func (o *ObjectHandle) NewRangeReader(ctx context.Context, offset, length int64)
 		StartOffset:     startOffset,
 		Generation:      gen,
 		Metageneration:  metaGen,
+		Decompressed:    decompressed,
 	}
 	return &Reader{
-		Attrs:    attrs,
-		body:     body,
-		size:     size,
-		remain:   remain,
-		wantCRC:  crc,
-		checkCRC: checkCRC,
-		reopen:   reopen,
+		Attrs:      attrs,
+		body:       body,
+		size:       size,
+		remain:     remain,
+		wantCRC:    crc,
+		checkCRC:   checkCRC,
+		reopen:     reopen,
+		maxResumes: o.maxReadResumes(),
 	}, nil
 }
 
@@ -318,6 +364,13 @@ func decompressiveTranscoding(res *http.Response) bool {
 		res.Header.Get("X-Goog-Stored-Content-Encoding") == "gzip"
 }
 
+// limitedReadCloser reads at most a limited number of bytes from a body, and
+// closes the body.
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
 func uncompressedByServer(res *http.Response) bool {
 	// If the data is stored as gzip but is not encoded as gzip, then it
 	// was uncompressed by the server.
@@ -363,15 +416,29 @@ var emptyBody = ioutil.NopCloser(strings.NewReader(""))
 //
 // Typically, a Reader computes the CRC of the downloaded content and compares it to
 // the stored CRC, returning an error from Read if there is a mismatch. This integrity check
-// is skipped if transcoding occurs. See https://cloud.google.com/storage/docs/transcoding.
+// is skipped if transcoding occurs, which is reported by Attrs.Decompressed.
+// See https://cloud.google.com/storage/docs/transcoding.
 type Reader struct {
-	Attrs              ReaderObjectAttrs
+	Attrs ReaderObjectAttrs
+
+	// ProgressFunc can be used to monitor the progress of a read. If
+	// ProgressFunc is not nil, it is invoked after each Read that returns
+	// content with the number of bytes read so far. Connections that are
+	// reopened after a transient error resume the count rather than
+	// restarting it.
+	//
+	// ProgressFunc should return quickly without blocking.
+	ProgressFunc func(int64)
+
 	body               io.ReadCloser
 	seen, remain, size int64
 	checkCRC           bool   // should we check the CRC?
 	wantCRC            uint32 // the CRC32c value the server sent in the header
 	gotCRC             uint32 // running crc
 	reopen             func(seen int64) (*http.Response, error)
+	// resumes counts the reconnections since data was last read, up to
+	// maxResumes.
+	resumes, maxResumes int
 
 	// The following fields are only for use in the gRPC hybrid client.
 	stream         storagepb.Storage_ReadObjectClient
@@ -405,6 +472,9 @@ func (r *Reader) Read(p []byte) (int, error) {
 	if r.remain != -1 {
 		r.remain -= int64(n)
 	}
+	if r.ProgressFunc != nil && n > 0 {
+		r.ProgressFunc(r.seen)
+	}
 	if r.checkCRC {
 		r.gotCRC = crc32.Update(r.gotCRC, crc32cTable, p[:n])
 		// Check CRC here. It would be natural to check it in Close, but
@@ -512,6 +582,7 @@ func (o *ObjectHandle) newRangeReaderWithGRPC(ctx context.Context, offset, lengt
 		stream:         res.stream,
 		reopenWithGRPC: reopen,
 		cancelStream:   cancel,
+		maxResumes:     o.maxReadResumes(),
 	}
 
 	// The first message was Recv'd on stream open, use it to populate the
@@ -559,9 +630,16 @@ func (r *Reader) readWithRetry(p []byte) (int, error) {
 		m, err := r.body.Read(p[n:])
 		n += m
 		r.seen += int64(m)
+		if m > 0 {
+			r.resumes = 0
+		}
 		if err == nil || err == io.EOF {
 			return n, err
 		}
+		if r.resumes >= r.maxResumes {
+			return n, err
+		}
+		r.resumes++
 		// Read failed (likely due to connection issues), but we will try to reopen
 		// the pipe and continue. Send a ranged read request that takes into account
 		// the number of bytes we've already seen.
@@ -647,6 +725,7 @@ func (r *Reader) readWithGRPC(p []byte) (int, error) {
 // * Recv is successful
 // * A non-retryable error is encountered
 // * The Reader's context is canceled
+// * The Reader has reconnected maxResumes times without receiving a message
 //
 // The last error received is the one that is returned, which could be from
 // an attempt to reopen the stream.
@@ -654,7 +733,10 @@ func (r *Reader) readWithGRPC(p []byte) (int, error) {
 // This is an experimental API and not intended for public use.
 func (r *Reader) recv() (*storagepb.ReadObjectResponse, error) {
 	msg, err := r.stream.Recv()
-	if err != nil && shouldRetry(err) {
+	if err == nil {
+		r.resumes = 0
+	} else if shouldRetry(err) && r.resumes < r.maxResumes {
+		r.resumes++
 		// This will "close" the existing stream and immediately attempt to
 		// reopen the stream, but will backoff if further attempts are necessary.
 		// Reopening the stream Recvs the first message, so if retrying is
@@ -102,39 +102,46 @@ func TestContentEncodingGzipWithReader(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer client.Close()
-	// 2. Different flavours of the read should all return the body.
+	// 2. Different flavours of the read should all return the requested range
+	// of the decompressed body.
 	readerCreators := []struct {
 		name   string
 		create func(ctx context.Context, obj *ObjectHandle) (*Reader, error)
+		want   []byte
 	}{
 		{
 			"NewReader", func(cxt context.Context, obj *ObjectHandle) (*Reader, error) {
 				return obj.NewReader(ctx)
 			},
+			original,
 		},
 		{
 			"NewRangeReader(0, -1)",
 			func(ctx context.Context, obj *ObjectHandle) (*Reader, error) {
 				return obj.NewRangeReader(ctx, 0, -1)
 			},
+			original,
 		},
 		{
 			"NewRangeReader(1kB, 2kB)",
 			func(ctx context.Context, obj *ObjectHandle) (*Reader, error) {
 				return obj.NewRangeReader(ctx, 1<<10, 2<<10)
 			},
+			original[1<<10 : 3<<10],
 		},
 		{
 			"NewRangeReader(2kB, -1)",
 			func(ctx context.Context, obj *ObjectHandle) (*Reader, error) {
 				return obj.NewRangeReader(ctx, 2<<10, -1)
 			},
+			original[2<<10:],
 		},
 		{
 			"NewRangeReader(2kB, 3kB)",
 			func(ctx context.Context, obj *ObjectHandle) (*Reader, error) {
 				return obj.NewRangeReader(ctx, 2<<10, 3<<10)
 			},
+			original[2<<10:],
 		},
 	}
 
@@ -155,7 +162,7 @@ func TestContentEncodingGzipWithReader(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			if g, w := got, original; !bytes.Equal(g, w) {
+			if g, w := got, tt.want; !bytes.Equal(g, w) {
 				t.Fatalf("Response mismatch\nGot:\n%q\n\nWant:\n%q", g, w)
 			}
 		})
@@ -18,9 +18,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -68,6 +70,34 @@ func TestRangeReader(t *testing.T) {
 	}
 }
 
+func TestReaderProgressFunc(t *testing.T) {
+	hc, close := newTestServer(handleRangeRead)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.Bucket("b").Object("o").NewRangeReader(ctx, 2, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var got []int64
+	r.ProgressFunc = func(n int64) { got = append(got, n) }
+	buf := make([]byte, 4)
+	for {
+		if _, err := r.Read(buf); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []int64{4, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got progress %v, want %v", got, want)
+	}
+}
+
 func handleRangeRead(w http.ResponseWriter, r *http.Request) {
 	rh := strings.TrimSpace(r.Header.Get("Range"))
 	data := readData
@@ -124,6 +154,68 @@ func handleRangeRead(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func TestRangeReaderDecompressiveTranscoding(t *testing.T) {
+	hc, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		// The service ignores the range of transcoded objects, and the
+		// checksum is that of the stored, compressed bytes.
+		w.Header().Set("X-Goog-Stored-Content-Encoding", "gzip")
+		w.Header().Set("X-Goog-Hash", "crc32c=AAAAAA==")
+		w.Write([]byte(readData))
+	})
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := c.Bucket("b").Object("o")
+	for _, test := range []struct {
+		offset, length int64
+		want           string
+		wantRemain     int64
+	}{
+		{0, -1, readData, 10},
+		{0, 4, "0123", 4},
+		{3, 4, "3456", 4},
+		{3, -1, "3456789", 7},
+		{8, 5, "89", 2},
+	} {
+		r, err := obj.NewRangeReader(ctx, test.offset, test.length)
+		if err != nil {
+			t.Errorf("%d, %d: %v", test.offset, test.length, err)
+			continue
+		}
+		if !r.Attrs.Decompressed {
+			t.Errorf("%d, %d: got Attrs.Decompressed false, want true", test.offset, test.length)
+		}
+		if got := r.Attrs.StartOffset; got != test.offset {
+			t.Errorf("%d, %d: got Attrs.StartOffset %d, want %d", test.offset, test.length, got, test.offset)
+		}
+		if got := r.Remain(); got != test.wantRemain {
+			t.Errorf("%d, %d: got Remain %d, want %d", test.offset, test.length, got, test.wantRemain)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Errorf("%d, %d: %v", test.offset, test.length, err)
+			continue
+		}
+		if got := string(b); got != test.want {
+			t.Errorf("%d, %d: got %q, want %q", test.offset, test.length, got, test.want)
+		}
+	}
+	for _, test := range []struct {
+		offset, length int64
+	}{
+		{-3, -1},
+		{12, 2},
+	} {
+		if _, err := obj.NewRangeReader(ctx, test.offset, test.length); err == nil {
+			t.Errorf("%d, %d: got nil error, want non-nil", test.offset, test.length)
+		}
+	}
+}
+
 type http2Error string
 
 func (h http2Error) Error() string {
@@ -347,3 +439,123 @@ func TestFakeReadCloser(t *testing.T) {
 		}
 	}
 }
+
+// resumeServer serves an object, cutting the connection short after
+// sending cut bytes of each of the first len(cuts) responses.
+type resumeServer struct {
+	content string
+	crc     uint32
+	cuts    []int
+	ranges  []string
+}
+
+func (s *resumeServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	var start int
+	if rng := r.Header.Get("Range"); rng != "" {
+		start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.content)-1, len(s.content)))
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(s.content)-start))
+	w.Header().Set("X-Goog-Hash", "crc32c="+encodeUint32(s.crc))
+	w.Header().Set("X-Goog-Generation", "7")
+	if start > 0 {
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	if len(s.ranges) > len(s.cuts) {
+		io.WriteString(w, s.content[start:])
+		return
+	}
+	io.WriteString(w, s.content[start:start+s.cuts[len(s.ranges)-1]])
+	w.(http.Flusher).Flush()
+	conn, _, _ := w.(http.Hijacker).Hijack()
+	conn.Close()
+}
+
+func TestReaderResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	crc := crc32.Checksum([]byte(content), crc32cTable)
+	for _, test := range []struct {
+		desc       string
+		cuts       []int
+		crc        uint32
+		handle     func(*ObjectHandle) *ObjectHandle
+		wantRanges []string
+		wantErr    bool
+	}{
+		{
+			desc:       "resumes from last byte",
+			cuts:       []int{10, 25, 5},
+			crc:        crc,
+			wantRanges: []string{"", "bytes=10-", "bytes=35-", "bytes=40-"},
+		},
+		{
+			desc:       "budget is reset by progress",
+			cuts:       []int{10, 0, 5, 0, 5},
+			crc:        crc,
+			handle:     func(o *ObjectHandle) *ObjectHandle { return o.ReadResumes(2) },
+			wantRanges: []string{"", "bytes=10-", "bytes=10-", "bytes=15-", "bytes=15-", "bytes=20-"},
+		},
+		{
+			desc:       "budget exhausted",
+			cuts:       []int{10, 0, 0, 0},
+			crc:        crc,
+			handle:     func(o *ObjectHandle) *ObjectHandle { return o.ReadResumes(2) },
+			wantRanges: []string{"", "bytes=10-", "bytes=10-"},
+			wantErr:    true,
+		},
+		{
+			desc:       "resuming disabled",
+			cuts:       []int{10},
+			crc:        crc,
+			handle:     func(o *ObjectHandle) *ObjectHandle { return o.ReadResumes(0) },
+			wantRanges: []string{""},
+			wantErr:    true,
+		},
+		{
+			desc:       "retry policy RetryNever",
+			cuts:       []int{10},
+			crc:        crc,
+			handle:     func(o *ObjectHandle) *ObjectHandle { return o.Retryer(WithPolicy(RetryNever)) },
+			wantRanges: []string{""},
+			wantErr:    true,
+		},
+		{
+			desc:       "CRC checked across reconnects",
+			cuts:       []int{10, 10},
+			crc:        crc + 1,
+			wantRanges: []string{"", "bytes=10-", "bytes=20-"},
+			wantErr:    true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			s := &resumeServer{content: content, crc: test.crc, cuts: test.cuts}
+			hc, close := newTestServer(s.handle)
+			defer close()
+			ctx := context.Background()
+			c, err := NewClient(ctx, option.WithHTTPClient(hc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj := c.Bucket("b").Object("o")
+			if test.handle != nil {
+				obj = test.handle(obj)
+			}
+			r, err := obj.NewReader(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := ioutil.ReadAll(r)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("got error %v, want error: %t", err, test.wantErr)
+			}
+			if err == nil && string(got) != content {
+				t.Errorf("got content %q, want %q", got, content)
+			}
+			if !reflect.DeepEqual(s.ranges, test.wantRanges) {
+				t.Errorf("got ranges %q, want %q", s.ranges, test.wantRanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// NewReaderAt creates a new ReaderAt to read the contents of the object at
+// arbitrary offsets. The object's attributes are fetched once, and the
+// generation of the object is pinned so that every read sees the same
+// content.
+// ErrObjectNotExist will be returned if the object is not found.
+//
+// The context is used for all reads made through the returned ReaderAt.
+//
+// Objects served with decompressive transcoding cannot be read in ranges, so
+// NewReaderAt returns an error for them unless the handle was configured with
+// ReadCompressed(true). See https://cloud.google.com/storage/docs/transcoding.
+func (o *ObjectHandle) NewReaderAt(ctx context.Context) (*ReaderAt, error) {
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if attrs.ContentEncoding == "gzip" && !o.readCompressed {
+		return nil, errors.New("storage: NewReaderAt: gzip-encoded objects require ReadCompressed(true)")
+	}
+	return &ReaderAt{
+		ctx:   ctx,
+		o:     o.Generation(attrs.Generation),
+		attrs: attrs,
+	}, nil
+}
+
+// A ReaderAt reads a Cloud Storage object at arbitrary offsets. Each ReadAt
+// call is served by its own ranged request, so ReaderAt can be used with
+// random-access consumers such as archive/zip without downloading the whole
+// object.
+//
+// ReaderAt implements io.ReaderAt and io.ReadSeeker. ReadAt is safe to call
+// concurrently. Read and Seek share an offset, and are safe to call
+// concurrently with each other and with ReadAt, although concurrent Reads
+// observe the shared offset in no particular order.
+type ReaderAt struct {
+	ctx   context.Context
+	o     *ObjectHandle
+	attrs *ObjectAttrs
+
+	mu  sync.Mutex
+	off int64 // offset of the next Read.
+}
+
+// Attrs returns the attributes of the object being read.
+func (r *ReaderAt) Attrs() *ObjectAttrs {
+	return r.attrs
+}
+
+// Size returns the size of the object in bytes.
+func (r *ReaderAt) Size() int64 {
+	return r.attrs.Size
+}
+
+// ReadAt reads len(p) bytes of the object starting at byte offset off. It
+// implements io.ReaderAt.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("storage: ReadAt: negative offset %d", off)
+	}
+	if off >= r.attrs.Size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	want := len(p)
+	if remain := r.attrs.Size - off; int64(want) > remain {
+		want = int(remain)
+	}
+	rr, err := r.o.NewRangeReader(r.ctx, off, int64(want))
+	if err != nil {
+		return 0, err
+	}
+	defer rr.Close()
+	n, err := io.ReadFull(rr, p[:want])
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("storage: ReadAt: object ended after %d bytes at offset %d, want %d", n, off, want)
+		}
+		return n, err
+	}
+	if want < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Read reads up to len(p) bytes from the current offset and advances it. It
+// implements io.Reader.
+func (r *ReaderAt) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n, err := r.ReadAt(p, r.off)
+	r.off += int64(n)
+	if err == io.EOF && n > 0 {
+		// Report io.EOF on the next call, as most readers do.
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the offset of the next Read, as described by io.Seeker. Seeking
+// past the end of the object is allowed; subsequent Reads return io.EOF.
+func (r *ReaderAt) Seek(offset int64, whence int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.attrs.Size
+	default:
+		return 0, fmt.Errorf("storage: Seek: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("storage: Seek: negative position %d", offset)
+	}
+	r.off = offset
+	return offset, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"google.golang.org/api/option"
+)
+
+func newTestReaderAt(t *testing.T, data string) (*ReaderAt, func()) {
+	t.Helper()
+	s := &slicedServer{data: data}
+	hc, close := newTestServer(s.handle)
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		close()
+		t.Fatal(err)
+	}
+	r, err := c.Bucket("b").Object("o").NewReaderAt(ctx)
+	if err != nil {
+		close()
+		t.Fatal(err)
+	}
+	return r, close
+}
+
+func TestReaderAt(t *testing.T) {
+	r, close := newTestReaderAt(t, readData)
+	defer close()
+	if got, want := r.Size(), int64(len(readData)); got != want {
+		t.Errorf("Size: got %d, want %d", got, want)
+	}
+	for _, test := range []struct {
+		off     int64
+		len     int
+		want    string
+		wantErr error
+	}{
+		{0, 10, readData, nil},
+		{2, 3, readData[2:5], nil},
+		{7, 5, readData[7:], io.EOF},
+		{10, 1, "", io.EOF},
+		{3, 0, "", nil},
+	} {
+		p := make([]byte, test.len)
+		n, err := r.ReadAt(p, test.off)
+		if err != test.wantErr {
+			t.Errorf("ReadAt(%d, %d): got error %v, want %v", test.off, test.len, err, test.wantErr)
+		}
+		if got := string(p[:n]); got != test.want {
+			t.Errorf("ReadAt(%d, %d): got %q, want %q", test.off, test.len, got, test.want)
+		}
+	}
+}
+
+func TestReaderAtSeek(t *testing.T) {
+	r, close := newTestReaderAt(t, readData)
+	defer close()
+	if _, err := r.Seek(-4, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := readData[6:]; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if pos, err := r.Seek(-8, io.SeekCurrent); err != nil || pos != 2 {
+		t.Errorf("Seek: got %d, %v, want 2, nil", pos, err)
+	}
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Error("Seek to a negative position: got nil error, want non-nil")
+	}
+}
+
+func TestReaderAtZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{"a.txt": "alpha", "b/c.txt": "charlie"}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, close := newTestReaderAt(t, buf.String())
+	defer close()
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(zr.File), len(files); got != want {
+		t.Fatalf("got %d files, want %d", got, want)
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := files[f.Name]; string(got) != want {
+			t.Errorf("%s: got %q, want %q", f.Name, got, want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+)
+
+// Object retention modes.
+const (
+	// ObjectRetentionUnlocked allows the retention configuration to be
+	// shortened or removed with SetRetentionOptions.OverrideUnlockedRetention.
+	ObjectRetentionUnlocked = "Unlocked"
+	// ObjectRetentionLocked prevents the retention configuration from being
+	// shortened or removed; it can only be extended.
+	ObjectRetentionLocked = "Locked"
+)
+
+// ObjectRetention is the retention configuration of an object. While it is in
+// effect, the object cannot be deleted or overwritten.
+// See https://cloud.google.com/storage/docs/object-lock.
+type ObjectRetention struct {
+	// Mode is the retention mode, ObjectRetentionUnlocked or
+	// ObjectRetentionLocked.
+	Mode string
+
+	// RetainUntil is the time until which the object is retained.
+	RetainUntil time.Time
+}
+
+type rawObjectRetention struct {
+	Mode            string `json:"mode,omitempty"`
+	RetainUntilTime string `json:"retainUntilTime,omitempty"`
+}
+
+func (r *rawObjectRetention) toObjectRetention() *ObjectRetention {
+	if r == nil {
+		return nil
+	}
+	return &ObjectRetention{
+		Mode:        r.Mode,
+		RetainUntil: convertTime(r.RetainUntilTime),
+	}
+}
+
+type rawRetentionObject struct {
+	Retention *rawObjectRetention `json:"retention"`
+}
+
+// SetRetentionOptions are options for ObjectHandle.SetRetention.
+type SetRetentionOptions struct {
+	// OverrideUnlockedRetention must be set to shorten or remove an unlocked
+	// retention configuration. It is not needed to extend a configuration or
+	// to lock it.
+	OverrideUnlockedRetention bool
+}
+
+// Retention returns the retention configuration of the object, or nil if the
+// object has none.
+// ErrObjectNotExist will be returned if the object is not found.
+func (o *ObjectHandle) Retention(ctx context.Context) (retention *ObjectRetention, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Object.Retention")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	query := url.Values{"fields": {"retention"}}
+	if err := applyConds("Retention", o.gen, o.conds, queryConds(query)); err != nil {
+		return nil, err
+	}
+	if o.userProject != "" {
+		query.Set("userProject", o.userProject)
+	}
+	var resp rawRetentionObject
+	err = run(ctx, func() error {
+		return o.c.doJSON(ctx, "GET", o.jsonPath(), query, nil, &resp)
+	}, o.retry, true)
+	if err != nil {
+		return nil, objectNotExistErr(err)
+	}
+	return resp.Retention.toObjectRetention(), nil
+}
+
+// SetRetention sets the retention configuration of the object, and returns
+// the updated configuration. A nil retention removes the configuration, which
+// is only possible for unlocked configurations with
+// opts.OverrideUnlockedRetention set. The bucket must have been created with
+// BucketAttrs.EnableObjectRetention.
+// ErrObjectNotExist will be returned if the object is not found.
+func (o *ObjectHandle) SetRetention(ctx context.Context, retention *ObjectRetention, opts *SetRetentionOptions) (_ *ObjectRetention, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Object.SetRetention")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	req := &rawRetentionObject{}
+	if retention != nil {
+		switch retention.Mode {
+		case ObjectRetentionUnlocked, ObjectRetentionLocked:
+		default:
+			return nil, fmt.Errorf("storage: invalid object retention mode %q", retention.Mode)
+		}
+		if retention.RetainUntil.IsZero() {
+			return nil, errors.New("storage: object retention requires RetainUntil")
+		}
+		req.Retention = &rawObjectRetention{
+			Mode:            retention.Mode,
+			RetainUntilTime: retention.RetainUntil.Format(time.RFC3339),
+		}
+	}
+	query := url.Values{"fields": {"retention"}}
+	if err := applyConds("SetRetention", o.gen, o.conds, queryConds(query)); err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.OverrideUnlockedRetention {
+		query.Set("overrideUnlockedRetention", "true")
+	}
+	if o.userProject != "" {
+		query.Set("userProject", o.userProject)
+	}
+	var resp rawRetentionObject
+	isIdempotent := o.conds != nil && o.conds.MetagenerationMatch != 0
+	err = run(ctx, func() error {
+		return o.c.doJSON(ctx, "PATCH", o.jsonPath(), query, req, &resp)
+	}, o.retry, isIdempotent)
+	if err != nil {
+		return nil, objectNotExistErr(err)
+	}
+	return resp.Retention.toObjectRetention(), nil
+}
+
+// ObjectRetentionEnabled reports whether retention configurations can be set
+// on the objects in the bucket.
+func (b *BucketHandle) ObjectRetentionEnabled(ctx context.Context) (enabled bool, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Bucket.ObjectRetentionEnabled")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := url.Values{"fields": {"objectRetention"}}
+	if b.userProject != "" {
+		query.Set("userProject", b.userProject)
+	}
+	var resp struct {
+		ObjectRetention *struct {
+			Mode string `json:"mode"`
+		} `json:"objectRetention"`
+	}
+	err = run(ctx, func() error {
+		return b.c.doJSON(ctx, "GET", "b/"+url.PathEscape(b.name), query, nil, &resp)
+	}, b.retry, true)
+	if err != nil {
+		return false, bucketNotExistErr(err)
+	}
+	return resp.ObjectRetention != nil && resp.ObjectRetention.Mode == "Enabled", nil
+}
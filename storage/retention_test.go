@@ -0,0 +1,127 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestObjectRetention(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PATCH" && r.URL.Query().Get("overrideUnlockedRetention") == "true":
+			fmt.Fprint(w, `{}`)
+		default:
+			fmt.Fprint(w, `{"retention": {"mode": "Locked", "retainUntilTime": "2030-01-01T00:00:00Z"}}`)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	obj := c.Bucket("b").Object("o")
+	want := &ObjectRetention{Mode: ObjectRetentionLocked, RetainUntil: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	got, err := obj.Retention(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Retention mismatch (-want +got):\n%s", diff)
+	}
+	got, err = obj.If(Conditions{MetagenerationMatch: 3}).SetRetention(ctx, want, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SetRetention mismatch (-want +got):\n%s", diff)
+	}
+	got, err = obj.SetRetention(ctx, nil, &SetRetentionOptions{OverrideUnlockedRetention: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != nil {
+		t.Errorf("got retention %+v after removal, want nil", got)
+	}
+
+	wantReqs := []recordedRequest{
+		{"GET", "/storage/v1/b/b/o/o", url.Values{"fields": {"retention"}}, ""},
+		{"PATCH", "/storage/v1/b/b/o/o", url.Values{"fields": {"retention"}, "ifMetagenerationMatch": {"3"}},
+			`{"retention":{"mode":"Locked","retainUntilTime":"2030-01-01T00:00:00Z"}}`},
+		{"PATCH", "/storage/v1/b/b/o/o", url.Values{"fields": {"retention"}, "overrideUnlockedRetention": {"true"}},
+			`{"retention":null}`},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, r := range []*ObjectRetention{
+		{Mode: "Forever", RetainUntil: want.RetainUntil},
+		{Mode: ObjectRetentionUnlocked},
+	} {
+		if _, err := obj.SetRetention(ctx, r, nil); err == nil {
+			t.Errorf("SetRetention(%+v): got nil error, want non-nil", r)
+		}
+	}
+}
+
+func TestObjectRetentionEnabled(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/storage/v1/b/enabled":
+			fmt.Fprint(w, `{"objectRetention": {"mode": "Enabled"}}`)
+		case "/storage/v1/b/disabled":
+			fmt.Fprint(w, `{}`)
+		case "/storage/v1/b":
+			fmt.Fprint(w, `{"name": "new"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	defer close()
+	ctx := context.Background()
+	for _, test := range []struct {
+		bucket string
+		want   bool
+	}{
+		{"enabled", true},
+		{"disabled", false},
+	} {
+		got, err := c.Bucket(test.bucket).ObjectRetentionEnabled(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: got %t, want %t", test.bucket, got, test.want)
+		}
+	}
+	if _, err := c.Bucket("missing").ObjectRetentionEnabled(ctx); err != ErrBucketNotExist {
+		t.Errorf("got error %v, want %v", err, ErrBucketNotExist)
+	}
+
+	if err := c.Bucket("new").Create(ctx, "p", &BucketAttrs{EnableObjectRetention: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := reqs[len(reqs)-1].query.Get("enableObjectRetention"); got != "true" {
+		t.Errorf("got enableObjectRetention %q, want %q", got, "true")
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/internal/trace"
+	"golang.org/x/xerrors"
+	"google.golang.org/api/googleapi"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// SoftDeletePolicy is the soft delete policy of a bucket. While the policy is
+// in effect, deleted and overwritten objects are kept as soft-deleted objects
+// for the retention duration, and can be listed with Query.SoftDeleted and
+// restored with ObjectHandle.Restore.
+// See https://cloud.google.com/storage/docs/soft-delete.
+type SoftDeletePolicy struct {
+	// RetentionDuration is how long soft-deleted objects are kept. It is
+	// rounded down to whole seconds. Zero disables soft delete.
+	RetentionDuration time.Duration
+
+	// EffectiveTime is the time from which the policy, or its most recent
+	// update, is in effect.
+	// This field is read-only.
+	EffectiveTime time.Time
+}
+
+type rawSoftDeletePolicy struct {
+	RetentionDurationSeconds int64  `json:"retentionDurationSeconds,string"`
+	EffectiveTime            string `json:"effectiveTime,omitempty"`
+}
+
+type rawSoftDeleteBucket struct {
+	SoftDeletePolicy *rawSoftDeletePolicy `json:"softDeletePolicy,omitempty"`
+}
+
+func (p *rawSoftDeletePolicy) toSoftDeletePolicy() *SoftDeletePolicy {
+	if p == nil {
+		return &SoftDeletePolicy{}
+	}
+	return &SoftDeletePolicy{
+		RetentionDuration: time.Duration(p.RetentionDurationSeconds) * time.Second,
+		EffectiveTime:     convertTime(p.EffectiveTime),
+	}
+}
+
+// SoftDeletePolicy returns the soft delete policy of the bucket.
+func (b *BucketHandle) SoftDeletePolicy(ctx context.Context) (policy *SoftDeletePolicy, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Bucket.SoftDeletePolicy")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	query := url.Values{"fields": {"softDeletePolicy"}}
+	if b.userProject != "" {
+		query.Set("userProject", b.userProject)
+	}
+	var resp rawSoftDeleteBucket
+	err = run(ctx, func() error {
+		return b.c.doJSON(ctx, "GET", "b/"+url.PathEscape(b.name), query, nil, &resp)
+	}, b.retry, true)
+	if err != nil {
+		return nil, bucketNotExistErr(err)
+	}
+	return resp.SoftDeletePolicy.toSoftDeletePolicy(), nil
+}
+
+// SetSoftDeletePolicy sets the retention duration of the bucket's soft
+// delete policy, and returns the updated policy. A zero RetentionDuration
+// disables soft delete.
+func (b *BucketHandle) SetSoftDeletePolicy(ctx context.Context, policy SoftDeletePolicy) (_ *SoftDeletePolicy, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Bucket.SetSoftDeletePolicy")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if policy.RetentionDuration < 0 {
+		return nil, fmt.Errorf("storage: negative soft delete retention duration %v", policy.RetentionDuration)
+	}
+	query := url.Values{"fields": {"softDeletePolicy"}}
+	if b.userProject != "" {
+		query.Set("userProject", b.userProject)
+	}
+	req := &rawSoftDeleteBucket{SoftDeletePolicy: &rawSoftDeletePolicy{
+		RetentionDurationSeconds: int64(policy.RetentionDuration / time.Second),
+	}}
+	var resp rawSoftDeleteBucket
+	// Setting the policy to a fixed value is idempotent.
+	err = run(ctx, func() error {
+		return b.c.doJSON(ctx, "PATCH", "b/"+url.PathEscape(b.name), query, req, &resp)
+	}, b.retry, true)
+	if err != nil {
+		return nil, bucketNotExistErr(err)
+	}
+	return resp.SoftDeletePolicy.toSoftDeletePolicy(), nil
+}
+
+// RestoreOptions are options for ObjectHandle.Restore.
+type RestoreOptions struct {
+	// CopySourceACL restores the object's ACL as it was when the object was
+	// deleted. By default, the restored object gets the bucket's default
+	// object ACL.
+	CopySourceACL bool
+}
+
+// Restore restores a soft-deleted generation of the object, which becomes
+// the live version of the object. The handle must specify the generation to
+// restore with Generation; soft-deleted generations can be found by listing
+// objects with Query.SoftDeleted. Conditions on the handle apply to the live
+// version of the object, for example DoesNotExist avoids overwriting an
+// object that was recreated since the deletion.
+// ErrObjectNotExist will be returned if the generation is not found.
+func (o *ObjectHandle) Restore(ctx context.Context, opts *RestoreOptions) (attrs *ObjectAttrs, err error) {
+	ctx = trace.StartSpan(ctx, "cloud.google.com/go/storage.Object.Restore")
+	defer func() { trace.EndSpan(ctx, err) }()
+
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if o.gen < 0 {
+		return nil, errors.New("storage: Restore requires a generation")
+	}
+	query := url.Values{}
+	if err := applyConds("Restore", o.gen, o.conds, queryConds(query)); err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.CopySourceACL {
+		query.Set("copySourceAcl", "true")
+	}
+	if o.userProject != "" {
+		query.Set("userProject", o.userProject)
+	}
+	var obj raw.Object
+	isIdempotent := o.conds != nil && (o.conds.GenerationMatch != 0 || o.conds.DoesNotExist)
+	err = run(ctx, func() error {
+		return o.c.doJSON(ctx, "POST", o.jsonPath()+"/restore", query, nil, &obj)
+	}, o.retry, isIdempotent)
+	if err != nil {
+		return nil, objectNotExistErr(err)
+	}
+	return newObject(&obj), nil
+}
+
+// queryConds adapts query parameters to applyConds.
+type queryConds url.Values
+
+func (q queryConds) set(name string, v int64) {
+	url.Values(q).Set(name, strconv.FormatInt(v, 10))
+}
+
+func (q queryConds) Generation(gen int64)               { q.set("generation", gen) }
+func (q queryConds) IfGenerationMatch(gen int64)        { q.set("ifGenerationMatch", gen) }
+func (q queryConds) IfGenerationNotMatch(gen int64)     { q.set("ifGenerationNotMatch", gen) }
+func (q queryConds) IfMetagenerationMatch(gen int64)    { q.set("ifMetagenerationMatch", gen) }
+func (q queryConds) IfMetagenerationNotMatch(gen int64) { q.set("ifMetagenerationNotMatch", gen) }
+
+// jsonPath returns the path of the object relative to the JSON API base path.
+func (o *ObjectHandle) jsonPath() string {
+	return "b/" + url.PathEscape(o.bucket) + "/o/" + url.PathEscape(o.object)
+}
+
+func objectNotExistErr(err error) error {
+	var e *googleapi.Error
+	if ok := xerrors.As(err, &e); ok && e.Code == http.StatusNotFound {
+		return ErrObjectNotExist
+	}
+	return err
+}
+
+func bucketNotExistErr(err error) error {
+	var e *googleapi.Error
+	if ok := xerrors.As(err, &e); ok && e.Code == http.StatusNotFound {
+		return ErrBucketNotExist
+	}
+	return err
+}
+
+// doJSON sends a request to the JSON API for a method that the generated
+// client does not support, and decodes the response into out. The path is
+// relative to the API's base path, and in, if non-nil, is sent as the JSON
+// request body.
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	u := c.raw.BasePath + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	setClientHeader(req.Header)
+	res, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/api/option"
+)
+
+type recordedRequest struct {
+	method, path string
+	query        url.Values
+	body         string
+}
+
+func newRecordingTestClient(t *testing.T, reqs *[]recordedRequest, handler func(w http.ResponseWriter, r *http.Request)) (*Client, func()) {
+	t.Helper()
+	hc, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*reqs = append(*reqs, recordedRequest{r.Method, r.URL.Path, r.URL.Query(), string(body)})
+		handler(w, r)
+	})
+	c, err := NewClient(context.Background(), option.WithHTTPClient(hc))
+	if err != nil {
+		close()
+		t.Fatal(err)
+	}
+	return c, close
+}
+
+func TestSoftDeletePolicy(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"softDeletePolicy": {"retentionDurationSeconds": "604800", "effectiveTime": "2022-03-01T10:00:00Z"}}`)
+	})
+	defer close()
+	ctx := context.Background()
+	b := c.Bucket("b").UserProject("p")
+
+	want := &SoftDeletePolicy{
+		RetentionDuration: 7 * 24 * time.Hour,
+		EffectiveTime:     time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC),
+	}
+	got, err := b.SoftDeletePolicy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SoftDeletePolicy mismatch (-want +got):\n%s", diff)
+	}
+	got, err = b.SetSoftDeletePolicy(ctx, SoftDeletePolicy{RetentionDuration: 7*24*time.Hour + time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SetSoftDeletePolicy mismatch (-want +got):\n%s", diff)
+	}
+
+	wantReqs := []recordedRequest{
+		{"GET", "/storage/v1/b/b", url.Values{"fields": {"softDeletePolicy"}, "userProject": {"p"}}, ""},
+		{"PATCH", "/storage/v1/b/b", url.Values{"fields": {"softDeletePolicy"}, "userProject": {"p"}}, `{"softDeletePolicy":{"retentionDurationSeconds":"604800"}}`},
+	}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := b.SetSoftDeletePolicy(ctx, SoftDeletePolicy{RetentionDuration: -time.Second}); err == nil {
+		t.Error("negative duration: got nil error, want non-nil")
+	}
+}
+
+func TestSoftDeletePolicyBucketNotExist(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	defer close()
+	if _, err := c.Bucket("b").SoftDeletePolicy(context.Background()); err != ErrBucketNotExist {
+		t.Errorf("got error %v, want %v", err, ErrBucketNotExist)
+	}
+}
+
+func TestObjectRestore(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("generation") == "404" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"bucket": "b", "name": "dir/obj", "generation": "8"}`)
+	})
+	defer close()
+	ctx := context.Background()
+	obj := c.Bucket("b").Object("dir/obj")
+
+	attrs, err := obj.Generation(5).If(Conditions{DoesNotExist: true}).Restore(ctx, &RestoreOptions{CopySourceACL: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := attrs.Generation, int64(8); got != want {
+		t.Errorf("got generation %d, want %d", got, want)
+	}
+	wantReqs := []recordedRequest{{
+		"POST", "/storage/v1/b/b/o/dir/obj/restore",
+		url.Values{"generation": {"5"}, "ifGenerationMatch": {"0"}, "copySourceAcl": {"true"}},
+		"",
+	}}
+	if diff := cmp.Diff(wantReqs, reqs, cmp.AllowUnexported(recordedRequest{})); diff != "" {
+		t.Errorf("requests mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := obj.Generation(404).Restore(ctx, nil); err != ErrObjectNotExist {
+		t.Errorf("got error %v, want %v", err, ErrObjectNotExist)
+	}
+	if _, err := obj.Restore(ctx, nil); err == nil {
+		t.Error("no generation: got nil error, want non-nil")
+	}
+}
+
+func TestObjectIteratorSoftDeleted(t *testing.T) {
+	var reqs []recordedRequest
+	c, close := newRecordingTestClient(t, &reqs, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"items": [{"name": "a", "generation": "3", "timeDeleted": "2022-03-01T10:00:00Z"}]}`)
+	})
+	defer close()
+	it := c.Bucket("b").Objects(context.Background(), &Query{SoftDeleted: true})
+	attrs, err := it.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := attrs.Deleted, time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got deletion time %v, want %v", got, want)
+	}
+	if got := reqs[0].query.Get("softDeleted"); got != "true" {
+		t.Errorf("got softDeleted %q, want %q", got, "true")
+	}
+}
@@ -112,6 +112,12 @@ type Client struct {
 	// May be nil.
 	creds *google.Credentials
 	retry *retryConfig
+	// userProject is the default project billed for requests. See
+	// WithUserProject.
+	userProject string
+	// emulator is the scheme and host of the emulator used by the client, or
+	// nil if it uses Cloud Storage.
+	emulator *url.URL
 
 	// gc is an optional gRPC-based, GAPIC client.
 	//
@@ -127,6 +133,7 @@ type Client struct {
 // are safe for concurrent use by multiple goroutines.
 func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
 	var creds *google.Credentials
+	config, opts := newClientConfig(opts)
 
 	// In general, it is recommended to use raw.NewService instead of htransport.NewClient
 	// since raw.NewService configures the correct default endpoints when initializing the
@@ -134,8 +141,13 @@ func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error
 	// access the http client directly to make requests, so we create the client manually
 	// here so it can be re-used by both reader.go and raw.NewService. This means we need to
 	// manually configure the default endpoint options on the http client. Furthermore, we
-	// need to account for STORAGE_EMULATOR_HOST override when setting the default endpoints.
-	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host == "" {
+	// need to account for an emulator, set with WithEmulator or STORAGE_EMULATOR_HOST,
+	// when setting the default endpoints.
+	host := config.emulatorHost
+	if host == "" {
+		host = os.Getenv("STORAGE_EMULATOR_HOST")
+	}
+	if host == "" {
 		// Prepend default options to avoid overriding options passed by the user.
 		opts = append([]option.ClientOption{option.WithScopes(ScopeFullControl, "https://www.googleapis.com/auth/cloud-platform"), option.WithUserAgent(userAgent)}, opts...)
 
@@ -150,20 +162,10 @@ func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error
 			opts = append(opts, internaloption.WithCredentials(creds))
 		}
 	} else {
-		var hostURL *url.URL
-
-		if strings.Contains(host, "://") {
-			h, err := url.Parse(host)
-			if err != nil {
-				return nil, err
-			}
-			hostURL = h
-		} else {
-			// Add scheme for user if not supplied in STORAGE_EMULATOR_HOST
-			// URL is only parsed correctly if it has a scheme, so we build it ourselves
-			hostURL = &url.URL{Scheme: "http", Host: host}
+		hostURL, err := parseEmulatorHost(host)
+		if err != nil {
+			return nil, err
 		}
-
 		hostURL.Path = "storage/v1/"
 		endpoint := hostURL.String()
 
@@ -179,26 +181,52 @@ func NewClient(ctx context.Context, opts ...option.ClientOption) (*Client, error
 	if err != nil {
 		return nil, fmt.Errorf("dialing: %v", err)
 	}
-	// RawService should be created with the chosen endpoint to take account of user override.
-	rawService, err := raw.NewService(ctx, option.WithEndpoint(ep), option.WithHTTPClient(hc))
-	if err != nil {
-		return nil, fmt.Errorf("storage client: %v", err)
-	}
 	// Update readHost and scheme with the chosen endpoint.
 	u, err := url.Parse(ep)
 	if err != nil {
 		return nil, fmt.Errorf("supplied endpoint %q is not valid: %v", ep, err)
 	}
+	var emulator *url.URL
+	if host != "" {
+		emulator = &url.URL{Scheme: u.Scheme, Host: u.Host}
+		hc = newEmulatorHTTPClient(hc, emulator)
+	}
+	// RawService should be created with the chosen endpoint to take account of user override.
+	rawService, err := raw.NewService(ctx, option.WithEndpoint(ep), option.WithHTTPClient(hc))
+	if err != nil {
+		return nil, fmt.Errorf("storage client: %v", err)
+	}
 
 	return &Client{
-		hc:       hc,
-		raw:      rawService,
-		scheme:   u.Scheme,
-		readHost: u.Host,
-		creds:    creds,
+		hc:          hc,
+		raw:         rawService,
+		scheme:      u.Scheme,
+		readHost:    u.Host,
+		creds:       creds,
+		userProject: config.userProject,
+		emulator:    emulator,
 	}, nil
 }
 
+// NewGRPCClient creates a new Storage client that uses the Cloud Storage gRPC
+// API, rather than the JSON and XML APIs over HTTP, to read and write object
+// data: NewReader, NewRangeReader and NewWriter are served over gRPC. This can
+// reduce per-request overhead for high-QPS workloads running inside Google
+// Cloud. The gRPC API is in private preview and must be enabled for the
+// project.
+//
+// The client options configure the gRPC connection. Operations that are not
+// yet available over gRPC, such as bucket and object metadata calls, use the
+// JSON API with the default client settings.
+//
+// To use a local emulator, set STORAGE_EMULATOR_HOST_GRPC to the address of
+// its gRPC endpoint, in addition to STORAGE_EMULATOR_HOST for its HTTP one.
+//
+// This is an experimental API and subject to change.
+func NewGRPCClient(ctx context.Context, opts ...option.ClientOption) (*Client, error) {
+	return newHybridClient(ctx, &hybridClientOptions{GRPCOpts: opts})
+}
+
 // hybridClientOptions carries the set of client options for HTTP and gRPC clients.
 type hybridClientOptions struct {
 	HTTPOpts []option.ClientOption
@@ -214,8 +242,12 @@ func newHybridClient(ctx context.Context, opts *hybridClientOptions) (*Client, e
 		opts = &hybridClientOptions{}
 	}
 	opts.GRPCOpts = append(defaultGRPCOptions(), opts.GRPCOpts...)
+	// Storage-specific options configure the Client, which is created with
+	// the HTTP options.
+	storageOpts, grpcOpts := splitClientOptions(opts.GRPCOpts)
+	opts.GRPCOpts = grpcOpts
 
-	c, err := NewClient(ctx, opts.HTTPOpts...)
+	c, err := NewClient(ctx, append(storageOpts, opts.HTTPOpts...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -336,6 +368,18 @@ func (s bucketBoundHostname) path(bucket, object string) string {
 	return object
 }
 
+// signedURLHost returns the host of a URL for bucket in the given style. If
+// hostname is set, it replaces the default storage.googleapis.com host.
+func signedURLHost(style URLStyle, bucket, hostname string) string {
+	if hostname == "" {
+		return style.host(bucket)
+	}
+	if _, ok := style.(virtualHostedStyle); ok {
+		return bucket + "." + hostname
+	}
+	return hostname
+}
+
 // PathStyle is the default style, and will generate a URL of the form
 // "storage.googleapis.com/<bucket-name>/<object-name>".
 func PathStyle() URLStyle {
@@ -443,10 +487,22 @@ type SignedURLOptions struct {
 
 	// Insecure determines whether the signed URL should use HTTPS (default) or
 	// HTTP.
-	// Only supported for V4 signing.
 	// Optional.
 	Insecure bool
 
+	// Hostname replaces storage.googleapis.com in the signed URL, for example
+	// to sign URLs for an emulator, a private endpoint or a custom domain that
+	// fronts the XML API. With VirtualHostedStyle, the bucket name is
+	// prepended to Hostname. Hostname may include a port. It cannot be used
+	// with BucketBoundHostname, which already sets the host.
+	//
+	// If Hostname is empty and an emulator is in use, through
+	// STORAGE_EMULATOR_HOST or, for BucketHandle.SignedURL, WithEmulator, the
+	// host and scheme of the emulator are used, and Style is ignored in favor
+	// of PathStyle.
+	// Optional.
+	Hostname string
+
 	// Scheme determines the version of URL signing to use. Default is
 	// SigningSchemeV2.
 	Scheme SigningScheme
@@ -465,6 +521,7 @@ func (opts *SignedURLOptions) clone() *SignedURLOptions {
 		MD5:             opts.MD5,
 		Style:           opts.Style,
 		Insecure:        opts.Insecure,
+		Hostname:        opts.Hostname,
 		Scheme:          opts.Scheme,
 	}
 }
@@ -591,6 +648,11 @@ func SignedURL(bucket, object string, opts *SignedURLOptions) (string, error) {
 	if err := validateOptions(opts, now); err != nil {
 		return "", err
 	}
+	emulator, err := envEmulator()
+	if err != nil {
+		return "", err
+	}
+	opts = opts.forEmulator(emulator)
 
 	switch opts.Scheme {
 	case SigningSchemeV2:
@@ -634,6 +696,9 @@ func validateOptions(opts *SignedURLOptions, now time.Time) error {
 	if _, ok := opts.Style.(pathStyle); !ok && opts.Scheme == SigningSchemeV2 {
 		return errors.New("storage: only path-style URLs are permitted with SigningSchemeV2")
 	}
+	if _, ok := opts.Style.(bucketBoundHostname); ok && opts.Hostname != "" {
+		return errors.New("storage: Hostname cannot be used with BucketBoundHostname")
+	}
 	if opts.Scheme == SigningSchemeV4 {
 		cutoff := now.Add(604801 * time.Second) // 7 days + 1 second
 		if !opts.Expires.Before(cutoff) {
@@ -722,7 +787,7 @@ func signedURLV4(bucket, name string, opts *SignedURLOptions, now time.Time) (st
 	fmt.Fprintf(buf, "%s\n", escapedQuery)
 
 	// Fill in the hostname based on the desired URL style.
-	u.Host = opts.Style.host(bucket)
+	u.Host = signedURLHost(opts.Style, bucket, opts.Hostname)
 
 	// Fill in the URL scheme.
 	if opts.Insecure {
@@ -856,7 +921,10 @@ func signedURLV2(bucket, name string, opts *SignedURLOptions) (string, error) {
 	}
 	encoded := base64.StdEncoding.EncodeToString(b)
 	u.Scheme = "https"
-	u.Host = "storage.googleapis.com"
+	if opts.Insecure {
+		u.Scheme = "http"
+	}
+	u.Host = signedURLHost(PathStyle(), bucket, opts.Hostname)
 	q := u.Query()
 	q.Set("GoogleAccessId", opts.GoogleAccessID)
 	q.Set("Expires", fmt.Sprintf("%d", opts.Expires.Unix()))
@@ -877,6 +945,7 @@ type ObjectHandle struct {
 	encryptionKey  []byte // AES-256 key
 	userProject    string // for requester-pays buckets
 	readCompressed bool   // Accept-Encoding: gzip
+	readResumes    *int   // nil means defaultReadResumes
 	retry          *retryConfig
 }
 
@@ -1125,12 +1194,46 @@ func (o *ObjectHandle) Delete(ctx context.Context) error {
 }
 
 // ReadCompressed when true causes the read to happen without decompressing.
+// Objects with "Content-Encoding: gzip" are then served as stored, so ranges
+// and the CRC32C check apply to the compressed bytes, and decompressing the
+// data is left to the caller. See https://cloud.google.com/storage/docs/transcoding.
 func (o *ObjectHandle) ReadCompressed(compressed bool) *ObjectHandle {
 	o2 := *o
 	o2.readCompressed = compressed
 	return &o2
 }
 
+// defaultReadResumes is the default maximum number of consecutive times a
+// Reader reconnects without reading any data.
+const defaultReadResumes = 5
+
+// ReadResumes sets the maximum number of consecutive times a Reader created
+// from the handle reconnects after the connection fails while the object's
+// content is being read, for example because it was reset or timed out. Each
+// reconnection continues from the last byte read with a Range request for the
+// same generation, so the content is still checked against the object's
+// CRC32C as a whole. The count is reset whenever data is read, so a long read
+// can survive any number of failures as long as it makes progress.
+//
+// The default is 5. Zero disables reconnecting, as does a retry policy of
+// RetryNever.
+func (o *ObjectHandle) ReadResumes(n int) *ObjectHandle {
+	o2 := *o
+	o2.readResumes = &n
+	return &o2
+}
+
+// maxReadResumes returns the resume budget of Readers created from o.
+func (o *ObjectHandle) maxReadResumes() int {
+	if o.retry != nil && o.retry.policy == RetryNever {
+		return 0
+	}
+	if o.readResumes == nil {
+		return defaultReadResumes
+	}
+	return *o.readResumes
+}
+
 // NewWriter returns a storage Writer that writes to the GCS object
 // associated with this ObjectHandle.
 //
@@ -1568,6 +1671,11 @@ type Query struct {
 	// object will be included in the results.
 	Versions bool
 
+	// SoftDeleted indicates whether to list only soft-deleted objects, which
+	// can be restored with ObjectHandle.Restore. Each soft-deleted generation
+	// of an object is listed. See SoftDeletePolicy.
+	SoftDeleted bool
+
 	// fieldSelection is used to select only specific fields to be returned by
 	// the query. It's used internally and is populated for the user by
 	// calling Query.SetAttrSelection
@@ -1584,6 +1692,17 @@ type Query struct {
 	// listed will have names between startOffset (inclusive) and endOffset (exclusive).
 	EndOffset string
 
+	// MatchGlob is a glob pattern used to filter results server-side, for
+	// example "logs/2022-*/**.json". Only objects whose names match the
+	// pattern are returned. See
+	// https://cloud.google.com/storage/docs/json_api/v1/objects/list#list-objects-and-prefixes-using-glob
+	// for the syntax.
+	// The gRPC API does not support glob filtering, so objects are always
+	// listed with the JSON API, even by clients that use gRPC for reads and
+	// writes.
+	// Optional.
+	MatchGlob string
+
 	// Projection defines the set of properties to return. It will default to ProjectionFull,
 	// which returns all properties. Passing ProjectionNoACL will omit Owner and ACL,
 	// which may improve performance when listing many objects.
@@ -1822,8 +1941,9 @@ func (o *ObjectHandle) Retryer(opts ...RetryOption) *ObjectHandle {
 	o2 := *o
 	var retry *retryConfig
 	if o.retry != nil {
-		// merge the options with the existing retry
-		retry = o.retry
+		// merge the options with a copy of the existing retry, so that the
+		// handle this one was derived from is not affected.
+		retry = o.retry.clone()
 	} else {
 		retry = &retryConfig{}
 	}
@@ -1947,10 +2067,32 @@ func (wef *withErrorFunc) apply(config *retryConfig) {
 	config.shouldRetry = wef.shouldRetry
 }
 
+// WithMaxAttempts allows configuration of the maximum number of times an
+// operation is attempted, including the first attempt, before the last error
+// is returned. By default, retries continue until the context is done.
+// A value of 1 disables retries.
+//
+// For uploads made by a Writer, the limit applies to the upload as a whole
+// rather than to each chunk.
+func WithMaxAttempts(maxAttempts int) RetryOption {
+	return &withMaxAttempts{
+		maxAttempts: maxAttempts,
+	}
+}
+
+type withMaxAttempts struct {
+	maxAttempts int
+}
+
+func (wma *withMaxAttempts) apply(config *retryConfig) {
+	config.maxAttempts = &wma.maxAttempts
+}
+
 type retryConfig struct {
 	backoff     *gax.Backoff
 	policy      RetryPolicy
 	shouldRetry func(err error) bool
+	maxAttempts *int
 }
 
 func (r *retryConfig) clone() *retryConfig {
@@ -1967,10 +2109,17 @@ func (r *retryConfig) clone() *retryConfig {
 		}
 	}
 
+	var maxAttempts *int
+	if r.maxAttempts != nil {
+		n := *r.maxAttempts
+		maxAttempts = &n
+	}
+
 	return &retryConfig{
 		backoff:     bo,
 		policy:      r.policy,
 		shouldRetry: r.shouldRetry,
+		maxAttempts: maxAttempts,
 	}
 }
 
@@ -2015,6 +2164,9 @@ func setEncryptionHeaders(headers http.Header, key []byte, copySource bool) erro
 // ServiceAccount fetches the email address of the given project's Google Cloud Storage service account.
 func (c *Client) ServiceAccount(ctx context.Context, projectID string) (string, error) {
 	r := c.raw.Projects.ServiceAccount.Get(projectID)
+	if c.userProject != "" {
+		r.UserProject(c.userProject)
+	}
 	var res *raw.ServiceAccount
 	var err error
 	err = run(ctx, func() error {
@@ -42,6 +42,7 @@ import (
 	"google.golang.org/api/option"
 	raw "google.golang.org/api/storage/v1"
 	storagepb "google.golang.org/genproto/googleapis/storage/v2"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -321,6 +322,34 @@ func TestSignedURLV4(t *testing.T) {
 				"&X-Goog-Signature=7369676e6564" + // hex('signed') = '7369676e6564'
 				"&X-Goog-SignedHeaders=host",
 		},
+		{
+			desc:       "With Hostname and virtual hosted style",
+			objectName: "object-name",
+			now:        expires.Add(-24 * time.Hour),
+			opts: &SignedURLOptions{
+				GoogleAccessID: "xxx@clientid",
+				SignBytes: func(b []byte) ([]byte, error) {
+					// The string to sign ends with the hash of the canonical
+					// request, which includes the host header.
+					if !strings.Contains(string(b), "27d09a6ebf62b7768cd88ae2c65938d92b1c1a5ce68cb229bccdc594b14131ac") {
+						return nil, fmt.Errorf("unexpected string to sign %q", b)
+					}
+					return []byte("signed"), nil
+				},
+				Method:   "GET",
+				Expires:  expires,
+				Scheme:   SigningSchemeV4,
+				Style:    VirtualHostedStyle(),
+				Hostname: "localhost:8080",
+				Insecure: true,
+			},
+			want: "http://bucket-name.localhost:8080/object-name" +
+				"?X-Goog-Algorithm=GOOG4-RSA-SHA256" +
+				"&X-Goog-Credential=xxx%40clientid%2F20021001%2Fauto%2Fstorage%2Fgoog4_request" +
+				"&X-Goog-Date=20021001T100000Z&X-Goog-Expires=86400" +
+				"&X-Goog-Signature=7369676e6564" +
+				"&X-Goog-SignedHeaders=host",
+		},
 	}
 	oldUTCNow := utcNow
 	defer func() {
@@ -487,6 +516,18 @@ func TestSignedURL_MissingOptions(t *testing.T) {
 			},
 			"are permitted with SigningSchemeV2",
 		},
+		{
+			&SignedURLOptions{
+				GoogleAccessID: "access_id",
+				PrivateKey:     pk,
+				Method:         "GET",
+				Expires:        now.Add(time.Hour),
+				Scheme:         SigningSchemeV4,
+				Style:          BucketBoundHostname("cdn.example.com"),
+				Hostname:       "localhost:8080",
+			},
+			"cannot be used with BucketBoundHostname",
+		},
 	}
 	oldUTCNow := utcNow
 	defer func() {
@@ -855,6 +896,26 @@ func TestObjectRetryer(t *testing.T) {
 				shouldRetry: func(err error) bool { return false },
 			},
 		},
+		{
+			name: "set max attempts only",
+			call: func(o *ObjectHandle) *ObjectHandle {
+				return o.Retryer(WithMaxAttempts(4))
+			},
+			want: &retryConfig{
+				maxAttempts: intPtr(4),
+			},
+		},
+		{
+			name: "derived handle does not change its parent",
+			call: func(o *ObjectHandle) *ObjectHandle {
+				parent := o.Retryer(WithPolicy(RetryNever))
+				parent.Retryer(WithPolicy(RetryAlways), WithMaxAttempts(2))
+				return parent
+			},
+			want: &retryConfig{
+				policy: RetryNever,
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(s *testing.T) {
@@ -1450,6 +1511,66 @@ func TestEmptyObjectIterator(t *testing.T) {
 	}
 }
 
+func TestObjectIteratorMatchGlob(t *testing.T) {
+	t.Parallel()
+	var gotQuery url.Values
+	hClient, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		gotQuery = r.URL.Query()
+		fmt.Fprintf(w, `{"items": [{"name": "logs/2022-01/a.json"}]}`)
+	})
+	defer close()
+	ctx := context.Background()
+	client, err := NewClient(ctx, option.WithHTTPClient(hClient))
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := client.Bucket("b").Objects(ctx, &Query{MatchGlob: "logs/2022-*/**.json"})
+	attrs, err := it.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := attrs.Name, "logs/2022-01/a.json"; got != want {
+		t.Errorf("got name %q, want %q", got, want)
+	}
+	if got, want := gotQuery.Get("matchGlob"), "logs/2022-*/**.json"; got != want {
+		t.Errorf("got matchGlob %q, want %q", got, want)
+	}
+}
+
+func TestObjectIteratorMatchGlobHybridClient(t *testing.T) {
+	t.Parallel()
+	var gotQuery url.Values
+	hClient, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		gotQuery = r.URL.Query()
+		fmt.Fprintf(w, `{"items": [{"name": "logs/2022-01/a.json"}]}`)
+	})
+	defer close()
+	ctx := context.Background()
+	// The gRPC endpoint is never dialed: listing with a glob goes through
+	// the JSON API.
+	client, err := newHybridClient(ctx, &hybridClientOptions{
+		HTTPOpts: []option.ClientOption{option.WithHTTPClient(hClient)},
+		GRPCOpts: []option.ClientOption{
+			option.WithEndpoint("localhost:0"),
+			option.WithGRPCDialOption(grpc.WithInsecure()),
+			option.WithoutAuthentication(),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	it := client.Bucket("b").Objects(ctx, &Query{MatchGlob: "logs/2022-*/**.json"})
+	if _, err := it.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gotQuery.Get("matchGlob"), "logs/2022-*/**.json"; got != want {
+		t.Errorf("got matchGlob %q, want %q", got, want)
+	}
+}
+
 // Test that BucketIterator's Next method correctly terminates if there is
 // nothing to iterate over.
 func TestEmptyBucketIterator(t *testing.T) {
@@ -1563,6 +1684,71 @@ func TestUserProject(t *testing.T) {
 	check("storage.notifications.list", func() { b.Notifications(ctx) })
 }
 
+func TestClientUserProject(t *testing.T) {
+	// Verify that the client's user project is sent, unless overridden.
+	t.Parallel()
+	ctx := context.Background()
+	type request struct {
+		query  url.Values
+		header http.Header
+	}
+	got := make(chan request, 1)
+	hClient, close := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		got <- request{r.URL.Query(), r.Header}
+		if strings.Contains(r.URL.Path, "/rewriteTo/") {
+			fmt.Fprintf(w, `{"done": true}`)
+		} else {
+			fmt.Fprintf(w, "{}")
+		}
+	})
+	defer close()
+	client, err := NewClient(ctx, option.WithHTTPClient(hClient), WithUserProject("p"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := client.Bucket("b")
+	o := b.Object("o")
+
+	check := func(msg, want string, f func()) {
+		f()
+		select {
+		case r := <-got:
+			up := r.query.Get("userProject")
+			if up == "" {
+				up = r.header.Get("X-Goog-User-Project")
+			}
+			if up != want {
+				t.Errorf("%s: got user project %q, want %q", msg, up, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("%s: timed out", msg)
+		}
+	}
+
+	check("buckets.insert", "p", func() { b.Create(ctx, "proj", nil) })
+	check("buckets.get", "p", func() { b.Attrs(ctx) })
+	check("buckets.list", "p", func() { client.Buckets(ctx, "proj").Next() })
+	check("storage.objects.get", "p", func() { o.Attrs(ctx) })
+	check("storage.objects.list", "p", func() { b.Objects(ctx, nil).Next() })
+	check("storage.objects.insert", "p", func() { o.NewWriter(ctx).Close() })
+	check("storage.objects.rewrite", "p", func() { o.CopierFrom(b.Object("x")).Run(ctx) })
+	check("reader", "p", func() { o.NewReader(ctx) })
+	check("storage.objectAccessControls.list", "p", func() { o.ACL().List(ctx) })
+	check("storage.bucketAccessControls.list", "p", func() { b.ACL().List(ctx) })
+	check("storage.defaultObjectAccessControls.list", "p", func() { b.DefaultObjectACL().List(ctx) })
+	check("buckets.getIamPolicy", "p", func() { b.IAM().Policy(ctx) })
+	check("storage.projects.serviceAccount.get", "p", func() { client.ServiceAccount(ctx, "proj") })
+	check("storage.projects.hmacKeys.get", "p", func() { client.HMACKeyHandle("proj", "a").Get(ctx) })
+	check("storage.projects.hmacKeys.list", "p", func() { client.ListHMACKeys(ctx, "proj").Next() })
+	check("storage.projects.hmacKeys.create", "p", func() { client.CreateHMACKey(ctx, "proj", "sa@example.com") })
+	check("bucket override", "q", func() { b.UserProject("q").Attrs(ctx) })
+	check("object override", "q", func() { b.UserProject("q").Object("o").Attrs(ctx) })
+	check("HMAC key override", "q", func() {
+		client.HMACKeyHandle("proj", "a").Get(ctx, UserProjectForHMACKeys("q"))
+	})
+}
+
 func newTestServer(handler func(w http.ResponseWriter, r *http.Request)) (*http.Client, func()) {
 	ts := httptest.NewTLSServer(http.HandlerFunc(handler))
 	tlsConf := &tls.Config{InsecureSkipVerify: true}
@@ -2166,6 +2352,7 @@ func TestSignedURLOptionsClone(t *testing.T) {
 		MD5:             "some-checksum",
 		Style:           VirtualHostedStyle(),
 		Insecure:        true,
+		Hostname:        "localhost:8080",
 		Scheme:          SigningSchemeV2,
 	}
 
@@ -2229,3 +2416,26 @@ func isZeroValue(v reflect.Value) (bool, error) {
 		return false, fmt.Errorf("unable to check kind %s", v.Kind())
 	}
 }
+
+func TestNewGRPCClient(t *testing.T) {
+	for _, env := range []string{"STORAGE_EMULATOR_HOST", "STORAGE_EMULATOR_HOST_GRPC"} {
+		orig := os.Getenv(env)
+		defer os.Setenv(env, orig)
+	}
+	os.Setenv("STORAGE_EMULATOR_HOST", "localhost:9000")
+	os.Setenv("STORAGE_EMULATOR_HOST_GRPC", "http://localhost:9001")
+
+	c, err := NewGRPCClient(context.Background())
+	if err != nil {
+		t.Fatalf("NewGRPCClient: %v", err)
+	}
+	if c.gc == nil {
+		t.Error("NewGRPCClient did not initialize the gRPC client")
+	}
+	if got, want := c.readHost, "localhost:9000"; got != want {
+		t.Errorf("got HTTP host %q, want %q", got, want)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
@@ -99,11 +99,32 @@ type Writer struct {
 	// calls to the underlying service (see
 	// https://cloud.google.com/storage/docs/json_api/v1/how-tos/resumable-upload),
 	// then ProgressFunc will be invoked after each call with the number of bytes of
-	// content copied so far.
+	// content copied so far. It is also invoked with the size of the object
+	// once the write completes, if that was not already reported.
 	//
 	// ProgressFunc should return quickly without blocking.
 	ProgressFunc func(int64)
 
+	// EnableFlush makes the Writer upload the object through a resumable
+	// upload session that it manages itself, so that Flush can persist
+	// written data before the object is finalized by Close. Data is sent
+	// from Write, Flush and Close; Write blocks while a full chunk is sent.
+	// ChunkSize must not be zero. EnableFlush is not supported with the
+	// gRPC API.
+	//
+	// EnableFlush must be set before the first Write call.
+	EnableFlush bool
+
+	// ResumeSessionURI, if set with EnableFlush, makes the Writer continue
+	// the upload session with that URI, as returned by SessionURI, instead
+	// of starting a new one. The ObjectAttrs and preconditions of the
+	// session are those it was started with. Call Flush before the first
+	// Write to learn how many bytes the service has persisted; data written
+	// afterwards is appended from that offset.
+	//
+	// ResumeSessionURI must be set before the first Write call.
+	ResumeSessionURI string
+
 	ctx context.Context
 	o   *ObjectHandle
 
@@ -125,6 +146,9 @@ type Writer struct {
 	//
 	// This is an experimental API and not intended for public use.
 	upid string
+
+	// The upload session of a Writer with EnableFlush.
+	session *uploadSession
 }
 
 func (w *Writer) open() error {
@@ -165,8 +189,13 @@ func (w *Writer) open() error {
 			Context(w.ctx).
 			Name(w.o.object)
 
+		// reported is the progress last passed to ProgressFunc.
+		var reported int64
 		if w.ProgressFunc != nil {
-			call.ProgressUpdater(func(n, _ int64) { w.ProgressFunc(n) })
+			call.ProgressUpdater(func(n, _ int64) {
+				reported = n
+				w.ProgressFunc(n)
+			})
 		}
 		if attrs.KMSKeyName != "" {
 			call.KmsKeyName(attrs.KMSKeyName)
@@ -194,17 +223,9 @@ func (w *Writer) open() error {
 			// for a resumable upload (as long as the chunk size is non-zero). Hence
 			// there is no need to add retries here.
 
-			// Retry only when the operation is idempotent or the retry policy is RetryAlways.
-			isIdempotent := w.o.conds != nil && (w.o.conds.GenerationMatch >= 0 || w.o.conds.DoesNotExist == true)
-			var useRetry bool
-			if (w.o.retry == nil || w.o.retry.policy == RetryIdempotent) && isIdempotent {
-				useRetry = true
-			} else if w.o.retry != nil && w.o.retry.policy == RetryAlways {
-				useRetry = true
-			}
-			if useRetry {
+			if w.useRetry() {
 				if w.o.retry != nil {
-					call.WithRetry(w.o.retry.backoff, w.o.retry.shouldRetry)
+					call.WithRetry(w.o.retry.backoff, w.o.retry.uploadErrorFunc())
 				} else {
 					call.WithRetry(nil, nil)
 				}
@@ -219,6 +240,11 @@ func (w *Writer) open() error {
 			return
 		}
 		w.obj = newObject(resp)
+		// Uploads sent in a single request do not report progress while
+		// they are in flight, so report completion.
+		if w.obj.Size != reported {
+			w.progress(w.obj.Size)
+		}
 	}()
 	return nil
 }
@@ -240,15 +266,17 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 		return 0, werr
 	}
 	if !w.opened {
-		// gRPC client has been initialized - use gRPC to upload.
-		if w.o.c.gc != nil {
-			if err := w.openGRPC(); err != nil {
-				return 0, err
-			}
-		} else if err := w.open(); err != nil {
+		if err := w.openAny(); err != nil {
 			return 0, err
 		}
 	}
+	if w.session != nil {
+		n, err = w.session.write(p)
+		if err != nil {
+			w.error(err)
+		}
+		return n, err
+	}
 	n, err = w.pw.Write(p)
 	if err != nil {
 		w.mu.Lock()
@@ -269,10 +297,17 @@ func (w *Writer) Write(p []byte) (n int, err error) {
 // can be retrieved by calling Attrs.
 func (w *Writer) Close() error {
 	if !w.opened {
-		if err := w.open(); err != nil {
+		open := w.open
+		if w.EnableFlush {
+			open = w.openSession
+		}
+		if err := open(); err != nil {
 			return err
 		}
 	}
+	if w.session != nil {
+		return w.session.close()
+	}
 
 	// Closing either the read or write causes the entire pipe to close.
 	if err := w.pw.Close(); err != nil {
@@ -285,6 +320,60 @@ func (w *Writer) Close() error {
 	return w.err
 }
 
+// openAny opens the Writer for the configured way of uploading.
+func (w *Writer) openAny() error {
+	switch {
+	case w.EnableFlush:
+		return w.openSession()
+	// gRPC client has been initialized - use gRPC to upload.
+	case w.o.c.gc != nil:
+		return w.openGRPC()
+	default:
+		return w.open()
+	}
+}
+
+// Flush sends the data written so far that the service can persist without
+// finalizing the object, and returns the number of bytes of the object that
+// the service has persisted. Persisted data is kept by the service even if
+// the program stops; a new Writer with ResumeSessionURI set to SessionURI
+// can continue the upload. The service persists data in multiples of
+// 256 KiB, so less than 256 KiB of the written data may remain buffered in
+// the Writer until the next Flush or Close.
+//
+// Flush requires EnableFlush.
+func (w *Writer) Flush() (int64, error) {
+	if !w.EnableFlush {
+		return 0, errors.New("storage: Writer.Flush requires Writer.EnableFlush")
+	}
+	w.mu.Lock()
+	werr := w.err
+	w.mu.Unlock()
+	if werr != nil {
+		return 0, werr
+	}
+	if !w.opened {
+		if err := w.openAny(); err != nil {
+			return 0, err
+		}
+	}
+	off, err := w.session.flush()
+	if err != nil {
+		w.error(err)
+	}
+	return off, err
+}
+
+// SessionURI returns the URI of the upload session of a Writer with
+// EnableFlush, once data has been sent to the service by Write or Flush. It
+// can be saved to resume the upload with ResumeSessionURI.
+func (w *Writer) SessionURI() string {
+	if w.session == nil {
+		return ""
+	}
+	return w.session.uri
+}
+
 // monitorCancel is intended to be used as a background goroutine. It monitors the
 // context, and when it observes that the context has been canceled, it manually
 // closes things that do not take a context.
@@ -335,9 +424,25 @@ func (w *Writer) validateWriteAttrs() error {
 	if w.ChunkSize < 0 {
 		return errors.New("storage: Writer.ChunkSize must be non-negative")
 	}
+	if w.EnableFlush && w.ChunkSize == 0 {
+		return errors.New("storage: Writer.EnableFlush requires a non-zero ChunkSize")
+	}
+	if w.ResumeSessionURI != "" && !w.EnableFlush {
+		return errors.New("storage: Writer.ResumeSessionURI requires Writer.EnableFlush")
+	}
 	return nil
 }
 
+// useRetry reports whether the upload should be retried: only when the
+// operation is idempotent or the retry policy is RetryAlways.
+func (w *Writer) useRetry() bool {
+	isIdempotent := w.o.conds != nil && (w.o.conds.GenerationMatch >= 0 || w.o.conds.DoesNotExist == true)
+	if (w.o.retry == nil || w.o.retry.policy == RetryIdempotent) && isIdempotent {
+		return true
+	}
+	return w.o.retry != nil && w.o.retry.policy == RetryAlways
+}
+
 // progress is a convenience wrapper that reports write progress to the Writer
 // ProgressFunc if it is set and progress is non-zero.
 func (w *Writer) progress(p int64) {
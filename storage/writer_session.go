@@ -0,0 +1,335 @@
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// defaultChunkRetryDeadline is the per-chunk retry deadline used when
+// Writer.ChunkRetryDeadline is zero, matching the generated client.
+const defaultChunkRetryDeadline = 32 * time.Second
+
+// uploadSession is a JSON API resumable upload session managed by a Writer
+// with EnableFlush. See
+// https://cloud.google.com/storage/docs/performing-resumable-uploads.
+type uploadSession struct {
+	w         *Writer
+	uri       string
+	chunkSize int
+
+	// offset is the number of bytes persisted by the service, and buf holds
+	// the written data that follows it.
+	offset int64
+	buf    []byte
+
+	// synced is whether offset has been read from the service for a resumed
+	// session.
+	synced   bool
+	reported int64
+	closed   bool
+}
+
+func (w *Writer) openSession() error {
+	if err := w.validateWriteAttrs(); err != nil {
+		return err
+	}
+	if w.o.c.gc != nil {
+		return errors.New("storage: Writer.EnableFlush is not supported with the gRPC API")
+	}
+	size := w.ChunkSize
+	if rem := size % googleapi.MinUploadChunkSize; rem != 0 {
+		size += googleapi.MinUploadChunkSize - rem
+	}
+	w.session = &uploadSession{
+		w:         w,
+		uri:       w.ResumeSessionURI,
+		chunkSize: size,
+		synced:    w.ResumeSessionURI == "",
+	}
+	w.opened = true
+	return nil
+}
+
+func (s *uploadSession) write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("storage: Writer is closed")
+	}
+	s.buf = append(s.buf, p...)
+	for len(s.buf) >= s.chunkSize {
+		if err := s.upload(s.chunkSize, false); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+func (s *uploadSession) flush() (int64, error) {
+	if s.closed {
+		return 0, errors.New("storage: Writer is closed")
+	}
+	if err := s.upload(len(s.buf)-len(s.buf)%googleapi.MinUploadChunkSize, false); err != nil {
+		return 0, err
+	}
+	return s.offset, nil
+}
+
+func (s *uploadSession) close() error {
+	w := s.w
+	if s.closed {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.err
+	}
+	s.closed = true
+	defer close(w.donec)
+	w.mu.Lock()
+	werr := w.err
+	w.mu.Unlock()
+	if werr != nil {
+		return werr
+	}
+	if err := s.upload(len(s.buf), true); err != nil {
+		w.error(err)
+		return err
+	}
+	if w.obj.Size != s.reported {
+		w.progress(w.obj.Size)
+	}
+	return nil
+}
+
+// upload sends data until the service has persisted the first n bytes of the
+// buffer or, if final, has finalized the object with the whole buffer.
+func (s *uploadSession) upload(n int, final bool) error {
+	ctx := s.w.ctx
+	if s.uri == "" {
+		if err := s.start(ctx); err != nil {
+			return err
+		}
+	}
+	if !s.synced {
+		if err := s.query(ctx); err != nil {
+			return err
+		}
+	}
+	target := s.offset + int64(n)
+	for s.w.obj == nil && (s.offset < target || final) {
+		size := len(s.buf)
+		if size > s.chunkSize {
+			size = s.chunkSize
+		}
+		if !final && int64(size) > target-s.offset {
+			size = int(target - s.offset)
+		}
+		last := final && size == len(s.buf)
+		before := s.offset
+		if err := s.sendChunk(ctx, size, last); err != nil {
+			return err
+		}
+		if s.w.obj == nil && s.offset == before {
+			return errors.New("storage: upload session made no progress")
+		}
+		if s.offset != s.reported && s.w.obj == nil {
+			s.reported = s.offset
+			s.w.progress(s.offset)
+		}
+	}
+	return nil
+}
+
+// start starts the upload session.
+func (s *uploadSession) start(ctx context.Context) error {
+	w := s.w
+	attrs := w.ObjectAttrs
+	rawObj := attrs.toRawObject(w.o.bucket)
+	if w.SendCRC32C {
+		rawObj.Crc32c = encodeUint32(attrs.CRC32C)
+	}
+	if w.MD5 != nil {
+		rawObj.Md5Hash = base64.StdEncoding.EncodeToString(w.MD5)
+	}
+	body, err := json.Marshal(rawObj)
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"alt":        {"json"},
+		"name":       {w.o.object},
+		"projection": {"full"},
+		"uploadType": {"resumable"},
+	}
+	if err := applyConds("NewWriter", w.o.gen, w.o.conds, queryConds(query)); err != nil {
+		return err
+	}
+	if attrs.KMSKeyName != "" {
+		query.Set("kmsKeyName", attrs.KMSKeyName)
+	}
+	if attrs.PredefinedACL != "" {
+		query.Set("predefinedAcl", attrs.PredefinedACL)
+	}
+	if w.o.userProject != "" {
+		query.Set("userProject", w.o.userProject)
+	}
+	u := googleapi.ResolveRelative(w.o.c.raw.BasePath, "/upload/storage/v1/b/"+url.PathEscape(w.o.bucket)+"/o") + "?" + query.Encode()
+
+	return run(ctx, func() error {
+		req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+		if attrs.ContentType != "" {
+			req.Header.Set("X-Upload-Content-Type", attrs.ContentType)
+		}
+		if err := setEncryptionHeaders(req.Header, w.o.encryptionKey, false); err != nil {
+			return err
+		}
+		setClientHeader(req.Header)
+		res, err := w.o.c.hc.Do(req)
+		if err != nil {
+			return err
+		}
+		defer googleapi.CloseBody(res)
+		if err := googleapi.CheckResponse(res); err != nil {
+			return err
+		}
+		if s.uri = res.Header.Get("Location"); s.uri == "" {
+			return errors.New("storage: upload session response has no Location")
+		}
+		return nil
+	}, w.o.retry, w.useRetry())
+}
+
+// query reads the number of bytes persisted by the service.
+func (s *uploadSession) query(ctx context.Context) error {
+	return run(ctx, func() error {
+		return s.put(ctx, "bytes */*", nil)
+	}, s.w.o.retry, true)
+}
+
+// sendChunk sends the first size bytes of the buffer. After a failure, the
+// persisted offset is read from the service before the rest of the data is
+// sent again.
+func (s *uploadSession) sendChunk(ctx context.Context, size int, last bool) error {
+	deadline := s.w.ChunkRetryDeadline
+	if deadline == 0 {
+		deadline = defaultChunkRetryDeadline
+	}
+	start := time.Now()
+	end := s.offset + int64(size)
+	failed := false
+	return run(ctx, func() error {
+		err := s.sendChunkOnce(ctx, end, last, failed)
+		if err != nil && time.Since(start) > deadline {
+			// Stop retrying.
+			return fmt.Errorf("storage: chunk retry deadline of %v exceeded: %v", deadline, err)
+		}
+		failed = err != nil
+		return err
+	}, s.w.o.retry, true)
+}
+
+// sendChunkOnce sends the buffered data up to the object offset end.
+func (s *uploadSession) sendChunkOnce(ctx context.Context, end int64, last, resync bool) error {
+	if resync {
+		// The previous attempt may have persisted part of the chunk.
+		if err := s.put(ctx, "bytes */*", nil); err != nil {
+			return err
+		}
+		if s.w.obj != nil || (!last && s.offset >= end) {
+			return nil
+		}
+	}
+	size := int(end - s.offset)
+	var rng string
+	switch {
+	case size == 0:
+		rng = fmt.Sprintf("bytes */%d", s.offset)
+	case last:
+		rng = fmt.Sprintf("bytes %d-%d/%d", s.offset, s.offset+int64(size)-1, s.offset+int64(size))
+	default:
+		rng = fmt.Sprintf("bytes %d-%d/*", s.offset, s.offset+int64(size)-1)
+	}
+	return s.put(ctx, rng, s.buf[:size])
+}
+
+// put sends a request to the session with the Content-Range rng, and updates
+// the persisted offset, or the Writer's object if the upload is complete.
+func (s *uploadSession) put(ctx context.Context, rng string, data []byte) error {
+	req, err := http.NewRequest("PUT", s.uri, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Range", rng)
+	// Ask for 200 rather than 308 responses for incomplete uploads, so that
+	// they are not mistaken for redirects.
+	req.Header.Set("X-GUploader-No-308", "yes")
+	setClientHeader(req.Header)
+	res, err := s.w.o.c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer googleapi.CloseBody(res)
+	if res.StatusCode == http.StatusPermanentRedirect || res.Header.Get("X-Http-Status-Code-Override") == "308" {
+		persisted := int64(0)
+		if r := res.Header.Get("Range"); r != "" {
+			end, err := strconv.ParseInt(r[strings.LastIndex(r, "-")+1:], 10, 64)
+			if err != nil || !strings.HasPrefix(r, "bytes=0-") {
+				return fmt.Errorf("storage: invalid upload session Range %q", r)
+			}
+			persisted = end + 1
+		}
+		if !s.synced {
+			// The data written to a resumed session follows what the
+			// service has persisted.
+			s.offset, s.synced = persisted, true
+			return nil
+		}
+		if persisted < s.offset || persisted > s.offset+int64(len(s.buf)) {
+			return fmt.Errorf("storage: upload session persisted %d bytes, want between %d and %d", persisted, s.offset, s.offset+int64(len(s.buf)))
+		}
+		s.buf = s.buf[persisted-s.offset:]
+		s.offset, s.synced = persisted, true
+		return nil
+	}
+	if err := googleapi.CheckResponse(res); err != nil {
+		return err
+	}
+	var obj raw.Object
+	if err := json.NewDecoder(res.Body).Decode(&obj); err != nil {
+		return err
+	}
+	s.w.obj = newObject(&obj)
+	s.offset, s.synced = int64(obj.Size), true
+	s.buf = nil
+	return nil
+}
@@ -15,14 +15,22 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/internal/testutil"
+	"github.com/googleapis/gax-go/v2"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
@@ -175,3 +183,286 @@ func TestCloseDoesNotLeak(t *testing.T) {
 
 	wc.Close()
 }
+
+func TestWriterProgressFunc(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	const contents = "hello world"
+
+	mt := &mockTransport{}
+	mt.addResult(&http.Response{StatusCode: 200, Body: bodyReader(`{"size": "11"}`)}, nil)
+	client := mockClient(t, mt)
+	wc := client.Bucket("bucketname").Object("filename1").NewWriter(ctx)
+	var got []int64
+	wc.ProgressFunc = func(n int64) { got = append(got, n) }
+	if _, err := wc.Write([]byte(contents)); err != nil {
+		t.Fatal(err)
+	}
+	if err := wc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{int64(len(contents))}; !testutil.Equal(got, want) {
+		t.Errorf("got progress %v, want %v", got, want)
+	}
+}
+
+// resumableServer fakes the JSON API resumable upload protocol for a single
+// session.
+type resumableServer struct {
+	mu        sync.Mutex
+	started   int
+	query     url.Values
+	data      []byte
+	done      bool
+	ranges    []string
+	failNext  bool // fail the next chunk after persisting half of it
+	persistAt int  // persist only this many bytes of non-final chunks, if non-zero
+}
+
+func (s *resumableServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/upload/storage/v1/b/b/o":
+		s.started++
+		s.query = r.URL.Query()
+		w.Header().Set("Location", "https://"+r.Host+"/upload/session")
+	case r.Method == "PUT" && r.URL.Path == "/upload/session":
+		body, _ := ioutil.ReadAll(r.Body)
+		rng := r.Header.Get("Content-Range")
+		s.ranges = append(s.ranges, rng)
+		if s.done {
+			fmt.Fprintf(w, `{"bucket": "b", "name": "o", "size": "%d"}`, len(s.data))
+			return
+		}
+		var start, end int64
+		total := "*"
+		if strings.HasPrefix(rng, "bytes */") {
+			total = strings.TrimPrefix(rng, "bytes */")
+			start, end = int64(len(s.data)), int64(len(s.data))-1
+		} else if _, err := fmt.Sscanf(rng, "bytes %d-%d/%s", &start, &end, &total); err != nil {
+			http.Error(w, "bad Content-Range "+rng, http.StatusBadRequest)
+			return
+		}
+		if start != int64(len(s.data)) || end-start+1 != int64(len(body)) {
+			http.Error(w, fmt.Sprintf("bad Content-Range %s after %d bytes", rng, len(s.data)), http.StatusBadRequest)
+			return
+		}
+		if total == "*" && len(body) > 0 {
+			switch {
+			case s.failNext:
+				s.failNext = false
+				s.data = append(s.data, body[:len(body)/2]...)
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			case s.persistAt > 0 && s.persistAt < len(body):
+				body = body[:s.persistAt]
+			}
+		}
+		s.data = append(s.data, body...)
+		if total != "*" {
+			if n, _ := strconv.Atoi(total); n == len(s.data) {
+				s.done = true
+				fmt.Fprintf(w, `{"bucket": "b", "name": "o", "size": "%d"}`, len(s.data))
+				return
+			}
+		}
+		w.Header().Set("X-Http-Status-Code-Override", "308")
+		if len(s.data) > 0 {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(s.data)-1))
+		}
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	const kib = 1024
+	s := &resumableServer{}
+	hc, close := newTestServer(s.handle)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 900*kib)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	w := c.Bucket("b").Object("o").If(Conditions{DoesNotExist: true}).NewWriter(ctx)
+	w.EnableFlush = true
+	w.ChunkSize = 400 * kib // rounded up to 512 KiB
+	w.ContentType = "text/plain"
+	var progress []int64
+	w.ProgressFunc = func(n int64) { progress = append(progress, n) }
+
+	if _, err := w.Write(data[:300*kib]); err != nil {
+		t.Fatal(err)
+	}
+	if w.SessionURI() != "" {
+		t.Error("session started before any data was sent")
+	}
+	off, err := w.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != 256*kib {
+		t.Errorf("first Flush: got offset %d, want %d", off, 256*kib)
+	}
+	if _, err := w.Write(data[300*kib:]); err != nil {
+		t.Fatal(err)
+	}
+	if off, err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if off != 768*kib {
+		t.Errorf("second Flush: got offset %d, want %d", off, 768*kib)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.data, data) {
+		t.Error("uploaded data does not match")
+	}
+	if got, want := w.Attrs().Size, int64(len(data)); got != want {
+		t.Errorf("got size %d, want %d", got, want)
+	}
+	wantRanges := []string{
+		"bytes 0-262143/*",
+		"bytes 262144-786431/*",
+		"bytes 786432-921599/921600",
+	}
+	if diff := testutil.Diff(wantRanges, s.ranges); diff != "" {
+		t.Errorf("Content-Range mismatch (-want +got):\n%s", diff)
+	}
+	if want := []int64{256 * kib, 768 * kib, 900 * kib}; !testutil.Equal(progress, want) {
+		t.Errorf("got progress %v, want %v", progress, want)
+	}
+	if got, want := s.query.Get("ifGenerationMatch"), "0"; got != want {
+		t.Errorf("got ifGenerationMatch %q, want %q", got, want)
+	}
+	if got, want := s.query.Get("name"), "o"; got != want {
+		t.Errorf("got name %q, want %q", got, want)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("Write after Close: got nil error, want non-nil")
+	}
+}
+
+func TestWriterFlushRetry(t *testing.T) {
+	const kib = 1024
+	s := &resumableServer{failNext: true, persistAt: 256 * kib}
+	hc, close := newTestServer(s.handle)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("0123456789abcdef"), 100*kib)
+
+	obj := c.Bucket("b").Object("o").Retryer(WithBackoff(gax.Backoff{Initial: time.Millisecond}))
+	w := obj.NewWriter(ctx)
+	w.EnableFlush = true
+	w.ChunkSize = 1024 * kib
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.data, data) {
+		t.Error("uploaded data does not match")
+	}
+	// The first chunk fails after half of it is persisted; the rest is sent
+	// again after the upload status is queried, 256 KiB at a time.
+	wantRanges := []string{
+		"bytes 0-1048575/*",
+		"bytes */*",
+		"bytes 524288-1048575/*",
+		"bytes 786432-1048575/*",
+	}
+	if diff := testutil.Diff(wantRanges, s.ranges[:len(wantRanges)]); diff != "" {
+		t.Errorf("Content-Range mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWriterResumeSession(t *testing.T) {
+	const kib = 1024
+	s := &resumableServer{}
+	hc, close := newTestServer(s.handle)
+	defer close()
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(hc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 600*kib)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	obj := c.Bucket("b").Object("o")
+
+	// The first writer persists part of the data and is abandoned.
+	w := obj.NewWriter(ctx)
+	w.EnableFlush = true
+	if _, err := w.Write(data[:550*kib]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	uri := w.SessionURI()
+	if uri == "" {
+		t.Fatal("got empty SessionURI")
+	}
+
+	w = obj.NewWriter(ctx)
+	w.EnableFlush = true
+	w.ResumeSessionURI = uri
+	off, err := w.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != 512*kib {
+		t.Fatalf("got resumed offset %d, want %d", off, 512*kib)
+	}
+	if _, err := w.Write(data[off:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(s.data, data) {
+		t.Error("uploaded data does not match")
+	}
+	if s.started != 1 {
+		t.Errorf("started %d sessions, want 1", s.started)
+	}
+}
+
+func TestWriterFlushErrors(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewClient(ctx, option.WithHTTPClient(&http.Client{Transport: &mockTransport{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := c.Bucket("b").Object("o")
+
+	if _, err := obj.NewWriter(ctx).Flush(); err == nil {
+		t.Error("Flush without EnableFlush: got nil error, want non-nil")
+	}
+	w := obj.NewWriter(ctx)
+	w.EnableFlush = true
+	w.ChunkSize = 0
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("EnableFlush with zero ChunkSize: got nil error, want non-nil")
+	}
+	w = obj.NewWriter(ctx)
+	w.ResumeSessionURI = "https://example.com/upload"
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("ResumeSessionURI without EnableFlush: got nil error, want non-nil")
+	}
+}